@@ -17,6 +17,7 @@ import (
 
 	"github.com/osbuild/osbuild-composer/internal/auth"
 	"github.com/osbuild/osbuild-composer/internal/cloudapi"
+	"github.com/osbuild/osbuild-composer/internal/distro"
 	"github.com/osbuild/osbuild-composer/internal/distroregistry"
 	"github.com/osbuild/osbuild-composer/internal/jobqueue"
 	"github.com/osbuild/osbuild-composer/internal/jobqueue/dbjobqueue"
@@ -62,9 +63,19 @@ func NewComposer(config *ComposerConfigFile, stateDir, cacheDir string) (*Compos
 		return nil, err
 	}
 
+	workersDir, err := c.ensureStateDirectory("workers", 0700)
+	if err != nil {
+		return nil, err
+	}
+
 	c.distros = distroregistry.NewDefault()
 	logrus.Infof("Loaded %d distros", len(c.distros.List()))
 
+	err = c.allowMountpoints(config.Mountpoints.AllowedPrefixes)
+	if err != nil {
+		return nil, err
+	}
+
 	c.rpm = rpmmd.NewRPMMD(path.Join(c.cacheDir, "rpmmd"), "/usr/libexec/osbuild-composer/dnf-json")
 
 	var jobs jobqueue.JobQueue
@@ -93,17 +104,43 @@ func NewComposer(config *ComposerConfigFile, stateDir, cacheDir string) (*Compos
 		return nil, fmt.Errorf("Unable to parse request job timeout: %v", err)
 	}
 
-	c.workers = worker.NewServer(c.logger, jobs, artifactsDir, requestJobTimeout, config.Worker.BasePath)
+	c.workers = worker.NewServer(c.logger, jobs, artifactsDir, workersDir, requestJobTimeout, config.Worker.BasePath)
 
 	return &c, nil
 }
 
+// allowMountpoints extends the mountpoint policies of all distros that
+// support it with the given prefixes.
+func (c *Composer) allowMountpoints(prefixes []string) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		if !path.IsAbs(prefix) || path.Clean(prefix) != prefix {
+			return fmt.Errorf("invalid mountpoint prefix %q: must be an absolute and clean path", prefix)
+		}
+	}
+
+	for _, name := range c.distros.List() {
+		if d, ok := c.distros.GetDistro(name).(distro.MountpointPolicyExtender); ok {
+			d.AllowMountpoints(prefixes...)
+		}
+	}
+	logrus.Infof("Allowed additional mountpoint prefixes %v", prefixes)
+
+	return nil
+}
+
 func (c *Composer) InitWeldr(repoPaths []string, weldrListener net.Listener,
 	distrosImageTypeDenylist map[string][]string) (err error) {
 	c.weldr, err = weldr.New(repoPaths, c.stateDir, c.rpm, c.distros, c.logger, c.workers, distrosImageTypeDenylist)
 	if err != nil {
 		return err
 	}
+	if c.config.CustomManifests.Enabled {
+		c.weldr.EnableCustomManifests(c.config.CustomManifests.AllowFileSources)
+	}
 	c.weldrListener = weldrListener
 
 	return nil
@@ -111,6 +148,9 @@ func (c *Composer) InitWeldr(repoPaths []string, weldrListener net.Listener,
 
 func (c *Composer) InitAPI(cert, key string, enableTLS bool, enableMTLS bool, enableJWT bool, l net.Listener) error {
 	c.api = cloudapi.NewServer(c.workers, c.rpm, c.distros, c.config.Koji.AWS.Bucket)
+	if c.config.CustomManifests.Enabled {
+		c.api.EnableCustomManifests(c.config.CustomManifests.AllowFileSources)
+	}
 	c.koji = kojiapi.NewServer(c.logger, c.workers, c.rpm, c.distros)
 
 	if !enableTLS {
@@ -10,10 +10,12 @@ import (
 )
 
 type ComposerConfigFile struct {
-	Koji     KojiAPIConfig   `toml:"koji"`
-	Worker   WorkerAPIConfig `toml:"worker"`
-	WeldrAPI WeldrAPIConfig  `toml:"weldr_api"`
-	LogLevel string          `toml:"log_level"`
+	Koji            KojiAPIConfig         `toml:"koji"`
+	Worker          WorkerAPIConfig       `toml:"worker"`
+	WeldrAPI        WeldrAPIConfig        `toml:"weldr_api"`
+	Mountpoints     MountpointsConfig     `toml:"mountpoints"`
+	CustomManifests CustomManifestsConfig `toml:"custom_manifests"`
+	LogLevel        string                `toml:"log_level"`
 }
 
 type KojiAPIConfig struct {
@@ -59,6 +61,18 @@ type WeldrDistroConfig struct {
 	ImageTypeDenyList []string `toml:"image_type_denylist"`
 }
 
+type MountpointsConfig struct {
+	// Prefixes allowed in addition to the mountpoint policies of the distros
+	AllowedPrefixes []string `toml:"allowed_prefixes"`
+}
+
+type CustomManifestsConfig struct {
+	// Build manifests given by users through the weldr and cloud APIs
+	Enabled bool `toml:"enabled"`
+	// Allow the sources of those manifests to reference files of the host
+	AllowFileSources bool `toml:"allow_file_sources"`
+}
+
 // weldrDistrosImageTypeDenyList returns a map of distro-specific Image Type
 // deny lists for Weldr API.
 func (c *ComposerConfigFile) weldrDistrosImageTypeDenyList() map[string][]string {
@@ -59,6 +59,8 @@ func TestDefaultConfig(t *testing.T) {
 	}
 
 	require.Equal(t, expectedWeldrAPIConfig, defaultConfig.WeldrAPI)
+
+	require.Equal(t, CustomManifestsConfig{}, defaultConfig.CustomManifests)
 }
 
 func TestConfig(t *testing.T) {
@@ -75,6 +77,11 @@ func TestConfig(t *testing.T) {
 	require.Equal(t, []string{"qcow2", "vmdk"}, config.WeldrAPI.DistroConfigs["*"].ImageTypeDenyList)
 	require.Equal(t, []string{"qcow2"}, config.WeldrAPI.DistroConfigs["rhel-84"].ImageTypeDenyList)
 
+	require.Equal(t, []string{"/srv/data", "/opt/app"}, config.Mountpoints.AllowedPrefixes)
+
+	require.True(t, config.CustomManifests.Enabled)
+	require.False(t, config.CustomManifests.AllowFileSources)
+
 	require.Equal(t, "overwrite-me-db", config.Worker.PGDatabase)
 
 	require.NoError(t, os.Setenv("PGDATABASE", "composer-db"))
@@ -6,6 +6,8 @@ import (
 
 	"github.com/coreos/go-systemd/activation"
 	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/osbuild-composer/internal/common"
 )
 
 const (
@@ -34,6 +36,7 @@ func main() {
 	}
 
 	logrus.SetOutput(os.Stdout)
+	logrus.AddHook(common.SecretsHook{})
 	logLevel, err := logrus.ParseLevel(config.LogLevel)
 
 	if err == nil {
@@ -167,6 +167,7 @@ func main() {
 		},
 		id1,
 		packages,
+		nil,
 	)
 	if err != nil {
 		panic(err)
@@ -182,6 +183,7 @@ func main() {
 		},
 		id2,
 		packages,
+		nil,
 	)
 	if err != nil {
 		panic(err)
@@ -76,7 +76,7 @@ func main() {
 	// Import Image to Compute Engine
 	if !skipImport {
 		log.Printf("[GCP] 📥 Importing image into Compute Engine as '%s'", imageName)
-		imageBuild, importErr := g.ComputeImageImport(ctx, bucketName, objectName, imageName, osFamily, region)
+		imageBuild, importErr := g.ComputeImageImport(ctx, bucketName, objectName, imageName, osFamily, region, nil)
 		if imageBuild != nil {
 			log.Printf("[GCP] 📜 Image import log URL: %s", imageBuild.LogUrl)
 			log.Printf("[GCP] 🎉 Image import finished with status: %s", imageBuild.Status)
@@ -95,7 +95,7 @@ func (impl *OSBuildKojiJobImpl) Run(job worker.Job) error {
 			// this worker only supports returning one (1) export
 			return fmt.Errorf("at most one build artifact can be exported")
 		}
-		result.OSBuildOutput, err = RunOSBuild(args.Manifest, impl.Store, outputDirectory, exports, os.Stderr)
+		result.OSBuildOutput, err = RunOSBuild(args.Manifest, impl.Store, outputDirectory, exports, nil, os.Stderr)
 		if err != nil {
 			return err
 		}
@@ -4,20 +4,24 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	cloudbuildpb "google.golang.org/genproto/googleapis/devtools/cloudbuild/v1"
 
 	"github.com/osbuild/osbuild-composer/internal/cloud/gcp"
 	"github.com/osbuild/osbuild-composer/internal/common"
 	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild1"
+	"github.com/osbuild/osbuild-composer/internal/prometheus"
 	"github.com/osbuild/osbuild-composer/internal/target"
 	"github.com/osbuild/osbuild-composer/internal/upload/awsupload"
 	"github.com/osbuild/osbuild-composer/internal/upload/azure"
 	"github.com/osbuild/osbuild-composer/internal/upload/koji"
+	"github.com/osbuild/osbuild-composer/internal/upload/oci"
 	"github.com/osbuild/osbuild-composer/internal/upload/vmware"
 	"github.com/osbuild/osbuild-composer/internal/worker"
 )
@@ -29,11 +33,38 @@ type OSBuildJobImpl struct {
 	GCPCreds    []byte
 	AzureCreds  *azure.Credentials
 	AWSCreds    string
+	OCICreds    *oci.Credentials
+	// GPG home directory with the keys ostree commits can be signed with
+	GPGHomedir string
+	// TLS credentials of ostree repositories that require mutual TLS, by
+	// the names jobs reference them with
+	TLSSecrets map[string]OSTreeTLSSecret
 }
 
-func appendTargetError(res *worker.OSBuildJobResult, err error) {
+// OSTreeTLSSecret are the paths of the TLS client certificate and key, and
+// the CA certificate, of an ostree repository that requires mutual TLS
+type OSTreeTLSSecret struct {
+	ClientCert string `toml:"client_cert"`
+	ClientKey  string `toml:"client_key"`
+	CA         string `toml:"ca"`
+}
+
+// env returns the environment variables the org.osbuild.mtls secrets
+// provider of the osbuild ostree source reads the credentials from
+func (s OSTreeTLSSecret) env() []string {
+	env := []string{
+		"OSBUILD_SOURCES_OSTREE_SSL_CLIENT_CERT=" + s.ClientCert,
+		"OSBUILD_SOURCES_OSTREE_SSL_CLIENT_KEY=" + s.ClientKey,
+	}
+	if s.CA != "" {
+		env = append(env, "OSBUILD_SOURCES_OSTREE_SSL_CA_CERT="+s.CA)
+	}
+	return env
+}
+
+func appendTargetError(logger *logrus.Entry, res *worker.OSBuildJobResult, err error) {
 	errStr := err.Error()
-	log.Printf("target failed: %s", errStr)
+	logger.Printf("target failed: %s", errStr)
 	res.TargetErrors = append(res.TargetErrors, errStr)
 }
 
@@ -49,6 +80,8 @@ func (impl *OSBuildJobImpl) getAWS(region string, accessId string, secret string
 }
 
 func (impl *OSBuildJobImpl) Run(job worker.Job) error {
+	logger := jobLogger(job)
+
 	// Initialize variable needed for reporting back to osbuild-composer.
 	var osbuildJobResult *worker.OSBuildJobResult = &worker.OSBuildJobResult{
 		Success: false,
@@ -64,12 +97,12 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 	defer func() {
 		err := job.Update(osbuildJobResult)
 		if err != nil {
-			log.Printf("Error reporting job result: %v", err)
+			logger.Printf("Error reporting job result: %v", err)
 		}
 
 		err = os.RemoveAll(outputDirectory)
 		if err != nil {
-			log.Printf("Error removing temporary output directory (%s): %v", outputDirectory, err)
+			logger.Printf("Error removing temporary output directory (%s): %v", outputDirectory, err)
 		}
 	}()
 
@@ -84,10 +117,14 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 	if err != nil {
 		return err
 	}
+	if args.ImageType != "" {
+		logger = logger.WithField(common.LogFieldImageType, args.ImageType)
+	}
+
 	// The specification allows multiple upload targets because it is an array, but we don't support it.
 	// Return an error to osbuild-composer.
 	if len(args.Targets) > 1 {
-		log.Printf("The job specification contains more than one upload target. This is not supported any more. " +
+		logger.Printf("The job specification contains more than one upload target. This is not supported any more. " +
 			"This might indicate a deployment of incompatible osbuild-worker and osbuild-composer versions.")
 		return nil
 	}
@@ -102,43 +139,59 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		return fmt.Errorf("at most one build artifact can be exported")
 	}
 
+	var env []string
+	if args.OSTreeTLSSecret != "" {
+		secret, ok := impl.TLSSecrets[args.OSTreeTLSSecret]
+		if !ok {
+			// retrying won't help until the worker is configured
+			osbuildJobResult.OSTreeTLSSecretError = fmt.Sprintf("ostree TLS secret %q is not configured on the worker", args.OSTreeTLSSecret)
+			logger.Error(osbuildJobResult.OSTreeTLSSecretError)
+			return nil
+		}
+		env = secret.env()
+	}
+
 	// Run osbuild and handle two kinds of errors
-	osbuildJobResult.OSBuildOutput, err = RunOSBuild(args.Manifest, impl.Store, outputDirectory, exports, os.Stderr)
+	osbuildJobResult.OSBuildOutput, err = RunOSBuild(args.Manifest, impl.Store, outputDirectory, exports, env, os.Stderr)
 	// First handle the case when "running" osbuild failed
 	if err != nil {
 		return err
 	}
 
-	log.Println("Build stages results:")
+	logger.Println("Build stages results:")
 
 	// Include the build stages output inside the worker's logs.
 	for _, stage := range osbuildJobResult.OSBuildOutput.Build.Stages {
 		if stage.Success {
-			log.Println(stage.Name, " success")
+			logger.Println(stage.Name, " success")
 		} else {
-			log.Printf("%s failure:\n", stage.Name)
+			logger.Printf("%s failure:\n", stage.Name)
 			stageOutput := strings.Split(stage.Output, "\n")
 			for _, line := range stageOutput {
-				log.Printf("	%s", line)
+				logger.Printf("	%s", line)
 			}
 		}
 	}
 
-	log.Println("Stages results:")
+	logger.Println("Stages results:")
 
 	// Include the stages output inside the worker's logs.
 	for _, stage := range osbuildJobResult.OSBuildOutput.Stages {
 		if stage.Success {
-			log.Println(stage.Name, " success")
+			logger.Println(stage.Name, " success")
 		} else {
-			log.Printf("%s failure:\n", stage.Name)
+			logger.Printf("%s failure:\n", stage.Name)
 			stageOutput := strings.Split(stage.Output, "\n")
 			for _, line := range stageOutput {
-				log.Printf("	%s", line)
+				logger.Printf("	%s", line)
 			}
 		}
 	}
 
+	if summary := osbuildJobResult.OSBuildOutput.Summary; summary != nil && summary.FailedStage != nil {
+		logger.Errorf("Build %s", summary.FailedStage)
+	}
+
 	// Second handle the case when the build failed, but osbuild finished successfully
 	if !osbuildJobResult.OSBuildOutput.Success {
 		return nil
@@ -150,6 +203,23 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 	// by any of the image types and it can't be specified during the request.
 	// Use the first (and presumably only) export for the imagePath.
 	exportPath := exports[0]
+
+	if args.OSTreeSigning != nil {
+		archivePath := path.Join(outputDirectory, exportPath, args.ImageName)
+		keyID, err := signOSTreeCommit(args.OSTreeSigning, impl.GPGHomedir, archivePath, impl.Output)
+		if err != nil {
+			logger.Errorf("Signing the ostree commit failed: %v", err)
+			osbuildJobResult.OSTreeSigningError = err.Error()
+			return nil
+		}
+		osbuildJobResult.OSTreeSigningKeyID = keyID
+	}
+
+	osbuildJobResult.Artifacts, err = worker.ChecksumArtifacts(path.Join(outputDirectory, exportPath))
+	if err != nil {
+		return fmt.Errorf("cannot compute the checksums of the artifacts: %v", err)
+	}
+
 	if osbuildJobResult.OSBuildOutput.Success && args.ImageName != "" {
 		var f *os.File
 		imagePath := path.Join(outputDirectory, exportPath, args.ImageName)
@@ -159,6 +229,15 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				return err
 			}
 			streamOptimizedPath = f.Name()
+
+			// the converted image is what is uploaded as the artifact
+			checksum, err := worker.ChecksumFile(args.ImageName, streamOptimizedPath)
+			if err != nil {
+				return fmt.Errorf("cannot compute the checksum of the stream optimized image: %v", err)
+			}
+			if artifact := worker.FindArtifact(osbuildJobResult.Artifacts, args.ImageName); artifact != nil {
+				*artifact = checksum
+			}
 		} else {
 			f, err = os.Open(imagePath)
 			if err != nil {
@@ -176,6 +255,13 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		osbuildJobResult.Success = true
 		osbuildJobResult.UploadStatus = "success"
 	} else if len(args.Targets) == 1 {
+		if osbuildJobResult.OSBuildOutput.Success {
+			uploadStart := time.Now()
+			defer func() {
+				prometheus.UploadFinished(args.Targets[0].Name, time.Since(uploadStart), osbuildJobResult.Success)
+			}()
+		}
+
 		switch options := args.Targets[0].Options.(type) {
 		case *target.VMWareTargetOptions:
 			credentials := vmware.Credentials{
@@ -189,14 +275,14 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 
 			tempDirectory, err := ioutil.TempDir(impl.Output, job.Id().String()+"-vmware-*")
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
 			defer func() {
 				err := os.RemoveAll(tempDirectory)
 				if err != nil {
-					log.Printf("Error removing temporary directory for vmware symlink(%s): %v", tempDirectory, err)
+					logger.Printf("Error removing temporary directory for vmware symlink(%s): %v", tempDirectory, err)
 				}
 			}()
 
@@ -205,13 +291,13 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 			imagePath := path.Join(tempDirectory, imageName)
 			err = os.Symlink(streamOptimizedPath, imagePath)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
 			err = vmware.UploadImage(credentials, imagePath)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
@@ -220,7 +306,7 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		case *target.AWSTargetOptions:
 			a, err := impl.getAWS(options.Region, options.AccessKeyID, options.SecretAccessKey, options.SessionToken)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
@@ -231,18 +317,18 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 
 			_, err = a.Upload(path.Join(outputDirectory, exportPath, options.Filename), options.Bucket, key)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
 			ami, err := a.Register(args.Targets[0].ImageName, options.Bucket, key, options.ShareWithAccounts, common.CurrentArch())
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
 			if ami == nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("No ami returned"))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("No ami returned"))
 				return nil
 			}
 
@@ -256,7 +342,7 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		case *target.AWSS3TargetOptions:
 			a, err := impl.getAWS(options.Region, options.AccessKeyID, options.SecretAccessKey, options.SessionToken)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
@@ -268,12 +354,12 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 
 			_, err = a.Upload(path.Join(outputDirectory, exportPath, options.Filename), options.Bucket, key)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 			url, err := a.S3ObjectPresignedURL(options.Bucket, key)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
@@ -284,7 +370,7 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		case *target.AzureTargetOptions:
 			azureStorageClient, err := azure.NewStorageClient(options.StorageAccount, options.StorageAccessKey)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return err
 			}
 
@@ -302,7 +388,7 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 			)
 
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
@@ -311,54 +397,67 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 		case *target.GCPTargetOptions:
 			ctx := context.Background()
 
-			g, err := gcp.New(impl.GCPCreds)
+			creds := impl.GCPCreds
+			if options.Credentials != nil {
+				creds = options.Credentials
+			}
+			g, err := gcp.New(creds)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
-			log.Printf("[GCP] 🚀 Uploading image to: %s/%s", options.Bucket, options.Object)
+			logger.Printf("[GCP] 🚀 Uploading image to: %s/%s", options.Bucket, options.Object)
 			_, err = g.StorageObjectUpload(ctx, path.Join(outputDirectory, exportPath, options.Filename),
 				options.Bucket, options.Object, map[string]string{gcp.MetadataKeyImageName: args.Targets[0].ImageName})
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
 
-			log.Printf("[GCP] 📥 Importing image into Compute Engine as '%s'", args.Targets[0].ImageName)
-			imageBuild, importErr := g.ComputeImageImport(ctx, options.Bucket, options.Object, args.Targets[0].ImageName, options.Os, options.Region)
-			if imageBuild != nil {
-				log.Printf("[GCP] 📜 Image import log URL: %s", imageBuild.LogUrl)
-				log.Printf("[GCP] 🎉 Image import finished with status: %s", imageBuild.Status)
-
-				// Cleanup all resources potentially left after the image import job
-				deleted, err := g.CloudbuildBuildCleanup(ctx, imageBuild.Id)
-				for _, d := range deleted {
-					log.Printf("[GCP] 🧹 Deleted resource after image import job: %s", d)
-				}
-				if err != nil {
-					log.Printf("[GCP] Encountered error during image import cleanup: %v", err)
+			var importErr error
+			if strings.HasSuffix(options.Filename, ".tar.gz") {
+				// the archive contains a raw disk, which Compute Engine
+				// creates the image from without converting it
+				logger.Printf("[GCP] 📥 Creating Compute Engine image '%s'", args.Targets[0].ImageName)
+				_, importErr = g.ComputeImageInsert(ctx, options.Bucket, options.Object, args.Targets[0].ImageName, options.Region, options.GuestOSFeatures)
+			} else {
+				logger.Printf("[GCP] 📥 Importing image into Compute Engine as '%s'", args.Targets[0].ImageName)
+				var imageBuild *cloudbuildpb.Build
+				imageBuild, importErr = g.ComputeImageImport(ctx, options.Bucket, options.Object, args.Targets[0].ImageName, options.Os, options.Region, options.GuestOSFeatures)
+				if imageBuild != nil {
+					logger.Printf("[GCP] 📜 Image import log URL: %s", imageBuild.LogUrl)
+					logger.Printf("[GCP] 🎉 Image import finished with status: %s", imageBuild.Status)
+
+					// Cleanup all resources potentially left after the image import job
+					deleted, err := g.CloudbuildBuildCleanup(ctx, imageBuild.Id)
+					for _, d := range deleted {
+						logger.Printf("[GCP] 🧹 Deleted resource after image import job: %s", d)
+					}
+					if err != nil {
+						logger.Printf("[GCP] Encountered error during image import cleanup: %v", err)
+					}
 				}
 			}
 
 			// Cleanup storage before checking for errors
-			log.Printf("[GCP] 🧹 Deleting uploaded image file: %s/%s", options.Bucket, options.Object)
+			logger.Printf("[GCP] 🧹 Deleting uploaded image file: %s/%s", options.Bucket, options.Object)
 			if err = g.StorageObjectDelete(ctx, options.Bucket, options.Object); err != nil {
-				log.Printf("[GCP] Encountered error while deleting object: %v", err)
+				logger.Printf("[GCP] Encountered error while deleting object: %v", err)
 			}
 
-			// check error from ComputeImageImport()
+			// check error from ComputeImageImport() or ComputeImageInsert()
 			if importErr != nil {
-				appendTargetError(osbuildJobResult, importErr)
+				appendTargetError(logger, osbuildJobResult, importErr)
 				return nil
 			}
-			log.Printf("[GCP] 💿 Image URL: %s", g.ComputeImageURL(args.Targets[0].ImageName))
+			logger.Printf("[GCP] 💿 Image URL: %s", g.ComputeImageURL(args.Targets[0].ImageName))
 
 			if len(options.ShareWithAccounts) > 0 {
-				log.Printf("[GCP] 🔗 Sharing the image with: %+v", options.ShareWithAccounts)
+				logger.Printf("[GCP] 🔗 Sharing the image with: %+v", options.ShareWithAccounts)
 				err = g.ComputeImageShare(ctx, args.Targets[0].ImageName, options.ShareWithAccounts)
 				if err != nil {
-					appendTargetError(osbuildJobResult, err)
+					appendTargetError(logger, osbuildJobResult, err)
 					return nil
 				}
 			}
@@ -374,16 +473,16 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 			ctx := context.Background()
 
 			if impl.AzureCreds == nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("osbuild job has org.osbuild.azure.image target but this worker doesn't have azure credentials"))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("osbuild job has org.osbuild.azure.image target but this worker doesn't have azure credentials"))
 				return nil
 			}
 
 			c, err := azure.NewClient(*impl.AzureCreds, options.TenantID)
 			if err != nil {
-				appendTargetError(osbuildJobResult, err)
+				appendTargetError(logger, osbuildJobResult, err)
 				return nil
 			}
-			log.Print("[Azure] 🔑 Logged in Azure")
+			logger.Print("[Azure] 🔑 Logged in Azure")
 
 			storageAccountTag := azure.Tag{
 				Name:  "imageBuilderStorageAccount",
@@ -397,12 +496,12 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				storageAccountTag,
 			)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("searching for a storage account failed: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("searching for a storage account failed: %v", err))
 				return nil
 			}
 
 			if storageAccount == "" {
-				log.Print("[Azure] 📦 Creating a new storage account")
+				logger.Print("[Azure] 📦 Creating a new storage account")
 				const storageAccountPrefix = "ib"
 				storageAccount = azure.RandomStorageAccountName(storageAccountPrefix)
 
@@ -415,12 +514,12 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 					storageAccountTag,
 				)
 				if err != nil {
-					appendTargetError(osbuildJobResult, fmt.Errorf("creating a new storage account failed: %v", err))
+					appendTargetError(logger, osbuildJobResult, fmt.Errorf("creating a new storage account failed: %v", err))
 					return nil
 				}
 			}
 
-			log.Print("[Azure] 🔑📦 Retrieving a storage account key")
+			logger.Print("[Azure] 🔑📦 Retrieving a storage account key")
 			storageAccessKey, err := c.GetStorageAccountKey(
 				ctx,
 				options.SubscriptionID,
@@ -428,22 +527,22 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				storageAccount,
 			)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("retrieving the storage account key failed: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("retrieving the storage account key failed: %v", err))
 				return nil
 			}
 
 			azureStorageClient, err := azure.NewStorageClient(storageAccount, storageAccessKey)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("creating the storage client failed: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("creating the storage client failed: %v", err))
 				return nil
 			}
 
 			storageContainer := "imagebuilder"
 
-			log.Print("[Azure] 📦 Ensuring that we have a storage container")
+			logger.Print("[Azure] 📦 Ensuring that we have a storage container")
 			err = azureStorageClient.CreateStorageContainerIfNotExist(ctx, storageAccount, storageContainer)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("cannot create a storage container: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("cannot create a storage container: %v", err))
 				return nil
 			}
 
@@ -452,7 +551,7 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				blobName += ".vhd"
 			}
 
-			log.Print("[Azure] ⬆ Uploading the image")
+			logger.Print("[Azure] ⬆ Uploading the image")
 			err = azureStorageClient.UploadPageBlob(
 				azure.BlobMetadata{
 					StorageAccount: storageAccount,
@@ -463,11 +562,11 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				azure.DefaultUploadThreads,
 			)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("uploading the image failed: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("uploading the image failed: %v", err))
 				return nil
 			}
 
-			log.Print("[Azure] 📝 Registering the image")
+			logger.Print("[Azure] 📝 Registering the image")
 			err = c.RegisterImage(
 				ctx,
 				options.SubscriptionID,
@@ -479,21 +578,119 @@ func (impl *OSBuildJobImpl) Run(job worker.Job) error {
 				options.Location,
 			)
 			if err != nil {
-				appendTargetError(osbuildJobResult, fmt.Errorf("registering the image failed: %v", err))
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("registering the image failed: %v", err))
 				return nil
 			}
 
-			log.Print("[Azure] 🎉 Image uploaded and registered!")
+			logger.Print("[Azure] 🎉 Image uploaded and registered!")
+
+			var galleryImageVersionID string
+			if options.Gallery != nil {
+				var targetRegions []azure.GalleryTargetRegion
+				for _, r := range options.Gallery.TargetRegions {
+					targetRegions = append(targetRegions, azure.GalleryTargetRegion{
+						Name:         r.Name,
+						ReplicaCount: r.ReplicaCount,
+					})
+				}
+
+				logger.Printf("[Azure] 🖼 Publishing the image as version %s of %s/%s", options.Gallery.Version, options.Gallery.Name, options.Gallery.ImageDefinition)
+				galleryCtx, cancel := context.WithTimeout(ctx, azure.DefaultGalleryReplicationTimeout)
+				galleryImageVersionID, err = c.CreateGalleryImageVersion(
+					galleryCtx,
+					options.SubscriptionID,
+					options.ResourceGroup,
+					args.Targets[0].ImageName,
+					options.Location,
+					azure.GalleryImageVersion{
+						Gallery:         options.Gallery.Name,
+						ImageDefinition: options.Gallery.ImageDefinition,
+						Version:         options.Gallery.Version,
+						TargetRegions:   targetRegions,
+					},
+				)
+				cancel()
+				if err != nil {
+					appendTargetError(logger, osbuildJobResult, fmt.Errorf("publishing the image to the gallery failed: %v", err))
+					return nil
+				}
+
+				logger.Printf("[Azure] 🎉 Image version %s replicated!", galleryImageVersionID)
+			}
 
 			osbuildJobResult.TargetResults = append(osbuildJobResult.TargetResults, target.NewAzureImageTargetResult(&target.AzureImageTargetResultOptions{
-				ImageName: args.Targets[0].ImageName,
+				ImageName:             args.Targets[0].ImageName,
+				GalleryImageVersionID: galleryImageVersionID,
+			}))
+
+			osbuildJobResult.Success = true
+			osbuildJobResult.UploadStatus = "success"
+		case *target.OCITargetOptions:
+			ctx := context.Background()
+
+			if impl.OCICreds == nil {
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("osbuild job has org.osbuild.oci target but this worker doesn't have OCI credentials"))
+				return nil
+			}
+
+			c, err := oci.NewClient(*impl.OCICreds, options.Region)
+			if err != nil {
+				appendTargetError(logger, osbuildJobResult, err)
+				return nil
+			}
+
+			namespace, err := c.Namespace(ctx)
+			if err != nil {
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("retrieving the object storage namespace failed: %w", err))
+				return nil
+			}
+
+			logger.Printf("[OCI] 🚀 Uploading image to: %s/%s", options.Bucket, options.Object)
+			err = c.UploadObject(ctx, namespace, options.Bucket, options.Object, path.Join(outputDirectory, exportPath, options.Filename))
+			if err != nil {
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("uploading the image failed: %w", err))
+				return nil
+			}
+
+			logger.Printf("[OCI] 📥 Creating custom image '%s'", args.Targets[0].ImageName)
+			imageID, importErr := c.CreateImage(ctx, oci.ImageOptions{
+				Compartment: options.Compartment,
+				DisplayName: args.Targets[0].ImageName,
+				Namespace:   namespace,
+				Bucket:      options.Bucket,
+				Object:      options.Object,
+				LaunchMode:  options.LaunchMode,
+			})
+			if importErr == nil {
+				logger.Printf("[OCI] ⏳ Waiting for the import of image %s", imageID)
+				importCtx, cancel := context.WithTimeout(ctx, oci.DefaultImageImportTimeout)
+				importErr = c.WaitForImage(importCtx, imageID)
+				cancel()
+			}
+
+			// Cleanup storage before checking for errors
+			logger.Printf("[OCI] 🧹 Deleting uploaded image file: %s/%s", options.Bucket, options.Object)
+			if err = c.DeleteObject(ctx, namespace, options.Bucket, options.Object); err != nil {
+				logger.Printf("[OCI] Encountered error while deleting object: %v", err)
+			}
+
+			if importErr != nil {
+				appendTargetError(logger, osbuildJobResult, fmt.Errorf("creating the custom image failed: %w", importErr))
+				return nil
+			}
+
+			logger.Printf("[OCI] 🎉 Image %s created", imageID)
+
+			osbuildJobResult.TargetResults = append(osbuildJobResult.TargetResults, target.NewOCITargetResult(&target.OCITargetResultOptions{
+				ImageID: imageID,
+				Region:  c.Region(),
 			}))
 
 			osbuildJobResult.Success = true
 			osbuildJobResult.UploadStatus = "success"
 		default:
 			err = fmt.Errorf("invalid target type: %s", args.Targets[0].Name)
-			appendTargetError(osbuildJobResult, err)
+			appendTargetError(logger, osbuildJobResult, err)
 			return nil
 		}
 	}
@@ -8,18 +8,22 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 
 	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/prometheus"
 	"github.com/osbuild/osbuild-composer/internal/rpmmd"
 	"github.com/osbuild/osbuild-composer/internal/upload/azure"
 	"github.com/osbuild/osbuild-composer/internal/upload/koji"
+	"github.com/osbuild/osbuild-composer/internal/upload/oci"
 	"github.com/osbuild/osbuild-composer/internal/worker"
 )
 
@@ -74,8 +78,11 @@ func WatchJob(ctx context.Context, job worker.Job) {
 		select {
 		case <-time.After(15 * time.Second):
 			canceled, err := job.Canceled()
+			if err == nil {
+				prometheus.WorkerLastHeartbeat.SetToCurrentTime()
+			}
 			if err == nil && canceled {
-				logrus.Info("Job was canceled. Exiting.")
+				jobLogger(job).Info("Job was canceled. Exiting.")
 				os.Exit(0)
 			}
 		case <-ctx.Done():
@@ -84,6 +91,15 @@ func WatchJob(ctx context.Context, job worker.Job) {
 	}
 }
 
+// jobLogger returns a logger which adds the ID and type of job to all lines it
+// logs.
+func jobLogger(job worker.Job) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		common.LogFieldJobID:   job.Id().String(),
+		common.LogFieldJobType: job.Type(),
+	})
+}
+
 // Requests and runs 1 job of specified type(s)
 // Returning an error here will result in the worker backing off for a while and retrying
 func RequestAndRunJob(client *worker.Client, acceptedJobTypes []string, jobImpls map[string]JobImplementation) error {
@@ -98,13 +114,15 @@ func RequestAndRunJob(client *worker.Client, acceptedJobTypes []string, jobImpls
 		return err
 	}
 
+	logger := jobLogger(job)
+
 	impl, exists := jobImpls[job.Type()]
 	if !exists {
-		logrus.Errorf("Ignoring job with unknown type %s", job.Type())
+		logger.Errorf("Ignoring job with unknown type %s", job.Type())
 		return err
 	}
 
-	logrus.Infof("Running '%s' job %v\n", job.Type(), job.Id())
+	logger.Infof("Running '%s' job %v\n", job.Type(), job.Id())
 
 	ctx, cancelWatcher := context.WithCancel(context.Background())
 	go WatchJob(ctx, job)
@@ -112,12 +130,12 @@ func RequestAndRunJob(client *worker.Client, acceptedJobTypes []string, jobImpls
 	err = impl.Run(job)
 	cancelWatcher()
 	if err != nil {
-		logrus.Warnf("Job %s failed: %v", job.Id(), err)
+		logger.Warnf("Job %s failed: %v", job.Id(), err)
 		// Don't return this error so the worker picks up the next job immediately
 		return nil
 	}
 
-	logrus.Infof("Job %s finished", job.Id())
+	logger.Infof("Job %s finished", job.Id())
 	return nil
 }
 
@@ -138,11 +156,21 @@ func main() {
 		AWS *struct {
 			Credentials string `toml:"credentials"`
 		} `toml:"aws"`
+		OCI *struct {
+			Credentials string `toml:"credentials"`
+		} `toml:"oci"`
 		Authentication *struct {
 			OAuthURL         string `toml:"oauth_url"`
 			OfflineTokenPath string `toml:"offline_token"`
 		} `toml:"authentication"`
 		BasePath string `toml:"base_path"`
+		Metrics  *struct {
+			ListenAddress string `toml:"listen_address"`
+		} `toml:"metrics"`
+		OSTreeSigning *struct {
+			GPGHomedir string `toml:"gpg_homedir"`
+		} `toml:"ostree_signing"`
+		OSTreeTLSSecrets map[string]OSTreeTLSSecret `toml:"ostree_tls_secrets"`
 	}
 	var unix bool
 	flag.BoolVar(&unix, "unix", false, "Interpret 'address' as a path to a unix domain socket instead of a network address")
@@ -155,6 +183,8 @@ func main() {
 
 	flag.Parse()
 
+	logrus.AddHook(common.SecretsHook{})
+
 	address := flag.Arg(0)
 	if address == "" {
 		flag.Usage()
@@ -176,6 +206,15 @@ func main() {
 		config.BasePath = "/api/worker/v1"
 	}
 
+	if config.Metrics != nil && config.Metrics.ListenAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(prometheus.WorkerRegistry, promhttp.HandlerOpts{}))
+		go func() {
+			err := http.ListenAndServe(config.Metrics.ListenAddress, mux)
+			logrus.Fatalf("Metrics listener failed: %v", err)
+		}()
+	}
+
 	cacheDirectory, ok := os.LookupEnv("CACHE_DIRECTORY")
 	if !ok {
 		logrus.Fatal("CACHE_DIRECTORY is not set. Is the service file missing CacheDirectory=?")
@@ -281,6 +320,24 @@ func main() {
 		awsCredentials = config.AWS.Credentials
 	}
 
+	// Load OCI credentials early, including the API signing key they
+	// reference, so that a malformed file or an unreadable key is reported
+	// before the first osbuild job with the org.osbuild.oci target.
+	var ociCredentials *oci.Credentials
+	if config.OCI != nil {
+		ociCredentials, err = oci.ParseOCICredentialsFile(config.OCI.Credentials)
+		if err != nil {
+			logrus.Fatalf("cannot load OCI credentials: %v", err)
+		}
+	}
+
+	// Keys referenced by ostree commit signing requests are looked up in
+	// this GPG home directory. Secret keys of requests don't need it.
+	var gpgHomedir string
+	if config.OSTreeSigning != nil {
+		gpgHomedir = config.OSTreeSigning.GPGHomedir
+	}
+
 	// depsolve jobs can be done during other jobs
 	depsolveCtx, depsolveCtxCancel := context.WithCancel(context.Background())
 	defer depsolveCtxCancel()
@@ -321,6 +378,9 @@ func main() {
 			GCPCreds:    gcpCredentials,
 			AzureCreds:  azureCredentials,
 			AWSCreds:    awsCredentials,
+			OCICreds:    ociCredentials,
+			GPGHomedir:  gpgHomedir,
+			TLSSecrets:  config.OSTreeTLSSecrets,
 		},
 		"osbuild-koji": &OSBuildKojiJobImpl{
 			Store:       store,
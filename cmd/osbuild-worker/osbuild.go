@@ -5,18 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild1"
+	"github.com/osbuild/osbuild-composer/internal/prometheus"
 )
 
 // Run an instance of osbuild, returning a parsed osbuild.Result.
 //
 // Note that osbuild returns non-zero when the pipeline fails. This function
 // does not return an error in this case. Instead, the failure is communicated
-// with its corresponding logs through osbuild.Result.
-func RunOSBuild(manifest distro.Manifest, store, outputDirectory string, exports []string, errorWriter io.Writer) (*osbuild.Result, error) {
+// with its corresponding logs through osbuild.Result. The variables of env
+// are added to the environment of osbuild.
+func RunOSBuild(manifest distro.Manifest, store, outputDirectory string, exports, env []string, errorWriter io.Writer) (*osbuild.Result, error) {
 	cmd := exec.Command(
 		"osbuild",
 		"--store", store,
@@ -27,6 +30,9 @@ func RunOSBuild(manifest distro.Manifest, store, outputDirectory string, exports
 	for _, export := range exports {
 		cmd.Args = append(cmd.Args, "--export", export)
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	cmd.Stderr = errorWriter
 
 	stdin, err := cmd.StdinPipe()
@@ -68,5 +74,15 @@ func RunOSBuild(manifest distro.Manifest, store, outputDirectory string, exports
 		}
 	}
 
+	if result.Summary != nil {
+		for _, pipeline := range result.Summary.Pipelines {
+			for _, stage := range pipeline.Stages {
+				if stage.Duration > 0 {
+					prometheus.StageFinished(stage.Type, stage.Duration, stage.Success)
+				}
+			}
+		}
+	}
+
 	return &result, nil
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/osbuild/osbuild-composer/internal/worker"
+)
+
+// signOSTreeCommit signs the ostree commit in the commit archive at
+// archivePath with the key of signing and returns the ID of the key. Keys
+// referenced by their ID are looked up in gpgHomedir, secret keys of the
+// request are imported into a temporary home directory below tmpDir, which
+// is removed afterwards.
+func signOSTreeCommit(signing *worker.OSTreeSigning, gpgHomedir, archivePath, tmpDir string) (string, error) {
+	workDir, err := ioutil.TempDir(tmpDir, "ostree-sign-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create a directory to sign the commit in: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	keyID := signing.KeyID
+	homedir := gpgHomedir
+	if signing.Key != "" {
+		homedir = path.Join(workDir, "gnupg")
+		if err := os.Mkdir(homedir, 0700); err != nil {
+			return "", fmt.Errorf("cannot create a GPG home directory: %v", err)
+		}
+		// stop the agent gpg started for the home directory before it is
+		// removed with the key
+		defer func() {
+			_ = exec.Command("gpgconf", "--homedir", homedir, "--kill", "gpg-agent").Run()
+		}()
+		keyID, err = importGPGSecretKey(homedir, signing.Key)
+		if err != nil {
+			return "", err
+		}
+	} else if homedir == "" {
+		return "", fmt.Errorf("ostree signing key %q is not available, the worker has no GPG home directory configured", keyID)
+	} else if err := exec.Command("gpg", "--homedir", homedir, "--batch", "--list-secret-keys", keyID).Run(); err != nil {
+		return "", fmt.Errorf("ostree signing key %q is not available on the worker", keyID)
+	}
+
+	treeDir := path.Join(workDir, "tree")
+	if err := os.Mkdir(treeDir, 0755); err != nil {
+		return "", fmt.Errorf("cannot create a directory to extract the commit to: %v", err)
+	}
+	if out, err := exec.Command("tar", "--extract", "--file", archivePath, "--directory", treeDir).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cannot extract the commit archive: %v: %s", err, out)
+	}
+
+	commit, err := composeCommit(path.Join(treeDir, "compose.json"))
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("ostree", "gpg-sign", "--repo", path.Join(treeDir, "repo"), "--gpg-homedir", homedir, commit, keyID)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cannot sign ostree commit %s with key %q: %v: %s", commit, keyID, err, out)
+	}
+
+	// replace the archive with one of the signed repository
+	signedPath := archivePath + ".signed"
+	if out, err := exec.Command("tar", "--create", "--file", signedPath, "--directory", treeDir, ".").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cannot create the signed commit archive: %v: %s", err, out)
+	}
+	if err := os.Rename(signedPath, archivePath); err != nil {
+		return "", fmt.Errorf("cannot replace the commit archive: %v", err)
+	}
+
+	return keyID, nil
+}
+
+// importGPGSecretKey imports the ASCII armored secret key into homedir and
+// returns its fingerprint.
+func importGPGSecretKey(homedir, key string) (string, error) {
+	cmd := exec.Command("gpg", "--homedir", homedir, "--batch", "--import")
+	cmd.Stdin = strings.NewReader(key)
+	// the output doesn't contain the key itself
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("cannot import the ostree signing key: %v: %s", err, out)
+	}
+
+	out, err := exec.Command("gpg", "--homedir", homedir, "--batch", "--with-colons", "--list-secret-keys").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot list the imported ostree signing key: %v", err)
+	}
+	// the first fingerprint is the one of the primary key
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9], nil
+		}
+	}
+	return "", fmt.Errorf("the ostree signing key doesn't contain a secret key")
+}
+
+// composeCommit returns the ID of the commit in the compose.json file the
+// org.osbuild.ostree.commit stage writes next to the repository.
+func composeCommit(composePath string) (string, error) {
+	data, err := ioutil.ReadFile(composePath)
+	if err != nil {
+		return "", fmt.Errorf("cannot read the compose metadata of the commit: %v", err)
+	}
+	var compose struct {
+		Commit string `json:"ostree-commit"`
+	}
+	if err := json.Unmarshal(data, &compose); err != nil {
+		return "", fmt.Errorf("cannot parse the compose metadata of the commit: %v", err)
+	}
+	if compose.Commit == "" {
+		return "", fmt.Errorf("the compose metadata doesn't contain the ostree commit")
+	}
+	return compose.Commit, nil
+}
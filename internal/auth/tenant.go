@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/openshift-online/ocm-sdk-go/authentication"
+)
+
+// Claims of the access token that identify the tenant a request was made by,
+// in order of preference.
+var tenantClaims = []string{"rh-org-id", "account_number"}
+
+// TenantFromContext returns the tenant of the access token the request of ctx
+// was authenticated with, or an empty string if the request was not
+// authenticated or the token does not identify a tenant.
+func TenantFromContext(ctx context.Context) string {
+	token, err := authentication.TokenFromContext(ctx)
+	if err != nil || token == nil {
+		return ""
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+
+	for _, claim := range tenantClaims {
+		if tenant, ok := claims[claim].(string); ok && tenant != "" {
+			return tenant
+		}
+	}
+	return ""
+}
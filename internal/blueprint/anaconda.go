@@ -0,0 +1,118 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// anacondaModulePrefix is the prefix of the DBus names of the kickstart
+// modules of Anaconda
+const anacondaModulePrefix = "org.fedoraproject.Anaconda.Modules."
+
+// anacondaModules are the kickstart modules of Anaconda in RHEL 8
+var anacondaModules = []string{
+	"Localization",
+	"Network",
+	"Payloads",
+	"Security",
+	"Services",
+	"Storage",
+	"Subscription",
+	"Timezone",
+	"Users",
+}
+
+// MaxISOLabelLength is the length of the volume ID of ISO9660 filesystems
+const MaxISOLabelLength = 32
+
+// ISO labels are restricted to the d-characters of ISO9660 and hyphens, the
+// installer finds its kickstart by the label, so it must not need quoting in
+// kernel options either
+var isoLabelRegexp = regexp.MustCompile(`^[A-Z0-9_-]+$`)
+
+// ValidateISOLabel returns an error if label can't be the volume ID of the
+// ISO of an installer.
+func ValidateISOLabel(label string) error {
+	if len(label) > MaxISOLabelLength {
+		return fmt.Errorf("ISO label %q is longer than %d characters", label, MaxISOLabelLength)
+	}
+	if !isoLabelRegexp.MatchString(label) {
+		return fmt.Errorf("ISO label %q must only contain uppercase letters, digits, underscores and hyphens", label)
+	}
+	return nil
+}
+
+// MinInstallerRootFSSize is the minimum size in bytes of the root filesystem
+// image of installers, which must hold the installer tree
+const MinInstallerRootFSSize = 2 * 1024 * 1024 * 1024
+
+// InstallerRootFSCustomization overrides the root filesystem image of the
+// installer that the ISO boots into.
+type InstallerRootFSCustomization struct {
+	// Size in bytes of the filesystem image, rounded up to MiB
+	Size uint64 `json:"size,omitempty" toml:"size,omitempty"`
+	// Compression of the squashfs holding the image, "xz" or "zstd"
+	Compression string `json:"compression,omitempty" toml:"compression,omitempty"`
+}
+
+// Validate returns an error if the size is below MinInstallerRootFSSize or
+// the compression is unknown.
+func (c *InstallerRootFSCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Size != 0 && c.Size < MinInstallerRootFSSize {
+		return fmt.Errorf("installer root filesystem size %d is smaller than the minimum of %d bytes", c.Size, MinInstallerRootFSSize)
+	}
+	switch c.Compression {
+	case "", "xz", "zstd":
+	default:
+		return fmt.Errorf("unsupported installer root filesystem compression %q", c.Compression)
+	}
+	return nil
+}
+
+// AnacondaModulesCustomization enables and disables kickstart modules of the
+// installer in addition to the defaults of the image type. Modules are named
+// by their DBus names, e.g. org.fedoraproject.Anaconda.Modules.Users.
+type AnacondaModulesCustomization struct {
+	Enable  []string `json:"enable,omitempty" toml:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty" toml:"disable,omitempty"`
+}
+
+// Validate returns an error if a module is unknown or if it is both enabled
+// and disabled.
+func (c *AnacondaModulesCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	enabled := make(map[string]bool)
+	for _, module := range c.Enable {
+		if err := checkAnacondaModule(module); err != nil {
+			return err
+		}
+		enabled[module] = true
+	}
+	for _, module := range c.Disable {
+		if err := checkAnacondaModule(module); err != nil {
+			return err
+		}
+		if enabled[module] {
+			return fmt.Errorf("installer module %q is both enabled and disabled", module)
+		}
+	}
+	return nil
+}
+
+func checkAnacondaModule(module string) error {
+	name := strings.TrimPrefix(module, anacondaModulePrefix)
+	if name != module {
+		for _, known := range anacondaModules {
+			if name == known {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown installer module %q, must be one of %s%s", module, anacondaModulePrefix, strings.Join(anacondaModules, ", "+anacondaModulePrefix))
+}
@@ -0,0 +1,70 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnacondaModulesCustomization_Validate(t *testing.T) {
+	var unset *AnacondaModulesCustomization
+	assert.NoError(t, unset.Validate())
+
+	modules := &AnacondaModulesCustomization{
+		Enable:  []string{"org.fedoraproject.Anaconda.Modules.Users", "org.fedoraproject.Anaconda.Modules.Services"},
+		Disable: []string{"org.fedoraproject.Anaconda.Modules.Network"},
+	}
+	assert.NoError(t, modules.Validate())
+
+	modules.Enable = append(modules.Enable, "org.fedoraproject.Anaconda.Modules.User")
+	assert.EqualError(t, modules.Validate(), `unknown installer module "org.fedoraproject.Anaconda.Modules.User", must be one of `+
+		"org.fedoraproject.Anaconda.Modules.Localization, org.fedoraproject.Anaconda.Modules.Network, "+
+		"org.fedoraproject.Anaconda.Modules.Payloads, org.fedoraproject.Anaconda.Modules.Security, "+
+		"org.fedoraproject.Anaconda.Modules.Services, org.fedoraproject.Anaconda.Modules.Storage, "+
+		"org.fedoraproject.Anaconda.Modules.Subscription, org.fedoraproject.Anaconda.Modules.Timezone, "+
+		"org.fedoraproject.Anaconda.Modules.Users")
+
+	modules.Enable = []string{"Users"}
+	assert.Error(t, modules.Validate())
+
+	modules.Enable = []string{"org.fedoraproject.Anaconda.Modules.Network"}
+	assert.EqualError(t, modules.Validate(), `installer module "org.fedoraproject.Anaconda.Modules.Network" is both enabled and disabled`)
+}
+
+func TestValidateISOLabel(t *testing.T) {
+	for _, label := range []string{"ACME-OS-8_6", "X", "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"} {
+		assert.NoError(t, ValidateISOLabel(label), label)
+	}
+
+	cases := []struct {
+		label string
+		err   string
+	}{
+		{"", `ISO label "" must only contain uppercase letters, digits, underscores and hyphens`},
+		{"Acme-OS", `ISO label "Acme-OS" must only contain uppercase letters, digits, underscores and hyphens`},
+		{"ACME OS", `ISO label "ACME OS" must only contain uppercase letters, digits, underscores and hyphens`},
+		{"ACME:OS", `ISO label "ACME:OS" must only contain uppercase letters, digits, underscores and hyphens`},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456", `ISO label "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456" is longer than 32 characters`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, ValidateISOLabel(c.label), c.err)
+	}
+}
+
+func TestInstallerRootFSCustomization_Validate(t *testing.T) {
+	var rootfs *InstallerRootFSCustomization
+	assert.NoError(t, rootfs.Validate())
+
+	rootfs = &InstallerRootFSCustomization{}
+	assert.NoError(t, rootfs.Validate())
+
+	rootfs = &InstallerRootFSCustomization{Size: MinInstallerRootFSSize, Compression: "zstd"}
+	assert.NoError(t, rootfs.Validate())
+
+	rootfs.Size = MinInstallerRootFSSize - 1
+	assert.EqualError(t, rootfs.Validate(), "installer root filesystem size 2147483647 is smaller than the minimum of 2147483648 bytes")
+
+	rootfs.Size = 0
+	rootfs.Compression = "lz4"
+	assert.EqualError(t, rootfs.Validate(), `unsupported installer root filesystem compression "lz4"`)
+}
@@ -0,0 +1,25 @@
+package blueprint
+
+import (
+	"errors"
+)
+
+// AuthselectCustomization selects the authselect profile of the image, e.g.
+// sssd for images joined to IdM or Active Directory
+type AuthselectCustomization struct {
+	Profile string `json:"profile" toml:"profile"`
+	// Optional features of the profile, e.g. with-mkhomedir. authselect
+	// rejects the features the profile doesn't know when the image is built.
+	Features []string `json:"features,omitempty" toml:"features,omitempty"`
+}
+
+// Validate returns an error if the profile is empty.
+func (c *AuthselectCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Profile == "" {
+		return errors.New("authselect profile must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthselectCustomization_Validate(t *testing.T) {
+	var unset *AuthselectCustomization
+	assert.NoError(t, unset.Validate())
+	assert.NoError(t, (&AuthselectCustomization{Profile: "sssd"}).Validate())
+	assert.NoError(t, (&AuthselectCustomization{Profile: "sssd", Features: []string{"with-mkhomedir", "with-unknown"}}).Validate())
+
+	assert.EqualError(t, (&AuthselectCustomization{}).Validate(), "authselect profile must not be empty")
+	assert.EqualError(t, (&AuthselectCustomization{Features: []string{"with-mkhomedir"}}).Validate(), "authselect profile must not be empty")
+}
@@ -0,0 +1,36 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxBannerSize is the maximum size of a login banner in bytes
+const MaxBannerSize = 16 * 1024
+
+// BannerCustomization sets the login banners of the image. The texts are
+// written to the files as they are, including trailing newlines.
+type BannerCustomization struct {
+	// Message of the day in /etc/motd, shown after logging in
+	Motd string `json:"motd,omitempty" toml:"motd,omitempty"`
+	// Text in /etc/issue, shown before logging in on a console
+	Issue string `json:"issue,omitempty" toml:"issue,omitempty"`
+}
+
+// Validate returns an error if none of the banners is set or if one of them
+// is larger than MaxBannerSize.
+func (c *BannerCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Motd == "" && c.Issue == "" {
+		return errors.New("banner customization requires a motd or an issue text")
+	}
+	if len(c.Motd) > MaxBannerSize {
+		return fmt.Errorf("motd banner exceeds the maximum size of %d bytes", MaxBannerSize)
+	}
+	if len(c.Issue) > MaxBannerSize {
+		return fmt.Errorf("issue banner exceeds the maximum size of %d bytes", MaxBannerSize)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package blueprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBannerCustomization_Validate(t *testing.T) {
+	var unset *BannerCustomization
+	assert.NoError(t, unset.Validate())
+	assert.NoError(t, (&BannerCustomization{Motd: "Authorized use only.\n\n"}).Validate())
+	assert.NoError(t, (&BannerCustomization{Issue: "$(reboot) `id` \\S\n"}).Validate())
+	assert.NoError(t, (&BannerCustomization{Motd: strings.Repeat("x", MaxBannerSize), Issue: strings.Repeat("x", MaxBannerSize)}).Validate())
+
+	assert.EqualError(t, (&BannerCustomization{}).Validate(), "banner customization requires a motd or an issue text")
+	assert.EqualError(t, (&BannerCustomization{Motd: strings.Repeat("x", MaxBannerSize+1)}).Validate(), "motd banner exceeds the maximum size of 16384 bytes")
+	assert.EqualError(t, (&BannerCustomization{Issue: strings.Repeat("x", MaxBannerSize+1)}).Validate(), "issue banner exceeds the maximum size of 16384 bytes")
+}
@@ -4,6 +4,7 @@ package blueprint
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/coreos/go-semver/semver"
 )
@@ -18,6 +19,7 @@ type Blueprint struct {
 	Groups         []Group         `json:"groups" toml:"groups"`
 	Customizations *Customizations `json:"customizations,omitempty" toml:"customizations,omitempty"`
 	Distro         string          `json:"distro" toml:"distro"`
+	Containers     []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
 }
 
 type Change struct {
@@ -55,6 +57,17 @@ func (b *Blueprint) DeepCopy() Blueprint {
 	return bp
 }
 
+// Redacted returns a deep copy of the blueprint without the secrets that are
+// only needed to build images, like the passphrase of disk encryption, so
+// that it can be shown to users
+func (b *Blueprint) Redacted() Blueprint {
+	bp := b.DeepCopy()
+	if encryption := bp.Customizations.GetDiskEncryption(); encryption != nil {
+		encryption.Passphrase = ""
+	}
+	return bp
+}
+
 // Initialize ensures that the blueprint has sane defaults for any missing fields
 func (b *Blueprint) Initialize() error {
 	if b.Packages == nil {
@@ -74,6 +87,78 @@ func (b *Blueprint) Initialize() error {
 	if err != nil {
 		return fmt.Errorf("Invalid 'version', must use Semantic Versioning: %s", err.Error())
 	}
+	if _, err := b.Customizations.GetFirewall().NormalizedPorts(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetFirewall().ValidateZones(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetServices().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.checkUserExpireDates(time.Now()); err != nil {
+		return err
+	}
+	if err := b.Customizations.checkInstallationDevices(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetDracut().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetKernel().Modules.Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.ValidateSysctl(); err != nil {
+		return err
+	}
+	if err := b.Customizations.ValidateUdevRules(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetTuned().Validate(b.Customizations.GetServices(), b.Customizations.GetDNF()); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetKdump().Validate(b.Customizations.GetServices()); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetAuthselect().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.ValidateSudoers(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetBanner().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.ValidateCACerts(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetProxy().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetDNF().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.ValidateRepositories(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetOSTree().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetEdgeContainer().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetInstallerModules().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetIgnition().Validate(); err != nil {
+		return err
+	}
+	if err := b.Customizations.GetFDO().Validate(); err != nil {
+		return err
+	}
+	if err := b.ValidateContainers(); err != nil {
+		return err
+	}
 	return nil
 }
 
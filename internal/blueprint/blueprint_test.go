@@ -30,6 +30,29 @@ func TestDeepCopy(t *testing.T) {
 	require.Equalf(t, bpCopy.Packages[0].Version, "1.2.3", "Blueprint.DeepCopy failed, copy modified.")
 }
 
+func TestRedacted(t *testing.T) {
+	bp := Blueprint{
+		Name: "redacted-test",
+		Customizations: &Customizations{
+			Disk: &DiskCustomization{
+				Encryption: &DiskEncryptionCustomization{
+					Passphrase: "secret",
+					Clevis:     &ClevisCustomization{Pin: "null", Policy: "{}"},
+				},
+			},
+		},
+	}
+
+	redacted := bp.Redacted()
+	assert.Equal(t, "", redacted.Customizations.Disk.Encryption.Passphrase)
+	assert.Equal(t, bp.Customizations.Disk.Encryption.Clevis, redacted.Customizations.Disk.Encryption.Clevis)
+	assert.Equal(t, "secret", bp.Customizations.Disk.Encryption.Passphrase, "Blueprint.Redacted modified the original")
+
+	// blueprints without secrets are copied as they are
+	bp.Customizations = nil
+	assert.Equal(t, bp, bp.Redacted())
+}
+
 func TestBlueprintInitialize(t *testing.T) {
 	cases := []struct {
 		NewBlueprint  Blueprint
@@ -43,6 +66,8 @@ func TestBlueprintInitialize(t *testing.T) {
 		{Blueprint{Name: "bp-test-5", Description: "Invalid version 5", Version: "foo"}, true},
 		{Blueprint{Name: "bp-test-7", Description: "Zero version", Version: "0.0.0"}, false},
 		{Blueprint{Name: "bp-test-8", Description: "X.Y.Z version", Version: "2.1.3"}, false},
+		{Blueprint{Name: "bp-test-9", Description: "Valid firewall ports", Customizations: &Customizations{Firewall: &FirewallCustomization{Ports: []string{"22:tcp", "8000-8080/udp"}}}}, false},
+		{Blueprint{Name: "bp-test-10", Description: "Invalid firewall port", Customizations: &Customizations{Firewall: &FirewallCustomization{Ports: []string{"8080-tcp"}}}}, true},
 	}
 
 	for _, c := range cases {
@@ -0,0 +1,52 @@
+package blueprint
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// CACertCustomization adds certificates to the trust store of the image
+type CACertCustomization struct {
+	// PEM encoded certificates, one or more
+	PEM string `json:"pem" toml:"pem"`
+}
+
+// GetCACerts returns the CA certificates of the customizations.
+func (c *Customizations) GetCACerts() []CACertCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.CACerts
+}
+
+// ValidateCACerts returns an error if a PEM blob has no certificates, has
+// other blocks than certificates or text that isn't PEM encoded, or has a
+// certificate that can't be parsed.
+func (c *Customizations) ValidateCACerts() error {
+	for idx, cert := range c.GetCACerts() {
+		rest := []byte(cert.PEM)
+		blocks := 0
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			blocks++
+			if block.Type != "CERTIFICATE" {
+				return fmt.Errorf("CA certificate %d has a PEM block of type %q, must be CERTIFICATE", idx, block.Type)
+			}
+			if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+				return fmt.Errorf("CA certificate %d can't be parsed: %v", idx, err)
+			}
+		}
+		// pem.Decode skips over text before a block, but not after the last
+		// one, e.g. a truncated certificate
+		if blocks == 0 || strings.TrimSpace(string(rest)) != "" {
+			return fmt.Errorf("CA certificate %d is not a PEM encoded certificate", idx)
+		}
+	}
+	return nil
+}
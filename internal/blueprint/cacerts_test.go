@@ -0,0 +1,70 @@
+package blueprint
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCACert returns a new self-signed PEM encoded CA certificate
+func testCACert(t *testing.T, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestCustomizations_ValidateCACerts(t *testing.T) {
+	var unset *Customizations
+	assert.NoError(t, unset.ValidateCACerts())
+
+	root := testCACert(t, "Example Root CA")
+	intermediate := testCACert(t, "Example Intermediate CA")
+	valid := &Customizations{
+		CACerts: []CACertCustomization{
+			{PEM: root},
+			{PEM: "Example Root CA\n" + root + "\n" + intermediate + "\n"},
+		},
+	}
+	assert.NoError(t, valid.ValidateCACerts())
+
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}))
+	garbled := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}))
+	cases := []struct {
+		pem string
+		err string
+	}{
+		{"", "CA certificate 1 is not a PEM encoded certificate"},
+		{root[:len(root)/2], "CA certificate 1 is not a PEM encoded certificate"},
+		{root + intermediate[:len(intermediate)/2], "CA certificate 1 is not a PEM encoded certificate"},
+		{key, `CA certificate 1 has a PEM block of type "PRIVATE KEY", must be CERTIFICATE`},
+	}
+	for _, c := range cases {
+		customizations := &Customizations{CACerts: []CACertCustomization{{PEM: root}, {PEM: c.pem}}}
+		assert.EqualError(t, customizations.ValidateCACerts(), c.err)
+	}
+
+	// the error of the x509 package differs between Go versions
+	customizations := &Customizations{CACerts: []CACertCustomization{{PEM: garbled}}}
+	err := customizations.ValidateCACerts()
+	require.Error(t, err)
+	assert.Regexp(t, "^CA certificate 0 can't be parsed: ", err.Error())
+}
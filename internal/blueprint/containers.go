@@ -0,0 +1,60 @@
+package blueprint
+
+import (
+	"fmt"
+
+	"github.com/osbuild/osbuild-composer/internal/container"
+)
+
+// A Container is a container image that is pulled when the image is built
+// and stored in the container storage of the image
+type Container struct {
+	// Reference of the image to pull, e.g. quay.io/fedora/fedora:35. It is
+	// pinned to a digest when the compose is started.
+	Source string `json:"source" toml:"source"`
+	// Name, and optionally tag, to store the image under. Defaults to the
+	// source reference.
+	Name string `json:"name,omitempty" toml:"name,omitempty"`
+	// Verify TLS certificates of the registry, defaults to true
+	TLSVerify *bool `json:"tls-verify,omitempty" toml:"tls-verify,omitempty"`
+}
+
+// LocalName returns the name the container image is stored under.
+func (c Container) LocalName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Source
+}
+
+// GetTLSVerify returns whether the TLS certificates of the registry are
+// verified.
+func (c Container) GetTLSVerify() bool {
+	return c.TLSVerify == nil || *c.TLSVerify
+}
+
+// ValidateContainers returns an error if the source or name of a container
+// isn't a valid image reference, if a name contains a digest, or if two
+// containers are stored under the same name.
+func (b *Blueprint) ValidateContainers() error {
+	names := make(map[string]bool)
+	for _, c := range b.Containers {
+		if _, err := container.ParseReference(c.Source); err != nil {
+			return err
+		}
+		if c.Name != "" {
+			ref, err := container.ParseReference(c.Name)
+			if err != nil {
+				return err
+			}
+			if ref.Digest != "" {
+				return fmt.Errorf("container name %q must not contain a digest", c.Name)
+			}
+		}
+		if names[c.LocalName()] {
+			return fmt.Errorf("container %q is defined more than once", c.LocalName())
+		}
+		names[c.LocalName()] = true
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainer_LocalName(t *testing.T) {
+	assert.Equal(t, "quay.io/fedora/fedora:35", Container{Source: "quay.io/fedora/fedora:35"}.LocalName())
+	assert.Equal(t, "localhost/app:v1", Container{Source: "quay.io/example/app:v1", Name: "localhost/app:v1"}.LocalName())
+}
+
+func TestContainer_GetTLSVerify(t *testing.T) {
+	disabled := false
+	assert.True(t, Container{Source: "quay.io/fedora/fedora"}.GetTLSVerify())
+	assert.False(t, Container{Source: "registry.local/app", TLSVerify: &disabled}.GetTLSVerify())
+}
+
+func TestBlueprint_ValidateContainers(t *testing.T) {
+	digest := "sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10"
+
+	valid := Blueprint{
+		Containers: []Container{
+			{Source: "quay.io/fedora/fedora:35"},
+			{Source: "quay.io/fedora/fedora:35", Name: "localhost/fedora"},
+			{Source: "registry.example.com/app@" + digest},
+		},
+	}
+	assert.NoError(t, valid.ValidateContainers())
+
+	cases := []struct {
+		container Container
+		err       string
+	}{
+		{Container{Source: "quay.io/Fedora"}, `container reference "quay.io/Fedora" has an invalid repository name`},
+		{Container{Source: "quay.io/fedora@sha256:abc"}, `container reference "quay.io/fedora@sha256:abc" has an invalid digest`},
+		{Container{Source: "quay.io/fedora", Name: "local:"}, `container reference "local:" has an invalid tag`},
+		{Container{Source: "quay.io/fedora", Name: "localhost/app@" + digest}, `container name "localhost/app@` + digest + `" must not contain a digest`},
+		{Container{Source: "quay.io/fedora/fedora:35"}, `container "quay.io/fedora/fedora:35" is defined more than once`},
+	}
+	for _, c := range cases {
+		bp := Blueprint{Containers: append(append([]Container{}, valid.Containers...), c.container)}
+		assert.EqualError(t, bp.ValidateContainers(), c.err)
+	}
+}
@@ -2,7 +2,9 @@ package blueprint
 
 import (
 	"fmt"
+	"path"
 	"reflect"
+	"time"
 )
 
 type Customizations struct {
@@ -17,11 +19,149 @@ type Customizations struct {
 	Services           *ServicesCustomization    `json:"services,omitempty" toml:"services,omitempty"`
 	Filesystem         []FilesystemCustomization `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
 	InstallationDevice string                    `json:"installation_device,omitempty" toml:"installation_device,omitempty"`
+	// Devices that the edge simplified installer tries in order when the
+	// installation device doesn't exist
+	InstallationDeviceFallbacks []string                   `json:"installation_device_fallbacks,omitempty" toml:"installation_device_fallbacks,omitempty"`
+	Installer                   *InstallerCustomization    `json:"installer,omitempty" toml:"installer,omitempty"`
+	PasswordHash                *PasswordHashCustomization `json:"password_hash,omitempty" toml:"password_hash,omitempty"`
+	// How filesystems are identified in fstab and the root= kernel
+	// argument: "uuid" (the default), "label", or "partuuid"
+	DeviceID string                `json:"device_id,omitempty" toml:"device_id,omitempty"`
+	SELinux  *SELinuxCustomization `json:"selinux,omitempty" toml:"selinux,omitempty"`
+	RPM      *RPMCustomization     `json:"rpm,omitempty" toml:"rpm,omitempty"`
+	Grub     *GrubCustomization    `json:"grub,omitempty" toml:"grub,omitempty"`
+	// How the disk of the image is partitioned: "raw" (the default) puts
+	// the filesystems on partitions, "lvm" puts them on logical volumes
+	PartitioningMode string                   `json:"partitioning_mode,omitempty" toml:"partitioning_mode,omitempty"`
+	Disk             *DiskCustomization       `json:"disk,omitempty" toml:"disk,omitempty"`
+	Swap             *SwapCustomization       `json:"swap,omitempty" toml:"swap,omitempty"`
+	SSHD             *SSHDCustomization       `json:"sshd,omitempty" toml:"sshd,omitempty"`
+	Files            []FileCustomization      `json:"files,omitempty" toml:"files,omitempty"`
+	Directories      []DirectoryCustomization `json:"directories,omitempty" toml:"directories,omitempty"`
+	OpenSCAP         *OpenSCAPCustomization   `json:"openscap,omitempty" toml:"openscap,omitempty"`
+	// Enable FIPS mode
+	FIPS     *bool                  `json:"fips,omitempty" toml:"fips,omitempty"`
+	Ignition *IgnitionCustomization `json:"ignition,omitempty" toml:"ignition,omitempty"`
+	FDO      *FDOCustomization      `json:"fdo,omitempty" toml:"fdo,omitempty"`
+	Dracut   *DracutCustomization   `json:"dracut,omitempty" toml:"dracut,omitempty"`
+	// Kernel parameters set in /etc/sysctl.d
+	Sysctl     []SysctlCustomization    `json:"sysctl,omitempty" toml:"sysctl,omitempty"`
+	UdevRules  []UdevRulesCustomization `json:"udev_rules,omitempty" toml:"udev_rules,omitempty"`
+	Tuned      *TunedCustomization      `json:"tuned,omitempty" toml:"tuned,omitempty"`
+	Kdump      *KdumpCustomization      `json:"kdump,omitempty" toml:"kdump,omitempty"`
+	Authselect *AuthselectCustomization `json:"authselect,omitempty" toml:"authselect,omitempty"`
+	Banner     *BannerCustomization     `json:"banner,omitempty" toml:"banner,omitempty"`
+	// Certificates added to the trust store
+	CACerts []CACertCustomization `json:"cacerts,omitempty" toml:"cacerts,omitempty"`
+	Proxy   *ProxyCustomization   `json:"proxy,omitempty" toml:"proxy,omitempty"`
+	DNF     *DNFCustomization     `json:"dnf,omitempty" toml:"dnf,omitempty"`
+	// Additional repositories of the packages of the image
+	Repositories []RepositoryCustomization `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	OSTree       *OSTreeCustomization      `json:"ostree,omitempty" toml:"ostree,omitempty"`
+	// nginx server of edge-container images
+	EdgeContainer *EdgeContainerCustomization `json:"edge_container,omitempty" toml:"edge_container,omitempty"`
+	// IBM Secure Execution of s390x images
+	SecureExecution *SecureExecutionCustomization `json:"secure_execution,omitempty" toml:"secure_execution,omitempty"`
+}
+
+// OpenSCAPCustomization remediates the image against a profile of a SCAP
+// datastream when it is built
+type OpenSCAPCustomization struct {
+	// Path of the datastream in the image, the datastream of the
+	// distribution from scap-security-guide if unset
+	DataStream string `json:"datastream,omitempty" toml:"datastream,omitempty"`
+	// ID of the profile, e.g. xccdf_org.ssgproject.content_profile_cis
+	ProfileID string `json:"profile_id" toml:"profile_id"`
+}
+
+// SwapCustomization adds swap space to disk images
+type SwapCustomization struct {
+	// Size of the swap space in MiB
+	Size uint64 `json:"size" toml:"size"`
+	// Where the swap space is: "partition" (the default) for a dedicated
+	// partition, or "file" for a swap file on the root filesystem
+	Type string `json:"type,omitempty" toml:"type,omitempty"`
+}
+
+type DiskCustomization struct {
+	Encryption *DiskEncryptionCustomization `json:"encryption,omitempty" toml:"encryption,omitempty"`
+}
+
+// DiskEncryptionCustomization encrypts the root partition of disk images
+// with LUKS2
+type DiskEncryptionCustomization struct {
+	// Passphrase of the first key slot, which is also used to add the
+	// Clevis key slot. It is not shown in API responses.
+	Passphrase string `json:"passphrase,omitempty" toml:"passphrase,omitempty"`
+	// Binds the container to a Clevis policy, so that it is unlocked
+	// without the passphrase
+	Clevis *ClevisCustomization `json:"clevis,omitempty" toml:"clevis,omitempty"`
+}
+
+type ClevisCustomization struct {
+	Pin string `json:"pin" toml:"pin"`
+	// Configuration of the pin in JSON
+	Policy string `json:"policy" toml:"policy"`
 }
 
 type KernelCustomization struct {
 	Name   string `json:"name,omitempty" toml:"name,omitempty"`
 	Append string `json:"append" toml:"append"`
+	// Keys of default kernel arguments of the image type to remove, e.g.
+	// "console" removes all console= arguments
+	Remove []string `json:"remove,omitempty" toml:"remove,omitempty"`
+	// Kernel modules settings
+	Modules *KernelModulesCustomization `json:"modules,omitempty" toml:"modules,omitempty"`
+}
+
+// GrubCustomization sets how the GRUB menu of bootable images behaves
+type GrubCustomization struct {
+	// The default menu entry: its index, its title or id, or "saved"
+	Default string `json:"default,omitempty" toml:"default,omitempty"`
+	// Seconds until the default entry is booted
+	Timeout *int `json:"timeout,omitempty" toml:"timeout,omitempty"`
+	// How the menu is shown while waiting: menu, countdown, or hidden
+	TimeoutStyle string `json:"timeout_style,omitempty" toml:"timeout_style,omitempty"`
+	// Terminals of the menu: console, serial, or both, the default terminal
+	// of the platform if unset
+	Terminal []string `json:"terminal,omitempty" toml:"terminal,omitempty"`
+	// Settings of the serial terminal
+	Serial *GrubSerialCustomization `json:"serial,omitempty" toml:"serial,omitempty"`
+	// Password required to edit menu entries or use the GRUB command line,
+	// either plain text or hashed with grub2-mkpasswd-pbkdf2
+	Password string `json:"password,omitempty" toml:"password,omitempty"`
+	// Name of the GRUB superuser with the password, root if unset
+	Superuser string `json:"superuser,omitempty" toml:"superuser,omitempty"`
+}
+
+// GrubSerialCustomization sets up the serial port of the serial terminal of
+// GRUB
+type GrubSerialCustomization struct {
+	// Number of the serial port, 0 for the first one
+	Unit int `json:"unit,omitempty" toml:"unit,omitempty"`
+	// Baud rate of the port, 115200 if unset
+	Speed int `json:"speed,omitempty" toml:"speed,omitempty"`
+}
+
+type SELinuxCustomization struct {
+	// The SELinux policy of the image: targeted (the default), mls, or
+	// minimum
+	Policy string `json:"policy,omitempty" toml:"policy,omitempty"`
+	// Relabel the whole filesystem on first boot
+	ForceAutorelabel bool `json:"force_autorelabel,omitempty" toml:"force_autorelabel,omitempty"`
+	// Paths which are not labeled when the image is built
+	Exclude []string `json:"exclude,omitempty" toml:"exclude,omitempty"`
+	// The SELinux mode the image boots in: enforcing (the default) or
+	// permissive
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+}
+
+// RPMCustomization controls what the installed packages put into the image
+type RPMCustomization struct {
+	// Don't install documentation
+	ExcludeDocs *bool `json:"exclude_docs,omitempty" toml:"exclude_docs,omitempty"`
+	// Only install translations for these languages, e.g. "en_US"
+	InstallLangs []string `json:"install_langs,omitempty" toml:"install_langs,omitempty"`
 }
 
 type SSHKeyCustomization struct {
@@ -39,16 +179,58 @@ type UserCustomization struct {
 	Groups      []string `json:"groups,omitempty" toml:"groups,omitempty"`
 	UID         *int     `json:"uid,omitempty" toml:"uid,omitempty"`
 	GID         *int     `json:"gid,omitempty" toml:"gid,omitempty"`
+	HomeMode    *string  `json:"home_mode,omitempty" toml:"home_mode,omitempty"`
+	System      *bool    `json:"system,omitempty" toml:"system,omitempty"`
+	// Lock the password of the account, so that it can't log in with one
+	Locked *bool `json:"locked,omitempty" toml:"locked,omitempty"`
+	// Date on which the account is disabled, as YYYY-MM-DD
+	ExpireDate *string `json:"expire_date,omitempty" toml:"expire_date,omitempty"`
+	// Allow the user to run any command with sudo, without a password if
+	// SudoNopasswd is set
+	Sudo         *bool `json:"sudo,omitempty" toml:"sudo,omitempty"`
+	SudoNopasswd *bool `json:"sudo_nopasswd,omitempty" toml:"sudo_nopasswd,omitempty"`
+}
+
+// layout of the expiration dates of users
+const userExpireDateLayout = "2006-01-02"
+
+// checkUserExpireDates returns an error if the expiration date of a user
+// isn't a valid date or is before the day of now
+func (c *Customizations) checkUserExpireDates(now time.Time) error {
+	today := now.UTC().Format(userExpireDateLayout)
+	for _, user := range c.GetUsers() {
+		if user.ExpireDate == nil {
+			continue
+		}
+		if _, err := time.Parse(userExpireDateLayout, *user.ExpireDate); err != nil {
+			return fmt.Errorf("invalid expiration date %q of user %q, must be YYYY-MM-DD", *user.ExpireDate, user.Name)
+		}
+		// dates in this layout sort chronologically
+		if *user.ExpireDate < today {
+			return fmt.Errorf("expiration date %s of user %q is in the past", *user.ExpireDate, user.Name)
+		}
+	}
+	return nil
 }
 
 type GroupCustomization struct {
 	Name string `json:"name" toml:"name"`
 	GID  *int   `json:"gid,omitempty" toml:"gid,omitempty"`
+	// Allow the members of the group to run any command with sudo, without
+	// a password if SudoNopasswd is set
+	Sudo         *bool `json:"sudo,omitempty" toml:"sudo,omitempty"`
+	SudoNopasswd *bool `json:"sudo_nopasswd,omitempty" toml:"sudo_nopasswd,omitempty"`
 }
 
 type TimezoneCustomization struct {
 	Timezone   *string  `json:"timezone,omitempty" toml:"timezone,omitempty"`
 	NTPServers []string `json:"ntpservers,omitempty" toml:"ntpservers,omitempty"`
+	// Timezone from which chrony reads the leap seconds, e.g. right/UTC. An
+	// empty string removes the leapsectz directive.
+	LeapsecTz *string `json:"leapsectz,omitempty" toml:"leapsectz,omitempty"`
+	// Comment out the pool directives of the distribution, e.g. to only
+	// use the reference clock of the hypervisor
+	CommentPools bool `json:"comment_pools,omitempty" toml:"comment_pools,omitempty"`
 }
 
 type LocaleCustomization struct {
@@ -59,6 +241,10 @@ type LocaleCustomization struct {
 type FirewallCustomization struct {
 	Ports    []string                       `json:"ports,omitempty" toml:"ports,omitempty"`
 	Services *FirewallServicesCustomization `json:"services,omitempty" toml:"services,omitempty"`
+	// Zone of the connections that aren't bound to another zone, public if
+	// unset
+	DefaultZone string                      `json:"default_zone,omitempty" toml:"default_zone,omitempty"`
+	Zones       []FirewallZoneCustomization `json:"zones,omitempty" toml:"zones,omitempty"`
 }
 
 type FirewallServicesCustomization struct {
@@ -69,11 +255,82 @@ type FirewallServicesCustomization struct {
 type ServicesCustomization struct {
 	Enabled  []string `json:"enabled,omitempty" toml:"enabled,omitempty"`
 	Disabled []string `json:"disabled,omitempty" toml:"disabled,omitempty"`
+	// Units which can't be started at all, not even as dependencies
+	Masked []string `json:"masked,omitempty" toml:"masked,omitempty"`
+}
+
+// ServiceUnitName returns the name of the systemd unit of a service, which
+// is the name with the .service suffix if it has no unit type suffix.
+func ServiceUnitName(name string) string {
+	if path.Ext(name) == "" {
+		return name + ".service"
+	}
+	return name
+}
+
+// Validate returns an error naming the unit if a unit is both enabled and
+// masked.
+func (s *ServicesCustomization) Validate() error {
+	if s == nil {
+		return nil
+	}
+	masked := make(map[string]bool)
+	for _, unit := range s.Masked {
+		masked[ServiceUnitName(unit)] = true
+	}
+	for _, unit := range s.Enabled {
+		if masked[ServiceUnitName(unit)] {
+			return fmt.Errorf("service %q is both enabled and masked", unit)
+		}
+	}
+	return nil
 }
 
 type FilesystemCustomization struct {
 	Mountpoint string `json:"mountpoint,omitempty" toml:"mountpoint,omitempty"`
 	MinSize    uint64 `json:"minsize,omitempty" toml:"size,omitempty"`
+	// Mount options of the fstab entry of the filesystem, in order
+	Options []string `json:"options,omitempty" toml:"options,omitempty"`
+	// Type of the filesystem, xfs if unset
+	FSType string `json:"fs_type,omitempty" toml:"fs_type,omitempty"`
+	// Subvolumes of a btrfs filesystem, one of which is mounted at the
+	// mountpoint of the filesystem
+	Subvolumes []BtrfsSubvolumeCustomization `json:"subvolumes,omitempty" toml:"subvolumes,omitempty"`
+}
+
+type BtrfsSubvolumeCustomization struct {
+	Name       string `json:"name" toml:"name"`
+	Mountpoint string `json:"mountpoint" toml:"mountpoint"`
+}
+
+// PasswordHashCustomization overrides the distribution's default method used
+// to hash plain text user passwords.
+type PasswordHashCustomization struct {
+	Method string `json:"method,omitempty" toml:"method,omitempty"`
+	Rounds int    `json:"rounds,omitempty" toml:"rounds,omitempty"`
+}
+
+type InstallerCustomization struct {
+	Post    []PostScriptCustomization     `json:"post,omitempty" toml:"post,omitempty"`
+	Modules *AnacondaModulesCustomization `json:"modules,omitempty" toml:"modules,omitempty"`
+	// Volume ID of the ISO, replacing the one of the distribution
+	ISOLabel string `json:"iso_label,omitempty" toml:"iso_label,omitempty"`
+	// Verify the checksum of the ISO before the installer starts
+	MediaCheck bool `json:"mediacheck,omitempty" toml:"mediacheck,omitempty"`
+	// Size and compression of the root filesystem of the installer
+	RootFS *InstallerRootFSCustomization `json:"rootfs,omitempty" toml:"rootfs,omitempty"`
+}
+
+// MaxPostScriptSize is the maximum size in bytes of a single %post script body
+const MaxPostScriptSize = 64 * 1024
+
+// PostScriptCustomization describes a kickstart %post section that is run by
+// the installer after the payload has been deployed.
+type PostScriptCustomization struct {
+	Interpreter string `json:"interpreter,omitempty" toml:"interpreter,omitempty"`
+	ErrorOnFail bool   `json:"erroronfail,omitempty" toml:"erroronfail,omitempty"`
+	NoChroot    bool   `json:"nochroot,omitempty" toml:"nochroot,omitempty"`
+	Script      string `json:"script" toml:"script"`
 }
 
 type CustomizationError struct {
@@ -161,6 +418,15 @@ func (c *Customizations) GetTimezoneSettings() (*string, []string) {
 	return c.Timezone.Timezone, c.Timezone.NTPServers
 }
 
+// GetChronySettings returns the leap seconds timezone of chrony and whether
+// the pool directives of the distribution are commented out
+func (c *Customizations) GetChronySettings() (*string, bool) {
+	if c == nil || c.Timezone == nil {
+		return nil, false
+	}
+	return c.Timezone.LeapsecTz, c.Timezone.CommentPools
+}
+
 func (c *Customizations) GetUsers() []UserCustomization {
 	if c == nil {
 		return nil
@@ -216,9 +482,13 @@ func (c *Customizations) GetGroups() []GroupCustomization {
 func (c *Customizations) GetKernel() *KernelCustomization {
 	var name string
 	var append string
+	var remove []string
+	var modules *KernelModulesCustomization
 	if c != nil && c.Kernel != nil {
 		name = c.Kernel.Name
 		append = c.Kernel.Append
+		remove = c.Kernel.Remove
+		modules = c.Kernel.Modules
 	}
 
 	if name == "" {
@@ -226,9 +496,53 @@ func (c *Customizations) GetKernel() *KernelCustomization {
 	}
 
 	return &KernelCustomization{
-		Name:   name,
-		Append: append,
+		Name:    name,
+		Append:  append,
+		Remove:  remove,
+		Modules: modules,
+	}
+}
+
+func (c *Customizations) GetTuned() *TunedCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Tuned
+}
+
+func (c *Customizations) GetKdump() *KdumpCustomization {
+	if c == nil {
+		return nil
 	}
+	return c.Kdump
+}
+
+func (c *Customizations) GetAuthselect() *AuthselectCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Authselect
+}
+
+func (c *Customizations) GetBanner() *BannerCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Banner
+}
+
+func (c *Customizations) GetProxy() *ProxyCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Proxy
+}
+
+func (c *Customizations) GetDNF() *DNFCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.DNF
 }
 
 func (c *Customizations) GetFirewall() *FirewallCustomization {
@@ -277,3 +591,171 @@ func (c *Customizations) GetInstallationDevice() string {
 	}
 	return c.InstallationDevice
 }
+
+func (c *Customizations) GetInstallerPostScripts() []PostScriptCustomization {
+	if c == nil || c.Installer == nil {
+		return nil
+	}
+	return c.Installer.Post
+}
+
+func (c *Customizations) GetInstallerModules() *AnacondaModulesCustomization {
+	if c == nil || c.Installer == nil {
+		return nil
+	}
+	return c.Installer.Modules
+}
+
+func (c *Customizations) GetInstallerISOLabel() string {
+	if c == nil || c.Installer == nil {
+		return ""
+	}
+	return c.Installer.ISOLabel
+}
+
+func (c *Customizations) GetInstallerMediaCheck() bool {
+	if c == nil || c.Installer == nil {
+		return false
+	}
+	return c.Installer.MediaCheck
+}
+
+func (c *Customizations) GetInstallerRootFS() *InstallerRootFSCustomization {
+	if c == nil || c.Installer == nil {
+		return nil
+	}
+	return c.Installer.RootFS
+}
+
+func (c *Customizations) GetPasswordHash() *PasswordHashCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.PasswordHash
+}
+
+func (c *Customizations) GetDeviceID() string {
+	if c == nil {
+		return ""
+	}
+	return c.DeviceID
+}
+
+func (c *Customizations) GetPartitioningMode() string {
+	if c == nil {
+		return ""
+	}
+	return c.PartitioningMode
+}
+
+func (c *Customizations) GetDiskEncryption() *DiskEncryptionCustomization {
+	if c == nil || c.Disk == nil {
+		return nil
+	}
+	return c.Disk.Encryption
+}
+
+func (c *Customizations) GetSwap() *SwapCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Swap
+}
+
+func (c *Customizations) GetSSHD() *SSHDCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.SSHD
+}
+
+func (c *Customizations) GetFiles() []FileCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Files
+}
+
+func (c *Customizations) GetDirectories() []DirectoryCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Directories
+}
+
+func (c *Customizations) GetOpenSCAP() *OpenSCAPCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.OpenSCAP
+}
+
+func (c *Customizations) GetFIPS() bool {
+	if c == nil || c.FIPS == nil {
+		return false
+	}
+	return *c.FIPS
+}
+
+func (c *Customizations) GetIgnition() *IgnitionCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Ignition
+}
+
+func (c *Customizations) GetSecureExecution() *SecureExecutionCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.SecureExecution
+}
+
+func (c *Customizations) GetFDO() *FDOCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.FDO
+}
+
+func (c *Customizations) GetDracut() *DracutCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Dracut
+}
+
+func (c *Customizations) GetSELinux() *SELinuxCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.SELinux
+}
+
+func (c *Customizations) GetGrub() *GrubCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Grub
+}
+
+func (c *Customizations) GetRPM() *RPMCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.RPM
+}
+
+func (c *Customizations) GetOSTree() *OSTreeCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.OSTree
+}
+
+func (c *Customizations) GetEdgeContainer() *EdgeContainerCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.EdgeContainer
+}
@@ -2,6 +2,7 @@ package blueprint
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -66,6 +67,7 @@ func TestGetKernel(t *testing.T) {
 	expectedKernel := KernelCustomization{
 		Append: "--test",
 		Name:   "kernel",
+		Remove: []string{"console"},
 	}
 
 	TestCustomizations := Customizations{
@@ -175,6 +177,26 @@ func TestGetTimezoneSettings(t *testing.T) {
 
 }
 
+func TestGetChronySettings(t *testing.T) {
+	var nilCustomizations *Customizations
+	leapsecTz, commentPools := nilCustomizations.GetChronySettings()
+	assert.Nil(t, leapsecTz)
+	assert.False(t, commentPools)
+
+	expectedLeapsecTz := "right/UTC"
+	TestCustomizations := Customizations{
+		Timezone: &TimezoneCustomization{
+			NTPServers:   []string{"server"},
+			LeapsecTz:    &expectedLeapsecTz,
+			CommentPools: true,
+		},
+	}
+
+	leapsecTz, commentPools = TestCustomizations.GetChronySettings()
+	assert.Equal(t, expectedLeapsecTz, *leapsecTz)
+	assert.True(t, commentPools)
+}
+
 func TestGetPrimaryLocale(t *testing.T) {
 
 	expectedLanguages := []string{
@@ -371,3 +393,87 @@ func TestGetFilesystemsMinSizeNonSectorSize(t *testing.T) {
 
 	assert.EqualValues(t, uint64(5632), retFilesystemsSize)
 }
+
+func TestGetInstallerPostScripts(t *testing.T) {
+	var nilCustomizations *Customizations
+	assert.Nil(t, nilCustomizations.GetInstallerPostScripts())
+	assert.Nil(t, (&Customizations{}).GetInstallerPostScripts())
+
+	expectedScripts := []PostScriptCustomization{
+		{
+			Script: "touch /etc/first",
+		},
+		{
+			Interpreter: "/usr/bin/python3",
+			NoChroot:    true,
+			Script:      "print('second')",
+		},
+	}
+
+	TestCustomizations := Customizations{
+		Installer: &InstallerCustomization{
+			Post: expectedScripts,
+		},
+	}
+
+	assert.Equal(t, expectedScripts, TestCustomizations.GetInstallerPostScripts())
+}
+
+func TestGetPasswordHash(t *testing.T) {
+	var nilCustomizations *Customizations
+	assert.Nil(t, nilCustomizations.GetPasswordHash())
+
+	expectedPasswordHash := PasswordHashCustomization{
+		Method: "sha512",
+		Rounds: 100000,
+	}
+
+	TestCustomizations := Customizations{
+		PasswordHash: &expectedPasswordHash,
+	}
+
+	assert.Equal(t, &expectedPasswordHash, TestCustomizations.GetPasswordHash())
+}
+
+func TestCheckUserExpireDates(t *testing.T) {
+	now := time.Date(2022, 3, 15, 23, 30, 0, 0, time.UTC)
+	customizations := func(expireDate string) *Customizations {
+		return &Customizations{User: []UserCustomization{{Name: "alice", ExpireDate: &expireDate}}}
+	}
+
+	assert.NoError(t, (*Customizations)(nil).checkUserExpireDates(now))
+	assert.NoError(t, customizations("2022-03-15").checkUserExpireDates(now))
+	assert.NoError(t, customizations("2030-01-01").checkUserExpireDates(now))
+	assert.EqualError(t, customizations("2022-03-14").checkUserExpireDates(now),
+		`expiration date 2022-03-14 of user "alice" is in the past`)
+	assert.EqualError(t, customizations("15/03/2022").checkUserExpireDates(now),
+		`invalid expiration date "15/03/2022" of user "alice", must be YYYY-MM-DD`)
+	assert.EqualError(t, customizations("2022-02-30").checkUserExpireDates(now),
+		`invalid expiration date "2022-02-30" of user "alice", must be YYYY-MM-DD`)
+}
+
+func TestServicesCustomization_Validate(t *testing.T) {
+	var unset *ServicesCustomization
+	assert.NoError(t, unset.Validate())
+
+	services := &ServicesCustomization{
+		Enabled:  []string{"sshd", "chronyd.service"},
+		Disabled: []string{"kdump"},
+		Masked:   []string{"kdump", "nfs-server.service", "rpcbind.socket"},
+	}
+	assert.NoError(t, services.Validate())
+
+	services.Enabled = append(services.Enabled, "nfs-server")
+	assert.EqualError(t, services.Validate(), `service "nfs-server" is both enabled and masked`)
+	services.Enabled = []string{"rpcbind.socket"}
+	assert.EqualError(t, services.Validate(), `service "rpcbind.socket" is both enabled and masked`)
+	// a service and a socket of the same name are different units
+	services.Masked = []string{"rpcbind"}
+	assert.NoError(t, services.Validate())
+}
+
+func TestServiceUnitName(t *testing.T) {
+	assert.Equal(t, "sshd.service", ServiceUnitName("sshd"))
+	assert.Equal(t, "sshd.service", ServiceUnitName("sshd.service"))
+	assert.Equal(t, "rpcbind.socket", ServiceUnitName("rpcbind.socket"))
+}
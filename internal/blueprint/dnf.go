@@ -0,0 +1,53 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DNFCustomization sets the DNF configuration of the image
+type DNFCustomization struct {
+	// Install the weak dependencies of packages, DNF does if unset
+	InstallWeakDeps *bool `json:"install_weak_deps,omitempty" toml:"install_weak_deps,omitempty"`
+	// Packages, or globs of packages, which DNF never installs or upgrades
+	ExcludePkgs []string `json:"excludepkgs,omitempty" toml:"excludepkgs,omitempty"`
+	// Also exclude the packages when the packages of the image are
+	// depsolved
+	ExcludeFromBuild bool `json:"exclude_from_build,omitempty" toml:"exclude_from_build,omitempty"`
+	// URL of the proxy server of DNF, the one of the proxy customization if
+	// unset
+	Proxy string `json:"proxy,omitempty" toml:"proxy,omitempty"`
+}
+
+// Validate returns an error if an excluded package is empty or has
+// separators, if the excludes are applied to the build without any, or if
+// the proxy isn't a valid proxy URL.
+func (c *DNFCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, pkg := range c.ExcludePkgs {
+		if pkg == "" || strings.ContainsAny(pkg, ", \t\r\n") {
+			return fmt.Errorf("invalid DNF excluded package %q", pkg)
+		}
+	}
+	if c.ExcludeFromBuild && len(c.ExcludePkgs) == 0 {
+		return errors.New("DNF exclude_from_build requires excluded packages")
+	}
+	if c.Proxy != "" {
+		if err := (&ProxyCustomization{URL: c.Proxy}).Validate(); err != nil {
+			return fmt.Errorf("DNF %v", err)
+		}
+	}
+	return nil
+}
+
+// BuildExcludes returns the excluded packages that also apply to the
+// depsolving of the packages of the image.
+func (c *DNFCustomization) BuildExcludes() []string {
+	if c == nil || !c.ExcludeFromBuild {
+		return nil
+	}
+	return c.ExcludePkgs
+}
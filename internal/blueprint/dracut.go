@@ -0,0 +1,42 @@
+package blueprint
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DracutCustomization extends the initramfs of installer images, e.g. with
+// drivers for hardware that the installer must support
+type DracutCustomization struct {
+	// Dracut modules to include in addition to the ones of the image type
+	AddModules []string `json:"add_modules,omitempty" toml:"add_modules,omitempty"`
+	// Kernel modules to include
+	AddDrivers []string `json:"add_drivers,omitempty" toml:"add_drivers,omitempty"`
+	// Absolute paths of files of the installer tree to include
+	Install []string `json:"install,omitempty" toml:"install,omitempty"`
+}
+
+// Validate returns an error if a module or driver name is empty or contains
+// whitespace or slashes, or if a path to install isn't absolute and clean.
+func (c *DracutCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, module := range c.AddModules {
+		if module == "" || strings.ContainsAny(module, " \t\n/") {
+			return fmt.Errorf("invalid dracut module name %q", module)
+		}
+	}
+	for _, driver := range c.AddDrivers {
+		if driver == "" || strings.ContainsAny(driver, " \t\n/") {
+			return fmt.Errorf("invalid dracut driver name %q", driver)
+		}
+	}
+	for _, p := range c.Install {
+		if !path.IsAbs(p) || path.Clean(p) != p {
+			return fmt.Errorf("dracut install path %q must be absolute and canonical", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDracutCustomization_Validate(t *testing.T) {
+	var unset *DracutCustomization
+	assert.NoError(t, unset.Validate())
+
+	dracut := &DracutCustomization{
+		AddModules: []string{"nvdimm", "iscsi"},
+		AddDrivers: []string{"hv_vmbus", "hv_storvsc"},
+		Install:    []string{"/usr/lib/firmware/acme/fw.bin"},
+	}
+	assert.NoError(t, dracut.Validate())
+
+	assert.EqualError(t, (&DracutCustomization{AddModules: []string{""}}).Validate(), `invalid dracut module name ""`)
+	assert.EqualError(t, (&DracutCustomization{AddModules: []string{"nvdimm iscsi"}}).Validate(), `invalid dracut module name "nvdimm iscsi"`)
+	assert.EqualError(t, (&DracutCustomization{AddDrivers: []string{"kernel/drivers/hv/hv_vmbus.ko"}}).Validate(), `invalid dracut driver name "kernel/drivers/hv/hv_vmbus.ko"`)
+	assert.EqualError(t, (&DracutCustomization{Install: []string{"usr/lib/firmware/fw.bin"}}).Validate(), `dracut install path "usr/lib/firmware/fw.bin" must be absolute and canonical`)
+	assert.EqualError(t, (&DracutCustomization{Install: []string{"/usr/lib/../lib/fw.bin"}}).Validate(), `dracut install path "/usr/lib/../lib/fw.bin" must be absolute and canonical`)
+}
@@ -0,0 +1,31 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// EdgeContainerCustomization configures the nginx server of edge-container
+// images, which serves the ostree repository at /repo below Root
+type EdgeContainerCustomization struct {
+	// Port nginx listens on, 8080 by default
+	Port int `json:"port,omitempty" toml:"port,omitempty"`
+	// Directory requests are served from, /usr/share/nginx/html by
+	// default
+	Root string `json:"root,omitempty" toml:"root,omitempty"`
+}
+
+// Validate returns an error if the port is out of range or the root isn't a
+// clean absolute path below /.
+func (c *EdgeContainerCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Port != 0 && (c.Port < 1 || c.Port > 65535) {
+		return fmt.Errorf("edge container port %d is not between 1 and 65535", c.Port)
+	}
+	if c.Root != "" && (!filepath.IsAbs(c.Root) || filepath.Clean(c.Root) != c.Root || c.Root == "/") {
+		return fmt.Errorf("edge container root %q must be a clean absolute path other than /", c.Root)
+	}
+	return nil
+}
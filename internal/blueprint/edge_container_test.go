@@ -0,0 +1,28 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdgeContainerCustomization_Validate(t *testing.T) {
+	var unset *EdgeContainerCustomization
+	assert.NoError(t, unset.Validate())
+	assert.NoError(t, (&EdgeContainerCustomization{}).Validate())
+
+	for _, c := range []EdgeContainerCustomization{
+		{Port: 80},
+		{Port: 65535},
+		{Root: "/srv/ostree"},
+	} {
+		c := c
+		assert.NoError(t, c.Validate(), c)
+	}
+
+	assert.EqualError(t, (&EdgeContainerCustomization{Port: -1}).Validate(), "edge container port -1 is not between 1 and 65535")
+	assert.EqualError(t, (&EdgeContainerCustomization{Port: 65536}).Validate(), "edge container port 65536 is not between 1 and 65535")
+	assert.EqualError(t, (&EdgeContainerCustomization{Root: "srv"}).Validate(), `edge container root "srv" must be a clean absolute path other than /`)
+	assert.EqualError(t, (&EdgeContainerCustomization{Root: "/srv/../etc"}).Validate(), `edge container root "/srv/../etc" must be a clean absolute path other than /`)
+	assert.EqualError(t, (&EdgeContainerCustomization{Root: "/"}).Validate(), `edge container root "/" must be a clean absolute path other than /`)
+}
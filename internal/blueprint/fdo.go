@@ -0,0 +1,50 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// FDOCustomization onboards devices installed with the edge simplified
+// installer with FIDO Device Onboard. The device is initialized against the
+// manufacturing server, whose DIUN public key is verified with exactly one
+// of the DiunPubKey options.
+type FDOCustomization struct {
+	ManufacturingServerURL string `json:"manufacturing_server_url" toml:"manufacturing_server_url"`
+	// Don't verify the DIUN public key of the manufacturing server
+	DiunPubKeyInsecure bool `json:"diun_pub_key_insecure,omitempty" toml:"diun_pub_key_insecure,omitempty"`
+	// Hash of the DIUN public key of the manufacturing server
+	DiunPubKeyHash string `json:"diun_pub_key_hash,omitempty" toml:"diun_pub_key_hash,omitempty"`
+	// PEM encoded root certificates of the DIUN public key of the
+	// manufacturing server
+	DiunPubKeyRootCerts string `json:"diun_pub_key_root_certs,omitempty" toml:"diun_pub_key_root_certs,omitempty"`
+}
+
+// Validate returns an error if the manufacturing server URL isn't an
+// absolute http or https URL, or if not exactly one of the DIUN public key
+// options is set.
+func (c *FDOCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	u, err := url.Parse(c.ManufacturingServerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("FDO manufacturing server URL %q must be an http or https URL", c.ManufacturingServerURL)
+	}
+
+	keyOptions := 0
+	if c.DiunPubKeyInsecure {
+		keyOptions++
+	}
+	if c.DiunPubKeyHash != "" {
+		keyOptions++
+	}
+	if c.DiunPubKeyRootCerts != "" {
+		keyOptions++
+	}
+	if keyOptions != 1 {
+		return errors.New("FDO requires exactly one of diun_pub_key_insecure, diun_pub_key_hash, and diun_pub_key_root_certs")
+	}
+	return nil
+}
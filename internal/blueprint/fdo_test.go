@@ -0,0 +1,36 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFDOCustomization_Validate(t *testing.T) {
+	var unset *FDOCustomization
+	assert.NoError(t, unset.Validate())
+
+	valid := []FDOCustomization{
+		{ManufacturingServerURL: "http://10.0.0.2:8080", DiunPubKeyInsecure: true},
+		{ManufacturingServerURL: "https://fdo.example.com", DiunPubKeyHash: "sha256:1bc2a9e6"},
+		{ManufacturingServerURL: "https://fdo.example.com", DiunPubKeyRootCerts: "-----BEGIN CERTIFICATE-----\n"},
+	}
+	for _, fdo := range valid {
+		assert.NoError(t, fdo.Validate())
+	}
+
+	keyErr := "FDO requires exactly one of diun_pub_key_insecure, diun_pub_key_hash, and diun_pub_key_root_certs"
+	cases := []struct {
+		fdo FDOCustomization
+		err string
+	}{
+		{FDOCustomization{DiunPubKeyInsecure: true}, `FDO manufacturing server URL "" must be an http or https URL`},
+		{FDOCustomization{ManufacturingServerURL: "fdo.example.com:8080", DiunPubKeyInsecure: true}, `FDO manufacturing server URL "fdo.example.com:8080" must be an http or https URL`},
+		{FDOCustomization{ManufacturingServerURL: "https://fdo.example.com"}, keyErr},
+		{FDOCustomization{ManufacturingServerURL: "https://fdo.example.com", DiunPubKeyInsecure: true, DiunPubKeyHash: "sha256:1bc2a9e6"}, keyErr},
+		{FDOCustomization{ManufacturingServerURL: "https://fdo.example.com", DiunPubKeyHash: "sha256:1bc2a9e6", DiunPubKeyRootCerts: "-----BEGIN CERTIFICATE-----\n"}, keyErr},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, c.fdo.Validate(), c.err)
+	}
+}
@@ -0,0 +1,162 @@
+package blueprint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MaxFileSize is the maximum size in bytes of the data of a file
+// customization. The data is embedded in the manifest, so it is meant for
+// configuration files rather than payloads.
+const MaxFileSize = 512 * 1024
+
+// FileCustomization creates a file with the given content in the image.
+// Files replace existing files of the same path.
+type FileCustomization struct {
+	// Absolute path of the file
+	Path string `json:"path" toml:"path"`
+	// Octal permissions of the file, 0644 if unset
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+	// Name or ID of the owner of the file, root if unset
+	User string `json:"user,omitempty" toml:"user,omitempty"`
+	// Name or ID of the group of the file, root if unset
+	Group string `json:"group,omitempty" toml:"group,omitempty"`
+	// Content of the file
+	Data string `json:"data,omitempty" toml:"data,omitempty"`
+}
+
+// DirectoryCustomization creates a directory in the image. The ownership and
+// the mode of existing directories are changed.
+type DirectoryCustomization struct {
+	// Absolute path of the directory
+	Path string `json:"path" toml:"path"`
+	// Octal permissions of the directory, 0755 if unset
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+	// Name or ID of the owner of the directory, root if unset
+	User string `json:"user,omitempty" toml:"user,omitempty"`
+	// Name or ID of the group of the directory, root if unset
+	Group string `json:"group,omitempty" toml:"group,omitempty"`
+	// Create missing parent directories, otherwise the parent directory
+	// must exist in the image
+	EnsureParents bool `json:"ensure_parents,omitempty" toml:"ensure_parents,omitempty"`
+}
+
+// customizationPathPrefixes are the directories under which files can be
+// created. Everything else belongs to the packages of the image.
+var customizationPathPrefixes = []string{"/etc", "/home", "/opt", "/root", "/srv", "/var"}
+
+// deniedCustomizationPaths are paths under the allowed directories, which
+// must not be changed with file customizations, and the reason why.
+var deniedCustomizationPaths = []struct {
+	path   string
+	reason string
+}{
+	{"/etc/passwd", "user accounts are managed with user customizations"},
+	{"/etc/shadow", "user accounts are managed with user customizations"},
+	{"/etc/group", "groups are managed with group customizations"},
+	{"/etc/gshadow", "groups are managed with group customizations"},
+	{"/etc/fstab", "it is generated from the partition table of the image"},
+	{"/var/run", "it is a symlink to /run, which is not part of the image"},
+}
+
+// modeRegexp matches octal permissions without file type bits
+var modeRegexp = regexp.MustCompile(`^0?[0-7]{3,4}$`)
+
+// checkCustomizationPath returns an error naming p and the reason if files
+// or directories can't be created at p.
+func checkCustomizationPath(p string) error {
+	if !path.IsAbs(p) {
+		return fmt.Errorf("path %q must be absolute", p)
+	}
+	if path.Clean(p) != p {
+		return fmt.Errorf("path %q must be canonical, e.g. %q", p, path.Clean(p))
+	}
+
+	for _, denied := range deniedCustomizationPaths {
+		// backup copies like /etc/shadow- contain the same secrets
+		if p == denied.path || p == denied.path+"-" || strings.HasPrefix(p, denied.path+"/") {
+			return fmt.Errorf("path %q is not allowed: %s", p, denied.reason)
+		}
+	}
+
+	for _, prefix := range customizationPathPrefixes {
+		if p == prefix {
+			return fmt.Errorf("path %q is not allowed: it is a top-level directory of the image", p)
+		}
+		if strings.HasPrefix(p, prefix+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("path %q is not allowed: only paths under %s can be customized", p, strings.Join(customizationPathPrefixes, ", "))
+}
+
+// Validate returns an error if the file can't be created in the image.
+func (f *FileCustomization) Validate() error {
+	if err := checkCustomizationPath(f.Path); err != nil {
+		return err
+	}
+	if f.Mode != "" && !modeRegexp.MatchString(f.Mode) {
+		return fmt.Errorf("invalid mode %q of file %q, must be octal, e.g. 0644", f.Mode, f.Path)
+	}
+	if len(f.Data) > MaxFileSize {
+		return fmt.Errorf("data of file %q is %d bytes, which exceeds the maximum of %d bytes", f.Path, len(f.Data), MaxFileSize)
+	}
+	return nil
+}
+
+// ValidateFileCustomizations returns an error if one of the files is invalid
+// or if two files have the same path.
+func ValidateFileCustomizations(files []FileCustomization) error {
+	paths := make(map[string]bool)
+	for idx := range files {
+		if err := files[idx].Validate(); err != nil {
+			return err
+		}
+		if paths[files[idx].Path] {
+			return fmt.Errorf("file %q is defined more than once", files[idx].Path)
+		}
+		paths[files[idx].Path] = true
+	}
+	return nil
+}
+
+// Validate returns an error if the directory can't be created in the image.
+func (d *DirectoryCustomization) Validate() error {
+	if err := checkCustomizationPath(d.Path); err != nil {
+		return err
+	}
+	if d.Mode != "" && !modeRegexp.MatchString(d.Mode) {
+		return fmt.Errorf("invalid mode %q of directory %q, must be octal, e.g. 0755", d.Mode, d.Path)
+	}
+	return nil
+}
+
+// ValidateDirectoryCustomizations returns an error if one of the directories
+// is invalid, if two directories have the same path, or if a directory has
+// the path of one of the files or is inside of it.
+func ValidateDirectoryCustomizations(dirs []DirectoryCustomization, files []FileCustomization) error {
+	paths := make(map[string]bool)
+	for idx := range dirs {
+		if err := dirs[idx].Validate(); err != nil {
+			return err
+		}
+		if paths[dirs[idx].Path] {
+			return fmt.Errorf("directory %q is defined more than once", dirs[idx].Path)
+		}
+		paths[dirs[idx].Path] = true
+	}
+
+	for _, file := range files {
+		for _, dir := range dirs {
+			if dir.Path == file.Path {
+				return fmt.Errorf("path %q is defined both as a file and as a directory", file.Path)
+			}
+			if strings.HasPrefix(dir.Path, file.Path+"/") {
+				return fmt.Errorf("directory %q is inside of file %q", dir.Path, file.Path)
+			}
+		}
+	}
+	return nil
+}
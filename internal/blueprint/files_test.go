@@ -0,0 +1,95 @@
+package blueprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCustomization_Validate(t *testing.T) {
+	valid := []FileCustomization{
+		{Path: "/etc/myapp/config.yaml", Mode: "0640", User: "myapp", Group: "myapp", Data: "debug: true\n"},
+		{Path: "/etc/motd.d/banner", Mode: "644"},
+		{Path: "/var/lib/myapp/state", User: "1001", Group: "1001"},
+		{Path: "/root/.bashrc.d/aliases"},
+		{Path: "/etc/shadowsocks/config.json"},
+		{Path: "/opt/myapp/env", Data: strings.Repeat("x", MaxFileSize)},
+	}
+	for _, f := range valid {
+		assert.NoError(t, f.Validate(), f.Path)
+	}
+
+	cases := []struct {
+		file FileCustomization
+		err  string
+	}{
+		{FileCustomization{Path: "etc/myapp.conf"}, `path "etc/myapp.conf" must be absolute`},
+		{FileCustomization{Path: "/etc/../usr/bin/ls"}, `path "/etc/../usr/bin/ls" must be canonical, e.g. "/usr/bin/ls"`},
+		{FileCustomization{Path: "/usr/bin/myapp"}, `path "/usr/bin/myapp" is not allowed: only paths under /etc, /home, /opt, /root, /srv, /var can be customized`},
+		{FileCustomization{Path: "/boot/efi/EFI/redhat/grub.cfg"}, `path "/boot/efi/EFI/redhat/grub.cfg" is not allowed: only paths under /etc, /home, /opt, /root, /srv, /var can be customized`},
+		{FileCustomization{Path: "/etc/shadow"}, `path "/etc/shadow" is not allowed: user accounts are managed with user customizations`},
+		{FileCustomization{Path: "/etc/gshadow-"}, `path "/etc/gshadow-" is not allowed: groups are managed with group customizations`},
+		{FileCustomization{Path: "/etc/fstab"}, `path "/etc/fstab" is not allowed: it is generated from the partition table of the image`},
+		{FileCustomization{Path: "/var/run/myapp.pid"}, `path "/var/run/myapp.pid" is not allowed: it is a symlink to /run, which is not part of the image`},
+		{FileCustomization{Path: "/etc"}, `path "/etc" is not allowed: it is a top-level directory of the image`},
+		{FileCustomization{Path: "/etc/myapp.conf", Mode: "rw-r--r--"}, `invalid mode "rw-r--r--" of file "/etc/myapp.conf", must be octal, e.g. 0644`},
+		{FileCustomization{Path: "/etc/myapp.conf", Mode: "0100644"}, `invalid mode "0100644" of file "/etc/myapp.conf", must be octal, e.g. 0644`},
+		{FileCustomization{Path: "/etc/myapp.conf", Data: strings.Repeat("x", MaxFileSize+1)}, `data of file "/etc/myapp.conf" is 524289 bytes, which exceeds the maximum of 524288 bytes`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, c.file.Validate(), c.err)
+	}
+}
+
+func TestValidateFileCustomizations(t *testing.T) {
+	assert.NoError(t, ValidateFileCustomizations(nil))
+	assert.NoError(t, ValidateFileCustomizations([]FileCustomization{
+		{Path: "/etc/myapp/a.conf"},
+		{Path: "/etc/myapp/b.conf"},
+	}))
+	assert.EqualError(t, ValidateFileCustomizations([]FileCustomization{
+		{Path: "/etc/myapp/a.conf"},
+		{Path: "/usr/lib/myapp/b.conf"},
+	}), `path "/usr/lib/myapp/b.conf" is not allowed: only paths under /etc, /home, /opt, /root, /srv, /var can be customized`)
+	assert.EqualError(t, ValidateFileCustomizations([]FileCustomization{
+		{Path: "/etc/myapp/a.conf"},
+		{Path: "/etc/myapp/a.conf", Data: "again"},
+	}), `file "/etc/myapp/a.conf" is defined more than once`)
+}
+
+func TestDirectoryCustomization_Validate(t *testing.T) {
+	valid := []DirectoryCustomization{
+		{Path: "/var/log/myapp", Mode: "0750", User: "myapp", Group: "myapp"},
+		{Path: "/srv/data/cache", EnsureParents: true},
+	}
+	for _, d := range valid {
+		assert.NoError(t, d.Validate(), d.Path)
+	}
+
+	assert.EqualError(t, (&DirectoryCustomization{Path: "/usr/share/myapp"}).Validate(),
+		`path "/usr/share/myapp" is not allowed: only paths under /etc, /home, /opt, /root, /srv, /var can be customized`)
+	assert.EqualError(t, (&DirectoryCustomization{Path: "/var/log/myapp/"}).Validate(),
+		`path "/var/log/myapp/" must be canonical, e.g. "/var/log/myapp"`)
+	assert.EqualError(t, (&DirectoryCustomization{Path: "/var/log/myapp", Mode: "u=rwx"}).Validate(),
+		`invalid mode "u=rwx" of directory "/var/log/myapp", must be octal, e.g. 0755`)
+}
+
+func TestValidateDirectoryCustomizations(t *testing.T) {
+	dirs := []DirectoryCustomization{
+		{Path: "/var/log/myapp"},
+		{Path: "/etc/myapp"},
+	}
+	files := []FileCustomization{
+		{Path: "/etc/myapp/config.yaml"},
+	}
+	assert.NoError(t, ValidateDirectoryCustomizations(nil, files))
+	assert.NoError(t, ValidateDirectoryCustomizations(dirs, files))
+
+	assert.EqualError(t, ValidateDirectoryCustomizations(append(dirs, DirectoryCustomization{Path: "/etc/myapp", Mode: "0700"}), files),
+		`directory "/etc/myapp" is defined more than once`)
+	assert.EqualError(t, ValidateDirectoryCustomizations(append(dirs, DirectoryCustomization{Path: "/etc/myapp/config.yaml"}), files),
+		`path "/etc/myapp/config.yaml" is defined both as a file and as a directory`)
+	assert.EqualError(t, ValidateDirectoryCustomizations(append(dirs, DirectoryCustomization{Path: "/etc/myapp/config.yaml/d"}), files),
+		`directory "/etc/myapp/config.yaml/d" is inside of file "/etc/myapp/config.yaml"`)
+}
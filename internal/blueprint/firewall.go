@@ -0,0 +1,259 @@
+package blueprint
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Protocols that ports can be opened for
+var firewallProtocols = map[string]bool{
+	"tcp":  true,
+	"udp":  true,
+	"sctp": true,
+}
+
+// Names of services in /etc/services, which firewalld resolves to port numbers
+var firewallPortNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// Ports opened by the firewalld services that are commonly enabled in
+// blueprints
+var firewallServicePorts = map[string][]FirewallPort{
+	"ssh":           {tcpPort(22)},
+	"http":          {tcpPort(80)},
+	"https":         {tcpPort(443)},
+	"dns":           {tcpPort(53), udpPort(53)},
+	"ntp":           {udpPort(123)},
+	"dhcpv6-client": {udpPort(546)},
+	"mdns":          {udpPort(5353)},
+	"cockpit":       {tcpPort(9090)},
+	"postgresql":    {tcpPort(5432)},
+	"mysql":         {tcpPort(3306)},
+	"redis":         {tcpPort(6379)},
+}
+
+// FirewallPort is a port, a range of ports, or a named port of a protocol
+// that is opened in the firewall.
+type FirewallPort struct {
+	Start uint16
+	End   uint16
+	// Name of the port in /etc/services, Start and End are 0 if set
+	Name     string
+	Protocol string
+}
+
+func tcpPort(port uint16) FirewallPort {
+	return FirewallPort{Start: port, End: port, Protocol: "tcp"}
+}
+
+func udpPort(port uint16) FirewallPort {
+	return FirewallPort{Start: port, End: port, Protocol: "udp"}
+}
+
+// ParseFirewallPort parses a port specification of a blueprint, in the
+// "port:proto", "start-end:proto", or "name:proto" form. "/" is accepted as
+// separator between the port and the protocol, too.
+func ParseFirewallPort(spec string) (FirewallPort, error) {
+	idx := strings.LastIndexAny(spec, ":/")
+	if idx == -1 {
+		return FirewallPort{}, fmt.Errorf("firewall port %q has no protocol, must be of the form port:protocol", spec)
+	}
+	ports, protocol := spec[:idx], spec[idx+1:]
+	if !firewallProtocols[protocol] {
+		return FirewallPort{}, fmt.Errorf("firewall port %q has an unsupported protocol, must be one of tcp, udp, or sctp", spec)
+	}
+
+	if firewallPortNameRegex.MatchString(ports) {
+		return FirewallPort{Name: ports, Protocol: protocol}, nil
+	}
+
+	start, end := ports, ports
+	if i := strings.Index(ports, "-"); i != -1 {
+		start, end = ports[:i], ports[i+1:]
+	}
+	startPort, err := parsePortNumber(start)
+	if err != nil {
+		return FirewallPort{}, fmt.Errorf("firewall port %q: %v", spec, err)
+	}
+	endPort, err := parsePortNumber(end)
+	if err != nil {
+		return FirewallPort{}, fmt.Errorf("firewall port %q: %v", spec, err)
+	}
+	if startPort > endPort {
+		return FirewallPort{}, fmt.Errorf("firewall port %q: the range ends before it starts", spec)
+	}
+
+	return FirewallPort{Start: startPort, End: endPort, Protocol: protocol}, nil
+}
+
+func parsePortNumber(s string) (uint16, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid port number %q, must be between 1 and 65535", s)
+	}
+	return uint16(port), nil
+}
+
+// String returns the port in the form the osbuild firewall stage expects
+func (p FirewallPort) String() string {
+	switch {
+	case p.Name != "":
+		return p.Name + ":" + p.Protocol
+	case p.Start == p.End:
+		return fmt.Sprintf("%d:%s", p.Start, p.Protocol)
+	default:
+		return fmt.Sprintf("%d-%d:%s", p.Start, p.End, p.Protocol)
+	}
+}
+
+// Overlaps returns true if both ports open a common port number of the same
+// protocol. Named ports only overlap with the same named port.
+func (p FirewallPort) Overlaps(other FirewallPort) bool {
+	if p.Protocol != other.Protocol {
+		return false
+	}
+	if p.Name != "" || other.Name != "" {
+		return p.Name == other.Name
+	}
+	return p.Start <= other.End && other.Start <= p.End
+}
+
+// NormalizedPorts returns the ports to open in the form the osbuild firewall
+// stage expects. It returns an error if a port is invalid or if two ports
+// overlap.
+func (f *FirewallCustomization) NormalizedPorts() ([]string, error) {
+	if f == nil || f.Ports == nil {
+		return nil, nil
+	}
+
+	parsed := make([]FirewallPort, 0, len(f.Ports))
+	for _, spec := range f.Ports {
+		port, err := ParseFirewallPort(spec)
+		if err != nil {
+			return nil, err
+		}
+		for otherIdx, other := range parsed {
+			if port.Overlaps(other) {
+				return nil, fmt.Errorf("firewall port %q overlaps with %q", spec, f.Ports[otherIdx])
+			}
+		}
+		parsed = append(parsed, port)
+	}
+
+	ports := make([]string, len(parsed))
+	for idx, port := range parsed {
+		ports[idx] = port.String()
+	}
+	return ports, nil
+}
+
+// PortWarnings returns a message for every valid port that is already opened
+// by one of the enabled services.
+func (f *FirewallCustomization) PortWarnings() []string {
+	if f == nil || f.Services == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, spec := range f.Ports {
+		port, err := ParseFirewallPort(spec)
+		if err != nil {
+			continue
+		}
+		for _, service := range f.Services.Enabled {
+			for _, servicePort := range firewallServicePorts[service] {
+				if port.Overlaps(servicePort) {
+					warnings = append(warnings, fmt.Sprintf("firewall port %q is already opened by the enabled service %q", spec, service))
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// FirewallZoneCustomization binds sources and interfaces to a firewalld zone
+type FirewallZoneCustomization struct {
+	Name string `json:"name" toml:"name"`
+	// IP addresses, networks, MAC addresses, or "ipset:<name>"
+	Sources    []string `json:"sources,omitempty" toml:"sources,omitempty"`
+	Interfaces []string `json:"interfaces,omitempty" toml:"interfaces,omitempty"`
+}
+
+// Names of zones as firewalld accepts them, which are limited to 17
+// characters
+var firewallZoneNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,17}$`)
+
+// Names of network interfaces, which the kernel limits to 15 characters
+var firewallInterfaceRegex = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,15}$`)
+
+func checkFirewallZoneName(name string) error {
+	if !firewallZoneNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid firewall zone name %q, must be 1 to 17 letters, digits, underscores, or dashes", name)
+	}
+	return nil
+}
+
+func checkFirewallSource(source string) error {
+	valid := false
+	if strings.HasPrefix(source, "ipset:") {
+		valid = firewallZoneNameRegex.MatchString(strings.TrimPrefix(source, "ipset:"))
+	} else if net.ParseIP(source) != nil {
+		valid = true
+	} else if _, _, err := net.ParseCIDR(source); err == nil {
+		valid = true
+	} else if _, err := net.ParseMAC(source); err == nil {
+		valid = true
+	}
+	if !valid {
+		return fmt.Errorf("invalid firewall zone source %q, must be an IP address, a network, a MAC address, or an ipset", source)
+	}
+	return nil
+}
+
+// ValidateZones returns an error if the default zone or a zone has an
+// invalid name, if a zone is defined more than once, if a source or an
+// interface is invalid, or if it is bound to more than one zone.
+func (f *FirewallCustomization) ValidateZones() error {
+	if f == nil {
+		return nil
+	}
+	if f.DefaultZone != "" {
+		if err := checkFirewallZoneName(f.DefaultZone); err != nil {
+			return err
+		}
+	}
+
+	zones := make(map[string]bool)
+	bound := make(map[string]string)
+	for _, zone := range f.Zones {
+		if err := checkFirewallZoneName(zone.Name); err != nil {
+			return err
+		}
+		if zones[zone.Name] {
+			return fmt.Errorf("firewall zone %q is defined more than once", zone.Name)
+		}
+		zones[zone.Name] = true
+
+		for _, source := range zone.Sources {
+			if err := checkFirewallSource(source); err != nil {
+				return err
+			}
+			if other, ok := bound["source "+source]; ok {
+				return fmt.Errorf("firewall zone source %q is bound to the zones %q and %q", source, other, zone.Name)
+			}
+			bound["source "+source] = zone.Name
+		}
+		for _, iface := range zone.Interfaces {
+			if !firewallInterfaceRegex.MatchString(iface) {
+				return fmt.Errorf("invalid firewall zone interface %q", iface)
+			}
+			if other, ok := bound["interface "+iface]; ok {
+				return fmt.Errorf("firewall zone interface %q is bound to the zones %q and %q", iface, other, zone.Name)
+			}
+			bound["interface "+iface] = zone.Name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFirewallPort(t *testing.T) {
+	cases := []struct {
+		spec       string
+		normalized string
+		err        string
+	}{
+		{"22:tcp", "22:tcp", ""},
+		{"22/tcp", "22:tcp", ""},
+		{"53:udp", "53:udp", ""},
+		{"3868/sctp", "3868:sctp", ""},
+		{"8000-8080:tcp", "8000-8080:tcp", ""},
+		{"8000-8080/udp", "8000-8080:udp", ""},
+		{"8080-8080:tcp", "8080:tcp", ""},
+		{"1-65535:tcp", "1-65535:tcp", ""},
+		{"imap:tcp", "imap:tcp", ""},
+		{"dhcpv6-client/udp", "dhcpv6-client:udp", ""},
+		{"22", "", `firewall port "22" has no protocol, must be of the form port:protocol`},
+		{"8080-tcp", "", `firewall port "8080-tcp" has no protocol, must be of the form port:protocol`},
+		{"22:icmp", "", `firewall port "22:icmp" has an unsupported protocol, must be one of tcp, udp, or sctp`},
+		{"22:", "", `firewall port "22:" has an unsupported protocol, must be one of tcp, udp, or sctp`},
+		{":tcp", "", `firewall port ":tcp": invalid port number "", must be between 1 and 65535`},
+		{"0:tcp", "", `firewall port "0:tcp": invalid port number "0", must be between 1 and 65535`},
+		{"65536:tcp", "", `firewall port "65536:tcp": invalid port number "65536", must be between 1 and 65535`},
+		{"-1:tcp", "", `firewall port "-1:tcp": invalid port number "", must be between 1 and 65535`},
+		{"80-:tcp", "", `firewall port "80-:tcp": invalid port number "", must be between 1 and 65535`},
+		{"8080-8000:tcp", "", `firewall port "8080-8000:tcp": the range ends before it starts`},
+		{"1-2-3:tcp", "", `firewall port "1-2-3:tcp": invalid port number "2-3", must be between 1 and 65535`},
+	}
+	for _, c := range cases {
+		port, err := ParseFirewallPort(c.spec)
+		if c.err != "" {
+			assert.EqualError(t, err, c.err)
+			continue
+		}
+		require.NoError(t, err, c.spec)
+		assert.Equal(t, c.normalized, port.String())
+	}
+}
+
+func TestFirewallCustomization_NormalizedPorts(t *testing.T) {
+	var firewall *FirewallCustomization
+	ports, err := firewall.NormalizedPorts()
+	assert.NoError(t, err)
+	assert.Nil(t, ports)
+
+	cases := []struct {
+		ports      []string
+		normalized []string
+		err        string
+	}{
+		{[]string{}, []string{}, ""},
+		{[]string{"22/tcp", "22:udp", "8000-8080/tcp", "8081:tcp", "imap:tcp"}, []string{"22:tcp", "22:udp", "8000-8080:tcp", "8081:tcp", "imap:tcp"}, ""},
+		{[]string{"22:tcp", "22/tcp"}, nil, `firewall port "22/tcp" overlaps with "22:tcp"`},
+		{[]string{"8000-8080:tcp", "8080-8090:tcp"}, nil, `firewall port "8080-8090:tcp" overlaps with "8000-8080:tcp"`},
+		{[]string{"8000-8080:tcp", "8042:tcp"}, nil, `firewall port "8042:tcp" overlaps with "8000-8080:tcp"`},
+		{[]string{"imap:tcp", "imap/tcp"}, nil, `firewall port "imap/tcp" overlaps with "imap:tcp"`},
+		{[]string{"22:tcp", "22"}, nil, `firewall port "22" has no protocol, must be of the form port:protocol`},
+	}
+	for _, c := range cases {
+		firewall := &FirewallCustomization{Ports: c.ports}
+		ports, err := firewall.NormalizedPorts()
+		if c.err != "" {
+			assert.EqualError(t, err, c.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, c.normalized, ports)
+	}
+}
+
+func TestFirewallCustomization_PortWarnings(t *testing.T) {
+	firewall := &FirewallCustomization{
+		Ports: []string{"22:tcp", "53:udp", "8080:tcp", "9000-9100:tcp", "bad"},
+		Services: &FirewallServicesCustomization{
+			Enabled:  []string{"ssh", "dns", "cockpit", "unknown"},
+			Disabled: []string{"http"},
+		},
+	}
+	assert.Equal(t, []string{
+		`firewall port "22:tcp" is already opened by the enabled service "ssh"`,
+		`firewall port "53:udp" is already opened by the enabled service "dns"`,
+		`firewall port "9000-9100:tcp" is already opened by the enabled service "cockpit"`,
+	}, firewall.PortWarnings())
+
+	firewall.Services = nil
+	assert.Nil(t, firewall.PortWarnings())
+}
+
+func TestFirewallCustomization_ValidateZones(t *testing.T) {
+	var unset *FirewallCustomization
+	assert.NoError(t, unset.ValidateZones())
+
+	firewall := &FirewallCustomization{
+		DefaultZone: "drop",
+		Zones: []FirewallZoneCustomization{
+			{Name: "internal", Interfaces: []string{"eth1", "bond0.100"}},
+			{Name: "trusted", Sources: []string{"10.0.0.0/8", "fd00::1", "52:54:00:12:34:56", "ipset:admins"}},
+		},
+	}
+	assert.NoError(t, firewall.ValidateZones())
+
+	cases := []struct {
+		firewall FirewallCustomization
+		err      string
+	}{
+		{FirewallCustomization{DefaultZone: "my zone"}, `invalid firewall zone name "my zone", must be 1 to 17 letters, digits, underscores, or dashes`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{{Name: "a-very-long-zone-name"}}}, `invalid firewall zone name "a-very-long-zone-name", must be 1 to 17 letters, digits, underscores, or dashes`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{{Name: "internal"}, {Name: "internal"}}}, `firewall zone "internal" is defined more than once`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{{Name: "trusted", Sources: []string{"10.0.0.0/33"}}}}, `invalid firewall zone source "10.0.0.0/33", must be an IP address, a network, a MAC address, or an ipset`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{{Name: "trusted", Sources: []string{"ipset:"}}}}, `invalid firewall zone source "ipset:", must be an IP address, a network, a MAC address, or an ipset`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{{Name: "internal", Interfaces: []string{"eth1/2"}}}}, `invalid firewall zone interface "eth1/2"`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{
+			{Name: "internal", Interfaces: []string{"eth1"}},
+			{Name: "dmz", Interfaces: []string{"eth1"}},
+		}}, `firewall zone interface "eth1" is bound to the zones "internal" and "dmz"`},
+		{FirewallCustomization{Zones: []FirewallZoneCustomization{
+			{Name: "internal", Sources: []string{"10.0.0.0/8"}},
+			{Name: "trusted", Sources: []string{"10.0.0.0/8"}},
+		}}, `firewall zone source "10.0.0.0/8" is bound to the zones "internal" and "trusted"`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, c.firewall.ValidateZones(), c.err)
+	}
+}
@@ -0,0 +1,80 @@
+package blueprint
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// IgnitionCustomization provisions edge images with Ignition on first boot.
+// The configuration is either embedded in the image or fetched from a URL.
+type IgnitionCustomization struct {
+	Embedded  *EmbeddedIgnitionCustomization  `json:"embedded,omitempty" toml:"embedded,omitempty"`
+	FirstBoot *FirstBootIgnitionCustomization `json:"firstboot,omitempty" toml:"firstboot,omitempty"`
+}
+
+// EmbeddedIgnitionCustomization embeds an Ignition configuration in the boot
+// partition of the image
+type EmbeddedIgnitionCustomization struct {
+	// Base64 encoded Ignition configuration
+	Config string `json:"config" toml:"config"`
+}
+
+// FirstBootIgnitionCustomization fetches the Ignition configuration from a
+// URL on first boot
+type FirstBootIgnitionCustomization struct {
+	ProvisioningURL string `json:"url" toml:"url"`
+}
+
+// Validate returns an error if both or none of the configuration sources are
+// set, if the embedded configuration isn't valid base64, or if the URL isn't
+// an absolute http or https URL.
+func (c *IgnitionCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Embedded != nil && c.FirstBoot != nil {
+		return errors.New("ignition embedded and firstboot configurations are mutually exclusive")
+	}
+
+	switch {
+	case c.Embedded != nil:
+		if c.Embedded.Config == "" {
+			return errors.New("ignition embedded configuration must not be empty")
+		}
+		if _, err := base64.StdEncoding.DecodeString(c.Embedded.Config); err != nil {
+			return fmt.Errorf("ignition embedded configuration is not valid base64: %v", err)
+		}
+	case c.FirstBoot != nil:
+		u, err := url.Parse(c.FirstBoot.ProvisioningURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ignition firstboot URL %q must be an http or https URL", c.FirstBoot.ProvisioningURL)
+		}
+	default:
+		return errors.New("ignition customization requires either an embedded or a firstboot configuration")
+	}
+	return nil
+}
+
+// EmbeddedConfig returns the decoded embedded Ignition configuration, or nil
+// if none is set. The configuration must have been validated.
+func (c *IgnitionCustomization) EmbeddedConfig() []byte {
+	if c == nil || c.Embedded == nil {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(c.Embedded.Config)
+	if err != nil {
+		panic("invalid ignition configuration: " + err.Error())
+	}
+	return data
+}
+
+// FirstBootURL returns the URL of the Ignition configuration fetched on first
+// boot, or "" if none is set.
+func (c *IgnitionCustomization) FirstBootURL() string {
+	if c == nil || c.FirstBoot == nil {
+		return ""
+	}
+	return c.FirstBoot.ProvisioningURL
+}
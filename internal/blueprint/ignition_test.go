@@ -0,0 +1,53 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnitionCustomization_Validate(t *testing.T) {
+	var unset *IgnitionCustomization
+	assert.NoError(t, unset.Validate())
+	assert.Nil(t, unset.EmbeddedConfig())
+	assert.Equal(t, "", unset.FirstBootURL())
+
+	embedded := &IgnitionCustomization{
+		Embedded: &EmbeddedIgnitionCustomization{Config: "eyJpZ25pdGlvbiI6e319"},
+	}
+	assert.NoError(t, embedded.Validate())
+	assert.Equal(t, []byte(`{"ignition":{}}`), embedded.EmbeddedConfig())
+
+	firstBoot := &IgnitionCustomization{
+		FirstBoot: &FirstBootIgnitionCustomization{ProvisioningURL: "http://192.168.1.1:8080/config.ign"},
+	}
+	assert.NoError(t, firstBoot.Validate())
+	assert.Equal(t, "http://192.168.1.1:8080/config.ign", firstBoot.FirstBootURL())
+
+	cases := []struct {
+		ignition IgnitionCustomization
+		err      string
+	}{
+		{IgnitionCustomization{}, "ignition customization requires either an embedded or a firstboot configuration"},
+		{IgnitionCustomization{Embedded: embedded.Embedded, FirstBoot: firstBoot.FirstBoot}, "ignition embedded and firstboot configurations are mutually exclusive"},
+		{IgnitionCustomization{Embedded: &EmbeddedIgnitionCustomization{}}, "ignition embedded configuration must not be empty"},
+		{IgnitionCustomization{Embedded: &EmbeddedIgnitionCustomization{Config: "{not base64}"}}, "ignition embedded configuration is not valid base64: illegal base64 data at input byte 0"},
+		{IgnitionCustomization{FirstBoot: &FirstBootIgnitionCustomization{ProvisioningURL: "config.ign"}}, `ignition firstboot URL "config.ign" must be an http or https URL`},
+		{IgnitionCustomization{FirstBoot: &FirstBootIgnitionCustomization{ProvisioningURL: "file:///boot/config.ign"}}, `ignition firstboot URL "file:///boot/config.ign" must be an http or https URL`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, c.ignition.Validate(), c.err)
+	}
+}
+
+func TestBlueprintInitializeIgnition(t *testing.T) {
+	bp := Blueprint{
+		Name: "edge",
+		Customizations: &Customizations{
+			Ignition: &IgnitionCustomization{
+				Embedded: &EmbeddedIgnitionCustomization{Config: "not base64!"},
+			},
+		},
+	}
+	assert.EqualError(t, bp.Initialize(), "ignition embedded configuration is not valid base64: illegal base64 data at input byte 3")
+}
@@ -0,0 +1,58 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// installationDeviceDirs are the directories of the device nodes that the
+// edge simplified installer can install to. Devices directly under /dev
+// have kernel names, the others have persistent names.
+var installationDeviceDirs = []string{"/dev", "/dev/disk/by-id", "/dev/disk/by-path"}
+
+// ValidateInstallationDevice returns an error if device isn't the path of a
+// device node that can be installed to, e.g. /dev/vda or
+// /dev/disk/by-id/wwn-0x5000c500a0b1c2d3.
+func ValidateInstallationDevice(device string) error {
+	if !path.IsAbs(device) || path.Clean(device) != device {
+		return fmt.Errorf("installation device %q must be an absolute path under /dev, e.g. \"/dev/vda\"", device)
+	}
+	// the devices are joined with commas into a kernel argument
+	if strings.ContainsAny(device, ", \t") {
+		return fmt.Errorf("installation device %q must not contain commas or whitespace", device)
+	}
+	for _, dir := range installationDeviceDirs {
+		if path.Dir(device) == dir && device != "/dev/disk" {
+			return nil
+		}
+	}
+	return fmt.Errorf("installation device %q must be under one of %s", device, strings.Join(installationDeviceDirs, ", "))
+}
+
+// GetInstallationDevices returns the installation device followed by its
+// fallbacks, or nil if no installation device is set.
+func (c *Customizations) GetInstallationDevices() []string {
+	if c == nil || c.InstallationDevice == "" {
+		return nil
+	}
+	return append([]string{c.InstallationDevice}, c.InstallationDeviceFallbacks...)
+}
+
+// checkInstallationDevices returns an error if one of the installation
+// devices is invalid or if fallbacks are set without an installation device.
+func (c *Customizations) checkInstallationDevices() error {
+	if c == nil {
+		return nil
+	}
+	if c.InstallationDevice == "" && len(c.InstallationDeviceFallbacks) > 0 {
+		return errors.New("installation device fallbacks require an installation device")
+	}
+	for _, device := range c.GetInstallationDevices() {
+		if err := ValidateInstallationDevice(device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateInstallationDevice(t *testing.T) {
+	valid := []string{
+		"/dev/vda",
+		"/dev/nvme0n1",
+		"/dev/disk/by-id/wwn-0x5000c500a0b1c2d3",
+		"/dev/disk/by-path/pci-0000:00:1f.2-ata-1",
+	}
+	for _, device := range valid {
+		assert.NoError(t, ValidateInstallationDevice(device), device)
+	}
+
+	cases := []struct {
+		device string
+		err    string
+	}{
+		{"sdb", `installation device "sdb" must be an absolute path under /dev, e.g. "/dev/vda"`},
+		{"/dev/../sdb", `installation device "/dev/../sdb" must be an absolute path under /dev, e.g. "/dev/vda"`},
+		{"/dev/vda,/dev/sda", `installation device "/dev/vda,/dev/sda" must not contain commas or whitespace`},
+		{"/dev", `installation device "/dev" must be under one of /dev, /dev/disk/by-id, /dev/disk/by-path`},
+		{"/dev/disk", `installation device "/dev/disk" must be under one of /dev, /dev/disk/by-id, /dev/disk/by-path`},
+		{"/dev/disk/by-uuid/0194fdc2-fa2f-4cc0-81d3-ff12045b73c8", `installation device "/dev/disk/by-uuid/0194fdc2-fa2f-4cc0-81d3-ff12045b73c8" must be under one of /dev, /dev/disk/by-id, /dev/disk/by-path`},
+		{"/mnt/vda", `installation device "/mnt/vda" must be under one of /dev, /dev/disk/by-id, /dev/disk/by-path`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, ValidateInstallationDevice(c.device), c.err)
+	}
+}
+
+func TestGetInstallationDevices(t *testing.T) {
+	var unset *Customizations
+	assert.Nil(t, unset.GetInstallationDevices())
+	assert.Nil(t, (&Customizations{InstallationDeviceFallbacks: []string{"/dev/sda"}}).GetInstallationDevices())
+
+	c := &Customizations{
+		InstallationDevice:          "/dev/vda",
+		InstallationDeviceFallbacks: []string{"/dev/sda", "/dev/nvme0n1"},
+	}
+	assert.Equal(t, []string{"/dev/vda", "/dev/sda", "/dev/nvme0n1"}, c.GetInstallationDevices())
+}
+
+func TestBlueprintInitializeInstallationDevices(t *testing.T) {
+	bp := Blueprint{
+		Name: "edge",
+		Customizations: &Customizations{
+			InstallationDevice:          "/dev/vda",
+			InstallationDeviceFallbacks: []string{"sda"},
+		},
+	}
+	assert.EqualError(t, bp.Initialize(), `installation device "sda" must be an absolute path under /dev, e.g. "/dev/vda"`)
+
+	bp.Customizations.InstallationDevice = ""
+	assert.EqualError(t, bp.Initialize(), "installation device fallbacks require an installation device")
+}
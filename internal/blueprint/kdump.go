@@ -0,0 +1,58 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// crashkernelRegexp matches the values of the crashkernel= kernel argument:
+// auto, a size with an optional offset, e.g. 512M@16M, or size ranges, e.g.
+// 1G-4G:192M,4G-:256M
+var crashkernelRegexp = regexp.MustCompile(`^(auto|[0-9]+[KMG](@[0-9]+[KMG])?|[0-9]+[KMG]-([0-9]+[KMG])?:[0-9]+[KMG](,[0-9]+[KMG]-([0-9]+[KMG])?:[0-9]+[KMG])*(@[0-9]+[KMG])?)$`)
+
+// KdumpCustomization enables or disables kdump, which saves a dump of the
+// kernel memory when the kernel crashes
+type KdumpCustomization struct {
+	Enabled bool `json:"enabled" toml:"enabled"`
+	// Memory reserved for the crash kernel, the value of the crashkernel=
+	// kernel argument, e.g. auto or 512M. Defaults to auto.
+	Crashkernel string `json:"crashkernel,omitempty" toml:"crashkernel,omitempty"`
+}
+
+// Validate returns an error if the crash kernel memory is set while kdump is
+// disabled or isn't a valid crashkernel= value, or if the kdump service is
+// disabled or masked while kdump is enabled.
+func (c *KdumpCustomization) Validate(services *ServicesCustomization) error {
+	if c == nil {
+		return nil
+	}
+	if !c.Enabled {
+		if c.Crashkernel != "" {
+			return fmt.Errorf("kdump crashkernel %q requires kdump to be enabled", c.Crashkernel)
+		}
+		return nil
+	}
+	if c.Crashkernel != "" && !crashkernelRegexp.MatchString(c.Crashkernel) {
+		return fmt.Errorf("invalid kdump crashkernel %q", c.Crashkernel)
+	}
+	if services != nil {
+		for _, service := range append(services.Disabled, services.Masked...) {
+			if ServiceUnitName(service) == "kdump.service" {
+				return fmt.Errorf("kdump requires the kdump service, which is disabled or masked")
+			}
+		}
+	}
+	return nil
+}
+
+// CrashkernelArg returns the crashkernel= kernel argument of an enabled
+// kdump customization, or "" if kdump is disabled or unset.
+func (c *KdumpCustomization) CrashkernelArg() string {
+	if c == nil || !c.Enabled {
+		return ""
+	}
+	if c.Crashkernel == "" {
+		return "crashkernel=auto"
+	}
+	return "crashkernel=" + c.Crashkernel
+}
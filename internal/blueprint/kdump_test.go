@@ -0,0 +1,32 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKdumpCustomization_Validate(t *testing.T) {
+	var unset *KdumpCustomization
+	assert.NoError(t, unset.Validate(&ServicesCustomization{Masked: []string{"kdump"}}))
+
+	assert.NoError(t, (&KdumpCustomization{}).Validate(&ServicesCustomization{Masked: []string{"kdump"}}))
+	for _, crashkernel := range []string{"", "auto", "512M", "256M@16M", "1G-4G:192M,4G-:256M", "1G-:256M@16M"} {
+		assert.NoError(t, (&KdumpCustomization{Enabled: true, Crashkernel: crashkernel}).Validate(nil), crashkernel)
+	}
+	assert.NoError(t, (&KdumpCustomization{Enabled: true}).Validate(&ServicesCustomization{Disabled: []string{"tuned"}}))
+
+	assert.EqualError(t, (&KdumpCustomization{Crashkernel: "512M"}).Validate(nil), `kdump crashkernel "512M" requires kdump to be enabled`)
+	for _, crashkernel := range []string{"512", "512M quiet", "1G-4G", "512M,", "yes"} {
+		assert.EqualError(t, (&KdumpCustomization{Enabled: true, Crashkernel: crashkernel}).Validate(nil), `invalid kdump crashkernel "`+crashkernel+`"`)
+	}
+	assert.EqualError(t, (&KdumpCustomization{Enabled: true}).Validate(&ServicesCustomization{Masked: []string{"kdump.service"}}), "kdump requires the kdump service, which is disabled or masked")
+}
+
+func TestKdumpCustomization_CrashkernelArg(t *testing.T) {
+	var unset *KdumpCustomization
+	assert.Equal(t, "", unset.CrashkernelArg())
+	assert.Equal(t, "", (&KdumpCustomization{}).CrashkernelArg())
+	assert.Equal(t, "crashkernel=auto", (&KdumpCustomization{Enabled: true}).CrashkernelArg())
+	assert.Equal(t, "crashkernel=512M", (&KdumpCustomization{Enabled: true, Crashkernel: "512M"}).CrashkernelArg())
+}
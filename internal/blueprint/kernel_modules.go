@@ -0,0 +1,31 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// kernelModuleNameRegexp matches the names of kernel modules as modprobe
+// accepts them
+var kernelModuleNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// KernelModulesCustomization controls which kernel modules images load
+type KernelModulesCustomization struct {
+	// Modules which are never loaded automatically, neither from the image
+	// nor from its initramfs
+	Blacklist []string `json:"blacklist,omitempty" toml:"blacklist,omitempty"`
+}
+
+// Validate returns an error if a module name contains anything but
+// alphanumerics, dashes, and underscores.
+func (c *KernelModulesCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, module := range c.Blacklist {
+		if !kernelModuleNameRegexp.MatchString(module) {
+			return fmt.Errorf("invalid kernel module name %q, must only contain alphanumerics, dashes, and underscores", module)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelModulesCustomization_Validate(t *testing.T) {
+	var unset *KernelModulesCustomization
+	assert.NoError(t, unset.Validate())
+
+	assert.NoError(t, (&KernelModulesCustomization{Blacklist: []string{"nouveau", "firewire-core", "floppy", "nouveau"}}).Validate())
+
+	assert.EqualError(t, (&KernelModulesCustomization{Blacklist: []string{""}}).Validate(), `invalid kernel module name "", must only contain alphanumerics, dashes, and underscores`)
+	assert.EqualError(t, (&KernelModulesCustomization{Blacklist: []string{"nouveau firewire-core"}}).Validate(), `invalid kernel module name "nouveau firewire-core", must only contain alphanumerics, dashes, and underscores`)
+	assert.EqualError(t, (&KernelModulesCustomization{Blacklist: []string{"nouveau.ko"}}).Validate(), `invalid kernel module name "nouveau.ko", must only contain alphanumerics, dashes, and underscores`)
+}
@@ -0,0 +1,79 @@
+package blueprint
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ostreeRemoteNameRegexp matches the names of remotes that ostree accepts
+var ostreeRemoteNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// OSTreeCustomization configures the ostree repository of edge images
+type OSTreeCustomization struct {
+	// Remote from which the image is upgraded
+	Remote *OSTreeRemoteCustomization `json:"remote,omitempty" toml:"remote,omitempty"`
+}
+
+// OSTreeRemoteCustomization is an ostree remote, which rpm-ostree upgrades
+// the deployed commit from
+type OSTreeRemoteCustomization struct {
+	Name string `json:"name" toml:"name"`
+	URL  string `json:"url" toml:"url"`
+	// URL of the content of the repository, if it differs from the URL of
+	// its metadata, or "mirrorlist=" followed by the URL of a mirrorlist
+	ContentURL string `json:"contenturl,omitempty" toml:"contenturl,omitempty"`
+	// ASCII armored GPG key the commits are verified with, commits aren't
+	// verified if it is unset
+	GPGKey string `json:"gpgkey,omitempty" toml:"gpgkey,omitempty"`
+}
+
+// Validate returns an error if the remote is missing, has an invalid name,
+// if its URLs aren't http, https, or file URLs, or if its GPG key isn't an
+// ASCII armored key.
+func (c *OSTreeCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	remote := c.Remote
+	if remote == nil {
+		return errors.New("ostree customization requires a remote")
+	}
+	if !ostreeRemoteNameRegexp.MatchString(remote.Name) {
+		return fmt.Errorf("invalid ostree remote name %q", remote.Name)
+	}
+	if !isOSTreeRemoteURL(remote.URL) {
+		return fmt.Errorf("ostree remote URL %q must be an http, https, or file URL", remote.URL)
+	}
+	if remote.ContentURL != "" && !isOSTreeRemoteURL(strings.TrimPrefix(remote.ContentURL, "mirrorlist=")) {
+		return fmt.Errorf("ostree remote content URL %q must be an http, https, or file URL", remote.ContentURL)
+	}
+	if remote.GPGKey != "" && !IsInlineGPGKey(remote.GPGKey) {
+		return fmt.Errorf("ostree remote %q GPG key must be an ASCII armored key", remote.Name)
+	}
+	return nil
+}
+
+// GetRemote returns the remote of the customization, or nil if none is set.
+func (c *OSTreeCustomization) GetRemote() *OSTreeRemoteCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Remote
+}
+
+func isOSTreeRemoteURL(location string) bool {
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	}
+	return false
+}
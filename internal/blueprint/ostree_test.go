@@ -0,0 +1,44 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSTreeCustomization_Validate(t *testing.T) {
+	var unset *OSTreeCustomization
+	assert.NoError(t, unset.Validate())
+	assert.Nil(t, unset.GetRemote())
+
+	validCases := []OSTreeRemoteCustomization{
+		{Name: "edge", URL: "https://ostree.example.com/repo/"},
+		{Name: "edge.local", URL: "file:///srv/ostree/repo", GPGKey: testGPGKey},
+		{Name: "edge_1", URL: "http://ostree.example.com/repo/", ContentURL: "mirrorlist=https://ostree.example.com/mirrorlist"},
+		{Name: "edge", URL: "https://ostree.example.com/repo/", ContentURL: "https://cdn.example.com/repo/"},
+	}
+	for _, remote := range validCases {
+		remote := remote
+		c := &OSTreeCustomization{Remote: &remote}
+		assert.NoError(t, c.Validate(), remote.Name)
+		assert.Equal(t, &remote, c.GetRemote())
+	}
+
+	errCases := []struct {
+		remote *OSTreeRemoteCustomization
+		err    string
+	}{
+		{nil, "ostree customization requires a remote"},
+		{&OSTreeRemoteCustomization{URL: "https://ostree.example.com/repo/"}, `invalid ostree remote name ""`},
+		{&OSTreeRemoteCustomization{Name: "-edge", URL: "https://ostree.example.com/repo/"}, `invalid ostree remote name "-edge"`},
+		{&OSTreeRemoteCustomization{Name: "my remote", URL: "https://ostree.example.com/repo/"}, `invalid ostree remote name "my remote"`},
+		{&OSTreeRemoteCustomization{Name: "edge"}, `ostree remote URL "" must be an http, https, or file URL`},
+		{&OSTreeRemoteCustomization{Name: "edge", URL: "ftp://ostree.example.com/repo/"}, `ostree remote URL "ftp://ostree.example.com/repo/" must be an http, https, or file URL`},
+		{&OSTreeRemoteCustomization{Name: "edge", URL: "https:///repo"}, `ostree remote URL "https:///repo" must be an http, https, or file URL`},
+		{&OSTreeRemoteCustomization{Name: "edge", URL: "https://ostree.example.com/repo/", ContentURL: "mirrorlist="}, `ostree remote content URL "mirrorlist=" must be an http, https, or file URL`},
+		{&OSTreeRemoteCustomization{Name: "edge", URL: "https://ostree.example.com/repo/", GPGKey: "https://ostree.example.com/key"}, `ostree remote "edge" GPG key must be an ASCII armored key`},
+	}
+	for _, c := range errCases {
+		assert.EqualError(t, (&OSTreeCustomization{Remote: c.remote}).Validate(), c.err)
+	}
+}
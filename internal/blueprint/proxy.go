@@ -0,0 +1,43 @@
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ProxyCustomization sets the proxy server of the image in the environment
+// of logins and of the first boot commands, in the DNF configuration, and in
+// the subscription-manager configuration
+type ProxyCustomization struct {
+	// URL of the proxy server, e.g. http://proxy.example.com:3128
+	URL string `json:"url" toml:"url"`
+	// Hosts and domains that are connected to without the proxy
+	NoProxy []string `json:"no_proxy,omitempty" toml:"no_proxy,omitempty"`
+}
+
+// Validate returns an error if the URL isn't an http or https URL of a
+// server, or has credentials, which would end up in the logs of the build,
+// or if a host without the proxy is empty or has separators.
+func (c *ProxyCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		// the error of url.Parse quotes the URL, which may have credentials
+		return fmt.Errorf("proxy URL must be an http or https URL")
+	}
+	if u.User != nil {
+		return fmt.Errorf("proxy URL must not have credentials")
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("proxy URL %q must not have a path, query, or fragment", c.URL)
+	}
+	for _, host := range c.NoProxy {
+		if host == "" || strings.ContainsAny(host, ", \t\r\n") {
+			return fmt.Errorf("invalid host %q without the proxy", host)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// repositoryIDRegexp matches the IDs of repositories that DNF accepts
+var repositoryIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// RepositoryCustomization adds a repository from which the packages of the
+// image are depsolved, and optionally writes it to the image
+type RepositoryCustomization struct {
+	ID         string `json:"id" toml:"id"`
+	Name       string `json:"name,omitempty" toml:"name,omitempty"`
+	BaseURL    string `json:"baseurl,omitempty" toml:"baseurl,omitempty"`
+	Metalink   string `json:"metalink,omitempty" toml:"metalink,omitempty"`
+	Mirrorlist string `json:"mirrorlist,omitempty" toml:"mirrorlist,omitempty"`
+	GPGCheck   bool   `json:"gpgcheck,omitempty" toml:"gpgcheck,omitempty"`
+	// URLs of the GPG keys of the repository, or ASCII armored keys
+	GPGKeys []string `json:"gpgkeys,omitempty" toml:"gpgkeys,omitempty"`
+	// Write the repository to /etc/yum.repos.d of the image, otherwise it
+	// is only used when the image is built
+	InstallToImage bool `json:"install_to_image,omitempty" toml:"install_to_image,omitempty"`
+}
+
+// IsInlineGPGKey returns true if key is an ASCII armored GPG key rather than
+// the URL of one.
+func IsInlineGPGKey(key string) bool {
+	return strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN PGP PUBLIC KEY BLOCK-----")
+}
+
+// InlineGPGKeys returns the ASCII armored GPG keys of the repository.
+func (r *RepositoryCustomization) InlineGPGKeys() []string {
+	var keys []string
+	for _, key := range r.GPGKeys {
+		if IsInlineGPGKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// GetRepositories returns the repositories of the customizations.
+func (c *Customizations) GetRepositories() []RepositoryCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Repositories
+}
+
+// ValidateRepositories returns an error if the ID of a repository is invalid
+// or not unique, if a repository doesn't have exactly one of a base URL, a
+// metalink, and a mirrorlist, if one of its URLs isn't valid, or if it
+// checks GPG signatures without any GPG key.
+func (c *Customizations) ValidateRepositories() error {
+	ids := make(map[string]bool)
+	for _, repo := range c.GetRepositories() {
+		if !repositoryIDRegexp.MatchString(repo.ID) {
+			return fmt.Errorf("invalid repository ID %q", repo.ID)
+		}
+		if ids[repo.ID] {
+			return fmt.Errorf("repository %q is defined more than once", repo.ID)
+		}
+		ids[repo.ID] = true
+
+		sources := 0
+		for _, source := range []string{repo.BaseURL, repo.Metalink, repo.Mirrorlist} {
+			if source == "" {
+				continue
+			}
+			sources++
+			if !isRepositoryURL(source) {
+				return fmt.Errorf("invalid URL %q of repository %q", source, repo.ID)
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("repository %q requires exactly one of baseurl, metalink, and mirrorlist", repo.ID)
+		}
+
+		if repo.GPGCheck && len(repo.GPGKeys) == 0 {
+			return fmt.Errorf("repository %q checks GPG signatures but has no GPG keys", repo.ID)
+		}
+		for _, key := range repo.GPGKeys {
+			if !IsInlineGPGKey(key) && !isRepositoryURL(key) {
+				return fmt.Errorf("GPG key of repository %q must be a URL or an ASCII armored key", repo.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// isRepositoryURL returns true if s is an absolute http, https, or file URL
+func isRepositoryURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "file":
+		return u.Path != ""
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGPGKey = "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQINBGAcScoBEADLf8YHkezJ6adlMYw7aGGIlJalt8Jj2x/B2K+hIfIuxGtpVj7e\n-----END PGP PUBLIC KEY BLOCK-----\n"
+
+func TestRepositoryCustomization_InlineGPGKeys(t *testing.T) {
+	repo := RepositoryCustomization{
+		GPGKeys: []string{"https://example.com/RPM-GPG-KEY", testGPGKey, "file:///etc/pki/rpm-gpg/RPM-GPG-KEY-redhat-release"},
+	}
+	assert.Equal(t, []string{testGPGKey}, repo.InlineGPGKeys())
+	assert.True(t, IsInlineGPGKey("\n"+testGPGKey))
+	assert.False(t, IsInlineGPGKey("https://example.com/RPM-GPG-KEY"))
+}
+
+func TestCustomizations_ValidateRepositories(t *testing.T) {
+	var unset *Customizations
+	assert.NoError(t, unset.ValidateRepositories())
+
+	valid := &Customizations{
+		Repositories: []RepositoryCustomization{
+			{ID: "internal", BaseURL: "https://repo.example.com/el8/x86_64/", GPGCheck: true, GPGKeys: []string{testGPGKey, "https://repo.example.com/RPM-GPG-KEY"}, InstallToImage: true},
+			{ID: "epel-8", Metalink: "https://mirrors.fedoraproject.org/metalink?repo=epel-8&arch=x86_64"},
+			{ID: "local", Mirrorlist: "file:///srv/mirrorlist"},
+		},
+	}
+	assert.NoError(t, valid.ValidateRepositories())
+
+	cases := []struct {
+		repo RepositoryCustomization
+		err  string
+	}{
+		{RepositoryCustomization{BaseURL: "https://repo.example.com"}, `invalid repository ID ""`},
+		{RepositoryCustomization{ID: "my repo", BaseURL: "https://repo.example.com"}, `invalid repository ID "my repo"`},
+		{RepositoryCustomization{ID: "internal", BaseURL: "https://repo.example.com"}, `repository "internal" is defined more than once`},
+		{RepositoryCustomization{ID: "none"}, `repository "none" requires exactly one of baseurl, metalink, and mirrorlist`},
+		{RepositoryCustomization{ID: "both", BaseURL: "https://repo.example.com", Metalink: "https://mirrors.example.com"}, `repository "both" requires exactly one of baseurl, metalink, and mirrorlist`},
+		{RepositoryCustomization{ID: "ftp", BaseURL: "ftp://repo.example.com"}, `invalid URL "ftp://repo.example.com" of repository "ftp"`},
+		{RepositoryCustomization{ID: "key", BaseURL: "https://repo.example.com", GPGKeys: []string{"mQINBGAcScoBEADLf8YHkezJ6adl"}}, `GPG key of repository "key" must be a URL or an ASCII armored key`},
+		{RepositoryCustomization{ID: "gpg", BaseURL: "https://repo.example.com", GPGCheck: true}, `repository "gpg" checks GPG signatures but has no GPG keys`},
+	}
+	for _, c := range cases {
+		customizations := &Customizations{Repositories: []RepositoryCustomization{valid.Repositories[0], c.repo}}
+		assert.EqualError(t, customizations.ValidateRepositories(), c.err)
+	}
+}
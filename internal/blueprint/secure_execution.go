@@ -0,0 +1,33 @@
+package blueprint
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// SecureExecutionCustomization makes s390x images boot as IBM Secure
+// Execution guests. The kernel, initrd and kernel command line are encrypted
+// into an image that only the hosts of the host key documents can boot.
+type SecureExecutionCustomization struct {
+	// PEM encoded host key documents of the hosts the image may run on
+	HostKeyDocuments []string `json:"host_key_documents" toml:"host_key_documents"`
+}
+
+// Validate returns an error if there is no host key document or if one
+// isn't a PEM encoded certificate.
+func (c *SecureExecutionCustomization) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if len(c.HostKeyDocuments) == 0 {
+		return errors.New("Secure Execution requires at least one host key document")
+	}
+	for idx, doc := range c.HostKeyDocuments {
+		block, _ := pem.Decode([]byte(doc))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("host key document %d is not a PEM encoded certificate", idx)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecureExecutionCustomization_Validate(t *testing.T) {
+	var unset *SecureExecutionCustomization
+	assert.NoError(t, unset.Validate())
+
+	hkd := "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"
+	se := SecureExecutionCustomization{HostKeyDocuments: []string{hkd}}
+	assert.NoError(t, se.Validate())
+
+	se.HostKeyDocuments = nil
+	assert.EqualError(t, se.Validate(), "Secure Execution requires at least one host key document")
+
+	se.HostKeyDocuments = []string{hkd, "-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"}
+	assert.EqualError(t, se.Validate(), "host key document 1 is not a PEM encoded certificate")
+
+	se.HostKeyDocuments = []string{"not a certificate"}
+	assert.EqualError(t, se.Validate(), "host key document 0 is not a PEM encoded certificate")
+}
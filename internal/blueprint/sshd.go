@@ -0,0 +1,70 @@
+package blueprint
+
+import (
+	"fmt"
+)
+
+// Values of the PermitRootLogin option of sshd
+const (
+	PermitRootLoginYes                = "yes"
+	PermitRootLoginNo                 = "no"
+	PermitRootLoginProhibitPassword   = "prohibit-password"
+	PermitRootLoginForcedCommandsOnly = "forced-commands-only"
+)
+
+// SSHDCustomization sets options of the configuration of the OpenSSH server.
+// Options that are not set keep the value of the distribution.
+type SSHDCustomization struct {
+	// Whether and how root can log in: yes, no, prohibit-password, or
+	// forced-commands-only
+	PermitRootLogin *string `json:"permit_root_login,omitempty" toml:"permit_root_login,omitempty"`
+	// Whether users can log in with their password
+	PasswordAuthentication *bool `json:"password_authentication,omitempty" toml:"password_authentication,omitempty"`
+	// Seconds of inactivity after which the client is asked for a response
+	ClientAliveInterval *int `json:"client_alive_interval,omitempty" toml:"client_alive_interval,omitempty"`
+	// Authentication attempts per connection
+	MaxAuthTries *int `json:"max_auth_tries,omitempty" toml:"max_auth_tries,omitempty"`
+}
+
+// Validate returns an error if one of the options has a value sshd doesn't
+// accept.
+func (s *SSHDCustomization) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.PermitRootLogin != nil {
+		switch *s.PermitRootLogin {
+		case PermitRootLoginYes, PermitRootLoginNo, PermitRootLoginProhibitPassword, PermitRootLoginForcedCommandsOnly:
+		default:
+			return fmt.Errorf("invalid sshd PermitRootLogin %q, must be one of yes, no, prohibit-password, or forced-commands-only", *s.PermitRootLogin)
+		}
+	}
+	if s.ClientAliveInterval != nil && *s.ClientAliveInterval < 0 {
+		return fmt.Errorf("sshd ClientAliveInterval must not be negative")
+	}
+	if s.MaxAuthTries != nil && *s.MaxAuthTries < 1 {
+		return fmt.Errorf("sshd MaxAuthTries must be at least 1")
+	}
+	return nil
+}
+
+// Warnings returns a message for every user whose password can't be used to
+// log in over SSH with the configuration.
+func (s *SSHDCustomization) Warnings(users []UserCustomization) []string {
+	if s == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, user := range users {
+		if user.Password == nil {
+			continue
+		}
+		if user.Name == "root" && s.PermitRootLogin != nil && *s.PermitRootLogin != PermitRootLoginYes {
+			warnings = append(warnings, fmt.Sprintf("user %q has a password, but sshd doesn't permit root to log in with a password", user.Name))
+		} else if s.PasswordAuthentication != nil && !*s.PasswordAuthentication && user.Key == nil {
+			warnings = append(warnings, fmt.Sprintf("user %q has a password but no SSH key, and sshd doesn't permit password authentication", user.Name))
+		}
+	}
+	return warnings
+}
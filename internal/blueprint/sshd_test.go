@@ -0,0 +1,52 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHDCustomization_Validate(t *testing.T) {
+	var sshd *SSHDCustomization
+	assert.NoError(t, sshd.Validate())
+
+	sshd = &SSHDCustomization{
+		PermitRootLogin:        common.StringToPtr("prohibit-password"),
+		PasswordAuthentication: common.BoolToPtr(false),
+		ClientAliveInterval:    common.IntToPtr(0),
+		MaxAuthTries:           common.IntToPtr(3),
+	}
+	assert.NoError(t, sshd.Validate())
+
+	assert.EqualError(t, (&SSHDCustomization{PermitRootLogin: common.StringToPtr("without-password")}).Validate(),
+		`invalid sshd PermitRootLogin "without-password", must be one of yes, no, prohibit-password, or forced-commands-only`)
+	assert.EqualError(t, (&SSHDCustomization{ClientAliveInterval: common.IntToPtr(-1)}).Validate(),
+		"sshd ClientAliveInterval must not be negative")
+	assert.EqualError(t, (&SSHDCustomization{MaxAuthTries: common.IntToPtr(0)}).Validate(),
+		"sshd MaxAuthTries must be at least 1")
+}
+
+func TestSSHDCustomization_Warnings(t *testing.T) {
+	users := []UserCustomization{
+		{Name: "root", Password: common.StringToPtr("secret")},
+		{Name: "admin", Password: common.StringToPtr("secret")},
+		{Name: "operator", Password: common.StringToPtr("secret"), Key: common.StringToPtr("ssh-ed25519 AAAA")},
+		{Name: "deploy", Key: common.StringToPtr("ssh-ed25519 AAAA")},
+	}
+
+	sshd := &SSHDCustomization{
+		PermitRootLogin:        common.StringToPtr("no"),
+		PasswordAuthentication: common.BoolToPtr(false),
+	}
+	assert.Equal(t, []string{
+		`user "root" has a password, but sshd doesn't permit root to log in with a password`,
+		`user "admin" has a password but no SSH key, and sshd doesn't permit password authentication`,
+	}, sshd.Warnings(users))
+
+	sshd = &SSHDCustomization{PermitRootLogin: common.StringToPtr("yes")}
+	assert.Nil(t, sshd.Warnings(users))
+
+	sshd = nil
+	assert.Nil(t, sshd.Warnings(users))
+}
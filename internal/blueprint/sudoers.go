@@ -0,0 +1,59 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// sudoersNameRegexp matches the user and group names that can be used
+// unquoted in a sudoers file, which are the names useradd accepts
+var sudoersNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.][A-Za-z0-9_.-]*\$?$`)
+
+// SudoRule is a rule of the sudoers file of the customizations which allows
+// a user or the members of a group to run any command
+type SudoRule struct {
+	Name     string
+	Group    bool
+	Nopasswd bool
+}
+
+// GetSudoRules returns the sudo rules of the users and groups of the
+// customizations, the ones of the users first. SudoNopasswd implies Sudo.
+func (c *Customizations) GetSudoRules() []SudoRule {
+	var rules []SudoRule
+	for _, user := range c.GetUsers() {
+		if rule, ok := sudoRule(user.Name, false, user.Sudo, user.SudoNopasswd); ok {
+			rules = append(rules, rule)
+		}
+	}
+	for _, group := range c.GetGroups() {
+		if rule, ok := sudoRule(group.Name, true, group.Sudo, group.SudoNopasswd); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func sudoRule(name string, group bool, sudo, nopasswd *bool) (SudoRule, bool) {
+	rule := SudoRule{
+		Name:     name,
+		Group:    group,
+		Nopasswd: nopasswd != nil && *nopasswd,
+	}
+	return rule, rule.Nopasswd || (sudo != nil && *sudo)
+}
+
+// ValidateSudoers returns an error if a user or group with sudo rights has a
+// name with characters that aren't allowed in a sudoers file.
+func (c *Customizations) ValidateSudoers() error {
+	for _, rule := range c.GetSudoRules() {
+		if !sudoersNameRegexp.MatchString(rule.Name) {
+			kind := "user"
+			if rule.Group {
+				kind = "group"
+			}
+			return fmt.Errorf("invalid sudo %s name %q", kind, rule.Name)
+		}
+	}
+	return nil
+}
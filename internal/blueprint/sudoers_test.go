@@ -0,0 +1,48 @@
+package blueprint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomizations_GetSudoRules(t *testing.T) {
+	var unset *Customizations
+	assert.Empty(t, unset.GetSudoRules())
+
+	yes, no := true, false
+	c := &Customizations{
+		User: []UserCustomization{
+			{Name: "alice", Sudo: &yes},
+			{Name: "bob"},
+			{Name: "cloud-user", SudoNopasswd: &yes},
+			{Name: "carol", Sudo: &no},
+		},
+		Group: []GroupCustomization{
+			{Name: "admins", Sudo: &yes, SudoNopasswd: &no},
+			{Name: "users"},
+		},
+	}
+	assert.Equal(t, []SudoRule{
+		{Name: "alice"},
+		{Name: "cloud-user", Nopasswd: true},
+		{Name: "admins", Group: true},
+	}, c.GetSudoRules())
+}
+
+func TestCustomizations_ValidateSudoers(t *testing.T) {
+	yes := true
+	valid := &Customizations{
+		User:  []UserCustomization{{Name: "machine$", Sudo: &yes}, {Name: "bad,name"}},
+		Group: []GroupCustomization{{Name: "wheel.admins", SudoNopasswd: &yes}},
+	}
+	assert.NoError(t, valid.ValidateSudoers())
+
+	for _, name := range []string{"", "bad,name", "al ice", "%admins", "root:root", "a=b", "-dash", "#1000", "ali\\ce"} {
+		c := &Customizations{User: []UserCustomization{{Name: name, Sudo: &yes}}}
+		assert.EqualError(t, c.ValidateSudoers(), fmt.Sprintf("invalid sudo user name %q", name))
+	}
+	c := &Customizations{Group: []GroupCustomization{{Name: "ad!mins", SudoNopasswd: &yes}}}
+	assert.EqualError(t, c.ValidateSudoers(), `invalid sudo group name "ad!mins"`)
+}
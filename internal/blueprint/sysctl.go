@@ -0,0 +1,45 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sysctlKeyRegexp matches dotted kernel parameter names, e.g.
+// net.ipv4.ip_forward
+var sysctlKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+$`)
+
+// SysctlCustomization sets a kernel parameter when the image boots
+type SysctlCustomization struct {
+	Key   string `json:"key" toml:"key"`
+	Value string `json:"value" toml:"value"`
+}
+
+// GetSysctl returns the kernel parameters of the customizations.
+func (c *Customizations) GetSysctl() []SysctlCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.Sysctl
+}
+
+// ValidateSysctl returns an error if a key isn't a dotted kernel parameter
+// name, if a value is empty or spans multiple lines, or if a key is set
+// more than once.
+func (c *Customizations) ValidateSysctl() error {
+	keys := make(map[string]bool)
+	for _, sysctl := range c.GetSysctl() {
+		if !sysctlKeyRegexp.MatchString(sysctl.Key) {
+			return fmt.Errorf("invalid sysctl key %q, must be a dotted kernel parameter name, e.g. \"net.ipv4.ip_forward\"", sysctl.Key)
+		}
+		if strings.TrimSpace(sysctl.Value) == "" || strings.ContainsAny(sysctl.Value, "\r\n") {
+			return fmt.Errorf("invalid value %q of sysctl key %q", sysctl.Value, sysctl.Key)
+		}
+		if keys[sysctl.Key] {
+			return fmt.Errorf("sysctl key %q is set more than once", sysctl.Key)
+		}
+		keys[sysctl.Key] = true
+	}
+	return nil
+}
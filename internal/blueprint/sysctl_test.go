@@ -0,0 +1,37 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomizations_ValidateSysctl(t *testing.T) {
+	var unset *Customizations
+	assert.NoError(t, unset.ValidateSysctl())
+
+	valid := &Customizations{
+		Sysctl: []SysctlCustomization{
+			{Key: "net.ipv4.ip_forward", Value: "1"},
+			{Key: "vm.swappiness", Value: "10"},
+			{Key: "net.ipv4.conf.eth0-1.rp_filter", Value: "2"},
+			{Key: "kernel.printk", Value: "3 4 1 3"},
+		},
+	}
+	assert.NoError(t, valid.ValidateSysctl())
+
+	cases := []struct {
+		sysctl []SysctlCustomization
+		err    string
+	}{
+		{[]SysctlCustomization{{Key: "swappiness", Value: "10"}}, `invalid sysctl key "swappiness", must be a dotted kernel parameter name, e.g. "net.ipv4.ip_forward"`},
+		{[]SysctlCustomization{{Key: "net/ipv4/ip_forward", Value: "1"}}, `invalid sysctl key "net/ipv4/ip_forward", must be a dotted kernel parameter name, e.g. "net.ipv4.ip_forward"`},
+		{[]SysctlCustomization{{Key: "vm..swappiness", Value: "10"}}, `invalid sysctl key "vm..swappiness", must be a dotted kernel parameter name, e.g. "net.ipv4.ip_forward"`},
+		{[]SysctlCustomization{{Key: "vm.swappiness", Value: " "}}, `invalid value " " of sysctl key "vm.swappiness"`},
+		{[]SysctlCustomization{{Key: "vm.swappiness", Value: "10\nkernel.panic = 1"}}, `invalid value "10\nkernel.panic = 1" of sysctl key "vm.swappiness"`},
+		{[]SysctlCustomization{{Key: "vm.swappiness", Value: "10"}, {Key: "vm.swappiness", Value: "60"}}, `sysctl key "vm.swappiness" is set more than once`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, (&Customizations{Sysctl: c.sysctl}).ValidateSysctl(), c.err)
+	}
+}
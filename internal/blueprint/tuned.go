@@ -0,0 +1,45 @@
+package blueprint
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+var tunedProfileRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// TunedCustomization selects the active TuneD profile of the image
+type TunedCustomization struct {
+	// Name of the profile, e.g. latency-performance. Profiles of other
+	// packages than tuned, e.g. tuned-profiles-sap-hana, need the package
+	// in the blueprint.
+	Profile string `json:"profile" toml:"profile"`
+}
+
+// Validate returns an error if the profile name is empty or not a valid
+// profile directory name, if the tuned service is disabled or masked, which
+// would leave the profile unapplied, or if DNF excludes the tuned package,
+// which keeps it from being installed or updated.
+func (c *TunedCustomization) Validate(services *ServicesCustomization, dnf *DNFCustomization) error {
+	if c == nil {
+		return nil
+	}
+	if !tunedProfileRegexp.MatchString(c.Profile) {
+		return fmt.Errorf("invalid TuneD profile name %q", c.Profile)
+	}
+	if services != nil {
+		for _, service := range append(services.Disabled, services.Masked...) {
+			if ServiceUnitName(service) == "tuned.service" {
+				return fmt.Errorf("TuneD profile %q requires the tuned service, which is disabled or masked", c.Profile)
+			}
+		}
+	}
+	if dnf != nil {
+		for _, pkg := range dnf.ExcludePkgs {
+			if matched, _ := path.Match(pkg, "tuned"); matched {
+				return fmt.Errorf("TuneD profile %q requires the tuned package, which is excluded by DNF", c.Profile)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTunedCustomization_Validate(t *testing.T) {
+	var unset *TunedCustomization
+	assert.NoError(t, unset.Validate(&ServicesCustomization{Masked: []string{"tuned"}}, &DNFCustomization{ExcludePkgs: []string{"tuned"}}))
+
+	tuned := &TunedCustomization{Profile: "latency-performance"}
+	assert.NoError(t, tuned.Validate(nil, nil))
+	assert.NoError(t, tuned.Validate(&ServicesCustomization{Disabled: []string{"kdump"}}, nil))
+	assert.NoError(t, tuned.Validate(nil, &DNFCustomization{ExcludePkgs: []string{"tuned-profiles-*", "kernel-rt"}}))
+
+	assert.EqualError(t, (&TunedCustomization{}).Validate(nil, nil), `invalid TuneD profile name ""`)
+	assert.EqualError(t, (&TunedCustomization{Profile: "../sap-hana"}).Validate(nil, nil), `invalid TuneD profile name "../sap-hana"`)
+	assert.EqualError(t, (&TunedCustomization{Profile: "virtual-guest powersave"}).Validate(nil, nil), `invalid TuneD profile name "virtual-guest powersave"`)
+	assert.EqualError(t, tuned.Validate(&ServicesCustomization{Disabled: []string{"tuned"}}, nil), `TuneD profile "latency-performance" requires the tuned service, which is disabled or masked`)
+	assert.EqualError(t, tuned.Validate(&ServicesCustomization{Masked: []string{"tuned.service"}}, nil), `TuneD profile "latency-performance" requires the tuned service, which is disabled or masked`)
+
+	for _, excluded := range [][]string{{"tuned"}, {"kernel-rt", "tune*"}} {
+		for _, fromBuild := range []bool{false, true} {
+			dnf := &DNFCustomization{ExcludePkgs: excluded, ExcludeFromBuild: fromBuild}
+			assert.EqualError(t, tuned.Validate(nil, dnf), `TuneD profile "latency-performance" requires the tuned package, which is excluded by DNF`)
+		}
+	}
+}
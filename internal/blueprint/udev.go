@@ -0,0 +1,203 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// udevRulesFilenameRegexp matches the names of rules files, which are
+// prefixed with a number that orders them, e.g. 70-persistent-net.rules
+var udevRulesFilenameRegexp = regexp.MustCompile(`^[0-9]{2}-[A-Za-z0-9_.-]+\.rules$`)
+
+// UdevRulesCustomization adds a rules file to /etc/udev/rules.d/
+type UdevRulesCustomization struct {
+	// Name of the rules file, e.g. 70-serial.rules
+	Filename string `json:"filename" toml:"filename"`
+	// Lines of the rules file
+	Rules []string `json:"rules" toml:"rules"`
+}
+
+// GetUdevRules returns the udev rules files of the customizations.
+func (c *Customizations) GetUdevRules() []UdevRulesCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.UdevRules
+}
+
+// ValidateUdevRules returns an error if the name of a rules file isn't of the
+// form NN-name.rules, if a file is added more than once or has no rules, or if
+// a rule spans multiple lines or can't be parsed.
+func (c *Customizations) ValidateUdevRules() error {
+	filenames := make(map[string]bool)
+	for _, file := range c.GetUdevRules() {
+		if !udevRulesFilenameRegexp.MatchString(file.Filename) {
+			return fmt.Errorf("invalid udev rules filename %q, must be of the form NN-name.rules", file.Filename)
+		}
+		if filenames[file.Filename] {
+			return fmt.Errorf("udev rules file %q is added more than once", file.Filename)
+		}
+		filenames[file.Filename] = true
+		if len(file.Rules) == 0 {
+			return fmt.Errorf("udev rules file %q has no rules", file.Filename)
+		}
+		for _, rule := range file.Rules {
+			if strings.ContainsAny(rule, "\r\n") {
+				return fmt.Errorf("udev rule %q of %q spans multiple lines", rule, file.Filename)
+			}
+			if _, err := ParseUdevRule(rule); err != nil {
+				return fmt.Errorf("udev rule %q of %q %v", rule, file.Filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// UdevRuleOp is a match, e.g. KERNEL=="ttyUSB0", or an assignment, e.g.
+// ATTR{power/control}="auto", of a udev rule
+type UdevRuleOp struct {
+	Key   string
+	Arg   string
+	Op    string
+	Value string
+}
+
+type udevKey struct {
+	// whether the key takes an argument in braces, the argument of
+	// TEST and RUN is optional
+	arg         bool
+	optionalArg bool
+	match       bool
+	assign      bool
+}
+
+// udevKeys are the keys of udev(7)
+var udevKeys = map[string]udevKey{
+	"ACTION":     {match: true},
+	"DEVPATH":    {match: true},
+	"KERNEL":     {match: true},
+	"KERNELS":    {match: true},
+	"NAME":       {match: true, assign: true},
+	"SYMLINK":    {match: true, assign: true},
+	"SUBSYSTEM":  {match: true},
+	"SUBSYSTEMS": {match: true},
+	"DRIVER":     {match: true},
+	"DRIVERS":    {match: true},
+	"ATTR":       {arg: true, match: true, assign: true},
+	"ATTRS":      {arg: true, match: true},
+	"SYSCTL":     {arg: true, match: true, assign: true},
+	"TAG":        {match: true, assign: true},
+	"TAGS":       {match: true},
+	"ENV":        {arg: true, match: true, assign: true},
+	"CONST":      {arg: true, match: true},
+	"TEST":       {arg: true, optionalArg: true, match: true},
+	"PROGRAM":    {match: true},
+	"RESULT":     {match: true},
+	"OWNER":      {assign: true},
+	"GROUP":      {assign: true},
+	"MODE":       {assign: true},
+	"SECLABEL":   {arg: true, assign: true},
+	"RUN":        {arg: true, optionalArg: true, assign: true},
+	"LABEL":      {assign: true},
+	"GOTO":       {assign: true},
+	"IMPORT":     {arg: true, assign: true},
+	"OPTIONS":    {assign: true},
+}
+
+// udevOperators are the operators of udev rules, the two character ones
+// first so that they are matched before "="
+var udevOperators = []string{"==", "!=", "+=", "-=", ":=", "="}
+
+// ParseUdevRule returns the matches and assignments of the udev rule, a
+// comma separated list of operations with quoted values. Comments, lines
+// starting with "#", have none.
+func ParseUdevRule(rule string) ([]UdevRuleOp, error) {
+	if strings.HasPrefix(strings.TrimSpace(rule), "#") {
+		return nil, nil
+	}
+
+	var ops []UdevRuleOp
+	for pos := 0; ; {
+		for pos < len(rule) && (rule[pos] == ' ' || rule[pos] == '\t' || rule[pos] == ',') {
+			pos++
+		}
+		if pos == len(rule) {
+			break
+		}
+
+		var op UdevRuleOp
+		start := pos
+		for pos < len(rule) && (rule[pos] >= 'A' && rule[pos] <= 'Z') {
+			pos++
+		}
+		op.Key = rule[start:pos]
+		if pos < len(rule) && rule[pos] == '{' {
+			end := strings.IndexByte(rule[pos:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("has an unterminated argument of key %q", op.Key)
+			}
+			op.Arg = rule[pos+1 : pos+end]
+			pos += end + 1
+		}
+		key, ok := udevKeys[op.Key]
+		if !ok {
+			return nil, fmt.Errorf("has an unknown key %q", op.Key)
+		}
+		if (op.Arg == "" && key.arg && !key.optionalArg) || (op.Arg != "" && !key.arg) {
+			return nil, fmt.Errorf("has an invalid argument of key %q", op.Key)
+		}
+
+		for pos < len(rule) && rule[pos] == ' ' {
+			pos++
+		}
+		for _, operator := range udevOperators {
+			if strings.HasPrefix(rule[pos:], operator) {
+				op.Op = operator
+				pos += len(operator)
+				break
+			}
+		}
+		switch op.Op {
+		case "":
+			return nil, fmt.Errorf("has no operator after key %q", op.Key)
+		case "==", "!=":
+			if !key.match {
+				return nil, fmt.Errorf("can't match key %q", op.Key)
+			}
+		default:
+			if !key.assign {
+				return nil, fmt.Errorf("can't assign key %q", op.Key)
+			}
+		}
+
+		for pos < len(rule) && rule[pos] == ' ' {
+			pos++
+		}
+		if pos == len(rule) || rule[pos] != '"' {
+			return nil, fmt.Errorf("has an unquoted value of key %q", op.Key)
+		}
+		pos++
+		start = pos
+		for pos < len(rule) && rule[pos] != '"' {
+			if rule[pos] == '\\' {
+				pos++
+			}
+			pos++
+		}
+		if pos >= len(rule) {
+			return nil, fmt.Errorf("has unbalanced quotes")
+		}
+		op.Value = rule[start:pos]
+		pos++
+
+		if pos < len(rule) && rule[pos] != ',' && rule[pos] != ' ' && rule[pos] != '\t' {
+			return nil, fmt.Errorf("has no separator after the value of key %q", op.Key)
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("has no keys")
+	}
+	return ops, nil
+}
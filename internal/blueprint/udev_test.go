@@ -0,0 +1,75 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomizations_ValidateUdevRules(t *testing.T) {
+	var unset *Customizations
+	assert.NoError(t, unset.ValidateUdevRules())
+
+	valid := &Customizations{
+		UdevRules: []UdevRulesCustomization{
+			{
+				Filename: "70-persistent-net.rules",
+				Rules: []string{
+					"# rename the onboard NIC",
+					`SUBSYSTEM=="net", ACTION=="add", ATTR{address}=="52:54:00:12:34:56", NAME="lan0"`,
+				},
+			},
+			{
+				Filename: "99-serial.rules",
+				Rules:    []string{`KERNEL=="ttyUSB[0-9]*", MODE="0660", GROUP="dialout", ENV{ID_NAME}="say \"hi\""`},
+			},
+		},
+	}
+	assert.NoError(t, valid.ValidateUdevRules())
+
+	cases := []struct {
+		rules UdevRulesCustomization
+		err   string
+	}{
+		{UdevRulesCustomization{Filename: "serial.rules", Rules: []string{`KERNEL=="ttyS0"`}}, `invalid udev rules filename "serial.rules", must be of the form NN-name.rules`},
+		{UdevRulesCustomization{Filename: "70-serial.conf", Rules: []string{`KERNEL=="ttyS0"`}}, `invalid udev rules filename "70-serial.conf", must be of the form NN-name.rules`},
+		{UdevRulesCustomization{Filename: "../70-serial.rules", Rules: []string{`KERNEL=="ttyS0"`}}, `invalid udev rules filename "../70-serial.rules", must be of the form NN-name.rules`},
+		{UdevRulesCustomization{Filename: "70-serial.rules"}, `udev rules file "70-serial.rules" has no rules`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`KERNEL=="ttyS0", MODE="0660`}}, `udev rule "KERNEL==\"ttyS0\", MODE=\"0660" of "70-serial.rules" has unbalanced quotes`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{"KERNEL==\"ttyS0\"\nMODE=\"0660\""}}, `udev rule "KERNEL==\"ttyS0\"\nMODE=\"0660\"" of "70-serial.rules" spans multiple lines`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`KERNAL=="ttyS0"`}}, `udev rule "KERNAL==\"ttyS0\"" of "70-serial.rules" has an unknown key "KERNAL"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`ATTR=="1"`}}, `udev rule "ATTR==\"1\"" of "70-serial.rules" has an invalid argument of key "ATTR"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`MODE=="0660"`}}, `udev rule "MODE==\"0660\"" of "70-serial.rules" can't match key "MODE"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`KERNEL="ttyS0"`}}, `udev rule "KERNEL=\"ttyS0\"" of "70-serial.rules" can't assign key "KERNEL"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`KERNEL==ttyS0`}}, `udev rule "KERNEL==ttyS0" of "70-serial.rules" has an unquoted value of key "KERNEL"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{`KERNEL=="ttyS0"MODE="0660"`}}, `udev rule "KERNEL==\"ttyS0\"MODE=\"0660\"" of "70-serial.rules" has no separator after the value of key "KERNEL"`},
+		{UdevRulesCustomization{Filename: "70-serial.rules", Rules: []string{""}}, `udev rule "" of "70-serial.rules" has no keys`},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, (&Customizations{UdevRules: []UdevRulesCustomization{c.rules}}).ValidateUdevRules(), c.err)
+	}
+
+	duplicate := &Customizations{
+		UdevRules: []UdevRulesCustomization{
+			{Filename: "70-serial.rules", Rules: []string{`KERNEL=="ttyS0", MODE="0660"`}},
+			{Filename: "70-serial.rules", Rules: []string{`KERNEL=="ttyS1", MODE="0660"`}},
+		},
+	}
+	assert.EqualError(t, duplicate.ValidateUdevRules(), `udev rules file "70-serial.rules" is added more than once`)
+}
+
+func TestParseUdevRule(t *testing.T) {
+	ops, err := ParseUdevRule(`SUBSYSTEM=="net", ATTR{address}=="52:54:00:12:34:56",NAME="lan0", ENV{ID_NAME}="say \"hi\"", RUN+="/bin/true"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []UdevRuleOp{
+		{Key: "SUBSYSTEM", Op: "==", Value: "net"},
+		{Key: "ATTR", Arg: "address", Op: "==", Value: "52:54:00:12:34:56"},
+		{Key: "NAME", Op: "=", Value: "lan0"},
+		{Key: "ENV", Arg: "ID_NAME", Op: "=", Value: `say \"hi\"`},
+		{Key: "RUN", Op: "+=", Value: "/bin/true"},
+	}, ops)
+
+	ops, err = ParseUdevRule("# a comment")
+	assert.NoError(t, err)
+	assert.Empty(t, ops)
+}
@@ -46,10 +46,12 @@ import (
 // region - A valid region where the resulting image should be located. If empty,
 //          the multi-region location closest to the source is chosen automatically.
 //          See: https://cloud.google.com/storage/docs/locations
+// guestOSFeatures - Guest OS features the resulting image has, e.g. UEFI_COMPATIBLE.
+//                   See: https://cloud.google.com/compute/docs/images/create-custom#guest-os-features
 //
 // Uses:
 //	- Cloud Build API
-func (g *GCP) ComputeImageImport(ctx context.Context, bucket, object, imageName, os, region string) (*cloudbuildpb.Build, error) {
+func (g *GCP) ComputeImageImport(ctx context.Context, bucket, object, imageName, os, region string, guestOSFeatures []string) (*cloudbuildpb.Build, error) {
 	cloudbuildClient, err := cloudbuild.NewClient(ctx, option.WithCredentials(g.creds))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Cloud Build client: %v", err)
@@ -108,6 +110,9 @@ func (g *GCP) ComputeImageImport(ctx context.Context, bucket, object, imageName,
 		// than the GCP guest tools not being installed.
 		buildStepArgs = append(buildStepArgs, "-data_disk")
 	}
+	if len(guestOSFeatures) > 0 {
+		buildStepArgs = append(buildStepArgs, fmt.Sprintf("-guest_os_features=%s", strings.Join(guestOSFeatures, ",")))
+	}
 
 	imageBuild := &cloudbuildpb.Build{
 		Steps: []*cloudbuildpb.BuildStep{{
@@ -180,6 +185,68 @@ func (g *GCP) ComputeImageImport(ctx context.Context, bucket, object, imageName,
 	return imageBuild, nil
 }
 
+// ComputeImageInsert creates a Compute Engine image from a previously uploaded
+// gzip compressed tar archive, which contains the raw disk of the image as
+// its only member named `disk.raw`. Unlike ComputeImageImport(), the image is
+// not converted by a Cloud Build API job and no other resources are created.
+//
+// To delete the Storage object (image) used for the image creation, use StorageObjectDelete().
+//
+// bucket - Google storage bucket name with the uploaded archive
+// object - Google storage object name of the uploaded archive
+// imageName - Desired image name. This must be unique within the whole project.
+// region - A valid region where the resulting image should be stored. If empty,
+//          the multi-region location closest to the source is chosen automatically.
+//          See: https://cloud.google.com/storage/docs/locations
+// guestOSFeatures - Guest OS features the image has, e.g. UEFI_COMPATIBLE.
+//                   See: https://cloud.google.com/compute/docs/images/create-custom#guest-os-features
+//
+// Uses:
+//	- Compute Engine API
+func (g *GCP) ComputeImageInsert(ctx context.Context, bucket, object, imageName, region string, guestOSFeatures []string) (*compute.Image, error) {
+	computeService, err := compute.NewService(ctx, option.WithCredentials(g.creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Compute Engine client: %v", err)
+	}
+
+	image := &compute.Image{
+		Name: imageName,
+		RawDisk: &compute.ImageRawDisk{
+			Source: fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, object),
+		},
+	}
+	if region != "" {
+		image.StorageLocations = []string{region}
+	}
+	for _, feature := range guestOSFeatures {
+		image.GuestOsFeatures = append(image.GuestOsFeatures, &compute.GuestOsFeature{Type: feature})
+	}
+
+	operation, err := computeService.Images.Insert(g.creds.ProjectID, image).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image: %v", err)
+	}
+
+	// Wait for the image to be created, every call to Wait() returns after
+	// the operation finished or at most two minutes
+	for operation.Status != "DONE" {
+		operation, err = computeService.GlobalOperations.Wait(g.creds.ProjectID, operation.Name).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to wait for the image creation: %v", err)
+		}
+	}
+	if operation.Error != nil && len(operation.Error.Errors) > 0 {
+		return nil, fmt.Errorf("image creation didn't finish successfully: %s", operation.Error.Errors[0].Message)
+	}
+
+	image, err = computeService.Images.Get(g.creds.ProjectID, imageName).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the created image: %v", err)
+	}
+
+	return image, nil
+}
+
 // ComputeImageURL returns an image's URL to Google Cloud Console. The method does
 // not check at all, if the image actually exists or not.
 func (g *GCP) ComputeImageURL(imageName string) string {
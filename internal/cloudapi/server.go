@@ -24,6 +24,13 @@ func NewServer(workers *worker.Server, rpmMetadata rpmmd.RPMMD, distros *distror
 	return server
 }
 
+// EnableCustomManifests enables building manifests given by the user in the
+// v2 API. Unless allowFileSources is set, manifests whose sources reference
+// files of the host are rejected.
+func (server *Server) EnableCustomManifests(allowFileSources bool) {
+	server.v2.EnableCustomManifests(allowFileSources)
+}
+
 func (server *Server) V1(path string) http.Handler {
 	return server.v1.Handler(path)
 }
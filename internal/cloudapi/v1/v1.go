@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -118,9 +119,10 @@ func (h *apiHandlers) Compose(ctx echo.Context) error {
 
 	// imagerequest
 	type imageRequest struct {
-		manifest distro.Manifest
-		arch     string
-		exports  []string
+		manifest  distro.Manifest
+		arch      string
+		exports   []string
+		imageType string
 	}
 	imageRequests := make([]imageRequest, len(request.ImageRequests))
 	var targets []*target.Target
@@ -176,7 +178,7 @@ func (h *apiHandlers) Compose(ctx echo.Context) error {
 			pkgSpecSets[name] = pkgs
 		}
 
-		imageOptions := distro.ImageOptions{Size: imageType.Size(0)}
+		imageOptions := distro.ImageOptions{Size: imageType.Size(0), BuildDate: time.Now()}
 		if request.Customizations != nil && request.Customizations.Subscription != nil {
 			imageOptions.Subscription = &distro.SubscriptionImageOptions{
 				Organization:  fmt.Sprintf("%d", request.Customizations.Subscription.Organization),
@@ -200,7 +202,7 @@ func (h *apiHandlers) Compose(ctx echo.Context) error {
 		var parent string
 		if ostreeOptions != nil && ostreeOptions.Url != nil {
 			imageOptions.OSTree.URL = *ostreeOptions.Url
-			parent, err = ostree.ResolveRef(imageOptions.OSTree.URL, imageOptions.OSTree.Ref)
+			parent, err = ostree.ResolveRef(imageOptions.OSTree.URL, imageOptions.OSTree.Ref, nil)
 			if err != nil {
 				return echo.NewHTTPError(http.StatusBadRequest, "Error resolving OSTree repo %s: %s", imageOptions.OSTree.URL, err)
 			}
@@ -239,6 +241,7 @@ func (h *apiHandlers) Compose(ctx echo.Context) error {
 		imageRequests[i].manifest = manifest
 		imageRequests[i].arch = arch.Name()
 		imageRequests[i].exports = imageType.Exports()
+		imageRequests[i].imageType = imageType.Name()
 
 		uploadRequest := ir.UploadRequest
 		/* oneOf is not supported by the openapi generator so marshal and unmarshal the uploadrequest based on the type */
@@ -373,9 +376,10 @@ func (h *apiHandlers) Compose(ctx echo.Context) error {
 	}
 
 	id, err := h.server.workers.EnqueueOSBuild(ir.arch, &worker.OSBuildJob{
-		Manifest: ir.manifest,
-		Targets:  targets,
-		Exports:  ir.exports,
+		Manifest:  ir.manifest,
+		Targets:   targets,
+		Exports:   ir.exports,
+		ImageType: ir.imageType,
 	})
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enqueue manifest")
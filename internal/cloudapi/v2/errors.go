@@ -34,6 +34,15 @@ const (
 	ErrorResourceNotFound        ServiceErrorCode = 21
 	ErrorMethodNotAllowed        ServiceErrorCode = 22
 	ErrorNotAcceptable           ServiceErrorCode = 23
+	ErrorCustomManifestsDisabled ServiceErrorCode = 24
+	ErrorInvalidManifest         ServiceErrorCode = 25
+	ErrorManifestFileSources     ServiceErrorCode = 26
+	ErrorTooManyPreviews         ServiceErrorCode = 27
+	ErrorInvalidOSTreeParams     ServiceErrorCode = 28
+	ErrorInvalidSeed             ServiceErrorCode = 29
+	ErrorInvalidGuestOSFeature   ServiceErrorCode = 30
+	ErrorInvalidAzureGallery     ServiceErrorCode = 31
+	ErrorOSTreeTLSSecretMissing  ServiceErrorCode = 32
 
 	// Internal errors, these are bugs
 	ErrorFailedToInitializeBlueprint              ServiceErrorCode = 1000
@@ -93,6 +102,15 @@ func getServiceErrors() serviceErrors {
 		serviceError{ErrorResourceNotFound, http.StatusNotFound, "Requested resource doesn't exist"},
 		serviceError{ErrorMethodNotAllowed, http.StatusMethodNotAllowed, "Requested method isn't supported for resource"},
 		serviceError{ErrorNotAcceptable, http.StatusNotAcceptable, "Only 'application/json' content is supported"},
+		serviceError{ErrorCustomManifestsDisabled, http.StatusForbidden, "Building custom manifests is not enabled"},
+		serviceError{ErrorInvalidManifest, http.StatusBadRequest, "Invalid manifest, it must be a version 2 manifest whose last pipeline produces a file"},
+		serviceError{ErrorManifestFileSources, http.StatusBadRequest, "Manifest sources must not reference local files"},
+		serviceError{ErrorTooManyPreviews, http.StatusTooManyRequests, "Too many compose previews were requested, try again later"},
+		serviceError{ErrorInvalidOSTreeParams, http.StatusBadRequest, "Invalid OSTree parameters or parameter combination"},
+		serviceError{ErrorInvalidSeed, http.StatusBadRequest, "Invalid seed, it must not be negative"},
+		serviceError{ErrorInvalidGuestOSFeature, http.StatusBadRequest, "Invalid GCP guest OS feature, see the guest_os_features of the GCP upload options for the valid ones"},
+		serviceError{ErrorInvalidAzureGallery, http.StatusBadRequest, "Invalid Azure gallery, its name, image definition and MAJOR.MINOR.PATCH version are required and replica counts must be positive"},
+		serviceError{ErrorOSTreeTLSSecretMissing, http.StatusBadRequest, "The ostree TLS secret of the compose is not configured on the worker"},
 
 		serviceError{ErrorFailedToInitializeBlueprint, http.StatusInternalServerError, "Failed to initialize blueprint"},
 		serviceError{ErrorFailedToGenerateManifestSeed, http.StatusInternalServerError, "Failed to generate manifest seed"},
@@ -218,6 +236,12 @@ func (s *Server) HTTPErrorHandler(echoError error, c echo.Context) {
 			sec := find(code)
 			apiErr := APIError(code, sec, c)
 
+			// The manifest is only rejected because of the request, so tell
+			// the client what is wrong with it.
+			if he, ok := echoError.(*echo.HTTPError); ok && code == ErrorFailedToMakeManifest && he.Internal != nil {
+				apiErr.Reason = fmt.Sprintf("%s: %v", apiErr.Reason, he.Internal)
+			}
+
 			if sec.httpStatus == http.StatusInternalServerError {
 				internalError, ok := echoError.(*echo.HTTPError)
 				errMsg := fmt.Sprintf("Internal server error. Code: %s, OperationId: %s", apiErr.Code, apiErr.OperationId)
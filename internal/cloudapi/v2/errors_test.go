@@ -1,7 +1,11 @@
 package v2
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -93,3 +97,28 @@ func TestAPIErrorList(t *testing.T) {
 	require.Equal(t, len(getServiceErrors()), errs.Total)
 	require.Equal(t, 1000, errs.Page)
 }
+
+func TestHTTPErrorHandlerManifestReason(t *testing.T) {
+	e := echo.New()
+	s := &Server{}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/image-builder-composer/v2/compose", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.Set("operationID", "test-operation-id")
+	s.HTTPErrorHandler(HTTPErrorWithInternal(ErrorFailedToMakeManifest, errors.New(`groups "devs" and "ops" have the same GID 2000`)), ctx)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var apiError Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiError))
+	require.Equal(t, "IMAGE-BUILDER-COMPOSER-11", apiError.Code)
+	require.Equal(t, `Failed to get manifest: groups "devs" and "ops" have the same GID 2000`, apiError.Reason)
+
+	// other errors keep their generic reason
+	rec = httptest.NewRecorder()
+	ctx = e.NewContext(req, rec)
+	ctx.Set("operationID", "test-operation-id")
+	s.HTTPErrorHandler(HTTPErrorWithInternal(ErrorBodyDecodingError, errors.New("unexpected EOF")), ctx)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiError))
+	require.Equal(t, "Malformed json, unable to decode body", apiError.Reason)
+}
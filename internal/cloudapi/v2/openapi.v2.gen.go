@@ -38,9 +38,46 @@ type AWSS3UploadStatus struct {
 	Url string `json:"url"`
 }
 
+// ArtifactChecksum defines model for ArtifactChecksum.
+type ArtifactChecksum struct {
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// AzureGallery defines model for AzureGallery.
+type AzureGallery struct {
+
+	// Name of the image definition in the gallery.
+	ImageDefinition string `json:"image_definition"`
+
+	// Name of the Shared Image Gallery.
+	Name string `json:"name"`
+
+	// Regions the image version is replicated to. If not specified, it
+	// is only replicated to the location of the upload with a single
+	// replica.
+	TargetRegions *[]AzureGalleryTargetRegion `json:"target_regions,omitempty"`
+
+	// Version of the image in the MAJOR.MINOR.PATCH format.
+	Version string `json:"version"`
+}
+
+// AzureGalleryTargetRegion defines model for AzureGalleryTargetRegion.
+type AzureGalleryTargetRegion struct {
+	Name         string `json:"name"`
+	ReplicaCount *int   `json:"replica_count,omitempty"`
+}
+
 // AzureUploadOptions defines model for AzureUploadOptions.
 type AzureUploadOptions struct {
 
+	// Version of an image definition of a Shared Image Gallery in the
+	// resource group, which the image is published as once it is registered.
+	// The gallery and the image definition must exist. If not specified, the
+	// image is only registered as a managed image.
+	Gallery *AzureGallery `json:"gallery,omitempty"`
+
 	// Name of the uploaded image. It must be unique in the given resource group.
 	// If name is omitted from the request, a random one based on a UUID is
 	// generated.
@@ -65,7 +102,10 @@ type AzureUploadOptions struct {
 
 // AzureUploadStatus defines model for AzureUploadStatus.
 type AzureUploadStatus struct {
-	ImageName string `json:"image_name"`
+
+	// Resource ID of the gallery image version, if the image was published to a gallery.
+	GalleryImageVersionId *string `json:"gallery_image_version_id,omitempty"`
+	ImageName             string  `json:"image_name"`
 }
 
 // ComposeId defines model for ComposeId.
@@ -74,6 +114,20 @@ type ComposeId struct {
 	ObjectReference
 	// Embedded fields due to inline allOf schema
 	Id string `json:"id"`
+
+	// Problems that don't prevent the compose from being queued,
+	// e.g. that no worker builds images for its architecture.
+	Warnings *[]string `json:"warnings,omitempty"`
+}
+
+// ComposeManifestRequest defines model for ComposeManifestRequest.
+type ComposeManifestRequest struct {
+	Architecture string `json:"architecture"`
+
+	// A version 2 osbuild manifest. The last pipeline of the manifest
+	// is exported and must produce a file.
+	Manifest      map[string]interface{} `json:"manifest"`
+	UploadOptions *AWSS3UploadOptions    `json:"upload_options,omitempty"`
 }
 
 // ComposeMetadata defines model for ComposeMetadata.
@@ -85,10 +139,21 @@ type ComposeMetadata struct {
 	// ID (hash) of the built commit
 	OstreeCommit *string `json:"ostree_commit,omitempty"`
 
+	// ID of the GPG key the commit was signed with
+	OstreeSigningKeyId *string `json:"ostree_signing_key_id,omitempty"`
+
 	// Package list including NEVRA
 	Packages *[]PackageMetadata `json:"packages,omitempty"`
 }
 
+// ComposePreview defines model for ComposePreview.
+type ComposePreview struct {
+
+	// The osbuild manifest of the compose. Every source lists only
+	// the number of items it fetches, e.g. {"count": 312}.
+	Manifest map[string]interface{} `json:"manifest"`
+}
+
 // ComposeRequest defines model for ComposeRequest.
 type ComposeRequest struct {
 	// Embedded struct due to allOf(#/components/schemas/ObjectReference)
@@ -105,6 +170,10 @@ type ComposeStatus struct {
 	ObjectReference
 	// Embedded fields due to inline allOf schema
 	ImageStatus ImageStatus `json:"image_status"`
+
+	// Problems of a pending compose, e.g. that no worker builds
+	// images for its architecture.
+	Warnings *[]string `json:"warnings,omitempty"`
 }
 
 // Customizations defines model for Customizations.
@@ -138,6 +207,11 @@ type GCPUploadOptions struct {
 	// Name of an existing STANDARD Storage class Bucket.
 	Bucket string `json:"bucket"`
 
+	// Guest OS features the Compute Engine image is created with. See
+	// https://cloud.google.com/compute/docs/images/create-custom#guest-os-features.
+	// If not specified, the image has no guest OS features.
+	GuestOsFeatures *[]string `json:"guest_os_features,omitempty"`
+
 	// The name to use for the imported and shared Compute Engine image.
 	// The image name must be unique within the GCP project, which is used
 	// for the OS image upload and import. If not specified a random
@@ -173,17 +247,47 @@ type GCPUploadStatus struct {
 
 // ImageRequest defines model for ImageRequest.
 type ImageRequest struct {
-	Architecture  string        `json:"architecture"`
-	ImageType     ImageTypes    `json:"image_type"`
-	Ostree        *OSTree       `json:"ostree,omitempty"`
-	Repositories  []Repository  `json:"repositories"`
+	Architecture string `json:"architecture"`
+
+	// Compose ID recorded in the image, e.g. in the .discinfo of
+	// installer ISOs, instead of the one derived from the release of
+	// the distribution and the build date. It must be a single word.
+	// Rejected for distributions other than RHEL 8.6 and CentOS
+	// Stream 8.
+	ComposeId *string    `json:"compose_id,omitempty"`
+	ImageType ImageTypes `json:"image_type"`
+	Ostree    *OSTree    `json:"ostree,omitempty"`
+
+	// Compression of the qcow2 image, overriding the default of the
+	// image type. Only supported for guest-image, and rejected for
+	// distributions other than RHEL 8.6 and CentOS Stream 8.
+	Qcow2Compression *string      `json:"qcow2_compression,omitempty"`
+	Repositories     []Repository `json:"repositories"`
+
+	// Logical sector size in bytes of the disk of the image, overriding
+	// the default of the image type. Disks with 4096 byte sectors are
+	// for 4K native storage. Rejected for distributions other than
+	// RHEL 8.6 and CentOS Stream 8.
+	SectorSize *int `json:"sector_size,omitempty"`
+
+	// Seed from which the UUIDs of the partitions and filesystems of
+	// the image are derived. Images built from the same request with
+	// the same seed get the same UUIDs and the same build date, which
+	// is derived from the seed as well. If unset, the seed is derived
+	// from the compose_id of the request, or a random seed and the
+	// current date are used.
+	Seed          *int64        `json:"seed,omitempty"`
 	UploadOptions UploadOptions `json:"upload_options"`
 }
 
 // ImageStatus defines model for ImageStatus.
 type ImageStatus struct {
-	Status       ImageStatusValue `json:"status"`
-	UploadStatus *UploadStatus    `json:"upload_status,omitempty"`
+
+	// Checksums of the files the compose produced
+	Artifacts    *[]ArtifactChecksum `json:"artifacts,omitempty"`
+	Error        *Error              `json:"error,omitempty"`
+	Status       ImageStatusValue    `json:"status"`
+	UploadStatus *UploadStatus       `json:"upload_status,omitempty"`
 }
 
 // ImageStatusValue defines model for ImageStatusValue.
@@ -209,6 +313,8 @@ const (
 	ImageTypes_edge_commit    ImageTypes = "edge-commit"
 	ImageTypes_edge_installer ImageTypes = "edge-installer"
 	ImageTypes_gcp            ImageTypes = "gcp"
+	ImageTypes_guest_image    ImageTypes = "guest-image"
+	ImageTypes_oci            ImageTypes = "oci"
 )
 
 // List defines model for List.
@@ -219,10 +325,73 @@ type List struct {
 	Total int    `json:"total"`
 }
 
+// OCIUploadOptions defines model for OCIUploadOptions.
+type OCIUploadOptions struct {
+
+	// Name of an existing Object Storage bucket, which the image is
+	// uploaded to before the custom image is created from it.
+	Bucket string `json:"bucket"`
+
+	// OCID of the compartment the custom image is created in.
+	CompartmentId string `json:"compartment_id"`
+
+	// Display name of the custom image. If not specified, a random
+	// 'composer-api-<uuid>' string is used.
+	ImageName *string `json:"image_name,omitempty"`
+
+	// Launch mode of the instances created from the image. If not
+	// specified, OCI chooses it.
+	LaunchMode *string `json:"launch_mode,omitempty"`
+
+	// The OCI region where the image is uploaded to and created in.
+	Region string `json:"region"`
+}
+
+// OCIUploadStatus defines model for OCIUploadStatus.
+type OCIUploadStatus struct {
+
+	// OCID of the custom image
+	ImageId string `json:"image_id"`
+	Region  string `json:"region"`
+}
+
 // OSTree defines model for OSTree.
 type OSTree struct {
-	Ref *string `json:"ref,omitempty"`
-	Url *string `json:"url,omitempty"`
+
+	// Sign the commit with this ASCII armored GPG secret key instead
+	// of a key of the worker. Mutually exclusive with gpg_keyid and
+	// only supported by image types that produce an ostree commit
+	// archive.
+	GpgKey *string `json:"gpg_key,omitempty"`
+
+	// Sign the commit with the GPG key with this ID, a key ID or
+	// fingerprint. The secret key must be in the GPG home directory
+	// of the worker, composes fail if it isn't. Only supported by
+	// image types that produce an ostree commit archive.
+	GpgKeyid *string `json:"gpg_keyid,omitempty"`
+	Ref      *string `json:"ref,omitempty"`
+
+	// Generate a static delta from the parent commit, the one ref
+	// resolves to in the repository at url, to the new commit.
+	// Requires url.
+	StaticDeltas *bool `json:"static_deltas,omitempty"`
+
+	// PEM encoded CA certificate of the repository at url, defaults
+	// to the system CAs
+	TlsCa *string `json:"tls_ca,omitempty"`
+
+	// PEM encoded TLS client certificate of the repository at url,
+	// if it requires mutual TLS. Only used to resolve ref, workers
+	// pull the commit with the credentials of tls_secret.
+	TlsClientCert *string `json:"tls_client_cert,omitempty"`
+
+	// PEM encoded key of tls_client_cert
+	TlsClientKey *string `json:"tls_client_key,omitempty"`
+
+	// Name of the secret of the worker configuration with the same
+	// TLS credentials. Required with tls_client_cert.
+	TlsSecret *string `json:"tls_secret,omitempty"`
+	Url       *string `json:"url,omitempty"`
 }
 
 // ObjectReference defines model for ObjectReference.
@@ -280,6 +449,7 @@ const (
 	UploadTypes_aws_s3 UploadTypes = "aws.s3"
 	UploadTypes_azure  UploadTypes = "azure"
 	UploadTypes_gcp    UploadTypes = "gcp"
+	UploadTypes_oci    UploadTypes = "oci"
 )
 
 // User defines model for User.
@@ -301,6 +471,12 @@ type Size string
 // PostComposeJSONBody defines parameters for PostCompose.
 type PostComposeJSONBody ComposeRequest
 
+// PostComposeManifestJSONBody defines parameters for PostComposeManifest.
+type PostComposeManifestJSONBody ComposeManifestRequest
+
+// PostComposePreviewJSONBody defines parameters for PostComposePreview.
+type PostComposePreviewJSONBody ComposeRequest
+
 // GetErrorListParams defines parameters for GetErrorList.
 type GetErrorListParams struct {
 
@@ -314,11 +490,23 @@ type GetErrorListParams struct {
 // PostComposeRequestBody defines body for PostCompose for application/json ContentType.
 type PostComposeJSONRequestBody PostComposeJSONBody
 
+// PostComposeManifestRequestBody defines body for PostComposeManifest for application/json ContentType.
+type PostComposeManifestJSONRequestBody PostComposeManifestJSONBody
+
+// PostComposePreviewRequestBody defines body for PostComposePreview for application/json ContentType.
+type PostComposePreviewJSONRequestBody PostComposePreviewJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
 	// Create compose
 	// (POST /compose)
 	PostCompose(ctx echo.Context) error
+	// Create compose from a manifest
+	// (POST /compose/manifest)
+	PostComposeManifest(ctx echo.Context) error
+	// Preview the manifest of a compose
+	// (POST /compose/preview)
+	PostComposePreview(ctx echo.Context) error
 	// The status of a compose
 	// (GET /composes/{id})
 	GetComposeStatus(ctx echo.Context, id string) error
@@ -352,6 +540,28 @@ func (w *ServerInterfaceWrapper) PostCompose(ctx echo.Context) error {
 	return err
 }
 
+// PostComposeManifest converts echo context to params.
+func (w *ServerInterfaceWrapper) PostComposeManifest(ctx echo.Context) error {
+	var err error
+
+	ctx.Set("Bearer.Scopes", []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.PostComposeManifest(ctx)
+	return err
+}
+
+// PostComposePreview converts echo context to params.
+func (w *ServerInterfaceWrapper) PostComposePreview(ctx echo.Context) error {
+	var err error
+
+	ctx.Set("Bearer.Scopes", []string{""})
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.PostComposePreview(ctx)
+	return err
+}
+
 // GetComposeStatus converts echo context to params.
 func (w *ServerInterfaceWrapper) GetComposeStatus(ctx echo.Context) error {
 	var err error
@@ -467,6 +677,8 @@ func RegisterHandlers(router EchoRouter, si ServerInterface) {
 	}
 
 	router.POST("/compose", wrapper.PostCompose)
+	router.POST("/compose/manifest", wrapper.PostComposeManifest)
+	router.POST("/compose/preview", wrapper.PostComposePreview)
 	router.GET("/composes/:id", wrapper.GetComposeStatus)
 	router.GET("/composes/:id/metadata", wrapper.GetComposeMetadata)
 	router.GET("/errors", wrapper.GetErrorList)
@@ -478,65 +690,105 @@ func RegisterHandlers(router EchoRouter, si ServerInterface) {
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+xbe28bOZL/KkTvAZ7BdestxxYwmHUcb9Z7kziwnFncxYZBdZfU3HSTHZJtWQ703Q98",
-	"dKsf1MM7nlkM4H8SSSSrflWsKlYV6e9eyNKMUaBSeJPvXoY5TkECt98WoP6PQIScZJIw6k28T3gBiNAI",
-	"Hj3fg0ecZgnUpj/gJAdv4vW99dr3iFrzLQe+8nyP4lSN6Jm+J8IYUqyWyFWmfheSE7rQywR5cvD+mKcz",
-	"4IjNEZGQCkQoAhzGyBKsoikIlGh6va149NxdeNbFoCZ99s/pxfngc5YwHF1paEZ+zjLgkhj+HBYa8/cC",
-	"lTfxIA+WIGTQ9/wmC98TMeZwvyQyvsdhyHK7JeXqL15/MByNj9+cnPb6A+/O97QOHHBL4phzvNK0Kc5E",
-	"zOS9EbiKKV0FxWgb1dr3OHzLCYdIAbAyubHelavZ7F8QSsW3qqmpxDJ3KAqnpI4IpyTohSfD3pvT4Zs3",
-	"4/HpOBrNXBp7poobwii+JY0t4KfDl91ltz73MN+muJwnbt+pslCTnPSfcg57hCMpXkBpMg1PxCkoP5Qx",
-	"oFyTgQjpBR10KVGaC4lmgHJKvuUqXOiJC/IAFHEQLOchoAVneda5pZdzpJggIhBLiZQQoTlnqV6iZAEh",
-	"fYQRxzRiKWIU0AwLiBCjCKPPny/fISJu6QIocCwh6tzSTSwwFq6BuUwoYSGWdgfrAv5iR9AyBg4ai6aC",
-	"RMzyJNLCFXJjGiG1l0IC1/z/zpZIMpQQIRFOElSwEZNbGkuZiUm3G7FQdFIScibYXHZClnaBBrnohgnp",
-	"YrU9XetbPz8QWP6kfwrChAQJliDkX/BT4Xz3itF9yeSooQBljZCrrXV7kdmOe70du3e6vnUHqKa5Fzcs",
-	"DzG9tmTea46uWJjPSgj3JGqDunynIFWn/RtgRjCOTmaDMMCzwSgYjfrD4LQXjoPj/mDYO4aT3ikMXOgk",
-	"UEzlDlwKhJl0GCprLnNCI0Rk4S3aRdEnxiVODrGbwmYkeYAgIhxCyfiqO89phFOgEieiNRrEbBlIFijW",
-	"gYHcUNI4fAPz8ew46IfDeTCKcC/Ax4NB0Jv1jnuD4Wn0JnqzN9BtNNbe25YFVrxyT+TaFhnrgeuQSNDA",
-	"WyHggnCukiYBl9oAcJJczb3Jl+/ef3GYexPvL91NUtW1aUP3Si++hjlwoCF4a78FOqqD7Q+GoI77AE5O",
-	"Z0F/EA0DPBofB6PB8fF4PBr1er2e53tzxlMsvYmX51qZewSLHALdbUT6ABJHWOKXFIwJyQHuQ5amRDpd",
-	"5ocYi/jHwnNmOUkkstMd7pfh8CteGNrN1FSPmLhLaJjkEaEL9PHi1+szr5Iv7ZLH0igV0cqm1rv0d22O",
-	"q5dUX5gLyVLyhMtTehe98/rste9FRKlulstWosJjSIITl4qN/fONMLtYXqrJheBNg6txbxLeaYob534x",
-	"D9PMRUl3r1AWgjs6WDpbZGhtWh1K1YYrGX7GhFxwEM/M7isRdZ9c0+rcte/lwhZ7BznHZwH8EI/wvQvO",
-	"GX9RN2AROLWhJuFKouBIcLAwitkdGzWHcnqDsHubtZS/kOc4vJ7tsM1C/Qftg9GuayNqdqpJuZG/P/+0",
-	"J/uf5eFXkNvzQUwRPBIhVYSd3px9fHd2/Q5NJeMqAocJFgK91SQ6zWzcfgksh63xx1153MRgygXJUC4A",
-	"zRm3+VXGuLTZuC5QI6RCSS4BXdAFoTYF69zSmzId04QaxYoqa20K9v78E8o4U2rz0TImYayKlFxAdEsL",
-	"vldTS8skdJq9wdJBqrJhEokMQjInCputYm7pUWjCHA9wRoLbvNcbhuoI15/gCBllFOwQFpUkUqF+TpWz",
-	"qVLbqlQimvFKrlrKtCRJolRTKleyqn5VmWb1qfsspSqx+k4iTb3I5jpoCoCKNDZMWB51FowtEtBJrDCm",
-	"o/PbblnL2PKwqkRfQ0zzRJLAIi+mozBhAoRUMNUkk1fe0h9s2VKYpzHMctmPSs1hzARQhHPJUixJiJNk",
-	"1VQy5M/o3DTqSZWTsHmhFy03KqYrvJpK3ZJd5qvNs3NLL3AYF0aitR4yKjFRJXGhKV5kVJYNUsg76FeN",
-	"wOSNAmEOk1uKUICO1Fkw+Q4pJgmJ1kcTdEaR/oZwFHEQygSxRBwyDkLFow2vUJFADbE66G+MI6s9Hx3h",
-	"hITwV/td7flRx3IWwB9ICGdm3TMxGNaWxDbe6SpgMtbelv0VZ5nImOws7KJiTRWSrkWeqw0rf9HYULga",
-	"KohSQoVTBxFLMaGT7+Z/xVC7J5rmRAIyv6IfMk5SzFc/tpkniWGoOzLqVDe7j6Vd29TIxvWOEOPoqIHJ",
-	"7XW7TZMIs8YEB2WoCNPVLS30W/emLzr5mLSsQtWIdXs4dPM83zPb1laz53tWwdUfn5FmbWuF2kPMVSaW",
-	"Z+zL1am+Z4+j+2a5iEUINMJUBjOOSRQMe8Nxf7i3IKyQ8/eVvbVEv93H5WFMJIQy5w1xHk+O749H2895",
-	"8/MB+fjNKgNd05iSct+aq+mNmqUlzpggkvFmtrVr+XWxaOVKus1pf8+yg8qyeq7VakVXVVfTSgN6i+1d",
-	"sS3bTOzZ1c6v+r5kI+BhBGp23hSvqJTqWA0jZSg0T/W0PAxBKCHnmCRGFRlQVcJrPyOJ/WiQmc9F21V9",
-	"u3NYWMVuKqzwUrFZhJnne7ptpuJStICg7Drob4QKiZMEuJN0kffXFf6VUHcZUlyP2QFCJSxMNVVcVbVH",
-	"JJM4cQ01NKyZ+uW9mrnOMov9rWWA71kHcdxqzNudgu5J1zhyV+nG5c1bLyTajBvlXgtBbCG0I4ZbuVu0",
-	"3u5++YWuNAeXUpoNIGegc4KAjG0ZKUK8IzNPAAv3mCCLNBpvG6K4CLRbDi7HwANwQQ4phW3s0bA3yzZw",
-	"faOEEqNy7Uq4bNeSWIC1jo1RlZVARDscohibZrbKY4HKbkSE7CrDO9lYnqLDRJeJbq3zyROXOaYgcULo",
-	"VzfXlKgaWnTmEDGO7THYYXzRLdb9rELvT2Y8GA5UYTY4VnL/VB5oeyFoJokNFHUQJQY13AmBSiY0/5+t",
-	"ln86CdQxh9MKZ6z+PR6ZXzS+t1jA1fQALDwWaWXnZ4wlgGk7sVHTXH4xbXSZGk4RSvJguiVfYdW+YoaQ",
-	"gwzUUAVphoVYMh654KqtvnfaTNtkDpCeUEEWceNKXfIc/JZCfI/xBaa2eVfnP+iNesOBM5dR6SjwNuRq",
-	"d66jtFtBvjc9qyHxm1quMa2orCKuaydbjR9G4YDOlevZw9rfu6Z5h75vSasztZdH+ypbt7h2597st4hf",
-	"JDqHS3/gimbJ8AzZixVK9E3SdlhyxXNKt2VQh2TnBoFNz93Zn18cKtXUtbqulZ7hpeiIYSNPcyHUXekX",
-	"bDXrGrJeJ2zcWQ86H/A0K4RWHBQiDiAajMf9U3R2dnZ2Pvz4hM/7yf+9u+x/vLkYq98uP/L3/3PBP/wv",
-	"+e8PHz4v87/j67N/pNe/sMun6/ng27tB9G781Ht789g9fnSBaBeTqsje/xRlS9F3p58+QZhzIldTpUGj",
-	"oreAuVH6TH/6WxF+//HPm+IllQ6qZl5JV8Vv856K0Dlrd8mmtosjmb4GtN1Uk4abJoPoeL6XkBCoSZvs",
-	"E66zDIcxoEGn59lEtDzpl8tlB+thfbzataL7y+X5xcfpRTDo9DqxTBO9h0RqpV1N32r29i6KI92uRDgj",
-	"lXxo4g3sBQRVAxNv2Ol1+joPl7FWU9c2eXX4YcLRTT/ngCUgjCgskZ3to4ypFIjgJFmhkFFh2+xsjgQ8",
-	"AMeFLrR6bN9ZP4QzfU/CUQRqie2hVi8zLiNv4n1iQlrRPGMHIORbFq3MTYtOwLRHZVlCTI+0+y97ibJ5",
-	"JbfzHrJ+H7qu25s6eM3Tk4ypvVDUBr3+S3O/jAzjhsrNIIqxQEJiLiFS2zjq9V6Mv72fafO+pKb/a3e6",
-	"eN5k+Pd/f/5nuVRG8hUoIgIRg8ZwH/7+3D9TnMuYcfJkbhIy4CpvQ6VxGiSjPwLJV8qWtNwHo4TxH2EC",
-	"nyk8ZhBKiBCoOYiFYc6VW1RjrT7Giij75W5953siT1OsqqsiaBTBRa0rIo3ofifRWp9irsu79yDNxYg+",
-	"lPU1HrKHP2JcU0xAQbPk9OWOtpQwySMQaBmDjIGryZQZWoUOdYoBEUTtePMeZP1W3689Nf7ifkZVEjZg",
-	"JUMLfV2on/CqGLt5wWvfEVXjS/U974u/qrlrBa/eSwevspnWsqC6Xv5jsasIHK9h6zVsHRS2bhqBZ3v8",
-	"0j2Yovu2M5AVEw3FOaFExI3wBQgecSiRyjiVVxNGEQeZcwoRikBVQQIxWn1uXLxlNjemO8JZ2SV8DWh7",
-	"A9rmSV3bum6qW1m8rDDPxYutfI1zr3HuzxHnWrFJGTSuGLKKd5q4qMS3VojZPC5rBReXZJspXX0PtK11",
-	"VJmnL4p+V9ffyOCydvNQl82RVcarm/1n3MwY+p/PyXBpQDhJUMaEILMESmvauNn+oghT02aiYfnHLgbZ",
-	"5u3ebIX00el21MMygJLubz31h3/wGV5u5auPvvroc3zUrK2S1n5ZNk23n39XdorbqutgLTntrYhQpHRg",
-	"nzj+GTOHneKsy8tGE2fq3W6ckY5aLmJi/zoMZ6Srq5lAt9SBB8XT4+7DwGtK8cE+M2RRHpq3sYaXzifa",
-	"rITEC/hNDKcSLwhdtNk8k47WNS1eO3rru/X/BwAA///p3GBJ3j4AAA==",
+	"H4sIAAAAAAAC/+w8a28bubV/hZgWSIuO3rJjG1i0iuzNqvXrWnYWt6tcgZqhJNYz5CzJsaME/u8Xh+S8",
+	"qYfbpO0C2Q8ba4bkOTwvnhfnixfwOOGMMCW9sy9eggWOiSLC/loR+DckMhA0UZQz78y7xSuCKAvJJ8/3",
+	"yCccJxGpDH/CUUq8M6/nvbz4HoU5v6ZEbDzfYziGN3qk78lgTWIMU9QmgedSCcpWepqknx2wr9N4QQTi",
+	"S0QViSWiDBEcrJFdsIxNtkCOTbe7FR89dhc+L9lLvfTo5+nFuP+QRByHNxo1s3/BEyIUNfAFWWmcv2RY",
+	"eWceSVvPRKpWz/PrIHxPrrEg82eq1nMcBDy1LMln/+L1+oPh0fHbk9Nur+999D1NAwe6+eJYCLzRazOc",
+	"yDVXc7PhMk7xppW9bWL14nuC/JpSQUJAwO7JjevHfDZf/IMECuCWKTVVWKUOQuGYVjHCMW11g5NB9+3p",
+	"4O3bo6PTo3C4cFHslSSubQbg5mtsQX46+LpcdtNzD/BthEtF5NadMggY5FxfKLrEgRqvSfAo07i5fFNU",
+	"Qiof278G/Lm/RX77R8c1UgwW3WA47J+eLINe0Bue4uViOQxOTk+Pl4vT/rD/FpNhjwyPh6eL08EwwMPT",
+	"o9PT3uLtyVF/cXJ05IRjFTuH0uu+Hbwd9k76Q99bchFj5Z15lKnjYTGdMkVWRDTIozfp5wbAbMFJr8+p",
+	"IO9xFIHhaNilD0RIyhkYJswQjcFEhmRJGVXZYzQFnQnRRL+0K4EBU2syY4JInoqAoJXgaeKj5zUN1vDK",
+	"LkYlStJFROWahAhLxFlAEFXwHIRIKiJI2J6x+zVBK7s2ZmFphRI6cSoVIp+oVG00WSLGFZIJCeiSktA3",
+	"+ORQOYs2JRAAG6MYM7wioVm5PQOLUJUd/WJegHRYchwToIsTQUOVbCPtwrJ7Z55Yk6h14hKMTGC3A3Kx",
+	"oLp6vJlbqC4ICosVUXOjtrIJ6868KG3qyQqG5lMS0QArEiLFXYSnasYKipcG6/UiHuBMmOB3qm0DAhuM",
+	"MJKUrSItR3qe4Ul+PvxekKV35v2uU5z2HXuedcqCfa/3Z3bhOkbsbnbKf0lmDRevRn+9uWtfTa5v7tq3",
+	"o/vxT8goaZXwvXa33QU5wkoRAWv+3y/d1unHP81m7dofv99rV61WN6Sw2ME+Fa9Q4gDTCAafpDDIfVBp",
+	"rsz1SWmot8RppLyzXokGfd+LKaMxGOPeYZZr6zb2HFurwpAdKhveS0bQ/XpmZDM3EGiijM1ZEJQy+mua",
+	"y8aKPhGGqsavPWOgG7AcaENMFWjBUvBYTwEaEKl8hJHALOQx4oygBZYkRJwhjB4eJueIyhlbEUYEqJDR",
+	"horLoxFzsSpTs+YGL+0b9LwmgpTkXK55GoVoUdo3mN6KXf6JP4MiR1QqhKMo12Z5NmNrpRJ51umEPJDt",
+	"mAaCS75U7YDHHcJaqewEEe1gYEXHOlt/fqLk+Qf9qBVEtBVhRaT6Hf6ceWNzADTPgbypEWCftBp2zDU7",
+	"dnO6yroDSFPnxT1PA8zu7DLvNUTXoZ8uchTmNGwiNTkHlMrD/glkhuQoPFn0gxZe9Iet4bA3aJ12g6PW",
+	"ca8/6B6Tk+4pcbo+ijDM1A68AAkz6DCsrLgsKQv1KW+0RasjuuVC4egQuclkRtEn0gqpIIHiYtNZpizE",
+	"MWEKR7LxtrXmzy3FWwC6ZVCuEekoeEuWR4vjVi8YLFvDEHdb+Ljfb3UX3eNuf3Aavg3f7rXQBcWavG1I",
+	"YEkr9xi8ba6ytXdzY8DsIeDkVyaJqGDcKvPVyie6j2j5rHvGZQdNcYTd3kunvFnZOUTgOqKsHLLj0JhO",
+	"IvgTDYmQnatcDsY8TlJFOgYPSmSncG46GmvZMc5Ux+5JdrJTuCHhVct/iCmtMby0gIuHgCyXZKI5gqPo",
+	"Zumd/bL7cLrRk+/IkgjCAuK9+HWu07CKbK8/IBBAt8jJ6aLV64eDFh4eHbeG/ePjo6PhsNvtwubzGCJN",
+	"tTQ2iPGMBaNs5XAAbwVfRCQGDxArFHL2RqFEkCfClBaWwGzTnGYLQtkK/ZqSlIT+jJH2qm3mMY6euXgk",
+	"Ai1SGoXSyJgEvwlRJREWwZoqEqhUkJqntycTUGdK6GDGx4IdV5jRJZHqzpy5jti9hEmV1p9OjuflAKzA",
+	"KLaLNqk3yt3lPuJS7x1lo9sIQpsIS4USmpCIsvwMyoZo75l8SrhQ9gjWTkcieJgGBGG0pJGlV0P+jO2d",
+	"88Jh2ukXNTMDjexCmTKlTe8Q/iuicIgV/poqwKUShMwDHsdUOU+nP6yxXP8xoyUQXSE73ME8u56kK9CA",
+	"+SPZ7Dn13t++R49kk0l/TJW2lbAAMdGLC0yCg0cQeVf2Ub8xnhRlQZSGoEXXFx/uRoeGPHaNnN4uNdmu",
+	"FLeCgPvVVIbtgg2iWxfojEDWJLTRxRMcMvb0ge2ZUHDGYBSrJz4VWhIVrIn0kTYcX2ae9vxm3hka9Pov",
+	"TjmvyeghQllS/a8lk0EqFY/pZ3yQro2ro198L6QgKItUNTJv23MD5vgRxWZ2gdT5gWzjdaJVoNcX3mlN",
+	"C+fkqx1wGrjM1927KYvCYWeYzlolhGkFs2Lqo+3H1Ix9s3OqvM8tNG4IVZVUZYtSSqknXKqVIPKV6fSS",
+	"E7eP7tPyWDhppK2uHGSqHiQRh9gn37sQgouvqqY8JE5qwCBcCsQcASSWnDle1diqIeTDawu72ax3eUlf",
+	"Y5D0aIfuZOQ/iA+GunvlVC/lxvz9+HZPUmaRBo9EbY+3MTNJW1DH6f3o+nx0d46migs4D4MIS4ne6SXq",
+	"Kc2W/dGyEBz2cQXma87lfEkwqKvDLryHIehmirIh+viyUQa6YCtwyfK8cSAIVvaEb6MpIUW8GkQ8Ddsr",
+	"zlcR0dFqYCMVCGSzyMTMb5mz4ncavRaXrQy2TRI1MtcWgTWWYJ5WdZRrAf8v3sPFj5P5+ObqdnQ/eXd5",
+	"4fne+w/Xk3HFHBAGGblf7Bvfu3q4vJ/MJ7fz6cO764t7z/emF+OHu4v5u5sb8+vDfDy6HZn14Nfl5MPF",
+	"/Gry/m50X3o6vb4tjWti8mFydz+5mU/H08lcw/yfh4sHePHz5Pr85uep99HByLqp2pWyA7cE3kC8mkJU",
+	"woWlYcmHliZn7uKzrTfov81CtSwfMN/mLt6Pb8ETB33IqhtUAtRwxjK4N1O7ls1tA3iDSzNdnqf/ZuyN",
+	"PZtECye0NUu73UEAoZv+i7xBhjgZOKhgqArWr0kPFvW+Jilhi+Z9KcmT7+mZRhGQJieu4mX6QkRo6akr",
+	"1jkpMfymoV49S4NohUJb9Ukam2D0KZuzXWXiNFK0ZTHPhqMg4hK0x5YfjF86Y38wf+R2x1icfNofte6v",
+	"uSQM4VTxGCsa4Cja1IlM0lfUwGuJWGq8Z0sXvW+UDQd89SpVSXaaKW2bZuwCOgiskGiqB5wpTCGXnFFK",
+	"ZN66BYMA8zb6oDEw+QJwd8jZjCHUQm/gkD/7QmJMIxq+vDlDI4b0L4TDUBBpEwSCJIJIOGgKWAEsgWrb",
+	"aqMfuUCWej56gyMakL/Y38DzN20LWRLxRAMyMvNeiYMBbZfYBjvetLhaa21L/oKTRCZctVd2UjanjJJO",
+	"4r2WGnb/WUUA8KqRIIwpk04ahDzGlJ19Mf8CQK2eaJpSRZB5iv6QCBpjsfljE3gUGYC6lCGJkIb7WNm5",
+	"dYoUqvcGcYHe1HDadlDtEk0qzRxjHEyRj21mLKNv/RzTAteQCs/3avJwKPM83zNsa5LZ8z1L4PLDj/+0",
+	"c583lVjvxBWM5s7TtgTv6/OTumQN68/raUIsA8JCzFRrITANW4Pu4Kg32JvhLC3n70t3ViLMr5JVs2eg",
+	"Mxtj409IaQsScKErc6w4BW1QZx+1QyoDypYc8eWMUSaVzhmjyfRG+gh+Exxm9pAzKNwL+lQt0UUES6Ln",
+	"w+9yyJx3JZhsSIhVtT6YlbIhtIRKyB0BmhFtZSsLSaQFGfSSobufLi7RSftYrz4mTN1MZ2yqBMExOqkf",
+	"PTC4ddI+bndb/W6/3x30hu3e9qSBeXxAcH2/SYgskmT75txM72HUi+/plhrI0YEJchfYx8XLjPR6VsZA",
+	"/kSEoDpS1xQ39WU7NGvnMIfWDfQXyDSx5gfoatxru5SpXRZUn7HXkB3VqG59Z8YZ6ODniEIn12epQqf3",
+	"KkjCJVVc1IO0XYS8yyZtnLG6rmvN3f2El3wF7gkygxAMAjVYbBSRGZ2h66nS2FCm9ow1yY3K1D6n8lEa",
+	"Az7snh7rpS047TEY73f4N8QwFOKQ9d3a6CDBn7GDWXDU6/uAwcdmZwEQiTjsxpRkWl20I0F1PSdNgoWi",
+	"BicAD/l1uZHKJI9mrKAFFrmZaJv2G2lTzbnRkBA/2DyapteM5Y8BPbQiqhho0MhsiX5UGBQbYOh6QMM4",
+	"6bWwRM8kinRYkTJJlF+8KybNWD6rMK9F5dv2IXBRtCKYxQ1WMxakQhCmNE6aBBB/GKY0+tTyzo+uiz+v",
+	"K0+8qjJRMnI1/WuA/ZidXFtbOW1TocNpz/oMc9nRwlIpitlCTXhw51K9hdGh/STLih2U3Hl1JvWDbi4u",
+	"GHTYAhVXps6eLMtZpbUBVEpGyDQIiAQmLTGNDCttrla7UjSyfxrMzN9ZSwr8cpnf0jFWAoWfAcwqSDzf",
+	"0y0Fnu+RcEVaeZlI/8o9Bc/3SgeK53s8oE5wWR6vKkSPlLnTill/ucN80c9b3iiucOR6VaO6Burnjemm",
+	"HdRM9rem9XzvZjz5ikk9k5PNQ2sz1dULOmN5p5HiaEGW3Ma7Jl3WzMJpO0aVI9OxPSUIgouFismWzpab",
+	"cVHlK43diQdl1dwkD2jYa5dmt3nQa7ex/W9/J0IVpXMqkwhvTC6KLxuouLou/9k80quaynDKgvU85qED",
+	"6Uv9EsHLDGetSiwgNQbmIpDtY8ZKG7kZTyDzwiWRBautCl+P7icfIHV4cfVwObq/OPd873Z0N4IU48Po",
+	"cvJ3/WT8ML2/udrimG3PewHgRt4rZ31ZUuFk3CYKJG0tBWaPy1S8onW+IaY7w8hcXXeHkXulvSRSDnE2",
+	"LAJBru5pp1hvuUjwGpLkyO+81WDDjsbWV4ku5zu8QLpilfI9+LJqTSUaTceTCcIi5pCkgEq/JIEgShf8",
+	"baA4Y7qMCE8s9UzNsI2uUpVCZhCRT0GUSnB+9dIWEaodqRnj1XBlsSm51zZxlHd4MGRCL4vqjGlf56na",
+	"9FEqemSQDt500c9QUGFy7tsNgoSIGVtStiIiEZTZrpUSVbIwN8uK375Hax4TlPffaXoVZPIz70giOOeh",
+	"6Ux3/bM3qhHJLTYzdjBtUIU0hcx1PxW3jEbvxucXP7qlddmsvHdOOiY/0QF/wDVNKqxoMA9JpLCs9EEv",
+	"cSSJX6842QZeSAromUjPLIxhgrV/bXbk5wkJQZbmRkX0RHRC2FI79243CCuUisjPstuMPNtVdMpB65SE",
+	"ERXJWXAeEWw64yM5D7CjeH5xhQiDwmaIxiMUgH4tdSN/ETc0kLBUkDNm0TFBFBqPpFtwNfSIgskDCLvR",
+	"uL+cIjP4MHRmzAiZyMgQa0WFdazI6TKK4siSGOjtW3mVM5akUeRUnUCQkDBFcWSCgEjOjWa09+7SaZjK",
+	"m8zsS40wW1Y1YHd3NlulrWgjVAaWdJUK2wie7QzizxnThC72CCG8Mc52YBW3RkEEfGjghgvprVe9mua9",
+	"Vtdv2Pm11d0GjC3F/C3ueLOT0M+caA3BdfTU+66ciU8nEiThW95kjqDDROkkpPOdpKs4PNr2iuEs8bol",
+	"ke14UboZs5tQNtC2N1SyaQW6viFCjiPEgaUEVzO+wJJY6ShkKa8MhqwtSLjGytbZmSJMdUIqlW79PSlM",
+	"NqzDZYfLTqUDVkTOFk6icETZoxtqTIXgQraXJOQC27R4m4tVJ5v3Z5DzH8z71qAPDnb/GPb9Q57g3ouC",
+	"BhLZCLKKRI4DvG4HhCkuNfw/Wyr/cNKSOkdWgozh/8dD80Tj9w5LcjM9ABexlnGJ8/kxUXdZYZhLL6a1",
+	"dqKaUkCHvmmLsWawenlX26kWvCphmmApIYPuQhdYPXfKTFNkDtg9ZZKu1rXLykqkxHVucrHCzHZpVeH3",
+	"u8PuoO+sbUB5iogmyuU2rDZQt4T5Xm+5golfp3IFaIlkpe26ONlIBnBGDmhRcl0of/H3zpkOXjel0YK0",
+	"F0bzKtm+KY2MiG5+2h118X+FXnlL48HkOnBGveb4CmIdOKMejmpSFSnEw1J9ImVsWz7vkNKVwcDWrty5",
+	"SD87tcqJ4PK8RrIQP8u2HDSyhtsSgbrL8Su2LurSdbWAVFgN/dJZ76snjxvmVsp1i4T9o6PeKRqNRqPx",
+	"4PozHveiv59Petf3F0fwbHIt3v/tQlz9L/3T1dXDc/oTvhv9Nb675JPPd8v+r+f98Pzoc/fd/afO8add",
+	"t5gLqKkkonfYnVdX66GpgaWCqs0UKGhI9I5gYYi+0H/9mFn5v/58n30KQ9tuMy5fF44J80EMKBG7akam",
+	"eQTykrogo5u4TGrY3mOBvE9EA8KMd2Y27I0SHKwJ6uv7R9rU5w7F8/NzG+vX+hS3c2XncjK+uJ5etPrt",
+	"bnut4kjzkCpNtJvpOw3e1r4F0l1SCCe05HadeX3b0MrgxZk3aHd1KTjBaq3J1LHhN/ydcFdv/1jnsxDO",
+	"YkjTJZ1wZcKAaANBg7QZXr5EkjwRgTNaaPLYdjf9JRMTAVKBQgJTbOtWuTkW7kh5t1wquzXPyAGR6h0P",
+	"N6ZzV/t58CdOzD1yylnnH7Ypt/jMyc6++2r//0tV3uB81w9kwoEXsFq/2/va0CehAezuZ4AuT6mwUCQE",
+	"Ng673a8G35aEmrAnzLSdZUUrkdEH4Pe+PfxRCqEkfyT6mwLUYGOgD7499AeGU7Xmgn42GYCECHAPUS6c",
+	"BpPhvwOTR8afWc4HQ4Sjf4cIPDDyKTG1eV1eRDzQld7QK9tafYxlVvaXjy8ffU+mMXScFUbDIq/nZZam",
+	"U75GdLjJMdk+e1+wuFrfuHUEqQieKmQvx2ujxAjkKUGc7Ncn8BOmEV5AF86aMEQY/J33DFWTIHyZoSB2",
+	"mqnsLuG3NVf1G4vfzdZ3s3Wg2fpt2g6TDMe5gldtSVK6q+g0JeckMTlck043d6TMza+aqNi+MJUKVrl1",
+	"awzPc/YRA93V4+dmRlf7wMjg4qLjfd5OLutXeHWXjNmq5tVig1y3H9WabMz9x50mJ7up+d/jIHW/NvRs",
+	"iw5Ruy/TtXrZ9LvZ+S/ylvqn3x6Te85BFjbIGgRofxM5O8hvyneyIl+1G2WLVTGBsvOFhi86KeCq+LzP",
+	"mgp1psMsYzMqiAu9YkQA28x6wRUdKu3NcyLBQdLdmFzoxpJyP5vO2xD4oEvDSL0nqnop2K98evMX9336",
+	"fGGDrOJopVt3KNMZX32V3kbTtgugbI3K37f82t/EePn47U1d3i/XEKoqXf5jxo2G3+3a9yjwFZbsvmZ4",
+	"ttuvTlyqme40ZNlAs+KSMvNForL5InB1KVAIEngiNlGccexIiEICqWWIA8tdztmn3ExT+w5zltd2vxu0",
+	"/bFqRqttzlvGyux+rAnpM1Z+t3Pf7dxvw841bNNS35koBBnsnV5cluxbw8QU335oGBfXzoohHd3W/eLv",
+	"Haf7vr+p6hd7cEm7+aoRXyJLjO9q9p9RMyPovz0lw7kAQbUt4VJSSOJm0lSo2f6gCLO8Cz1LHhjMii8w",
+	"QCtu6PIFzDYP8gDydf/VU3/wbz7Dc1Z+19HvOvoaHTVzy0trvcxr0NvPvxs7xC3VVWTtclpboW4DNLAf",
+	"qvgteg47t/OSt4gZO1NtHsAJbcN0uab247g4oeYrQy1dGyOilRWwOk99r76LK/uxCGijN184MbC0P9EE",
+	"JZW+/PEvAJwqvIL0UwPMK9fJb5/qjzVAJ8j/DwCWSHN57mUAAA==",
 }
 
 // GetSwagger returns the Swagger specification corresponding to the generated code
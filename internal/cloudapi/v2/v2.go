@@ -3,12 +3,15 @@ package v2
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -16,7 +19,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 
+	"github.com/osbuild/osbuild-composer/internal/auth"
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/osbuild/osbuild-composer/internal/distro"
@@ -35,8 +41,24 @@ type Server struct {
 	rpmMetadata rpmmd.RPMMD
 	distros     *distroregistry.Registry
 	awsBucket   string
+
+	// Whether manifests given by the user are built, and whether those
+	// may reference files of the host in their sources
+	customManifests          bool
+	allowManifestFileSources bool
+
+	// Rate limits compose previews, because each of them depsolves the
+	// packages of the compose
+	previewLimiter *rate.Limiter
 }
 
+// After a burst of composePreviewBurst compose previews, another one is
+// allowed every composePreviewInterval
+const (
+	composePreviewBurst    = 5
+	composePreviewInterval = 6 * time.Second
+)
+
 type apiHandlers struct {
 	server *Server
 }
@@ -49,10 +71,20 @@ func NewServer(workers *worker.Server, rpmMetadata rpmmd.RPMMD, distros *distror
 		rpmMetadata: rpmMetadata,
 		distros:     distros,
 		awsBucket:   bucket,
+
+		previewLimiter: rate.NewLimiter(rate.Every(composePreviewInterval), composePreviewBurst),
 	}
 	return server
 }
 
+// EnableCustomManifests enables building manifests given by the user, which
+// is disabled by default. Unless allowFileSources is set, manifests whose
+// sources reference files of the host are rejected.
+func (server *Server) EnableCustomManifests(allowFileSources bool) {
+	server.customManifests = true
+	server.allowManifestFileSources = allowFileSources
+}
+
 func (server *Server) Handler(path string) http.Handler {
 	e := echo.New()
 	e.Binder = binder{}
@@ -135,22 +167,80 @@ func (h *apiHandlers) GetError(ctx echo.Context, id string) error {
 	return ctx.JSON(http.StatusOK, apiError)
 }
 
-func (h *apiHandlers) PostCompose(ctx echo.Context) error {
-	var request ComposeRequest
-	err := ctx.Bind(&request)
+// depsolveFunc depsolves the package sets of an image for the architecture of
+// the distribution. Errors are HTTP errors.
+type depsolveFunc func(packageSets map[string]rpmmd.PackageSet, repos []rpmmd.RepoConfig, distribution distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error)
+
+// depsolveJob depsolves the package sets in a depsolve job and waits until a
+// worker finished it
+func (h *apiHandlers) depsolveJob(packageSets map[string]rpmmd.PackageSet, repos []rpmmd.RepoConfig, distribution distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
+	depsolveJobID, err := h.server.workers.EnqueueDepsolve(&worker.DepsolveJob{
+		PackageSets:      packageSets,
+		Repos:            repos,
+		ModulePlatformID: distribution.ModulePlatformID(),
+		Arch:             arch,
+		Releasever:       distribution.Releasever(),
+	})
 	if err != nil {
-		return err
+		return nil, HTTPErrorWithInternal(ErrorEnqueueingJob, err)
+	}
+
+	var depsolveResults worker.DepsolveJobResult
+	for {
+		status, _, err := h.server.workers.JobStatus(depsolveJobID, &depsolveResults)
+		if err != nil {
+			return nil, HTTPErrorWithInternal(ErrorGettingDepsolveJobStatus, err)
+		}
+		if status.Canceled {
+			return nil, HTTPErrorWithInternal(ErrorDepsolveJobCanceled, err)
+		}
+		if !status.Finished.IsZero() {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if depsolveResults.Error != "" {
+		if depsolveResults.ErrorType == worker.DepsolveErrorType {
+			return nil, HTTPError(ErrorDNFError)
+		}
+		return nil, HTTPErrorWithInternal(ErrorFailedToDepsolve, errors.New(depsolveResults.Error))
 	}
+	return depsolveResults.PackageSpecs, nil
+}
+
+// depsolveLocally depsolves the package sets in composer itself, without
+// queueing a job
+func (h *apiHandlers) depsolveLocally(packageSets map[string]rpmmd.PackageSet, repos []rpmmd.RepoConfig, distribution distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
+	packageSpecSets := make(map[string][]rpmmd.PackageSpec)
+	for name, packageSet := range packageSets {
+		packageSpecs, _, err := h.server.rpmMetadata.Depsolve(packageSet, repos, distribution.ModulePlatformID(), arch, distribution.Releasever())
+		if err != nil {
+			if _, ok := err.(*rpmmd.DNFError); ok {
+				return nil, HTTPErrorWithInternal(ErrorDNFError, err)
+			}
+			return nil, HTTPErrorWithInternal(ErrorFailedToDepsolve, err)
+		}
+		packageSpecSets[name] = packageSpecs
+	}
+	return packageSpecSets, nil
+}
+
+// composeManifest depsolves the package sets of the image request of the
+// compose request with depsolve and generates its manifest, without queueing
+// a build
+func (h *apiHandlers) composeManifest(request ComposeRequest, depsolve depsolveFunc) (distro.ImageType, distro.Manifest, error) {
+	ir := request.ImageRequest
 
 	distribution := h.server.distros.GetDistro(request.Distribution)
 	if distribution == nil {
-		return HTTPError(ErrorUnsupportedDistribution)
+		return nil, nil, HTTPError(ErrorUnsupportedDistribution)
 	}
 
 	var bp = blueprint.Blueprint{}
-	err = bp.Initialize()
+	err := bp.Initialize()
 	if err != nil {
-		return HTTPErrorWithInternal(ErrorFailedToInitializeBlueprint, err)
+		return nil, nil, HTTPErrorWithInternal(ErrorFailedToInitializeBlueprint, err)
 	}
 	if request.Customizations != nil && request.Customizations.Packages != nil {
 		for _, p := range *request.Customizations.Packages {
@@ -160,28 +250,36 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		}
 	}
 
-	var imageRequest struct {
-		manifest distro.Manifest
-		arch     string
-		exports  []string
-		target   *target.Target
-	}
-
-	// use the same seed for all images so we get the same IDs
-	bigSeed, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		return HTTPError(ErrorFailedToGenerateManifestSeed)
+	// the seed of the request derives the same UUIDs and build date every
+	// time, the compose ID does so for requests without a seed, so that
+	// rebuilds of a compose are reproducible. Other requests get a random
+	// seed and the current date.
+	var manifestSeed int64
+	buildDate := time.Now()
+	if ir.Seed != nil {
+		if *ir.Seed < 0 {
+			return nil, nil, HTTPError(ErrorInvalidSeed)
+		}
+		manifestSeed = *ir.Seed
+		buildDate = buildDateFromSeed(manifestSeed)
+	} else if ir.ComposeId != nil {
+		manifestSeed = seedFromComposeID(*ir.ComposeId)
+		buildDate = buildDateFromSeed(manifestSeed)
+	} else {
+		bigSeed, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+		if err != nil {
+			return nil, nil, HTTPError(ErrorFailedToGenerateManifestSeed)
+		}
+		manifestSeed = bigSeed.Int64()
 	}
-	manifestSeed := bigSeed.Int64()
 
-	ir := request.ImageRequest
 	arch, err := distribution.GetArch(ir.Architecture)
 	if err != nil {
-		return HTTPError(ErrorUnsupportedArchitecture)
+		return nil, nil, HTTPError(ErrorUnsupportedArchitecture)
 	}
 	imageType, err := arch.GetImageType(imageTypeFromApiImageType(ir.ImageType))
 	if err != nil {
-		return HTTPError(ErrorUnsupportedImageType)
+		return nil, nil, HTTPError(ErrorUnsupportedImageType)
 	}
 	repositories := make([]rpmmd.RepoConfig, len(ir.Repositories))
 	for j, repo := range ir.Repositories {
@@ -194,47 +292,25 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		} else if repo.Metalink != nil {
 			repositories[j].Metalink = *repo.Metalink
 		} else {
-			return HTTPError(ErrorInvalidRepository)
+			return nil, nil, HTTPError(ErrorInvalidRepository)
 		}
 	}
 
-	packageSets := imageType.PackageSets(bp)
-	depsolveJobID, err := h.server.workers.EnqueueDepsolve(&worker.DepsolveJob{
-		PackageSets:      packageSets,
-		Repos:            repositories,
-		ModulePlatformID: distribution.ModulePlatformID(),
-		Arch:             arch.Name(),
-		Releasever:       distribution.Releasever(),
-	})
+	pkgSpecSets, err := depsolve(imageType.PackageSets(bp), repositories, distribution, arch.Name())
 	if err != nil {
-		return HTTPErrorWithInternal(ErrorEnqueueingJob, err)
+		return nil, nil, err
 	}
 
-	var depsolveResults worker.DepsolveJobResult
-	for {
-		status, _, err := h.server.workers.JobStatus(depsolveJobID, &depsolveResults)
-		if err != nil {
-			return HTTPErrorWithInternal(ErrorGettingDepsolveJobStatus, err)
-		}
-		if status.Canceled {
-			return HTTPErrorWithInternal(ErrorDepsolveJobCanceled, err)
-		}
-		if !status.Finished.IsZero() {
-			break
-		}
-		time.Sleep(50 * time.Millisecond)
+	imageOptions := distro.ImageOptions{Size: imageType.Size(0), BuildDate: buildDate}
+	if ir.ComposeId != nil {
+		imageOptions.ComposeID = *ir.ComposeId
 	}
-
-	if depsolveResults.Error != "" {
-		if depsolveResults.ErrorType == worker.DepsolveErrorType {
-			return HTTPError(ErrorDNFError)
-		}
-		return HTTPErrorWithInternal(ErrorFailedToDepsolve, errors.New(depsolveResults.Error))
+	if ir.Qcow2Compression != nil {
+		imageOptions.Qcow2Compression = *ir.Qcow2Compression
+	}
+	if ir.SectorSize != nil {
+		imageOptions.SectorSize = uint64(*ir.SectorSize)
 	}
-
-	pkgSpecSets := depsolveResults.PackageSpecs
-
-	imageOptions := distro.ImageOptions{Size: imageType.Size(0)}
 	if request.Customizations != nil && request.Customizations.Subscription != nil {
 		imageOptions.Subscription = &distro.SubscriptionImageOptions{
 			Organization:  request.Customizations.Subscription.Organization,
@@ -250,20 +326,50 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 	if ostreeOptions == nil || ostreeOptions.Ref == nil {
 		imageOptions.OSTree = distro.OSTreeImageOptions{Ref: imageType.OSTreeRef()}
 	} else if !ostree.VerifyRef(*ostreeOptions.Ref) {
-		return HTTPError(ErrorInvalidOSTreeRef)
+		return nil, nil, HTTPError(ErrorInvalidOSTreeRef)
 	} else {
 		imageOptions.OSTree = distro.OSTreeImageOptions{Ref: *ostreeOptions.Ref}
 	}
 
+	mtls := ostreeMTLS(ostreeOptions)
+	if mtls != nil {
+		if ostreeOptions.Url == nil {
+			return nil, nil, HTTPError(ErrorInvalidOSTreeParams)
+		}
+		if err := mtls.Validate(); err != nil {
+			return nil, nil, HTTPErrorWithInternal(ErrorInvalidOSTreeParams, err)
+		}
+		imageOptions.OSTree.MTLS = true
+	}
+
 	var parent string
 	if ostreeOptions != nil && ostreeOptions.Url != nil {
 		imageOptions.OSTree.URL = *ostreeOptions.Url
-		parent, err = ostree.ResolveRef(imageOptions.OSTree.URL, imageOptions.OSTree.Ref)
+		parent, err = ostree.ResolveRef(imageOptions.OSTree.URL, imageOptions.OSTree.Ref, mtls)
 		if err != nil {
-			return HTTPErrorWithInternal(ErrorInvalidOSTreeRepo, err)
+			return nil, nil, HTTPErrorWithInternal(ErrorInvalidOSTreeRepo, err)
 		}
 		imageOptions.OSTree.Parent = parent
 	}
+	if ostreeOptions != nil && ostreeOptions.StaticDeltas != nil && *ostreeOptions.StaticDeltas {
+		// the delta is generated from the parent commit
+		if ostreeOptions.Url == nil {
+			return nil, nil, HTTPError(ErrorInvalidOSTreeParams)
+		}
+		imageOptions.OSTree.StaticDeltas = true
+	}
+	if ostreeOptions != nil && (ostreeOptions.GpgKeyid != nil || ostreeOptions.GpgKey != nil) {
+		// the worker signs the commit in commit archives after the build
+		if imageType.Filename() != "commit.tar" || (ostreeOptions.GpgKeyid != nil && ostreeOptions.GpgKey != nil) {
+			return nil, nil, HTTPError(ErrorInvalidOSTreeParams)
+		}
+		if ostreeOptions.GpgKeyid != nil && !ostree.VerifyGPGKeyID(*ostreeOptions.GpgKeyid) {
+			return nil, nil, HTTPError(ErrorInvalidOSTreeParams)
+		}
+		if ostreeOptions.GpgKey != nil && !ostree.IsGPGSecretKey(*ostreeOptions.GpgKey) {
+			return nil, nil, HTTPError(ErrorInvalidOSTreeParams)
+		}
+	}
 
 	// Set the blueprint customisation to take care of the user
 	var blueprintCustoms *blueprint.Customizations
@@ -291,12 +397,53 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 
 	manifest, err := imageType.Manifest(blueprintCustoms, imageOptions, repositories, pkgSpecSets, manifestSeed)
 	if err != nil {
-		return HTTPErrorWithInternal(ErrorFailedToMakeManifest, err)
+		return nil, nil, HTTPErrorWithInternal(ErrorFailedToMakeManifest, err)
+	}
+
+	return imageType, manifest, nil
+
+}
+
+func (h *apiHandlers) PostCompose(ctx echo.Context) error {
+	var request ComposeRequest
+	err := ctx.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	var imageRequest struct {
+		manifest        distro.Manifest
+		arch            string
+		exports         []string
+		target          *target.Target
+		imageType       string
+		ostreeTLSSecret string
+		ostreeSigning   *worker.OSTreeSigning
 	}
 
+	err = validateUploadOptions(request.ImageRequest)
+	if err != nil {
+		return err
+	}
+
+	imageType, manifest, err := h.composeManifest(request, h.depsolveJob)
+	if err != nil {
+		return err
+	}
+	ir := request.ImageRequest
+
 	imageRequest.manifest = manifest
-	imageRequest.arch = arch.Name()
+	imageRequest.arch = imageType.Arch().Name()
 	imageRequest.exports = imageType.Exports()
+	imageRequest.imageType = imageType.Name()
+	if mtls := ostreeMTLS(ir.Ostree); mtls != nil {
+		imageRequest.ostreeTLSSecret = mtls.Secret
+	}
+	if ir.Ostree != nil && ir.Ostree.GpgKeyid != nil {
+		imageRequest.ostreeSigning = &worker.OSTreeSigning{KeyID: *ir.Ostree.GpgKeyid}
+	} else if ir.Ostree != nil && ir.Ostree.GpgKey != nil {
+		imageRequest.ostreeSigning = &worker.OSTreeSigning{Key: *ir.Ostree.GpgKey}
+	}
 
 	/* oneOf is not supported by the openapi generator so marshal and unmarshal the uploadrequest based on the type */
 	switch ir.ImageType {
@@ -328,6 +475,8 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		imageRequest.target = t
 	case ImageTypes_edge_installer:
 		fallthrough
+	case ImageTypes_guest_image:
+		fallthrough
 	case ImageTypes_edge_commit:
 		var awsS3UploadOptions AWSS3UploadOptions
 		jsonUploadOptions, err := json.Marshal(ir.UploadOptions)
@@ -364,6 +513,10 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		if gcpUploadOptions.ShareWithAccounts != nil {
 			share = *gcpUploadOptions.ShareWithAccounts
 		}
+		var guestOSFeatures []string
+		if gcpUploadOptions.GuestOsFeatures != nil {
+			guestOSFeatures = *gcpUploadOptions.GuestOsFeatures
+		}
 
 		object := fmt.Sprintf("composer-api-%s", uuid.New().String())
 		t := target.NewGCPTarget(&target.GCPTargetOptions{
@@ -373,6 +526,7 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 			Bucket:            gcpUploadOptions.Bucket,
 			Object:            object,
 			ShareWithAccounts: share,
+			GuestOSFeatures:   guestOSFeatures,
 		})
 		// Import will fail if an image with this name already exists
 		if gcpUploadOptions.ImageName != nil {
@@ -392,13 +546,34 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		if err != nil {
 			return HTTPError(ErrorJSONUnMarshallingError)
 		}
-		t := target.NewAzureImageTarget(&target.AzureImageTargetOptions{
+		azureOptions := &target.AzureImageTargetOptions{
 			Filename:       imageType.Filename(),
 			TenantID:       azureUploadOptions.TenantId,
 			Location:       azureUploadOptions.Location,
 			SubscriptionID: azureUploadOptions.SubscriptionId,
 			ResourceGroup:  azureUploadOptions.ResourceGroup,
-		})
+		}
+		if g := azureUploadOptions.Gallery; g != nil {
+			gallery := &target.AzureGalleryOptions{
+				Name:            g.Name,
+				ImageDefinition: g.ImageDefinition,
+				Version:         g.Version,
+			}
+			if g.TargetRegions != nil {
+				for _, r := range *g.TargetRegions {
+					replicaCount := 1
+					if r.ReplicaCount != nil {
+						replicaCount = *r.ReplicaCount
+					}
+					gallery.TargetRegions = append(gallery.TargetRegions, target.AzureGalleryTargetRegion{
+						Name:         r.Name,
+						ReplicaCount: replicaCount,
+					})
+				}
+			}
+			azureOptions.Gallery = gallery
+		}
+		t := target.NewAzureImageTarget(azureOptions)
 
 		if azureUploadOptions.ImageName != nil {
 			t.ImageName = *azureUploadOptions.ImageName
@@ -407,21 +582,199 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 			t.ImageName = fmt.Sprintf("composer-api-%s", uuid.New().String())
 		}
 
+		imageRequest.target = t
+	case ImageTypes_oci:
+		var ociUploadOptions OCIUploadOptions
+		jsonUploadOptions, err := json.Marshal(ir.UploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONMarshallingError)
+		}
+		err = json.Unmarshal(jsonUploadOptions, &ociUploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONUnMarshallingError)
+		}
+
+		object := fmt.Sprintf("composer-api-%s", uuid.New().String())
+		ociOptions := &target.OCITargetOptions{
+			Filename:    imageType.Filename(),
+			Region:      ociUploadOptions.Region,
+			Compartment: ociUploadOptions.CompartmentId,
+			Bucket:      ociUploadOptions.Bucket,
+			Object:      object,
+		}
+		if ociUploadOptions.LaunchMode != nil {
+			ociOptions.LaunchMode = *ociUploadOptions.LaunchMode
+		}
+		t := target.NewOCITarget(ociOptions)
+		if ociUploadOptions.ImageName != nil {
+			t.ImageName = *ociUploadOptions.ImageName
+		} else {
+			t.ImageName = object
+		}
+
 		imageRequest.target = t
 	default:
 		return HTTPError(ErrorUnsupportedImageType)
 	}
 
 	id, err := h.server.workers.EnqueueOSBuild(imageRequest.arch, &worker.OSBuildJob{
-		Manifest: imageRequest.manifest,
-		Targets:  []*target.Target{imageRequest.target},
-		Exports:  imageRequest.exports,
+		Manifest:        imageRequest.manifest,
+		Targets:         []*target.Target{imageRequest.target},
+		Exports:         imageRequest.exports,
+		ImageType:       imageRequest.imageType,
+		OSTreeTLSSecret: imageRequest.ostreeTLSSecret,
+		OSTreeSigning:   imageRequest.ostreeSigning,
+	})
+	if err != nil {
+		return HTTPErrorWithInternal(ErrorEnqueueingJob, err)
+	}
+
+	logger := common.LoggerFromContext(ctx.Request().Context()).WithFields(logrus.Fields{
+		common.LogFieldComposeID: id.String(),
+		common.LogFieldJobID:     id.String(),
+		common.LogFieldJobType:   "osbuild",
+		common.LogFieldImageType: imageRequest.imageType,
+	})
+	if tenant := auth.TenantFromContext(ctx.Request().Context()); tenant != "" {
+		logger = logger.WithField(common.LogFieldTenant, tenant)
+	}
+	logger.Infof("Job ID %s enqueued for operationID %s", id, ctx.Get("operationID"))
+
+	return ctx.JSON(http.StatusCreated, &ComposeId{
+		ObjectReference: ObjectReference{
+			Href: "/api/image-builder-composer/v2/compose",
+			Id:   id.String(),
+			Kind: "ComposeId",
+		},
+		Id:       id.String(),
+		Warnings: h.logArchWarnings(logger, imageRequest.arch),
+	})
+}
+
+// archWarnings returns a warning if no worker builds images for arch. The
+// compose is queued anyway, because workers may join later.
+func (h *apiHandlers) archWarnings(arch string) *[]string {
+	if h.server.workers.HasWorkersForArch(arch) {
+		return nil
+	}
+	return &[]string{fmt.Sprintf("no worker is building images for %s, the compose waits until one is available", arch)}
+}
+
+// logArchWarnings returns the warnings of archWarnings and logs them
+func (h *apiHandlers) logArchWarnings(logger *logrus.Entry, arch string) *[]string {
+	warnings := h.archWarnings(arch)
+	if warnings != nil {
+		for _, warning := range *warnings {
+			logger.Warn(warning)
+		}
+	}
+	return warnings
+}
+
+// PostComposePreview returns the manifest of a compose request without
+// queueing a build or a depsolve job. The sources of the manifest are
+// summarized.
+func (h *apiHandlers) PostComposePreview(ctx echo.Context) error {
+	var request ComposeRequest
+	err := ctx.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	if !h.server.previewLimiter.Allow() {
+		return HTTPError(ErrorTooManyPreviews)
+	}
+
+	_, manifest, err := h.composeManifest(request, h.depsolveLocally)
+	if err != nil {
+		return err
+	}
+
+	summarized, err := manifest.SummarizeSources()
+	if err != nil {
+		return HTTPErrorWithInternal(ErrorJSONUnMarshallingError, err)
+	}
+	var preview ComposePreview
+	err = json.Unmarshal(summarized, &preview.Manifest)
+	if err != nil {
+		return HTTPErrorWithInternal(ErrorJSONUnMarshallingError, err)
+	}
+
+	return ctx.JSON(http.StatusOK, &preview)
+}
+
+// PostComposeManifest queues a compose that builds a manifest given by the
+// user, without depsolving or generating a manifest.
+func (h *apiHandlers) PostComposeManifest(ctx echo.Context) error {
+	if !h.server.customManifests {
+		return HTTPError(ErrorCustomManifestsDisabled)
+	}
+
+	var request ComposeManifestRequest
+	err := ctx.Bind(&request)
+	if err != nil {
+		return err
+	}
+
+	var arch distro.Arch
+	for _, name := range h.server.distros.List() {
+		arch, err = h.server.distros.GetDistro(name).GetArch(request.Architecture)
+		if err == nil {
+			break
+		}
+	}
+	if arch == nil {
+		return HTTPError(ErrorUnsupportedArchitecture)
+	}
+
+	manifest, err := json.Marshal(request.Manifest)
+	if err != nil {
+		return HTTPErrorWithInternal(ErrorJSONMarshallingError, err)
+	}
+	imageType, err := distro.NewCustomImageType(arch, manifest)
+	if err != nil {
+		return HTTPErrorWithInternal(ErrorInvalidManifest, err)
+	}
+	if !h.server.allowManifestFileSources {
+		if err := distro.CheckManifestSources(manifest); err != nil {
+			return HTTPErrorWithInternal(ErrorManifestFileSources, err)
+		}
+	}
+
+	var targets []*target.Target
+	if request.UploadOptions != nil {
+		key := fmt.Sprintf("composer-api-%s", uuid.New().String())
+		t := target.NewAWSS3Target(&target.AWSS3TargetOptions{
+			Filename: imageType.Filename(),
+			Region:   request.UploadOptions.Region,
+			Bucket:   h.server.awsBucket,
+			Key:      key,
+		})
+		t.ImageName = key
+		targets = append(targets, t)
+	}
+
+	id, err := h.server.workers.EnqueueOSBuild(arch.Name(), &worker.OSBuildJob{
+		Manifest:  manifest,
+		Targets:   targets,
+		ImageName: imageType.Filename(),
+		Exports:   imageType.Exports(),
+		ImageType: imageType.Name(),
 	})
 	if err != nil {
 		return HTTPErrorWithInternal(ErrorEnqueueingJob, err)
 	}
 
-	ctx.Logger().Infof("Job ID %s enqueued for operationID %s", id, ctx.Get("operationID"))
+	logger := common.LoggerFromContext(ctx.Request().Context()).WithFields(logrus.Fields{
+		common.LogFieldComposeID: id.String(),
+		common.LogFieldJobID:     id.String(),
+		common.LogFieldJobType:   "osbuild",
+		common.LogFieldImageType: imageType.Name(),
+	})
+	if tenant := auth.TenantFromContext(ctx.Request().Context()); tenant != "" {
+		logger = logger.WithField(common.LogFieldTenant, tenant)
+	}
+	logger.Infof("Job ID %s of a custom manifest enqueued for operationID %s", id, ctx.Get("operationID"))
 
 	return ctx.JSON(http.StatusCreated, &ComposeId{
 		ObjectReference: ObjectReference{
@@ -429,10 +782,63 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 			Id:   id.String(),
 			Kind: "ComposeId",
 		},
-		Id: id.String(),
+		Id:       id.String(),
+		Warnings: h.logArchWarnings(logger, arch.Name()),
 	})
 }
 
+// validateUploadOptions rejects upload options which the upload of the image
+// would fail with, before the image request is depsolved and queued
+func validateUploadOptions(ir ImageRequest) error {
+	switch ir.ImageType {
+	case ImageTypes_gcp:
+		var gcpUploadOptions GCPUploadOptions
+		jsonUploadOptions, err := json.Marshal(ir.UploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONMarshallingError)
+		}
+		err = json.Unmarshal(jsonUploadOptions, &gcpUploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONUnMarshallingError)
+		}
+
+		if gcpUploadOptions.GuestOsFeatures != nil {
+			err = target.ValidateGCPGuestOSFeatures(*gcpUploadOptions.GuestOsFeatures)
+			if err != nil {
+				return HTTPErrorWithInternal(ErrorInvalidGuestOSFeature, err)
+			}
+		}
+	case ImageTypes_azure:
+		var azureUploadOptions AzureUploadOptions
+		jsonUploadOptions, err := json.Marshal(ir.UploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONMarshallingError)
+		}
+		err = json.Unmarshal(jsonUploadOptions, &azureUploadOptions)
+		if err != nil {
+			return HTTPError(ErrorJSONUnMarshallingError)
+		}
+
+		if g := azureUploadOptions.Gallery; g != nil {
+			if g.Name == "" || g.ImageDefinition == "" || !azureGalleryVersionRegex.MatchString(g.Version) {
+				return HTTPError(ErrorInvalidAzureGallery)
+			}
+			if g.TargetRegions != nil {
+				for _, r := range *g.TargetRegions {
+					if r.Name == "" || (r.ReplicaCount != nil && *r.ReplicaCount < 1) {
+						return HTTPError(ErrorInvalidAzureGallery)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// azureGalleryVersionRegex matches the MAJOR.MINOR.PATCH versions of gallery
+// images
+var azureGalleryVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
 func imageTypeFromApiImageType(it ImageTypes) string {
 	switch it {
 	case ImageTypes_aws:
@@ -445,10 +851,50 @@ func imageTypeFromApiImageType(it ImageTypes) string {
 		return "rhel-edge-commit"
 	case ImageTypes_edge_installer:
 		return "rhel-edge-installer"
+	case ImageTypes_guest_image:
+		return "qcow2"
+	case ImageTypes_oci:
+		return "qcow2"
 	}
 	return ""
 }
 
+// buildDateFromSeed derives the build date of composes with a seed from it,
+// so that the images built from the same request and seed record the same
+// date. It is within the range of 32 bit timestamps.
+func buildDateFromSeed(seed int64) time.Time {
+	return time.Unix(seed%math.MaxInt32, 0).UTC()
+}
+
+// seedFromComposeID derives the seed of requests without one from the compose
+// ID of the request, which is not negative
+func seedFromComposeID(composeID string) int64 {
+	sum := sha256.Sum256([]byte(composeID))
+	return int64(binary.BigEndian.Uint64(sum[:8]) & math.MaxInt64)
+}
+
+// ostreeMTLS returns the mutual TLS credentials of the ostree options, or
+// nil if none are set
+func ostreeMTLS(o *OSTree) *ostree.MTLS {
+	if o == nil {
+		return nil
+	}
+	r := ostree.OSTreeRequest{}
+	if o.TlsClientCert != nil {
+		r.TLSClientCert = *o.TlsClientCert
+	}
+	if o.TlsClientKey != nil {
+		r.TLSClientKey = *o.TlsClientKey
+	}
+	if o.TlsCa != nil {
+		r.TLSCA = *o.TlsCa
+	}
+	if o.TlsSecret != nil {
+		r.TLSSecret = *o.TlsSecret
+	}
+	return r.MTLS()
+}
+
 func (h *apiHandlers) GetComposeStatus(ctx echo.Context, id string) error {
 	jobId, err := uuid.Parse(id)
 	if err != nil {
@@ -495,9 +941,20 @@ func (h *apiHandlers) GetComposeStatus(ctx echo.Context, id string) error {
 			}
 		case "org.osbuild.azure.image":
 			uploadType = UploadTypes_azure
-			gcpOptions := tr.Options.(*target.AzureImageTargetResultOptions)
-			uploadOptions = AzureUploadStatus{
-				ImageName: gcpOptions.ImageName,
+			azureOptions := tr.Options.(*target.AzureImageTargetResultOptions)
+			azureStatus := AzureUploadStatus{
+				ImageName: azureOptions.ImageName,
+			}
+			if azureOptions.GalleryImageVersionID != "" {
+				azureStatus.GalleryImageVersionId = &azureOptions.GalleryImageVersionID
+			}
+			uploadOptions = azureStatus
+		case "org.osbuild.oci":
+			uploadType = UploadTypes_oci
+			ociOptions := tr.Options.(*target.OCITargetResultOptions)
+			uploadOptions = OCIUploadStatus{
+				ImageId: ociOptions.ImageID,
+				Region:  ociOptions.Region,
 			}
 		default:
 			return HTTPError(ErrorUnknownUploadTarget)
@@ -510,6 +967,37 @@ func (h *apiHandlers) GetComposeStatus(ctx echo.Context, id string) error {
 		}
 	}
 
+	var artifacts *[]ArtifactChecksum
+	if len(result.Artifacts) > 0 {
+		artifacts = &[]ArtifactChecksum{}
+		for _, artifact := range result.Artifacts {
+			*artifacts = append(*artifacts, ArtifactChecksum{
+				Name:   artifact.Name,
+				Size:   artifact.Size,
+				Sha256: artifact.Sha256,
+			})
+		}
+	}
+
+	// retrying the compose fails the same way until the worker is
+	// configured, so tell the client apart from other failures
+	var composeError *Error
+	if result.OSTreeTLSSecretError != "" {
+		composeError = APIError(ErrorOSTreeTLSSecretMissing, nil, ctx)
+	}
+
+	// pending composes may wait for a worker of their architecture
+	var warnings *[]string
+	if status.Started.IsZero() && !status.Canceled {
+		jobType, _, _, err := h.server.workers.Job(jobId, nil)
+		if err != nil {
+			return HTTPErrorWithInternal(ErrorComposeNotFound, err)
+		}
+		if arch := strings.TrimPrefix(jobType, "osbuild:"); arch != jobType {
+			warnings = h.archWarnings(arch)
+		}
+	}
+
 	return ctx.JSON(http.StatusOK, ComposeStatus{
 		ObjectReference: ObjectReference{
 			Href: fmt.Sprintf("/api/image-builder-composer/v2/composes/%v", jobId),
@@ -519,7 +1007,10 @@ func (h *apiHandlers) GetComposeStatus(ctx echo.Context, id string) error {
 		ImageStatus: ImageStatus{
 			Status:       composeStatusFromJobStatus(status, &result),
 			UploadStatus: us,
+			Artifacts:    artifacts,
+			Error:        composeError,
 		},
+		Warnings: warnings,
 	})
 }
 
@@ -659,6 +1150,9 @@ func (h *apiHandlers) GetComposeMetadata(ctx echo.Context, id string) error {
 		}
 		resp.OstreeCommit = &commitMetadata.Compose.OSTreeCommit
 	}
+	if result.OSTreeSigningKeyID != "" {
+		resp.OstreeSigningKeyId = &result.OSTreeSigningKeyID
+	}
 
 	return ctx.JSON(200, resp)
 }
@@ -0,0 +1,14 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedFromComposeID(t *testing.T) {
+	seed := seedFromComposeID("RHEL-8.6.0-20220314.1")
+	assert.Equal(t, seed, seedFromComposeID("RHEL-8.6.0-20220314.1"))
+	assert.NotEqual(t, seed, seedFromComposeID("RHEL-8.6.0-20220314.2"))
+	assert.GreaterOrEqual(t, seed, int64(0))
+}
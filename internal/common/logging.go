@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Names of the structured logging fields shared by osbuild-composer and
+// osbuild-worker. Filtering the logs of both on one of these fields yields all
+// lines that belong to, for example, a single compose.
+const (
+	LogFieldOperationID = "operation_id"
+	LogFieldComposeID   = "compose_id"
+	LogFieldJobID       = "job_id"
+	LogFieldJobType     = "job_type"
+	LogFieldImageType   = "image_type"
+	LogFieldTenant      = "tenant"
+)
+
+type loggerContextKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying the given logger.
+func ContextWithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or one without any
+// fields that logs to the standard logger.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*logrus.Entry); ok {
+			return logger
+		}
+	}
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// ContextWithLogFields returns a copy of ctx carrying a logger with the given
+// fields added to the fields of the logger of ctx.
+func ContextWithLogFields(ctx context.Context, fields logrus.Fields) context.Context {
+	return ContextWithLogger(ctx, LoggerFromContext(ctx).WithFields(fields))
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	// field names whose values are never logged
+	secretFieldRegexp = regexp.MustCompile(`(?i)(password|passwd|passphrase|secret|token|activation_?key|access_?key|credentials)`)
+
+	// key: value and key=value pairs with a secret key, with quoted and
+	// unquoted values
+	secretKey                = `(?i)("?[a-z_]*(?:password|passwd|passphrase|secret|token|activation_?key|access_?key_?id|credentials)[a-z_]*"?\s*[:=]\s*`
+	secretQuotedPairRegexp   = regexp.MustCompile(secretKey + `")(?:[^"\\]|\\.)*"`)
+	secretUnquotedPairRegexp = regexp.MustCompile(secretKey + `)[^"\s,;&}\]][^\s,;&}\]]*`)
+
+	// bearer tokens of authorization headers
+	bearerRegexp = regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9\-._~+/]+=*`)
+
+	// crypt(3) password hashes
+	passwordHashRegexp = regexp.MustCompile(`\$(?:1|2[aby]|5|6|7|y)\$[^\s"',}]+`)
+
+	// ASCII armored GPG secret keys, e.g. of ostree commit signing, and PEM
+	// encoded private keys, e.g. of ostree TLS client certificates
+	privateKeyRegexp = regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`)
+)
+
+// ScrubSecrets replaces passwords, tokens, keys and password hashes in s.
+func ScrubSecrets(s string) string {
+	s = secretQuotedPairRegexp.ReplaceAllString(s, "${1}"+redacted+`"`)
+	s = secretUnquotedPairRegexp.ReplaceAllString(s, "${1}"+redacted)
+	s = bearerRegexp.ReplaceAllString(s, "${1}"+redacted)
+	s = privateKeyRegexp.ReplaceAllString(s, redacted)
+	return passwordHashRegexp.ReplaceAllString(s, redacted)
+}
+
+// SecretsHook is a logrus hook that scrubs secrets from the message and the
+// fields of all log entries before they are written.
+type SecretsHook struct{}
+
+func (SecretsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (SecretsHook) Fire(entry *logrus.Entry) error {
+	entry.Message = ScrubSecrets(entry.Message)
+	for key, value := range entry.Data {
+		if secretFieldRegexp.MatchString(key) {
+			entry.Data[key] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = ScrubSecrets(v)
+		case error:
+			if scrubbed := ScrubSecrets(v.Error()); scrubbed != v.Error() {
+				entry.Data[key] = scrubbed
+			}
+		}
+	}
+	return nil
+}
@@ -3,16 +3,23 @@ package common
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/segmentio/ksuid"
+	"github.com/sirupsen/logrus"
 )
 
 const OperationIDKey string = "operationID"
 
-// Adds a time-sortable globally unique identifier to an echo.Context if not already set
+// Adds a time-sortable globally unique identifier to an echo.Context if not already set.
+// The logger of the request context logs it in the operation_id field.
 func OperationIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		if c.Get(OperationIDKey) == nil {
 			c.Set(OperationIDKey, GenerateOperationID())
 		}
+		req := c.Request()
+		ctx := ContextWithLogFields(req.Context(), logrus.Fields{
+			LogFieldOperationID: c.Get(OperationIDKey),
+		})
+		c.SetRequest(req.WithContext(ctx))
 		return next(c)
 	}
 }
@@ -0,0 +1,287 @@
+// Package container resolves container image references to pinned digests
+// by querying the registries they are hosted on.
+package container
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRegistry is the registry of references without a domain.
+	DefaultRegistry = "docker.io"
+	// DefaultTag is the tag of references without a tag or digest.
+	DefaultTag = "latest"
+
+	// the API endpoint of DefaultRegistry
+	defaultRegistryHost = "registry-1.docker.io"
+
+	// timeout of the requests to registries and their token services
+	requestTimeout = 30 * time.Second
+)
+
+var (
+	repositoryRE = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRE        = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRE     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// manifest media types accepted from the registry, most specific last
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// A Reference is a parsed container image reference of the form
+// [registry/]repository[:tag][@digest].
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference. A reference without a registry
+// refers to DefaultRegistry and one without a tag or digest to DefaultTag.
+func ParseReference(ref string) (Reference, error) {
+	var r Reference
+	rest := ref
+
+	if i := strings.Index(rest, "@"); i >= 0 {
+		r.Digest = rest[i+1:]
+		rest = rest[:i]
+		if !digestRE.MatchString(r.Digest) {
+			return Reference{}, fmt.Errorf("container reference %q has an invalid digest", ref)
+		}
+	}
+
+	if i := strings.Index(rest, "/"); i >= 0 {
+		domain := rest[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			r.Registry = domain
+			rest = rest[i+1:]
+		}
+	}
+	if r.Registry == "" {
+		r.Registry = DefaultRegistry
+	}
+
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		r.Tag = rest[i+1:]
+		rest = rest[:i]
+		if !tagRE.MatchString(r.Tag) {
+			return Reference{}, fmt.Errorf("container reference %q has an invalid tag", ref)
+		}
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = DefaultTag
+	}
+
+	r.Repository = rest
+	if r.Registry == DefaultRegistry && !strings.Contains(r.Repository, "/") {
+		r.Repository = "library/" + r.Repository
+	}
+	if !repositoryRE.MatchString(r.Repository) {
+		return Reference{}, fmt.Errorf("container reference %q has an invalid repository name", ref)
+	}
+
+	return r, nil
+}
+
+// Name returns the reference without tag and digest.
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the fully qualified reference.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// A Spec is a container image pinned to the digest of its manifest.
+type Spec struct {
+	// Source is the image name, without tag or digest, the image is
+	// pulled from.
+	Source string
+	// Digest of the image manifest.
+	Digest string
+	// LocalName is the name the image is stored under in the image.
+	LocalName string
+	TLSVerify *bool
+}
+
+// Resolve pins the reference ref to the digest of its manifest. References
+// that already contain a digest are not looked up. Registries are always
+// accessed over https, tlsVerify only controls whether their certificates
+// are verified.
+func Resolve(ref string, tlsVerify bool) (string, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	return resolver{client: newClient(tlsVerify), scheme: "https"}.resolve(r)
+}
+
+func newClient(tlsVerify bool) *http.Client {
+	client := &http.Client{Timeout: requestTimeout}
+	if !tlsVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{
+			// the certificate verification was disabled for the image
+			InsecureSkipVerify: true,
+		}
+		client.Transport = transport
+	}
+	return client
+}
+
+type resolver struct {
+	client *http.Client
+	scheme string
+}
+
+func (res resolver) resolve(r Reference) (string, error) {
+	if r.Digest != "" {
+		return r.Digest, nil
+	}
+
+	host := r.Registry
+	if host == DefaultRegistry {
+		host = defaultRegistryHost
+	}
+	u := url.URL{
+		Scheme: res.scheme,
+		Host:   host,
+		Path:   "/v2/" + r.Repository + "/manifests/" + r.Tag,
+	}
+
+	resp, err := res.get(u.String(), "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		token, err := res.token(resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("cannot authenticate to registry %q: %v", r.Registry, err)
+		}
+		resp, err = res.get(u.String(), token)
+		if err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %q returned status %s for %q%s", r.Registry, resp.Status, r.String(), registryErrors(body))
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	if !digestRE.MatchString(digest) {
+		return "", fmt.Errorf("registry %q returned invalid digest %q for %q", r.Registry, digest, r.String())
+	}
+	return digest, nil
+}
+
+func (res resolver) get(u, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, mt := range manifestMediaTypes {
+		req.Header.Add("Accept", mt)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return res.client.Do(req)
+}
+
+// token requests an anonymous bearer token as described by the challenge of
+// the registry.
+func (res resolver) token(challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	params := map[string]string{}
+	for _, m := range regexp.MustCompile(`(\w+)="([^"]*)"`).FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid authentication realm %q", params["realm"])
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	resp, err := res.client.Get(realm.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service returned status %s", resp.Status)
+	}
+
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("token service returned an invalid response: %v", err)
+	}
+	if t.Token == "" {
+		t.Token = t.AccessToken
+	}
+	if t.Token == "" {
+		return "", fmt.Errorf("token service returned no token")
+	}
+	return t.Token, nil
+}
+
+// registryErrors formats the error messages of a registry error response.
+func registryErrors(body []byte) string {
+	var resp struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Errors) == 0 {
+		return ""
+	}
+	msgs := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		msgs = append(msgs, e.Code+": "+e.Message)
+	}
+	return ": " + strings.Join(msgs, ", ")
+}
@@ -0,0 +1,149 @@
+package container
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + fmt.Sprintf("%x", sha256.Sum256([]byte("manifest")))
+
+	validCases := map[string]Reference{
+		"fedora":                         {"docker.io", "library/fedora", "latest", ""},
+		"fedora:35":                      {"docker.io", "library/fedora", "35", ""},
+		"quay.io/fedora/fedora":          {"quay.io", "fedora/fedora", "latest", ""},
+		"localhost:5000/app:v1.2":        {"localhost:5000", "app", "v1.2", ""},
+		"registry.example.com/a/b/c:tag": {"registry.example.com", "a/b/c", "tag", ""},
+		"quay.io/app@" + digest:          {"quay.io", "app", "", digest},
+		"quay.io/app:v1@" + digest:       {"quay.io", "app", "v1", digest},
+	}
+	for in, expected := range validCases {
+		r, err := ParseReference(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, r, in)
+	}
+
+	errCases := []string{
+		"",
+		"quay.io/",
+		"Fedora",
+		"quay.io/app:",
+		"quay.io/app:-tag",
+		"quay.io/app@sha256:abc",
+		"quay.io/app@md5:" + digest[7:],
+	}
+	for _, in := range errCases {
+		_, err := ParseReference(in)
+		assert.Error(t, err, in)
+	}
+
+	r, err := ParseReference("fedora:35")
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io/library/fedora", r.Name())
+	assert.Equal(t, "docker.io/library/fedora:35", r.String())
+}
+
+func TestResolve(t *testing.T) {
+	manifest := `{"schemaVersion": 2}`
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest)))
+	headerDigest := "sha256:" + fmt.Sprintf("%x", sha256.Sum256([]byte("header")))
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/v2/public/manifests/v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", headerDigest)
+		fmt.Fprint(w, manifest)
+	})
+	handler.HandleFunc("/v2/nodigest/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, manifest)
+	})
+	handler.HandleFunc("/v2/private/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry",scope="repository:private:pull"`, r.Host))
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", headerDigest)
+		fmt.Fprint(w, manifest)
+	})
+	handler.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:private:pull" || r.URL.Query().Get("service") != "registry" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"token": "secret-token"}`)
+	})
+	handler.HandleFunc("/v2/missing/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors": [{"code": "MANIFEST_UNKNOWN", "message": "manifest unknown"}]}`)
+	})
+	handler.HandleFunc("/v2/baddigest/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", "not-a-digest")
+		fmt.Fprint(w, manifest)
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	registry := u.Host
+
+	res := resolver{client: srv.Client(), scheme: "http"}
+	resolve := func(ref string) (string, error) {
+		r, err := ParseReference(ref)
+		require.NoError(t, err)
+		return res.resolve(r)
+	}
+
+	validCases := map[string]string{
+		registry + "/public:v1":                   headerDigest,
+		registry + "/nodigest":                    manifestDigest,
+		registry + "/private":                     headerDigest,
+		registry + "/missing@" + manifestDigest:   manifestDigest,
+		registry + "/public:v1@" + manifestDigest: manifestDigest,
+	}
+	for in, expected := range validCases {
+		digest, err := resolve(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, digest, in)
+	}
+
+	errCases := map[string]string{
+		registry + "/missing":   fmt.Sprintf("registry %q returned status 404 Not Found for %q: MANIFEST_UNKNOWN: manifest unknown", registry, registry+"/missing:latest"),
+		registry + "/public":    fmt.Sprintf("registry %q returned status 404 Not Found for %q", registry, registry+"/public:latest"),
+		registry + "/baddigest": fmt.Sprintf("registry %q returned invalid digest \"not-a-digest\" for %q", registry, registry+"/baddigest:latest"),
+	}
+	for in, expected := range errCases {
+		_, err := resolve(in)
+		assert.EqualError(t, err, expected, in)
+	}
+}
+
+func TestResolveTLSVerify(t *testing.T) {
+	manifest := `{"schemaVersion": 2}`
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, manifest)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	ref := u.Host + "/app:v1"
+
+	// the certificate of the test server isn't trusted
+	_, err = Resolve(ref, true)
+	assert.Error(t, err)
+
+	// registries without verified certificates are still accessed over TLS
+	digest, err := Resolve(ref, false)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest))), digest)
+
+	assert.Equal(t, requestTimeout, newClient(true).Timeout)
+	assert.Equal(t, requestTimeout, newClient(false).Timeout)
+}
@@ -2,23 +2,107 @@ package crypt
 
 import (
 	"crypto/rand"
+	"fmt"
 	"math/big"
 	"strings"
 )
 
+// Method is a password hashing method supported by Crypt.
+type Method string
+
+const (
+	MethodSHA512   Method = "sha512"
+	MethodYescrypt Method = "yescrypt"
+)
+
+const (
+	// Limits of the number of rounds accepted by the SHA512 method, as
+	// defined by the SHA-crypt specification.
+	SHA512MinRounds = 1000
+	SHA512MaxRounds = 999999999
+)
+
+// Options tune the hashing method used by Crypt.
+type Options struct {
+	// Number of rounds used by the SHA512 method. Zero selects the default
+	// of the system's crypt implementation (5000).
+	Rounds int
+}
+
+// Crypt encrypts the given password with the given method and a random salt.
+// A nil options value selects the defaults of the method.
+//
+// Note that this function is not deterministic.
+func Crypt(method Method, phrase string, options *Options) (string, error) {
+	var rounds int
+	if options != nil {
+		rounds = options.Rounds
+	}
+
+	switch method {
+	case MethodSHA512:
+		return CryptSHA512WithRounds(phrase, rounds)
+	case MethodYescrypt:
+		if rounds != 0 {
+			return "", fmt.Errorf("the number of rounds can't be set for the %q method", method)
+		}
+		return CryptYescrypt(phrase)
+	default:
+		return "", fmt.Errorf("unsupported password hashing method %q", method)
+	}
+}
+
 // CryptSHA512 encrypts the given password with SHA512 and a random salt.
 //
 // Note that this function is not deterministic.
 func CryptSHA512(phrase string) (string, error) {
+	return CryptSHA512WithRounds(phrase, 0)
+}
+
+// CryptSHA512WithRounds encrypts the given password with SHA512, a random
+// salt and the given number of rounds. Zero rounds selects the default of the
+// system's crypt implementation and omits the setting from the hash.
+//
+// Note that this function is not deterministic.
+func CryptSHA512WithRounds(phrase string, rounds int) (string, error) {
 	const SHA512SaltLength = 16
 
+	if rounds != 0 && (rounds < SHA512MinRounds || rounds > SHA512MaxRounds) {
+		return "", fmt.Errorf("the number of SHA512 rounds must be between %d and %d", SHA512MinRounds, SHA512MaxRounds)
+	}
+
 	salt, err := genSalt(SHA512SaltLength)
 
 	if err != nil {
-		return "", nil
+		return "", err
+	}
+
+	hashSettings := "$6$"
+	if rounds != 0 {
+		hashSettings += fmt.Sprintf("rounds=%d$", rounds)
+	}
+	hashSettings += salt
+	return crypt(phrase, hashSettings)
+}
+
+// CryptYescrypt encrypts the given password with yescrypt, using the default
+// cost parameters of libxcrypt, and a random salt.
+//
+// Note that this function is not deterministic.
+func CryptYescrypt(phrase string) (string, error) {
+	// 16 characters of the crypt base64 alphabet encode exactly 12 bytes,
+	// which is a valid yescrypt salt
+	const YescryptSaltLength = 16
+	// the default yescrypt parameters of libxcrypt's crypt_gensalt()
+	const YescryptParams = "j9T"
+
+	salt, err := genSalt(YescryptSaltLength)
+
+	if err != nil {
+		return "", err
 	}
 
-	hashSettings := "$6$" + salt
+	hashSettings := "$y$" + YescryptParams + "$" + salt
 	return crypt(phrase, hashSettings)
 }
 
@@ -41,13 +125,14 @@ func genSalt(length int) (string, error) {
 // PasswordIsCrypted returns true if the password appears to be an encrypted
 // one, according to a very simple heuristic.
 //
-// Any string starting with one of $2$, $6$ or $5$ is considered to be
-// encrypted. Any other string is consdirede to be unencrypted.
+// Any string starting with one of $2b$, $5$, $6$, $7$ or $y$ is considered
+// to be encrypted. Any other string is consdirede to be unencrypted.
 //
-// This functionality is taken from pylorax.
+// This functionality is taken from pylorax and extended with the scrypt and
+// yescrypt prefixes supported by libxcrypt.
 func PasswordIsCrypted(s string) bool {
 	// taken from lorax src: src/pylorax/api/compose.py:533
-	prefixes := [...]string{"$2b$", "$6$", "$5$"}
+	prefixes := [...]string{"$2b$", "$6$", "$5$", "$7$", "$y$"}
 
 	for _, prefix := range prefixes {
 		if strings.HasPrefix(s, prefix) {
@@ -3,9 +3,11 @@
 package crypt
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_crypt_PasswordIsCrypted(t *testing.T) {
@@ -30,7 +32,11 @@ func Test_crypt_PasswordIsCrypted(t *testing.T) {
 		}, {
 			name:     "scrypt",
 			password: "$7$123456789012345", //not actual hash output from scrypt
-			want:     false,
+			want:     true,
+		}, {
+			name:     "yescrypt",
+			password: "$y$j9T$abcdefghijklmnop$7asOTx5b6Exfl3myM6K0pLBn.I2hsEvu7G0F7NMfaO.",
+			want:     true,
 		}, {
 			name:     "plain",
 			password: "password",
@@ -62,3 +68,89 @@ func TestGenSalt(t *testing.T) {
 	retSaltSecond, _ := genSalt(length)
 	assert.NotEqual(t, retSaltFirst, retSaltSecond)
 }
+
+func Test_crypt_KnownVectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		settings string
+		want     string
+	}{
+		{
+			// test vector from the SHA-crypt specification
+			name:     "sha512-rounds",
+			password: "Hello world!",
+			settings: "$6$rounds=10000$saltstringsaltst",
+			want:     "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		}, {
+			name:     "sha512",
+			password: "password",
+			settings: "$6$1234567890123456",
+			want:     "$6$1234567890123456$YfUD.j5zIFtfV6VgikPof2dzCCCZwL2YDraBX4HXi.J7iNq24667epYUCZGxExqOmHTnPWybzfYaynT29vKXJ/",
+		}, {
+			name:     "yescrypt",
+			password: "password",
+			settings: "$y$j9T$abcdefghijklmnop",
+			want:     "$y$j9T$abcdefghijklmnop$7asOTx5b6Exfl3myM6K0pLBn.I2hsEvu7G0F7NMfaO.",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := crypt(test.password, test.settings)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func Test_crypt_Methods(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  Method
+		options *Options
+		prefix  string
+	}{
+		{
+			name:   "sha512-default",
+			method: MethodSHA512,
+			prefix: "$6$",
+		}, {
+			name:    "sha512-rounds",
+			method:  MethodSHA512,
+			options: &Options{Rounds: 65536},
+			prefix:  "$6$rounds=65536$",
+		}, {
+			name:   "yescrypt",
+			method: MethodYescrypt,
+			prefix: "$y$j9T$",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := Crypt(test.method, "password", test.options)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hash, test.prefix), "unexpected hash format: %s", hash)
+			assert.True(t, PasswordIsCrypted(hash))
+
+			// the hash must verify the password it was created from
+			settings := hash[:strings.LastIndex(hash, "$")]
+			verified, err := crypt("password", settings)
+			require.NoError(t, err)
+			assert.Equal(t, hash, verified)
+		})
+	}
+}
+
+func Test_crypt_InvalidOptions(t *testing.T) {
+	_, err := Crypt(MethodSHA512, "password", &Options{Rounds: SHA512MinRounds - 1})
+	assert.Error(t, err)
+
+	_, err = Crypt(MethodSHA512, "password", &Options{Rounds: SHA512MaxRounds + 1})
+	assert.Error(t, err)
+
+	_, err = Crypt(MethodYescrypt, "password", &Options{Rounds: 10000})
+	assert.Error(t, err)
+
+	_, err = Crypt("md5", "password", nil)
+	assert.EqualError(t, err, "unsupported password hashing method \"md5\"")
+}
@@ -0,0 +1,42 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// Defaults of grub2-mkpasswd-pbkdf2
+	grub2PBKDF2Iterations = 10000
+	grub2PBKDF2SaltLength = 64
+	grub2PBKDF2KeyLength  = 64
+
+	grub2PBKDF2Prefix = "grub.pbkdf2.sha512."
+)
+
+// CryptGRUB2PBKDF2 hashes the given password for the password_pbkdf2 command
+// of GRUB, the same way grub2-mkpasswd-pbkdf2 does, with a random salt.
+//
+// Note that this function is not deterministic.
+func CryptGRUB2PBKDF2(phrase string) (string, error) {
+	salt := make([]byte, grub2PBKDF2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return grub2PBKDF2(phrase, salt, grub2PBKDF2Iterations), nil
+}
+
+func grub2PBKDF2(phrase string, salt []byte, iterations int) string {
+	key := pbkdf2.Key([]byte(phrase), salt, iterations, grub2PBKDF2KeyLength, sha512.New)
+	return fmt.Sprintf("%s%d.%X.%X", grub2PBKDF2Prefix, iterations, salt, key)
+}
+
+// PasswordIsGRUB2PBKDF2 returns true if the password appears to be hashed for
+// the password_pbkdf2 command of GRUB already.
+func PasswordIsGRUB2PBKDF2(s string) bool {
+	return strings.HasPrefix(s, grub2PBKDF2Prefix)
+}
@@ -0,0 +1,33 @@
+package crypt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRUB2PBKDF2(t *testing.T) {
+	// same result as the PBKDF2-HMAC-SHA512 implementation of Python's hashlib
+	assert.Equal(t,
+		"grub.pbkdf2.sha512.1000.73616C7473616C7473616C7473616C74.EF5E6BA88AF97573953E9061AAAB2E825D37EF34F96D6253598999B4870AF210678AC2A9C1F63B92892FC230EB347A87845E743DBECC0FA1EF909C220D0C38C3",
+		grub2PBKDF2("password", []byte("saltsaltsaltsalt"), 1000))
+}
+
+func TestCryptGRUB2PBKDF2(t *testing.T) {
+	first, err := CryptGRUB2PBKDF2("password")
+	require.NoError(t, err)
+	second, err := CryptGRUB2PBKDF2("password")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	fields := strings.Split(first, ".")
+	require.Len(t, fields, 6)
+	assert.Equal(t, []string{"grub", "pbkdf2", "sha512", "10000"}, fields[:4])
+	assert.Len(t, fields[4], 2*64)
+	assert.Len(t, fields[5], 2*64)
+	assert.NotContains(t, first, "password")
+	assert.True(t, PasswordIsGRUB2PBKDF2(first))
+	assert.False(t, PasswordIsGRUB2PBKDF2("$6$1234567890123456$YfUD"))
+}
@@ -0,0 +1,131 @@
+package disk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
+)
+
+// BtrfsSubvolume is a subvolume of a btrfs filesystem, which is created in
+// the top-level volume and mounted with the subvol= mount option.
+type BtrfsSubvolume struct {
+	// Name of the subvolume, relative to the top-level volume
+	Name       string
+	Mountpoint string
+}
+
+// Subvolume names end up in the subvol= mount options of fstab and the
+// kernel command line and must not contain separators of either
+var btrfsSubvolumeNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// MountOption returns the mount option that mounts the subvolume.
+func (sv BtrfsSubvolume) MountOption() string {
+	return "subvol=" + sv.Name
+}
+
+// RootSubvolume returns the btrfs subvolume mounted at /, or nil if the root
+// filesystem isn't mounted from a subvolume.
+func (pt PartitionTable) RootSubvolume() *BtrfsSubvolume {
+	rootFilesystem := pt.RootFilesystem()
+	if rootFilesystem == nil {
+		return nil
+	}
+	for _, sv := range rootFilesystem.Subvolumes {
+		if sv.Mountpoint == "/" {
+			return &sv
+		}
+	}
+	return nil
+}
+
+type fstabMount struct {
+	path    string
+	options string
+}
+
+// fstabMounts returns the paths the filesystem is mounted at, which are the
+// mountpoints of its subvolumes if it has any, and the mount options of each
+// of them. Swap partitions aren't mounted and their path is "none".
+func (fs *Filesystem) fstabMounts() []fstabMount {
+	if fs.Type == SwapFilesystemType {
+		return []fstabMount{{"none", fs.FSTabOptions}}
+	}
+	if len(fs.Subvolumes) == 0 {
+		return []fstabMount{{fs.Mountpoint, fs.FSTabOptions}}
+	}
+
+	mounts := make([]fstabMount, 0, len(fs.Subvolumes))
+	for _, sv := range fs.Subvolumes {
+		subvolumeFS := Filesystem{FSTabOptions: fs.FSTabOptions}
+		subvolumeFS.SetFSTabOption("subvol", sv.Name)
+		mounts = append(mounts, fstabMount{sv.Mountpoint, subvolumeFS.FSTabOptions})
+	}
+	return mounts
+}
+
+// ValidateSubvolumes returns an error if the subvolumes of the filesystem
+// customization m can't be created. Subvolumes need a btrfs filesystem with
+// one of them mounted at the mountpoint of the filesystem, and their names
+// and mountpoints must be unique.
+func ValidateSubvolumes(m blueprint.FilesystemCustomization) error {
+	if len(m.Subvolumes) == 0 {
+		return nil
+	}
+	if m.FSType != "btrfs" {
+		return fmt.Errorf("subvolumes of %s require the btrfs filesystem type", m.Mountpoint)
+	}
+
+	names := make(map[string]bool, len(m.Subvolumes))
+	mountpoints := make(map[string]bool, len(m.Subvolumes))
+	for _, sv := range m.Subvolumes {
+		if !btrfsSubvolumeNameRegex.MatchString(sv.Name) || sv.Name == "." || sv.Name == ".." {
+			return fmt.Errorf("invalid name %q of a subvolume of %s", sv.Name, m.Mountpoint)
+		}
+		if names[sv.Name] {
+			return fmt.Errorf("duplicate subvolume %q of %s", sv.Name, m.Mountpoint)
+		}
+		names[sv.Name] = true
+
+		if sv.Mountpoint != m.Mountpoint && !strings.HasPrefix(sv.Mountpoint, strings.TrimSuffix(m.Mountpoint, "/")+"/") {
+			return fmt.Errorf("subvolume %q of %s can't be mounted outside of it at %s", sv.Name, m.Mountpoint, sv.Mountpoint)
+		}
+		if mountpoints[sv.Mountpoint] {
+			return fmt.Errorf("duplicate subvolume mountpoint %s of %s", sv.Mountpoint, m.Mountpoint)
+		}
+		mountpoints[sv.Mountpoint] = true
+	}
+
+	if !mountpoints[m.Mountpoint] {
+		return fmt.Errorf("no subvolume of %s is mounted at %s", m.Mountpoint, m.Mountpoint)
+	}
+	return nil
+}
+
+// ValidateSubvolumeNesting returns an error if the mountpoint of a filesystem
+// other than a btrfs subvolume is below the mountpoint of a subvolume.
+// Subvolumes mounted at / are the exception, as every mountpoint is below
+// them.
+func ValidateSubvolumeNesting(mountpoints []blueprint.FilesystemCustomization) error {
+	var subvolumeMountpoints []string
+	for _, m := range mountpoints {
+		for _, sv := range m.Subvolumes {
+			if sv.Mountpoint != "/" {
+				subvolumeMountpoints = append(subvolumeMountpoints, sv.Mountpoint)
+			}
+		}
+	}
+
+	for _, m := range mountpoints {
+		if len(m.Subvolumes) > 0 {
+			continue
+		}
+		for _, mountpoint := range subvolumeMountpoints {
+			if m.Mountpoint == mountpoint || strings.HasPrefix(m.Mountpoint, mountpoint+"/") {
+				return fmt.Errorf("mountpoint %s can't be nested in the btrfs subvolume mounted at %s", m.Mountpoint, mountpoint)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package disk_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePartitionTable_BtrfsSubvolumes(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/",
+			FSType:     "btrfs",
+			Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+				{Name: "root", Mountpoint: "/"},
+				{Name: "home", Mountpoint: "/home"},
+			},
+		},
+		{
+			Mountpoint: "/data",
+			FSType:     "btrfs",
+			MinSize:    1073741824,
+		},
+	}
+	pt := disk.CreatePartitionTable(mountpoints, 0, base, rand.New(rand.NewSource(0)))
+
+	root := pt.RootPartition()
+	require.NotNil(t, root)
+	assert.Equal(t, "btrfs", root.Filesystem.Type)
+	assert.Equal(t, []disk.BtrfsSubvolume{{Name: "root", Mountpoint: "/"}, {Name: "home", Mountpoint: "/home"}}, root.Filesystem.Subvolumes)
+	assert.Equal(t, &disk.BtrfsSubvolume{Name: "root", Mountpoint: "/"}, pt.RootSubvolume())
+	assert.Equal(t, "btrfs", pt.FilesystemByMountpoint("/data").Type)
+	assert.Empty(t, pt.FilesystemByMountpoint("/data").Subvolumes)
+
+	// a /boot partition is added for GRUB
+	boot := pt.BootPartition()
+	require.NotNil(t, boot)
+	assert.Equal(t, "xfs", boot.Filesystem.Type)
+
+	options := make(map[string]string)
+	for _, entry := range pt.FSTabStageOptionsV2().FileSystems {
+		options[entry.Path] = entry.Options
+		if entry.Path == "/" || entry.Path == "/home" {
+			assert.Equal(t, root.Filesystem.UUID, entry.UUID)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"/":     "defaults,subvol=root",
+		"/home": "defaults,subvol=home",
+		"/data": "defaults",
+		"/boot": "defaults",
+	}, options)
+
+	// the base partition table is not modified
+	assert.Equal(t, "xfs", base.Partitions[0].Filesystem.Type)
+	assert.Empty(t, base.Partitions[0].Filesystem.Subvolumes)
+	assert.Nil(t, base.RootSubvolume())
+}
+
+func TestValidateSubvolumes(t *testing.T) {
+	subvolumes := []blueprint.BtrfsSubvolumeCustomization{
+		{Name: "data", Mountpoint: "/data"},
+		{Name: "snapshots", Mountpoint: "/data/.snapshots"},
+	}
+	assert.NoError(t, disk.ValidateSubvolumes(blueprint.FilesystemCustomization{Mountpoint: "/data"}))
+	assert.NoError(t, disk.ValidateSubvolumes(blueprint.FilesystemCustomization{Mountpoint: "/data", FSType: "btrfs", Subvolumes: subvolumes}))
+	assert.EqualError(t,
+		disk.ValidateSubvolumes(blueprint.FilesystemCustomization{Mountpoint: "/data", FSType: "btrfs", Subvolumes: subvolumes[1:]}),
+		"no subvolume of /data is mounted at /data")
+	assert.EqualError(t,
+		disk.ValidateSubvolumes(blueprint.FilesystemCustomization{Mountpoint: "/data", FSType: "btrfs", Subvolumes: append(subvolumes, blueprint.BtrfsSubvolumeCustomization{Name: "..", Mountpoint: "/data/up"})}),
+		`invalid name ".." of a subvolume of /data`)
+	assert.EqualError(t,
+		disk.ValidateSubvolumes(blueprint.FilesystemCustomization{Mountpoint: "/data", FSType: "btrfs", Subvolumes: append(subvolumes, blueprint.BtrfsSubvolumeCustomization{Name: "other", Mountpoint: "/data"})}),
+		"duplicate subvolume mountpoint /data of /data")
+}
+
+func TestValidateSubvolumeNesting(t *testing.T) {
+	root := blueprint.FilesystemCustomization{
+		Mountpoint: "/",
+		FSType:     "btrfs",
+		Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+			{Name: "root", Mountpoint: "/"},
+			{Name: "home", Mountpoint: "/home"},
+		},
+	}
+	assert.NoError(t, disk.ValidateSubvolumeNesting([]blueprint.FilesystemCustomization{root, {Mountpoint: "/var"}}))
+	assert.NoError(t, disk.ValidateSubvolumeNesting([]blueprint.FilesystemCustomization{root, {Mountpoint: "/homework"}}))
+	assert.EqualError(t,
+		disk.ValidateSubvolumeNesting([]blueprint.FilesystemCustomization{root, {Mountpoint: "/home/user"}}),
+		"mountpoint /home/user can't be nested in the btrfs subvolume mounted at /home")
+	assert.EqualError(t,
+		disk.ValidateSubvolumeNesting([]blueprint.FilesystemCustomization{root, {Mountpoint: "/home"}}),
+		"mountpoint /home can't be nested in the btrfs subvolume mounted at /home")
+}
+
+func TestValidateFilesystemCustomizations(t *testing.T) {
+	root := blueprint.FilesystemCustomization{
+		Mountpoint: "/",
+		FSType:     "btrfs",
+		Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+			{Name: "root", Mountpoint: "/"},
+			{Name: "home", Mountpoint: "/home"},
+		},
+	}
+	assert.NoError(t, disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{{Mountpoint: "/var"}}, "xfs"))
+	assert.NoError(t, disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{root, {Mountpoint: "/var", FSType: "ext4"}}, "xfs", "ext4", "btrfs"))
+	assert.EqualError(t,
+		disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{{Mountpoint: "/var", Options: []string{"no atime"}}}, "xfs", "ext4"),
+		`invalid mount option "no atime" of /var`)
+	assert.EqualError(t,
+		disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{{Mountpoint: "/var", FSType: "zfs"}}, "xfs", "ext4"),
+		`unsupported filesystem type "zfs" of /var, must be one of xfs, ext4`)
+	assert.EqualError(t,
+		disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{root}, "xfs", "ext4"),
+		`unsupported filesystem type "btrfs" of /, must be one of xfs, ext4`)
+	assert.EqualError(t,
+		disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{{Mountpoint: "/", Subvolumes: root.Subvolumes}}, "xfs", "ext4"),
+		"subvolumes of / require the btrfs filesystem type")
+	assert.EqualError(t,
+		disk.ValidateFilesystemCustomizations([]blueprint.FilesystemCustomization{root, {Mountpoint: "/home/user"}}, "xfs", "ext4", "btrfs"),
+		"mountpoint /home/user can't be nested in the btrfs subvolume mounted at /home")
+}
@@ -1,8 +1,10 @@
 package disk
 
 import (
+	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
@@ -13,6 +15,11 @@ const (
 	BIOSBootPartitionGUID = "21686148-6449-6E6F-744E-656564454649"
 	BIOSBootPartitionUUID = "FAC7F1FB-3E8D-4137-A512-961DE09A5549"
 
+	// partition type of the PowerPC Reference Platform (PReP) boot
+	// partition, which holds the GRUB core image on ppc64le
+	PRePBootPartitionGUID  = "9E1A2D38-C612-4316-AA26-8B49521E5A8B"
+	PRePBootPartitionDOSID = "41"
+
 	FilesystemDataGUID = "0FC63DAF-8483-4772-8E79-3D69D8477DE4"
 	FilesystemDataUUID = "CB07C243-BC44-4717-853E-28852021225B"
 
@@ -21,8 +28,105 @@ const (
 	EFIFilesystemUUID      = "7B77-95E7"
 
 	RootPartitionUUID = "6264D520-3FB9-423F-8AB8-7A0A8E3D3562"
+
+	// size of the /boot partition that is added for roots GRUB can't load
+	// the kernels from, e.g. on btrfs subvolumes, LVM or LUKS
+	bootPartitionSize = 1073741824
+
+	// custom filesystems are aligned to this size
+	mebibyte = 1024 * 1024
+
+	// the first partition starts at 1 MiB, the root partition leaves the
+	// space of 100 sectors of 512 bytes free at the end of the disk for
+	// the backup GPT header
+	firstPartitionOffset = mebibyte
+	lastPartitionPadding = 100 * 512
 )
 
+// space filesystems need on top of the data they hold: a fixed part for the
+// journal or log and a part proportional to the size of the data for the
+// metadata and, on ext4, the blocks reserved for root
+var filesystemOverheads = map[string]struct{ fixed, percent uint64 }{
+	"xfs":   {fixed: 64 * mebibyte, percent: 2},
+	"ext4":  {fixed: 64 * mebibyte, percent: 7},
+	"btrfs": {fixed: 256 * mebibyte, percent: 2},
+}
+
+// FilesystemOverhead returns the space in bytes a filesystem of type fsType
+// needs on top of size bytes of data. Filesystems without a type are created
+// as xfs.
+func FilesystemOverhead(fsType string, size uint64) uint64 {
+	if fsType == "" {
+		fsType = "xfs"
+	}
+	overhead, ok := filesystemOverheads[fsType]
+	if !ok {
+		return 0
+	}
+	return overhead.fixed + size*overhead.percent/100
+}
+
+// filesystemSize returns the size in bytes of a filesystem of type fsType
+// that holds at least size bytes of data
+func filesystemSize(fsType string, size uint64) uint64 {
+	return alignUp(size+FilesystemOverhead(fsType, size), mebibyte)
+}
+
+// MinImageSize returns the size in bytes an image needs at least to hold
+// the partitions of basePartitionTable with the filesystems of mountpoints,
+// including the overhead of their types
+func MinImageSize(mountpoints []blueprint.FilesystemCustomization, basePartitionTable PartitionTable) uint64 {
+	// the random UUIDs don't change the size of the partition table
+	pt := CreatePartitionTable(mountpoints, 0, basePartitionTable, rand.New(rand.NewSource(0)))
+	return pt.Size
+}
+
+// CheckImageSize returns an error naming the mountpoints if an image of size
+// bytes is too small to hold the partitions of basePartitionTable with their
+// filesystems
+func CheckImageSize(mountpoints []blueprint.FilesystemCustomization, size uint64, basePartitionTable PartitionTable) error {
+	minSize := MinImageSize(mountpoints, basePartitionTable)
+	if size >= minSize {
+		return nil
+	}
+	if len(mountpoints) == 0 {
+		return fmt.Errorf("image size of %d bytes is smaller than the %d bytes the partition table requires", size, minSize)
+	}
+	names := make([]string, 0, len(mountpoints))
+	for _, m := range mountpoints {
+		names = append(names, m.Mountpoint)
+	}
+	return fmt.Errorf("image size of %d bytes is smaller than the %d bytes the partition table and the filesystems of %s require", size, minSize, strings.Join(names, ", "))
+}
+
+// ValidateFilesystemCustomizations returns an error if one of the filesystem
+// customizations mountpoints has invalid fstab options, sets a type other
+// than one of fsTypes or has invalid btrfs subvolumes. Filesystems without a
+// type keep the one of the base partition table.
+func ValidateFilesystemCustomizations(mountpoints []blueprint.FilesystemCustomization, fsTypes ...string) error {
+	for _, m := range mountpoints {
+		if err := ValidateFSTabOptions(m.Mountpoint, m.Options); err != nil {
+			return err
+		}
+		if m.FSType != "" && !stringsContain(fsTypes, m.FSType) {
+			return fmt.Errorf("unsupported filesystem type %q of %s, must be one of %s", m.FSType, m.Mountpoint, strings.Join(fsTypes, ", "))
+		}
+		if err := ValidateSubvolumes(m); err != nil {
+			return err
+		}
+	}
+	return ValidateSubvolumeNesting(mountpoints)
+}
+
+func stringsContain(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 func CreatePartitionTable(
 	mountpoints []blueprint.FilesystemCustomization,
 	imageSize uint64,
@@ -30,37 +134,111 @@ func CreatePartitionTable(
 	rng *rand.Rand,
 ) PartitionTable {
 
+	// the base partition table is shared by all manifests of an image
+	// type and must not be modified
+	basePartitionTable = basePartitionTable.Clone()
+
 	if bootPartition := basePartitionTable.BootPartition(); bootPartition != nil {
 		// the boot partition UUID needs to be set since this
 		// needs to be randomly generated
 		bootPartition.Filesystem.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
 	}
 
+	// custom filesystems of LVM partition tables are put on logical
+	// volumes instead of partitions
+	var vg *LVMVolumeGroup
+	if rootPartition := basePartitionTable.RootPartition(); rootPartition != nil {
+		vg = rootPartition.VolumeGroup
+	}
+
+	// space in bytes the root filesystem needs at least
+	var rootMinSize uint64
 	for _, m := range mountpoints {
-		if m.Mountpoint != "/" {
-			partitionSize := m.MinSize / sectorSize
-			partition := basePartitionTable.createPartition(m.Mountpoint, partitionSize, rng)
+		if m.Mountpoint == "/" {
+			rootFilesystem := basePartitionTable.RootFilesystem()
+			rootFilesystem.applyCustomization(m)
+			if m.MinSize > 0 {
+				rootMinSize = filesystemSize(rootFilesystem.Type, m.MinSize)
+			}
+		} else if vg != nil {
+			lv := vg.LogicalVolumeByMountpoint(m.Mountpoint)
+			if lv == nil {
+				lv = vg.createLogicalVolume(m.Mountpoint, m.MinSize, rng)
+			}
+			lv.Filesystem.applyCustomization(m)
+			if size := alignUp(filesystemSize(lv.Filesystem.Type, m.MinSize), lvmExtentSize); size > lv.Size {
+				lv.Size = size
+			}
+		} else {
+			partition := basePartitionTable.createPartition(m.Mountpoint, 0, rng)
+			partition.Filesystem.applyCustomization(m)
+			partition.Size = filesystemSize(partition.Filesystem.Type, m.MinSize) / basePartitionTable.GetSectorSize()
 			basePartitionTable.Partitions = append(basePartitionTable.Partitions, partition)
 		}
 	}
 
-	if tableSize := basePartitionTable.getPartitionTableSize(); imageSize < tableSize {
-		imageSize = tableSize
+	if vg != nil {
+		for _, lv := range vg.LogicalVolumes {
+			if lv.Filesystem.UUID == "" && lv.Filesystem.Mountpoint != "/" {
+				lv.Filesystem.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+			}
+		}
+		// the physical volume needs to hold at least the logical volumes
+		// of a fixed size and the root volume
+		rootMinSize = vg.fixedSize() + alignUp(rootMinSize, lvmExtentSize)
+	}
+	if rootMinSize > 0 {
+		rootPartition := &basePartitionTable.Partitions[basePartitionTable.RootPartitionIndex()]
+		if rootPartition.LUKS != nil {
+			rootMinSize += luks2HeaderSize
+		}
+		if size := rootMinSize / basePartitionTable.GetSectorSize(); size > rootPartition.Size {
+			rootPartition.Size = size
+		}
+	}
+
+	if basePartitionTable.RootSubvolume() != nil && basePartitionTable.BootPartition() == nil {
+		partitionSize := bootPartitionSize / basePartitionTable.GetSectorSize()
+		partition := basePartitionTable.createPartition("/boot", partitionSize, rng)
+		basePartitionTable.Partitions = append(basePartitionTable.Partitions, partition)
+	}
+
+	// the image needs to hold all partitions at their minimum size after
+	// the space before the first partition and the space the root
+	// partition leaves free at the end of the disk
+	sectorSize := basePartitionTable.GetSectorSize()
+	startSectors := firstPartitionOffset / sectorSize
+	endSectors := alignUp(lastPartitionPadding, sectorSize) / sectorSize
+	if tableSize := (startSectors + basePartitionTable.getPartitionTableSize() + endSectors) * sectorSize; imageSize < tableSize {
+		imageSize = alignUp(tableSize, mebibyte)
 	}
 
 	basePartitionTable.Size = imageSize
 
-	// start point for all of the arches is
-	// 2048 sectors.
-	var start uint64 = basePartitionTable.updatePartitionStartPointOffsets(2048)
+	var start uint64 = basePartitionTable.updatePartitionStartPointOffsets(startSectors)
 
 	// treat the root partition as a special case
 	// by setting the size dynamically
 	rootPartition := basePartitionTable.RootPartition()
-	rootPartition.Size = ((imageSize / sectorSize) - start - 100)
-	rootPartition.Filesystem.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	rootPartition.Size = ((imageSize / sectorSize) - start - endSectors)
+	basePartitionTable.RootFilesystem().UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	if luks := rootPartition.LUKS; luks != nil && luks.UUID == "" {
+		luks.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	}
+	basePartitionTable.setSwapUUIDs(rng)
+	if vg != nil {
+		vg.fillRootVolume(rootPartition.Size * sectorSize)
+	}
 	basePartitionTable.updateRootPartition(*rootPartition)
 
+	for _, m := range mountpoints {
+		if len(m.Options) > 0 {
+			if fs := basePartitionTable.FilesystemByMountpoint(m.Mountpoint); fs != nil {
+				fs.SetFSTabOptions(m.Options)
+			}
+		}
+	}
+
 	return basePartitionTable
 }
 
@@ -87,6 +265,18 @@ func (pt *PartitionTable) createPartition(mountpoint string, size uint64, rng *r
 	}
 }
 
+// applyCustomization sets the type and the subvolumes of the filesystem from
+// the filesystem customization m. Filesystems keep their type if m doesn't
+// set one.
+func (fs *Filesystem) applyCustomization(m blueprint.FilesystemCustomization) {
+	if m.FSType != "" {
+		fs.Type = m.FSType
+	}
+	for _, sv := range m.Subvolumes {
+		fs.Subvolumes = append(fs.Subvolumes, BtrfsSubvolume{Name: sv.Name, Mountpoint: sv.Mountpoint})
+	}
+}
+
 func newRandomUUIDFromReader(r io.Reader) (uuid.UUID, error) {
 	var id uuid.UUID
 	_, err := io.ReadFull(r, id[:])
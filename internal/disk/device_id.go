@@ -0,0 +1,142 @@
+package disk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeviceIDMode selects how the filesystems of a partition table are
+// identified in fstab and in the root= kernel argument.
+type DeviceIDMode string
+
+const (
+	// Identify filesystems by their UUID (the default)
+	DeviceIDFilesystemUUID DeviceIDMode = "uuid"
+	// Identify filesystems by their label
+	DeviceIDFilesystemLabel DeviceIDMode = "label"
+	// Identify filesystems by the UUID of the partition they are on
+	DeviceIDPartitionUUID DeviceIDMode = "partuuid"
+	// Identify filesystems by the name of the GPT partition they are on
+	DeviceIDPartitionLabel DeviceIDMode = "partlabel"
+)
+
+// Maximum length of filesystem labels by filesystem type
+var maxLabelLength = map[string]int{
+	"vfat": 11,
+	"xfs":  12,
+	"ext4": 16,
+	"swap": 15,
+}
+
+// SetDeviceIDMode selects how the filesystems of the partition table are
+// identified. Filesystems without a label get one derived from their
+// mountpoint when they are to be identified by label.
+func (pt *PartitionTable) SetDeviceIDMode(mode DeviceIDMode) error {
+	switch mode {
+	case "", DeviceIDFilesystemUUID:
+		mode = DeviceIDFilesystemUUID
+	case DeviceIDFilesystemLabel:
+		if err := pt.generateLabels(); err != nil {
+			return err
+		}
+	case DeviceIDPartitionLabel:
+		if pt.Type != "gpt" {
+			return fmt.Errorf("filesystems can only be identified by partition label on GPT partition tables")
+		}
+		for _, p := range pt.Partitions {
+			if p.VolumeGroup != nil {
+				return fmt.Errorf("filesystems on the logical volumes of %s can't be identified by partition label", p.VolumeGroup.Name)
+			}
+			if p.LUKS != nil {
+				return fmt.Errorf("filesystems on encrypted partitions can't be identified by partition label")
+			}
+			if p.Filesystem != nil && p.Name == "" {
+				return fmt.Errorf("partition of %s has no name", p.Filesystem.Mountpoint)
+			}
+		}
+	case DeviceIDPartitionUUID:
+		for idx, p := range pt.Partitions {
+			if p.VolumeGroup != nil {
+				return fmt.Errorf("filesystems on the logical volumes of %s can't be identified by partition UUID", p.VolumeGroup.Name)
+			}
+			if p.LUKS != nil {
+				return fmt.Errorf("filesystems on encrypted partitions can't be identified by partition UUID")
+			}
+			if p.Filesystem != nil && pt.partitionUUID(idx) == "" {
+				return fmt.Errorf("partition of %s has no UUID", p.Filesystem.Mountpoint)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown device identification mode %q", mode)
+	}
+
+	pt.DeviceIDMode = mode
+	return nil
+}
+
+func (pt *PartitionTable) generateLabels() error {
+	mountpoints := make(map[string]string)
+	for _, fs := range pt.filesystems() {
+		if fs.Label == "" {
+			label := strings.ReplaceAll(strings.Trim(fs.Mountpoint, "/"), "/", "-")
+			if fs.Type == SwapFilesystemType {
+				label = "swap"
+			} else if label == "" {
+				label = "root"
+			}
+			maxLength, exists := maxLabelLength[fs.Type]
+			if !exists {
+				maxLength = maxLabelLength["xfs"]
+			}
+			if len(label) > maxLength {
+				label = label[:maxLength]
+			}
+			fs.Label = label
+		}
+
+		if other, exists := mountpoints[fs.Label]; exists {
+			return fmt.Errorf("filesystems of %s and %s have the same label %q", other, fs.Mountpoint, fs.Label)
+		}
+		mountpoints[fs.Label] = fs.Mountpoint
+	}
+	return nil
+}
+
+// Returns the UUID of the partition at idx. Partitions of dos partition
+// tables are identified by the disk signature and the partition number.
+func (pt PartitionTable) partitionUUID(idx int) string {
+	if uuid := pt.Partitions[idx].UUID; uuid != "" || pt.Type != "dos" {
+		return uuid
+	}
+	return fmt.Sprintf("%s-%02x", strings.TrimPrefix(pt.UUID, "0x"), idx+1)
+}
+
+// DeviceSpec returns the device specification of the filesystem of the
+// partition at idx, as used in the first column of fstab and in the root=
+// kernel argument, e.g. UUID=... or LABEL=...
+func (pt PartitionTable) DeviceSpec(idx int) string {
+	return pt.deviceSpec(pt.Partitions[idx].Filesystem, idx)
+}
+
+func (pt PartitionTable) deviceSpec(fs *Filesystem, idx int) string {
+	switch pt.DeviceIDMode {
+	case DeviceIDFilesystemLabel:
+		return "LABEL=" + fs.Label
+	case DeviceIDPartitionUUID:
+		return "PARTUUID=" + pt.partitionUUID(idx)
+	case DeviceIDPartitionLabel:
+		return "PARTLABEL=" + pt.Partitions[idx].Name
+	default:
+		return "UUID=" + fs.UUID
+	}
+}
+
+// RootDeviceSpec returns the device specification of the root filesystem, or
+// an empty string if the partition table has no root filesystem.
+func (pt PartitionTable) RootDeviceSpec() string {
+	idx := pt.RootPartitionIndex()
+	if idx == -1 {
+		return ""
+	}
+	return pt.deviceSpec(pt.RootFilesystem(), idx)
+}
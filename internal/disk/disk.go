@@ -5,6 +5,7 @@
 package disk
 
 import (
+	"fmt"
 	"sort"
 
 	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild1"
@@ -18,6 +19,12 @@ type PartitionTable struct {
 	// Partition table type, e.g. dos, gpt.
 	Type       string
 	Partitions []Partition
+	// How filesystems are identified in fstab and the root= kernel
+	// argument, set with SetDeviceIDMode. Empty means by UUID.
+	DeviceIDMode DeviceIDMode
+	// Size of the sectors of the disk in bytes, in which the starts and
+	// the sizes of partitions are given. 0 means 512 bytes.
+	SectorSize uint64
 }
 
 type Partition struct {
@@ -28,8 +35,20 @@ type Partition struct {
 	// ID of the partition, dos doesn't use traditional UUIDs, therefore this
 	// is just a string.
 	UUID string
+	// Name of the partition (GPT), which makes it available as
+	// /dev/disk/by-partlabel/<name>
+	Name string
+	// Attribute bits of the partition (GPT), e.g. 2 for legacy BIOS
+	// bootable or 55 for the type specific bit 55
+	Attrs []uint
 	// If nil, the partition is raw; It doesn't contain a filesystem.
 	Filesystem *Filesystem
+	// If set, the partition is the LVM physical volume of the volume group
+	// and doesn't contain a filesystem itself.
+	VolumeGroup *LVMVolumeGroup
+	// If set, the filesystem or the physical volume of the partition is in
+	// the LUKS2 container
+	LUKS *LUKSContainer
 }
 
 type Filesystem struct {
@@ -45,6 +64,10 @@ type Filesystem struct {
 	FSTabFreq uint64
 	// The sixth field of fstab(5); fs_passno
 	FSTabPassNo uint64
+	// Subvolumes of a btrfs filesystem. If set, the subvolumes are mounted
+	// instead of the top-level volume and one of them is mounted at
+	// Mountpoint.
+	Subvolumes []BtrfsSubvolume
 }
 
 // Converts PartitionTable to osbuild.QEMUAssemblerOptions that encode
@@ -84,15 +107,21 @@ func (pt PartitionTable) FSTabStageOptions() *osbuild.FSTabStageOptions {
 }
 
 // Generates org.osbuild.fstab stage options from this partition table.
+// Filesystems are identified according to the DeviceIDMode of the table.
 func (pt PartitionTable) FSTabStageOptionsV2() *osbuild2.FSTabStageOptions {
 	var options osbuild2.FSTabStageOptions
-	for _, p := range pt.Partitions {
+	for idx, p := range pt.Partitions {
+		if p.VolumeGroup != nil {
+			for _, lv := range p.VolumeGroup.LogicalVolumes {
+				options.FileSystems = append(options.FileSystems, pt.fstabEntries(lv.Filesystem, idx)...)
+			}
+			continue
+		}
 		fs := p.Filesystem
 		if fs == nil {
 			continue
 		}
-
-		options.AddFilesystem(fs.UUID, fs.Type, fs.Mountpoint, fs.FSTabOptions, fs.FSTabFreq, fs.FSTabPassNo)
+		options.FileSystems = append(options.FileSystems, pt.fstabEntries(fs, idx)...)
 	}
 
 	// sort the entries by PassNo to maintain backward compatibility
@@ -103,16 +132,79 @@ func (pt PartitionTable) FSTabStageOptionsV2() *osbuild2.FSTabStageOptions {
 	return &options
 }
 
+// fstabEntries returns the fstab entries of the filesystem fs of the
+// partition at idx
+func (pt PartitionTable) fstabEntries(fs *Filesystem, idx int) []*osbuild2.FSTabEntry {
+	var options osbuild2.FSTabStageOptions
+	for _, mount := range fs.fstabMounts() {
+		options.AddFilesystem(fs.UUID, fs.Type, mount.path, mount.options, fs.FSTabFreq, fs.FSTabPassNo)
+		entry := options.FileSystems[len(options.FileSystems)-1]
+		switch pt.DeviceIDMode {
+		case DeviceIDFilesystemLabel:
+			entry.UUID = ""
+			entry.Label = fs.Label
+		case DeviceIDPartitionUUID:
+			entry.UUID = ""
+			entry.PartUUID = pt.partitionUUID(idx)
+		case DeviceIDPartitionLabel:
+			entry.UUID = ""
+			entry.PartLabel = pt.Partitions[idx].Name
+		}
+	}
+	return options.FileSystems
+}
+
+// filesystems returns the filesystems of the partition table, including the
+// ones on logical volumes, in the order of the partitions
+func (pt *PartitionTable) filesystems() []*Filesystem {
+	var filesystems []*Filesystem
+	for _, p := range pt.Partitions {
+		if p.Filesystem != nil {
+			filesystems = append(filesystems, p.Filesystem)
+		}
+		if p.VolumeGroup != nil {
+			for _, lv := range p.VolumeGroup.LogicalVolumes {
+				filesystems = append(filesystems, lv.Filesystem)
+			}
+		}
+	}
+	return filesystems
+}
+
+// Clone returns a deep copy of the partition table, so that the copy can be
+// modified without affecting the original one.
+func (pt PartitionTable) Clone() PartitionTable {
+	clone := pt
+	clone.Partitions = make([]Partition, len(pt.Partitions))
+	for idx, partition := range pt.Partitions {
+		if partition.Filesystem != nil {
+			fs := *partition.Filesystem
+			fs.Subvolumes = append([]BtrfsSubvolume(nil), fs.Subvolumes...)
+			partition.Filesystem = &fs
+		}
+		if partition.VolumeGroup != nil {
+			partition.VolumeGroup = partition.VolumeGroup.clone()
+		}
+		partition.Attrs = append([]uint(nil), partition.Attrs...)
+		if partition.LUKS != nil {
+			luks := *partition.LUKS
+			if luks.Clevis != nil {
+				clevis := *luks.Clevis
+				luks.Clevis = &clevis
+			}
+			partition.LUKS = &luks
+		}
+		clone.Partitions[idx] = partition
+	}
+	return clone
+}
+
 // Returns the root partition (the partition whose filesystem has / as
 // a mountpoint) of the partition table. Nil is returned if there's no such
 // partition.
 func (pt PartitionTable) RootPartition() *Partition {
 	for _, p := range pt.Partitions {
-		if p.Filesystem == nil {
-			continue
-		}
-
-		if p.Filesystem.Mountpoint == "/" {
+		if p.containsRoot() {
 			return &p
 		}
 	}
@@ -120,6 +212,22 @@ func (pt PartitionTable) RootPartition() *Partition {
 	return nil
 }
 
+// RootFilesystem returns the filesystem mounted at /, which is either on the
+// root partition or on a logical volume of it. Nil is returned if there's no
+// such filesystem.
+func (pt PartitionTable) RootFilesystem() *Filesystem {
+	return pt.FilesystemByMountpoint("/")
+}
+
+// containsRoot returns true if the filesystem mounted at / is on the
+// partition, either directly or on one of its logical volumes
+func (p Partition) containsRoot() bool {
+	if p.VolumeGroup != nil {
+		return p.VolumeGroup.LogicalVolumeByMountpoint("/") != nil
+	}
+	return p.Filesystem != nil && p.Filesystem.Mountpoint == "/"
+}
+
 // Returns the /boot partition (the partition whose filesystem has /boot as
 // a mountpoint) of the partition table. Nil is returned if there's no such
 // partition.
@@ -145,25 +253,30 @@ func (pt PartitionTable) BootPartitionIndex() int {
 	// find partition with '/boot' mountpoint and fallback to '/'
 	rootIdx := -1
 	for idx, part := range pt.Partitions {
-		if part.Filesystem == nil {
-			continue
-		}
-		if part.Filesystem.Mountpoint == "/boot" {
+		if part.Filesystem != nil && part.Filesystem.Mountpoint == "/boot" {
 			return idx
-		} else if part.Filesystem.Mountpoint == "/" {
+		} else if part.containsRoot() {
 			rootIdx = idx
 		}
 	}
 	return rootIdx
 }
 
+// PRePBootPartitionIndex returns the index of the PReP boot partition, which
+// GRUB writes its core image to on ppc64le, or -1 if there is none.
+func (pt PartitionTable) PRePBootPartitionIndex() int {
+	for idx, part := range pt.Partitions {
+		if part.Type == PRePBootPartitionDOSID || part.Type == PRePBootPartitionGUID {
+			return idx
+		}
+	}
+	return -1
+}
+
 func (pt PartitionTable) RootPartitionIndex() int {
 	rootIdx := -1
 	for idx, part := range pt.Partitions {
-		if part.Filesystem == nil {
-			continue
-		}
-		if part.Filesystem.Mountpoint == "/" {
+		if part.containsRoot() {
 			rootIdx = idx
 		}
 	}
@@ -177,7 +290,7 @@ func (pt *PartitionTable) updatePartitionStartPointOffsets(start uint64) uint64
 	var rootIdx = -1
 	for i := range pt.Partitions {
 		partition := &pt.Partitions[i]
-		if partition.Filesystem != nil && partition.Filesystem.Mountpoint == "/" {
+		if partition.containsRoot() {
 			rootIdx = i
 			continue
 		}
@@ -200,6 +313,69 @@ func (pt *PartitionTable) getPartitionTableSize() uint64 {
 	return size
 }
 
+// GetSectorSize returns the size of the sectors of the disk in bytes
+func (pt PartitionTable) GetSectorSize() uint64 {
+	if pt.SectorSize == 0 {
+		return sectorSize
+	}
+	return pt.SectorSize
+}
+
+// CheckSectorSize returns an error if disks can't have sectors of size
+// bytes. Disks have the 512 byte sectors of most disks or the 4K sectors of
+// 4K native disks.
+func CheckSectorSize(size uint64) error {
+	if size != 512 && size != 4096 {
+		return fmt.Errorf("unsupported sector size %d, must be 512 or 4096", size)
+	}
+	return nil
+}
+
+// ConvertSectorSize returns a copy of the partition table for a disk with
+// sectors of size bytes, with the starts and sizes of the partitions
+// converted to the new sectors. They must be multiples of the new sector
+// size in bytes.
+func (pt PartitionTable) ConvertSectorSize(size uint64) (PartitionTable, error) {
+	if err := CheckSectorSize(size); err != nil {
+		return pt, err
+	}
+	oldSize := pt.GetSectorSize()
+	converted := pt.Clone()
+	converted.SectorSize = size
+	for idx := range converted.Partitions {
+		p := &converted.Partitions[idx]
+		if (p.Start*oldSize)%size != 0 || (p.Size*oldSize)%size != 0 {
+			return pt, fmt.Errorf("partition %d isn't aligned to sectors of %d bytes", idx, size)
+		}
+		p.Start = p.Start * oldSize / size
+		p.Size = p.Size * oldSize / size
+	}
+	return converted, nil
+}
+
+// GrowLastPartition returns a copy of the partition table for a disk of the
+// given size, whose last partition is grown by as much as the disk grows.
+// All other partitions are unchanged.
+func (pt PartitionTable) GrowLastPartition(size uint64) (PartitionTable, error) {
+	if size < pt.Size {
+		return pt, fmt.Errorf("partition table can't shrink from %d to %d bytes", pt.Size, size)
+	}
+	if len(pt.Partitions) == 0 {
+		return pt, fmt.Errorf("partition table has no partitions to grow")
+	}
+
+	grown := pt.Clone()
+	last := &grown.Partitions[0]
+	for idx := range grown.Partitions {
+		if grown.Partitions[idx].Start > last.Start {
+			last = &grown.Partitions[idx]
+		}
+	}
+	last.Size += (size - pt.Size) / pt.GetSectorSize()
+	grown.Size = size
+	return grown, nil
+}
+
 // Converts Partition to osbuild.QEMUPartition that encodes the same partition.
 func (p Partition) QEMUPartition() osbuild.QEMUPartition {
 	var fs *osbuild.QEMUFilesystem
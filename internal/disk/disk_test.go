@@ -2,11 +2,14 @@ package disk_test
 
 import (
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/osbuild/osbuild-composer/internal/osbuild2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDisk_DynamicallyResizePartitionTable(t *testing.T) {
@@ -43,5 +46,450 @@ func TestDisk_DynamicallyResizePartitionTable(t *testing.T) {
 	var expectedSize uint64 = 2147483648
 	rng := rand.New(rand.NewSource(0))
 	pt = disk.CreatePartitionTable(mountpoints, 1024, pt, rng)
-	assert.GreaterOrEqual(t, expectedSize, pt.Size)
+	assert.GreaterOrEqual(t, pt.Size, expectedSize)
+}
+
+func TestDisk_CreatePartitionTableIsReproducible(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size:     2048,
+				Bootable: true,
+				Type:     disk.BIOSBootPartitionGUID,
+				UUID:     disk.BIOSBootPartitionUUID,
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Label:        "root",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{
+		{
+			MinSize:    1073741824,
+			Mountpoint: "/var",
+		},
+	}
+
+	first := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(42)))
+	second := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(42)))
+	assert.Equal(t, first, second)
+
+	// the base partition table must be left untouched
+	assert.Len(t, base.Partitions, 2)
+	assert.Equal(t, uint64(0), base.Partitions[1].Size)
+	assert.Equal(t, "", base.Partitions[1].Filesystem.UUID)
+
+	// a different seed yields different UUIDs
+	third := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(43)))
+	assert.NotEqual(t, first.RootPartition().Filesystem.UUID, third.RootPartition().Filesystem.UUID)
+}
+
+func TestDisk_DeviceIDModes(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size: 204800,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					FSTabOptions: "umask=077",
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Label:        "root",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{
+		{
+			MinSize:    1073741824,
+			Mountpoint: "/var/lib/containers",
+		},
+	}
+
+	cases := []struct {
+		mode       disk.DeviceIDMode
+		rootDevice string
+		check      func(pt disk.PartitionTable, entry *osbuild2.FSTabEntry, idx int)
+	}{
+		{
+			disk.DeviceIDFilesystemUUID,
+			"UUID=",
+			func(pt disk.PartitionTable, entry *osbuild2.FSTabEntry, idx int) {
+				assert.Equal(t, pt.Partitions[idx].Filesystem.UUID, entry.UUID)
+				assert.Empty(t, entry.Label)
+				assert.Empty(t, entry.PartUUID)
+			},
+		},
+		{
+			disk.DeviceIDFilesystemLabel,
+			"LABEL=root",
+			func(pt disk.PartitionTable, entry *osbuild2.FSTabEntry, idx int) {
+				assert.Equal(t, pt.Partitions[idx].Filesystem.Label, entry.Label)
+				assert.Empty(t, entry.UUID)
+				assert.Empty(t, entry.PartUUID)
+			},
+		},
+		{
+			disk.DeviceIDPartitionUUID,
+			"PARTUUID=" + disk.RootPartitionUUID,
+			func(pt disk.PartitionTable, entry *osbuild2.FSTabEntry, idx int) {
+				assert.Equal(t, pt.Partitions[idx].UUID, entry.PartUUID)
+				assert.Empty(t, entry.UUID)
+				assert.Empty(t, entry.Label)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		pt := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(0)))
+		require.NoError(t, pt.SetDeviceIDMode(c.mode))
+		assert.Equal(t, c.mode, pt.DeviceIDMode)
+
+		rootDevice := pt.RootDeviceSpec()
+		assert.True(t, strings.HasPrefix(rootDevice, c.rootDevice), "%s: unexpected root device %s", c.mode, rootDevice)
+		if c.mode == disk.DeviceIDFilesystemUUID {
+			assert.Equal(t, "UUID="+pt.RootPartition().Filesystem.UUID, rootDevice)
+		}
+
+		fstab := pt.FSTabStageOptionsV2()
+		require.Len(t, fstab.FileSystems, 3)
+		for _, entry := range fstab.FileSystems {
+			for idx, p := range pt.Partitions {
+				if p.Filesystem != nil && p.Filesystem.Mountpoint == entry.Path {
+					c.check(pt, entry, idx)
+					assert.Equal(t, pt.DeviceSpec(idx)[strings.Index(pt.DeviceSpec(idx), "=")+1:], entry.UUID+entry.Label+entry.PartUUID)
+				}
+			}
+		}
+	}
+
+	// labels are generated from the mountpoint
+	pt := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(0)))
+	require.NoError(t, pt.SetDeviceIDMode(disk.DeviceIDFilesystemLabel))
+	labels := []string{}
+	for _, p := range pt.Partitions {
+		labels = append(labels, p.Filesystem.Label)
+	}
+	assert.ElementsMatch(t, []string{"boot-efi", "root", "var-lib-cont"}, labels)
+
+	// the base partition table is not modified
+	assert.Empty(t, base.Partitions[0].Filesystem.Label)
+	assert.Empty(t, base.DeviceIDMode)
+
+	assert.Error(t, pt.SetDeviceIDMode("path"))
+}
+
+func TestDisk_DeviceIDModeErrors(t *testing.T) {
+	pt := disk.PartitionTable{
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/var/lib/containers/a"}},
+			{Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/var/lib/containers/b"}},
+		},
+	}
+	labelled := pt.Clone()
+	assert.EqualError(t, labelled.SetDeviceIDMode(disk.DeviceIDFilesystemLabel), `filesystems of /var/lib/containers/a and /var/lib/containers/b have the same label "var-lib-cont"`)
+	assert.EqualError(t, pt.SetDeviceIDMode(disk.DeviceIDPartitionUUID), "partition of /var/lib/containers/a has no UUID")
+	assert.EqualError(t, pt.SetDeviceIDMode(disk.DeviceIDPartitionLabel), "partition of /var/lib/containers/a has no name")
+
+	// GPT partitions are identified by their names
+	named := pt.Clone()
+	named.Partitions[0].Name = "containers-a"
+	named.Partitions[1].Name = "containers-b"
+	require.NoError(t, named.SetDeviceIDMode(disk.DeviceIDPartitionLabel))
+	assert.Equal(t, "PARTLABEL=containers-b", named.DeviceSpec(1))
+	fstab := named.FSTabStageOptionsV2()
+	require.Len(t, fstab.FileSystems, 2)
+	assert.Equal(t, "containers-a", fstab.FileSystems[0].PartLabel)
+	assert.Empty(t, fstab.FileSystems[0].UUID)
+
+	// dos partitions are identified by the disk signature
+	dos := disk.PartitionTable{
+		UUID: "0x14fc63d2",
+		Type: "dos",
+		Partitions: []disk.Partition{
+			{Size: 8192},
+			{Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/"}},
+		},
+	}
+	assert.EqualError(t, dos.SetDeviceIDMode(disk.DeviceIDPartitionLabel), "filesystems can only be identified by partition label on GPT partition tables")
+	require.NoError(t, dos.SetDeviceIDMode(disk.DeviceIDPartitionUUID))
+	assert.Equal(t, "PARTUUID=14fc63d2-02", dos.RootDeviceSpec())
+}
+
+func TestDisk_GrowLastPartition(t *testing.T) {
+	pt := disk.PartitionTable{
+		Size: 10485760,
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Start: 2048,
+				Size:  2048,
+				Type:  disk.BIOSBootPartitionGUID,
+			},
+			{
+				Start:      6144,
+				Size:       14240,
+				Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/"},
+			},
+			{
+				Start:      4096,
+				Size:       2048,
+				Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/boot"},
+			},
+		},
+	}
+
+	grown, err := pt.GrowLastPartition(20971520)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20971520), grown.Size)
+	assert.Equal(t, uint64(14240+20480), grown.Partitions[1].Size)
+	assert.Equal(t, pt.Partitions[0], grown.Partitions[0])
+	assert.Equal(t, pt.Partitions[2].Size, grown.Partitions[2].Size)
+
+	// the original partition table is not modified
+	assert.Equal(t, uint64(10485760), pt.Size)
+	assert.Equal(t, uint64(14240), pt.Partitions[1].Size)
+
+	_, err = pt.GrowLastPartition(1048576)
+	assert.EqualError(t, err, "partition table can't shrink from 10485760 to 1048576 bytes")
+
+	_, err = disk.PartitionTable{}.GrowLastPartition(1048576)
+	assert.EqualError(t, err, "partition table has no partitions to grow")
+}
+
+func TestDisk_SectorSize(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{{Mountpoint: "/var", MinSize: 1073741824}}
+	assert.Equal(t, uint64(512), base.GetSectorSize())
+
+	// 1 GiB of data and the overhead of xfs
+	const varSize = 1109 * 1024 * 1024
+	pt := disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(0)))
+	assert.Equal(t, uint64(varSize/512), pt.Partitions[1].Size)
+	assert.Equal(t, uint64(4294967296/512-2048-varSize/512-100), pt.Partitions[0].Size)
+
+	// partitions of disks with 4K sectors are sized in 4K sectors
+	base.SectorSize = 4096
+	assert.Equal(t, uint64(4096), base.GetSectorSize())
+	pt = disk.CreatePartitionTable(mountpoints, 4294967296, base, rand.New(rand.NewSource(0)))
+	assert.Equal(t, uint64(4096), pt.SectorSize)
+	assert.Equal(t, uint64(varSize/4096), pt.Partitions[1].Size)
+	// the first partition still starts at 1 MiB and 13 sectors hold the
+	// 100 sectors of 512 bytes at the end of the disk
+	assert.Equal(t, uint64(256), pt.Partitions[1].Start)
+	assert.Equal(t, uint64(4294967296/4096-256-varSize/4096-13), pt.Partitions[0].Size)
+
+	grown, err := pt.GrowLastPartition(8589934592)
+	require.NoError(t, err)
+	assert.Equal(t, pt.Partitions[0].Size+4294967296/4096, grown.Partitions[0].Size)
+}
+
+func TestDisk_PRePBootPartitionIndex(t *testing.T) {
+	pt := disk.PartitionTable{
+		Type: "dos",
+		Partitions: []disk.Partition{
+			{Size: 8192, Type: disk.PRePBootPartitionDOSID, Bootable: true},
+			{Filesystem: &disk.Filesystem{Type: "xfs", Mountpoint: "/"}},
+		},
+	}
+	assert.Equal(t, 0, pt.PRePBootPartitionIndex())
+
+	pt.Type = "gpt"
+	pt.Partitions[0].Type = disk.PRePBootPartitionGUID
+	pt.Partitions[0], pt.Partitions[1] = pt.Partitions[1], pt.Partitions[0]
+	assert.Equal(t, 1, pt.PRePBootPartitionIndex())
+
+	pt.Partitions[1].Type = disk.BIOSBootPartitionGUID
+	assert.Equal(t, -1, pt.PRePBootPartitionIndex())
+}
+
+func TestDisk_ConvertSectorSize(t *testing.T) {
+	base := disk.PartitionTable{
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{Start: 2048, Size: 2048, Type: disk.BIOSBootPartitionGUID},
+			{Start: 4096, Size: 204800, Type: disk.EFISystemPartitionGUID},
+		},
+	}
+
+	converted, err := base.ConvertSectorSize(4096)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4096), converted.GetSectorSize())
+	assert.Equal(t, uint64(256), converted.Partitions[0].Start)
+	assert.Equal(t, uint64(256), converted.Partitions[0].Size)
+	assert.Equal(t, uint64(512), converted.Partitions[1].Start)
+	assert.Equal(t, uint64(25600), converted.Partitions[1].Size)
+	// the base partition table is unchanged
+	assert.Equal(t, uint64(2048), base.Partitions[0].Size)
+
+	back, err := converted.ConvertSectorSize(512)
+	require.NoError(t, err)
+	assert.Equal(t, base.Partitions, back.Partitions)
+
+	base.Partitions[0].Size = 2047
+	_, err = base.ConvertSectorSize(4096)
+	assert.EqualError(t, err, "partition 0 isn't aligned to sectors of 4096 bytes")
+
+	_, err = base.ConvertSectorSize(1024)
+	assert.EqualError(t, err, "unsupported sector size 1024, must be 512 or 4096")
+}
+
+func TestDisk_FilesystemOverhead(t *testing.T) {
+	const GiB = 1024 * 1024 * 1024
+	xfs := disk.FilesystemOverhead("xfs", 2*GiB)
+	ext4 := disk.FilesystemOverhead("ext4", 2*GiB)
+	assert.Equal(t, uint64(110058536), xfs)
+	assert.Equal(t, uint64(217432719), ext4)
+	assert.Greater(t, ext4, xfs)
+	// filesystems without a type are created as xfs
+	assert.Equal(t, xfs, disk.FilesystemOverhead("", 2*GiB))
+	// the overhead grows with the size of the filesystem
+	assert.Greater(t, disk.FilesystemOverhead("xfs", 4*GiB), xfs)
+	assert.Equal(t, uint64(0), disk.FilesystemOverhead("vfat", 2*GiB))
+}
+
+func TestDisk_CheckImageSize(t *testing.T) {
+	const GiB = 1024 * 1024 * 1024
+	base := disk.PartitionTable{
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size:     2048,
+				Bootable: true,
+				Type:     disk.BIOSBootPartitionGUID,
+				UUID:     disk.BIOSBootPartitionUUID,
+			},
+			{
+				Size: 204800,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:       "vfat",
+					UUID:       disk.EFIFilesystemUUID,
+					Mountpoint: "/boot/efi",
+				},
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/", MinSize: 2 * GiB},
+		{Mountpoint: "/var", MinSize: 2 * GiB, FSType: "ext4"},
+	}
+
+	// 1 MiB before the partitions, 1 MiB for BIOS boot, 100 MiB for the
+	// ESP, 2153 MiB for / on xfs, 2256 MiB for /var on ext4 and the end
+	// of the disk rounded up to 1 MiB
+	minSize := disk.MinImageSize(mountpoints, base)
+	assert.Equal(t, uint64(4512*1024*1024), minSize)
+	assert.NoError(t, disk.CheckImageSize(mountpoints, minSize, base))
+	assert.NoError(t, disk.CheckImageSize(mountpoints, 5*GiB, base))
+	assert.EqualError(t, disk.CheckImageSize(mountpoints, minSize-1024*1024, base),
+		"image size of 4730126336 bytes is smaller than the 4731174912 bytes the partition table and the filesystems of /, /var require")
+
+	// 2 GiB of data don't fit into a 2 GiB filesystem
+	assert.Error(t, disk.CheckImageSize(mountpoints[:1], 2*GiB, base))
+	assert.NoError(t, disk.CheckImageSize(nil, 2*GiB, base))
+	assert.EqualError(t, disk.CheckImageSize(nil, 100*1024*1024, base),
+		"image size of 104857600 bytes is smaller than the 108003328 bytes the partition table requires")
+
+	// the size of the partition table doesn't depend on the sector size
+	base4K, err := base.ConvertSectorSize(4096)
+	require.NoError(t, err)
+	assert.Equal(t, minSize, disk.MinImageSize(mountpoints, base4K))
+}
+
+func TestDisk_CreatePartitionTableMinSizes(t *testing.T) {
+	const GiB = 1024 * 1024 * 1024
+	base := disk.PartitionTable{
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size:     2048,
+				Bootable: true,
+				Type:     disk.BIOSBootPartitionGUID,
+				UUID:     disk.BIOSBootPartitionUUID,
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/", MinSize: 2 * GiB},
+		{Mountpoint: "/var", MinSize: 2 * GiB, FSType: "ext4"},
+		{Mountpoint: "/home", MinSize: 2 * GiB},
+	}
+
+	// the requested size is a lower bound only
+	pt := disk.CreatePartitionTable(mountpoints, 4*GiB, base, rand.New(rand.NewSource(0)))
+	assert.Greater(t, pt.Size, uint64(4*GiB))
+	assert.Equal(t, disk.MinImageSize(mountpoints, base), pt.Size)
+	assert.Zero(t, pt.Size%(1024*1024))
+	for _, m := range mountpoints {
+		var partition *disk.Partition
+		for idx := range pt.Partitions {
+			if fs := pt.Partitions[idx].Filesystem; fs != nil && fs.Mountpoint == m.Mountpoint {
+				partition = &pt.Partitions[idx]
+			}
+		}
+		require.NotNil(t, partition, m.Mountpoint)
+		fsType := partition.Filesystem.Type
+		assert.GreaterOrEqual(t, partition.Size*512, m.MinSize+disk.FilesystemOverhead(fsType, m.MinSize), m.Mountpoint)
+		assert.LessOrEqual(t, partition.Start+partition.Size, pt.Size/512, m.Mountpoint)
+	}
+
+	pt = disk.CreatePartitionTable(mountpoints, 10*GiB, base, rand.New(rand.NewSource(0)))
+	assert.Equal(t, uint64(10*GiB), pt.Size)
 }
@@ -0,0 +1,112 @@
+package disk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The x-systemd.* mount options understood by systemd-fstab-generator(8),
+// and whether they take a value
+var systemdFSTabOptions = map[string]bool{
+	"x-systemd.requires":            true,
+	"x-systemd.requires-mounts-for": true,
+	"x-systemd.before":              true,
+	"x-systemd.after":               true,
+	"x-systemd.wanted-by":           true,
+	"x-systemd.required-by":         true,
+	"x-systemd.device-bound":        false,
+	"x-systemd.automount":           false,
+	"x-systemd.idle-timeout":        true,
+	"x-systemd.device-timeout":      true,
+	"x-systemd.mount-timeout":       true,
+	"x-systemd.makefs":              false,
+	"x-systemd.growfs":              false,
+	"x-systemd.rw-only":             false,
+}
+
+// Mount options that can't be used for the root filesystem, which is mounted
+// by the initrd before the network is up and before automount units exist
+var rootDeniedFSTabOptions = map[string]bool{
+	"x-systemd.automount": true,
+	"_netdev":             true,
+}
+
+// ValidateFSTabOptions returns an error if options can't be used as the
+// mount options of the fstab entry of mountpoint.
+func ValidateFSTabOptions(mountpoint string, options []string) error {
+	for _, option := range options {
+		if option == "" || strings.ContainsAny(option, ", \t\n") {
+			return fmt.Errorf("invalid mount option %q of %s", option, mountpoint)
+		}
+
+		name, value := option, ""
+		hasValue := false
+		if idx := strings.Index(option, "="); idx != -1 {
+			name, value, hasValue = option[:idx], option[idx+1:], true
+		}
+
+		if strings.HasPrefix(name, "x-systemd.") {
+			needsValue, known := systemdFSTabOptions[name]
+			if !known {
+				return fmt.Errorf("unknown mount option %q of %s", name, mountpoint)
+			}
+			if needsValue && value == "" {
+				return fmt.Errorf("mount option %q of %s requires a value", name, mountpoint)
+			}
+			if !needsValue && hasValue {
+				return fmt.Errorf("mount option %q of %s doesn't take a value", name, mountpoint)
+			}
+		}
+
+		if mountpoint == "/" && rootDeniedFSTabOptions[name] {
+			return fmt.Errorf("mount option %q can't be used for the root filesystem", name)
+		}
+	}
+	return nil
+}
+
+// SetFSTabOptions sets the mount options of the fstab entry of the
+// filesystem, in the given order. No options mean "defaults".
+func (fs *Filesystem) SetFSTabOptions(options []string) {
+	if len(options) == 0 {
+		fs.FSTabOptions = "defaults"
+		return
+	}
+	fs.FSTabOptions = strings.Join(options, ",")
+}
+
+// SetFSTabOption sets the value of the mount option name of the fstab entry
+// of the filesystem, replacing a previous value of the option or appending
+// it to the options.
+func (fs *Filesystem) SetFSTabOption(name, value string) {
+	option := name + "=" + value
+
+	var options []string
+	if fs.FSTabOptions != "" {
+		options = strings.Split(fs.FSTabOptions, ",")
+	}
+	for idx, o := range options {
+		if o == name || strings.HasPrefix(o, name+"=") {
+			options[idx] = option
+			fs.FSTabOptions = strings.Join(options, ",")
+			return
+		}
+	}
+	fs.FSTabOptions = strings.Join(append(options, option), ",")
+}
+
+// FilesystemByMountpoint returns the filesystem of the partition table that
+// is mounted at mountpoint, or nil if there is none.
+func (pt *PartitionTable) FilesystemByMountpoint(mountpoint string) *Filesystem {
+	for _, p := range pt.Partitions {
+		if p.Filesystem != nil && p.Filesystem.Mountpoint == mountpoint {
+			return p.Filesystem
+		}
+		if p.VolumeGroup != nil {
+			if lv := p.VolumeGroup.LogicalVolumeByMountpoint(mountpoint); lv != nil {
+				return lv.Filesystem
+			}
+		}
+	}
+	return nil
+}
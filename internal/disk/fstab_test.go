@@ -0,0 +1,154 @@
+package disk_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFSTabOptions(t *testing.T) {
+	cases := []struct {
+		mountpoint string
+		options    []string
+		err        string
+	}{
+		{"/", nil, ""},
+		{"/", []string{"defaults", "noatime"}, ""},
+		{"/", []string{"x-systemd.growfs"}, ""},
+		{"/var/lib/data", []string{"nofail", "x-systemd.automount", "x-systemd.idle-timeout=5min"}, ""},
+		{"/srv", []string{"_netdev", "x-systemd.requires=network-online.target", "x-systemd.after=network-online.target"}, ""},
+		{"/", []string{"x-systemd.automount"}, `mount option "x-systemd.automount" can't be used for the root filesystem`},
+		{"/", []string{"defaults", "_netdev"}, `mount option "_netdev" can't be used for the root filesystem`},
+		{"/srv", []string{""}, `invalid mount option "" of /srv`},
+		{"/srv", []string{"ro,noexec"}, `invalid mount option "ro,noexec" of /srv`},
+		{"/srv", []string{"no atime"}, `invalid mount option "no atime" of /srv`},
+		{"/srv", []string{"x-systemd.requires"}, `mount option "x-systemd.requires" of /srv requires a value`},
+		{"/srv", []string{"x-systemd.requires="}, `mount option "x-systemd.requires" of /srv requires a value`},
+		{"/srv", []string{"x-systemd.automount=yes"}, `mount option "x-systemd.automount" of /srv doesn't take a value`},
+		{"/srv", []string{"x-systemd.wants=foo.service"}, `unknown mount option "x-systemd.wants" of /srv`},
+	}
+	for _, c := range cases {
+		err := disk.ValidateFSTabOptions(c.mountpoint, c.options)
+		if c.err == "" {
+			assert.NoError(t, err, c.options)
+		} else {
+			assert.EqualError(t, err, c.err, c.options)
+		}
+	}
+}
+
+func TestFilesystem_SetFSTabOption(t *testing.T) {
+	cases := []struct {
+		options  string
+		expected string
+	}{
+		{"", "umask=0077"},
+		{"defaults", "defaults,umask=0077"},
+		{"defaults,uid=0,gid=0,umask=077,shortname=winnt", "defaults,uid=0,gid=0,umask=0077,shortname=winnt"},
+		{"umask,ro", "umask=0077,ro"},
+	}
+	for _, c := range cases {
+		fs := disk.Filesystem{FSTabOptions: c.options}
+		fs.SetFSTabOption("umask", "0077")
+		assert.Equal(t, c.expected, fs.FSTabOptions)
+	}
+}
+
+func TestDisk_FSTabOptionsFromCustomizations(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size: 204800,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					FSTabOptions: "umask=077",
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		mountpoints []blueprint.FilesystemCustomization
+		options     map[string]string
+	}{
+		{
+			"no options",
+			[]blueprint.FilesystemCustomization{{Mountpoint: "/srv", MinSize: 1073741824}},
+			map[string]string{"/boot/efi": "umask=077", "/": "defaults", "/srv": "defaults"},
+		},
+		{
+			"root options",
+			[]blueprint.FilesystemCustomization{{Mountpoint: "/", Options: []string{"defaults", "noatime"}}},
+			map[string]string{"/boot/efi": "umask=077", "/": "defaults,noatime"},
+		},
+		{
+			"network filesystem",
+			[]blueprint.FilesystemCustomization{
+				{
+					Mountpoint: "/srv",
+					MinSize:    1073741824,
+					Options:    []string{"_netdev", "nofail", "x-systemd.requires=iscsi.service"},
+				},
+			},
+			map[string]string{"/boot/efi": "umask=077", "/": "defaults", "/srv": "_netdev,nofail,x-systemd.requires=iscsi.service"},
+		},
+		{
+			"automount",
+			[]blueprint.FilesystemCustomization{
+				{
+					Mountpoint: "/var/lib/data",
+					MinSize:    1073741824,
+					Options:    []string{"x-systemd.automount", "x-systemd.idle-timeout=60", "nofail"},
+				},
+				{
+					Mountpoint: "/srv",
+					MinSize:    1073741824,
+				},
+			},
+			map[string]string{"/boot/efi": "umask=077", "/": "defaults", "/var/lib/data": "x-systemd.automount,x-systemd.idle-timeout=60,nofail", "/srv": "defaults"},
+		},
+	}
+
+	for _, c := range cases {
+		pt := disk.CreatePartitionTable(c.mountpoints, 0, base, rand.New(rand.NewSource(0)))
+
+		entries := pt.FSTabStageOptionsV2().FileSystems
+		options := make(map[string]string)
+		for _, entry := range entries {
+			options[entry.Path] = entry.Options
+		}
+		assert.Equal(t, c.options, options, c.name)
+
+		// the options are serialized in the order they were given
+		data, err := json.Marshal(entries)
+		require.NoError(t, err)
+		for mountpoint, o := range c.options {
+			assert.Contains(t, string(data), `"path":"`+mountpoint+`","options":"`+o+`"`, c.name)
+		}
+	}
+
+	// the base partition table is not modified
+	assert.Equal(t, "defaults", base.Partitions[1].Filesystem.FSTabOptions)
+}
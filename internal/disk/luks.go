@@ -0,0 +1,82 @@
+package disk
+
+import (
+	"fmt"
+)
+
+// space at the start of a LUKS2 container that cryptsetup reserves for the
+// header and the key slots
+const luks2HeaderSize = 16 * 1024 * 1024
+
+// LUKSContainer is the LUKS2 container that encrypts the contents of a
+// partition
+type LUKSContainer struct {
+	UUID  string
+	Label string
+	// Passphrase of the first key slot
+	Passphrase string
+	// If set, the container is bound to a Clevis policy
+	Clevis *ClevisBind
+}
+
+// ClevisBind is a Clevis pin and its configuration in JSON
+type ClevisBind struct {
+	Pin    string
+	Policy string
+}
+
+// MapperName returns the name of the device mapper device the container is
+// unlocked as at boot
+func (luks *LUKSContainer) MapperName() string {
+	return "luks-" + luks.UUID
+}
+
+// EncryptedPartitionTable returns a copy of the partition table whose root
+// partition is encrypted by the container luks. A /boot partition is added
+// if the partition table has none, since the bootloader can't read the
+// kernels from the encrypted root partition.
+func EncryptedPartitionTable(pt PartitionTable, luks LUKSContainer) (PartitionTable, error) {
+	encrypted := pt.Clone()
+	rootIdx := encrypted.RootPartitionIndex()
+	if rootIdx == -1 {
+		return pt, fmt.Errorf("partition table has no root partition")
+	}
+
+	root := &encrypted.Partitions[rootIdx]
+	if root.LUKS != nil {
+		return pt, fmt.Errorf("root partition is already encrypted")
+	}
+	if luks.Clevis != nil {
+		clevis := *luks.Clevis
+		luks.Clevis = &clevis
+	}
+	root.LUKS = &luks
+
+	encrypted.ensureBootPartition()
+	return encrypted, nil
+}
+
+// ensureBootPartition adds an xfs /boot partition before the root partition
+// if the partition table has no /boot partition
+func (pt *PartitionTable) ensureBootPartition() {
+	if pt.BootPartition() != nil {
+		return
+	}
+
+	boot := Partition{
+		Size: bootPartitionSize / pt.GetSectorSize(),
+		Filesystem: &Filesystem{
+			Type:         "xfs",
+			Mountpoint:   "/boot",
+			FSTabOptions: "defaults",
+		},
+	}
+	if pt.Type == "gpt" {
+		boot.Type = FilesystemDataGUID
+		boot.UUID = FilesystemDataUUID
+	}
+	rootIdx := pt.RootPartitionIndex()
+	partitions := append([]Partition{}, pt.Partitions[:rootIdx]...)
+	partitions = append(partitions, boot)
+	pt.Partitions = append(partitions, pt.Partitions[rootIdx:]...)
+}
@@ -0,0 +1,55 @@
+package disk_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedPartitionTable(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	clevis := disk.ClevisBind{Pin: "null", Policy: "{}"}
+	encrypted, err := disk.EncryptedPartitionTable(base, disk.LUKSContainer{Passphrase: "secret", Clevis: &clevis})
+	require.NoError(t, err)
+
+	// the base partition table and the Clevis binding are not shared
+	assert.Nil(t, base.Partitions[0].LUKS)
+	clevis.Pin = "tang"
+	assert.Equal(t, "null", encrypted.RootPartition().LUKS.Clevis.Pin)
+
+	_, err = disk.EncryptedPartitionTable(encrypted, disk.LUKSContainer{Passphrase: "secret"})
+	assert.EqualError(t, err, "root partition is already encrypted")
+
+	pt := disk.CreatePartitionTable(nil, 0, encrypted, rand.New(rand.NewSource(0)))
+	require.Len(t, pt.Partitions, 2)
+	require.NotNil(t, pt.BootPartition())
+	assert.Nil(t, pt.BootPartition().LUKS)
+
+	luks := pt.RootPartition().LUKS
+	require.NotNil(t, luks)
+	assert.Equal(t, "secret", luks.Passphrase)
+	assert.NotEmpty(t, luks.UUID)
+	assert.NotEqual(t, pt.RootFilesystem().UUID, luks.UUID)
+	assert.Equal(t, "luks-"+luks.UUID, luks.MapperName())
+	assert.Empty(t, encrypted.RootPartition().LUKS.UUID)
+
+	assert.EqualError(t, pt.SetDeviceIDMode(disk.DeviceIDPartitionUUID),
+		"filesystems on encrypted partitions can't be identified by partition UUID")
+}
@@ -0,0 +1,194 @@
+package disk
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LVMPartitionGUID = "E6D6D379-F507-44C2-A23C-238F2A3DF928"
+	// MBR partition type of LVM physical volumes
+	LVMPartitionDOSID = "8e"
+
+	// Logical volumes are allocated in extents of this size
+	lvmExtentSize = 4 * 1024 * 1024
+	// Space at the start of a physical volume that is reserved for the
+	// LVM metadata
+	lvmMetadataSize = 4 * 1024 * 1024
+
+	// Name of the volume group of LVM partition tables
+	lvmVolumeGroupName = "rootvg"
+)
+
+// LVMVolumeGroup is the volume group whose physical volume is a partition
+type LVMVolumeGroup struct {
+	Name           string
+	LogicalVolumes []LVMLogicalVolume
+}
+
+// LVMLogicalVolume is a logical volume of a volume group, which contains a
+// filesystem
+type LVMLogicalVolume struct {
+	Name string
+	// Size of the logical volume in bytes, a multiple of the extent size
+	Size       uint64
+	Filesystem *Filesystem
+}
+
+// The logical volumes of LVM partition tables besides the root volume and
+// their sizes in bytes
+var lvmDefaultLogicalVolumes = []LVMLogicalVolume{
+	{
+		Name: "homelv",
+		Size: 1024 * 1024 * 1024,
+		Filesystem: &Filesystem{
+			Type:         "xfs",
+			Mountpoint:   "/home",
+			FSTabOptions: "defaults",
+		},
+	},
+	{
+		Name: "varlv",
+		Size: 2 * 1024 * 1024 * 1024,
+		Filesystem: &Filesystem{
+			Type:         "xfs",
+			Mountpoint:   "/var",
+			FSTabOptions: "defaults",
+		},
+	},
+}
+
+// LVMPartitionTable returns a copy of the partition table whose root
+// partition is replaced by an LVM physical volume. Its volume group contains
+// the root filesystem and separate filesystems for /home and /var, each on
+// a logical volume. A /boot partition is added if the partition table has
+// none. The logical volume of the root filesystem takes up the space the
+// others leave in the physical volume.
+func LVMPartitionTable(pt PartitionTable) (PartitionTable, error) {
+	lvm := pt.Clone()
+	rootIdx := lvm.RootPartitionIndex()
+	if rootIdx == -1 {
+		return pt, fmt.Errorf("partition table has no root partition")
+	}
+
+	root := &lvm.Partitions[rootIdx]
+	if root.VolumeGroup != nil {
+		return pt, fmt.Errorf("partition table already uses LVM")
+	}
+
+	vg := &LVMVolumeGroup{Name: lvmVolumeGroupName}
+	for _, lv := range lvmDefaultLogicalVolumes {
+		fs := *lv.Filesystem
+		vg.LogicalVolumes = append(vg.LogicalVolumes, LVMLogicalVolume{Name: lv.Name, Size: lv.Size, Filesystem: &fs})
+	}
+	vg.LogicalVolumes = append(vg.LogicalVolumes, LVMLogicalVolume{Name: "rootlv", Filesystem: root.Filesystem})
+
+	root.Filesystem = nil
+	root.VolumeGroup = vg
+	if lvm.Type == "dos" {
+		root.Type = LVMPartitionDOSID
+	} else {
+		root.Type = LVMPartitionGUID
+	}
+
+	lvm.ensureBootPartition()
+	return lvm, nil
+}
+
+func (vg *LVMVolumeGroup) clone() *LVMVolumeGroup {
+	clone := *vg
+	clone.LogicalVolumes = make([]LVMLogicalVolume, len(vg.LogicalVolumes))
+	for idx, lv := range vg.LogicalVolumes {
+		if lv.Filesystem != nil {
+			fs := *lv.Filesystem
+			fs.Subvolumes = append([]BtrfsSubvolume(nil), fs.Subvolumes...)
+			lv.Filesystem = &fs
+		}
+		clone.LogicalVolumes[idx] = lv
+	}
+	return &clone
+}
+
+// LogicalVolumeByMountpoint returns the logical volume of the volume group
+// whose filesystem is mounted at mountpoint, or nil if there is none.
+func (vg *LVMVolumeGroup) LogicalVolumeByMountpoint(mountpoint string) *LVMLogicalVolume {
+	for idx := range vg.LogicalVolumes {
+		lv := &vg.LogicalVolumes[idx]
+		if lv.Filesystem != nil && lv.Filesystem.Mountpoint == mountpoint {
+			return lv
+		}
+	}
+	return nil
+}
+
+// createLogicalVolume adds a logical volume of at least size bytes for a
+// new xfs filesystem mounted at mountpoint. It is named after the
+// mountpoint, e.g. srv_datalv for /srv/data.
+func (vg *LVMVolumeGroup) createLogicalVolume(mountpoint string, size uint64, rng *rand.Rand) *LVMLogicalVolume {
+	base := strings.ReplaceAll(strings.Trim(mountpoint, "/"), "/", "_")
+	name := base + "lv"
+	for idx := 2; vg.logicalVolumeByName(name) != nil; idx++ {
+		name = fmt.Sprintf("%s%dlv", base, idx)
+	}
+
+	lv := LVMLogicalVolume{
+		Name: name,
+		Size: alignUp(size, lvmExtentSize),
+		Filesystem: &Filesystem{
+			Type:         "xfs",
+			UUID:         uuid.Must(newRandomUUIDFromReader(rng)).String(),
+			Mountpoint:   mountpoint,
+			FSTabOptions: "defaults",
+		},
+	}
+	// the root volume is created last as it takes up the remaining space
+	last := len(vg.LogicalVolumes) - 1
+	vg.LogicalVolumes = append(vg.LogicalVolumes[:last], lv, vg.LogicalVolumes[last])
+	return &vg.LogicalVolumes[last]
+}
+
+func (vg *LVMVolumeGroup) logicalVolumeByName(name string) *LVMLogicalVolume {
+	for idx := range vg.LogicalVolumes {
+		if vg.LogicalVolumes[idx].Name == name {
+			return &vg.LogicalVolumes[idx]
+		}
+	}
+	return nil
+}
+
+// fixedSize returns the size in bytes of the physical volume of the volume
+// group without the root volume, whose size isn't fixed
+func (vg *LVMVolumeGroup) fixedSize() uint64 {
+	size := uint64(lvmMetadataSize)
+	for _, lv := range vg.LogicalVolumes {
+		if lv.Filesystem == nil || lv.Filesystem.Mountpoint != "/" {
+			size += lv.Size
+		}
+	}
+	return size
+}
+
+// fillRootVolume sizes the root volume to take up the space that the other
+// logical volumes leave in a physical volume of pvSize bytes
+func (vg *LVMVolumeGroup) fillRootVolume(pvSize uint64) {
+	root := vg.LogicalVolumeByMountpoint("/")
+	if root == nil {
+		return
+	}
+	fixed := vg.fixedSize()
+	if pvSize < fixed {
+		root.Size = 0
+		return
+	}
+	root.Size = (pvSize - fixed) / lvmExtentSize * lvmExtentSize
+}
+
+func alignUp(size, alignment uint64) uint64 {
+	if size == 0 {
+		return alignment
+	}
+	return (size + alignment - 1) / alignment * alignment
+}
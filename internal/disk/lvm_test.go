@@ -0,0 +1,147 @@
+package disk_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePartitionTable_LVM(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Size: 204800,
+				Type: disk.EFISystemPartitionGUID,
+				UUID: disk.EFISystemPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "vfat",
+					UUID:         disk.EFIFilesystemUUID,
+					Mountpoint:   "/boot/efi",
+					FSTabOptions: "umask=077",
+					FSTabPassNo:  2,
+				},
+			},
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	lvmBase, err := disk.LVMPartitionTable(base)
+	require.NoError(t, err)
+
+	// the base partition table is not modified
+	assert.Equal(t, "/", base.Partitions[1].Filesystem.Mountpoint)
+	assert.Nil(t, base.Partitions[1].VolumeGroup)
+
+	_, err = disk.LVMPartitionTable(lvmBase)
+	assert.EqualError(t, err, "partition table already uses LVM")
+
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/var", MinSize: 5 * 1024 * 1024 * 1024},
+		{Mountpoint: "/srv/data", MinSize: 1000000},
+		{Mountpoint: "/", Options: []string{"defaults", "noatime"}},
+	}
+	const imageSize = 20 * 1024 * 1024 * 1024
+	pt := disk.CreatePartitionTable(mountpoints, imageSize, lvmBase, rand.New(rand.NewSource(0)))
+
+	// the ESP, a new /boot partition and the physical volume
+	require.Len(t, pt.Partitions, 3)
+	require.NotNil(t, pt.BootPartition())
+	assert.Equal(t, "xfs", pt.BootPartition().Filesystem.Type)
+	assert.NotEmpty(t, pt.BootPartition().Filesystem.UUID)
+
+	pv := pt.RootPartition()
+	require.NotNil(t, pv)
+	assert.Equal(t, disk.LVMPartitionGUID, pv.Type)
+	assert.Nil(t, pv.Filesystem)
+	require.NotNil(t, pv.VolumeGroup)
+	assert.Equal(t, "rootvg", pv.VolumeGroup.Name)
+
+	var names []string
+	for _, lv := range pv.VolumeGroup.LogicalVolumes {
+		names = append(names, lv.Name)
+		assert.NotEmpty(t, lv.Filesystem.UUID, lv.Name)
+		assert.Zero(t, lv.Size%(4*1024*1024), lv.Name)
+	}
+	// the root volume is created last
+	assert.Equal(t, []string{"homelv", "varlv", "srv_datalv", "rootlv"}, names)
+
+	lvs := pv.VolumeGroup.LogicalVolumes
+	assert.Equal(t, uint64(1024*1024*1024), lvs[0].Size)
+	// the minimum sizes and the overhead of xfs, rounded up to extents
+	assert.Equal(t, uint64(5288*1024*1024), lvs[1].Size)
+	assert.Equal(t, uint64(68*1024*1024), lvs[2].Size)
+	var used uint64
+	for _, lv := range lvs {
+		used += lv.Size
+	}
+	pvSize := pv.Size * pt.GetSectorSize()
+	assert.LessOrEqual(t, used, pvSize)
+	assert.Greater(t, used+8*1024*1024, pvSize)
+
+	assert.Same(t, lvs[3].Filesystem, pt.RootFilesystem())
+	assert.Equal(t, "defaults,noatime", pt.RootFilesystem().FSTabOptions)
+	assert.Equal(t, "UUID="+lvs[3].Filesystem.UUID, pt.RootDeviceSpec())
+
+	options := make(map[string]string)
+	for _, entry := range pt.FSTabStageOptionsV2().FileSystems {
+		options[entry.Path] = entry.Options
+	}
+	assert.Equal(t, map[string]string{
+		"/boot/efi": "umask=077",
+		"/boot":     "defaults",
+		"/":         "defaults,noatime",
+		"/home":     "defaults",
+		"/var":      "defaults",
+		"/srv/data": "defaults",
+	}, options)
+
+	require.NoError(t, pt.SetDeviceIDMode(disk.DeviceIDFilesystemLabel))
+	assert.Equal(t, "LABEL=root", pt.RootDeviceSpec())
+	assert.Equal(t, "srv-data", pt.FilesystemByMountpoint("/srv/data").Label)
+	assert.EqualError(t, pt.SetDeviceIDMode(disk.DeviceIDPartitionUUID),
+		"filesystems on the logical volumes of rootvg can't be identified by partition UUID")
+}
+
+func TestLVMPartitionTable_DOS(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "0x14fc63d2",
+		Type: "dos",
+		Partitions: []disk.Partition{
+			{
+				Size:     8192,
+				Type:     "41",
+				Bootable: true,
+			},
+			{
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	pt, err := disk.LVMPartitionTable(base)
+	require.NoError(t, err)
+	require.Len(t, pt.Partitions, 3)
+	assert.Equal(t, "41", pt.Partitions[0].Type)
+	assert.Equal(t, "/boot", pt.Partitions[1].Filesystem.Mountpoint)
+	assert.Equal(t, "", pt.Partitions[1].Type)
+	assert.Equal(t, disk.LVMPartitionDOSID, pt.Partitions[2].Type)
+
+	_, err = disk.LVMPartitionTable(disk.PartitionTable{Type: "dos"})
+	assert.EqualError(t, err, "partition table has no root partition")
+}
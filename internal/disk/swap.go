@@ -0,0 +1,73 @@
+package disk
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// partition type of Linux swap partitions on gpt and dos partition
+	// tables
+	SwapPartitionGUID  = "0657FD6D-A4AB-43C4-84E5-0933C84B4F4F"
+	SwapPartitionDOSID = "82"
+
+	// type of the filesystem of swap partitions, which are activated
+	// instead of mounted
+	SwapFilesystemType = "swap"
+)
+
+// SwapPartitionTable returns a copy of the partition table with a swap
+// partition of size bytes
+func SwapPartitionTable(pt PartitionTable, size uint64) (PartitionTable, error) {
+	if pt.SwapPartitionIndex() != -1 {
+		return pt, fmt.Errorf("partition table already has a swap partition")
+	}
+	if size == 0 {
+		return pt, fmt.Errorf("swap partition must not be empty")
+	}
+
+	swap := Partition{
+		Size: size / pt.GetSectorSize(),
+		Type: SwapPartitionDOSID,
+		Filesystem: &Filesystem{
+			Type:         SwapFilesystemType,
+			FSTabOptions: "sw",
+		},
+	}
+	if pt.Type == "gpt" {
+		swap.Type = SwapPartitionGUID
+	}
+
+	withSwap := pt.Clone()
+	withSwap.Partitions = append(withSwap.Partitions, swap)
+	return withSwap, nil
+}
+
+// SwapPartitionIndex returns the index of the swap partition, or -1 if the
+// partition table has none
+func (pt PartitionTable) SwapPartitionIndex() int {
+	for idx, p := range pt.Partitions {
+		if p.Filesystem != nil && p.Filesystem.Type == SwapFilesystemType {
+			return idx
+		}
+	}
+	return -1
+}
+
+// setSwapUUIDs assigns random UUIDs to the swap partition and its swap
+// signature if they have none
+func (pt *PartitionTable) setSwapUUIDs(rng *rand.Rand) {
+	idx := pt.SwapPartitionIndex()
+	if idx == -1 {
+		return
+	}
+	swap := &pt.Partitions[idx]
+	if swap.Filesystem.UUID == "" {
+		swap.Filesystem.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	}
+	if swap.UUID == "" && pt.Type == "gpt" {
+		swap.UUID = uuid.Must(newRandomUUIDFromReader(rng)).String()
+	}
+}
@@ -0,0 +1,70 @@
+package disk_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapPartitionTable(t *testing.T) {
+	base := disk.PartitionTable{
+		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{
+				Type: disk.FilesystemDataGUID,
+				UUID: disk.RootPartitionUUID,
+				Filesystem: &disk.Filesystem{
+					Type:         "xfs",
+					Mountpoint:   "/",
+					FSTabOptions: "defaults",
+				},
+			},
+		},
+	}
+	withSwap, err := disk.SwapPartitionTable(base, 1073741824)
+	require.NoError(t, err)
+	assert.Len(t, base.Partitions, 1)
+	assert.Equal(t, -1, base.SwapPartitionIndex())
+
+	_, err = disk.SwapPartitionTable(withSwap, 1073741824)
+	assert.EqualError(t, err, "partition table already has a swap partition")
+	_, err = disk.SwapPartitionTable(base, 0)
+	assert.EqualError(t, err, "swap partition must not be empty")
+
+	pt := disk.CreatePartitionTable(nil, 0, withSwap, rand.New(rand.NewSource(0)))
+	idx := pt.SwapPartitionIndex()
+	require.Equal(t, 1, idx)
+	swap := pt.Partitions[idx]
+	assert.Equal(t, disk.SwapPartitionGUID, swap.Type)
+	assert.Equal(t, uint64(2097152), swap.Size)
+	assert.NotEmpty(t, swap.UUID)
+	assert.NotEmpty(t, swap.Filesystem.UUID)
+	// the root partition fills the rest of the image after the swap partition
+	assert.Greater(t, pt.RootPartition().Start, swap.Start)
+
+	entries := pt.FSTabStageOptionsV2().FileSystems
+	require.Len(t, entries, 2)
+	assert.Equal(t, "swap", entries[1].VFSType)
+	assert.Equal(t, "none", entries[1].Path)
+	assert.Equal(t, "sw", entries[1].Options)
+	assert.Equal(t, swap.Filesystem.UUID, entries[1].UUID)
+
+	require.NoError(t, pt.SetDeviceIDMode(disk.DeviceIDFilesystemLabel))
+	assert.Equal(t, "swap", pt.Partitions[idx].Filesystem.Label)
+	assert.Equal(t, "root", pt.RootFilesystem().Label)
+
+	dos := base.Clone()
+	dos.Type = "dos"
+	dos.Partitions[0].Type = "83"
+	dos.Partitions[0].UUID = ""
+	withSwap, err = disk.SwapPartitionTable(dos, 1073741824)
+	require.NoError(t, err)
+	pt = disk.CreatePartitionTable(nil, 0, withSwap, rand.New(rand.NewSource(0)))
+	swap = pt.Partitions[pt.SwapPartitionIndex()]
+	assert.Equal(t, disk.SwapPartitionDOSID, swap.Type)
+	assert.Empty(t, swap.UUID)
+}
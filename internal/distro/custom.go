@@ -0,0 +1,171 @@
+package distro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/rpmmd"
+)
+
+// CustomImageTypeName is the name of the image type of composes that build
+// a manifest given by the user instead of one generated by composer.
+const CustomImageTypeName = "custom"
+
+// customImageType is the image type of composes that build a manifest given
+// by the user. The last pipeline of the manifest is exported and the name of
+// the artifact is taken from the filename option of the last stage of that
+// pipeline that has one.
+type customImageType struct {
+	arch     Arch
+	export   string
+	filename string
+}
+
+// The parts of an osbuild manifest that are needed to validate it. Stages
+// are not parsed into their osbuild2 types, because user manifests may use
+// stages that composer doesn't know about.
+type customManifest struct {
+	Version   string                     `json:"version"`
+	Pipelines []customManifestPipeline   `json:"pipelines"`
+	Sources   map[string]json.RawMessage `json:"sources"`
+}
+
+type customManifestPipeline struct {
+	Name   string `json:"name"`
+	Build  string `json:"build"`
+	Stages []struct {
+		Type    string `json:"type"`
+		Options struct {
+			Filename string `json:"filename"`
+		} `json:"options"`
+	} `json:"stages"`
+}
+
+// NewCustomImageType validates the structure of a version 2 manifest and
+// returns the image type of composes that build it.
+func NewCustomImageType(arch Arch, manifest Manifest) (ImageType, error) {
+	var m customManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+
+	if m.Version != "2" {
+		return nil, fmt.Errorf("unsupported manifest version %q, only version 2 manifests can be built", m.Version)
+	}
+	if len(m.Pipelines) == 0 {
+		return nil, errors.New("the manifest doesn't contain any pipelines")
+	}
+
+	names := make(map[string]bool)
+	for idx, p := range m.Pipelines {
+		if p.Name == "" {
+			return nil, fmt.Errorf("pipeline %d of the manifest has no name", idx)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("the manifest contains more than one pipeline named %q", p.Name)
+		}
+		if p.Build != "" && !names[strings.TrimPrefix(p.Build, "name:")] {
+			return nil, fmt.Errorf("the build pipeline %q of pipeline %q is not defined before it", p.Build, p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	export := m.Pipelines[len(m.Pipelines)-1]
+	var filename string
+	for _, stage := range export.Stages {
+		if stage.Options.Filename != "" {
+			filename = stage.Options.Filename
+		}
+	}
+	if filename == "" || strings.Contains(filename, "/") {
+		return nil, fmt.Errorf("the last pipeline of the manifest, %q, doesn't produce a file", export.Name)
+	}
+
+	return &customImageType{
+		arch:     arch,
+		export:   export.Name,
+		filename: filename,
+	}, nil
+}
+
+// CheckManifestSources returns an error if the sources of the manifest
+// reference files of the host with file:// URLs.
+func CheckManifestSources(manifest Manifest) error {
+	var m customManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return fmt.Errorf("invalid manifest: %v", err)
+	}
+
+	for name, source := range m.Sources {
+		var items interface{}
+		if err := json.Unmarshal(source, &items); err != nil {
+			return fmt.Errorf("invalid source %q: %v", name, err)
+		}
+		if url := findFileURL(items); url != "" {
+			return fmt.Errorf("source %q references a local file: %s", name, url)
+		}
+	}
+	return nil
+}
+
+// Returns the first string in v that is a file:// URL
+func findFileURL(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(strings.ToLower(v), "file:") {
+			return v
+		}
+	case []interface{}:
+		for _, item := range v {
+			if url := findFileURL(item); url != "" {
+				return url
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if url := findFileURL(item); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+func (t *customImageType) Name() string {
+	return CustomImageTypeName
+}
+
+func (t *customImageType) Arch() Arch {
+	return t.arch
+}
+
+func (t *customImageType) Filename() string {
+	return t.filename
+}
+
+func (t *customImageType) MIMEType() string {
+	return "application/octet-stream"
+}
+
+func (t *customImageType) OSTreeRef() string {
+	return ""
+}
+
+func (t *customImageType) Size(size uint64) uint64 {
+	return size
+}
+
+func (t *customImageType) PackageSets(bp blueprint.Blueprint) map[string]rpmmd.PackageSet {
+	return nil
+}
+
+func (t *customImageType) Exports() []string {
+	return []string{t.export}
+}
+
+func (t *customImageType) Manifest(b *blueprint.Customizations, options ImageOptions, repos []rpmmd.RepoConfig, packageSpecSets map[string][]rpmmd.PackageSpec, seed int64) (Manifest, error) {
+	return nil, errors.New("custom image types are built from the manifest they were created for")
+}
@@ -0,0 +1,95 @@
+package distro_test
+
+import (
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/distro"
+	"github.com/osbuild/osbuild-composer/internal/distro/test_distro"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const customManifest = `
+{
+	"version": "2",
+	"pipelines": [
+		{
+			"name": "build",
+			"runner": "org.osbuild.rhel86",
+			"stages": [{"type": "org.osbuild.rpm", "options": {}}]
+		},
+		{
+			"name": "os",
+			"build": "name:build",
+			"stages": [{"type": "org.osbuild.rpm", "options": {}}]
+		},
+		{
+			"name": "image",
+			"build": "name:build",
+			"stages": [
+				{"type": "org.osbuild.truncate", "options": {"filename": "disk.img", "size": "10737418240"}},
+				{"type": "org.osbuild.copy", "options": {}}
+			]
+		}
+	],
+	"sources": {
+		"org.osbuild.curl": {
+			"items": {
+				"sha256:0e49b8b7e6e9d5e1a9f1cdeb7e4b0ae4a01d7d2fa8c1d7d3b0e4d4a5c1d7e3f9": "https://example.com/bash.rpm"
+			}
+		}
+	}
+}`
+
+func TestNewCustomImageType(t *testing.T) {
+	arch, err := test_distro.New().GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+
+	imageType, err := distro.NewCustomImageType(arch, distro.Manifest(customManifest))
+	require.NoError(t, err)
+	assert.Equal(t, distro.CustomImageTypeName, imageType.Name())
+	assert.Equal(t, arch, imageType.Arch())
+	assert.Equal(t, "disk.img", imageType.Filename())
+	assert.Equal(t, []string{"image"}, imageType.Exports())
+	assert.Equal(t, uint64(42), imageType.Size(42))
+	_, err = imageType.Manifest(nil, distro.ImageOptions{}, nil, nil, 0)
+	assert.Error(t, err)
+}
+
+func TestNewCustomImageType_Invalid(t *testing.T) {
+	arch, err := test_distro.New().GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+
+	cases := []struct {
+		manifest string
+		err      string
+	}{
+		{`[]`, "invalid manifest: json: cannot unmarshal array into Go value of type distro.customManifest"},
+		{`{"pipeline": {}}`, `unsupported manifest version "", only version 2 manifests can be built`},
+		{`{"version": "1"}`, `unsupported manifest version "1", only version 2 manifests can be built`},
+		{`{"version": "2"}`, "the manifest doesn't contain any pipelines"},
+		{`{"version": "2", "pipelines": []}`, "the manifest doesn't contain any pipelines"},
+		{`{"version": "2", "pipelines": [{"stages": []}]}`, "pipeline 0 of the manifest has no name"},
+		{`{"version": "2", "pipelines": [{"name": "a"}, {"name": "a"}]}`, `the manifest contains more than one pipeline named "a"`},
+		{`{"version": "2", "pipelines": [{"name": "a", "build": "name:b"}, {"name": "b"}]}`, `the build pipeline "name:b" of pipeline "a" is not defined before it`},
+		{`{"version": "2", "pipelines": [{"name": "tree", "stages": [{"type": "org.osbuild.rpm"}]}]}`, `the last pipeline of the manifest, "tree", doesn't produce a file`},
+		{`{"version": "2", "pipelines": [{"name": "image", "stages": [{"type": "org.osbuild.truncate", "options": {"filename": "../disk.img"}}]}]}`, `the last pipeline of the manifest, "image", doesn't produce a file`},
+	}
+	for _, c := range cases {
+		_, err := distro.NewCustomImageType(arch, distro.Manifest(c.manifest))
+		assert.EqualError(t, err, c.err, c.manifest)
+	}
+}
+
+func TestCheckManifestSources(t *testing.T) {
+	assert.NoError(t, distro.CheckManifestSources(distro.Manifest(customManifest)))
+	assert.NoError(t, distro.CheckManifestSources(distro.Manifest(`{"version": "2"}`)))
+
+	for _, manifest := range []string{
+		`{"sources": {"org.osbuild.curl": {"items": {"sha256:aaaa": "file:///etc/shadow"}}}}`,
+		`{"sources": {"org.osbuild.curl": {"items": {"sha256:aaaa": {"url": "FILE:/etc/shadow"}}}}}`,
+		`{"sources": {"org.osbuild.ostree": {"items": {"abcd": {"remote": {"url": "file:///var/repo"}}}}}}`,
+	} {
+		assert.Error(t, distro.CheckManifestSources(distro.Manifest(manifest)), manifest)
+	}
+}
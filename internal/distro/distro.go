@@ -9,8 +9,10 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/container"
 	"github.com/osbuild/osbuild-composer/internal/disk"
 	"github.com/osbuild/osbuild-composer/internal/rpmmd"
 )
@@ -114,9 +116,146 @@ type ImageType interface {
 
 // The ImageOptions specify options for a specific image build
 type ImageOptions struct {
-	OSTree       OSTreeImageOptions
-	Size         uint64
+	OSTree OSTreeImageOptions
+	Size   uint64
+	// FixedSize is set if the user chose Size. Images of a fixed size
+	// that can't hold their partitions are rejected, other images are
+	// grown to hold them.
+	FixedSize    bool
 	Subscription *SubscriptionImageOptions
+	// BuildDate is the creation time of the compose, recorded in the
+	// metadata of the image. The zero value selects the Unix epoch, which
+	// keeps manifests reproducible.
+	BuildDate time.Time
+	// SourceDateEpoch, when set, makes the build reproducible: it replaces
+	// BuildDate and is exported as SOURCE_DATE_EPOCH to all stages.
+	SourceDateEpoch *time.Time
+	// ComposeID, when set, replaces the compose ID that the distribution
+	// derives from its release and BuildDate, e.g. in the .discinfo of
+	// installer ISOs. Rebuilders use it to stamp their own compose IDs.
+	ComposeID string
+	// Containers are the container images of the blueprint, pinned to the
+	// digests they were resolved to when the compose was started.
+	Containers []container.Spec
+	// Qcow2Compression overrides the default compression of the image
+	// types that produce qcow2 images, Qcow2CompressionNone disables it.
+	Qcow2Compression string
+	// SectorSize overrides the logical sector size in bytes of the disks of
+	// the image types with partition tables, 512 or 4096.
+	SectorSize uint64
+}
+
+// Qcow2CompressionNone selects uncompressed qcow2 images
+const Qcow2CompressionNone = "none"
+
+// CheckUnsupportedImageOptions returns an error if options set the compose
+// ID, the source date epoch, containers, the qcow2 compression, the sector
+// size, ostree static deltas or mutual TLS. Distributions that don't
+// implement these options reject them instead of ignoring them.
+func CheckUnsupportedImageOptions(distroName string, options ImageOptions) error {
+	var name string
+	switch {
+	case options.ComposeID != "":
+		name = "compose ID"
+	case options.SourceDateEpoch != nil:
+		name = "source date epoch"
+	case len(options.Containers) > 0:
+		name = "containers"
+	case options.Qcow2Compression != "":
+		name = "qcow2 compression"
+	case options.SectorSize != 0:
+		name = "sector size"
+	case options.OSTree.StaticDeltas:
+		name = "ostree static deltas"
+	case options.OSTree.MTLS:
+		name = "ostree mutual TLS"
+	default:
+		return nil
+	}
+	return fmt.Errorf("the %s image option is not supported for %s", name, distroName)
+}
+
+// CheckUnsupportedCustomizations returns an error if c contains
+// customizations that were added after the distributions which only
+// implement the original set, e.g. kdump or the masked services. supported
+// names the newer customizations that the distribution implements anyway,
+// e.g. "Filesystem.Options".
+func CheckUnsupportedCustomizations(distroName string, c *blueprint.Customizations, supported ...string) error {
+	if c == nil {
+		return nil
+	}
+
+	allowed := append([]string{"Hostname", "Kernel", "SSHKey", "User", "Group", "Timezone", "Locale", "Firewall", "Services", "Filesystem", "InstallationDevice"}, supported...)
+	if err := c.CheckAllowed(allowed...); err != nil {
+		return fmt.Errorf("blueprint contains customizations that are not supported for %s: %v", distroName, err)
+	}
+
+	var options []string
+	if c.Kernel != nil && len(c.Kernel.Remove) > 0 {
+		options = append(options, "Kernel.Remove")
+	}
+	if c.Kernel != nil && c.Kernel.Modules != nil {
+		options = append(options, "Kernel.Modules")
+	}
+	if c.Firewall != nil && c.Firewall.DefaultZone != "" {
+		options = append(options, "Firewall.DefaultZone")
+	}
+	if c.Firewall != nil && len(c.Firewall.Zones) > 0 {
+		options = append(options, "Firewall.Zones")
+	}
+	if c.Services != nil && len(c.Services.Masked) > 0 {
+		options = append(options, "Services.Masked")
+	}
+	if c.Timezone != nil && c.Timezone.LeapsecTz != nil {
+		options = append(options, "Timezone.LeapsecTz")
+	}
+	if c.Timezone != nil && c.Timezone.CommentPools {
+		options = append(options, "Timezone.CommentPools")
+	}
+	for _, user := range c.User {
+		if user.HomeMode != nil {
+			options = append(options, "User.HomeMode")
+		}
+		if user.System != nil {
+			options = append(options, "User.System")
+		}
+		if user.Locked != nil {
+			options = append(options, "User.Locked")
+		}
+		if user.ExpireDate != nil {
+			options = append(options, "User.ExpireDate")
+		}
+		if user.Sudo != nil || user.SudoNopasswd != nil {
+			options = append(options, "User.Sudo")
+		}
+	}
+	for _, group := range c.Group {
+		if group.Sudo != nil || group.SudoNopasswd != nil {
+			options = append(options, "Group.Sudo")
+		}
+	}
+	for _, fs := range c.Filesystem {
+		if len(fs.Options) > 0 {
+			options = append(options, "Filesystem.Options")
+		}
+		if fs.FSType != "" {
+			options = append(options, "Filesystem.FSType")
+		}
+		if len(fs.Subvolumes) > 0 {
+			options = append(options, "Filesystem.Subvolumes")
+		}
+	}
+
+	supportedMap := make(map[string]bool)
+	for _, option := range supported {
+		supportedMap[option] = true
+	}
+	for _, option := range options {
+		if !supportedMap[option] {
+			return fmt.Errorf("blueprint contains customizations that are not supported for %s: '%s' is not allowed", distroName, option)
+		}
+	}
+	return nil
 }
 
 // The OSTreeImageOptions specify ostree-specific image options
@@ -124,6 +263,13 @@ type OSTreeImageOptions struct {
 	Ref    string
 	Parent string
 	URL    string
+	// StaticDeltas generates a static delta from the parent commit to the
+	// new one, which requires a Parent and URL
+	StaticDeltas bool
+	// MTLS is set if the repository at URL requires mutual TLS. The
+	// credentials are never part of the manifest, workers pull with the
+	// ones of the secret named in the job.
+	MTLS bool
 }
 
 // The SubscriptionImageOptions specify subscription-specific image options
@@ -176,6 +322,51 @@ func (m Manifest) Version() (string, error) {
 	}
 }
 
+// SummarizeSources returns a copy of the manifest in which the definition of
+// every source is replaced by the number of items it fetches, e.g.
+// {"org.osbuild.curl": {"count": 312}}. Sources of real manifests list every
+// package and make up most of their size.
+func (m Manifest) SummarizeSources() (Manifest, error) {
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(m, &manifest); err != nil {
+		return nil, err
+	}
+
+	raw, exists := manifest["sources"]
+	if !exists {
+		return m, nil
+	}
+	var sources map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &sources); err != nil {
+		return nil, err
+	}
+
+	type sourceSummary struct {
+		Count int `json:"count"`
+	}
+	summary := make(map[string]sourceSummary, len(sources))
+	for name, source := range sources {
+		// version 2 sources list their items in "items", version 1
+		// sources in a field of their own, e.g. "urls"
+		var count int
+		for _, field := range source {
+			var items map[string]json.RawMessage
+			if json.Unmarshal(field, &items) == nil {
+				count += len(items)
+			}
+		}
+		summary[name] = sourceSummary{count}
+	}
+
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	manifest["sources"] = raw
+
+	return json.Marshal(manifest)
+}
+
 func GetHostDistroName() (string, bool, bool, error) {
 	f, err := os.Open("/etc/os-release")
 	if err != nil {
@@ -3,6 +3,7 @@ package distro_test
 import (
 	"testing"
 
+	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	"github.com/osbuild/osbuild-composer/internal/distro/distro_test_common"
 	"github.com/osbuild/osbuild-composer/internal/distroregistry"
@@ -96,3 +97,71 @@ func TestDistro_Version(t *testing.T) {
 		require.Error(err, "Invalid manifest did not return an error")
 	}
 }
+
+func TestDistro_SummarizeSources(t *testing.T) {
+	require := require.New(t)
+
+	manifest := distro.Manifest(`{
+	"version": "2",
+	"pipelines": [{"name": "os"}],
+	"sources": {
+		"org.osbuild.curl": {
+			"items": {
+				"sha256:aaa": {"url": "https://example.com/a.rpm"},
+				"sha256:bbb": {"url": "https://example.com/b.rpm"}
+			}
+		},
+		"org.osbuild.inline": {
+			"items": {}
+		}
+	}
+}`)
+	summarized, err := manifest.SummarizeSources()
+	require.NoError(err)
+	require.JSONEq(`{
+	"version": "2",
+	"pipelines": [{"name": "os"}],
+	"sources": {
+		"org.osbuild.curl": {"count": 2},
+		"org.osbuild.inline": {"count": 0}
+	}
+}`, string(summarized))
+
+	// version 1 manifests list their urls in a field of their own
+	summarized, err = distro.Manifest(`{"sources": {"org.osbuild.files": {"urls": {"sha256:aaa": "https://example.com/a.rpm"}}}}`).SummarizeSources()
+	require.NoError(err)
+	require.JSONEq(`{"sources": {"org.osbuild.files": {"count": 1}}}`, string(summarized))
+
+	summarized, err = distro.Manifest(`{"version": "2"}`).SummarizeSources()
+	require.NoError(err)
+	require.JSONEq(`{"version": "2"}`, string(summarized))
+
+	_, err = distro.Manifest("{").SummarizeSources()
+	require.Error(err)
+}
+
+func TestCheckUnsupportedImageOptions(t *testing.T) {
+	require.NoError(t, distro.CheckUnsupportedImageOptions("rhel-85", distro.ImageOptions{Size: 1024}))
+	require.EqualError(t, distro.CheckUnsupportedImageOptions("rhel-85", distro.ImageOptions{ComposeID: "RHEL-8.5.0-20220314.1"}),
+		"the compose ID image option is not supported for rhel-85")
+	require.EqualError(t, distro.CheckUnsupportedImageOptions("rhel-85", distro.ImageOptions{Qcow2Compression: "zstd"}),
+		"the qcow2 compression image option is not supported for rhel-85")
+	require.EqualError(t, distro.CheckUnsupportedImageOptions("rhel-85", distro.ImageOptions{SectorSize: 4096}),
+		"the sector size image option is not supported for rhel-85")
+}
+
+func TestCheckUnsupportedCustomizations(t *testing.T) {
+	hostname := "my-host"
+	require.NoError(t, distro.CheckUnsupportedCustomizations("rhel-85", nil))
+	require.NoError(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Hostname: &hostname}))
+	require.EqualError(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Kdump: &blueprint.KdumpCustomization{}}),
+		"blueprint contains customizations that are not supported for rhel-85: 'Kdump' is not allowed")
+	require.EqualError(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Services: &blueprint.ServicesCustomization{Masked: []string{"rpcbind.socket"}}}),
+		"blueprint contains customizations that are not supported for rhel-85: 'Services.Masked' is not allowed")
+	require.EqualError(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Timezone: &blueprint.TimezoneCustomization{NTPServers: []string{"ntp.example.com"}, CommentPools: true}}),
+		"blueprint contains customizations that are not supported for rhel-85: 'Timezone.CommentPools' is not allowed")
+
+	filesystems := []blueprint.FilesystemCustomization{{Mountpoint: "/home", MinSize: 1024, Options: []string{"nodev"}}}
+	require.Error(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Filesystem: filesystems}))
+	require.NoError(t, distro.CheckUnsupportedCustomizations("rhel-85", &blueprint.Customizations{Filesystem: filesystems}, "Filesystem.Options"))
+}
@@ -33,12 +33,18 @@ func TestDistro_Manifest(t *testing.T, pipelinePath string, prefix string, regis
 			GPGKey     string `json:"gpgkey,omitempty"`
 			CheckGPG   bool   `json:"check_gpg,omitempty"`
 		}
+		type ostreeOptions struct {
+			Ref    string `json:"ref"`
+			Parent string `json:"parent"`
+			URL    string `json:"url"`
+		}
 		type composeRequest struct {
 			Distro       string               `json:"distro"`
 			Arch         string               `json:"arch"`
 			ImageType    string               `json:"image-type"`
 			Repositories []repository         `json:"repositories"`
 			Blueprint    *blueprint.Blueprint `json:"blueprint"`
+			OSTree       ostreeOptions        `json:"ostree"`
 		}
 		var tt struct {
 			ComposeRequest *composeRequest                `json:"compose-request"`
@@ -82,12 +88,19 @@ func TestDistro_Manifest(t *testing.T, pipelinePath string, prefix string, regis
 				t.Errorf("unknown image type: %v", tt.ComposeRequest.ImageType)
 				return
 			}
+			ostreeOptions := distro.OSTreeImageOptions{
+				Ref:    tt.ComposeRequest.OSTree.Ref,
+				Parent: tt.ComposeRequest.OSTree.Parent,
+				URL:    tt.ComposeRequest.OSTree.URL,
+			}
+			if ostreeOptions.Ref == "" {
+				// use default OSTreeRef for image type
+				ostreeOptions.Ref = imageType.OSTreeRef()
+			}
 			got, err := imageType.Manifest(tt.ComposeRequest.Blueprint.Customizations,
 				distro.ImageOptions{
-					Size: imageType.Size(0),
-					OSTree: distro.OSTreeImageOptions{
-						Ref: imageType.OSTreeRef(),
-					},
+					Size:   imageType.Size(0),
+					OSTree: ostreeOptions,
 				},
 				repos,
 				tt.PackageSets,
@@ -0,0 +1,76 @@
+package distro
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MountpointPolicy decides which mountpoints blueprints may request custom
+// filesystems for.
+type MountpointPolicy struct {
+	// Mountpoints that are allowed, together with all mountpoints below
+	// them. "/" only allows the root mountpoint itself.
+	AllowedPrefixes []string
+
+	// Mountpoints that are never allowed, even when they are below one of
+	// the allowed prefixes.
+	DeniedPaths []string
+}
+
+// MountpointPolicyError is returned for mountpoints that a policy rejects. Rule
+// names the rule of the policy that rejected the mountpoint.
+type MountpointPolicyError struct {
+	Mountpoint string
+	Rule       string
+}
+
+func (e *MountpointPolicyError) Error() string {
+	return fmt.Sprintf("%q (%s)", e.Mountpoint, e.Rule)
+}
+
+// Check returns a *MountpointPolicyError if the policy does not allow
+// mountpoint, nil otherwise.
+func (p MountpointPolicy) Check(mountpoint string) error {
+	if len(p.AllowedPrefixes) == 0 {
+		return &MountpointPolicyError{mountpoint, "image type does not allow custom mountpoints"}
+	}
+
+	if !path.IsAbs(mountpoint) || path.Clean(mountpoint) != mountpoint {
+		return &MountpointPolicyError{mountpoint, "mountpoint must be an absolute and clean path"}
+	}
+
+	for _, denied := range p.DeniedPaths {
+		if mountpoint == denied {
+			return &MountpointPolicyError{mountpoint, fmt.Sprintf("denied path %q", denied)}
+		}
+	}
+
+	for _, allowed := range p.AllowedPrefixes {
+		if mountpoint == allowed || (allowed != "/" && strings.HasPrefix(mountpoint, allowed+"/")) {
+			return nil
+		}
+	}
+
+	return &MountpointPolicyError{mountpoint, "not below any allowed prefix"}
+}
+
+// Allow returns a copy of the policy which additionally allows the given
+// prefixes. Policies which do not allow any custom mountpoints are returned
+// unchanged.
+func (p MountpointPolicy) Allow(prefixes ...string) MountpointPolicy {
+	if len(p.AllowedPrefixes) == 0 {
+		return p
+	}
+	return MountpointPolicy{
+		AllowedPrefixes: append(append([]string{}, p.AllowedPrefixes...), prefixes...),
+		DeniedPaths:     append([]string{}, p.DeniedPaths...),
+	}
+}
+
+// MountpointPolicyExtender is implemented by distros whose mountpoint policy
+// can be extended by the deployment, for example to allow mountpoints that
+// on-premises installations need.
+type MountpointPolicyExtender interface {
+	AllowMountpoints(prefixes ...string)
+}
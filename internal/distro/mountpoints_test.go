@@ -0,0 +1,74 @@
+package distro_test
+
+import (
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/distro"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountpointPolicy_Check(t *testing.T) {
+	policy := distro.MountpointPolicy{
+		AllowedPrefixes: []string{"/", "/var", "/boot"},
+		DeniedPaths:     []string{"/boot/efi", "/var/run"},
+	}
+
+	cases := []struct {
+		mountpoint string
+		rule       string
+	}{
+		{"/", ""},
+		{"/var", ""},
+		{"/var/log", ""},
+		{"/var/log/audit", ""},
+		{"/boot", ""},
+		{"/boot/efi/extra", ""},
+		{"/variable", "not below any allowed prefix"},
+		{"/opt", "not below any allowed prefix"},
+		{"/boot/efi", `denied path "/boot/efi"`},
+		{"/var/run", `denied path "/var/run"`},
+		{"//", "mountpoint must be an absolute and clean path"},
+		{"/var/", "mountpoint must be an absolute and clean path"},
+		{"/var/../opt", "mountpoint must be an absolute and clean path"},
+		{"var", "mountpoint must be an absolute and clean path"},
+	}
+	for _, c := range cases {
+		err := policy.Check(c.mountpoint)
+		if c.rule == "" {
+			assert.NoError(t, err, c.mountpoint)
+			continue
+		}
+		require.Error(t, err, c.mountpoint)
+		policyErr, ok := err.(*distro.MountpointPolicyError)
+		require.True(t, ok)
+		assert.Equal(t, c.mountpoint, policyErr.Mountpoint)
+		assert.Equal(t, c.rule, policyErr.Rule)
+	}
+}
+
+func TestMountpointPolicy_Empty(t *testing.T) {
+	policy := distro.MountpointPolicy{}
+	for _, mountpoint := range []string{"/", "/var", "/opt/app"} {
+		assert.EqualError(t, policy.Check(mountpoint), `"`+mountpoint+`" (image type does not allow custom mountpoints)`)
+	}
+
+	// policies which don't allow anything can't be extended
+	assert.Error(t, policy.Allow("/opt").Check("/opt"))
+}
+
+func TestMountpointPolicy_Allow(t *testing.T) {
+	policy := distro.MountpointPolicy{
+		AllowedPrefixes: []string{"/var"},
+		DeniedPaths:     []string{"/srv/secret"},
+	}
+	extended := policy.Allow("/srv")
+
+	assert.NoError(t, extended.Check("/srv/data"))
+	assert.Error(t, extended.Check("/srv/secret"))
+	assert.NoError(t, extended.Check("/var/log"))
+
+	// the original policy is not modified
+	assert.Error(t, policy.Check("/srv/data"))
+	assert.Equal(t, []string{"/var"}, policy.AllowedPrefixes)
+}
@@ -238,6 +238,12 @@ func (t *imageType) Manifest(c *blueprint.Customizations,
 	repos []rpmmd.RepoConfig,
 	packageSpecSets map[string][]rpmmd.PackageSpec,
 	seed int64) (distro.Manifest, error) {
+	if err := distro.CheckUnsupportedImageOptions(t.arch.distro.name, options); err != nil {
+		return distro.Manifest{}, err
+	}
+	if err := distro.CheckUnsupportedCustomizations(t.arch.distro.name, c); err != nil {
+		return distro.Manifest{}, err
+	}
 	pipeline, err := t.pipeline(c, options, repos, packageSpecSets["packages"], packageSpecSets["build-packages"])
 	if err != nil {
 		return distro.Manifest{}, err
@@ -265,6 +265,12 @@ func (t *imageType) Manifest(c *blueprint.Customizations,
 	repos []rpmmd.RepoConfig,
 	packageSpecSets map[string][]rpmmd.PackageSpec,
 	seed int64) (distro.Manifest, error) {
+	if err := distro.CheckUnsupportedImageOptions(t.arch.distro.name, options); err != nil {
+		return distro.Manifest{}, err
+	}
+	if err := distro.CheckUnsupportedCustomizations(t.arch.distro.name, c); err != nil {
+		return distro.Manifest{}, err
+	}
 	source := rand.NewSource(seed)
 	rng := rand.New(source)
 	pipeline, err := t.pipeline(c, options, repos, packageSpecSets["packages"], packageSpecSets["build-packages"], rng)
@@ -311,6 +311,12 @@ func (t *imageType) getPartitionTable(
 		return basePartitionTable, fmt.Errorf("unknown arch: " + archName)
 	}
 
+	if options.FixedSize {
+		if err := disk.CheckImageSize(mountpoints, options.Size, basePartitionTable); err != nil {
+			return basePartitionTable, err
+		}
+	}
+
 	return disk.CreatePartitionTable(mountpoints, options.Size, basePartitionTable, rng), nil
 }
 
@@ -411,6 +417,10 @@ func isMountpointAllowed(mountpoint string) bool {
 
 // checkOptions checks the validity and compatibility of options and customizations for the image type.
 func (t *imageType) checkOptions(customizations *blueprint.Customizations, options distro.ImageOptions) error {
+	if err := distro.CheckUnsupportedImageOptions(t.arch.distro.name, options); err != nil {
+		return err
+	}
+
 	if t.bootISO && t.rpmOstree {
 		if options.OSTree.Parent == "" {
 			return fmt.Errorf("boot ISO image type %q requires specifying a URL from which to retrieve the OSTree commit", t.name)
@@ -433,6 +443,10 @@ func (t *imageType) checkOptions(customizations *blueprint.Customizations, optio
 		return fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
 
+	if customizations.GetEdgeContainer() != nil {
+		return fmt.Errorf("edge container customizations are not supported for image type %q", t.name)
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	if mountpoints != nil && t.rpmOstree {
@@ -453,7 +467,13 @@ func (t *imageType) checkOptions(customizations *blueprint.Customizations, optio
 		return fmt.Errorf("The following custom mountpoints are not supported %+q", invalidMountpoints)
 	}
 
-	return nil
+	// btrfs roots need kernel options and a /boot partition the
+	// pipelines of this distro don't add
+	if err := disk.ValidateFilesystemCustomizations(mountpoints, "xfs", "ext4"); err != nil {
+		return err
+	}
+
+	return distro.CheckUnsupportedCustomizations(t.arch.distro.name, customizations, "Filesystem.Options", "Filesystem.FSType")
 }
 
 // New creates a new distro object, defining the supported architectures and image types
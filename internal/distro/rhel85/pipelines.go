@@ -56,7 +56,10 @@ func qcow2Pipelines(t *imageType, customizations *blueprint.Customizations, opti
 	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "0.10")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "0.10")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 
 	return pipelines, nil
@@ -100,7 +103,10 @@ func vhdPipelines(t *imageType, customizations *blueprint.Customizations, option
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -131,7 +137,10 @@ func vmdkPipelines(t *imageType, customizations *blueprint.Customizations, optio
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vmdk", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vmdk", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -162,7 +171,10 @@ func openstackPipelines(t *imageType, customizations *blueprint.Customizations,
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -1101,7 +1113,7 @@ func ostreeDeployPipeline(
 			OsName: osname,
 			Ref:    options.OSTree.Ref,
 			Mounts: []string{"/boot", "/boot/efi"},
-			Rootfs: osbuild.Rootfs{
+			Rootfs: &osbuild.Rootfs{
 				Label: "root",
 			},
 			KernelOpts: []string{
@@ -1303,14 +1315,18 @@ func mkfsStages(pt *disk.PartitionTable, device *osbuild.Device) []*osbuild2.Sta
 	return stages
 }
 
-func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format, qcow2Compat string) *osbuild.Pipeline {
+func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format, qcow2Compat string) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = format
 	p.Build = "name:build"
 
-	qemuStage := osbuild.NewQEMUStage(qemuStageOptions(outputFilename, format, qcow2Compat), qemuStageInputs(inputPipelineName, inputFilename))
+	options, err := qemuStageOptions(outputFilename, format, qcow2Compat)
+	if err != nil {
+		return nil, err
+	}
+	qemuStage := osbuild.NewQEMUStage(options, qemuStageInputs(inputPipelineName, inputFilename))
 	p.AddStage(qemuStage)
-	return p
+	return p, nil
 }
 
 func bootloaderConfigStage(t *imageType, partitionTable disk.PartitionTable, kernel *blueprint.KernelCustomization, kernelVer string, install, greenboot bool) *osbuild.Stage {
@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"math/rand"
 	"path"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/container"
+	"github.com/osbuild/osbuild-composer/internal/crypt"
 	"github.com/osbuild/osbuild-composer/internal/disk"
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild2"
@@ -31,14 +37,71 @@ const (
 	// installer package set name
 	installerPkgsKey = "installer"
 
-	// blueprint package set name
+	// blueprint package set name, image types with a payloadPkgsKey merge
+	// the blueprint packages into the payload package set instead
 	blueprintPkgsKey = "blueprint"
 )
 
-var mountpointAllowList = []string{
-	"/", "/var", "/opt", "/srv", "/usr", "/app", "/data", "/home",
+// date layout used in compose IDs
+const composeIDDateFormat = "20060102"
+
+// longest time in seconds blueprints may let GRUB wait for a menu selection
+const maxGrubTimeout = 60
+
+// names of GRUB superusers, which are written unquoted to the GRUB
+// configuration
+var grubSuperuserRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// partitioning modes of blueprints
+const (
+	// filesystems on partitions
+	partitioningModeRaw = "raw"
+	// filesystems on the logical volumes of a single physical volume
+	partitioningModeLVM = "lvm"
+)
+
+// types of swap space of blueprints
+const (
+	// a dedicated swap partition
+	swapTypePartition = "partition"
+	// a swap file on the root filesystem
+	swapTypeFile = "file"
+)
+
+// compression of the artifacts of image types
+const (
+	compressionXz   = "xz"
+	compressionGzip = "gzip"
+)
+
+// console keymaps of blueprints, e.g. us, de-nodeadkeys or fr_CH-latin1
+var keymapRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+(-[a-zA-Z0-9_]+)*$`)
+
+// console keymaps named after a language, whose X11 layout is the one of the
+// language
+var keymapLanguageRegexp = regexp.MustCompile(`^([a-z]{2})(-|$)`)
+
+// location of swap files in the root filesystem
+const swapFilePath = "/swapfile"
+
+// directory of the image for the results of the OpenSCAP remediation
+const oscapDataDir = "/oscap_data"
+
+// space in bytes the root filesystem needs at least when a blueprint doesn't
+// set its size, which swap space can't be taken from
+const minRootSize = 2 * 1024 * 1024 * 1024
+
+// mountpoints blueprints may request custom filesystems for, unless the image
+// type overrides the policy
+var defaultMountpointPolicy = distro.MountpointPolicy{
+	AllowedPrefixes: []string{"/", "/var", "/opt", "/srv", "/usr", "/app", "/data", "/home"},
+	DeniedPaths:     []string{"/boot/efi"},
 }
 
+// ostree based images are deployed from a commit and don't support custom
+// mountpoints
+var ostreeMountpointPolicy = distro.MountpointPolicy{}
+
 type distribution struct {
 	name             string
 	product          string
@@ -49,32 +112,52 @@ type distribution struct {
 	ostreeRefTmpl    string
 	isolabelTmpl     string
 	runner           string
-	arches           map[string]distro.Arch
+	// template of the compose ID of installer media, formatted with the
+	// build date
+	composeIDTmpl string
+	// installer media are marked as final, i.e. not pre-release, builds
+	isFinal bool
+	// product variant of installer media
+	variant string
+	// default method used to hash plain text user passwords
+	passwordHashMethod crypt.Method
+	mountpointPolicy   distro.MountpointPolicy
+	arches             map[string]distro.Arch
 }
 
 // distribution objects without the arches > image types
 var distroMap = map[string]distribution{
 	"rhel-86": {
-		name:             "rhel-86",
-		product:          "Red Hat Enterprise Linux",
-		osVersion:        "8.6",
-		releaseVersion:   "8",
-		modulePlatformID: "platform:el8",
-		vendor:           "redhat",
-		ostreeRefTmpl:    "rhel/8/%s/edge",
-		isolabelTmpl:     "RHEL-8-6-0-BaseOS-%s",
-		runner:           "org.osbuild.rhel86",
+		name:               "rhel-86",
+		product:            "Red Hat Enterprise Linux",
+		osVersion:          "8.6",
+		releaseVersion:     "8",
+		modulePlatformID:   "platform:el8",
+		vendor:             "redhat",
+		ostreeRefTmpl:      "rhel/8/%s/edge",
+		isolabelTmpl:       "RHEL-8-6-0-BaseOS-%s",
+		runner:             "org.osbuild.rhel86",
+		composeIDTmpl:      "RHEL-8.6.0-%s.n.0",
+		isFinal:            true,
+		variant:            "BaseOS",
+		passwordHashMethod: crypt.MethodSHA512,
+		mountpointPolicy:   defaultMountpointPolicy,
 	},
 	"centos-8": {
-		name:             "centos-8",
-		product:          "CentOS Stream",
-		osVersion:        "8-stream",
-		releaseVersion:   "8",
-		modulePlatformID: "platform:el8",
-		vendor:           "centos",
-		ostreeRefTmpl:    "centos/8/%s/edge",
-		isolabelTmpl:     "CentOS-Stream-8-%s-dvd",
-		runner:           "org.osbuild.centos8",
+		name:               "centos-8",
+		product:            "CentOS Stream",
+		osVersion:          "8-stream",
+		releaseVersion:     "8",
+		modulePlatformID:   "platform:el8",
+		vendor:             "centos",
+		ostreeRefTmpl:      "centos/8/%s/edge",
+		isolabelTmpl:       "CentOS-Stream-8-%s-dvd",
+		runner:             "org.osbuild.centos8",
+		composeIDTmpl:      "CentOS-Stream-8-%s.0",
+		isFinal:            true,
+		variant:            "BaseOS",
+		passwordHashMethod: crypt.MethodSHA512,
+		mountpointPolicy:   defaultMountpointPolicy,
 	},
 }
 
@@ -94,6 +177,27 @@ func (d *distribution) OSTreeRef() string {
 	return d.ostreeRefTmpl
 }
 
+// AllowMountpoints extends the mountpoint policy of the distribution with the
+// given prefixes. Image types that override the policy are not affected.
+func (d *distribution) AllowMountpoints(prefixes ...string) {
+	d.mountpointPolicy = d.mountpointPolicy.Allow(prefixes...)
+}
+
+// composeID returns the compose ID of installer media built at the given
+// time. The date is formatted the same way as in the compose IDs of the
+// distribution's official media, e.g. RHEL-8.6.0-20211017.n.0. A zero build
+// date selects the Unix epoch.
+func (d *distribution) composeID(buildDate time.Time) string {
+	if buildDate.IsZero() {
+		buildDate = time.Unix(0, 0)
+	}
+	return fmt.Sprintf(d.composeIDTmpl, buildDate.UTC().Format(composeIDDateFormat))
+}
+
+// Compose IDs are written into line based metadata files like .discinfo, so
+// they must be a single word
+var composeIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
 func (d *distribution) ListArches() []string {
 	archNames := make([]string, 0, len(d.arches))
 	for name := range d.arches {
@@ -128,6 +232,15 @@ func (d *distribution) isRHEL() bool {
 	return strings.HasPrefix(d.name, "rhel")
 }
 
+// oscapDataStream returns the path of the SCAP datastream of the
+// distribution, which scap-security-guide installs.
+func (d *distribution) oscapDataStream() string {
+	if d.isRHEL() {
+		return "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml"
+	}
+	return "/usr/share/xml/scap/ssg/content/ssg-centos8-ds.xml"
+}
+
 type architecture struct {
 	distro           *distribution
 	name             string
@@ -218,6 +331,47 @@ type imageType struct {
 	bootType distro.BootType
 	// List of valid arches for the image type
 	basePartitionTables distro.BasePartitionTableMap
+	// If set, it is used instead of the mountpoint policy of the distro
+	mountpointPolicy *distro.MountpointPolicy
+	// Mount the EFI system partition with umask=0077, so that only root
+	// can read it
+	espUmask bool
+
+	// Defaults for what the installed packages put into the image, which
+	// blueprints can override
+	rpmDefaults blueprint.RPMCustomization
+	// Path of the PTP clock of the hypervisor, which chrony keeps using
+	// as a reference clock when blueprints set NTP servers
+	ptpClock string
+	// Anaconda kickstart modules enabled in the installer, which
+	// blueprints can extend or reduce
+	kickstartModules []string
+	// Compatibility level of qcow2 images, empty selects the default of
+	// qemu-img
+	qcow2Compat string
+	// Compression of qcow2 images, which image options can override
+	qcow2Compression string
+	// If set, the blueprint packages are merged into this package set, the
+	// payload of the installed system, and depsolved together with it
+	// instead of in a separate blueprint package set
+	payloadPkgsKey string
+	// If set, the pipelines produce the uncompressed filename, which is
+	// compressed into the artifact of the image type by an additional
+	// archive pipeline
+	compression string
+	// Size in bytes and compression of the root filesystem of the installer
+	// of boot ISOs built by the bootiso.mono stage, which blueprints can
+	// override. The size is 0 for all other image types.
+	installerRootFSSize        uint64
+	installerRootFSCompression string
+	// Logical sector size in bytes of the disk, which image options can
+	// override, 512 if 0
+	sectorSize uint64
+	// Product variant of installer media, if not the one of the distro
+	variant string
+	// The format of the disk image records 512 byte logical sectors in its
+	// metadata, so the partition table can't use 4K sectors
+	legacySectors bool
 }
 
 func (t *imageType) Name() string {
@@ -229,10 +383,22 @@ func (t *imageType) Arch() distro.Arch {
 }
 
 func (t *imageType) Filename() string {
+	switch t.compression {
+	case compressionXz:
+		return t.filename + ".xz"
+	case compressionGzip:
+		return t.filename + ".gz"
+	}
 	return t.filename
 }
 
 func (t *imageType) MIMEType() string {
+	switch t.compression {
+	case compressionXz:
+		return "application/xz"
+	case compressionGzip:
+		return "application/gzip"
+	}
 	return t.mimeType
 }
 
@@ -247,7 +413,7 @@ func (t *imageType) OSTreeRef() string {
 func (t *imageType) Size(size uint64) uint64 {
 	const MegaByte = 1024 * 1024
 	// Microsoft Azure requires vhd images to be rounded up to the nearest MB
-	if t.name == "vhd" && size%MegaByte != 0 {
+	if (t.name == "vhd" || t.name == "vhd-gen2") && size%MegaByte != 0 {
 		size = (size/MegaByte + 1) * MegaByte
 	}
 	if size == 0 {
@@ -292,18 +458,98 @@ func (t *imageType) PackageSets(bp blueprint.Blueprint) map[string]rpmmd.Package
 	if timezone != nil {
 		bpPackages = append(bpPackages, "chrony")
 	}
+	if policy := selinuxPolicy(bp.Customizations.GetSELinux()); policy != osbuild.SELinuxTypeTargeted {
+		bpPackages = append(bpPackages, "selinux-policy-"+string(policy))
+	}
+	if bp.Customizations.GetPartitioningMode() == partitioningModeLVM {
+		// the initrd needs the LVM tools to activate the root volume
+		bpPackages = append(bpPackages, "lvm2")
+	}
+	if bp.Customizations.GetFIPS() {
+		bpPackages = append(bpPackages, "crypto-policies-scripts")
+	}
+	if bp.Customizations.GetTuned() != nil {
+		bpPackages = append(bpPackages, "tuned")
+	}
+	if kdump := bp.Customizations.GetKdump(); kdump != nil && kdump.Enabled {
+		bpPackages = append(bpPackages, "kexec-tools")
+	}
+	if bp.Customizations.GetAuthselect() != nil {
+		bpPackages = append(bpPackages, "authselect")
+	}
+	if len(bp.Customizations.GetCACerts()) > 0 {
+		bpPackages = append(bpPackages, "ca-certificates")
+	}
+	if bp.Customizations.GetOpenSCAP() != nil {
+		// oscap remediates the tree from within
+		bpPackages = append(bpPackages, "openscap-scanner", "scap-security-guide")
+	}
+	if encryption := bp.Customizations.GetDiskEncryption(); encryption != nil {
+		// the initrd needs cryptsetup to unlock the root partition
+		bpPackages = append(bpPackages, "cryptsetup")
+		if encryption.Clevis != nil {
+			bpPackages = append(bpPackages, "clevis-dracut")
+		}
+	}
+	if bp.Customizations.GetSecureExecution() != nil {
+		// genprotimg creates the Secure Execution image from the build root
+		mergedSets[buildPkgsKey] = mergedSets[buildPkgsKey].Append(rpmmd.PackageSet{Include: []string{"s390utils-base"}})
+	}
+	if len(bp.Containers) > 0 {
+		// the images are copied with skopeo from the build root, the image
+		// needs the configuration of the container storage
+		mergedSets[buildPkgsKey] = mergedSets[buildPkgsKey].Append(rpmmd.PackageSet{Include: []string{"skopeo"}})
+		bpPackages = append(bpPackages, "containers-common")
+	}
 
-	// depsolve bp packages separately
-	// bp packages aren't restricted by exclude lists
-	mergedSets[blueprintPkgsKey] = rpmmd.PackageSet{Include: bpPackages}
+	if t.payloadPkgsKey != "" {
+		// bp packages aren't restricted by the exclude list of the payload
+		payload := mergedSets[t.payloadPkgsKey]
+		mergedSets[t.payloadPkgsKey] = rpmmd.PackageSet{
+			Include: append(append([]string{}, payload.Include...), bpPackages...),
+			Exclude: withoutPackages(payload.Exclude, bpPackages),
+		}
+	} else {
+		// depsolve bp packages separately
+		// bp packages aren't restricted by exclude lists
+		mergedSets[blueprintPkgsKey] = rpmmd.PackageSet{Include: bpPackages}
+	}
 	kernel := bp.Customizations.GetKernel().Name
 
 	// add bp kernel to main OS package set to avoid duplicate kernels
 	mergedSets[osPkgsKey] = mergedSets[osPkgsKey].Append(rpmmd.PackageSet{Include: []string{kernel}})
+
+	// the excludes of the DNF customization restrict the blueprint packages
+	// too, depsolving fails if one of them is excluded
+	if excludes := bp.Customizations.GetDNF().BuildExcludes(); len(excludes) > 0 {
+		for _, name := range []string{osPkgsKey, blueprintPkgsKey} {
+			if set, ok := mergedSets[name]; ok {
+				mergedSets[name] = set.Append(rpmmd.PackageSet{Exclude: excludes})
+			}
+		}
+	}
 	return mergedSets
 
 }
 
+// withoutPackages returns the packages that are not in names
+func withoutPackages(packages, names []string) []string {
+	var result []string
+	for _, pkg := range packages {
+		found := false
+		for _, name := range names {
+			if pkg == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, pkg)
+		}
+	}
+	return result
+}
+
 func (t *imageType) Exports() []string {
 	if len(t.exports) > 0 {
 		return t.exports
@@ -311,6 +557,65 @@ func (t *imageType) Exports() []string {
 	return []string{"assembler"}
 }
 
+// isoLabel returns the volume ID of the ISO of a boot ISO image type, by
+// which the installer finds its kickstart and stage2. The label of the
+// blueprint replaces the one of the distribution.
+func (t *imageType) isoLabel(customizations *blueprint.Customizations) string {
+	if label := customizations.GetInstallerISOLabel(); label != "" {
+		return label
+	}
+	return fmt.Sprintf(t.arch.distro.isolabelTmpl, t.arch.name)
+}
+
+// installerRootFS returns the root filesystem of the installer of a boot ISO
+// image type, with the size and compression of the blueprint replacing the
+// ones of the image type. The xz BCJ filter is only set for xz compression.
+func (t *imageType) installerRootFS(customizations *blueprint.Customizations) osbuild.RootFS {
+	size := t.installerRootFSSize
+	compression := t.installerRootFSCompression
+	if rootfs := customizations.GetInstallerRootFS(); rootfs != nil {
+		if rootfs.Size != 0 {
+			size = rootfs.Size
+		}
+		if rootfs.Compression != "" {
+			compression = rootfs.Compression
+		}
+	}
+
+	const MegaByte = 1024 * 1024
+	fs := osbuild.RootFS{
+		Size: int((size + MegaByte - 1) / MegaByte),
+		Compression: osbuild.FSCompression{
+			Method: compression,
+		},
+	}
+	if compression == osbuild.FSCompressionXz {
+		fs.Compression.Options = new(osbuild.FSCompressionOptions)
+		if bcj := osbuild.BCJOption(t.arch.name); bcj != "" {
+			fs.Compression.Options.BCJ = bcj
+		}
+	}
+	return fs
+}
+
+// composeID returns the compose ID of the image, which the image options can
+// set instead of deriving it from the distribution and the build date.
+// installerVariant returns the product variant written to the buildstamp of
+// installer media, the one of the image type or else the one of the distro
+func (t *imageType) installerVariant() string {
+	if t.variant != "" {
+		return t.variant
+	}
+	return t.arch.distro.variant
+}
+
+func (t *imageType) composeID(options distro.ImageOptions) string {
+	if options.ComposeID != "" {
+		return options.ComposeID
+	}
+	return t.arch.distro.composeID(options.BuildDate)
+}
+
 // getBootType returns the BootType which should be used for this particular
 // combination of architecture and image type.
 func (t *imageType) getBootType() distro.BootType {
@@ -329,8 +634,26 @@ func (t *imageType) supportsUEFI() bool {
 	return false
 }
 
+// legacyPlatform returns the GRUB2 platform of the architecture for legacy
+// BIOS boot, or an empty string if the image type doesn't support it.
+func (t *imageType) legacyPlatform() string {
+	if t.getBootType() == distro.UEFIBootType {
+		return ""
+	}
+	return t.arch.legacy
+}
+
+// getSectorSize returns the sector size of the disk of the image, or 0 for
+// the default of the partition table.
+func (t *imageType) getSectorSize(options distro.ImageOptions) uint64 {
+	if options.SectorSize != 0 {
+		return options.SectorSize
+	}
+	return t.sectorSize
+}
+
 func (t *imageType) getPartitionTable(
-	mountpoints []blueprint.FilesystemCustomization,
+	customizations *blueprint.Customizations,
 	options distro.ImageOptions,
 	rng *rand.Rand,
 ) (disk.PartitionTable, error) {
@@ -342,13 +665,103 @@ func (t *imageType) getPartitionTable(
 		return basePartitionTable, fmt.Errorf("unknown arch: " + archName)
 	}
 
-	return disk.CreatePartitionTable(mountpoints, options.Size, basePartitionTable, rng), nil
+	// the base partition tables are given in 512 byte sectors
+	if sectorSize := t.getSectorSize(options); sectorSize != 0 {
+		var err error
+		basePartitionTable, err = basePartitionTable.ConvertSectorSize(sectorSize)
+		if err != nil {
+			return basePartitionTable, err
+		}
+	}
+
+	if customizations.GetPartitioningMode() == partitioningModeLVM {
+		var err error
+		basePartitionTable, err = disk.LVMPartitionTable(basePartitionTable)
+		if err != nil {
+			return basePartitionTable, err
+		}
+	}
+
+	if encryption := customizations.GetDiskEncryption(); encryption != nil {
+		luks := disk.LUKSContainer{Passphrase: encryption.Passphrase}
+		if clevis := encryption.Clevis; clevis != nil {
+			luks.Clevis = &disk.ClevisBind{Pin: clevis.Pin, Policy: clevis.Policy}
+		}
+		var err error
+		basePartitionTable, err = disk.EncryptedPartitionTable(basePartitionTable, luks)
+		if err != nil {
+			return basePartitionTable, err
+		}
+	}
+
+	if swap := customizations.GetSwap(); swap != nil && swap.Type != swapTypeFile {
+		var err error
+		basePartitionTable, err = disk.SwapPartitionTable(basePartitionTable, swap.Size*1024*1024)
+		if err != nil {
+			return basePartitionTable, err
+		}
+	}
+
+	if options.FixedSize {
+		if err := disk.CheckImageSize(customizations.GetFilesystems(), options.Size, basePartitionTable); err != nil {
+			return basePartitionTable, err
+		}
+	}
+
+	pt := disk.CreatePartitionTable(customizations.GetFilesystems(), options.Size, basePartitionTable, rng)
+	if err := pt.SetDeviceIDMode(disk.DeviceIDMode(customizations.GetDeviceID())); err != nil {
+		return pt, err
+	}
+	if esp := pt.FilesystemByMountpoint("/boot/efi"); esp != nil && t.espUmask {
+		esp.SetFSTabOption("umask", "0077")
+	}
+	return pt, nil
+}
+
+// rpmCustomization returns the settings for installing the packages of the
+// image, preferring the blueprint customization over the image type defaults.
+func (t *imageType) rpmCustomization(c *blueprint.Customizations) *blueprint.RPMCustomization {
+	rpm := t.rpmDefaults
+	if custom := c.GetRPM(); custom != nil {
+		if custom.ExcludeDocs != nil {
+			rpm.ExcludeDocs = custom.ExcludeDocs
+		}
+		if custom.InstallLangs != nil {
+			rpm.InstallLangs = custom.InstallLangs
+		}
+	}
+	return &rpm
+}
+
+// passwordHash describes how plain text user passwords are hashed
+type passwordHash struct {
+	method  crypt.Method
+	options *crypt.Options
+}
+
+// passwordHash returns the password hashing settings for the image type,
+// preferring the blueprint customization over the distribution default.
+func (t *imageType) passwordHash(c *blueprint.Customizations) passwordHash {
+	pwHash := passwordHash{
+		method: t.arch.distro.passwordHashMethod,
+	}
+	if custom := c.GetPasswordHash(); custom != nil {
+		if custom.Method != "" {
+			pwHash.method = crypt.Method(custom.Method)
+		}
+		if custom.Rounds != 0 {
+			pwHash.options = &crypt.Options{Rounds: custom.Rounds}
+		}
+	}
+	return pwHash
 }
 
 // local type for ostree commit metadata used to define commit sources
 type ostreeCommit struct {
 	Checksum string
 	URL      string
+	// the repository requires mutual TLS
+	MTLS bool
 }
 
 func (t *imageType) Manifest(customizations *blueprint.Customizations,
@@ -361,6 +774,10 @@ func (t *imageType) Manifest(customizations *blueprint.Customizations,
 		return distro.Manifest{}, err
 	}
 
+	if options.SourceDateEpoch != nil {
+		options.BuildDate = *options.SourceDateEpoch
+	}
+
 	source := rand.NewSource(seed)
 	rng := rand.New(source)
 
@@ -368,6 +785,20 @@ func (t *imageType) Manifest(customizations *blueprint.Customizations,
 	if err != nil {
 		return distro.Manifest{}, err
 	}
+	if t.compression != "" {
+		archive, err := compressionPipeline(t, pipelines[len(pipelines)-1].Name)
+		if err != nil {
+			return distro.Manifest{}, err
+		}
+		pipelines = append(pipelines, *archive)
+	}
+
+	if options.SourceDateEpoch != nil {
+		epoch := options.SourceDateEpoch.Unix()
+		for idx := range pipelines {
+			pipelines[idx].SourceEpoch = &epoch
+		}
+	}
 
 	// flatten spec sets for sources
 	allPackageSpecs := make([]rpmmd.PackageSpec, 0)
@@ -375,20 +806,41 @@ func (t *imageType) Manifest(customizations *blueprint.Customizations,
 		allPackageSpecs = append(allPackageSpecs, specs...)
 	}
 
+	files := customizationFiles(customizations)
+	if customizations.GetFIPS() {
+		files = append(files, systemFIPSFile)
+	}
+	if config := ignitionConfigFile(customizations.GetIgnition()); config != nil {
+		files = append(files, *config)
+	}
+	if rootCerts := fdoRootCertsFile(customizations.GetFDO()); rootCerts != nil {
+		files = append(files, *rootCerts)
+	}
+	files = append(files, hostKeyDocumentFiles(customizations.GetSecureExecution())...)
+	files = append(files, installerPostScriptFiles(customizations.GetInstallerPostScripts())...)
+	if t.rpmOstree && len(options.Containers) > 0 {
+		files = append(files, ostreeContainerStorageConfFile)
+	}
+	// deployments configure the remote in their repository instead
+	if t.rpmOstree && !t.bootable {
+		files = append(files, ostreeRemoteFiles(customizations.GetOSTree().GetRemote())...)
+		files = append(files, authorizedKeysFiles(customizations.GetUsers())...)
+	}
+
 	var commits []ostreeCommit
 	if options.OSTree.Parent != "" && options.OSTree.URL != "" {
-		commits = []ostreeCommit{{Checksum: options.OSTree.Parent, URL: options.OSTree.URL}}
+		commits = []ostreeCommit{{Checksum: options.OSTree.Parent, URL: options.OSTree.URL, MTLS: options.OSTree.MTLS}}
 	}
 	return json.Marshal(
 		osbuild.Manifest{
 			Version:   "2",
 			Pipelines: pipelines,
-			Sources:   t.sources(allPackageSpecs, commits),
+			Sources:   t.sources(allPackageSpecs, commits, options.Containers, files),
 		},
 	)
 }
 
-func (t *imageType) sources(packages []rpmmd.PackageSpec, ostreeCommits []ostreeCommit) osbuild.Sources {
+func (t *imageType) sources(packages []rpmmd.PackageSpec, ostreeCommits []ostreeCommit, containers []container.Spec, files []blueprint.FileCustomization) osbuild.Sources {
 	sources := osbuild.Sources{}
 	curl := &osbuild.CurlSource{
 		Items: make(map[string]osbuild.CurlSourceItem),
@@ -413,31 +865,40 @@ func (t *imageType) sources(packages []rpmmd.PackageSpec, ostreeCommits []ostree
 	for _, commit := range ostreeCommits {
 		item := new(osbuild.OSTreeSourceItem)
 		item.Remote.URL = commit.URL
+		if commit.MTLS {
+			item.Remote.Secrets = &osbuild.OSTreeSourceRemoteSecrets{
+				Name: osbuild.OSTreeMTLSSecrets,
+			}
+		}
 		ostree.Items[commit.Checksum] = *item
 	}
 	if len(ostree.Items) > 0 {
 		sources["org.osbuild.ostree"] = ostree
 	}
+
+	skopeo := osbuild.NewSkopeoSource()
+	for _, c := range containers {
+		skopeo.AddItem(c.Source, c.Digest, c.TLSVerify)
+	}
+	if len(skopeo.Items) > 0 {
+		sources["org.osbuild.skopeo"] = skopeo
+	}
+
+	inline := osbuild.NewInlineSource()
+	for _, file := range files {
+		inline.AddItem([]byte(file.Data))
+	}
+	if len(inline.Items) > 0 {
+		sources["org.osbuild.inline"] = inline
+	}
 	return sources
 }
 
-func isMountpointAllowed(mountpoint string) bool {
-	for _, allowed := range mountpointAllowList {
-		match, _ := path.Match(allowed, mountpoint)
-		if match {
-			return true
-		}
-		// ensure that only clean mountpoints
-		// are valid
-		if strings.Contains(mountpoint, "//") {
-			return false
-		}
-		match = strings.HasPrefix(mountpoint, allowed+"/")
-		if allowed != "/" && match {
-			return true
-		}
+func (t *imageType) getMountpointPolicy() distro.MountpointPolicy {
+	if t.mountpointPolicy != nil {
+		return *t.mountpointPolicy
 	}
-	return false
+	return t.arch.distro.mountpointPolicy
 }
 
 // checkOptions checks the validity and compatibility of options and customizations for the image type.
@@ -448,40 +909,684 @@ func (t *imageType) checkOptions(customizations *blueprint.Customizations, optio
 		}
 
 		if t.name == "edge-simplified-installer" {
-			if err := customizations.CheckAllowed("InstallationDevice"); err != nil {
+			if err := customizations.CheckAllowed("InstallationDevice", "InstallationDeviceFallbacks", "Ignition", "FDO", "Dracut", "Kdump", "OSTree"); err != nil {
 				return fmt.Errorf("boot ISO image type %q contains unsupported blueprint customizations: %v", t.name, err)
 			}
-		} else if customizations != nil {
-			return fmt.Errorf("boot ISO image type %q does not support blueprint customizations", t.name)
+			devices := customizations.GetInstallationDevices()
+			if len(devices) == 0 {
+				return fmt.Errorf("boot ISO image type %q requires specifying an installation device", t.name)
+			}
+			for _, device := range devices {
+				if err := blueprint.ValidateInstallationDevice(device); err != nil {
+					return err
+				}
+			}
+		} else if err := customizations.CheckAllowed("Installer", "User", "Group", "PasswordHash", "Locale", "Timezone", "Dracut"); err != nil {
+			return fmt.Errorf("boot ISO image type %q contains unsupported blueprint customizations: %v", t.name, err)
+		}
+	}
+
+	if err := customizations.GetServices().Validate(); err != nil {
+		return err
+	}
+
+	if ignition := customizations.GetIgnition(); ignition != nil {
+		if t.name != "edge-raw-image" && t.name != "edge-simplified-installer" {
+			return fmt.Errorf("Ignition customizations are not supported for image type %q", t.name)
+		}
+		if err := ignition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if ostree := customizations.GetOSTree(); ostree != nil {
+		if !t.rpmOstree {
+			return fmt.Errorf("ostree customizations are not supported for image type %q", t.name)
+		}
+		if err := ostree.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if ec := customizations.GetEdgeContainer(); ec != nil {
+		if t.name != "edge-container" {
+			return fmt.Errorf("edge container customizations are not supported for image type %q", t.name)
+		}
+		if err := ec.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if se := customizations.GetSecureExecution(); se != nil {
+		if t.arch.name != distro.S390xArchName || !t.bootable {
+			return fmt.Errorf("Secure Execution is not supported for image type %q on %s", t.name, t.arch.name)
+		}
+		if err := se.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if fdo := customizations.GetFDO(); fdo != nil {
+		if t.name != "edge-simplified-installer" {
+			return fmt.Errorf("FDO customizations are not supported for image type %q", t.name)
+		}
+		if err := fdo.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if dracut := customizations.GetDracut(); dracut != nil {
+		if !t.bootISO {
+			return fmt.Errorf("dracut customizations are not supported for image type %q", t.name)
+		}
+		if err := dracut.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if label := customizations.GetInstallerISOLabel(); label != "" {
+		if !t.bootISO {
+			return fmt.Errorf("ISO labels are not supported for image type %q", t.name)
+		}
+		if err := blueprint.ValidateISOLabel(label); err != nil {
+			return err
+		}
+	}
+
+	if customizations.GetInstallerMediaCheck() && !t.bootISO {
+		return fmt.Errorf("installer media checks are not supported for image type %q", t.name)
+	}
+
+	if rootfs := customizations.GetInstallerRootFS(); rootfs != nil {
+		// only the installers built by the bootiso.mono stage have a root
+		// filesystem image, e.g. not the edge-simplified-installer
+		if t.installerRootFSSize == 0 {
+			return fmt.Errorf("installer root filesystem customizations are not supported for image type %q", t.name)
+		}
+		if err := rootfs.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if modules := customizations.GetInstallerModules(); modules != nil {
+		if t.kickstartModules == nil {
+			return fmt.Errorf("installer modules are not supported for image type %q", t.name)
+		}
+		if err := modules.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if postScripts := customizations.GetInstallerPostScripts(); len(postScripts) > 0 {
+		if t.name != "image-installer" && t.name != "edge-installer" {
+			return fmt.Errorf("installer %%post scripts are not supported for image type %q", t.name)
+		}
+		for idx, script := range postScripts {
+			if script.Script == "" {
+				return fmt.Errorf("installer %%post script %d is empty", idx)
+			}
+			if len(script.Script) > blueprint.MaxPostScriptSize {
+				return fmt.Errorf("installer %%post script %d exceeds the maximum size of %d bytes", idx, blueprint.MaxPostScriptSize)
+			}
+		}
+	}
+
+	if pwHash := customizations.GetPasswordHash(); pwHash != nil {
+		switch crypt.Method(pwHash.Method) {
+		case "", crypt.MethodSHA512:
+			if pwHash.Rounds != 0 && (pwHash.Rounds < crypt.SHA512MinRounds || pwHash.Rounds > crypt.SHA512MaxRounds) {
+				return fmt.Errorf("password hash rounds must be between %d and %d", crypt.SHA512MinRounds, crypt.SHA512MaxRounds)
+			}
+		case crypt.MethodYescrypt:
+			if pwHash.Rounds != 0 {
+				return fmt.Errorf("password hash rounds are not supported for the %q method", pwHash.Method)
+			}
+		default:
+			return fmt.Errorf("unsupported password hash method %q", pwHash.Method)
+		}
+	}
+
+	if options.OSTree.StaticDeltas {
+		if !t.rpmOstree || t.bootable || t.bootISO {
+			return fmt.Errorf("static deltas are not supported for image type %q", t.name)
+		}
+		if options.OSTree.Parent == "" || options.OSTree.URL == "" {
+			return fmt.Errorf("static deltas require specifying a URL from which to retrieve the parent OSTree commit")
+		}
+	}
+
+	if options.SectorSize != 0 {
+		if t.basePartitionTables == nil {
+			return fmt.Errorf("sector size is not supported for image type %q", t.name)
+		}
+		if err := disk.CheckSectorSize(options.SectorSize); err != nil {
+			return err
+		}
+		if t.legacySectors && options.SectorSize != 512 {
+			return fmt.Errorf("image type %q only supports 512 byte sectors", t.name)
+		}
+	}
+
+	if options.ComposeID != "" && !composeIDRegexp.MatchString(options.ComposeID) {
+		return fmt.Errorf("invalid compose ID %q", options.ComposeID)
+	}
+
+	switch options.Qcow2Compression {
+	case "":
+	case distro.Qcow2CompressionNone, osbuild.Qcow2CompressionZlib, osbuild.Qcow2CompressionZstd:
+		if t.exports[0] != "qcow2" {
+			return fmt.Errorf("qcow2 compression is not supported for image type %q", t.name)
+		}
+		if options.Qcow2Compression == osbuild.Qcow2CompressionZstd && t.qcow2Compat == "0.10" {
+			return fmt.Errorf("zstd compression is not supported for image type %q", t.name)
+		}
+	default:
+		return fmt.Errorf("unsupported qcow2 compression %q", options.Qcow2Compression)
+	}
+
+	if t.name == "minimal-raw" && customizations != nil {
+		if customizations.InstallationDevice != "" || len(customizations.InstallationDeviceFallbacks) > 0 || customizations.Installer != nil {
+			return fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+		}
+	}
+
+	if t.name == "wsl" && customizations != nil {
+		// the image is booted by the kernel of WSL
+		if customizations.Kernel != nil || customizations.Kdump != nil || customizations.GetFIPS() || customizations.Grub != nil {
+			return fmt.Errorf("kernel and bootloader customizations are not supported for image type %q", t.name)
+		}
+	}
+
+	if t.name == "edge-raw-image" {
+		if options.OSTree.Parent == "" {
+			return fmt.Errorf("edge raw images require specifying a URL from which to retrieve the OSTree commit")
+		}
+		// like the simplified installer, the image is deployed from a
+		// commit, so users are provisioned with Ignition
+		if err := customizations.CheckAllowed("Ignition", "Kdump", "OSTree"); err != nil {
+			return fmt.Errorf("image type %q contains unsupported blueprint customizations: %v", t.name, err)
+		}
+	}
+
+	switch disk.DeviceIDMode(customizations.GetDeviceID()) {
+	case "", disk.DeviceIDFilesystemUUID:
+	case disk.DeviceIDFilesystemLabel, disk.DeviceIDPartitionUUID:
+		if t.rpmOstree || !t.bootable {
+			return fmt.Errorf("device identification customizations are not supported for image type %q", t.name)
+		}
+		// the GRUB2 stage always identifies the root filesystem by its
+		// UUID or label on the kernel command line, only zipl doesn't
+		if disk.DeviceIDMode(customizations.GetDeviceID()) == disk.DeviceIDPartitionUUID && t.arch.name != distro.S390xArchName {
+			return fmt.Errorf("device identification by partition UUID is not supported for image type %q on %s, only for s390x images", t.name, t.arch.name)
+		}
+	default:
+		return fmt.Errorf("unknown device identification mode %q, must be one of uuid, label, or partuuid", customizations.GetDeviceID())
+	}
+
+	switch customizations.GetPartitioningMode() {
+	case "", partitioningModeRaw:
+	case partitioningModeLVM:
+		if t.rpmOstree || !t.bootable || t.bootISO {
+			return fmt.Errorf("LVM partitioning is not supported for image type %q", t.name)
+		}
+		if disk.DeviceIDMode(customizations.GetDeviceID()) == disk.DeviceIDPartitionUUID {
+			return fmt.Errorf("filesystems on logical volumes can't be identified by partition UUID")
+		}
+	default:
+		return fmt.Errorf("unknown partitioning mode %q, must be one of raw or lvm", customizations.GetPartitioningMode())
+	}
+
+	if encryption := customizations.GetDiskEncryption(); encryption != nil {
+		if t.rpmOstree || !t.bootable || t.bootISO {
+			return fmt.Errorf("disk encryption is not supported for image type %q", t.name)
+		}
+		if encryption.Passphrase == "" {
+			return fmt.Errorf("disk encryption requires a passphrase")
+		}
+		if disk.DeviceIDMode(customizations.GetDeviceID()) == disk.DeviceIDPartitionUUID {
+			return fmt.Errorf("filesystems on encrypted partitions can't be identified by partition UUID")
+		}
+		if clevis := encryption.Clevis; clevis != nil {
+			switch clevis.Pin {
+			case "tang", "sss", "null":
+			case "tpm2":
+				// the key would be sealed by the TPM of the machine that
+				// builds the image
+				return fmt.Errorf("the clevis tpm2 pin can't be bound when the image is built")
+			default:
+				return fmt.Errorf("unsupported clevis pin %q, must be one of tang, sss, or null", clevis.Pin)
+			}
+			if !json.Valid([]byte(clevis.Policy)) {
+				return fmt.Errorf("clevis policy must be valid JSON")
+			}
+		}
+	}
+
+	if _, keyboard := customizations.GetPrimaryLocale(); keyboard != nil && !keymapRegexp.MatchString(*keyboard) {
+		return fmt.Errorf("invalid keyboard layout %q", *keyboard)
+	}
+
+	if err := customizations.GetSSHD().Validate(); err != nil {
+		return err
+	}
+
+	if customizations.GetFIPS() {
+		if t.rpmOstree || !t.bootable {
+			return fmt.Errorf("FIPS mode is not supported for image type %q", t.name)
+		}
+		kernel := customizations.GetKernel()
+		kernelOptions := strings.Fields(removeKernelOptions(t.kernelOptions, kernel.Remove) + " " + kernel.Append)
+		for _, option := range kernelOptions {
+			if option == "fips=0" {
+				return fmt.Errorf("FIPS mode can't be enabled for image type %q with the kernel option fips=0", t.name)
+			}
 		}
 	}
 
-	if t.name == "edge-raw-image" && options.OSTree.Parent == "" {
-		return fmt.Errorf("edge raw images require specifying a URL from which to retrieve the OSTree commit")
+	if openscap := customizations.GetOpenSCAP(); openscap != nil {
+		if t.rpmOstree || t.bootISO {
+			return fmt.Errorf("OpenSCAP customizations are not supported for image type %q", t.name)
+		}
+		if openscap.ProfileID == "" {
+			return fmt.Errorf("OpenSCAP profile ID must be set")
+		}
+		if openscap.DataStream != "" && (!path.IsAbs(openscap.DataStream) || path.Clean(openscap.DataStream) != openscap.DataStream) {
+			return fmt.Errorf("OpenSCAP datastream %q must be an absolute and clean path", openscap.DataStream)
+		}
+	}
+
+	if len(options.Containers) > 0 {
+		if t.bootISO || t.name == "edge-raw-image" {
+			return fmt.Errorf("containers are not supported for image type %q", t.name)
+		}
+		names := make(map[string]string)
+		for _, c := range options.Containers {
+			if name, exists := names[c.Digest]; exists {
+				return fmt.Errorf("containers %q and %q resolve to the same image %s", name, c.LocalName, c.Digest)
+			}
+			names[c.Digest] = c.LocalName
+		}
+	}
+
+	files, dirs := customizationFiles(customizations), customizations.GetDirectories()
+	if t.rpmOstree && len(options.Containers) > 0 {
+		files = append(files, ostreeContainerStorageConfFile)
+	}
+	// deployments configure the remote in their repository instead
+	if t.rpmOstree && !t.bootable {
+		files = append(files, ostreeRemoteFiles(customizations.GetOSTree().GetRemote())...)
+		files = append(files, authorizedKeysFiles(customizations.GetUsers())...)
+	}
+	if err := blueprint.ValidateFileCustomizations(files); err != nil {
+		return err
+	}
+	if err := blueprint.ValidateDirectoryCustomizations(dirs, files); err != nil {
+		return err
+	}
+	// the deployment of an ostree commit only keeps its /etc, /var and
+	// the home directories are created empty
+	if t.rpmOstree {
+		for _, file := range files {
+			if !strings.HasPrefix(file.Path, "/etc/") {
+				return fmt.Errorf("file %q is not supported for image type %q, ostree commits only keep files under /etc", file.Path, t.name)
+			}
+		}
+		for _, dir := range dirs {
+			if !strings.HasPrefix(dir.Path, "/etc/") {
+				return fmt.Errorf("directory %q is not supported for image type %q, ostree commits only keep directories under /etc", dir.Path, t.name)
+			}
+		}
+	}
+
+	if swap := customizations.GetSwap(); swap != nil {
+		if err := t.checkSwap(swap, customizations, options); err != nil {
+			return err
+		}
+	}
+
+	if selinux := customizations.GetSELinux(); selinux != nil {
+		switch selinuxPolicy(selinux) {
+		case osbuild.SELinuxTypeTargeted, osbuild.SELinuxTypeMLS, osbuild.SELinuxTypeMinimum:
+		default:
+			return fmt.Errorf("unsupported SELinux policy %q, must be one of targeted, mls, or minimum", selinux.Policy)
+		}
+		switch osbuild.SELinuxPolicyState(selinux.Mode) {
+		case "", osbuild.SELinuxStateEnforcing, osbuild.SELinuxStatePermissive:
+		case osbuild.SELinuxStateDisabled:
+			return fmt.Errorf("SELinux can't be disabled: images are labeled when they are built and labels of files created while SELinux is disabled would be missing, use the permissive mode instead")
+		default:
+			return fmt.Errorf("unsupported SELinux mode %q, must be enforcing or permissive", selinux.Mode)
+		}
+		// the image type needs SELinux to be permissive
+		if t.name == "ec2-sap" && osbuild.SELinuxPolicyState(selinux.Mode) == osbuild.SELinuxStateEnforcing {
+			return fmt.Errorf("SELinux mode %q is not supported for image type %q", selinux.Mode, t.name)
+		}
+		// OSTree commits are not relabeled when they are deployed, all
+		// of their files must be labeled when they are built
+		if t.rpmOstree && (selinux.ForceAutorelabel || len(selinux.Exclude) > 0) {
+			return fmt.Errorf("SELinux relabeling customizations are not supported for ostree types")
+		}
+		for _, excluded := range selinux.Exclude {
+			if !path.IsAbs(excluded) || path.Clean(excluded) != excluded || excluded == "/" {
+				return fmt.Errorf("path %q excluded from SELinux labeling must be an absolute and clean path below /", excluded)
+			}
+		}
+	}
+
+	if grub := customizations.GetGrub(); grub != nil {
+		if t.rpmOstree || !t.bootable {
+			return fmt.Errorf("GRUB customizations are not supported for image type %q", t.name)
+		}
+		// s390x images boot with zipl
+		if t.arch.name == distro.S390xArchName {
+			return fmt.Errorf("GRUB customizations are not supported for architecture %q", t.arch.name)
+		}
+		if strings.ContainsAny(grub.Default, "\"'\\$`\n") {
+			return fmt.Errorf("invalid default GRUB menu entry %q", grub.Default)
+		}
+		if grub.Timeout != nil && (*grub.Timeout < 0 || *grub.Timeout > maxGrubTimeout) {
+			return fmt.Errorf("GRUB timeout %d out of range, must be between 0 and %d seconds", *grub.Timeout, maxGrubTimeout)
+		}
+		switch osbuild.GRUB2TimeoutStyle(grub.TimeoutStyle) {
+		case "", osbuild.GRUB2TimeoutStyleMenu, osbuild.GRUB2TimeoutStyleCountdown, osbuild.GRUB2TimeoutStyleHidden:
+		default:
+			return fmt.Errorf("unsupported GRUB timeout style %q, must be one of menu, countdown, or hidden", grub.TimeoutStyle)
+		}
+		if grub.Superuser != "" {
+			if grub.Password == "" {
+				return fmt.Errorf("GRUB superuser %q requires a password", grub.Superuser)
+			}
+			if !grubSuperuserRegexp.MatchString(grub.Superuser) {
+				return fmt.Errorf("invalid GRUB superuser name %q", grub.Superuser)
+			}
+		}
+		serialTerminal := false
+		for _, terminal := range grub.Terminal {
+			switch terminal {
+			case "console":
+			case "serial":
+				serialTerminal = true
+			default:
+				return fmt.Errorf("unsupported GRUB terminal %q, must be console or serial", terminal)
+			}
+		}
+		if serial := grub.Serial; serial != nil {
+			if !serialTerminal {
+				return fmt.Errorf("GRUB serial settings require the serial terminal")
+			}
+			if serial.Unit < 0 {
+				return fmt.Errorf("GRUB serial unit %d must not be negative", serial.Unit)
+			}
+			switch serial.Speed {
+			case 0, 9600, 19200, 38400, 57600, 115200:
+			default:
+				return fmt.Errorf("unsupported GRUB serial speed %d, must be one of 9600, 19200, 38400, 57600, or 115200", serial.Speed)
+			}
+		}
+	}
+
+	if rpm := customizations.GetRPM(); rpm != nil {
+		for _, lang := range rpm.InstallLangs {
+			if lang == "" || strings.ContainsAny(lang, ": \t") {
+				return fmt.Errorf("invalid language %q in the languages to install", lang)
+			}
+		}
+	}
+
+	if err := checkUserIDs(customizations.GetUsers(), customizations.GetGroups()); err != nil {
+		return err
+	}
+	for _, user := range customizations.GetUsers() {
+		if user.HomeMode == nil {
+			continue
+		}
+		if mode, err := strconv.ParseUint(*user.HomeMode, 8, 32); err != nil || mode > 07777 {
+			return fmt.Errorf("invalid home directory mode %q of user %q, must be an octal number", *user.HomeMode, user.Name)
+		}
 	}
 
 	if kernelOpts := customizations.GetKernel(); kernelOpts.Append != "" && t.rpmOstree && (!t.bootable || t.bootISO) {
 		return fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
+	if kernelOpts := customizations.GetKernel(); len(kernelOpts.Remove) > 0 && (t.rpmOstree || !t.bootable || t.bootISO) {
+		return fmt.Errorf("removing kernel boot parameters is not supported for image type %q", t.name)
+	}
+	if err := customizations.GetKernel().Modules.Validate(); err != nil {
+		return err
+	}
+	if err := customizations.ValidateSysctl(); err != nil {
+		return err
+	}
+	if err := customizations.ValidateUdevRules(); err != nil {
+		return err
+	}
+	if tuned := customizations.GetTuned(); tuned != nil {
+		// the image type selects its own profile
+		if t.name == "ec2-sap" {
+			return fmt.Errorf("TuneD customizations are not supported for image type %q", t.name)
+		}
+		if err := tuned.Validate(customizations.GetServices(), customizations.GetDNF()); err != nil {
+			return err
+		}
+	}
+	// without the pools, chrony needs NTP servers or the reference clock of
+	// the hypervisor as a time source
+	if _, commentPools := customizations.GetChronySettings(); commentPools {
+		if _, ntpServers := customizations.GetTimezoneSettings(); len(ntpServers) == 0 && t.ptpClock == "" {
+			return fmt.Errorf("commenting out the NTP pools requires NTP servers for image type %q", t.name)
+		}
+	}
+	if kdump := customizations.GetKdump(); kdump != nil {
+		// commits don't have kernel arguments, the crash kernel memory is
+		// reserved by the image that deploys them
+		if kdump.Enabled && t.rpmOstree && !t.bootable {
+			return fmt.Errorf("enabling kdump is not supported for image type %q, enable it for the image that deploys the commit", t.name)
+		}
+		// the kernel arguments of images that don't boot are set when
+		// they are installed
+		if kdump.Crashkernel != "" && (!t.bootable || (t.bootISO && !t.rpmOstree)) {
+			return fmt.Errorf("kdump crashkernel customizations are not supported for image type %q", t.name)
+		}
+		if err := kdump.Validate(customizations.GetServices()); err != nil {
+			return err
+		}
+	}
+	if err := customizations.GetAuthselect().Validate(); err != nil {
+		return err
+	}
+	if err := customizations.ValidateSudoers(); err != nil {
+		return err
+	}
+	if err := customizations.GetBanner().Validate(); err != nil {
+		return err
+	}
+	if err := customizations.ValidateCACerts(); err != nil {
+		return err
+	}
+	if err := customizations.GetProxy().Validate(); err != nil {
+		return err
+	}
+	if err := customizations.GetDNF().Validate(); err != nil {
+		return err
+	}
+	if err := customizations.ValidateRepositories(); err != nil {
+		return err
+	}
+	if dracut := customizations.GetDracut(); dracut != nil {
+		for _, module := range kernelModulesBlacklist(customizations.GetKernel()) {
+			for _, driver := range dracut.AddDrivers {
+				if driver == module {
+					return fmt.Errorf("kernel module %q is both blacklisted and added to the initramfs", module)
+				}
+			}
+		}
+	}
+	for _, key := range customizations.GetKernel().Remove {
+		if key == "" || strings.ContainsAny(key, "= \t\n") {
+			return fmt.Errorf("invalid kernel argument key %q to remove, must be the part of an argument before \"=\"", key)
+		}
+	}
 
 	mountpoints := customizations.GetFilesystems()
-
-	if mountpoints != nil && t.rpmOstree {
-		return fmt.Errorf("Custom mountpoints are not supported for ostree types")
-	}
+	policy := t.getMountpointPolicy()
 
 	invalidMountpoints := []string{}
 	for _, m := range mountpoints {
-		if m.Mountpoint == "/usr" && m.MinSize < 2147483648 {
-			m.MinSize = 2147483648
+		if err := policy.Check(m.Mountpoint); err != nil {
+			invalidMountpoints = append(invalidMountpoints, err.Error())
 		}
-		if !isMountpointAllowed(m.Mountpoint) {
-			invalidMountpoints = append(invalidMountpoints, m.Mountpoint)
+		for _, sv := range m.Subvolumes {
+			if err := policy.Check(sv.Mountpoint); err != nil {
+				invalidMountpoints = append(invalidMountpoints, err.Error())
+			}
 		}
 	}
 
 	if len(invalidMountpoints) > 0 {
-		return fmt.Errorf("The following custom mountpoints are not supported %+q", invalidMountpoints)
+		return fmt.Errorf("The following custom mountpoints are not supported: %s", strings.Join(invalidMountpoints, ", "))
+	}
+
+	return disk.ValidateFilesystemCustomizations(mountpoints, "xfs", "ext4", "btrfs")
+}
+
+// checkSwap returns an error if the swap space of the blueprint can't be
+// added to the image. Swap space may take at most the space of the image
+// the root filesystem doesn't need.
+func (t *imageType) checkSwap(swap *blueprint.SwapCustomization, customizations *blueprint.Customizations, options distro.ImageOptions) error {
+	if t.rpmOstree || !t.bootable || t.bootISO {
+		return fmt.Errorf("swap customizations are not supported for image type %q", t.name)
+	}
+	switch swap.Type {
+	case "", swapTypePartition:
+		if customizations.GetDiskEncryption() != nil {
+			// only the root partition is encrypted
+			return fmt.Errorf("swap partitions can't be combined with disk encryption, use a swap file instead")
+		}
+	case swapTypeFile:
+		for _, m := range customizations.GetFilesystems() {
+			if m.Mountpoint == "/" && m.FSType == "btrfs" {
+				return fmt.Errorf("swap files on btrfs root filesystems are not supported")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown swap type %q, must be one of partition or file", swap.Type)
+	}
+	if swap.Size == 0 {
+		return fmt.Errorf("swap size must be set")
+	}
+
+	rootSize := uint64(minRootSize)
+	for _, m := range customizations.GetFilesystems() {
+		if m.Mountpoint == "/" && m.MinSize > 0 {
+			rootSize = m.MinSize
+		}
+	}
+	imageSize := t.Size(options.Size)
+	if swapSize := swap.Size * 1024 * 1024; imageSize < rootSize || swapSize > imageSize-rootSize {
+		return fmt.Errorf("swap size of %d MiB exceeds the image size of %d MiB minus the %d MiB of the root filesystem", swap.Size, imageSize/1024/1024, rootSize/1024/1024)
+	}
+	return nil
+}
+
+// systemGroups are the groups created by the base packages of the image,
+// which users may be added to without defining them in the blueprint.
+var systemGroups = map[string]bool{
+	"adm":             true,
+	"audio":           true,
+	"bin":             true,
+	"cdrom":           true,
+	"daemon":          true,
+	"dialout":         true,
+	"disk":            true,
+	"floppy":          true,
+	"ftp":             true,
+	"games":           true,
+	"input":           true,
+	"kmem":            true,
+	"kvm":             true,
+	"lock":            true,
+	"lp":              true,
+	"mail":            true,
+	"man":             true,
+	"mem":             true,
+	"nobody":          true,
+	"render":          true,
+	"root":            true,
+	"sys":             true,
+	"systemd-journal": true,
+	"tape":            true,
+	"tty":             true,
+	"users":           true,
+	"utmp":            true,
+	"utempter":        true,
+	"video":           true,
+	"wheel":           true,
+}
+
+// checkUserIDs returns an error if two users have the same explicit UID or if
+// the UID of a user without an explicit GID is the GID of a group other than
+// the user's own group, which useradd would otherwise fail to create. Two
+// groups with the same GID, users whose explicit GID is neither a system GID
+// nor the GID of a group of the blueprint, and users that are members of
+// groups which neither exist in the image nor are defined in the blueprint
+// are rejected too.
+func checkUserIDs(users []blueprint.UserCustomization, groups []blueprint.GroupCustomization) error {
+	uids := make(map[int]string)
+	// useradd creates a group named like the user with the UID as GID for
+	// users without an explicit GID
+	primaryGIDs := make(map[int]string)
+	for _, user := range users {
+		if user.UID == nil {
+			continue
+		}
+		if other, exists := uids[*user.UID]; exists && other != user.Name {
+			return fmt.Errorf("users %q and %q have the same UID %d", other, user.Name, *user.UID)
+		}
+		uids[*user.UID] = user.Name
+		if user.GID == nil {
+			primaryGIDs[*user.UID] = user.Name
+		}
+	}
+
+	gids := make(map[int]string)
+	for _, group := range groups {
+		if group.GID == nil {
+			continue
+		}
+		if user, exists := primaryGIDs[*group.GID]; exists && user != group.Name {
+			return fmt.Errorf("the UID of user %q is the GID %d of group %q", user, *group.GID, group.Name)
+		}
+		if other, exists := gids[*group.GID]; exists && other != group.Name {
+			return fmt.Errorf("groups %q and %q have the same GID %d", other, group.Name, *group.GID)
+		}
+		gids[*group.GID] = group.Name
+	}
+
+	for _, user := range users {
+		// GIDs below 1000 belong to the system groups of the image
+		if user.GID == nil || *user.GID < 1000 {
+			continue
+		}
+		_, isGroup := gids[*user.GID]
+		_, isPrimaryGroup := primaryGIDs[*user.GID]
+		if !isGroup && !isPrimaryGroup {
+			return fmt.Errorf("the GID %d of user %q is neither a system GID nor the GID of a group defined in the blueprint", *user.GID, user.Name)
+		}
+	}
+
+	knownGroups := make(map[string]bool)
+	for _, group := range groups {
+		knownGroups[group.Name] = true
+	}
+	for _, user := range users {
+		knownGroups[user.Name] = true
+	}
+	for _, user := range users {
+		for _, group := range user.Groups {
+			if !knownGroups[group] && !systemGroups[group] {
+				return fmt.Errorf("user %q is a member of group %q, which is neither a system group nor defined in the blueprint", user.Name, group)
+			}
+		}
 	}
 
 	return nil
@@ -505,7 +1610,8 @@ func NewCentosHostDistro(name, modulePlatformID, ostreeRef string) distro.Distro
 }
 
 func newDistro(distroName string) distro.Distro {
-	const GigaByte = 1024 * 1024 * 1024
+	const MegaByte = 1024 * 1024
+	const GigaByte = 1024 * MegaByte
 
 	rd := distroMap[distroName]
 
@@ -550,10 +1656,11 @@ func newDistro(distroName string) distro.Distro {
 			buildPkgsKey: edgeBuildPackageSet,
 			osPkgsKey:    edgeCommitPackageSet,
 		},
-		enabledServices: edgeServices,
-		rpmOstree:       true,
-		pipelines:       edgeCommitPipelines,
-		exports:         []string{"commit-archive"},
+		enabledServices:  edgeServices,
+		rpmOstree:        true,
+		pipelines:        edgeCommitPipelines,
+		exports:          []string{"commit-archive"},
+		mountpointPolicy: &ostreeMountpointPolicy,
 	}
 
 	edgeOCIImgType := imageType{
@@ -570,18 +1677,19 @@ func newDistro(distroName string) distro.Distro {
 				}
 			},
 		},
-		enabledServices: edgeServices,
-		rpmOstree:       true,
-		bootISO:         false,
-		pipelines:       edgeContainerPipelines,
-		exports:         []string{"container"},
+		enabledServices:  edgeServices,
+		rpmOstree:        true,
+		bootISO:          false,
+		pipelines:        edgeContainerPipelines,
+		exports:          []string{"container"},
+		mountpointPolicy: &ostreeMountpointPolicy,
 	}
 
 	edgeRawImgType := imageType{
 		name:        "edge-raw-image",
 		nameAliases: []string{"rhel-edge-raw-image"},
-		filename:    "image.raw.xz",
-		mimeType:    "application/xz",
+		filename:    "image.raw",
+		compression: compressionXz,
 		packageSets: map[string]packageSetFunc{
 			buildPkgsKey: edgeRawImageBuildPackageSet,
 		},
@@ -592,6 +1700,8 @@ func newDistro(distroName string) distro.Distro {
 		pipelines:           edgeRawImagePipelines,
 		exports:             []string{"archive"},
 		basePartitionTables: edgeBasePartitionTables,
+		mountpointPolicy:    &ostreeMountpointPolicy,
+		espUmask:            true,
 	}
 
 	edgeInstallerImgType := imageType{
@@ -611,11 +1721,16 @@ func newDistro(distroName string) distro.Distro {
 			osPkgsKey:        edgeCommitPackageSet,
 			installerPkgsKey: edgeInstallerPackageSet,
 		},
-		enabledServices: edgeServices,
-		rpmOstree:       true,
-		bootISO:         true,
-		pipelines:       edgeInstallerPipelines,
-		exports:         []string{"bootiso"},
+		enabledServices:            edgeServices,
+		rpmOstree:                  true,
+		bootISO:                    true,
+		variant:                    "edge",
+		kickstartModules:           defaultKickstartModules,
+		pipelines:                  edgeInstallerPipelines,
+		installerRootFSSize:        9216 * MegaByte,
+		installerRootFSCompression: osbuild.FSCompressionXz,
+		exports:                    []string{"bootiso"},
+		mountpointPolicy:           &ostreeMountpointPolicy,
 	}
 
 	edgeSimplifiedInstallerImgType := imageType{
@@ -639,9 +1754,12 @@ func newDistro(distroName string) distro.Distro {
 		rpmOstree:           true,
 		bootable:            true,
 		bootISO:             true,
+		variant:             "edge",
 		pipelines:           edgeSimplifiedInstallerPipelines,
 		exports:             []string{"bootiso"},
 		basePartitionTables: edgeBasePartitionTables,
+		mountpointPolicy:    &ostreeMountpointPolicy,
+		espUmask:            true,
 	}
 
 	qcow2ImgType := imageType{
@@ -659,6 +1777,8 @@ func newDistro(distroName string) distro.Distro {
 		pipelines:           qcow2Pipelines,
 		exports:             []string{"qcow2"},
 		basePartitionTables: defaultBasePartitionTables,
+		qcow2Compat:         "1.1",
+		qcow2Compression:    osbuild.Qcow2CompressionZstd,
 	}
 
 	vhdImgType := imageType{
@@ -679,7 +1799,58 @@ func newDistro(distroName string) distro.Distro {
 		defaultSize:         4 * GigaByte,
 		pipelines:           vhdPipelines,
 		exports:             []string{"vpc"},
+		legacySectors:       true,
+		basePartitionTables: defaultBasePartitionTables,
+		ptpClock:            "/dev/ptp_hyperv",
+	}
+
+	// vhd images for Hyper-V generation 2 virtual machines, which boot with
+	// UEFI
+	vhdGen2ImgType := imageType{
+		name:     "vhd-gen2",
+		filename: "disk.vhd",
+		mimeType: "application/x-vhd",
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    vhdCommonPackageSet,
+		},
+		enabledServices: []string{
+			"sshd",
+			"waagent",
+		},
+		defaultTarget:       "multi-user.target",
+		kernelOptions:       "ro biosdevname=0 rootdelay=300 console=ttyS0 earlyprintk=ttyS0 net.ifnames=0",
+		bootable:            true,
+		bootType:            distro.UEFIBootType,
+		defaultSize:         4 * GigaByte,
+		pipelines:           vhdPipelines,
+		exports:             []string{"vpc"},
+		legacySectors:       true,
+		basePartitionTables: vhdGen2BasePartitionTables,
+		ptpClock:            "/dev/ptp_hyperv",
+	}
+
+	vhdxImgType := imageType{
+		name:     "vhdx",
+		filename: "disk.vhdx",
+		mimeType: "application/x-vhdx",
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    vhdxPackageSet,
+		},
+		enabledServices: []string{
+			"hypervkvpd",
+			"hypervvssd",
+		},
+		defaultTarget:       "multi-user.target",
+		kernelOptions:       "console=tty0 console=ttyS0,115200n8 net.ifnames=0 crashkernel=auto",
+		bootable:            true,
+		defaultSize:         10 * GigaByte,
+		pipelines:           vhdxPipelines,
+		exports:             []string{"vhdx"},
+		legacySectors:       true,
 		basePartitionTables: defaultBasePartitionTables,
+		ptpClock:            "/dev/ptp_hyperv",
 	}
 
 	vmdkImgType := imageType{
@@ -698,6 +1869,63 @@ func newDistro(distroName string) distro.Distro {
 		basePartitionTables: defaultBasePartitionTables,
 	}
 
+	minimalrawImgType := imageType{
+		name:        "minimal-raw",
+		filename:    "disk.raw",
+		compression: compressionXz,
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    minimalrawPackageSet,
+		},
+		enabledServices:     []string{"sshd", "NetworkManager"},
+		defaultTarget:       "multi-user.target",
+		kernelOptions:       "ro",
+		bootable:            true,
+		bootType:            distro.UEFIBootType,
+		defaultSize:         2 * GigaByte,
+		pipelines:           minimalrawPipelines,
+		exports:             []string{"archive"},
+		basePartitionTables: minimalrawBasePartitionTables,
+		rpmDefaults: blueprint.RPMCustomization{
+			ExcludeDocs:  common.BoolToPtr(true),
+			InstallLangs: []string{"en_US"},
+		},
+	}
+
+	gceImgType := imageType{
+		name:     "gce",
+		filename: "image.tar.gz",
+		mimeType: "application/gzip",
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    gcePackageSet,
+		},
+		enabledServices:     []string{"sshd", "rngd", "dnf-automatic.timer"},
+		defaultTarget:       "multi-user.target",
+		kernelOptions:       "net.ifnames=0 biosdevname=0 scsi_mod.use_blk_mq=Y crashkernel=auto console=ttyS0,38400n8d",
+		bootable:            true,
+		defaultSize:         20 * GigaByte,
+		pipelines:           gcePipelines,
+		exports:             []string{"archive"},
+		basePartitionTables: defaultBasePartitionTables,
+	}
+
+	ovaImgType := imageType{
+		name:     "ova",
+		filename: "image.ova",
+		mimeType: "application/ovf",
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    vmdkCommonPackageSet,
+		},
+		kernelOptions:       "ro net.ifnames=0",
+		bootable:            true,
+		defaultSize:         4 * GigaByte,
+		pipelines:           ovaPipelines,
+		exports:             []string{"archive"},
+		basePartitionTables: defaultBasePartitionTables,
+	}
+
 	openstackImgType := imageType{
 		name:     "openstack",
 		filename: "disk.qcow2",
@@ -855,6 +2083,17 @@ func newDistro(distroName string) distro.Distro {
 		pipelines: tarPipelines,
 		exports:   []string{"root-tar"},
 	}
+	wslImgType := imageType{
+		name:     "wsl",
+		filename: "wsl.tar.gz",
+		mimeType: "application/gzip",
+		packageSets: map[string]packageSetFunc{
+			buildPkgsKey: distroBuildPackageSet,
+			osPkgsKey:    wslPackageSet,
+		},
+		pipelines: wslPipelines,
+		exports:   []string{"root-tar"},
+	}
 	tarInstallerImgTypeX86_64 := imageType{
 		name:     "image-installer",
 		filename: "installer.iso",
@@ -864,15 +2103,19 @@ func newDistro(distroName string) distro.Distro {
 			osPkgsKey:        bareMetalPackageSet,
 			installerPkgsKey: anacondaPackageSet,
 		},
-		rpmOstree: false,
-		bootISO:   true,
-		bootable:  true,
-		pipelines: tarInstallerPipelines,
-		exports:   []string{"bootiso"},
+		rpmOstree:                  false,
+		bootISO:                    true,
+		bootable:                   true,
+		kickstartModules:           defaultKickstartModules,
+		payloadPkgsKey:             osPkgsKey,
+		pipelines:                  tarInstallerPipelines,
+		installerRootFSSize:        9216 * MegaByte,
+		installerRootFSCompression: osbuild.FSCompressionXz,
+		exports:                    []string{"bootiso"},
 	}
 
-	x86_64.addImageTypes(qcow2ImgType, vhdImgType, vmdkImgType, openstackImgType, amiImgTypeX86_64, tarImgType, tarInstallerImgTypeX86_64, edgeCommitImgType, edgeInstallerImgType, edgeOCIImgType, edgeRawImgType, edgeSimplifiedInstallerImgType)
-	aarch64.addImageTypes(qcow2ImgType, openstackImgType, amiImgTypeAarch64, tarImgType, edgeCommitImgType, edgeInstallerImgType, edgeOCIImgType, edgeRawImgType, edgeSimplifiedInstallerImgType)
+	x86_64.addImageTypes(qcow2ImgType, vhdImgType, vhdGen2ImgType, vhdxImgType, vmdkImgType, ovaImgType, openstackImgType, amiImgTypeX86_64, tarImgType, tarInstallerImgTypeX86_64, edgeCommitImgType, edgeInstallerImgType, edgeOCIImgType, edgeRawImgType, edgeSimplifiedInstallerImgType, minimalrawImgType, wslImgType, gceImgType)
+	aarch64.addImageTypes(qcow2ImgType, openstackImgType, amiImgTypeAarch64, tarImgType, edgeCommitImgType, edgeInstallerImgType, edgeOCIImgType, edgeRawImgType, edgeSimplifiedInstallerImgType, minimalrawImgType, wslImgType)
 	ppc64le.addImageTypes(qcow2ImgType, tarImgType)
 	s390x.addImageTypes(qcow2ImgType, tarImgType)
 
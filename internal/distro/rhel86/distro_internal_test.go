@@ -1,12 +1,26 @@
 package rhel86
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/crypt"
 	"github.com/osbuild/osbuild-composer/internal/disk"
 	"github.com/osbuild/osbuild-composer/internal/distro"
+	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild2"
+	"github.com/osbuild/osbuild-composer/internal/rpmmd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,7 +60,7 @@ func TestDistro_UnsupportedArch(t *testing.T) {
 	testBasicImageType.arch = &architecture{
 		name: "unsupported_arch",
 	}
-	_, err := testBasicImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+	_, err := testBasicImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 	require.EqualError(t, err, "unknown arch: "+testBasicImageType.arch.name)
 }
 
@@ -56,7 +70,7 @@ func TestDistro_DefaultPartitionTables(t *testing.T) {
 		testBasicImageType.arch = &architecture{
 			name: archName,
 		}
-		pt, err := testBasicImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+		pt, err := testBasicImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 		require.Nil(t, err)
 		for _, m := range mountpoints {
 			contains := containsMountpoint(pt.Partitions, m.Mountpoint)
@@ -71,7 +85,7 @@ func TestDistro_Ec2PartitionTables(t *testing.T) {
 		testEc2ImageType.arch = &architecture{
 			name: archName,
 		}
-		pt, err := testEc2ImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
+		pt, err := testEc2ImageType.getPartitionTable(&blueprint.Customizations{Filesystem: mountpoints}, distro.ImageOptions{}, rng)
 		if _, exists := testEc2ImageType.basePartitionTables[archName]; exists {
 			require.Nil(t, err)
 			for _, m := range mountpoints {
@@ -83,3 +97,1184 @@ func TestDistro_Ec2PartitionTables(t *testing.T) {
 		}
 	}
 }
+
+// The x86_64 disk layouts are GPT, which isn't limited to 2 TiB, with a BIOS
+// boot partition for the GRUB core image of legacy boot and an ESP for UEFI
+func TestDistro_X86_64PartitionTables(t *testing.T) {
+	arch, err := New().GetArch(distro.X86_64ArchName)
+	require.NoError(t, err)
+	for _, name := range arch.ListImageTypes() {
+		it, err := arch.GetImageType(name)
+		require.NoError(t, err)
+		imgType := it.(*imageType)
+		base, exists := imgType.basePartitionTables[distro.X86_64ArchName]
+		if !exists {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, "gpt", base.Type)
+			for _, p := range base.Partitions {
+				_, err := uuid.Parse(p.Type)
+				assert.NoError(t, err, "partition type %q is not a GUID", p.Type)
+			}
+			if imgType.legacyPlatform() != "" {
+				assert.Equal(t, disk.BIOSBootPartitionGUID, base.Partitions[0].Type)
+
+				pt, err := imgType.getPartitionTable(nil, distro.ImageOptions{Size: imgType.Size(0)}, rng)
+				require.NoError(t, err)
+				options := grub2InstStageOptions("disk.img", &pt, imgType.legacyPlatform())
+				assert.Equal(t, "gpt", options.Core.PartLabel)
+				assert.Equal(t, pt.Partitions[0].Start, options.Location)
+				assert.Equal(t, uint(pt.BootPartitionIndex()), options.Prefix.Number)
+			}
+			if imgType.supportsUEFI() {
+				hasESP := false
+				for _, p := range base.Partitions {
+					hasESP = hasESP || p.Type == disk.EFISystemPartitionGUID
+				}
+				assert.True(t, hasESP)
+			}
+		})
+	}
+}
+
+func TestKickstartPostOptions(t *testing.T) {
+	post, inputs := kickstartPostOptions(nil)
+	assert.Nil(t, post)
+	assert.Nil(t, inputs)
+
+	scripts := []blueprint.PostScriptCustomization{
+		{
+			Script: "echo first > /etc/first",
+		},
+		{
+			Interpreter: "/usr/bin/bash",
+			ErrorOnFail: true,
+			Script:      "echo second > /etc/second",
+		},
+		{
+			NoChroot: true,
+			Script:   "cp /tmp/log /mnt/sysimage/root/",
+		},
+		{
+			// the same body is only referenced once by the inputs
+			Script: "echo first > /etc/first",
+		},
+	}
+
+	post, inputs = kickstartPostOptions(scripts)
+	require.Len(t, post, len(scripts))
+	for idx, script := range scripts {
+		assert.Equal(t, "input://post/"+osbuild.InlineSourceChecksum([]byte(script.Script)), post[idx].Script)
+		assert.Equal(t, script.Interpreter, post[idx].Interpreter)
+		assert.Equal(t, script.ErrorOnFail, post[idx].ErrorOnFail)
+		assert.Equal(t, script.NoChroot, post[idx].NoChroot)
+	}
+	assert.False(t, post[1].NoChroot)
+	assert.True(t, post[2].NoChroot)
+
+	require.NotNil(t, inputs)
+	assert.Equal(t, osbuild.KickstartStageReferences{
+		osbuild.InlineSourceChecksum([]byte(scripts[0].Script)),
+		osbuild.InlineSourceChecksum([]byte(scripts[1].Script)),
+		osbuild.InlineSourceChecksum([]byte(scripts[2].Script)),
+	}, inputs.Post.References)
+
+	files := installerPostScriptFiles(scripts)
+	require.Len(t, files, len(scripts))
+	for idx, script := range scripts {
+		assert.Equal(t, script.Script, files[idx].Data)
+		assert.Empty(t, files[idx].Path)
+	}
+}
+
+func TestImageType_PasswordHash(t *testing.T) {
+	d := New().(*distribution)
+	it := imageType{
+		arch: &architecture{
+			distro: d,
+		},
+	}
+
+	pwHash := it.passwordHash(nil)
+	assert.Equal(t, crypt.MethodSHA512, pwHash.method)
+	assert.Nil(t, pwHash.options)
+
+	pwHash = it.passwordHash(&blueprint.Customizations{
+		PasswordHash: &blueprint.PasswordHashCustomization{
+			Rounds: 100000,
+		},
+	})
+	assert.Equal(t, crypt.MethodSHA512, pwHash.method)
+	assert.Equal(t, &crypt.Options{Rounds: 100000}, pwHash.options)
+
+	pwHash = it.passwordHash(&blueprint.Customizations{
+		PasswordHash: &blueprint.PasswordHashCustomization{
+			Method: "yescrypt",
+		},
+	})
+	assert.Equal(t, crypt.MethodYescrypt, pwHash.method)
+	assert.Nil(t, pwHash.options)
+}
+
+func TestUserStageOptions_PasswordHash(t *testing.T) {
+	plain := "password"
+	crypted := "$y$j9T$abcdefghijklmnop$7asOTx5b6Exfl3myM6K0pLBn.I2hsEvu7G0F7NMfaO."
+	users := []blueprint.UserCustomization{
+		{
+			Name:     "plain",
+			Password: &plain,
+		},
+		{
+			Name:     "crypted",
+			Password: &crypted,
+		},
+	}
+
+	options, err := userStageOptions(users, passwordHash{method: crypt.MethodSHA512, options: &crypt.Options{Rounds: 10000}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(*options.Users["plain"].Password, "$6$rounds=10000$"))
+	assert.Equal(t, crypted, *options.Users["crypted"].Password)
+
+	options, err = userStageOptions(users, passwordHash{method: crypt.MethodYescrypt})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(*options.Users["plain"].Password, "$y$"))
+	assert.Equal(t, crypted, *options.Users["crypted"].Password)
+}
+
+func TestAuthorizedKeysFiles(t *testing.T) {
+	files := authorizedKeysFiles([]blueprint.UserCustomization{
+		{Name: "web-admin", Key: common.StringToPtr(`ssh-ed25519 AAAA o'brien's key $(reboot) %h`)},
+		{Name: "build-bot", Key: common.StringToPtr("ssh-ed25519 AAAA first\nssh-ed25519 BBBB `id` \"second\""), GID: common.IntToPtr(1042)},
+		{Name: "svc", Locked: common.BoolToPtr(true)},
+		{Name: "alice", Key: common.StringToPtr("ssh-ed25519 CCCC\n"), Home: common.StringToPtr("/var/srv/alice home")},
+	})
+
+	// the keys are written as they are, only the accounts with a key get
+	// a file
+	assert.Equal(t, []blueprint.FileCustomization{
+		{
+			Path:  "/etc/ssh/authorized_keys/web-admin",
+			Mode:  "0600",
+			User:  "web-admin",
+			Group: "web-admin",
+			Data:  "ssh-ed25519 AAAA o'brien's key $(reboot) %h\n",
+		},
+		{
+			Path:  "/etc/ssh/authorized_keys/build-bot",
+			Mode:  "0600",
+			User:  "build-bot",
+			Group: "1042",
+			Data:  "ssh-ed25519 AAAA first\nssh-ed25519 BBBB `id` \"second\"\n",
+		},
+		{
+			Path:  "/etc/ssh/authorized_keys/alice",
+			Mode:  "0600",
+			User:  "alice",
+			Group: "alice",
+			Data:  "ssh-ed25519 CCCC\n",
+		},
+	}, files[:3])
+
+	require.Len(t, files, 4)
+	assert.Equal(t, "/etc/tmpfiles.d/authorized-keys.conf", files[3].Path)
+	assert.Equal(t, `d /var/home/web-admin/.ssh 0700 web-admin web-admin -
+C /var/home/web-admin/.ssh/authorized_keys 0600 web-admin web-admin - /etc/ssh/authorized_keys/web-admin
+d /var/home/build-bot/.ssh 0700 build-bot 1042 -
+C /var/home/build-bot/.ssh/authorized_keys 0600 build-bot 1042 - /etc/ssh/authorized_keys/build-bot
+d "/var/srv/alice home/.ssh" 0700 alice alice -
+C "/var/srv/alice home/.ssh/authorized_keys" 0600 alice alice - /etc/ssh/authorized_keys/alice
+`, files[3].Data)
+
+	assert.Nil(t, authorizedKeysFiles([]blueprint.UserCustomization{{Name: "svc"}}))
+}
+
+func TestTmpfilesField(t *testing.T) {
+	assert.Equal(t, "/var/home/web-admin", tmpfilesField("/var/home/web-admin"))
+	assert.Equal(t, "/var/home/50%%", tmpfilesField("/var/home/50%"))
+	assert.Equal(t, `"/var/home/a b"`, tmpfilesField("/var/home/a b"))
+	assert.Equal(t, `"/var/home/a\"b\nc"`, tmpfilesField("/var/home/a\"b\nc"))
+}
+
+func TestUserStageOptions_LockedAndExpiring(t *testing.T) {
+	users := []blueprint.UserCustomization{
+		{Name: "svc", Locked: common.BoolToPtr(true)},
+		{Name: "deploy", Locked: common.BoolToPtr(true), Key: common.StringToPtr("ssh-ed25519 AAAA")},
+		{Name: "contractor", ExpireDate: common.StringToPtr("2031-06-30")},
+	}
+	options, err := userStageOptions(users, passwordHash{method: crypt.MethodSHA512})
+	require.NoError(t, err)
+	assert.Equal(t, osbuild.UsersStageOptionsUser{Locked: common.BoolToPtr(true)}, options.Users["svc"])
+	assert.Equal(t, common.StringToPtr("2031-06-30"), options.Users["contractor"].ExpireDate)
+
+	// only the locked account with a key of its own gets authorized_keys
+	files := authorizedKeysFiles(users)
+	require.Len(t, files, 2)
+	assert.Equal(t, "/etc/ssh/authorized_keys/deploy", files[0].Path)
+}
+
+func TestAnacondaStageOptions(t *testing.T) {
+	assert.Equal(t, defaultKickstartModules, anacondaStageOptions(defaultKickstartModules, nil).KickstartModules)
+
+	interactive := &blueprint.AnacondaModulesCustomization{
+		Enable: []string{
+			"org.fedoraproject.Anaconda.Modules.Users",
+			"org.fedoraproject.Anaconda.Modules.Storage",
+			"org.fedoraproject.Anaconda.Modules.Services",
+		},
+	}
+	assert.Equal(t, []string{
+		"org.fedoraproject.Anaconda.Modules.Network",
+		"org.fedoraproject.Anaconda.Modules.Payloads",
+		"org.fedoraproject.Anaconda.Modules.Storage",
+		"org.fedoraproject.Anaconda.Modules.Users",
+		"org.fedoraproject.Anaconda.Modules.Services",
+	}, anacondaStageOptions(defaultKickstartModules, interactive).KickstartModules)
+
+	kiosk := &blueprint.AnacondaModulesCustomization{
+		Disable: []string{"org.fedoraproject.Anaconda.Modules.Network"},
+	}
+	assert.Equal(t, []string{
+		"org.fedoraproject.Anaconda.Modules.Payloads",
+		"org.fedoraproject.Anaconda.Modules.Storage",
+	}, anacondaStageOptions(defaultKickstartModules, kiosk).KickstartModules)
+	// the defaults of the image type are not modified
+	assert.Len(t, defaultKickstartModules, 3)
+}
+
+func TestDracutStageOptions(t *testing.T) {
+	defaults := dracutStageOptions("5.14.0", distro.X86_64ArchName, []string{"anaconda"}, nil, nil)
+	assert.Equal(t, []string{"/.buildstamp"}, defaults.Install)
+	assert.Nil(t, defaults.AddDrivers)
+	assert.Nil(t, defaults.Extra)
+
+	options := dracutStageOptions("5.14.0", distro.X86_64ArchName, []string{"anaconda"}, &blueprint.DracutCustomization{
+		AddModules: []string{"nvdimm", "anaconda", "lvm", "nvdimm"},
+		AddDrivers: []string{"hv_vmbus", "hv_storvsc", "hv_vmbus"},
+		Install:    []string{"/usr/lib/firmware/acme/fw.bin", "/.buildstamp"},
+	}, nil)
+	// the customization only appends modules which aren't included yet
+	assert.Equal(t, append(defaults.Modules, "nvdimm"), options.Modules)
+	assert.Equal(t, []string{"hv_vmbus", "hv_storvsc"}, options.AddDrivers)
+	assert.Equal(t, []string{"/.buildstamp", "/usr/lib/firmware/acme/fw.bin"}, options.Install)
+
+	// blacklisted kernel modules are omitted
+	options = dracutStageOptions("5.14.0", distro.X86_64ArchName, []string{"anaconda"}, nil, []string{"nouveau", "firewire-core"})
+	assert.Equal(t, []string{"--omit-drivers", "nouveau firewire-core"}, options.Extra)
+}
+
+func TestKernelModulesBlacklistStages(t *testing.T) {
+	assert.Nil(t, kernelModulesBlacklistStages(nil))
+	assert.Nil(t, kernelModulesBlacklistStages(&blueprint.KernelCustomization{Name: "kernel"}))
+	assert.Nil(t, modprobeBlacklistStageOptions(nil))
+
+	kernel := &blueprint.KernelCustomization{
+		Modules: &blueprint.KernelModulesCustomization{
+			Blacklist: []string{"nouveau", "firewire-core", "nouveau"},
+		},
+	}
+	stages := kernelModulesBlacklistStages(kernel)
+	require.Len(t, stages, 2)
+	assert.Equal(t, osbuild.NewModprobeStage(&osbuild.ModprobeStageOptions{
+		Filename: "blacklist-customizations.conf",
+		Commands: osbuild.ModprobeConfigCmdList{
+			osbuild.NewModprobeConfigCmdBlacklist("nouveau"),
+			osbuild.NewModprobeConfigCmdBlacklist("firewire-core"),
+		},
+	}), stages[0])
+	assert.Equal(t, osbuild.NewDracutConfStage(&osbuild.DracutConfStageOptions{
+		Filename: "50-blacklist-customizations.conf",
+		Config: osbuild.DracutConfigFile{
+			OmitDrivers: []string{"nouveau", "firewire-core"},
+		},
+	}), stages[1])
+}
+
+func TestRemoveKernelOptions(t *testing.T) {
+	defaults := "console=tty0 console=ttyS0,115200n8 no_timer_check net.ifnames=0 crashkernel=auto"
+	assert.Equal(t, defaults, removeKernelOptions(defaults, nil))
+	assert.Equal(t, "no_timer_check net.ifnames=0 crashkernel=auto", removeKernelOptions(defaults, []string{"console"}))
+	assert.Equal(t, "console=tty0 console=ttyS0,115200n8 crashkernel=auto", removeKernelOptions(defaults, []string{"no_timer_check", "net.ifnames"}))
+	// keys only match whole keys
+	assert.Equal(t, defaults, removeKernelOptions(defaults, []string{"net", "crash"}))
+}
+
+func TestDedupeKernelOptions(t *testing.T) {
+	assert.Equal(t, "ro net.ifnames=0 debug", dedupeKernelOptions("ro  net.ifnames=0 debug ro net.ifnames=0"))
+	assert.Equal(t, "console=tty0 console=ttyS0", dedupeKernelOptions("console=tty0 console=ttyS0"))
+}
+
+func TestSysctlStageOptions(t *testing.T) {
+	assert.Nil(t, sysctlStageOptions(nil))
+
+	options := sysctlStageOptions([]blueprint.SysctlCustomization{
+		{Key: "vm.swappiness", Value: "10"},
+		{Key: "net.ipv4.ip_forward", Value: "1"},
+	})
+	assert.Equal(t, osbuild.NewSysctldStageOptions("90-osbuild.conf", []osbuild.SysctldConfigLine{
+		{Key: "net.ipv4.ip_forward", Value: "1"},
+		{Key: "vm.swappiness", Value: "10"},
+	}), options)
+}
+
+func TestSudoersFile(t *testing.T) {
+	assert.Nil(t, sudoersFile(nil))
+
+	file := sudoersFile([]blueprint.SudoRule{
+		{Name: "alice"},
+		{Name: "cloud-user", Nopasswd: true},
+		{Name: "admins", Group: true},
+		{Name: "machine$", Group: true, Nopasswd: true},
+	})
+	require.NotNil(t, file)
+	assert.Equal(t, "/etc/sudoers.d/blueprint-customizations", file.Path)
+	assert.Equal(t, "0440", file.Mode)
+	assert.Equal(t, `# Created by osbuild-composer from the blueprint customizations
+alice ALL=(ALL) ALL
+cloud-user ALL=(ALL) NOPASSWD: ALL
+%admins ALL=(ALL) ALL
+%machine$ ALL=(ALL) NOPASSWD: ALL
+`, file.Data)
+
+	// every rule is a user specification in the grammar of sudoers(5)
+	userSpec := regexp.MustCompile(`^%?[A-Za-z0-9_.][A-Za-z0-9_.-]*\$? ALL=\(ALL\) (NOPASSWD: )?ALL$`)
+	for _, line := range strings.Split(strings.TrimSuffix(file.Data, "\n"), "\n")[1:] {
+		assert.Regexp(t, userSpec, line)
+	}
+
+	visudo, err := exec.LookPath("visudo")
+	if err != nil {
+		t.Skip("visudo is not available")
+	}
+	sudoers := filepath.Join(t.TempDir(), "sudoers")
+	require.NoError(t, ioutil.WriteFile(sudoers, []byte(file.Data), 0440))
+	out, err := exec.Command(visudo, "-c", "-f", sudoers).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestCheckUserIDs(t *testing.T) {
+	cases := []struct {
+		users  []blueprint.UserCustomization
+		groups []blueprint.GroupCustomization
+		err    string
+	}{
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000)},
+				{Name: "bob", UID: common.IntToPtr(1001)},
+				{Name: "carol"},
+			},
+			groups: []blueprint.GroupCustomization{
+				{Name: "alice", GID: common.IntToPtr(1000)},
+				{Name: "wheel"},
+				{Name: "devs", GID: common.IntToPtr(2000)},
+			},
+		},
+		{
+			// a user can be defined by an ssh key and a user customization
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000)},
+				{Name: "alice", UID: common.IntToPtr(1000)},
+			},
+		},
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000)},
+				{Name: "bob", UID: common.IntToPtr(1000)},
+			},
+			err: `users "alice" and "bob" have the same UID 1000`,
+		},
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000)},
+			},
+			groups: []blueprint.GroupCustomization{
+				{Name: "devs", GID: common.IntToPtr(1000)},
+			},
+			err: `the UID of user "alice" is the GID 1000 of group "devs"`,
+		},
+		{
+			// users with an explicit GID don't get a group of their own
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000), GID: common.IntToPtr(2000)},
+				{Name: "bob", UID: common.IntToPtr(1001), GID: common.IntToPtr(1002)},
+				{Name: "carol", UID: common.IntToPtr(1002)},
+				{Name: "dave", GID: common.IntToPtr(100)},
+			},
+			groups: []blueprint.GroupCustomization{
+				{Name: "ops", GID: common.IntToPtr(1000)},
+				{Name: "devs", GID: common.IntToPtr(2000)},
+			},
+		},
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", UID: common.IntToPtr(1000), GID: common.IntToPtr(3000)},
+			},
+			groups: []blueprint.GroupCustomization{
+				{Name: "devs", GID: common.IntToPtr(2000)},
+			},
+			err: `the GID 3000 of user "alice" is neither a system GID nor the GID of a group defined in the blueprint`,
+		},
+		{
+			groups: []blueprint.GroupCustomization{
+				{Name: "devs", GID: common.IntToPtr(2000)},
+				{Name: "ops", GID: common.IntToPtr(2000)},
+			},
+			err: `groups "devs" and "ops" have the same GID 2000`,
+		},
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", Groups: []string{"wheel", "devs", "bob"}},
+				{Name: "bob"},
+			},
+			groups: []blueprint.GroupCustomization{
+				{Name: "devs"},
+			},
+		},
+		{
+			users: []blueprint.UserCustomization{
+				{Name: "alice", Groups: []string{"wheel", "devs"}},
+			},
+			err: `user "alice" is a member of group "devs", which is neither a system group nor defined in the blueprint`,
+		},
+	}
+	for idx, c := range cases {
+		err := checkUserIDs(c.users, c.groups)
+		if c.err == "" {
+			assert.NoError(t, err, "case %d", idx)
+		} else {
+			assert.EqualError(t, err, c.err, "case %d", idx)
+		}
+	}
+}
+
+func TestDistro_ComposeID(t *testing.T) {
+	d := distroMap["rhel-86"]
+
+	buildDate := time.Date(2021, 10, 17, 23, 30, 0, 0, time.UTC)
+	assert.Equal(t, "RHEL-8.6.0-20211017.n.0", d.composeID(buildDate))
+	// the date is always taken in UTC
+	assert.Equal(t, "RHEL-8.6.0-20211017.n.0", d.composeID(buildDate.In(time.FixedZone("UTC+2", 2*60*60))))
+	// the zero value selects the Unix epoch
+	assert.Equal(t, "RHEL-8.6.0-19700101.n.0", d.composeID(time.Time{}))
+
+	c := distroMap["centos-8"]
+	assert.Equal(t, "CentOS-Stream-8-20211017.0", c.composeID(buildDate))
+
+	id := d.composeID(time.Now())
+	match := regexp.MustCompile(`^RHEL-8\.6\.0-(\d{8})\.n\.0$`).FindStringSubmatch(id)
+	require.NotNil(t, match, "unexpected compose ID %q", id)
+	_, err := time.Parse(composeIDDateFormat, match[1])
+	assert.NoError(t, err)
+}
+
+func TestBuildStampStageOptions(t *testing.T) {
+	d := distroMap["rhel-86"]
+	options := buildStampStageOptions("x86_64", d.product, d.osVersion, d.variant, d.isFinal)
+	assert.Equal(t, "BaseOS", options.Variant)
+	assert.True(t, options.Final)
+
+	options = buildStampStageOptions("x86_64", d.product, d.osVersion, "edge", false)
+	assert.Equal(t, "edge", options.Variant)
+	assert.False(t, options.Final)
+}
+
+func TestImageType_InstallerVariant(t *testing.T) {
+	arch, err := New().GetArch(distro.X86_64ArchName)
+	require.NoError(t, err)
+	expected := map[string]string{
+		"image-installer":           "BaseOS",
+		"edge-installer":            "edge",
+		"edge-simplified-installer": "edge",
+	}
+	for name, variant := range expected {
+		it, err := arch.GetImageType(name)
+		require.NoError(t, err)
+		assert.Equal(t, variant, it.(*imageType).installerVariant(), name)
+	}
+}
+
+func TestDefaultKernelVer(t *testing.T) {
+	kernel := rpmmd.PackageSpec{Name: "kernel", Version: "4.18.0", Release: "348.el8", Arch: "x86_64"}
+	kernelRT := rpmmd.PackageSpec{Name: "kernel-rt", Version: "4.18.0", Release: "348.rt7.130.el8", Arch: "x86_64"}
+	bash := rpmmd.PackageSpec{Name: "bash", Version: "4.4.20", Release: "3.el8", Arch: "x86_64"}
+
+	// a single kernel is the default one
+	ver, err := defaultKernelVer([]rpmmd.PackageSpec{bash, kernel}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "4.18.0-348.el8.x86_64", ver)
+
+	// with two kernels, the blueprint chooses the default one
+	customizations := &blueprint.Customizations{Kernel: &blueprint.KernelCustomization{Name: "kernel-rt"}}
+	ver, err = defaultKernelVer([]rpmmd.PackageSpec{kernel, bash, kernelRT}, customizations)
+	require.NoError(t, err)
+	assert.Equal(t, "4.18.0-348.rt7.130.el8.x86_64", ver)
+
+	customizations = &blueprint.Customizations{Kernel: &blueprint.KernelCustomization{Name: "kernel"}}
+	ver, err = defaultKernelVer([]rpmmd.PackageSpec{kernel, bash, kernelRT}, customizations)
+	require.NoError(t, err)
+	assert.Equal(t, "4.18.0-348.el8.x86_64", ver)
+
+	// ... and must do so
+	_, err = defaultKernelVer([]rpmmd.PackageSpec{kernel, bash, kernelRT}, &blueprint.Customizations{})
+	assert.EqualError(t, err, "the image contains multiple kernels (kernel, kernel-rt), choose the default one with customizations.kernel.name")
+
+	// the chosen kernel must be installed
+	_, err = defaultKernelVer([]rpmmd.PackageSpec{kernel, bash}, &blueprint.Customizations{Kernel: &blueprint.KernelCustomization{Name: "kernel-rt"}})
+	assert.EqualError(t, err, `the default kernel "kernel-rt" is not installed in the image`)
+}
+
+func TestQemuStageOptions(t *testing.T) {
+	options, err := qemuStageOptions("disk.qcow2", "qcow2", osbuild.Qcow2Options{Compat: "1.1", ClusterSize: 65536})
+	require.NoError(t, err)
+	assert.Equal(t, osbuild.Qcow2Options{Type: "qcow2", Compat: "1.1", ClusterSize: 65536}, options.Format)
+
+	options, err = qemuStageOptions("disk.vhd", "vpc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, osbuild.VPCOptions{Type: "vpc"}, options.Format)
+
+	_, err = qemuStageOptions("disk.vhd", "vpc", osbuild.Qcow2Options{})
+	assert.Error(t, err)
+
+	options, err = qemuStageOptions("disk.vhdx", "vhdx", osbuild.VHDXOptions{Subformat: osbuild.VHDXSubformatDynamic})
+	require.NoError(t, err)
+	assert.Equal(t, osbuild.VHDXOptions{Type: "vhdx", Subformat: "dynamic"}, options.Format)
+
+	_, err = qemuStageOptions("disk.vdi", "vdi", nil)
+	assert.EqualError(t, err, "unknown format in qemu stage: vdi")
+}
+
+func TestRootDeviceIDModes(t *testing.T) {
+	customizations := &blueprint.Customizations{Filesystem: mountpoints}
+	for _, mode := range []disk.DeviceIDMode{disk.DeviceIDFilesystemUUID, disk.DeviceIDFilesystemLabel, disk.DeviceIDPartitionUUID} {
+		customizations.DeviceID = string(mode)
+		pt, err := testBasicImageType.getPartitionTable(customizations, distro.ImageOptions{}, rng)
+		require.NoError(t, err)
+		require.Equal(t, "gpt", pt.Type)
+
+		root := pt.RootPartition()
+		fstabRoot := ""
+		for _, entry := range pt.FSTabStageOptionsV2().FileSystems {
+			if entry.Path == "/" {
+				fstabRoot = entry.UUID + entry.Label + entry.PartUUID
+			}
+		}
+
+		grub, err := grub2StageOptions(&pt, "ro", nil, nil, "", true, "", "redhat", false)
+		if mode == disk.DeviceIDPartitionUUID {
+			assert.EqualError(t, err, "GRUB2 can't identify the root filesystem by partition UUID")
+		} else {
+			require.NoError(t, err)
+		}
+		cmdline := kernelCmdlineStageOptions(&pt, "ro")
+
+		switch mode {
+		case disk.DeviceIDFilesystemUUID:
+			assert.Equal(t, root.Filesystem.UUID, fstabRoot)
+			assert.Equal(t, root.Filesystem.UUID, grub.RootFilesystemUUID.String())
+			assert.Nil(t, grub.RootFilesystem)
+			assert.Equal(t, root.Filesystem.UUID, cmdline.RootFsUUID)
+			assert.Equal(t, "ro", cmdline.KernelOpts)
+		case disk.DeviceIDFilesystemLabel:
+			assert.Equal(t, "root", fstabRoot)
+			assert.Equal(t, &osbuild.GRUB2FSDesc{Label: "root"}, grub.RootFilesystem)
+			assert.Equal(t, "ro", grub.KernelOptions)
+			assert.Empty(t, cmdline.RootFsUUID)
+			assert.Equal(t, "root=LABEL=root ro", cmdline.KernelOpts)
+		case disk.DeviceIDPartitionUUID:
+			assert.Equal(t, root.UUID, fstabRoot)
+			assert.Empty(t, cmdline.RootFsUUID)
+			assert.Equal(t, "root=PARTUUID="+root.UUID+" ro", cmdline.KernelOpts)
+		}
+	}
+
+	customizations.DeviceID = "path"
+	_, err := testBasicImageType.getPartitionTable(customizations, distro.ImageOptions{}, rng)
+	assert.Error(t, err)
+}
+
+func TestFSTabMountOptions(t *testing.T) {
+	customizations := &blueprint.Customizations{
+		Filesystem: []blueprint.FilesystemCustomization{
+			{
+				Mountpoint: "/var/lib/data",
+				MinSize:    1073741824,
+				Options:    []string{"nofail", "x-systemd.automount"},
+			},
+		},
+	}
+	pt, err := testBasicImageType.getPartitionTable(customizations, distro.ImageOptions{}, rng)
+	require.NoError(t, err)
+
+	options := make(map[string]string)
+	for _, entry := range pt.FSTabStageOptionsV2().FileSystems {
+		options[entry.Path] = entry.Options
+	}
+	assert.Equal(t, "nofail,x-systemd.automount", options["/var/lib/data"])
+	assert.Equal(t, "defaults,uid=0,gid=0,umask=077,shortname=winnt", options["/boot/efi"])
+
+	// the EFI system partition is only readable by root if the image type
+	// asks for it
+	espUmaskImageType := testBasicImageType
+	espUmaskImageType.espUmask = true
+	pt, err = espUmaskImageType.getPartitionTable(customizations, distro.ImageOptions{}, rng)
+	require.NoError(t, err)
+	assert.Equal(t, "defaults,uid=0,gid=0,umask=0077,shortname=winnt", pt.FilesystemByMountpoint("/boot/efi").FSTabOptions)
+}
+
+func TestGrub2StageOptionsConfig(t *testing.T) {
+	pt, err := testBasicImageType.getPartitionTable(nil, distro.ImageOptions{}, rng)
+	require.NoError(t, err)
+	kernel := &blueprint.KernelCustomization{Name: "kernel", Append: "debug"}
+
+	// without the customization, the options are the same as before
+	withoutGrub, err := grub2StageOptions(&pt, "ro", kernel, nil, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Nil(t, withoutGrub.Config)
+	emptyGrub, err := grub2StageOptions(&pt, "ro", kernel, &blueprint.GrubCustomization{}, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Equal(t, withoutGrub, emptyGrub)
+
+	timeout := 5
+	grub := &blueprint.GrubCustomization{
+		Default:      "1",
+		Timeout:      &timeout,
+		TimeoutStyle: "countdown",
+	}
+	withGrub, err := grub2StageOptions(&pt, "ro", kernel, grub, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.GRUB2Config{
+		Default:      "1",
+		Timeout:      &timeout,
+		TimeoutStyle: osbuild.GRUB2TimeoutStyleCountdown,
+	}, withGrub.Config)
+
+	// the kernel options are not affected
+	assert.Equal(t, "ro debug", withGrub.KernelOptions)
+	assert.Equal(t, withoutGrub.SavedEntry, withGrub.SavedEntry)
+	withGrub.Config = nil
+	assert.Equal(t, withoutGrub, withGrub)
+
+	// the serial terminal is set up on the first port by default
+	grub = &blueprint.GrubCustomization{Terminal: []string{"serial"}}
+	withSerial, err := grub2StageOptions(&pt, "ro", kernel, grub, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.GRUB2Config{
+		Terminal: []string{"serial"},
+		Serial:   "serial --speed=115200 --unit=0 --word=8 --parity=no --stop=1",
+	}, withSerial.Config)
+	grub.Serial = &blueprint.GrubSerialCustomization{Unit: 1, Speed: 9600}
+	withSerial, err = grub2StageOptions(&pt, "ro", kernel, grub, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Equal(t, "serial --speed=9600 --unit=1 --word=8 --parity=no --stop=1", withSerial.Config.Serial)
+
+	// plain text passwords are hashed, hashed ones are kept
+	grub = &blueprint.GrubCustomization{Password: "secret"}
+	withPassword, err := grub2StageOptions(&pt, "ro", kernel, grub, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Nil(t, withPassword.Config)
+	assert.Equal(t, []string{"root"}, withPassword.Superusers)
+	assert.True(t, crypt.PasswordIsGRUB2PBKDF2(withPassword.PasswordHash))
+	grub = &blueprint.GrubCustomization{Password: withPassword.PasswordHash, Superuser: "admin"}
+	withHash, err := grub2StageOptions(&pt, "ro", kernel, grub, "5.14", true, "", "redhat", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, withHash.Superusers)
+	assert.Equal(t, withPassword.PasswordHash, withHash.PasswordHash)
+}
+
+func TestSfdiskGrowStageOptions(t *testing.T) {
+	base, err := testBasicImageType.getPartitionTable(nil, distro.ImageOptions{Size: 4 * 1024 * 1024 * 1024}, rng)
+	require.NoError(t, err)
+	grown, err := base.GrowLastPartition(8 * 1024 * 1024 * 1024)
+	require.NoError(t, err)
+
+	options, err := sfdiskGrowStageOptions(&base, &grown)
+	require.NoError(t, err)
+	assert.Equal(t, base.Type, options.Label)
+	assert.Equal(t, base.UUID, options.UUID)
+	require.Len(t, options.Partitions, len(base.Partitions))
+	root := base.RootPartitionIndex()
+	for idx, p := range options.Partitions {
+		assert.Equal(t, base.Partitions[idx].Start, p.Start)
+		if idx == root {
+			assert.True(t, p.Resize)
+			assert.Equal(t, base.Partitions[idx].Size+4*1024*1024*1024/512, p.Size)
+		} else {
+			assert.True(t, p.Preserve)
+			assert.Equal(t, base.Partitions[idx].Size, p.Size)
+		}
+	}
+
+	// only the last partition can grow
+	other := grown.Clone()
+	other.Partitions[0].Size++
+	_, err = sfdiskGrowStageOptions(&base, &other)
+	assert.EqualError(t, err, "partition 0 is not the last partition and can't grow")
+
+	other = grown.Clone()
+	other.Partitions[root].Start++
+	_, err = sfdiskGrowStageOptions(&base, &other)
+	assert.EqualError(t, err, fmt.Sprintf("partition %d can't be changed in place", root))
+
+	other = grown.Clone()
+	other.UUID = "other"
+	_, err = sfdiskGrowStageOptions(&base, &other)
+	assert.EqualError(t, err, "partition tables of different layouts can't be changed in place")
+}
+
+func TestSfdiskStageOptionsPartitionNames(t *testing.T) {
+	for _, arch := range []string{distro.X86_64ArchName, distro.Aarch64ArchName} {
+		pt := disk.CreatePartitionTable(nil, 0, edgeBasePartitionTables[arch], rng)
+		options := sfdiskStageOptions(&pt)
+		require.Len(t, options.Partitions, len(pt.Partitions), arch)
+		// the filesystem partitions of edge images have partition labels
+		names := make(map[string]string)
+		for idx, p := range options.Partitions {
+			if p.Name != "" {
+				names[pt.Partitions[idx].Filesystem.Mountpoint] = p.Name
+			}
+		}
+		assert.Equal(t, map[string]string{"/boot/efi": "EFI-SYSTEM", "/boot": "boot", "/": "root"}, names, arch)
+	}
+
+	base := disk.PartitionTable{
+		Type: "gpt",
+		Partitions: []disk.Partition{
+			{Start: 2048, Size: 2048, Type: disk.BIOSBootPartitionGUID, Attrs: []uint{2}},
+			{Start: 4096, Size: 4194304, Type: disk.FilesystemDataGUID, Name: "data", Attrs: []uint{55}},
+		},
+	}
+	options := sfdiskStageOptions(&base)
+	assert.Equal(t, []uint{2}, options.Partitions[0].Attrs)
+	assert.Empty(t, options.Partitions[0].Name)
+	assert.Equal(t, []uint{55}, options.Partitions[1].Attrs)
+	assert.Equal(t, "data", options.Partitions[1].Name)
+
+	// the clone of a partition table doesn't share the attributes
+	clone := base.Clone()
+	clone.Partitions[0].Attrs[0] = 0
+	assert.Equal(t, []uint{2}, base.Partitions[0].Attrs)
+}
+
+func TestZiplStageOptions(t *testing.T) {
+	customizations := &blueprint.Customizations{Filesystem: mountpoints}
+	for _, mode := range []disk.DeviceIDMode{disk.DeviceIDFilesystemUUID, disk.DeviceIDFilesystemLabel, disk.DeviceIDPartitionUUID} {
+		customizations.DeviceID = string(mode)
+		pt, err := testBasicImageType.getPartitionTable(customizations, distro.ImageOptions{}, rng)
+		require.NoError(t, err)
+
+		// the parameters are the ones of the kernel-cmdline stage
+		cmdline := kernelCmdlineStageOptions(&pt, "ro console=ttyS0")
+		zipl := ziplStageOptions(&pt, "ro console=ttyS0")
+		switch mode {
+		case disk.DeviceIDFilesystemUUID:
+			assert.Equal(t, "root=UUID="+cmdline.RootFsUUID+" "+cmdline.KernelOpts, zipl.KernelOptions)
+		default:
+			assert.Equal(t, cmdline.KernelOpts, zipl.KernelOptions)
+		}
+		assert.Equal(t, 0, zipl.Timeout)
+	}
+}
+
+func TestOwnershipStages(t *testing.T) {
+	assert.Empty(t, ownershipStages(nil))
+
+	stages := ownershipStages([]pathOwnership{
+		{Path: "/etc/sudoers.d/admins", User: "root", Group: "0", Mode: "0440"},
+		{Path: "/var/lib/app", User: "1001", Group: "app", Recursive: true},
+		{Path: "/etc/cron.d", Mode: "go-w", Recursive: true},
+		{Path: "/etc/app.conf", User: "app", Mode: "0600"},
+		{Path: "/etc/app.conf", Group: "wheel", Mode: "0640"},
+	})
+	require.Len(t, stages, 2)
+
+	assert.Equal(t, "org.osbuild.chown", stages[0].Type)
+	assert.Equal(t, &osbuild.ChownStageOptions{
+		Items: map[string]osbuild.ChownStagePathOptions{
+			"/etc/sudoers.d/admins": {User: osbuild.ChownPrincipalName("root"), Group: osbuild.ChownPrincipalID(0)},
+			"/var/lib/app":          {User: osbuild.ChownPrincipalID(1001), Group: osbuild.ChownPrincipalName("app"), Recursive: true},
+			"/etc/app.conf":         {Group: osbuild.ChownPrincipalName("wheel")},
+		},
+	}, stages[0].Options)
+
+	assert.Equal(t, "org.osbuild.chmod", stages[1].Type)
+	assert.Equal(t, &osbuild.ChmodStageOptions{
+		Items: map[string]osbuild.ChmodStagePathOptions{
+			"/etc/sudoers.d/admins": {Mode: "0440"},
+			"/etc/cron.d":           {Mode: "go-w", Recursive: true},
+			"/etc/app.conf":         {Mode: "0640"},
+		},
+	}, stages[1].Options)
+
+	// stages without items are omitted
+	stages = ownershipStages([]pathOwnership{{Path: "/etc/motd", Mode: "0644"}})
+	require.Len(t, stages, 1)
+	assert.Equal(t, "org.osbuild.chmod", stages[0].Type)
+}
+
+func TestMkdirStageOptions(t *testing.T) {
+	options, err := mkdirStageOptions(
+		osbuild.Path{Path: "/var/lib/app/", Mode: os.FileMode(0750), Parents: true},
+		osbuild.Path{Path: "/srv//data/../cache", ExistOk: true},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.MkdirStageOptions{
+		Paths: []osbuild.Path{
+			{Path: "/var/lib/app", Mode: os.FileMode(0750), Parents: true},
+			{Path: "/srv/cache", ExistOk: true},
+		},
+	}, options)
+
+	_, err = mkdirStageOptions(osbuild.Path{Path: "/srv"}, osbuild.Path{Path: "var/lib"})
+	assert.EqualError(t, err, `directory "var/lib" must be an absolute path`)
+
+	// the EFI system partition directory is created as before
+	efiMkdir, err := efiMkdirStageOptions()
+	require.NoError(t, err)
+	data, err := json.Marshal(efiMkdir)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":[{"path":"/boot/efi","mode":448}]}`, string(data))
+}
+
+func TestDirectoryStages(t *testing.T) {
+	stages, err := directoryStages([]blueprint.DirectoryCustomization{
+		{Path: "/var/log/myapp", Mode: "0750", User: "myapp", Group: "myapp"},
+		{Path: "/srv/data/cache", EnsureParents: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, stages, 3)
+
+	assert.Equal(t, "org.osbuild.mkdir", stages[0].Type)
+	assert.Equal(t, &osbuild.MkdirStageOptions{
+		Paths: []osbuild.Path{
+			{Path: "/var/log/myapp", ExistOk: true},
+			{Path: "/srv/data/cache", Parents: true, ExistOk: true},
+		},
+	}, stages[0].Options)
+
+	assert.Equal(t, "org.osbuild.chown", stages[1].Type)
+	assert.Equal(t, &osbuild.ChownStageOptions{
+		Items: map[string]osbuild.ChownStagePathOptions{
+			"/var/log/myapp": {User: osbuild.ChownPrincipalName("myapp"), Group: osbuild.ChownPrincipalName("myapp")},
+		},
+	}, stages[1].Options)
+
+	// directories without a mode get 0755
+	assert.Equal(t, "org.osbuild.chmod", stages[2].Type)
+	assert.Equal(t, &osbuild.ChmodStageOptions{
+		Items: map[string]osbuild.ChmodStagePathOptions{
+			"/var/log/myapp":  {Mode: "0750"},
+			"/srv/data/cache": {Mode: "0755"},
+		},
+	}, stages[2].Options)
+}
+
+func TestFileStages(t *testing.T) {
+	stages, err := fileStages([]blueprint.FileCustomization{
+		{Path: "/etc/myapp/a.conf", Data: "same"},
+		{Path: "/etc/myapp/b.conf", Data: "same", User: "myapp", Mode: "0600"},
+		{Path: "/var/lib/myapp/empty"},
+	})
+	require.NoError(t, err)
+	require.Len(t, stages, 4)
+
+	// each parent directory is created once
+	assert.Equal(t, "org.osbuild.mkdir", stages[0].Type)
+	assert.Equal(t, &osbuild.MkdirStageOptions{
+		Paths: []osbuild.Path{
+			{Path: "/etc/myapp", Parents: true, ExistOk: true},
+			{Path: "/var/lib/myapp", Parents: true, ExistOk: true},
+		},
+	}, stages[0].Options)
+
+	// files with the same data share an input reference
+	same := osbuild.InlineSourceChecksum([]byte("same"))
+	empty := osbuild.InlineSourceChecksum(nil)
+	assert.Equal(t, "org.osbuild.copy", stages[1].Type)
+	assert.Equal(t, &osbuild.CopyStageOptions{
+		Paths: []osbuild.CopyStagePath{
+			{From: "input://inlinefile/" + same, To: "tree:///etc/myapp/a.conf", RemoveDestination: true},
+			{From: "input://inlinefile/" + same, To: "tree:///etc/myapp/b.conf", RemoveDestination: true},
+			{From: "input://inlinefile/" + empty, To: "tree:///var/lib/myapp/empty", RemoveDestination: true},
+		},
+	}, stages[1].Options)
+	inputs := stages[1].Inputs.(*osbuild.CopyStageInputs)
+	assert.Equal(t, osbuild.CopyStageReferences{same, empty}, (*inputs)["inlinefile"].References)
+
+	assert.Equal(t, "org.osbuild.chown", stages[2].Type)
+	assert.Equal(t, &osbuild.ChownStageOptions{
+		Items: map[string]osbuild.ChownStagePathOptions{
+			"/etc/myapp/b.conf": {User: osbuild.ChownPrincipalName("myapp")},
+		},
+	}, stages[2].Options)
+
+	// files without a mode get 0644
+	assert.Equal(t, "org.osbuild.chmod", stages[3].Type)
+	assert.Equal(t, &osbuild.ChmodStageOptions{
+		Items: map[string]osbuild.ChmodStagePathOptions{
+			"/etc/myapp/a.conf":    {Mode: "0644"},
+			"/etc/myapp/b.conf":    {Mode: "0600"},
+			"/var/lib/myapp/empty": {Mode: "0644"},
+		},
+	}, stages[3].Options)
+}
+
+func TestLiveImagePipelineSectorSize(t *testing.T) {
+	imgType := &imageType{arch: &architecture{name: distro.X86_64ArchName, legacy: "i386-pc"}}
+	base := defaultBasePartitionTables[distro.X86_64ArchName]
+
+	loopbackDevices := func(p *osbuild.Pipeline) []*osbuild.LoopbackDeviceOptions {
+		var devices []*osbuild.LoopbackDeviceOptions
+		for _, stage := range p.Stages {
+			for _, device := range stage.Devices {
+				if options, ok := device.Options.(*osbuild.LoopbackDeviceOptions); ok {
+					devices = append(devices, options)
+				}
+			}
+		}
+		return devices
+	}
+
+	// disks with 512 byte sectors don't set the sector size, all devices
+	// are locked
+	pt := disk.CreatePartitionTable(nil, 0, base, rng)
+	p := liveImagePipeline("os", "disk.img", &pt, imgType, "")
+	devices := loopbackDevices(p)
+	require.NotEmpty(t, devices)
+	for _, device := range devices {
+		assert.Nil(t, device.SectorSize)
+		assert.True(t, device.Lock)
+		assert.False(t, device.Partscan)
+	}
+
+	// all devices of disks with 4K sectors use them
+	base.SectorSize = 4096
+	pt = disk.CreatePartitionTable(nil, 0, base, rng)
+	p = liveImagePipeline("os", "disk.img", &pt, imgType, "")
+	devices = loopbackDevices(p)
+	require.NotEmpty(t, devices)
+	for _, device := range devices {
+		require.NotNil(t, device.SectorSize)
+		assert.Equal(t, uint64(4096), *device.SectorSize)
+	}
+	for _, stage := range p.Stages {
+		if options, ok := stage.Options.(*osbuild.Grub2InstStageOptions); ok {
+			require.NotNil(t, options.SectorSize)
+			assert.Equal(t, uint64(4096), *options.SectorSize)
+		}
+	}
+}
+
+func TestCopyFSTreeOptionsReadOnly(t *testing.T) {
+	pt := disk.CreatePartitionTable(nil, 0, defaultBasePartitionTables[distro.X86_64ArchName], rng)
+	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: "disk.img"})
+
+	_, _, mounts := copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{})
+	for _, mount := range *mounts {
+		assert.Empty(t, mount.Options, mount.Target)
+	}
+
+	_, _, mounts = copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{ReadOnly: []string{"/boot/efi"}})
+	for _, mount := range *mounts {
+		if mount.Target == "/boot/efi" {
+			assert.Equal(t, osbuild.MountOptions{"ro"}, mount.Options)
+		} else {
+			assert.Empty(t, mount.Options, mount.Target)
+		}
+	}
+
+	assert.Panics(t, func() {
+		copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{ReadOnly: []string{"/srv"}})
+	})
+}
+
+func TestCopyFSTreeOptionsBtrfsSubvolumes(t *testing.T) {
+	mountpoints := []blueprint.FilesystemCustomization{
+		{
+			Mountpoint: "/",
+			FSType:     "btrfs",
+			Subvolumes: []blueprint.BtrfsSubvolumeCustomization{
+				{Name: "root", Mountpoint: "/"},
+				{Name: "home", Mountpoint: "/home"},
+			},
+		},
+	}
+	pt := disk.CreatePartitionTable(mountpoints, 0, defaultBasePartitionTables[distro.X86_64ArchName], rng)
+	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: "disk.img"})
+
+	_, devices, mounts := copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{ReadOnly: []string{"/home"}})
+	assert.Contains(t, *devices, "root")
+	assert.Equal(t, osbuild.Mounts{
+		*osbuild.NewBtrfsMount("root", "root", "/", "subvol=root"),
+		*osbuild.NewXfsMount("boot", "boot", "/boot"),
+		*osbuild.NewBtrfsMount("home", "root", "/home", "subvol=home", "ro"),
+		*osbuild.NewFATMount("efi", "efi", "/boot/efi"),
+	}, *mounts)
+}
+
+func TestCopyFSTreeOptionsFSTabOptions(t *testing.T) {
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/data", Options: []string{"noatime"}},
+		{Mountpoint: "/home", Options: []string{"nodev", "nosuid"}},
+	}
+	pt := disk.CreatePartitionTable(mountpoints, 0, defaultBasePartitionTables[distro.X86_64ArchName], rng)
+
+	fstab := make(map[string]string)
+	for _, entry := range pt.FSTabStageOptionsV2().FileSystems {
+		fstab[entry.Path] = entry.Options
+	}
+	assert.Equal(t, "noatime", fstab["/data"])
+	assert.Equal(t, "nodev,nosuid", fstab["/home"])
+	assert.Equal(t, "defaults", fstab["/"])
+
+	// the mounts of the build don't use the mount options of the image
+	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: "disk.img"})
+	_, _, mounts := copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{})
+	for _, mount := range *mounts {
+		assert.Empty(t, mount.Options, mount.Target)
+	}
+}
+
+func TestCopyFSTreeOptionsNameCollisions(t *testing.T) {
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/data/app"},
+		{Mountpoint: "/var/app"},
+		{Mountpoint: "/var"},
+		{Mountpoint: "/data/root"},
+		{Mountpoint: "/x-a-b"},
+		{Mountpoint: "/x/a-b"},
+		{Mountpoint: "/y/a-b"},
+	}
+	pt := disk.CreatePartitionTable(mountpoints, 0, defaultBasePartitionTables[distro.X86_64ArchName], rng)
+	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: "disk.img"})
+
+	_, devices, mounts := copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{})
+	names := make(map[string]string)
+	for _, mount := range *mounts {
+		assert.NotContains(t, names, mount.Name, mount.Target)
+		assert.Contains(t, *devices, mount.Source, mount.Target)
+		names[mount.Name] = mount.Target
+	}
+	assert.Len(t, *devices, len(*mounts))
+	assert.Equal(t, map[string]string{
+		"root":      "/",
+		"efi":       "/boot/efi",
+		"data-app":  "/data/app",
+		"var-app":   "/var/app",
+		"var":       "/var",
+		"data-root": "/data/root",
+		"x-a-b":     "/x-a-b",
+		"x-a-b-1":   "/x/a-b",
+		"y-a-b":     "/y/a-b",
+	}, names)
+}
+
+func TestCopyFSTreeOptionsMountOrder(t *testing.T) {
+	mountpoints := []blueprint.FilesystemCustomization{
+		{Mountpoint: "/var/lib/containers"},
+		{Mountpoint: "/a/b"},
+		{Mountpoint: "/var/lib"},
+		{Mountpoint: "/a!"},
+		{Mountpoint: "/var"},
+	}
+	pt := disk.CreatePartitionTable(mountpoints, 0, defaultBasePartitionTables[distro.X86_64ArchName], rng)
+	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: "disk.img"})
+
+	// parents are mounted before their children, even if they sort after
+	// them, e.g. "/a!" > "/a/b" but / is mounted before /a/b
+	_, _, mounts := copyFSTreeOptions("root-tree", "os", &pt, loopback, copyFSTreeSettings{})
+	var targets []string
+	for _, mount := range *mounts {
+		targets = append(targets, mount.Target)
+	}
+	assert.Equal(t, []string{"/", "/a!", "/var", "/a/b", "/boot/efi", "/var/lib", "/var/lib/containers"}, targets)
+}
+
+func TestKeymapStageOptions(t *testing.T) {
+	cases := []struct {
+		keyboard string
+		expected *osbuild.KeymapStageOptions
+	}{
+		{"us", &osbuild.KeymapStageOptions{Keymap: "us", X11Keymap: &osbuild.X11KeymapOptions{Layouts: []string{"us"}}}},
+		{"de-nodeadkeys", &osbuild.KeymapStageOptions{Keymap: "de-nodeadkeys", X11Keymap: &osbuild.X11KeymapOptions{Layouts: []string{"de"}}}},
+		{"uk", &osbuild.KeymapStageOptions{Keymap: "uk", X11Keymap: &osbuild.X11KeymapOptions{Layouts: []string{"gb"}}}},
+		{"sv-latin1", &osbuild.KeymapStageOptions{Keymap: "sv-latin1", X11Keymap: &osbuild.X11KeymapOptions{Layouts: []string{"se"}}}},
+		{"dvorak", &osbuild.KeymapStageOptions{Keymap: "dvorak"}},
+		{"fr_CH-latin1", &osbuild.KeymapStageOptions{Keymap: "fr_CH-latin1"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, keymapStageOptions(c.keyboard), c.keyboard)
+	}
+}
+
+func TestRPMStageOptions(t *testing.T) {
+	repos := []rpmmd.RepoConfig{{GPGKey: "key"}, {}}
+
+	// without any settings, only the GPG keys are set
+	assert.Equal(t, &osbuild.RPMStageOptions{GPGKeys: []string{"key"}}, rpmStageOptions(repos, nil))
+	assert.Equal(t, &osbuild.RPMStageOptions{GPGKeys: []string{"key"}}, rpmStageOptions(repos, testBasicImageType.rpmCustomization(nil)))
+
+	minimal := imageType{
+		rpmDefaults: blueprint.RPMCustomization{
+			ExcludeDocs:  common.BoolToPtr(true),
+			InstallLangs: []string{"en_US"},
+		},
+	}
+	assert.Equal(t, &osbuild.RPMStageOptions{
+		GPGKeys:      []string{"key"},
+		Exclude:      &osbuild.Exclude{Docs: true},
+		InstallLangs: []string{"en_US"},
+	}, rpmStageOptions(repos, minimal.rpmCustomization(nil)))
+
+	// blueprints override the defaults of the image type
+	customizations := &blueprint.Customizations{
+		RPM: &blueprint.RPMCustomization{
+			ExcludeDocs: common.BoolToPtr(false),
+		},
+	}
+	assert.Equal(t, &osbuild.RPMStageOptions{
+		GPGKeys:      []string{"key"},
+		InstallLangs: []string{"en_US"},
+	}, rpmStageOptions(repos, minimal.rpmCustomization(customizations)))
+
+	// ... without modifying them
+	assert.True(t, *minimal.rpmDefaults.ExcludeDocs)
+}
+
+func TestFirewallStageOptions(t *testing.T) {
+	options, err := firewallStageOptions(&blueprint.FirewallCustomization{
+		Ports: []string{"22/tcp", "8000-8080:udp"},
+		Services: &blueprint.FirewallServicesCustomization{
+			Enabled: []string{"cockpit"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &osbuild.FirewallStageOptions{
+		Ports:           []string{"22:tcp", "8000-8080:udp"},
+		EnabledServices: []string{"cockpit"},
+	}, options)
+
+	_, err = firewallStageOptions(&blueprint.FirewallCustomization{Ports: []string{"22"}})
+	assert.EqualError(t, err, `firewall port "22" has no protocol, must be of the form port:protocol`)
+}
+
+func TestImageType_Compression(t *testing.T) {
+	gzipped := imageType{name: "test", filename: "disk.raw", mimeType: "application/octet-stream", compression: compressionGzip}
+	assert.Equal(t, "disk.raw.gz", gzipped.Filename())
+	assert.Equal(t, "application/gzip", gzipped.MIMEType())
+
+	archive, err := compressionPipeline(&gzipped, "image")
+	require.NoError(t, err)
+	assert.Equal(t, "archive", archive.Name)
+	require.Len(t, archive.Stages, 1)
+	assert.Equal(t, osbuild.NewGzipStage(
+		osbuild.NewGzipStageOptions("disk.raw.gz"),
+		osbuild.NewFilesInputs(osbuild.NewFilesInputReferencesPipeline("image", "disk.raw")),
+	), archive.Stages[0])
+
+	uncompressed := imageType{name: "test", filename: "disk.raw", mimeType: "application/octet-stream"}
+	assert.Equal(t, "disk.raw", uncompressed.Filename())
+	assert.Equal(t, "application/octet-stream", uncompressed.MIMEType())
+	_, err = compressionPipeline(&uncompressed, "image")
+	assert.Error(t, err)
+}
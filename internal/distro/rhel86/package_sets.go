@@ -282,6 +282,85 @@ func qcow2CommonPackageSet(t *imageType) rpmmd.PackageSet {
 	}.Append(bootPackageSet(t)).Append(distroSpecificPackageSet(t))
 }
 
+// minimal raw image package set, without cloud-init and firewalld
+func minimalrawPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"@core", "chrony", "dnf", "dracut-config-generic",
+			"NetworkManager", "openssh-server", "redhat-release",
+			"selinux-policy-targeted",
+		},
+		Exclude: []string{
+			"cloud-init", "dracut-config-rescue", "firewalld",
+			"langpacks-*", "plymouth", "rng-tools",
+		},
+	}.Append(bootPackageSet(t))
+}
+
+// GCE image package set, the guest environment is installed from the
+// Google Compute Engine repositories
+func gcePackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"@core", "langpacks-en", "acpid", "dhcp-client", "dnf-automatic",
+			"net-tools", "openssh-server", "python3", "rng-tools", "tar",
+			"vim",
+
+			// GCP guest environment
+			"gce-disk-expand", "google-compute-engine",
+			"google-osconfig-agent",
+		},
+		Exclude: []string{
+			"alsa-utils", "b43-fwcutter", "dmraid", "eject", "gpm",
+			"irqbalance", "microcode_ctl", "smartmontools", "aic94xx-firmware",
+			"atmel-firmware", "b43-openfwwf", "bfa-firmware",
+			"ipw2100-firmware", "ipw2200-firmware", "ivtv-firmware",
+			"iwl100-firmware", "iwl1000-firmware", "iwl3945-firmware",
+			"iwl4965-firmware", "iwl5000-firmware", "iwl5150-firmware",
+			"iwl6000-firmware", "iwl6000g2a-firmware", "iwl6050-firmware",
+			"kernel-firmware", "libertas-usb8388-firmware",
+			"ql2100-firmware", "ql2200-firmware", "ql23xx-firmware",
+			"ql2400-firmware", "ql2500-firmware", "rt61pci-firmware",
+			"rt73usb-firmware", "xorg-x11-drv-ati-firmware",
+			"zd1211-firmware",
+			// the image does not boot a rescue initramfs
+			"dracut-config-rescue",
+		},
+	}.Append(bootPackageSet(t)).Append(distroSpecificPackageSet(t))
+}
+
+// the rootfs of WSL is started by the Windows kernel, so the image has
+// neither a kernel nor a bootloader
+func wslPackageSet(t *imageType) rpmmd.PackageSet {
+	return rpmmd.PackageSet{
+		Include: []string{
+			"bash", "ca-certificates", "coreutils-single", "dnf",
+			"glibc-minimal-langpack", "hostname", "iproute", "less",
+			"passwd", "procps-ng", "redhat-release", "rootfiles",
+			"shadow-utils", "sudo", "systemd", "tar", "util-linux",
+			"vim-minimal", "yum",
+		},
+		Exclude: []string{
+			"dracut", "dracut-config-rescue", "grub2-tools", "kernel",
+			"kernel-core", "rng-tools",
+		},
+	}
+}
+
+// Hyper-V image package set, the packages of qcow2 images with the Hyper-V
+// guest daemons instead of the QEMU guest agent
+func vhdxPackageSet(t *imageType) rpmmd.PackageSet {
+	ps := qcow2CommonPackageSet(t)
+	include := make([]string, 0, len(ps.Include))
+	for _, pkg := range ps.Include {
+		if pkg != "qemu-guest-agent" {
+			include = append(include, pkg)
+		}
+	}
+	ps.Include = append(include, "hyperv-daemons")
+	return ps
+}
+
 func vhdCommonPackageSet(t *imageType) rpmmd.PackageSet {
 	return rpmmd.PackageSet{
 		Include: []string{
@@ -80,7 +80,7 @@ var defaultBasePartitionTables = distro.BasePartitionTableMap{
 		Partitions: []disk.Partition{
 			{
 				Size:     8192,
-				Type:     "41",
+				Type:     disk.PRePBootPartitionDOSID,
 				Bootable: true,
 			},
 			{
@@ -182,6 +182,51 @@ var ec2BasePartitionTables = distro.BasePartitionTableMap{
 	},
 }
 
+// minimalrawPartitionTable is the layout of minimal-raw images, which only
+// boot with UEFI on all architectures
+var minimalrawPartitionTable = disk.PartitionTable{
+	UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+	Type: "gpt",
+	Partitions: []disk.Partition{
+		{
+			Size: 409600, // 200 MB
+			Type: disk.EFISystemPartitionGUID,
+			UUID: disk.EFISystemPartitionUUID,
+			Filesystem: &disk.Filesystem{
+				Type:         "vfat",
+				UUID:         disk.EFIFilesystemUUID,
+				Mountpoint:   "/boot/efi",
+				FSTabOptions: "defaults,uid=0,gid=0,umask=077,shortname=winnt",
+				FSTabFreq:    0,
+				FSTabPassNo:  2,
+			},
+		},
+		{
+			Type: disk.FilesystemDataGUID,
+			UUID: disk.RootPartitionUUID,
+			Filesystem: &disk.Filesystem{
+				Type:         "xfs",
+				Label:        "root",
+				Mountpoint:   "/",
+				FSTabOptions: "defaults",
+				FSTabFreq:    0,
+				FSTabPassNo:  0,
+			},
+		},
+	},
+}
+
+var minimalrawBasePartitionTables = distro.BasePartitionTableMap{
+	distro.X86_64ArchName:  minimalrawPartitionTable,
+	distro.Aarch64ArchName: minimalrawPartitionTable,
+}
+
+// Hyper-V generation 2 virtual machines only boot with UEFI, so the images
+// for them have the layout of minimal-raw images
+var vhdGen2BasePartitionTables = distro.BasePartitionTableMap{
+	distro.X86_64ArchName: minimalrawPartitionTable,
+}
+
 var edgeBasePartitionTables = distro.BasePartitionTableMap{
 	distro.X86_64ArchName: disk.PartitionTable{
 		UUID: "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
@@ -197,6 +242,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 				Size: 260096, // 127 MB
 				Type: disk.EFISystemPartitionGUID,
 				UUID: disk.EFISystemPartitionUUID,
+				Name: "EFI-SYSTEM",
 				Filesystem: &disk.Filesystem{
 					Type:         "vfat",
 					UUID:         disk.EFIFilesystemUUID,
@@ -211,6 +257,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 				Size: 786432, // 384 MB
 				Type: disk.FilesystemDataGUID,
 				UUID: disk.FilesystemDataUUID,
+				Name: "boot",
 				Filesystem: &disk.Filesystem{
 					Type:         "xfs",
 					Mountpoint:   "/boot",
@@ -223,6 +270,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 			{
 				Type: disk.FilesystemDataGUID,
 				UUID: disk.RootPartitionUUID,
+				Name: "root",
 				Filesystem: &disk.Filesystem{
 					Type:         "xfs",
 					Label:        "root",
@@ -242,6 +290,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 				Size: 260096, // 127 MB
 				Type: disk.EFISystemPartitionGUID,
 				UUID: disk.EFISystemPartitionUUID,
+				Name: "EFI-SYSTEM",
 				Filesystem: &disk.Filesystem{
 					Type:         "vfat",
 					UUID:         disk.EFIFilesystemUUID,
@@ -256,6 +305,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 				Size: 786432, // 384 MB
 				Type: disk.FilesystemDataGUID,
 				UUID: disk.FilesystemDataUUID,
+				Name: "boot",
 				Filesystem: &disk.Filesystem{
 					Type:         "xfs",
 					Mountpoint:   "/boot",
@@ -268,6 +318,7 @@ var edgeBasePartitionTables = distro.BasePartitionTableMap{
 			{
 				Type: disk.FilesystemDataGUID,
 				UUID: disk.RootPartitionUUID,
+				Name: "root",
 				Filesystem: &disk.Filesystem{
 					Type:         "xfs",
 					Label:        "root",
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
@@ -20,17 +21,17 @@ func qcow2Pipelines(t *imageType, customizations *blueprint.Customizations, opti
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 
-	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable)
+	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable, customizations.GetKernel(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS())
 
 	if options.Subscription == nil {
 		// RHSM DNF plugins should be by default disabled on RHEL Guest KVM images
@@ -46,29 +47,68 @@ func qcow2Pipelines(t *imageType, customizations *blueprint.Customizations, opti
 		}))
 	}
 
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	if se := customizations.GetSecureExecution(); se != nil {
+		// the zipl stages of osbuild can only install the kernel and
+		// initrd of a boot loader entry, so the image boots the plain
+		// kernel until zipl installs the Secure Execution image in the
+		// guest
+		treePipeline.AddStage(genprotimgStage(kernelVer, se))
+	}
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
 	diskfile := "disk.img"
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "0.10")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", qcow2Options(t, options))
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 
 	return pipelines, nil
 }
 
-func prependKernelCmdlineStage(pipeline *osbuild.Pipeline, t *imageType, pt *disk.PartitionTable) *osbuild.Pipeline {
+// addFSTabStages adds the stages that write the fstab and, if pt has
+// encrypted partitions, the crypttab. If swap is a swap file, the stage that
+// creates it is added and the fstab activates it.
+func addFSTabStages(pipeline *osbuild.Pipeline, pt *disk.PartitionTable, swap *blueprint.SwapCustomization) {
+	fstabOptions := pt.FSTabStageOptionsV2()
+	if swapfileOptions, swapfileEntry := swapFileStageOptions(swap); swapfileOptions != nil {
+		pipeline.AddStage(osbuild.NewSwapfileStage(swapfileOptions))
+		fstabOptions.FileSystems = append(fstabOptions.FileSystems, swapfileEntry)
+	}
+	pipeline.AddStage(osbuild.NewFSTabStage(fstabOptions))
+	if crypttabOptions := crypttabStageOptions(pt); crypttabOptions != nil {
+		pipeline.AddStage(osbuild.NewCrypttabStage(crypttabOptions))
+	}
+}
+
+// addFinalTreeStages adds the stages that must be the last ones of an OS
+// tree: the OpenSCAP remediation, which must see the configuration all other
+// stages wrote, like the fstab, the kernel command line and the bootloader,
+// and the SELinux stage, which also labels the files the remediation changed
+func addFinalTreeStages(pipeline *osbuild.Pipeline, t *imageType, c *blueprint.Customizations) {
+	if openscap := c.GetOpenSCAP(); openscap != nil {
+		pipeline.AddStage(osbuild.NewOscapRemediationStage(oscapRemediationStageOptions(openscap, t.arch.distro.oscapDataStream())))
+	}
+	pipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false, c)))
+}
+
+func prependKernelCmdlineStage(pipeline *osbuild.Pipeline, t *imageType, pt *disk.PartitionTable, kernel *blueprint.KernelCustomization, selinux *blueprint.SELinuxCustomization, kdump *blueprint.KdumpCustomization, fips bool) *osbuild.Pipeline {
 	if t.arch.name == distro.S390xArchName {
-		rootPartition := pt.RootPartition()
-		if rootPartition == nil {
-			panic("s390x image must have a root partition, this is a programming error")
-		}
-		kernelStage := osbuild.NewKernelCmdlineStage(kernelCmdlineStageOptions(rootPartition.Filesystem.UUID, t.kernelOptions))
+		kernelStage := osbuild.NewKernelCmdlineStage(kernelCmdlineStageOptions(pt, imageKernelOptions(t, pt, kernel, selinux, kdump, fips)))
 		pipeline.Stages = append([]*osbuild.Stage{kernelStage}, pipeline.Stages...)
 	}
 	return pipeline
@@ -77,92 +117,308 @@ func prependKernelCmdlineStage(pipeline *osbuild.Pipeline, t *imageType, pt *dis
 func vhdPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
 	diskfile := "disk.img"
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", "")
+	// the virtual size of the fixed VHD is the size of the image, which
+	// Size() aligns to 1 MiB, instead of being rounded to the CHS geometry
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", osbuild.VPCOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pipelines = append(pipelines, *qemuPipeline)
+	return pipelines, nil
+}
+
+func vhdxPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	pipelines := make([]osbuild.Pipeline, 0)
+	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable, customizations.GetKernel(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS())
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
+	pipelines = append(pipelines, *treePipeline)
+
+	diskfile := "disk.img"
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
+	pipelines = append(pipelines, *imagePipeline)
+
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vhdx", osbuild.VHDXOptions{Subformat: osbuild.VHDXSubformatDynamic})
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
 
 func vmdkPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	// the worker converts the image to a stream optimized one
+	return vmdkCommonPipelines(t, customizations, options, repos, packageSetSpecs, rng, t.filename, osbuild.VMDKOptions{})
+}
+
+// ovaPipelines returns pipelines which produce an OVA archive of a stream
+// optimized VMDK and its OVF descriptor and manifest
+func ovaPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	vmdkFilename := "image.vmdk"
+	// OVA appliances only support stream optimized disks
+	pipelines, err := vmdkCommonPipelines(t, customizations, options, repos, packageSetSpecs, rng, vmdkFilename, osbuild.VMDKOptions{Subformat: osbuild.VMDKSubformatStreamOptimized})
+	if err != nil {
+		return nil, err
+	}
+
+	vmdkPipeline := pipelines[len(pipelines)-1]
+	ovfPipeline := ovfPipeline(vmdkPipeline.Name, vmdkFilename)
+	pipelines = append(pipelines, *ovfPipeline)
+
+	// the descriptor must be the first member of the archive, followed by
+	// the manifest
+	base := strings.TrimSuffix(vmdkFilename, ".vmdk")
+	archivePipeline := osbuild.Pipeline{
+		Name:  "archive",
+		Build: "name:build",
+	}
+	archivePipeline.AddStage(osbuild.NewTarStage(
+		&osbuild.TarStageOptions{
+			Filename: t.Filename(),
+			Format:   osbuild.TarArchiveFormatUstar,
+			Paths:    []string{base + ".ovf", base + ".mf", vmdkFilename},
+		},
+		tarStageInputs(ovfPipeline.Name),
+	))
+	pipelines = append(pipelines, archivePipeline)
+	return pipelines, nil
+}
+
+// vmdkCommonPipelines returns the pipelines of a VMDK with the file name
+// vmdkFilename and the vmdkOptions
+func vmdkCommonPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand, vmdkFilename string, vmdkOptions osbuild.VMDKOptions) ([]osbuild.Pipeline, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
 	diskfile := "disk.img"
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vmdk", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, vmdkFilename, "vmdk", vmdkOptions)
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
 
+// minimalrawPipelines returns pipelines which produce an XZ-compressed raw
+// disk image that boots with UEFI
+func minimalrawPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	pipelines := make([]osbuild.Pipeline, 0)
+	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable, customizations.GetKernel(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS())
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
+	pipelines = append(pipelines, *treePipeline)
+
+	imagePipeline := liveImagePipeline(treePipeline.Name, t.filename, &partitionTable, t, kernelVer)
+	pipelines = append(pipelines, *imagePipeline)
+	return pipelines, nil
+}
+
+func gcePipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	pipelines := make([]osbuild.Pipeline, 0)
+	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable, customizations.GetKernel(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS())
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
+	pipelines = append(pipelines, *treePipeline)
+
+	// Compute Engine requires the disk to be the only member of the archive
+	// and to be called disk.raw
+	diskfile := "disk.raw"
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
+	pipelines = append(pipelines, *imagePipeline)
+
+	archivePipeline := osbuild.Pipeline{
+		Name:  "archive",
+		Build: "name:build",
+	}
+	archivePipeline.AddStage(osbuild.NewTarStage(
+		&osbuild.TarStageOptions{
+			Filename: t.Filename(),
+			Format:   osbuild.TarArchiveFormatOldGNU,
+			Paths:    []string{diskfile},
+		},
+		tarStageInputs(imagePipeline.Name),
+	))
+	pipelines = append(pipelines, archivePipeline)
+	return pipelines, nil
+}
+
+func ovfPipeline(inputPipelineName, vmdkFilename string) *osbuild.Pipeline {
+	p := new(osbuild.Pipeline)
+	p.Name = "ovf"
+	p.Build = "name:build"
+
+	p.AddStage(osbuild.NewCopyStageSimple(
+		&osbuild.CopyStageOptions{
+			Paths: []osbuild.CopyStagePath{
+				{
+					From: "input://file/" + vmdkFilename,
+					To:   "tree:///" + vmdkFilename,
+				},
+			},
+		},
+		osbuild.NewFilesInputs(osbuild.NewFilesInputReferencesPipeline(inputPipelineName, vmdkFilename)),
+	))
+	p.AddStage(osbuild.NewOVFStage(&osbuild.OVFStageOptions{
+		VMDK: vmdkFilename,
+	}))
+	return p
+}
+
 func openstackPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
 
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
 	diskfile := "disk.img"
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", qcow2Options(t, options))
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -174,10 +430,11 @@ func openstackPipelines(t *imageType, customizations *blueprint.Customizations,
 //
 // The argument `withRHUI` should be set to `true` only if the image package set includes RHUI client packages.
 //
-// Note: the caller of this function has to append the `osbuild.NewSELinuxStage(selinuxStageOptions(false))` stage
-// as the last one to the returned pipeline. The stage is not appended on purpose, to allow caller to append
+// Note: the caller of this function has to add the stages of `addFinalTreeStages()`
+// as the last ones to the returned pipeline. The stages are not appended on purpose, to allow caller to append
 // any additional stages to the pipeline, but before the SELinuxStage, which must be always the last one.
 func ec2BaseTreePipeline(
+	t *imageType,
 	repos []rpmmd.RepoConfig,
 	packages []rpmmd.PackageSpec,
 	bpPackages []rpmmd.PackageSpec,
@@ -192,7 +449,7 @@ func ec2BaseTreePipeline(
 	p.Name = "os"
 	p.Build = "name:build"
 	packages = append(packages, bpPackages...)
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, t.rpmCustomization(c)), rpmStageInputs(packages)))
 
 	// If the /boot is on a separate partition, the prefix for the BLS stage must be ""
 	if pt.BootPartition() == nil {
@@ -208,14 +465,9 @@ func ec2BaseTreePipeline(
 		p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US.UTF-8"}))
 	}
 	if keyboard != nil {
-		p.AddStage(osbuild.NewKeymapStage(&osbuild.KeymapStageOptions{Keymap: *keyboard}))
+		p.AddStage(osbuild.NewKeymapStage(keymapStageOptions(*keyboard)))
 	} else {
-		p.AddStage(osbuild.NewKeymapStage(&osbuild.KeymapStageOptions{
-			Keymap: "us",
-			X11Keymap: &osbuild.X11KeymapOptions{
-				Layouts: []string{"us"},
-			},
-		}))
+		p.AddStage(osbuild.NewKeymapStage(keymapStageOptions("us")))
 	}
 
 	if hostname := c.GetHostname(); hostname != nil {
@@ -229,9 +481,14 @@ func ec2BaseTreePipeline(
 		p.AddStage(osbuild.NewTimezoneStage(&osbuild.TimezoneStageOptions{Zone: "UTC"}))
 	}
 
+	leapsecTz, commentPools := c.GetChronySettings()
 	if len(ntpServers) > 0 {
-		p.AddStage(osbuild.NewChronyStage(&osbuild.ChronyStageOptions{Timeservers: ntpServers}))
+		p.AddStage(osbuild.NewChronyStage(chronyStageOptions(ntpServers, leapsecTz, commentPools, t.ptpClock)))
 	} else {
+		if leapsecTz == nil {
+			// empty string will remove any occurrences of the option from the configuration
+			leapsecTz = common.StringToPtr("")
+		}
 		p.AddStage(osbuild.NewChronyStage(&osbuild.ChronyStageOptions{
 			Servers: []osbuild.ChronyConfigServer{
 				{
@@ -242,8 +499,7 @@ func ec2BaseTreePipeline(
 					Maxpoll:  common.IntToPtr(4),
 				},
 			},
-			// empty string will remove any occurrences of the option from the configuration
-			LeapsecTz: common.StringToPtr(""),
+			LeapsecTz: leapsecTz,
 		}))
 	}
 
@@ -252,19 +508,48 @@ func ec2BaseTreePipeline(
 	}
 
 	if users := c.GetUsers(); len(users) > 0 {
-		userOptions, err := userStageOptions(users)
+		userOptions, err := userStageOptions(users, t.passwordHash(c))
 		if err != nil {
 			return nil, err
 		}
 		p.AddStage(osbuild.NewUsersStage(userOptions))
 	}
 
+	enabledServices, disabledServices = kdumpServices(c.GetKdump(), enabledServices, disabledServices)
 	if services := c.GetServices(); services != nil || enabledServices != nil || disabledServices != nil || defaultTarget != "" {
 		p.AddStage(osbuild.NewSystemdStage(systemdStageOptions(enabledServices, disabledServices, services, defaultTarget)))
 	}
 
 	if firewall := c.GetFirewall(); firewall != nil {
-		p.AddStage(osbuild.NewFirewallStage(firewallStageOptions(firewall)))
+		options, err := firewallStageOptions(firewall)
+		if err != nil {
+			return nil, err
+		}
+		p.AddStage(osbuild.NewFirewallStage(options))
+	}
+
+	if sshd := c.GetSSHD(); sshd != nil {
+		p.AddStage(osbuild.NewSshdConfigStage(sshdConfigStageOptions(sshd)))
+	}
+
+	if dirs := c.GetDirectories(); len(dirs) > 0 {
+		stages, err := directoryStages(dirs)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
+	if files := customizationFiles(c); len(files) > 0 {
+		stages, err := fileStages(files)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
 	}
 
 	p.AddStage(osbuild.NewSystemdLogindStage(&osbuild.SystemdLogindStageOptions{
@@ -301,6 +586,10 @@ func ec2BaseTreePipeline(
 		},
 	}))
 
+	if selinuxOptions := selinuxConfigStageOptions(c.GetSELinux()); selinuxOptions != nil {
+		p.AddStage(osbuild.NewSELinuxConfigStage(selinuxOptions))
+	}
+
 	p.AddStage(osbuild.NewCloudInitStage(&osbuild.CloudInitStageOptions{
 		Filename: "00-rhel-default-user.cfg",
 		Config: osbuild.CloudInitConfigFile{
@@ -337,9 +626,56 @@ func ec2BaseTreePipeline(
 		},
 	}))
 
-	p.AddStage(osbuild.NewAuthselectStage(&osbuild.AuthselectStageOptions{
+	for _, stage := range kernelModulesBlacklistStages(c.GetKernel()) {
+		p.AddStage(stage)
+	}
+
+	if sysctlOptions := sysctlStageOptions(c.GetSysctl()); sysctlOptions != nil {
+		p.AddStage(osbuild.NewSysctldStage(sysctlOptions))
+	}
+
+	for _, rules := range c.GetUdevRules() {
+		p.AddStage(osbuild.NewUdevRulesStage(udevRulesStageOptions(rules)))
+	}
+
+	if tuned := c.GetTuned(); tuned != nil {
+		p.AddStage(osbuild.NewTunedStage(osbuild.NewTunedStageOptions(tuned.Profile)))
+	}
+
+	authselectOptions := &osbuild.AuthselectStageOptions{
 		Profile: "sssd",
-	}))
+	}
+	if authselect := c.GetAuthselect(); authselect != nil {
+		authselectOptions = authselectStageOptions(authselect)
+	}
+	p.AddStage(osbuild.NewAuthselectStage(authselectOptions))
+
+	// the certificates are copied with the files of the customizations
+	if len(c.GetCACerts()) > 0 {
+		p.AddStage(osbuild.NewUpdateCATrustStage())
+	}
+
+	if dnfOptions := dnfConfigStageOptions(c.GetDNF(), c.GetProxy()); dnfOptions != nil {
+		p.AddStage(osbuild.NewDNFConfigStage(dnfOptions))
+	}
+
+	// the repositories of the customizations are only used to build the
+	// image unless they are installed to it
+	for _, repo := range c.GetRepositories() {
+		if repo.InstallToImage {
+			p.AddStage(osbuild.NewYumReposStage(yumReposStageOptions(repo)))
+		}
+	}
+
+	if proxy := c.GetProxy(); proxy != nil {
+		for _, stage := range proxyStages(proxy, t.arch.distro.isRHEL()) {
+			p.AddStage(stage)
+		}
+	}
+
+	if len(options.Containers) > 0 {
+		p.AddStage(skopeoStage(options.Containers, ""))
+	}
 
 	if isRHEL {
 		if options.Subscription != nil {
@@ -381,14 +717,28 @@ func ec2BaseTreePipeline(
 		}
 	}
 
+	if c.GetFIPS() {
+		kernelVer, err := defaultKernelVer(bpPackages, c)
+		if err != nil {
+			return nil, err
+		}
+		stages, err := fipsStages(kernelVer)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
 	return p, nil
 }
 
-func ec2X86_64BaseTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec,
+func ec2X86_64BaseTreePipeline(t *imageType, repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec,
 	c *blueprint.Customizations, options distro.ImageOptions, enabledServices, disabledServices []string,
 	defaultTarget string, withRHUI, isRHEL bool, pt *disk.PartitionTable) (*osbuild.Pipeline, error) {
 
-	treePipeline, err := ec2BaseTreePipeline(repos, packages, bpPackages, c, options, enabledServices, disabledServices, defaultTarget, withRHUI, isRHEL, pt)
+	treePipeline, err := ec2BaseTreePipeline(t, repos, packages, bpPackages, c, options, enabledServices, disabledServices, defaultTarget, withRHUI, isRHEL, pt)
 	if err != nil {
 		return nil, err
 	}
@@ -415,7 +765,7 @@ func ec2CommonPipelines(t *imageType, customizations *blueprint.Customizations,
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -424,10 +774,10 @@ func ec2CommonPipelines(t *imageType, customizations *blueprint.Customizations,
 	switch arch := t.arch.Name(); arch {
 	// rhel-ec2-x86_64, rhel-ha-ec2
 	case distro.X86_64ArchName:
-		treePipeline, err = ec2X86_64BaseTreePipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
+		treePipeline, err = ec2X86_64BaseTreePipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
 	// rhel-ec2-aarch64
 	case distro.Aarch64ArchName:
-		treePipeline, err = ec2BaseTreePipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
+		treePipeline, err = ec2BaseTreePipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
 	default:
 		return nil, fmt.Errorf("ec2CommonPipelines: unsupported image architecture: %q", arch)
 	}
@@ -435,14 +785,21 @@ func ec2CommonPipelines(t *imageType, customizations *blueprint.Customizations,
 		return nil, err
 	}
 
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
 	// The last stage must be the SELinux stage
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 	return pipelines, nil
 }
@@ -453,7 +810,7 @@ func ec2SapPipelines(t *imageType, customizations *blueprint.Customizations, opt
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	partitionTable, err := t.getPartitionTable(customizations.GetFilesystems(), options, rng)
+	partitionTable, err := t.getPartitionTable(customizations, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -462,7 +819,7 @@ func ec2SapPipelines(t *imageType, customizations *blueprint.Customizations, opt
 	switch arch := t.arch.Name(); arch {
 	// rhel-sap-ec2
 	case distro.X86_64ArchName:
-		treePipeline, err = ec2X86_64BaseTreePipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
+		treePipeline, err = ec2X86_64BaseTreePipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget, withRHUI, t.arch.distro.isRHEL(), &partitionTable)
 	default:
 		return nil, fmt.Errorf("ec2SapPipelines: unsupported image architecture: %q", arch)
 	}
@@ -574,15 +931,22 @@ func ec2SapPipelines(t *imageType, customizations *blueprint.Customizations, opt
 		},
 	)))
 
-	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable)
-	treePipeline.AddStage(osbuild.NewFSTabStage(partitionTable.FSTabStageOptionsV2()))
-	kernelVer := kernelVerStr(packageSetSpecs[blueprintPkgsKey], customizations.GetKernel().Name, t.Arch().Name())
-	treePipeline.AddStage(bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), kernelVer, false, false))
+	treePipeline = prependKernelCmdlineStage(treePipeline, t, &partitionTable, customizations.GetKernel(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS())
+	addFSTabStages(treePipeline, &partitionTable, customizations.GetSwap())
+	kernelVer, err := defaultKernelVer(packageSetSpecs[blueprintPkgsKey], customizations)
+	if err != nil {
+		return nil, err
+	}
+	bootloader, err := bootloaderConfigStage(t, partitionTable, customizations.GetKernel(), customizations.GetGrub(), customizations.GetSELinux(), customizations.GetKdump(), customizations.GetFIPS(), kernelVer, false, false)
+	if err != nil {
+		return nil, err
+	}
+	treePipeline.AddStage(bootloader)
 	// The last stage must be the SELinux stage
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
-	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
+	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 	return pipelines, nil
 }
@@ -626,11 +990,11 @@ func tarPipelines(t *imageType, customizations *blueprint.Customizations, option
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 	tarPipeline := osbuild.Pipeline{
 		Name:  "root-tar",
@@ -641,6 +1005,29 @@ func tarPipelines(t *imageType, customizations *blueprint.Customizations, option
 	return pipelines, nil
 }
 
+func wslPipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
+	pipelines := make([]osbuild.Pipeline, 0)
+	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
+
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	if err != nil {
+		return nil, err
+	}
+	// WSL does not enforce SELinux, so the tree is not labelled
+	treePipeline.AddStage(osbuild.NewWSLConfStage(wslConfStageOptions(customizations.GetUsers())))
+	pipelines = append(pipelines, *treePipeline)
+
+	// the tar stage compresses the archive according to the suffix of the
+	// file name
+	tarPipeline := osbuild.Pipeline{
+		Name:  "root-tar",
+		Build: "name:build",
+	}
+	tarPipeline.AddStage(tarStage("os", t.Filename()))
+	pipelines = append(pipelines, tarPipeline)
+	return pipelines, nil
+}
+
 //makeISORootPath return a path that can be used to address files and folders in
 //the root of the iso
 func makeISORootPath(p string) string {
@@ -658,10 +1045,20 @@ func edgeInstallerPipelines(t *imageType, customizations *blueprint.Customizatio
 	ostreeRepoPath := "/ostree/repo"
 	payloadStages := ostreePayloadStages(options, ostreeRepoPath)
 	kickstartOptions := ostreeKickstartStageOptions(makeISORootPath(ostreeRepoPath), options.OSTree.Ref)
-	pipelines = append(pipelines, *anacondaTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, "edge"))
-	isolabel := fmt.Sprintf(d.isolabelTmpl, archName)
-	pipelines = append(pipelines, *bootISOTreePipeline(kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel, kickstartOptions, payloadStages))
-	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), d.isolabelTmpl, archName, false))
+	var kickstartInputs *osbuild.KickstartStageInputs
+	kickstartOptions.Post, kickstartInputs = kickstartPostOptions(customizations.GetInstallerPostScripts())
+	setKickstartLocalization(kickstartOptions, customizations)
+	users, groups, err := userKickstartStageOptions(customizations.GetUsers(), customizations.GetGroups(), t.passwordHash(customizations))
+	if err != nil {
+		return nil, err
+	}
+	kickstartOptions.Users = users
+	kickstartOptions.Groups = groups
+	anacondaOptions := anacondaStageOptions(t.kickstartModules, customizations.GetInstallerModules())
+	pipelines = append(pipelines, *anacondaTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, t.installerVariant(), d.isFinal, anacondaOptions, customizations.GetDracut(), nil))
+	isolabel := t.isoLabel(customizations)
+	pipelines = append(pipelines, *bootISOTreePipeline(kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel, t.composeID(options), false, customizations.GetInstallerMediaCheck(), nil, t.installerRootFS(customizations), kickstartOptions, kickstartInputs, payloadStages))
+	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), isolabel, false))
 	return pipelines, nil
 }
 
@@ -669,11 +1066,11 @@ func tarInstallerPipelines(t *imageType, customizations *blueprint.Customization
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	treePipeline, err := osPipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := osPipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
-	treePipeline.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	addFinalTreeStages(treePipeline, t, customizations)
 	pipelines = append(pipelines, *treePipeline)
 
 	kernelPkg := new(rpmmd.PackageSpec)
@@ -692,12 +1089,21 @@ func tarInstallerPipelines(t *imageType, customizations *blueprint.Customization
 	tarPath := "/liveimg.tar"
 	tarPayloadStages := []*osbuild.Stage{tarStage("os", tarPath)}
 	kickstartOptions := tarKickstartStageOptions(makeISORootPath(tarPath))
+	var kickstartInputs *osbuild.KickstartStageInputs
+	kickstartOptions.Post, kickstartInputs = kickstartPostOptions(customizations.GetInstallerPostScripts())
+	setKickstartLocalization(kickstartOptions, customizations)
 	archName := t.arch.name
 	d := t.arch.distro
-	pipelines = append(pipelines, *anacondaTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, "BaseOS"))
-	isolabel := fmt.Sprintf(d.isolabelTmpl, archName)
-	pipelines = append(pipelines, *bootISOTreePipeline(kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel, kickstartOptions, tarPayloadStages))
-	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), d.isolabelTmpl, t.Arch().Name(), true))
+	anacondaOptions := anacondaStageOptions(t.kickstartModules, customizations.GetInstallerModules())
+	pipelines = append(pipelines, *anacondaTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, t.installerVariant(), d.isFinal, anacondaOptions, customizations.GetDracut(), kernelModulesBlacklist(customizations.GetKernel())))
+	isolabel := t.isoLabel(customizations)
+	// the boot menu of the installer waits as long as the one of the image
+	var isoTimeout *int
+	if grub := customizations.GetGrub(); grub != nil {
+		isoTimeout = grub.Timeout
+	}
+	pipelines = append(pipelines, *bootISOTreePipeline(kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel, t.composeID(options), customizations.GetFIPS(), customizations.GetInstallerMediaCheck(), isoTimeout, t.installerRootFS(customizations), kickstartOptions, kickstartInputs, tarPayloadStages))
+	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), isolabel, true))
 	return pipelines, nil
 }
 
@@ -705,7 +1111,7 @@ func edgeCorePipelines(t *imageType, customizations *blueprint.Customizations, o
 	pipelines := make([]osbuild.Pipeline, 0)
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 
-	treePipeline, err := ostreeTreePipeline(repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
+	treePipeline, err := ostreeTreePipeline(t, repos, packageSetSpecs[osPkgsKey], packageSetSpecs[blueprintPkgsKey], customizations, options, t.enabledServices, t.disabledServices, t.defaultTarget)
 	if err != nil {
 		return nil, err
 	}
@@ -738,34 +1144,74 @@ func edgeContainerPipelines(t *imageType, customizations *blueprint.Customizatio
 
 	nginxConfigPath := "/etc/nginx.conf"
 	httpPort := "8080"
-	pipelines = append(pipelines, *containerTreePipeline(repos, packageSetSpecs[containerPkgsKey], options, customizations, nginxConfigPath, httpPort))
+	htmlRoot := "/usr/share/nginx/html"
+	if ec := customizations.GetEdgeContainer(); ec != nil {
+		if ec.Port != 0 {
+			httpPort = strconv.Itoa(ec.Port)
+		}
+		if ec.Root != "" {
+			htmlRoot = ec.Root
+		}
+	}
+	pipelines = append(pipelines, *containerTreePipeline(repos, packageSetSpecs[containerPkgsKey], options, customizations, nginxConfigPath, htmlRoot, httpPort))
 	pipelines = append(pipelines, *containerPipeline(t, nginxConfigPath, httpPort))
 	return pipelines, nil
 }
 
-func edgeImagePipelines(t *imageType, filename string, options distro.ImageOptions, rng *rand.Rand) ([]osbuild.Pipeline, string, error) {
+// edgeImagePipelines returns the pipelines of the raw image imgName with the
+// deployed commit, and the name of the pipeline the image is created in
+func edgeImagePipelines(t *imageType, ignition *blueprint.IgnitionCustomization, kdump *blueprint.KdumpCustomization, remote *blueprint.OSTreeRemoteCustomization, imgName string, options distro.ImageOptions, rng *rand.Rand) ([]osbuild.Pipeline, string, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	ostreeRepoPath := "/ostree/repo"
-	imgName := "image.raw"
 
-	partitionTable, err := t.getPartitionTable(nil, options, rng)
+	// the image is created with the default size of the image type and
+	// grown to the requested size after the commit is deployed into it
+	baseOptions := options
+	if options.Size > t.defaultSize {
+		baseOptions.Size = t.defaultSize
+	}
+	partitionTable, err := t.getPartitionTable(nil, baseOptions, rng)
 	if err != nil {
 		return nil, "", err
 	}
+	// the filesystems are identified by the names of their partitions,
+	// which don't change when the image is written to a disk
+	if err := partitionTable.SetDeviceIDMode(disk.DeviceIDPartitionLabel); err != nil {
+		return nil, "", err
+	}
+	var grownPartitionTable *disk.PartitionTable
+	if options.Size > partitionTable.Size {
+		if err := growFilesystemOnBoot(&partitionTable, imgName); err != nil {
+			return nil, "", err
+		}
+		grown, err := partitionTable.GrowLastPartition(options.Size)
+		if err != nil {
+			return nil, "", err
+		}
+		grownPartitionTable = &grown
+	}
 
 	// prepare ostree deployment tree
-	treePipeline := ostreeDeployPipeline(t, &partitionTable, ostreeRepoPath, nil, "", rng, options)
+	treePipeline, err := ostreeDeployPipeline(t, &partitionTable, ostreeRepoPath, nil, "", ignition, kdump, remote, rng, options)
+	if err != nil {
+		return nil, "", err
+	}
 	pipelines = append(pipelines, *treePipeline)
 
 	// make raw image from tree
-	imagePipeline := liveImagePipeline(treePipeline.Name, imgName, &partitionTable, t.arch, "")
+	imagePipeline := liveImagePipeline(treePipeline.Name, imgName, &partitionTable, t, "")
+	if grownPartitionTable != nil {
+		growStages, err := growImageStages(imgName, &partitionTable, grownPartitionTable)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, stage := range growStages {
+			imagePipeline.AddStage(stage)
+		}
+	}
 	pipelines = append(pipelines, *imagePipeline)
 
-	// compress image
-	xzPipeline := xzArchivePipeline(imagePipeline.Name, imgName, filename)
-	pipelines = append(pipelines, *xzPipeline)
-
-	return pipelines, xzPipeline.Name, nil
+	return pipelines, imagePipeline.Name, nil
 }
 
 func edgeRawImagePipelines(t *imageType, customizations *blueprint.Customizations, options distro.ImageOptions, repos []rpmmd.RepoConfig, packageSetSpecs map[string][]rpmmd.PackageSpec, rng *rand.Rand) ([]osbuild.Pipeline, error) {
@@ -775,7 +1221,7 @@ func edgeRawImagePipelines(t *imageType, customizations *blueprint.Customization
 	imgName := t.filename
 
 	// create the raw image
-	imagePipelines, _, err := edgeImagePipelines(t, imgName, options, rng)
+	imagePipelines, _, err := edgeImagePipelines(t, customizations.GetIgnition(), customizations.GetKdump(), customizations.GetOSTree().GetRemote(), imgName, options, rng)
 	if err != nil {
 		return nil, err
 	}
@@ -789,17 +1235,17 @@ func buildPipeline(repos []rpmmd.RepoConfig, buildPackageSpecs []rpmmd.PackageSp
 	p := new(osbuild.Pipeline)
 	p.Name = "build"
 	p.Runner = runner
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(buildPackageSpecs)))
-	p.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(true)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, nil), rpmStageInputs(buildPackageSpecs)))
+	p.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(true, nil)))
 	return p
 }
 
-func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec, c *blueprint.Customizations, options distro.ImageOptions, enabledServices, disabledServices []string, defaultTarget string) (*osbuild.Pipeline, error) {
+func osPipeline(t *imageType, repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec, c *blueprint.Customizations, options distro.ImageOptions, enabledServices, disabledServices []string, defaultTarget string) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = "os"
 	p.Build = "name:build"
 	packages = append(packages, bpPackages...)
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, t.rpmCustomization(c)), rpmStageInputs(packages)))
 	p.AddStage(osbuild.NewFixBLSStage(&osbuild.FixBLSStageOptions{}))
 	language, keyboard := c.GetPrimaryLocale()
 	if language != nil {
@@ -808,7 +1254,7 @@ func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackag
 		p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US.UTF-8"}))
 	}
 	if keyboard != nil {
-		p.AddStage(osbuild.NewKeymapStage(&osbuild.KeymapStageOptions{Keymap: *keyboard}))
+		p.AddStage(osbuild.NewKeymapStage(keymapStageOptions(*keyboard)))
 	}
 	if hostname := c.GetHostname(); hostname != nil {
 		p.AddStage(osbuild.NewHostnameStage(&osbuild.HostnameStageOptions{Hostname: *hostname}))
@@ -821,8 +1267,9 @@ func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackag
 		p.AddStage(osbuild.NewTimezoneStage(&osbuild.TimezoneStageOptions{Zone: "America/New_York"}))
 	}
 
-	if len(ntpServers) > 0 {
-		p.AddStage(osbuild.NewChronyStage(&osbuild.ChronyStageOptions{Timeservers: ntpServers}))
+	leapsecTz, commentPools := c.GetChronySettings()
+	if chronyOptions := chronyStageOptions(ntpServers, leapsecTz, commentPools, t.ptpClock); chronyOptions != nil {
+		p.AddStage(osbuild.NewChronyStage(chronyOptions))
 	}
 
 	if groups := c.GetGroups(); len(groups) > 0 {
@@ -830,19 +1277,48 @@ func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackag
 	}
 
 	if users := c.GetUsers(); len(users) > 0 {
-		userOptions, err := userStageOptions(users)
+		userOptions, err := userStageOptions(users, t.passwordHash(c))
 		if err != nil {
 			return nil, err
 		}
 		p.AddStage(osbuild.NewUsersStage(userOptions))
 	}
 
+	enabledServices, disabledServices = kdumpServices(c.GetKdump(), enabledServices, disabledServices)
 	if services := c.GetServices(); services != nil || enabledServices != nil || disabledServices != nil || defaultTarget != "" {
 		p.AddStage(osbuild.NewSystemdStage(systemdStageOptions(enabledServices, disabledServices, services, defaultTarget)))
 	}
 
 	if firewall := c.GetFirewall(); firewall != nil {
-		p.AddStage(osbuild.NewFirewallStage(firewallStageOptions(firewall)))
+		options, err := firewallStageOptions(firewall)
+		if err != nil {
+			return nil, err
+		}
+		p.AddStage(osbuild.NewFirewallStage(options))
+	}
+
+	if sshd := c.GetSSHD(); sshd != nil {
+		p.AddStage(osbuild.NewSshdConfigStage(sshdConfigStageOptions(sshd)))
+	}
+
+	if dirs := c.GetDirectories(); len(dirs) > 0 {
+		stages, err := directoryStages(dirs)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
+	if files := customizationFiles(c); len(files) > 0 {
+		stages, err := fileStages(files)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
 	}
 
 	// These are the current defaults for the sysconfig stage. This can be changed to be image type exclusive if different configs are needed.
@@ -857,6 +1333,57 @@ func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackag
 		},
 	}))
 
+	if selinuxOptions := selinuxConfigStageOptions(c.GetSELinux()); selinuxOptions != nil {
+		p.AddStage(osbuild.NewSELinuxConfigStage(selinuxOptions))
+	}
+
+	for _, stage := range kernelModulesBlacklistStages(c.GetKernel()) {
+		p.AddStage(stage)
+	}
+
+	if sysctlOptions := sysctlStageOptions(c.GetSysctl()); sysctlOptions != nil {
+		p.AddStage(osbuild.NewSysctldStage(sysctlOptions))
+	}
+
+	for _, rules := range c.GetUdevRules() {
+		p.AddStage(osbuild.NewUdevRulesStage(udevRulesStageOptions(rules)))
+	}
+
+	if tuned := c.GetTuned(); tuned != nil {
+		p.AddStage(osbuild.NewTunedStage(osbuild.NewTunedStageOptions(tuned.Profile)))
+	}
+
+	if authselect := c.GetAuthselect(); authselect != nil {
+		p.AddStage(osbuild.NewAuthselectStage(authselectStageOptions(authselect)))
+	}
+
+	// the certificates are copied with the files of the customizations
+	if len(c.GetCACerts()) > 0 {
+		p.AddStage(osbuild.NewUpdateCATrustStage())
+	}
+
+	if dnfOptions := dnfConfigStageOptions(c.GetDNF(), c.GetProxy()); dnfOptions != nil {
+		p.AddStage(osbuild.NewDNFConfigStage(dnfOptions))
+	}
+
+	// the repositories of the customizations are only used to build the
+	// image unless they are installed to it
+	for _, repo := range c.GetRepositories() {
+		if repo.InstallToImage {
+			p.AddStage(osbuild.NewYumReposStage(yumReposStageOptions(repo)))
+		}
+	}
+
+	if proxy := c.GetProxy(); proxy != nil {
+		for _, stage := range proxyStages(proxy, t.arch.distro.isRHEL()) {
+			p.AddStage(stage)
+		}
+	}
+
+	if len(options.Containers) > 0 {
+		p.AddStage(skopeoStage(options.Containers, ""))
+	}
+
 	if options.Subscription != nil {
 		commands := []string{
 			fmt.Sprintf("/usr/sbin/subscription-manager register --org=%s --activationkey=%s --serverurl %s --baseurl %s", options.Subscription.Organization, options.Subscription.ActivationKey, options.Subscription.ServerUrl, options.Subscription.BaseUrl),
@@ -871,10 +1398,25 @@ func osPipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackag
 		},
 		))
 	}
+
+	if c.GetFIPS() {
+		kernelVer, err := defaultKernelVer(bpPackages, c)
+		if err != nil {
+			return nil, err
+		}
+		stages, err := fipsStages(kernelVer)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
 	return p, nil
 }
 
-func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec, c *blueprint.Customizations, options distro.ImageOptions, enabledServices, disabledServices []string, defaultTarget string) (*osbuild.Pipeline, error) {
+func ostreeTreePipeline(t *imageType, repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, bpPackages []rpmmd.PackageSpec, c *blueprint.Customizations, options distro.ImageOptions, enabledServices, disabledServices []string, defaultTarget string) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = "ostree-tree"
 	p.Build = "name:build"
@@ -885,7 +1427,7 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 		p.AddStage(osbuild2.NewOSTreePasswdStage("org.osbuild.source", options.OSTree.Parent))
 	}
 
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, t.rpmCustomization(c)), rpmStageInputs(packages)))
 	p.AddStage(osbuild.NewFixBLSStage(&osbuild.FixBLSStageOptions{}))
 	language, keyboard := c.GetPrimaryLocale()
 	if language != nil {
@@ -894,7 +1436,7 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 		p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US.UTF-8"}))
 	}
 	if keyboard != nil {
-		p.AddStage(osbuild.NewKeymapStage(&osbuild.KeymapStageOptions{Keymap: *keyboard}))
+		p.AddStage(osbuild.NewKeymapStage(keymapStageOptions(*keyboard)))
 	}
 	if hostname := c.GetHostname(); hostname != nil {
 		p.AddStage(osbuild.NewHostnameStage(&osbuild.HostnameStageOptions{Hostname: *hostname}))
@@ -907,8 +1449,9 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 		p.AddStage(osbuild.NewTimezoneStage(&osbuild.TimezoneStageOptions{Zone: "America/New_York"}))
 	}
 
-	if len(ntpServers) > 0 {
-		p.AddStage(osbuild.NewChronyStage(&osbuild.ChronyStageOptions{Timeservers: ntpServers}))
+	leapsecTz, commentPools := c.GetChronySettings()
+	if chronyOptions := chronyStageOptions(ntpServers, leapsecTz, commentPools, t.ptpClock); chronyOptions != nil {
+		p.AddStage(osbuild.NewChronyStage(chronyOptions))
 	}
 
 	if groups := c.GetGroups(); len(groups) > 0 {
@@ -916,20 +1459,57 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 	}
 
 	if users := c.GetUsers(); len(users) > 0 {
-		userOptions, err := userStageOptions(users)
+		userOptions, err := userStageOptions(users, t.passwordHash(c))
 		if err != nil {
 			return nil, err
 		}
 		p.AddStage(osbuild.NewUsersStage(userOptions))
-		p.AddStage(osbuild.NewFirstBootStage(usersFirstBootOptions(userOptions)))
+		if keys := authorizedKeysFiles(users); len(keys) > 0 {
+			stages, err := fileStages(keys)
+			if err != nil {
+				return nil, err
+			}
+			for _, stage := range stages {
+				p.AddStage(stage)
+			}
+		}
 	}
 
+	enabledServices, disabledServices = kdumpServices(c.GetKdump(), enabledServices, disabledServices)
 	if services := c.GetServices(); services != nil || enabledServices != nil || disabledServices != nil || defaultTarget != "" {
 		p.AddStage(osbuild.NewSystemdStage(systemdStageOptions(enabledServices, disabledServices, services, defaultTarget)))
 	}
 
 	if firewall := c.GetFirewall(); firewall != nil {
-		p.AddStage(osbuild.NewFirewallStage(firewallStageOptions(firewall)))
+		options, err := firewallStageOptions(firewall)
+		if err != nil {
+			return nil, err
+		}
+		p.AddStage(osbuild.NewFirewallStage(options))
+	}
+
+	if sshd := c.GetSSHD(); sshd != nil {
+		p.AddStage(osbuild.NewSshdConfigStage(sshdConfigStageOptions(sshd)))
+	}
+
+	if dirs := c.GetDirectories(); len(dirs) > 0 {
+		stages, err := directoryStages(dirs)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
+	if files := customizationFiles(c); len(files) > 0 {
+		stages, err := fileStages(files)
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
 	}
 
 	// These are the current defaults for the sysconfig stage. This can be changed to be image type exclusive if different configs are needed.
@@ -944,6 +1524,76 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 		},
 	}))
 
+	if selinuxOptions := selinuxConfigStageOptions(c.GetSELinux()); selinuxOptions != nil {
+		p.AddStage(osbuild.NewSELinuxConfigStage(selinuxOptions))
+	}
+
+	for _, stage := range kernelModulesBlacklistStages(c.GetKernel()) {
+		p.AddStage(stage)
+	}
+
+	if sysctlOptions := sysctlStageOptions(c.GetSysctl()); sysctlOptions != nil {
+		p.AddStage(osbuild.NewSysctldStage(sysctlOptions))
+	}
+
+	for _, rules := range c.GetUdevRules() {
+		p.AddStage(osbuild.NewUdevRulesStage(udevRulesStageOptions(rules)))
+	}
+
+	if tuned := c.GetTuned(); tuned != nil {
+		p.AddStage(osbuild.NewTunedStage(osbuild.NewTunedStageOptions(tuned.Profile)))
+	}
+
+	if authselect := c.GetAuthselect(); authselect != nil {
+		p.AddStage(osbuild.NewAuthselectStage(authselectStageOptions(authselect)))
+	}
+
+	// the certificates are copied with the files of the customizations
+	if len(c.GetCACerts()) > 0 {
+		p.AddStage(osbuild.NewUpdateCATrustStage())
+	}
+
+	if dnfOptions := dnfConfigStageOptions(c.GetDNF(), c.GetProxy()); dnfOptions != nil {
+		p.AddStage(osbuild.NewDNFConfigStage(dnfOptions))
+	}
+
+	// the repositories of the customizations are only used to build the
+	// image unless they are installed to it
+	for _, repo := range c.GetRepositories() {
+		if repo.InstallToImage {
+			p.AddStage(osbuild.NewYumReposStage(yumReposStageOptions(repo)))
+		}
+	}
+
+	if proxy := c.GetProxy(); proxy != nil {
+		for _, stage := range proxyStages(proxy, t.arch.distro.isRHEL()) {
+			p.AddStage(stage)
+		}
+	}
+
+	// the commits carry the configuration of the remote instead of a
+	// repository, the deployment uses it to upgrade
+	if remote := c.GetOSTree().GetRemote(); remote != nil {
+		stages, err := fileStages(ostreeRemoteFiles(remote))
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
+	if len(options.Containers) > 0 {
+		p.AddStage(skopeoStage(options.Containers, ostreeContainerStoragePath))
+		stages, err := fileStages([]blueprint.FileCustomization{ostreeContainerStorageConfFile})
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+	}
+
 	if options.Subscription != nil {
 		commands := []string{
 			fmt.Sprintf("/usr/sbin/subscription-manager register --org=%s --activationkey=%s --serverurl %s --baseurl %s", options.Subscription.Organization, options.Subscription.ActivationKey, options.Subscription.ServerUrl, options.Subscription.BaseUrl),
@@ -959,7 +1609,7 @@ func ostreeTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec,
 		))
 	}
 
-	p.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false)))
+	p.AddStage(osbuild.NewSELinuxStage(selinuxStageOptions(false, c)))
 	p.AddStage(osbuild.NewOSTreePrepTreeStage(&osbuild.OSTreePrepTreeStageOptions{
 		EtcGroupMembers: []string{
 			// NOTE: We may want to make this configurable.
@@ -973,6 +1623,13 @@ func ostreeCommitPipeline(options distro.ImageOptions, osVersion string) *osbuil
 	p.Name = "ostree-commit"
 	p.Build = "name:build"
 	p.AddStage(osbuild.NewOSTreeInitStage(&osbuild.OSTreeInitStageOptions{Path: "/repo"}))
+	// the static delta is generated from the objects of the parent commit
+	if options.OSTree.StaticDeltas {
+		p.AddStage(osbuild.NewOSTreePullStage(
+			&osbuild.OSTreePullStageOptions{Repo: "/repo"},
+			ostreePullStageInputs("org.osbuild.source", options.OSTree.Parent, options.OSTree.Ref),
+		))
+	}
 
 	commitStageInput := new(osbuild.OSTreeCommitStageInput)
 	commitStageInput.Type = "org.osbuild.tree"
@@ -987,22 +1644,29 @@ func ostreeCommitPipeline(options distro.ImageOptions, osVersion string) *osbuil
 		},
 		&osbuild.OSTreeCommitStageInputs{Tree: commitStageInput}),
 	)
+	if options.OSTree.StaticDeltas {
+		p.AddStage(osbuild.NewOSTreeStaticDeltaStage(ostreeStaticDeltaStageOptions("/repo", options.OSTree)))
+	}
 	return p
 }
 
 func tarStage(source, filename string) *osbuild.Stage {
+	return osbuild.NewTarStage(&osbuild.TarStageOptions{Filename: filename}, tarStageInputs(source))
+}
+
+func tarStageInputs(source string) *osbuild.TarStageInputs {
 	tree := new(osbuild.TarStageInput)
 	tree.Type = "org.osbuild.tree"
 	tree.Origin = "org.osbuild.pipeline"
 	tree.References = []string{"name:" + source}
-	return osbuild.NewTarStage(&osbuild.TarStageOptions{Filename: filename}, &osbuild.TarStageInputs{Tree: tree})
+	return &osbuild.TarStageInputs{Tree: tree}
 }
 
-func containerTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, options distro.ImageOptions, c *blueprint.Customizations, nginxConfigPath, listenPort string) *osbuild.Pipeline {
+func containerTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, options distro.ImageOptions, c *blueprint.Customizations, nginxConfigPath, htmlRoot, listenPort string) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "container-tree"
 	p.Build = "name:build"
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, nil), rpmStageInputs(packages)))
 	language, _ := c.GetPrimaryLocale()
 	if language != nil {
 		p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: *language}))
@@ -1010,14 +1674,25 @@ func containerTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpe
 		p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US"}))
 	}
 
-	htmlRoot := "/usr/share/nginx/html"
 	repoPath := filepath.Join(htmlRoot, "repo")
 	p.AddStage(osbuild.NewOSTreeInitStage(&osbuild.OSTreeInitStageOptions{Path: repoPath}))
 
+	// pulls don't transfer deltas, so the delta is generated again in the
+	// served repo, which needs the objects of the parent commit for it. The
+	// parent is pulled first, so the ref ends up pointing to the new commit.
+	if options.OSTree.StaticDeltas {
+		p.AddStage(osbuild.NewOSTreePullStage(
+			&osbuild.OSTreePullStageOptions{Repo: repoPath},
+			ostreePullStageInputs("org.osbuild.source", options.OSTree.Parent, options.OSTree.Ref),
+		))
+	}
 	p.AddStage(osbuild.NewOSTreePullStage(
 		&osbuild.OSTreePullStageOptions{Repo: repoPath},
 		ostreePullStageInputs("org.osbuild.pipeline", "name:ostree-commit", options.OSTree.Ref),
 	))
+	if options.OSTree.StaticDeltas {
+		p.AddStage(osbuild.NewOSTreeStaticDeltaStage(ostreeStaticDeltaStageOptions(repoPath, options.OSTree)))
+	}
 
 	// make nginx log directory world writeable, otherwise nginx can't start in
 	// an unprivileged container
@@ -1066,27 +1741,36 @@ func edgeSimplifiedInstallerPipelines(t *imageType, customizations *blueprint.Cu
 	pipelines = append(pipelines, *buildPipeline(repos, packageSetSpecs[buildPkgsKey], t.arch.distro.runner))
 	installerPackages := packageSetSpecs[installerPkgsKey]
 	kernelVer := kernelVerStr(installerPackages, "kernel", t.Arch().Name())
-	imgName := "disk.img.xz"
-	installDevice := customizations.GetInstallationDevice()
+	imgName := "image.raw"
+	installDevices := customizations.GetInstallationDevices()
 
 	// create the raw image
-	imagePipelines, imgPipelineName, err := edgeImagePipelines(t, imgName, options, rng)
+	imagePipelines, imgPipelineName, err := edgeImagePipelines(t, customizations.GetIgnition(), customizations.GetKdump(), customizations.GetOSTree().GetRemote(), imgName, options, rng)
 	if err != nil {
 		return nil, err
 	}
 
 	pipelines = append(pipelines, imagePipelines...)
 
+	// compress image
+	xzPipeline := xzArchivePipeline(imgPipelineName, imgName, "disk.img.xz")
+	pipelines = append(pipelines, *xzPipeline)
+
 	// create boot ISO with raw image
 	d := t.arch.distro
 	archName := t.arch.name
-	installerTreePipeline := simplifiedInstallerTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, "edge")
-	isolabel := fmt.Sprintf(d.isolabelTmpl, archName)
-	efibootTreePipeline := simplifiedInstallerEFIBootTreePipeline(installDevice, kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel)
-	bootISOTreePipeline := simplifiedInstallerBootISOTreePipeline(imgPipelineName, kernelVer)
+	fdo := customizations.GetFDO()
+	installerTreePipeline, err := simplifiedInstallerTreePipeline(repos, installerPackages, kernelVer, archName, d.product, d.osVersion, t.installerVariant(), d.isFinal, fdo, customizations.GetDracut())
+	if err != nil {
+		return nil, err
+	}
+	isolabel := t.isoLabel(customizations)
+	kernelOpts := simplifiedInstallerKernelOptions(installDevices, isolabel, customizations.GetIgnition().FirstBootURL(), fdo)
+	efibootTreePipeline := simplifiedInstallerEFIBootTreePipeline(kernelOpts, kernelVer, archName, d.vendor, d.product, d.osVersion, isolabel)
+	bootISOTreePipeline := simplifiedInstallerBootISOTreePipeline(xzPipeline.Name, kernelVer)
 
 	pipelines = append(pipelines, *installerTreePipeline, *efibootTreePipeline, *bootISOTreePipeline)
-	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), d.isolabelTmpl, t.Arch().Name(), false))
+	pipelines = append(pipelines, *bootISOPipeline(t.Filename(), isolabel, false))
 
 	return pipelines, nil
 }
@@ -1137,7 +1821,7 @@ func simplifiedInstallerBootISOTreePipeline(archivePipelineName, kver string) *o
 	}
 
 	filename := "images/efiboot.img"
-	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: filename})
+	loopback := osbuild.NewLoopbackDevice(loopbackDeviceOptions(filename, &pt))
 	p.AddStage(osbuild.NewTruncateStage(&osbuild.TruncateStageOptions{Filename: filename, Size: fmt.Sprintf("%d", pt.Size)}))
 
 	for _, stage := range mkfsStages(&pt, loopback) {
@@ -1146,7 +1830,12 @@ func simplifiedInstallerBootISOTreePipeline(archivePipelineName, kver string) *o
 
 	inputName := "root-tree"
 	copyInputs := copyPipelineTreeInputs(inputName, "efiboot-tree")
-	copyOptions, copyDevices, copyMounts := copyFSTreeOptions(inputName, "efiboot-tree", &pt, loopback)
+	// vfat can't store ownership, SELinux labels, or other extended
+	// attributes
+	efibootCopy := copyFSTreeSettings{
+		Preserve: []osbuild.CopyAttribute{osbuild.CopyAttributeTimestamps},
+	}
+	copyOptions, copyDevices, copyMounts := copyFSTreeOptions(inputName, "efiboot-tree", &pt, loopback, efibootCopy)
 	p.AddStage(osbuild.NewCopyStage(copyOptions, copyInputs, copyDevices, copyMounts))
 
 	inputName = "coi"
@@ -1184,25 +1873,37 @@ func simplifiedInstallerBootISOTreePipeline(archivePipelineName, kver string) *o
 	return p
 }
 
-func simplifiedInstallerEFIBootTreePipeline(installDevice, kernelVer, arch, vendor, product, osVersion, isolabel string) *osbuild.Pipeline {
+func simplifiedInstallerEFIBootTreePipeline(kernelOpts []string, kernelVer, arch, vendor, product, osVersion, isolabel string) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "efiboot-tree"
 	p.Build = "name:build"
-	p.AddStage(osbuild.NewGrubISOStage(grubISOStageOptions(installDevice, kernelVer, arch, vendor, product, osVersion, isolabel)))
+	p.AddStage(osbuild.NewGrubISOStage(grubISOStageOptions(kernelOpts, kernelVer, arch, vendor, product, osVersion, isolabel)))
 	return p
 }
 
-func simplifiedInstallerTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, kernelVer, arch, product, osVersion, variant string) *osbuild.Pipeline {
+func simplifiedInstallerTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, kernelVer, arch, product, osVersion, variant string, isFinal bool, fdo *blueprint.FDOCustomization, dracut *blueprint.DracutCustomization) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = "coi-tree"
 	p.Build = "name:build"
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
-	p.AddStage(osbuild.NewBuildstampStage(buildStampStageOptions(arch, product, osVersion, variant)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, nil), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewBuildstampStage(buildStampStageOptions(arch, product, osVersion, variant, isFinal)))
 	p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US.UTF-8"}))
 	p.AddStage(osbuild.NewSystemdStage(systemdStageOptions([]string{"coreos-installer"}, nil, nil, "")))
-	p.AddStage(osbuild.NewDracutStage(dracutStageOptions(kernelVer, arch, []string{"rdcore"})))
 
-	return p
+	dracutOptions := dracutStageOptions(kernelVer, arch, []string{"rdcore"}, dracut, nil)
+	if rootCerts := fdoRootCertsFile(fdo); rootCerts != nil {
+		stages, err := fileStages([]blueprint.FileCustomization{*rootCerts})
+		if err != nil {
+			return nil, err
+		}
+		for _, stage := range stages {
+			p.AddStage(stage)
+		}
+		dracutOptions.Install = appendUnique(dracutOptions.Install, rootCerts.Path)
+	}
+	p.AddStage(osbuild.NewDracutStage(dracutOptions))
+
+	return p, nil
 }
 
 func ostreeDeployPipeline(
@@ -1211,18 +1912,28 @@ func ostreeDeployPipeline(
 	repoPath string,
 	kernel *blueprint.KernelCustomization,
 	kernelVer string,
+	ignition *blueprint.IgnitionCustomization,
+	kdump *blueprint.KdumpCustomization,
+	remote *blueprint.OSTreeRemoteCustomization,
 	rng *rand.Rand,
 	options distro.ImageOptions,
-) *osbuild.Pipeline {
+) (*osbuild.Pipeline, error) {
 
 	p := new(osbuild.Pipeline)
 	p.Name = "image-tree"
 	p.Build = "name:build"
 	osname := "redhat"
 
+	// the commit is pulled and deployed as a ref of the remote, so that
+	// rpm-ostree upgrades the deployment from it
+	var remoteName string
+	if remote != nil {
+		remoteName = remote.Name
+	}
+
 	p.AddStage(osbuild.OSTreeInitFsStage())
 	p.AddStage(osbuild.NewOSTreePullStage(
-		&osbuild.OSTreePullStageOptions{Repo: repoPath},
+		&osbuild.OSTreePullStageOptions{Repo: repoPath, Remote: remoteName},
 		ostreePullStageInputs("org.osbuild.source", options.OSTree.Parent, options.OSTree.Ref),
 	))
 	p.AddStage(osbuild.NewOSTreeOsInitStage(
@@ -1231,19 +1942,21 @@ func ostreeDeployPipeline(
 		},
 	))
 	p.AddStage(osbuild.NewOSTreeConfigStage(ostreeConfigStageOptions(repoPath, true)))
-	p.AddStage(osbuild.NewMkdirStage(efiMkdirStageOptions()))
+	if remote != nil {
+		p.AddStage(osbuild.NewOSTreeRemotesStage(ostreeRemotesStageOptions(repoPath, remote, options.OSTree.Ref)))
+	}
+	efiMkdir, err := efiMkdirStageOptions()
+	if err != nil {
+		return nil, err
+	}
+	p.AddStage(osbuild.NewMkdirStage(efiMkdir))
 	p.AddStage(osbuild.NewOSTreeDeployStage(
 		&osbuild.OSTreeDeployStageOptions{
 			OsName: osname,
 			Ref:    options.OSTree.Ref,
-			Mounts: []string{"/boot", "/boot/efi"},
-			Rootfs: osbuild.Rootfs{
-				Label: "root",
-			},
-			KernelOpts: []string{
-				"console=tty0",
-				"console=ttyS0",
-			},
+			Remote: remoteName,
+			Mounts:     []string{"/boot", "/boot/efi"},
+			KernelOpts: append([]string{"root=" + pt.RootDeviceSpec()}, ostreeDeployKernelOptions(ignition, kdump)...),
 		},
 	))
 	p.AddStage(osbuild.NewOSTreeFillvarStage(
@@ -1266,7 +1979,24 @@ func ostreeDeployPipeline(
 
 	// TODO: Add users?
 
-	p.AddStage(bootloaderConfigStage(t, *pt, kernel, kernelVer, true, true))
+	bootloader, err := bootloaderConfigStage(t, *pt, kernel, nil, nil, nil, false, kernelVer, true, true)
+	if err != nil {
+		return nil, err
+	}
+	p.AddStage(bootloader)
+
+	if ignition != nil {
+		p.AddStage(osbuild.NewIgnitionStage(&osbuild.IgnitionStageOptions{}))
+		if config := ignitionConfigFile(ignition); config != nil {
+			stages, err := fileStages([]blueprint.FileCustomization{*config})
+			if err != nil {
+				return nil, err
+			}
+			for _, stage := range stages {
+				p.AddStage(stage)
+			}
+		}
+	}
 
 	p.AddStage(osbuild.NewOSTreeSelinuxStage(
 		&osbuild.OSTreeSelinuxStageOptions{
@@ -1276,15 +2006,15 @@ func ostreeDeployPipeline(
 			},
 		},
 	))
-	return p
+	return p, nil
 }
 
-func anacondaTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, kernelVer, arch, product, osVersion, variant string) *osbuild.Pipeline {
+func anacondaTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec, kernelVer, arch, product, osVersion, variant string, isFinal bool, anacondaOptions *osbuild.AnacondaStageOptions, dracut *blueprint.DracutCustomization, blacklist []string) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "anaconda-tree"
 	p.Build = "name:build"
-	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos), rpmStageInputs(packages)))
-	p.AddStage(osbuild.NewBuildstampStage(buildStampStageOptions(arch, product, osVersion, variant)))
+	p.AddStage(osbuild.NewRPMStage(rpmStageOptions(repos, nil), rpmStageInputs(packages)))
+	p.AddStage(osbuild.NewBuildstampStage(buildStampStageOptions(arch, product, osVersion, variant, isFinal)))
 	p.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: "en_US.UTF-8"}))
 
 	rootPassword := ""
@@ -1312,23 +2042,27 @@ func anacondaTreePipeline(repos []rpmmd.RepoConfig, packages []rpmmd.PackageSpec
 	}
 
 	p.AddStage(osbuild.NewUsersStage(usersStageOptions))
-	p.AddStage(osbuild.NewAnacondaStage(anacondaStageOptions()))
+	p.AddStage(osbuild.NewAnacondaStage(anacondaOptions))
 	p.AddStage(osbuild.NewLoraxScriptStage(loraxScriptStageOptions(arch)))
 	p.AddStage(osbuild.NewDracutStage(dracutStageOptions(kernelVer, arch, []string{
 		"anaconda",
-	})))
+	}, dracut, blacklist)))
 
 	return p
 }
 
-func bootISOTreePipeline(kernelVer, arch, vendor, product, osVersion, isolabel string, ksOptions *osbuild.KickstartStageOptions, payloadStages []*osbuild.Stage) *osbuild.Pipeline {
+func bootISOTreePipeline(kernelVer, arch, vendor, product, osVersion, isolabel, composeID string, fips, mediacheck bool, timeout *int, rootfs osbuild.RootFS, ksOptions *osbuild.KickstartStageOptions, ksInputs *osbuild.KickstartStageInputs, payloadStages []*osbuild.Stage) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "bootiso-tree"
 	p.Build = "name:build"
 
-	p.AddStage(osbuild.NewBootISOMonoStage(bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolabel), bootISOMonoStageInputs()))
-	p.AddStage(osbuild.NewKickstartStage(ksOptions))
-	p.AddStage(osbuild.NewDiscinfoStage(discinfoStageOptions(arch)))
+	p.AddStage(osbuild.NewBootISOMonoStage(bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolabel, fips, mediacheck, timeout, rootfs), bootISOMonoStageInputs()))
+	if ksInputs != nil {
+		p.AddStage(osbuild.NewKickstartStageWithInputs(ksOptions, ksInputs))
+	} else {
+		p.AddStage(osbuild.NewKickstartStage(ksOptions))
+	}
+	p.AddStage(osbuild.NewDiscinfoStage(discinfoStageOptions(arch, composeID)))
 
 	for _, stage := range payloadStages {
 		p.AddStage(stage)
@@ -1336,25 +2070,25 @@ func bootISOTreePipeline(kernelVer, arch, vendor, product, osVersion, isolabel s
 
 	return p
 }
-func bootISOPipeline(filename, isolabel, arch string, isolinux bool) *osbuild.Pipeline {
+func bootISOPipeline(filename, isolabel string, isolinux bool) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "bootiso"
 	p.Build = "name:build"
 
-	p.AddStage(osbuild.NewXorrisofsStage(xorrisofsStageOptions(filename, isolabel, arch, isolinux), xorrisofsStageInputs("bootiso-tree")))
+	p.AddStage(osbuild.NewXorrisofsStage(xorrisofsStageOptions(filename, isolabel, isolinux), xorrisofsStageInputs("bootiso-tree")))
 	p.AddStage(osbuild.NewImplantisomd5Stage(&osbuild.Implantisomd5StageOptions{Filename: filename}))
 
 	return p
 }
 
-func liveImagePipeline(inputPipelineName string, outputFilename string, pt *disk.PartitionTable, arch *architecture, kernelVer string) *osbuild.Pipeline {
+func liveImagePipeline(inputPipelineName string, outputFilename string, pt *disk.PartitionTable, t *imageType, kernelVer string) *osbuild.Pipeline {
 	p := new(osbuild.Pipeline)
 	p.Name = "image"
 	p.Build = "name:build"
 
 	p.AddStage(osbuild.NewTruncateStage(&osbuild.TruncateStageOptions{Filename: outputFilename, Size: fmt.Sprintf("%d", pt.Size)}))
 	sfOptions := sfdiskStageOptions(pt)
-	loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: outputFilename})
+	loopback := osbuild.NewLoopbackDevice(loopbackDeviceOptions(outputFilename, pt))
 	p.AddStage(osbuild.NewSfdiskStage(sfOptions, loopback))
 
 	for _, stage := range mkfsStages(pt, loopback) {
@@ -1362,10 +2096,84 @@ func liveImagePipeline(inputPipelineName string, outputFilename string, pt *disk
 	}
 
 	inputName := "root-tree"
-	copyOptions, copyDevices, copyMounts := copyFSTreeOptions(inputName, inputPipelineName, pt, loopback)
+	copyOptions, copyDevices, copyMounts := copyFSTreeOptions(inputName, inputPipelineName, pt, loopback, copyFSTreeSettings{})
 	copyInputs := copyPipelineTreeInputs(inputName, inputPipelineName)
 	p.AddStage(osbuild.NewCopyStage(copyOptions, copyInputs, copyDevices, copyMounts))
-	p.AddStage(bootloaderInstStage(outputFilename, pt, arch, kernelVer, copyDevices, copyMounts, loopback))
+	p.AddStage(bootloaderInstStage(outputFilename, pt, t, kernelVer, copyDevices, copyMounts, loopback))
+	for _, stage := range lvm2MetadataStages(pt, loopback) {
+		p.AddStage(stage)
+	}
+	return p
+}
+
+// growFilesystemOnBoot marks the filesystem on the last partition of the raw
+// image filename with the partition table pt to be grown to the size of the
+// partition by systemd-growfs when it is first mounted, since osbuild can't
+// grow filesystems in images
+func growFilesystemOnBoot(pt *disk.PartitionTable, filename string) error {
+	if len(pt.Partitions) == 0 {
+		return fmt.Errorf("cannot grow the last partition of %s, it has no partitions", filename)
+	}
+	last := &pt.Partitions[0]
+	for idx := range pt.Partitions {
+		if pt.Partitions[idx].Start > last.Start {
+			last = &pt.Partitions[idx]
+		}
+	}
+	fs := last.Filesystem
+	if fs == nil || last.LUKS != nil {
+		return fmt.Errorf("cannot grow the last partition of %s, it doesn't contain a plain filesystem", filename)
+	}
+	switch fs.Type {
+	case "xfs", "ext4", "btrfs":
+	default:
+		return fmt.Errorf("cannot grow the %s filesystem of the last partition of %s", fs.Type, filename)
+	}
+	if fs.FSTabOptions == "" {
+		fs.FSTabOptions = "x-systemd.growfs"
+	} else {
+		fs.FSTabOptions += ",x-systemd.growfs"
+	}
+	return nil
+}
+
+// growImageStages returns the stages that grow the raw image filename with
+// the partition table base to the size of grown, growing its last partition
+// in place
+func growImageStages(filename string, base, grown *disk.PartitionTable) ([]*osbuild.Stage, error) {
+	sfOptions, err := sfdiskGrowStageOptions(base, grown)
+	if err != nil {
+		return nil, err
+	}
+	return []*osbuild.Stage{
+		osbuild.NewTruncateStage(&osbuild.TruncateStageOptions{Filename: filename, Size: fmt.Sprintf("%d", grown.Size)}),
+		osbuild.NewSfdiskStage(sfOptions, osbuild.NewLoopbackDevice(loopbackDeviceOptions(filename, grown))),
+	}, nil
+}
+
+// compressionPipeline returns the archive pipeline that compresses the file
+// of the image type, created in the pipeline inputPipelineName, into its
+// artifact.
+func compressionPipeline(t *imageType, inputPipelineName string) (*osbuild.Pipeline, error) {
+	switch t.compression {
+	case compressionXz:
+		return xzArchivePipeline(inputPipelineName, t.filename, t.Filename()), nil
+	case compressionGzip:
+		return gzipArchivePipeline(inputPipelineName, t.filename, t.Filename()), nil
+	}
+	return nil, fmt.Errorf("unknown compression of image type %q: %s", t.name, t.compression)
+}
+
+func gzipArchivePipeline(inputPipelineName, inputFilename, outputFilename string) *osbuild.Pipeline {
+	p := new(osbuild.Pipeline)
+	p.Name = "archive"
+	p.Build = "name:build"
+
+	p.AddStage(osbuild.NewGzipStage(
+		osbuild.NewGzipStageOptions(outputFilename),
+		osbuild.NewFilesInputs(osbuild.NewFilesInputReferencesPipeline(inputPipelineName, inputFilename)),
+	))
+
 	return p
 }
 
@@ -1395,88 +2203,242 @@ func mkfsStages(pt *disk.PartitionTable, device *osbuild.Device) []*osbuild2.Sta
 	}
 
 	for _, p := range pt.Partitions {
+		if p.LUKS != nil {
+			loopback := osbuild.NewLoopbackDevice(partitionLoopbackDeviceOptions(devOptions, p))
+			stages = append(stages, luks2Stages(p.LUKS, loopback)...)
+		}
+		if vg := p.VolumeGroup; vg != nil {
+			pvDevice, parents := partitionDevices(devOptions, p, "device")
+			stages = append(stages, addStageDevices(lvm2CreateStage(vg, pvDevice), parents))
+
+			pvDevice, parents = partitionDevices(devOptions, p, vg.Name)
+			for _, lv := range vg.LogicalVolumes {
+				lvDevice := osbuild.NewLVM2LVDevice(vg.Name, &osbuild.LVM2LVDeviceOptions{Volume: lv.Name})
+				for _, stage := range mkfsFilesystemStages(lv.Filesystem, lvDevice) {
+					// the logical volume needs its physical volume
+					stage.Devices[vg.Name] = *pvDevice
+					stages = append(stages, addStageDevices(stage, parents))
+				}
+			}
+			continue
+		}
 		if p.Filesystem == nil {
 			// no filesystem for partition (e.g., BIOS boot)
 			continue
 		}
-		var stage *osbuild.Stage
-		stageDevice := osbuild.NewLoopbackDevice(
-			&osbuild.LoopbackDeviceOptions{
-				Filename: devOptions.Filename,
-				Start:    p.Start,
-				Size:     p.Size,
-			},
-		)
-		switch p.Filesystem.Type {
-		case "xfs":
-			options := &osbuild.MkfsXfsStageOptions{
-				UUID:  p.Filesystem.UUID,
-				Label: p.Filesystem.Label,
-			}
-			stage = osbuild.NewMkfsXfsStage(options, stageDevice)
-		case "vfat":
-			options := &osbuild.MkfsFATStageOptions{
-				VolID: strings.Replace(p.Filesystem.UUID, "-", "", -1),
-			}
-			stage = osbuild.NewMkfsFATStage(options, stageDevice)
-		case "btrfs":
-			options := &osbuild.MkfsBtrfsStageOptions{
-				UUID:  p.Filesystem.UUID,
-				Label: p.Filesystem.Label,
-			}
-			stage = osbuild.NewMkfsBtrfsStage(options, stageDevice)
-		case "ext4":
-			options := &osbuild.MkfsExt4StageOptions{
-				UUID:  p.Filesystem.UUID,
-				Label: p.Filesystem.Label,
-			}
-			stage = osbuild.NewMkfsExt4Stage(options, stageDevice)
-		default:
-			panic("unknown fs type " + p.Type)
+		stageDevice, parents := partitionDevices(devOptions, p, "device")
+		for _, stage := range mkfsFilesystemStages(p.Filesystem, stageDevice) {
+			stages = append(stages, addStageDevices(stage, parents))
+		}
+	}
+	return stages
+}
+
+// addStageDevices adds devices, which the devices of stage are on, to the
+// devices of stage
+func addStageDevices(stage *osbuild.Stage, devices osbuild.Devices) *osbuild.Stage {
+	for name, device := range devices {
+		stage.Devices[name] = device
+	}
+	return stage
+}
+
+// luks2Stages returns the stages that create the LUKS2 container luks on
+// device and bind it to its Clevis policy
+func luks2Stages(luks *disk.LUKSContainer, device *osbuild.Device) []*osbuild.Stage {
+	stages := []*osbuild.Stage{
+		osbuild.NewLUKS2FormatStage(&osbuild.LUKS2FormatStageOptions{
+			Passphrase: luks.Passphrase,
+			UUID:       luks.UUID,
+			Label:      luks.Label,
+		}, device),
+	}
+	if luks.Clevis != nil {
+		stages = append(stages, osbuild.NewClevisLuksBindStage(&osbuild.ClevisLuksBindStageOptions{
+			Passphrase: luks.Passphrase,
+			Pin:        luks.Clevis.Pin,
+			Policy:     luks.Clevis.Policy,
+		}, device))
+	}
+	return stages
+}
+
+// mkfsFilesystemStages returns the stages that create the filesystem fs on
+// device
+func mkfsFilesystemStages(fs *disk.Filesystem, device *osbuild.Device) []*osbuild.Stage {
+	switch fs.Type {
+	case "xfs":
+		options := &osbuild.MkfsXfsStageOptions{
+			UUID:  fs.UUID,
+			Label: fs.Label,
+		}
+		return []*osbuild.Stage{osbuild.NewMkfsXfsStage(options, device)}
+	case "vfat":
+		options := &osbuild.MkfsFATStageOptions{
+			VolID: strings.Replace(fs.UUID, "-", "", -1),
+			Label: fs.Label,
+		}
+		return []*osbuild.Stage{osbuild.NewMkfsFATStage(options, device)}
+	case "btrfs":
+		options := &osbuild.MkfsBtrfsStageOptions{
+			UUID:  fs.UUID,
+			Label: fs.Label,
+		}
+		stages := []*osbuild.Stage{osbuild.NewMkfsBtrfsStage(options, device)}
+		if len(fs.Subvolumes) > 0 {
+			stages = append(stages, btrfsSubvolStage(fs.Subvolumes, device))
+		}
+		return stages
+	case "ext4":
+		options := &osbuild.MkfsExt4StageOptions{
+			UUID:  fs.UUID,
+			Label: fs.Label,
+		}
+		return []*osbuild.Stage{osbuild.NewMkfsExt4Stage(options, device)}
+	case disk.SwapFilesystemType:
+		options := &osbuild.MkswapStageOptions{
+			UUID:  fs.UUID,
+			Label: fs.Label,
+		}
+		return []*osbuild.Stage{osbuild.NewMkswapStage(options, device)}
+	default:
+		panic("unknown fs type " + fs.Type)
+	}
+}
+
+// lvm2CreateStage creates the physical volume on device and the volume group
+// with its logical volumes on it
+func lvm2CreateStage(vg *disk.LVMVolumeGroup, device *osbuild.Device) *osbuild.Stage {
+	options := &osbuild.LVM2CreateStageOptions{}
+	for _, lv := range vg.LogicalVolumes {
+		options.Volumes = append(options.Volumes, osbuild.LogicalVolume{
+			Name: lv.Name,
+			Size: fmt.Sprintf("%dB", lv.Size),
+		})
+	}
+	return osbuild.NewLVM2CreateStage(options, device)
+}
+
+// lvm2MetadataStages returns the stages that set the names of the volume
+// groups of pt, which the lvm2.create stage gives temporary names
+func lvm2MetadataStages(pt *disk.PartitionTable, device *osbuild.Device) []*osbuild.Stage {
+	devOptions, ok := device.Options.(*osbuild.LoopbackDeviceOptions)
+	if !ok {
+		panic("lvm2MetadataStages: failed to convert device options to loopback options")
+	}
+
+	var stages []*osbuild.Stage
+	for _, p := range pt.Partitions {
+		if p.VolumeGroup == nil {
+			continue
+		}
+		options := &osbuild.LVM2MetadataStageOptions{
+			VGName:       p.VolumeGroup.Name,
+			CreationHost: "osbuild",
+			CreationTime: "0",
+			Description:  "Built with osbuild",
 		}
-		stages = append(stages, stage)
+		stageDevice, parents := partitionDevices(devOptions, p, "device")
+		stages = append(stages, addStageDevices(osbuild.NewLVM2MetadataStage(options, stageDevice), parents))
 	}
 	return stages
 }
 
-func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format, qcow2Compat string) *osbuild.Pipeline {
+// btrfsSubvolStage creates the subvolumes in the top-level volume of the
+// btrfs filesystem on device
+func btrfsSubvolStage(subvolumes []disk.BtrfsSubvolume, device *osbuild.Device) *osbuild.Stage {
+	options := &osbuild.BtrfsSubVolStageOptions{}
+	for _, sv := range subvolumes {
+		options.Subvolumes = append(options.Subvolumes, osbuild.BtrfsSubVolume{Name: "/" + sv.Name})
+	}
+	devices := osbuild.Devices{"device": *device}
+	mounts := osbuild.Mounts{*osbuild.NewBtrfsMount("volume", "device", "/")}
+	return osbuild.NewBtrfsSubVolStage(options, &devices, &mounts)
+}
+
+func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format string, formatOptions osbuild.QEMUFormatOptions) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = format
 	p.Build = "name:build"
 
-	qemuStage := osbuild.NewQEMUStage(qemuStageOptions(outputFilename, format, qcow2Compat), qemuStageInputs(inputPipelineName, inputFilename))
-	p.AddStage(qemuStage)
-	return p
+	options, err := qemuStageOptions(outputFilename, format, formatOptions)
+	if err != nil {
+		return nil, err
+	}
+	p.AddStage(osbuild.NewQEMUStage(options, qemuStageInputs(inputPipelineName, inputFilename)))
+	return p, nil
 }
 
-func bootloaderConfigStage(t *imageType, partitionTable disk.PartitionTable, kernel *blueprint.KernelCustomization, kernelVer string, install, greenboot bool) *osbuild.Stage {
+func bootloaderConfigStage(t *imageType, partitionTable disk.PartitionTable, kernel *blueprint.KernelCustomization, grub *blueprint.GrubCustomization, selinux *blueprint.SELinuxCustomization, kdump *blueprint.KdumpCustomization, fips bool, kernelVer string, install, greenboot bool) (*osbuild.Stage, error) {
+	kernelOptions := imageKernelOptions(t, &partitionTable, kernel, selinux, kdump, fips)
 	if t.arch.name == distro.S390xArchName {
-		return osbuild.NewZiplStage(new(osbuild.ZiplStageOptions))
+		return osbuild.NewZiplStage(ziplStageOptions(&partitionTable, kernelOptions)), nil
 	}
 
-	kernelOptions := t.kernelOptions
 	uefi := t.supportsUEFI()
-	legacy := t.arch.legacy
+	legacy := t.legacyPlatform()
 
-	options := grub2StageOptions(partitionTable.RootPartition(), partitionTable.BootPartition(), kernelOptions, kernel, kernelVer, uefi, legacy, t.arch.distro.vendor, install)
+	options, err := grub2StageOptions(&partitionTable, kernelOptions, kernel, grub, kernelVer, uefi, legacy, t.arch.distro.vendor, install)
+	if err != nil {
+		return nil, err
+	}
 	options.Greenboot = greenboot
 
-	return osbuild.NewGRUB2Stage(options)
+	return osbuild.NewGRUB2Stage(options), nil
 }
 
-func bootloaderInstStage(filename string, pt *disk.PartitionTable, arch *architecture, kernelVer string, devices *osbuild.Devices, mounts *osbuild.Mounts, disk *osbuild.Device) *osbuild.Stage {
-	platform := arch.legacy
+func bootloaderInstStage(filename string, pt *disk.PartitionTable, t *imageType, kernelVer string, devices *osbuild.Devices, mounts *osbuild.Mounts, disk *osbuild.Device) *osbuild.Stage {
+	platform := t.legacyPlatform()
 	if platform != "" {
 		return osbuild.NewGrub2InstStage(grub2InstStageOptions(filename, pt, platform))
 	}
 
-	if arch.name == distro.S390xArchName {
+	if t.arch.name == distro.S390xArchName {
 		return osbuild.NewZiplInstStage(ziplInstStageOptions(kernelVer, pt), disk, devices, mounts)
 	}
 
 	return nil
 }
 
+// Names of the packages that install a bootable kernel
+var kernelPackageNames = []string{"kernel", "kernel-debug", "kernel-rt", "kernel-rt-debug", "kernel-64k", "kernel-64k-debug"}
+
+// defaultKernelVer returns the version of the kernel that the bootloader
+// boots by default. When the package set contains more than one kernel, the
+// blueprint must choose one with customizations.kernel.name.
+func defaultKernelVer(pkgs []rpmmd.PackageSpec, customizations *blueprint.Customizations) (string, error) {
+	kernelName := customizations.GetKernel().Name
+	if len(pkgs) == 0 {
+		// manifests of package sets which weren't depsolved, for example
+		// in tests, can't know the kernel version
+		return kernelVerStr(pkgs, kernelName, ""), nil
+	}
+	chosen := customizations != nil && customizations.Kernel != nil && customizations.Kernel.Name != ""
+
+	var kernels []string
+	var kernelPkg *rpmmd.PackageSpec
+	for idx := range pkgs {
+		pkg := &pkgs[idx]
+		if pkg.Name == kernelName {
+			kernelPkg = pkg
+		}
+		for _, name := range kernelPackageNames {
+			if pkg.Name == name {
+				kernels = append(kernels, name)
+			}
+		}
+	}
+
+	if !chosen && len(kernels) > 1 {
+		return "", fmt.Errorf("the image contains multiple kernels (%s), choose the default one with customizations.kernel.name", strings.Join(kernels, ", "))
+	}
+	if kernelPkg == nil {
+		return "", fmt.Errorf("the default kernel %q is not installed in the image", kernelName)
+	}
+
+	return fmt.Sprintf("%s-%s.%s", kernelPkg.Version, kernelPkg.Release, kernelPkg.Arch), nil
+}
+
 func kernelVerStr(pkgs []rpmmd.PackageSpec, kernelName, arch string) string {
 	kernelPkg := new(rpmmd.PackageSpec)
 	for _, pkg := range pkgs {
@@ -59,6 +59,16 @@ func copyPipelineTreeInputs(name, inputPipeline string) *osbuild.CopyStageInputs
 	return &osbuild.CopyStageInputs{inputName: treeInput}
 }
 
+// copyInlineFileInputs returns the inputs of a copy stage that references the
+// data of an inline source by their checksums.
+func copyInlineFileInputs(name string, checksums []string) *osbuild.CopyStageInputs {
+	filesInput := osbuild.CopyStageInput{}
+	filesInput.Type = "org.osbuild.files"
+	filesInput.Origin = "org.osbuild.source"
+	filesInput.References = checksums
+	return &osbuild.CopyStageInputs{name: filesInput}
+}
+
 func qemuStageInputs(stage, file string) *osbuild.QEMUStageInputs {
 	stageKey := "name:" + stage
 	ref := map[string]osbuild.QEMUFile{
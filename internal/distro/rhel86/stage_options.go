@@ -2,14 +2,19 @@ package rhel86
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/container"
 	"github.com/osbuild/osbuild-composer/internal/crypt"
 	"github.com/osbuild/osbuild-composer/internal/disk"
 	"github.com/osbuild/osbuild-composer/internal/distro"
@@ -21,7 +26,7 @@ const (
 	kspath = "/osbuild.ks"
 )
 
-func rpmStageOptions(repos []rpmmd.RepoConfig) *osbuild.RPMStageOptions {
+func rpmStageOptions(repos []rpmmd.RepoConfig, rpm *blueprint.RPMCustomization) *osbuild.RPMStageOptions {
 	var gpgKeys []string
 	for _, repo := range repos {
 		if repo.GPGKey == "" {
@@ -30,17 +35,64 @@ func rpmStageOptions(repos []rpmmd.RepoConfig) *osbuild.RPMStageOptions {
 		gpgKeys = append(gpgKeys, repo.GPGKey)
 	}
 
-	return &osbuild.RPMStageOptions{
+	options := &osbuild.RPMStageOptions{
 		GPGKeys: gpgKeys,
 	}
+	if rpm != nil {
+		if rpm.ExcludeDocs != nil && *rpm.ExcludeDocs {
+			options.Exclude = &osbuild.Exclude{Docs: true}
+		}
+		options.InstallLangs = rpm.InstallLangs
+	}
+	return options
+}
+
+// selinuxPolicy returns the SELinux policy of images with the given
+// customization, which defaults to the targeted policy.
+func selinuxPolicy(selinux *blueprint.SELinuxCustomization) osbuild.SELinuxPolicyType {
+	if selinux == nil || selinux.Policy == "" {
+		return osbuild.SELinuxTypeTargeted
+	}
+	return osbuild.SELinuxPolicyType(selinux.Policy)
+}
+
+// selinuxConfigStageOptions returns the options of the org.osbuild.selinux.config
+// stage setting the policy and mode of the image, or nil if the defaults of
+// the distribution are kept
+func selinuxConfigStageOptions(selinux *blueprint.SELinuxCustomization) *osbuild.SELinuxConfigStageOptions {
+	policy := selinuxPolicy(selinux)
+	var state osbuild.SELinuxPolicyState
+	if selinux != nil {
+		state = osbuild.SELinuxPolicyState(selinux.Mode)
+	}
+	if policy == osbuild.SELinuxTypeTargeted && state == "" {
+		return nil
+	}
+	options := &osbuild.SELinuxConfigStageOptions{State: state}
+	if policy != osbuild.SELinuxTypeTargeted {
+		options.Type = policy
+	}
+	return options
+}
+
+// selinuxKernelOptions appends the argument that makes SELinux permissive from
+// the start of the boot to kernelOptions if the image is permissive
+func selinuxKernelOptions(kernelOptions string, selinux *blueprint.SELinuxCustomization) string {
+	if selinux == nil || osbuild.SELinuxPolicyState(selinux.Mode) != osbuild.SELinuxStatePermissive {
+		return kernelOptions
+	}
+	return strings.TrimSpace(kernelOptions + " enforcing=0")
 }
 
 // selinuxStageOptions returns the options for the org.osbuild.selinux stage.
 // Setting the argument to 'true' relabels the '/usr/bin/cp' and '/usr/bin/tar'
 // binaries with 'install_exec_t'. This should be set in the build root.
-func selinuxStageOptions(labelcp bool) *osbuild.SELinuxStageOptions {
+// Files and directories the customizations c create in excluded paths are
+// labeled from the file contexts nonetheless.
+func selinuxStageOptions(labelcp bool, c *blueprint.Customizations) *osbuild.SELinuxStageOptions {
+	selinux := c.GetSELinux()
 	options := &osbuild.SELinuxStageOptions{
-		FileContexts: "etc/selinux/targeted/contexts/files/file_contexts",
+		FileContexts: fmt.Sprintf("etc/selinux/%s/contexts/files/file_contexts", selinuxPolicy(selinux)),
 	}
 	if labelcp {
 		options.Labels = map[string]string{
@@ -48,17 +100,153 @@ func selinuxStageOptions(labelcp bool) *osbuild.SELinuxStageOptions {
 			"/usr/bin/tar": "system_u:object_r:install_exec_t:s0",
 		}
 	}
+	if swap := c.GetSwap(); swap != nil && swap.Type == swapTypeFile {
+		if options.Labels == nil {
+			options.Labels = make(map[string]string)
+		}
+		// swapon is only allowed to use files labeled as swap files
+		options.Labels[swapFilePath] = "system_u:object_r:swapfile_t:s0"
+	}
+	if selinux != nil {
+		options.ExcludePaths = selinux.Exclude
+		if selinux.ForceAutorelabel {
+			options.ForceAutorelabel = common.BoolToPtr(true)
+		}
+		// files and directories created by customizations in excluded
+		// paths are labeled from the file contexts of the policy instead
+		// of on the first boot
+		options.LabelPaths = customizationLabelPaths(c, selinux.Exclude)
+	}
+	return options
+}
+
+// customizationLabelPaths returns the files and directories of the
+// customizations which are in one of the excluded paths and thus not labeled
+// with the rest of the tree
+func customizationLabelPaths(c *blueprint.Customizations, excluded []string) []string {
+	var paths []string
+	for _, file := range customizationFiles(c) {
+		paths = append(paths, file.Path)
+	}
+	for _, dir := range c.GetDirectories() {
+		paths = append(paths, dir.Path)
+	}
+
+	var labelPaths []string
+	for _, p := range paths {
+		for _, e := range excluded {
+			if pathIn(p, e) {
+				labelPaths = append(labelPaths, p)
+				break
+			}
+		}
+	}
+	return labelPaths
+}
+
+// pathIn returns whether p is the directory dir or below it
+func pathIn(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// X11 layouts of the console keymaps whose language part isn't the name of
+// their X11 layout
+var x11Layouts = map[string]string{
+	"uk": "gb",
+	"sv": "se",
+	"sg": "ch",
+	"sf": "ch",
+}
+
+// keymapStageOptions returns the options of the org.osbuild.keymap stage that
+// sets the console keymap to keyboard and the X11 layout to the one of the
+// same language. Keymaps that are not named after a language, e.g. dvorak,
+// only set the console keymap.
+func keymapStageOptions(keyboard string) *osbuild.KeymapStageOptions {
+	options := &osbuild.KeymapStageOptions{Keymap: keyboard}
+	if match := keymapLanguageRegexp.FindStringSubmatch(keyboard); match != nil {
+		layout := match[1]
+		if x11Layout, exists := x11Layouts[layout]; exists {
+			layout = x11Layout
+		}
+		options.X11Keymap = &osbuild.X11KeymapOptions{Layouts: []string{layout}}
+	}
+	return options
+}
+
+// sshdConfigStageOptions returns the options of the org.osbuild.sshd.config
+// stage that writes the sshd options of the blueprint to a drop-in. The
+// drop-in sorts first, so that its options take precedence over the ones of
+// other drop-ins.
+func sshdConfigStageOptions(sshd *blueprint.SSHDCustomization) *osbuild.SshdConfigStageOptions {
+	return &osbuild.SshdConfigStageOptions{
+		Filename: "00-blueprint.conf",
+		Config: osbuild.SshdConfig{
+			PermitRootLogin:        sshd.PermitRootLogin,
+			PasswordAuthentication: sshd.PasswordAuthentication,
+			ClientAliveInterval:    sshd.ClientAliveInterval,
+			MaxAuthTries:           sshd.MaxAuthTries,
+		},
+	}
+}
+
+// chronyStageOptions returns the options of the org.osbuild.chrony stage that
+// replaces the NTP servers of the distribution with ntpServers, sets the
+// timezone of the leap seconds to leapsecTz, and comments out the pools of
+// the distribution if commentPools is set, or nil if chrony isn't changed. If
+// ptpClock is set, the PTP clock of the hypervisor is kept as a reference
+// clock.
+func chronyStageOptions(ntpServers []string, leapsecTz *string, commentPools bool, ptpClock string) *osbuild.ChronyStageOptions {
+	if len(ntpServers) == 0 && leapsecTz == nil && !commentPools {
+		return nil
+	}
+	options := &osbuild.ChronyStageOptions{
+		Timeservers: ntpServers,
+		LeapsecTz:   leapsecTz,
+	}
+	if commentPools {
+		options.CommentPools = common.BoolToPtr(true)
+	}
+	if ptpClock != "" {
+		options.Refclocks = []osbuild.ChronyConfigRefclock{
+			{
+				Driver: osbuild.ChronyRefclockDriver{Name: "PHC", Path: ptpClock},
+				Poll:   common.IntToPtr(3),
+				Dpoll:  common.IntToPtr(-2),
+			},
+		}
+	}
 	return options
 }
 
-func userStageOptions(users []blueprint.UserCustomization) (*osbuild.UsersStageOptions, error) {
+// swapFileStageOptions returns the options of the stage that creates the swap
+// file of swap and the fstab entry that activates it, or nil if swap isn't a
+// swap file
+func swapFileStageOptions(swap *blueprint.SwapCustomization) (*osbuild.SwapfileStageOptions, *osbuild.FSTabEntry) {
+	if swap == nil || swap.Type != swapTypeFile {
+		return nil, nil
+	}
+	options := &osbuild.SwapfileStageOptions{
+		Path: swapFilePath,
+		Size: swap.Size * 1024 * 1024,
+	}
+	entry := &osbuild.FSTabEntry{
+		Device:  swapFilePath,
+		VFSType: "swap",
+		Path:    "none",
+		Options: "sw",
+	}
+	return options, entry
+}
+
+func userStageOptions(users []blueprint.UserCustomization, pwHash passwordHash) (*osbuild.UsersStageOptions, error) {
 	options := osbuild.UsersStageOptions{
 		Users: make(map[string]osbuild.UsersStageOptionsUser),
 	}
 
 	for _, c := range users {
 		if c.Password != nil && !crypt.PasswordIsCrypted(*c.Password) {
-			cryptedPassword, err := crypt.CryptSHA512(*c.Password)
+			cryptedPassword, err := crypt.Crypt(pwHash.method, *c.Password, pwHash.options)
 			if err != nil {
 				return nil, err
 			}
@@ -77,6 +265,10 @@ func userStageOptions(users []blueprint.UserCustomization) (*osbuild.UsersStageO
 
 		user.UID = c.UID
 		user.GID = c.GID
+		user.HomeMode = c.HomeMode
+		user.System = c.System
+		user.Locked = c.Locked
+		user.ExpireDate = c.ExpireDate
 
 		options.Users[c.Name] = user
 	}
@@ -84,25 +276,99 @@ func userStageOptions(users []blueprint.UserCustomization) (*osbuild.UsersStageO
 	return &options, nil
 }
 
-func usersFirstBootOptions(usersStageOptions *osbuild.UsersStageOptions) *osbuild.FirstBootStageOptions {
-	cmds := make([]string, 0, 3*len(usersStageOptions.Users)+1)
-	// workaround for creating authorized_keys file for user
-	varhome := filepath.Join("/var", "home")
-	for name, user := range usersStageOptions.Users {
-		if user.Key != nil {
-			sshdir := filepath.Join(varhome, name, ".ssh")
-			cmds = append(cmds, fmt.Sprintf("mkdir -p %s", sshdir))
-			cmds = append(cmds, fmt.Sprintf("sh -c 'echo %q >> %q'", *user.Key, filepath.Join(sshdir, "authorized_keys")))
-			cmds = append(cmds, fmt.Sprintf("chown %s:%s -Rc %s", name, name, sshdir))
+// userKickstartStageOptions returns the users and groups that the installer
+// creates from its kickstart. Passwords are hashed like for the users stage.
+// Kickstart has no equivalent of the home directory mode, system accounts,
+// locked accounts, and expiration dates.
+func userKickstartStageOptions(users []blueprint.UserCustomization, groups []blueprint.GroupCustomization, pwHash passwordHash) (map[string]osbuild.UsersStageOptionsUser, map[string]osbuild.GroupsStageOptionsGroup, error) {
+	var ksUsers map[string]osbuild.UsersStageOptionsUser
+	if len(users) > 0 {
+		for _, user := range users {
+			if user.HomeMode != nil || user.System != nil || user.Locked != nil || user.ExpireDate != nil {
+				return nil, nil, fmt.Errorf("user %q: home_mode, system, locked, and expire_date are not supported by the installer kickstart", user.Name)
+			}
+		}
+		userOptions, err := userStageOptions(users, pwHash)
+		if err != nil {
+			return nil, nil, err
 		}
+		ksUsers = userOptions.Users
 	}
-	cmds = append(cmds, fmt.Sprintf("restorecon -rvF %s", varhome))
-	options := &osbuild.FirstBootStageOptions{
-		Commands:       cmds,
-		WaitForNetwork: false,
+
+	var ksGroups map[string]osbuild.GroupsStageOptionsGroup
+	if len(groups) > 0 {
+		ksGroups = groupStageOptions(groups).Groups
 	}
+	return ksUsers, ksGroups, nil
+}
 
-	return options
+// authorizedKeysDir is the directory of ostree commits with the SSH keys of
+// the users
+const authorizedKeysDir = "/etc/ssh/authorized_keys"
+
+// authorizedKeysTmpfilesPath is the tmpfiles.d configuration of ostree
+// commits that installs the SSH keys of the users into their home directories
+const authorizedKeysTmpfilesPath = "/etc/tmpfiles.d/authorized-keys.conf"
+
+// authorizedKeysFiles returns the files that provide the SSH keys of the
+// users in ostree commits. Their deployments only keep /etc, so the keys are
+// written to authorizedKeysDir at build time, and a tmpfiles.d configuration
+// copies them into the .ssh directories of the users on boot unless those
+// already have an authorized_keys file.
+func authorizedKeysFiles(users []blueprint.UserCustomization) []blueprint.FileCustomization {
+	var files []blueprint.FileCustomization
+	var tmpfiles strings.Builder
+	for _, user := range users {
+		// accounts without a key of their own, e.g. locked service
+		// accounts, get no authorized_keys file
+		if user.Key == nil {
+			continue
+		}
+
+		group := user.Name
+		if user.GID != nil {
+			group = strconv.Itoa(*user.GID)
+		}
+		keys := path.Join(authorizedKeysDir, user.Name)
+		data := *user.Key
+		if !strings.HasSuffix(data, "\n") {
+			data += "\n"
+		}
+		files = append(files, blueprint.FileCustomization{
+			Path:  keys,
+			Mode:  "0600",
+			User:  user.Name,
+			Group: group,
+			Data:  data,
+		})
+
+		home := path.Join("/var/home", user.Name)
+		if user.Home != nil {
+			home = *user.Home
+		}
+		sshDir := path.Join(home, ".ssh")
+		owner := tmpfilesField(user.Name) + " " + tmpfilesField(group)
+		fmt.Fprintf(&tmpfiles, "d %s 0700 %s -\n", tmpfilesField(sshDir), owner)
+		fmt.Fprintf(&tmpfiles, "C %s 0600 %s - %s\n", tmpfilesField(path.Join(sshDir, "authorized_keys")), owner, tmpfilesField(keys))
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	return append(files, blueprint.FileCustomization{
+		Path: authorizedKeysTmpfilesPath,
+		Data: tmpfiles.String(),
+	})
+}
+
+// tmpfilesField returns s as a single field of a tmpfiles.d line. Specifiers
+// are escaped, and fields with whitespace, quotes or backslashes are quoted.
+func tmpfilesField(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if strings.ContainsAny(s, " \t\n\"'\\") {
+		return strconv.Quote(s)
+	}
+	return s
 }
 
 func groupStageOptions(groups []blueprint.GroupCustomization) *osbuild.GroupsStageOptions {
@@ -122,9 +388,13 @@ func groupStageOptions(groups []blueprint.GroupCustomization) *osbuild.GroupsSta
 	return &options
 }
 
-func firewallStageOptions(firewall *blueprint.FirewallCustomization) *osbuild.FirewallStageOptions {
+func firewallStageOptions(firewall *blueprint.FirewallCustomization) (*osbuild.FirewallStageOptions, error) {
+	ports, err := firewall.NormalizedPorts()
+	if err != nil {
+		return nil, err
+	}
 	options := osbuild.FirewallStageOptions{
-		Ports: firewall.Ports,
+		Ports: ports,
 	}
 
 	if firewall.Services != nil {
@@ -132,38 +402,112 @@ func firewallStageOptions(firewall *blueprint.FirewallCustomization) *osbuild.Fi
 		options.DisabledServices = firewall.Services.Disabled
 	}
 
-	return &options
+	if err := firewall.ValidateZones(); err != nil {
+		return nil, err
+	}
+	options.DefaultZone = firewall.DefaultZone
+	for _, zone := range firewall.Zones {
+		options.Zones = append(options.Zones, osbuild.FirewallZone{
+			Name:       zone.Name,
+			Sources:    zone.Sources,
+			Interfaces: zone.Interfaces,
+		})
+	}
+
+	return &options, nil
+}
+
+func authselectStageOptions(authselect *blueprint.AuthselectCustomization) *osbuild.AuthselectStageOptions {
+	return &osbuild.AuthselectStageOptions{
+		Profile:  authselect.Profile,
+		Features: authselect.Features,
+	}
+}
+
+// kdumpServices returns the services enabled and disabled by the image type
+// with the kdump service enabled or disabled as the kdump customization sets
+func kdumpServices(kdump *blueprint.KdumpCustomization, enabledServices, disabledServices []string) ([]string, []string) {
+	if kdump == nil {
+		return enabledServices, disabledServices
+	}
+	// copy, the slices of the image type are shared by all manifests
+	if kdump.Enabled {
+		return append(append([]string{}, enabledServices...), "kdump.service"), disabledServices
+	}
+	return enabledServices, append(append([]string{}, disabledServices...), "kdump.service")
 }
 
 func systemdStageOptions(enabledServices, disabledServices []string, s *blueprint.ServicesCustomization, target string) *osbuild.SystemdStageOptions {
+	var maskedServices []string
 	if s != nil {
 		enabledServices = append(enabledServices, s.Enabled...)
 		disabledServices = append(disabledServices, s.Disabled...)
+		maskedServices = s.Masked
+	}
+	if len(maskedServices) > 0 {
+		// masked units of the blueprint win over the services that the
+		// image type enables
+		masked := make(map[string]bool)
+		for _, unit := range maskedServices {
+			masked[blueprint.ServiceUnitName(unit)] = true
+		}
+		var enabled []string
+		for _, unit := range enabledServices {
+			if !masked[blueprint.ServiceUnitName(unit)] {
+				enabled = append(enabled, unit)
+			}
+		}
+		enabledServices = enabled
 	}
 	return &osbuild.SystemdStageOptions{
 		EnabledServices:  enabledServices,
 		DisabledServices: disabledServices,
+		MaskedServices:   maskedServices,
 		DefaultTarget:    target,
 	}
 }
 
-func buildStampStageOptions(arch, product, osVersion, variant string) *osbuild.BuildstampStageOptions {
+func buildStampStageOptions(arch, product, osVersion, variant string, final bool) *osbuild.BuildstampStageOptions {
 	return &osbuild.BuildstampStageOptions{
 		Arch:    arch,
 		Product: product,
 		Version: osVersion,
 		Variant: variant,
-		Final:   true,
+		Final:   final,
 	}
 }
 
-func anacondaStageOptions() *osbuild.AnacondaStageOptions {
+// defaultKickstartModules are the Anaconda kickstart modules that installers
+// need to install a payload without user interaction
+var defaultKickstartModules = []string{
+	"org.fedoraproject.Anaconda.Modules.Network",
+	"org.fedoraproject.Anaconda.Modules.Payloads",
+	"org.fedoraproject.Anaconda.Modules.Storage",
+}
+
+// anacondaStageOptions returns the options of the Anaconda stage, which
+// enables the default kickstart modules of the image type, plus the modules
+// that the customization enables, minus the ones it disables.
+func anacondaStageOptions(defaultModules []string, modules *blueprint.AnacondaModulesCustomization) *osbuild.AnacondaStageOptions {
+	disabled := make(map[string]bool)
+	var enable []string
+	if modules != nil {
+		for _, module := range modules.Disable {
+			disabled[module] = true
+		}
+		enable = modules.Enable
+	}
+
+	kickstartModules := []string{}
+	seen := make(map[string]bool)
+	for _, module := range append(append([]string{}, defaultModules...), enable...) {
+		if !seen[module] && !disabled[module] {
+			seen[module] = true
+			kickstartModules = append(kickstartModules, module)
+		}
+	}
 	return &osbuild.AnacondaStageOptions{
-		KickstartModules: []string{
-			"org.fedoraproject.Anaconda.Modules.Network",
-			"org.fedoraproject.Anaconda.Modules.Payloads",
-			"org.fedoraproject.Anaconda.Modules.Storage",
-		},
+		KickstartModules: kickstartModules,
 	}
 }
 
@@ -174,7 +518,12 @@ func loraxScriptStageOptions(arch string) *osbuild.LoraxScriptStageOptions {
 	}
 }
 
-func dracutStageOptions(kernelVer, arch string, additionalModules []string) *osbuild.DracutStageOptions {
+// dracutStageOptions returns the options of the initramfs of installers,
+// which contains the modules of the installer, additionalModules, and the
+// modules, drivers, and files of the dracut customization, but not the
+// blacklisted kernel modules. Duplicates are dropped, keeping the first
+// occurrence.
+func dracutStageOptions(kernelVer, arch string, additionalModules []string, dracut *blueprint.DracutCustomization, blacklist []string) *osbuild.DracutStageOptions {
 	kernel := []string{kernelVer}
 	modules := []string{
 		"bash",
@@ -231,11 +580,39 @@ func dracutStageOptions(kernelVer, arch string, additionalModules []string) *osb
 	}
 
 	modules = append(modules, additionalModules...)
-	return &osbuild.DracutStageOptions{
-		Kernel:  kernel,
-		Modules: modules,
-		Install: []string{"/.buildstamp"},
+	install := []string{"/.buildstamp"}
+	var drivers []string
+	if dracut != nil {
+		modules = append(modules, dracut.AddModules...)
+		drivers = appendUnique(nil, dracut.AddDrivers...)
+		install = append(install, dracut.Install...)
 	}
+	options := &osbuild.DracutStageOptions{
+		Kernel:     kernel,
+		Modules:    appendUnique(nil, modules...),
+		AddDrivers: drivers,
+		Install:    appendUnique(nil, install...),
+	}
+	if len(blacklist) > 0 {
+		options.Extra = []string{"--omit-drivers", strings.Join(blacklist, " ")}
+	}
+	return options
+}
+
+// appendUnique appends the elements of values to slice which it doesn't
+// contain yet, in their order.
+func appendUnique(slice []string, values ...string) []string {
+	seen := make(map[string]bool, len(slice))
+	for _, v := range slice {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			slice = append(slice, v)
+		}
+	}
+	return slice
 }
 
 func tarKickstartStageOptions(tarURL string) *osbuild.KickstartStageOptions {
@@ -259,11 +636,62 @@ func ostreeKickstartStageOptions(ostreeURL, ostreeRef string) *osbuild.Kickstart
 	}
 }
 
-func bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolabel string) *osbuild.BootISOMonoStageOptions {
-	comprOptions := new(osbuild.FSCompressionOptions)
-	if bcj := osbuild.BCJOption(arch); bcj != "" {
-		comprOptions.BCJ = bcj
+// setKickstartLocalization sets the language, the keyboard layout, and the
+// timezone of the installation from the customizations. The installer asks
+// for, or defaults, the settings which aren't customized.
+func setKickstartLocalization(options *osbuild.KickstartStageOptions, c *blueprint.Customizations) {
+	language, keyboard := c.GetPrimaryLocale()
+	if language != nil {
+		options.Language = *language
+	}
+	if keyboard != nil {
+		options.Keyboard = *keyboard
+	}
+	if timezone, _ := c.GetTimezoneSettings(); timezone != nil {
+		options.Timezone = *timezone
+	}
+}
+
+// installerPostScriptFiles returns the bodies of the installer %post scripts
+// as files, which only add them to the inline source: they are inputs of the
+// kickstart stage and aren't copied into the tree.
+func installerPostScriptFiles(scripts []blueprint.PostScriptCustomization) []blueprint.FileCustomization {
+	files := make([]blueprint.FileCustomization, len(scripts))
+	for idx, script := range scripts {
+		files[idx] = blueprint.FileCustomization{Data: script.Script}
+	}
+	return files
+}
+
+// kickstartPostOptions converts the installer %post script customizations to
+// kickstart stage options, preserving their order, and returns the inputs of
+// their bodies in the inline source.
+func kickstartPostOptions(scripts []blueprint.PostScriptCustomization) ([]osbuild.PostOptions, *osbuild.KickstartStageInputs) {
+	const inputName = "post"
+
+	if len(scripts) == 0 {
+		return nil, nil
 	}
+	post := make([]osbuild.PostOptions, 0, len(scripts))
+	var checksums []string
+	seenChecksums := make(map[string]bool)
+	for _, script := range scripts {
+		checksum := osbuild.InlineSourceChecksum([]byte(script.Script))
+		if !seenChecksums[checksum] {
+			seenChecksums[checksum] = true
+			checksums = append(checksums, checksum)
+		}
+		post = append(post, osbuild.PostOptions{
+			Interpreter: script.Interpreter,
+			ErrorOnFail: script.ErrorOnFail,
+			NoChroot:    script.NoChroot,
+			Script:      fmt.Sprintf("input://%s/%s", inputName, checksum),
+		})
+	}
+	return post, osbuild.NewKickstartStageInputs(checksums)
+}
+
+func bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolabel string, fips, mediacheck bool, timeout *int, rootfs osbuild.RootFS) *osbuild.BootISOMonoStageOptions {
 	var architectures []string
 
 	if arch == distro.X86_64ArchName {
@@ -281,7 +709,7 @@ func bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolab
 		},
 		ISOLabel:   isolabel,
 		Kernel:     kernelVer,
-		KernelOpts: fmt.Sprintf("inst.ks=hd:LABEL=%s:%s", isolabel, kspath),
+		KernelOpts: fipsKernelOptions(mediacheckKernelOptions(fmt.Sprintf("inst.ks=hd:LABEL=%s:%s", isolabel, kspath), mediacheck), fips, nil),
 		EFI: osbuild.EFI{
 			Architectures: architectures,
 			Vendor:        vendor,
@@ -291,17 +719,43 @@ func bootISOMonoStageOptions(kernelVer, arch, vendor, product, osVersion, isolab
 			Debug:   false,
 		},
 		Templates: "80-rhel",
-		RootFS: osbuild.RootFS{
-			Size: 9216,
-			Compression: osbuild.FSCompression{
-				Method:  "xz",
-				Options: comprOptions,
-			},
-		},
+		Timeout:   timeout,
+		RootFS:    rootfs,
+	}
+}
+
+// simplifiedInstallerKernelOptions returns the kernel options of the edge
+// simplified installer, which configure coreos-installer to install the raw
+// image to the first of installDevices that exists, and the FDO client to
+// onboard the device.
+func simplifiedInstallerKernelOptions(installDevices []string, isolabel, ignitionURL string, fdo *blueprint.FDOCustomization) []string {
+	kernelOpts := []string{"rd.neednet=1",
+		"console=tty0",
+		"console=ttyS0",
+		"systemd.log_target=console",
+		"systemd.journald.forward_to_console=1",
+		"edge.liveiso=" + isolabel,
+		"coreos.inst.install_dev=" + strings.Join(installDevices, ","),
+		"coreos.inst.image_file=/run/media/iso/disk.img.xz",
+		"coreos.inst.insecure"}
+	if ignitionURL != "" {
+		kernelOpts = append(kernelOpts, "ignition.config.url="+ignitionURL)
+	}
+	if fdo != nil {
+		kernelOpts = append(kernelOpts, "fdo.manufacturing_server_url="+fdo.ManufacturingServerURL)
+		switch {
+		case fdo.DiunPubKeyInsecure:
+			kernelOpts = append(kernelOpts, "fdo.diun_pub_key_insecure=true")
+		case fdo.DiunPubKeyHash != "":
+			kernelOpts = append(kernelOpts, "fdo.diun_pub_key_hash="+fdo.DiunPubKeyHash)
+		case fdo.DiunPubKeyRootCerts != "":
+			kernelOpts = append(kernelOpts, "fdo.diun_pub_key_root_certs="+fdoRootCertsPath)
+		}
 	}
+	return kernelOpts
 }
 
-func grubISOStageOptions(installDevice, kernelVer, arch, vendor, product, osVersion, isolabel string) *osbuild.GrubISOStageOptions {
+func grubISOStageOptions(kernelOpts []string, kernelVer, arch, vendor, product, osVersion, isolabel string) *osbuild.GrubISOStageOptions {
 	var architectures []string
 
 	if arch == "x86_64" {
@@ -319,33 +773,25 @@ func grubISOStageOptions(installDevice, kernelVer, arch, vendor, product, osVers
 		},
 		ISOLabel: isolabel,
 		Kernel: osbuild.ISOKernel{
-			Dir: "/images/pxeboot",
-			Opts: []string{"rd.neednet=1",
-				"console=tty0",
-				"console=ttyS0",
-				"systemd.log_target=console",
-				"systemd.journald.forward_to_console=1",
-				"edge.liveiso=" + isolabel,
-				"coreos.inst.install_dev=" + installDevice,
-				"coreos.inst.image_file=/run/media/iso/disk.img.xz",
-				"coreos.inst.insecure"},
+			Dir:  "/images/pxeboot",
+			Opts: kernelOpts,
 		},
 		Architectures: architectures,
 		Vendor:        vendor,
 	}
 }
 
-func discinfoStageOptions(arch string) *osbuild.DiscinfoStageOptions {
+func discinfoStageOptions(arch, release string) *osbuild.DiscinfoStageOptions {
 	return &osbuild.DiscinfoStageOptions{
 		BaseArch: arch,
-		Release:  "202010217.n.0",
+		Release:  release,
 	}
 }
 
-func xorrisofsStageOptions(filename, isolabel, arch string, isolinux bool) *osbuild.XorrisofsStageOptions {
+func xorrisofsStageOptions(filename, isolabel string, isolinux bool) *osbuild.XorrisofsStageOptions {
 	options := &osbuild.XorrisofsStageOptions{
 		Filename: filename,
-		VolID:    fmt.Sprintf(isolabel, arch),
+		VolID:    isolabel,
 		SysID:    "LINUX",
 		EFI:      "images/efiboot.img",
 	}
@@ -362,23 +808,46 @@ func xorrisofsStageOptions(filename, isolabel, arch string, isolinux bool) *osbu
 	return options
 }
 
-func grub2StageOptions(rootPartition *disk.Partition,
-	bootPartition *disk.Partition,
+// grub2StageOptions creates the options of the org.osbuild.grub2 stage. The
+// root filesystem is identified according to the DeviceIDMode of pt, like in
+// the fstab generated from it.
+func grub2StageOptions(pt *disk.PartitionTable,
 	kernelOptions string,
 	kernel *blueprint.KernelCustomization,
+	grub *blueprint.GrubCustomization,
 	kernelVer string,
 	uefi bool,
 	legacy string,
 	vendor string,
-	install bool) *osbuild.GRUB2StageOptions {
-	if rootPartition == nil {
+	install bool) (*osbuild.GRUB2StageOptions, error) {
+	rootFilesystem := pt.RootFilesystem()
+	if rootFilesystem == nil {
 		panic("root partition must be defined for grub2 stage, this is a programming error")
 	}
+	bootPartition := pt.BootPartition()
 
+	kernelOptions = rootKernelOptions(pt, kernelOptions)
 	stageOptions := osbuild.GRUB2StageOptions{
-		RootFilesystemUUID: uuid.MustParse(rootPartition.Filesystem.UUID),
-		KernelOptions:      kernelOptions,
-		Legacy:             legacy,
+		KernelOptions: kernelOptions,
+		Legacy:        legacy,
+	}
+
+	rootFsUUID := uuid.MustParse(rootFilesystem.UUID)
+	switch pt.DeviceIDMode {
+	case disk.DeviceIDFilesystemLabel:
+		stageOptions.RootFilesystem = &osbuild.GRUB2FSDesc{Label: rootFilesystem.Label}
+	case disk.DeviceIDPartitionUUID:
+		// the stage always adds a root= argument with the filesystem UUID
+		// or label, a second one with the partition UUID would conflict
+		return nil, fmt.Errorf("GRUB2 can't identify the root filesystem by partition UUID")
+	case disk.DeviceIDPartitionLabel:
+		// only the boot entries of ostree deployments are identified by
+		// partition label, they carry their own root= argument and the
+		// one of the stage only ends up in the unused kernelopts of
+		// grubenv
+		stageOptions.RootFilesystem = &osbuild.GRUB2FSDesc{Label: rootFilesystem.Label}
+	default:
+		stageOptions.RootFilesystemUUID = rootFsUUID
 	}
 
 	if bootPartition != nil {
@@ -399,12 +868,60 @@ func grub2StageOptions(rootPartition *disk.Partition,
 
 	if kernel != nil {
 		if kernel.Append != "" {
-			stageOptions.KernelOptions += " " + kernel.Append
+			stageOptions.KernelOptions = dedupeKernelOptions(stageOptions.KernelOptions + " " + kernel.Append)
 		}
 		stageOptions.SavedEntry = "ffffffffffffffffffffffffffffffff-" + kernelVer
 	}
 
-	return &stageOptions
+	if grub != nil && (grub.Default != "" || grub.Timeout != nil || grub.TimeoutStyle != "" || len(grub.Terminal) > 0) {
+		stageOptions.Config = &osbuild.GRUB2Config{
+			Default:      grub.Default,
+			Timeout:      grub.Timeout,
+			TimeoutStyle: osbuild.GRUB2TimeoutStyle(grub.TimeoutStyle),
+			Terminal:     grub.Terminal,
+			Serial:       grubSerialCommand(grub),
+		}
+	}
+
+	if grub != nil && grub.Password != "" {
+		passwordHash := grub.Password
+		if !crypt.PasswordIsGRUB2PBKDF2(passwordHash) {
+			var err error
+			passwordHash, err = crypt.CryptGRUB2PBKDF2(grub.Password)
+			if err != nil {
+				return nil, err
+			}
+		}
+		superuser := grub.Superuser
+		if superuser == "" {
+			superuser = "root"
+		}
+		stageOptions.Superusers = []string{superuser}
+		stageOptions.PasswordHash = passwordHash
+	}
+
+	return &stageOptions, nil
+}
+
+// grubSerialCommand returns the command setting up the serial terminal of
+// GRUB, or "" if the serial terminal isn't used
+func grubSerialCommand(grub *blueprint.GrubCustomization) string {
+	serial := false
+	for _, terminal := range grub.Terminal {
+		serial = serial || terminal == "serial"
+	}
+	if !serial {
+		return ""
+	}
+
+	unit, speed := 0, 115200
+	if grub.Serial != nil {
+		unit = grub.Serial.Unit
+		if grub.Serial.Speed != 0 {
+			speed = grub.Serial.Speed
+		}
+	}
+	return fmt.Sprintf("serial --speed=%d --unit=%d --word=8 --parity=no --stop=1", speed, unit)
 }
 
 // sfdiskStageOptions creates the options and devices properties for an
@@ -413,7 +930,9 @@ func sfdiskStageOptions(pt *disk.PartitionTable) *osbuild.SfdiskStageOptions {
 	partitions := make([]osbuild.Partition, len(pt.Partitions))
 	for idx, p := range pt.Partitions {
 		partitions[idx] = osbuild.Partition{
+			Attrs:    p.Attrs,
 			Bootable: p.Bootable,
+			Name:     p.Name,
 			Size:     p.Size,
 			Start:    p.Start,
 			Type:     p.Type,
@@ -424,15 +943,123 @@ func sfdiskStageOptions(pt *disk.PartitionTable) *osbuild.SfdiskStageOptions {
 		Label:      pt.Type,
 		UUID:       pt.UUID,
 		Partitions: partitions,
+		SectorSize: sectorSizeOption(pt),
 	}
 
 	return stageOptions
 }
 
+// sfdiskGrowStageOptions creates the options for an org.osbuild.sfdisk stage
+// that changes the partitions of a disk with the partition table base in
+// place to the ones of grown, which may only differ from base in the size of
+// its last partition
+func sfdiskGrowStageOptions(base, grown *disk.PartitionTable) (*osbuild.SfdiskStageOptions, error) {
+	if base.Type != grown.Type || base.UUID != grown.UUID || len(base.Partitions) != len(grown.Partitions) {
+		return nil, fmt.Errorf("partition tables of different layouts can't be changed in place")
+	}
+
+	last := 0
+	for idx, p := range grown.Partitions {
+		if p.Start > grown.Partitions[last].Start {
+			last = idx
+		}
+	}
+
+	partitions := make([]osbuild.Partition, len(grown.Partitions))
+	for idx, p := range grown.Partitions {
+		old := base.Partitions[idx]
+		if p.Start != old.Start || p.Type != old.Type || p.UUID != old.UUID || p.Name != old.Name || p.Size < old.Size {
+			return nil, fmt.Errorf("partition %d can't be changed in place", idx)
+		}
+		if p.Size != old.Size && idx != last {
+			return nil, fmt.Errorf("partition %d is not the last partition and can't grow", idx)
+		}
+		partitions[idx] = osbuild.Partition{
+			Attrs:    p.Attrs,
+			Bootable: p.Bootable,
+			Name:     p.Name,
+			Size:     p.Size,
+			Start:    p.Start,
+			Type:     p.Type,
+			UUID:     p.UUID,
+			Preserve: p.Size == old.Size,
+			Resize:   p.Size != old.Size,
+		}
+	}
+
+	return &osbuild.SfdiskStageOptions{
+		Label:      grown.Type,
+		UUID:       grown.UUID,
+		Partitions: partitions,
+		SectorSize: sectorSizeOption(grown),
+	}, nil
+}
+
+// sectorSizeOption returns the sector size of the partition table for the
+// sector-size options of stages and devices, which are only set when it
+// differs from the default of 512 bytes
+func sectorSizeOption(pt *disk.PartitionTable) *uint64 {
+	sectorSize := pt.GetSectorSize()
+	if sectorSize == 512 {
+		return nil
+	}
+	return &sectorSize
+}
+
+// loopbackDeviceOptions returns the options of a loopback device for the
+// whole disk image filename with the partition table pt. The device is
+// locked, so that loop devices are not allocated concurrently with other
+// users of them on the host.
+func loopbackDeviceOptions(filename string, pt *disk.PartitionTable) *osbuild.LoopbackDeviceOptions {
+	return &osbuild.LoopbackDeviceOptions{
+		Filename:   filename,
+		SectorSize: sectorSizeOption(pt),
+		Lock:       true,
+	}
+}
+
+// partitionLoopbackDeviceOptions returns the options of a loopback device
+// for partition p of the disk image that diskOptions are the options of
+func partitionLoopbackDeviceOptions(diskOptions *osbuild.LoopbackDeviceOptions, p disk.Partition) *osbuild.LoopbackDeviceOptions {
+	return &osbuild.LoopbackDeviceOptions{
+		Filename:   diskOptions.Filename,
+		Start:      p.Start,
+		Size:       p.Size,
+		SectorSize: diskOptions.SectorSize,
+		Lock:       diskOptions.Lock,
+	}
+}
+
+// partitionDevices returns the device named name that gives access to the
+// contents of the partition p and the devices it is on by name. The contents
+// of encrypted partitions are accessed through a LUKS2 device on the
+// loopback device of the partition.
+func partitionDevices(diskOptions *osbuild.LoopbackDeviceOptions, p disk.Partition, name string) (*osbuild.Device, osbuild.Devices) {
+	loopback := osbuild.NewLoopbackDevice(partitionLoopbackDeviceOptions(diskOptions, p))
+	if p.LUKS == nil {
+		return loopback, nil
+	}
+	parent := name + "-luks"
+	luks := osbuild.NewLUKS2Device(parent, &osbuild.LUKS2DeviceOptions{Passphrase: p.LUKS.Passphrase})
+	return luks, osbuild.Devices{parent: *loopback}
+}
+
+// copyFSTreeSettings changes how copyFSTreeOptions copies a tree. The zero
+// value copies all attributes of the files and overwrites existing files.
+type copyFSTreeSettings struct {
+	// Attributes of the files that are preserved, all if unset
+	Preserve []osbuild.CopyAttribute
+	// Remove existing files before copying over them
+	RemoveDestination bool
+	// Mountpoints whose filesystems are mounted read-only, which keeps the
+	// stages that use the mounts from changing them
+	ReadOnly []string
+}
+
 // copyFSTreeOptions creates the options, inputs, devices, and mounts properties
 // for an org.osbuild.copy stage for a given source tree using a partition
 // table description to define the mounts
-func copyFSTreeOptions(inputName, inputPipeline string, pt *disk.PartitionTable, device *osbuild.Device) (
+func copyFSTreeOptions(inputName, inputPipeline string, pt *disk.PartitionTable, device *osbuild.Device, settings copyFSTreeSettings) (
 	*osbuild.CopyStageOptions,
 	*osbuild.Devices,
 	*osbuild.Mounts,
@@ -444,45 +1071,56 @@ func copyFSTreeOptions(inputName, inputPipeline string, pt *disk.PartitionTable,
 		panic("copyStageOptions: failed to convert device options to loopback options")
 	}
 
+	readOnly := make(map[string]bool, len(settings.ReadOnly))
+	for _, mountpoint := range settings.ReadOnly {
+		readOnly[mountpoint] = true
+	}
+
+	names := mountNames(pt)
 	devices := make(map[string]osbuild.Device, len(pt.Partitions))
 	mounts := make([]osbuild.Mount, 0, len(pt.Partitions))
 	for _, p := range pt.Partitions {
+		if vg := p.VolumeGroup; vg != nil {
+			// the physical volume is named after its volume group
+			pvDevice, parents := partitionDevices(devOptions, p, vg.Name)
+			devices[vg.Name] = *pvDevice
+			for parentName, parent := range parents {
+				devices[parentName] = parent
+			}
+			for _, lv := range vg.LogicalVolumes {
+				devices[lv.Name] = *osbuild.NewLVM2LVDevice(vg.Name, &osbuild.LVM2LVDeviceOptions{Volume: lv.Name})
+				mounts = append(mounts, filesystemMounts(lv.Name, lv.Filesystem, names, readOnly)...)
+			}
+			continue
+		}
 		if p.Filesystem == nil {
 			// no filesystem for partition (e.g., BIOS boot)
 			continue
 		}
-		name := filepath.Base(p.Filesystem.Mountpoint)
-		if name == "/" {
-			name = "root"
+		if p.Filesystem.Type == disk.SwapFilesystemType {
+			// swap partitions are activated, not mounted
+			continue
 		}
-		devices[name] = *osbuild.NewLoopbackDevice(
-			&osbuild.LoopbackDeviceOptions{
-				Filename: devOptions.Filename,
-				Start:    p.Start,
-				Size:     p.Size,
-			},
-		)
-		var mount *osbuild.Mount
-		switch p.Filesystem.Type {
-		case "xfs":
-			mount = osbuild.NewXfsMount(name, name, p.Filesystem.Mountpoint)
-		case "vfat":
-			mount = osbuild.NewFATMount(name, name, p.Filesystem.Mountpoint)
-		case "ext4":
-			mount = osbuild.NewExt4Mount(name, name, p.Filesystem.Mountpoint)
-		case "btrfs":
-			mount = osbuild.NewBtrfsMount(name, name, p.Filesystem.Mountpoint)
-		default:
-			panic("unknown fs type " + p.Type)
-		}
-		mounts = append(mounts, *mount)
-	}
-
-	// sort the mounts, using < should just work because:
-	// - a parent directory should be always before its children:
-	//   / < /boot
-	// - the order of siblings doesn't matter
+		name := names[p.Filesystem.Mountpoint]
+		device, parents := partitionDevices(devOptions, p, name)
+		devices[name] = *device
+		for parentName, parent := range parents {
+			devices[parentName] = parent
+		}
+		mounts = append(mounts, filesystemMounts(name, p.Filesystem, names, readOnly)...)
+	}
+	for mountpoint := range readOnly {
+		panic("copyFSTreeOptions: no filesystem to mount read-only at " + mountpoint)
+	}
+
+	// a parent directory needs to be mounted before its children, which
+	// sorting by the number of path components ensures, e.g. / before /a/b
+	// even though "/a!" < "/a/b". The order of siblings doesn't matter, they
+	// are sorted by path to keep the manifests stable.
 	sort.Slice(mounts, func(i, j int) bool {
+		if di, dj := pathDepth(mounts[i].Target), pathDepth(mounts[j].Target); di != dj {
+			return di < dj
+		}
 		return mounts[i].Target < mounts[j].Target
 	})
 
@@ -492,8 +1130,10 @@ func copyFSTreeOptions(inputName, inputPipeline string, pt *disk.PartitionTable,
 	options := osbuild.CopyStageOptions{
 		Paths: []osbuild.CopyStagePath{
 			{
-				From: fmt.Sprintf("input://%s/", inputName),
-				To:   "mount://root/",
+				From:              fmt.Sprintf("input://%s/", inputName),
+				To:                "mount://root/",
+				RemoveDestination: settings.RemoveDestination,
+				Preserve:          settings.Preserve,
 			},
 		},
 	}
@@ -501,6 +1141,125 @@ func copyFSTreeOptions(inputName, inputPipeline string, pt *disk.PartitionTable,
 	return &options, &stageDevices, &stageMounts
 }
 
+// pathDepth returns the number of components of the absolute path p, 0 for
+// the root directory
+func pathDepth(p string) int {
+	p = filepath.Clean(p)
+	if p == "/" {
+		return 0
+	}
+	return strings.Count(p, "/")
+}
+
+// mountNames returns the names of the devices and mounts of the filesystems
+// of pt by mountpoint. The root filesystem is named root and the others after
+// the last component of their mountpoint, or after their whole mountpoint if
+// the last component isn't unique, e.g. /data/app and /var/app are named
+// data-app and var-app. Names that still collide get an index suffix.
+func mountNames(pt *disk.PartitionTable) map[string]string {
+	// names of logical volumes and volume groups are taken by their devices
+	taken := make(map[string]bool)
+	var mountpoints []string
+	addFilesystem := func(fs *disk.Filesystem) {
+		if fs == nil || fs.Type == disk.SwapFilesystemType {
+			return
+		}
+		// filesystems with subvolumes are mounted at one of them as well
+		mountpoints = append(mountpoints, fs.Mountpoint)
+		for _, sv := range fs.Subvolumes {
+			mountpoints = append(mountpoints, sv.Mountpoint)
+		}
+	}
+	for _, p := range pt.Partitions {
+		if vg := p.VolumeGroup; vg != nil {
+			taken[vg.Name] = true
+			for _, lv := range vg.LogicalVolumes {
+				taken[lv.Name] = true
+				addFilesystem(lv.Filesystem)
+			}
+			continue
+		}
+		addFilesystem(p.Filesystem)
+	}
+
+	baseName := func(mountpoint string) string {
+		if mountpoint == "/" {
+			return "root"
+		}
+		return filepath.Base(mountpoint)
+	}
+	counts := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, mountpoint := range mountpoints {
+		if !seen[mountpoint] {
+			seen[mountpoint] = true
+			counts[baseName(mountpoint)]++
+		}
+	}
+
+	names := make(map[string]string, len(counts))
+	if seen["/"] {
+		names["/"] = "root"
+		taken["root"] = true
+	}
+	for _, mountpoint := range mountpoints {
+		if _, ok := names[mountpoint]; ok {
+			continue
+		}
+		name := baseName(mountpoint)
+		if counts[name] > 1 || taken[name] {
+			name = strings.ReplaceAll(strings.Trim(mountpoint, "/"), "/", "-")
+		}
+		unique := name
+		for idx := 1; taken[unique]; idx++ {
+			unique = fmt.Sprintf("%s-%d", name, idx)
+		}
+		taken[unique] = true
+		names[mountpoint] = unique
+	}
+	return names
+}
+
+// filesystemMounts returns the mounts of the filesystem fs on device. The
+// mounts are named after their mountpoints by names. Mountpoints in readOnly
+// are mounted read-only and removed from it.
+func filesystemMounts(device string, fs *disk.Filesystem, names map[string]string, readOnly map[string]bool) []osbuild.Mount {
+	// btrfs filesystems with subvolumes are mounted once per subvolume
+	if len(fs.Subvolumes) > 0 {
+		mounts := make([]osbuild.Mount, 0, len(fs.Subvolumes))
+		for _, sv := range fs.Subvolumes {
+			mountOptions := []string{sv.MountOption()}
+			if readOnly[sv.Mountpoint] {
+				mountOptions = append(mountOptions, osbuild.MountOptionReadOnly)
+				delete(readOnly, sv.Mountpoint)
+			}
+			mounts = append(mounts, *osbuild.NewBtrfsMount(names[sv.Mountpoint], device, sv.Mountpoint, mountOptions...))
+		}
+		return mounts
+	}
+
+	var mountOptions []string
+	if readOnly[fs.Mountpoint] {
+		mountOptions = append(mountOptions, osbuild.MountOptionReadOnly)
+		delete(readOnly, fs.Mountpoint)
+	}
+	name := names[fs.Mountpoint]
+	var mount *osbuild.Mount
+	switch fs.Type {
+	case "xfs":
+		mount = osbuild.NewXfsMount(name, device, fs.Mountpoint, mountOptions...)
+	case "vfat":
+		mount = osbuild.NewFATMount(name, device, fs.Mountpoint, mountOptions...)
+	case "ext4":
+		mount = osbuild.NewExt4Mount(name, device, fs.Mountpoint, mountOptions...)
+	case "btrfs":
+		mount = osbuild.NewBtrfsMount(name, device, fs.Mountpoint, mountOptions...)
+	default:
+		panic("unknown fs type " + fs.Type)
+	}
+	return []osbuild.Mount{*mount}
+}
+
 func grub2InstStageOptions(filename string, pt *disk.PartitionTable, platform string) *osbuild.Grub2InstStageOptions {
 	bootPartIndex := pt.BootPartitionIndex()
 	if bootPartIndex == -1 {
@@ -524,12 +1283,20 @@ func grub2InstStageOptions(filename string, pt *disk.PartitionTable, platform st
 		Path:      prefixPath,
 	}
 
+	// the core image is written to the PReP boot partition on ppc64le and
+	// to the first partition, the BIOS boot partition, otherwise
+	location := pt.Partitions[0].Start
+	if prepIndex := pt.PRePBootPartitionIndex(); prepIndex != -1 {
+		location = pt.Partitions[prepIndex].Start
+	}
+
 	return &osbuild.Grub2InstStageOptions{
-		Filename: filename,
-		Platform: platform,
-		Location: pt.Partitions[0].Start,
-		Core:     core,
-		Prefix:   prefix,
+		Filename:   filename,
+		Platform:   platform,
+		Location:   location,
+		Core:       core,
+		Prefix:     prefix,
+		SectorSize: sectorSizeOption(pt),
 	}
 }
 
@@ -540,41 +1307,141 @@ func ziplInstStageOptions(kernel string, pt *disk.PartitionTable) *osbuild.ZiplI
 	}
 
 	return &osbuild.ZiplInstStageOptions{
-		Kernel:   kernel,
-		Location: pt.Partitions[bootPartIndex].Start,
+		Kernel:     kernel,
+		Location:   pt.Partitions[bootPartIndex].Start,
+		SectorSize: sectorSizeOption(pt),
+	}
+}
+
+// qcow2Options returns the qcow2 format options of the image type, with the
+// compression of the image options if they override it.
+func qcow2Options(t *imageType, options distro.ImageOptions) osbuild.Qcow2Options {
+	compression := t.qcow2Compression
+	switch options.Qcow2Compression {
+	case "":
+	case distro.Qcow2CompressionNone:
+		compression = ""
+	default:
+		compression = options.Qcow2Compression
+	}
+	return osbuild.Qcow2Options{
+		Compat:      t.qcow2Compat,
+		Compression: compression,
 	}
 }
 
-func qemuStageOptions(filename, format, compat string) *osbuild.QEMUStageOptions {
+// qemuStageOptions creates the options for an org.osbuild.qemu stage that
+// converts an image to format. formatOptions must be the options type of
+// format; nil selects the defaults of the format.
+func qemuStageOptions(filename, format string, formatOptions osbuild.QEMUFormatOptions) (*osbuild.QEMUStageOptions, error) {
 	var options osbuild.QEMUFormatOptions
 	switch format {
 	case "qcow2":
-		options = osbuild.Qcow2Options{
-			Type:   "qcow2",
-			Compat: compat,
+		o, ok := formatOptions.(osbuild.Qcow2Options)
+		if !ok && formatOptions != nil {
+			return nil, fmt.Errorf("invalid options for qemu format %s: %#v", format, formatOptions)
 		}
+		o.Type = format
+		options = o
 	case "vpc":
-		options = osbuild.VPCOptions{
-			Type: "vpc",
+		o, ok := formatOptions.(osbuild.VPCOptions)
+		if !ok && formatOptions != nil {
+			return nil, fmt.Errorf("invalid options for qemu format %s: %#v", format, formatOptions)
 		}
+		o.Type = format
+		options = o
 	case "vmdk":
-		options = osbuild.VMDKOptions{
-			Type: "vmdk",
+		o, ok := formatOptions.(osbuild.VMDKOptions)
+		if !ok && formatOptions != nil {
+			return nil, fmt.Errorf("invalid options for qemu format %s: %#v", format, formatOptions)
+		}
+		o.Type = format
+		options = o
+	case "vhdx":
+		o, ok := formatOptions.(osbuild.VHDXOptions)
+		if !ok && formatOptions != nil {
+			return nil, fmt.Errorf("invalid options for qemu format %s: %#v", format, formatOptions)
 		}
+		o.Type = format
+		options = o
 	default:
-		panic("unknown format in qemu stage: " + format)
+		return nil, fmt.Errorf("unknown format in qemu stage: %s", format)
 	}
 
 	return &osbuild.QEMUStageOptions{
 		Filename: filename,
 		Format:   options,
-	}
+	}, nil
 }
 
-func kernelCmdlineStageOptions(rootUUID string, kernelOptions string) *osbuild.KernelCmdlineStageOptions {
+// kernelCmdlineStageOptions creates the options of the
+// org.osbuild.kernel-cmdline stage, identifying the root filesystem according
+// to the DeviceIDMode of pt.
+func kernelCmdlineStageOptions(pt *disk.PartitionTable, kernelOptions string) *osbuild.KernelCmdlineStageOptions {
+	rootFilesystem := pt.RootFilesystem()
+	if rootFilesystem == nil {
+		panic("root partition must be defined for kernel-cmdline stage, this is a programming error")
+	}
+
+	kernelOptions = rootKernelOptions(pt, kernelOptions)
+	if pt.DeviceIDMode == "" || pt.DeviceIDMode == disk.DeviceIDFilesystemUUID {
+		return &osbuild.KernelCmdlineStageOptions{
+			RootFsUUID: rootFilesystem.UUID,
+			KernelOpts: kernelOptions,
+		}
+	}
+
 	return &osbuild.KernelCmdlineStageOptions{
-		RootFsUUID: rootUUID,
-		KernelOpts: kernelOptions,
+		KernelOpts: strings.TrimSpace("root=" + pt.RootDeviceSpec() + " " + kernelOptions),
+	}
+}
+
+// rootKernelOptions prepends the arguments the initrd needs to mount the
+// root filesystem of pt to kernelOptions: rd.luks.uuid= if the root partition
+// is encrypted and rootflags= if the root filesystem is mounted from a btrfs
+// subvolume
+func rootKernelOptions(pt *disk.PartitionTable, kernelOptions string) string {
+	if sv := pt.RootSubvolume(); sv != nil {
+		kernelOptions = strings.TrimSpace("rootflags=" + sv.MountOption() + " " + kernelOptions)
+	}
+	if rootPartition := pt.RootPartition(); rootPartition != nil && rootPartition.LUKS != nil {
+		kernelOptions = strings.TrimSpace("rd.luks.uuid=" + rootPartition.LUKS.MapperName() + " " + kernelOptions)
+	}
+	return kernelOptions
+}
+
+// crypttabStageOptions returns the options of the org.osbuild.crypttab stage
+// that unlocks the encrypted partitions of pt at boot, or nil if there are
+// none
+func crypttabStageOptions(pt *disk.PartitionTable) *osbuild.CrypttabStageOptions {
+	var volumes []osbuild.CrypttabVolume
+	for _, p := range pt.Partitions {
+		if p.LUKS == nil {
+			continue
+		}
+		volumes = append(volumes, osbuild.CrypttabVolume{
+			Volume: p.LUKS.MapperName(),
+			UUID:   p.LUKS.UUID,
+		})
+	}
+	if len(volumes) == 0 {
+		return nil
+	}
+	return &osbuild.CrypttabStageOptions{Volumes: volumes}
+}
+
+// ziplStageOptions returns the options of the zipl stage, whose kernel
+// parameters are the ones the kernel-cmdline stage sets for the same
+// partition table and kernel options
+func ziplStageOptions(pt *disk.PartitionTable, kernelOptions string) *osbuild.ZiplStageOptions {
+	cmdline := kernelCmdlineStageOptions(pt, kernelOptions)
+	parameters := cmdline.KernelOpts
+	if cmdline.RootFsUUID != "" {
+		parameters = strings.TrimSpace("root=UUID=" + cmdline.RootFsUUID + " " + parameters)
+	}
+
+	return &osbuild.ZiplStageOptions{
+		KernelOptions: parameters,
 	}
 }
 
@@ -592,6 +1459,23 @@ func nginxConfigStageOptions(path, htmlRoot, listen string) *osbuild.NginxConfig
 	}
 }
 
+// wslConfStageOptions returns the options of the WSL configuration of the
+// image, the first user of the blueprint is the default user of the
+// distribution and WSL does not overwrite the hostname of the image
+func wslConfStageOptions(users []blueprint.UserCustomization) *osbuild.WSLConfStageOptions {
+	options := &osbuild.WSLConfStageOptions{
+		Network: &osbuild.WSLConfNetworkOptions{
+			GenerateHostname: common.BoolToPtr(false),
+		},
+	}
+	if len(users) > 0 {
+		options.User = &osbuild.WSLConfUserOptions{
+			Default: users[0].Name,
+		}
+	}
+	return options
+}
+
 func chmodStageOptions(path, mode string, recursive bool) *osbuild.ChmodStageOptions {
 	return &osbuild.ChmodStageOptions{
 		Items: map[string]osbuild.ChmodStagePathOptions{
@@ -600,6 +1484,718 @@ func chmodStageOptions(path, mode string, recursive bool) *osbuild.ChmodStageOpt
 	}
 }
 
+// pathOwnership is the owner, group, and mode a path of the image is set to.
+// Empty fields are left unchanged.
+type pathOwnership struct {
+	Path      string
+	User      string
+	Group     string
+	Mode      string
+	Recursive bool
+}
+
+// ownershipStages returns an org.osbuild.chown stage followed by an
+// org.osbuild.chmod stage that set the ownership and the modes of the paths.
+// Ownership is changed first, because changing it clears setuid and setgid
+// bits. Stages without any items are omitted; when a path is given more than
+// once, its last entry is used.
+func ownershipStages(paths []pathOwnership) []*osbuild.Stage {
+	chown := make(map[string]osbuild.ChownStagePathOptions)
+	chmod := make(map[string]osbuild.ChmodStagePathOptions)
+	for _, p := range paths {
+		delete(chown, p.Path)
+		delete(chmod, p.Path)
+		if p.User != "" || p.Group != "" {
+			chown[p.Path] = osbuild.ChownStagePathOptions{
+				User:      osbuild.NewChownPrincipal(p.User),
+				Group:     osbuild.NewChownPrincipal(p.Group),
+				Recursive: p.Recursive,
+			}
+		}
+		if p.Mode != "" {
+			chmod[p.Path] = osbuild.ChmodStagePathOptions{
+				Mode:      p.Mode,
+				Recursive: p.Recursive,
+			}
+		}
+	}
+
+	var stages []*osbuild.Stage
+	if len(chown) > 0 {
+		stages = append(stages, osbuild.NewChownStage(&osbuild.ChownStageOptions{Items: chown}))
+	}
+	if len(chmod) > 0 {
+		stages = append(stages, osbuild.NewChmodStage(&osbuild.ChmodStageOptions{Items: chmod}))
+	}
+	return stages
+}
+
+// directoryStages returns the stages that create the directories of the
+// customizations in the given order and set their ownership and modes.
+// Directories that already exist are not an error, so the ownership and the
+// mode of directories of packages can be changed too.
+func directoryStages(dirs []blueprint.DirectoryCustomization) ([]*osbuild.Stage, error) {
+	paths := make([]osbuild.Path, len(dirs))
+	ownership := make([]pathOwnership, len(dirs))
+	for idx, dir := range dirs {
+		paths[idx] = osbuild.Path{
+			Path:    dir.Path,
+			Parents: dir.EnsureParents,
+			ExistOk: true,
+		}
+
+		mode := dir.Mode
+		if mode == "" {
+			mode = "0755"
+		}
+		ownership[idx] = pathOwnership{
+			Path:  dir.Path,
+			User:  dir.User,
+			Group: dir.Group,
+			Mode:  mode,
+		}
+	}
+
+	mkdirOptions, err := mkdirStageOptions(paths...)
+	if err != nil {
+		return nil, err
+	}
+	stages := []*osbuild.Stage{osbuild.NewMkdirStage(mkdirOptions)}
+	return append(stages, ownershipStages(ownership)...), nil
+}
+
+// fileStages returns the stages that create the files of the customizations
+// from the items of the inline source: the parent directories are created,
+// the files are copied into the tree, and their ownership and modes are set.
+func fileStages(files []blueprint.FileCustomization) ([]*osbuild.Stage, error) {
+	const inputName = "inlinefile"
+
+	var dirs []osbuild.Path
+	seenDirs := make(map[string]bool)
+	var checksums []string
+	seenChecksums := make(map[string]bool)
+	copyOptions := &osbuild.CopyStageOptions{}
+	var ownership []pathOwnership
+	for _, file := range files {
+		if dir := path.Dir(file.Path); !seenDirs[dir] {
+			seenDirs[dir] = true
+			dirs = append(dirs, osbuild.Path{Path: dir, Parents: true, ExistOk: true})
+		}
+
+		checksum := osbuild.InlineSourceChecksum([]byte(file.Data))
+		if !seenChecksums[checksum] {
+			seenChecksums[checksum] = true
+			checksums = append(checksums, checksum)
+		}
+		copyOptions.Paths = append(copyOptions.Paths, osbuild.CopyStagePath{
+			From:              fmt.Sprintf("input://%s/%s", inputName, checksum),
+			To:                "tree://" + file.Path,
+			RemoveDestination: true,
+		})
+
+		mode := file.Mode
+		if mode == "" {
+			mode = "0644"
+		}
+		ownership = append(ownership, pathOwnership{
+			Path:  file.Path,
+			User:  file.User,
+			Group: file.Group,
+			Mode:  mode,
+		})
+	}
+
+	mkdirOptions, err := mkdirStageOptions(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	stages := []*osbuild.Stage{
+		osbuild.NewMkdirStage(mkdirOptions),
+		osbuild.NewCopyStageSimple(copyOptions, copyInlineFileInputs(inputName, checksums)),
+	}
+	return append(stages, ownershipStages(ownership)...), nil
+}
+
+// sudoersFilePath is the path of the sudoers file of the customizations
+const sudoersFilePath = "/etc/sudoers.d/blueprint-customizations"
+
+// sudoersFile returns the sudoers file with the sudo rules of the users and
+// groups of the customizations, or nil if there are none
+func sudoersFile(rules []blueprint.SudoRule) *blueprint.FileCustomization {
+	if len(rules) == 0 {
+		return nil
+	}
+	var data strings.Builder
+	data.WriteString("# Created by osbuild-composer from the blueprint customizations\n")
+	for _, rule := range rules {
+		name := rule.Name
+		if rule.Group {
+			name = "%" + name
+		}
+		tag := ""
+		if rule.Nopasswd {
+			tag = "NOPASSWD: "
+		}
+		fmt.Fprintf(&data, "%s ALL=(ALL) %sALL\n", name, tag)
+	}
+	return &blueprint.FileCustomization{
+		Path: sudoersFilePath,
+		Mode: "0440",
+		Data: data.String(),
+	}
+}
+
+// bannerFiles returns the files with the login banners of the
+// customizations. The files of ostree commits are moved to /usr/etc when the
+// tree is committed.
+func bannerFiles(banner *blueprint.BannerCustomization) []blueprint.FileCustomization {
+	if banner == nil {
+		return nil
+	}
+	var files []blueprint.FileCustomization
+	if banner.Motd != "" {
+		files = append(files, blueprint.FileCustomization{Path: "/etc/motd", Data: banner.Motd})
+	}
+	if banner.Issue != "" {
+		files = append(files, blueprint.FileCustomization{Path: "/etc/issue", Data: banner.Issue})
+	}
+	return files
+}
+
+// caCertFiles returns the files with the CA certificates of the
+// customizations in the anchors of the trust store
+func caCertFiles(certs []blueprint.CACertCustomization) []blueprint.FileCustomization {
+	var files []blueprint.FileCustomization
+	for idx, cert := range certs {
+		files = append(files, blueprint.FileCustomization{
+			Path: fmt.Sprintf("/etc/pki/ca-trust/source/anchors/blueprint-customization-%d.pem", idx),
+			Data: cert.PEM,
+		})
+	}
+	return files
+}
+
+// proxyEnvironment returns the environment variables of the proxy
+// customization, in both cases as not all tools read the lowercase ones
+func proxyEnvironment(proxy *blueprint.ProxyCustomization) []string {
+	env := []string{
+		"http_proxy=" + proxy.URL,
+		"https_proxy=" + proxy.URL,
+		"HTTP_PROXY=" + proxy.URL,
+		"HTTPS_PROXY=" + proxy.URL,
+	}
+	if len(proxy.NoProxy) > 0 {
+		noProxy := strings.Join(proxy.NoProxy, ",")
+		env = append(env, "no_proxy="+noProxy, "NO_PROXY="+noProxy)
+	}
+	return env
+}
+
+// proxyEnvironmentFile returns /etc/environment with the variables of the
+// proxy customization, which pam_env sets for logins, or nil if there is no
+// proxy
+func proxyEnvironmentFile(proxy *blueprint.ProxyCustomization) *blueprint.FileCustomization {
+	if proxy == nil {
+		return nil
+	}
+	return &blueprint.FileCustomization{
+		Path: "/etc/environment",
+		Data: strings.Join(proxyEnvironment(proxy), "\n") + "\n",
+	}
+}
+
+// dnfConfigStageOptions returns the options of the org.osbuild.dnf.config
+// stage which sets the DNF configuration of the customizations, or nil if
+// they don't change it. The proxy of the DNF customization takes precedence
+// over the one of the proxy customization.
+func dnfConfigStageOptions(dnf *blueprint.DNFCustomization, proxy *blueprint.ProxyCustomization) *osbuild.DNFConfigStageOptions {
+	main := &osbuild.DNFConfigMain{}
+	if proxy != nil {
+		main.Proxy = proxy.URL
+	}
+	if dnf != nil {
+		if dnf.Proxy != "" {
+			main.Proxy = dnf.Proxy
+		}
+		main.InstallWeakDeps = dnf.InstallWeakDeps
+		main.ExcludePkgs = dnf.ExcludePkgs
+	}
+	if main.Proxy == "" && main.InstallWeakDeps == nil && len(main.ExcludePkgs) == 0 {
+		return nil
+	}
+	return &osbuild.DNFConfigStageOptions{
+		Config: &osbuild.DNFConfig{
+			Main: main,
+		},
+	}
+}
+
+// proxyStages returns the stages that configure subscription-manager if the
+// image has it, and the first boot commands, e.g. the registration with
+// Insights, to use the proxy of the customization
+func proxyStages(proxy *blueprint.ProxyCustomization, rhsm bool) []*osbuild.Stage {
+	var stages []*osbuild.Stage
+	if rhsm {
+		// the URL was validated
+		u, _ := url.Parse(proxy.URL)
+		stages = append(stages, osbuild.NewRHSMStage(&osbuild.RHSMStageOptions{
+			SubMan: &osbuild.RHSMStageOptionsSubMan{
+				Server: &osbuild.SubManConfigServerSection{
+					ProxyHostname: u.Hostname(),
+					ProxyPort:     u.Port(),
+					ProxyScheme:   u.Scheme,
+					NoProxy:       strings.Join(proxy.NoProxy, ","),
+				},
+			},
+		}))
+	}
+	return append(stages, osbuild.NewSystemdUnitStage(&osbuild.SystemdUnitStageOptions{
+		Unit:   "osbuild-first-boot.service",
+		Dropin: "10-proxy.conf",
+		Config: osbuild.SystemdServiceUnitDropin{
+			Service: &osbuild.SystemdUnitServiceSection{
+				Environment: strings.Join(proxyEnvironment(proxy), " "),
+			},
+		},
+	}))
+}
+
+// repositoryGPGKeyPath returns the path in the image of the inline GPG key
+// with index idx of the repository with the given ID
+func repositoryGPGKeyPath(id string, idx int) string {
+	return fmt.Sprintf("/etc/pki/rpm-gpg/RPM-GPG-KEY-%s-%d", id, idx)
+}
+
+// repositoryGPGKeyFiles returns the files with the inline GPG keys of the
+// repositories which are written to the image
+func repositoryGPGKeyFiles(repos []blueprint.RepositoryCustomization) []blueprint.FileCustomization {
+	var files []blueprint.FileCustomization
+	for _, repo := range repos {
+		if !repo.InstallToImage {
+			continue
+		}
+		for idx, key := range repo.InlineGPGKeys() {
+			files = append(files, blueprint.FileCustomization{
+				Path: repositoryGPGKeyPath(repo.ID, idx),
+				Data: key,
+			})
+		}
+	}
+	return files
+}
+
+// yumReposStageOptions returns the options of the org.osbuild.yum.repos
+// stage which writes the repository to /etc/yum.repos.d/<id>.repo. Inline
+// GPG keys are referenced by the paths of their files.
+func yumReposStageOptions(repo blueprint.RepositoryCustomization) *osbuild.YumReposStageOptions {
+	yumRepo := osbuild.YumRepository{
+		ID:         repo.ID,
+		Name:       repo.Name,
+		Metalink:   repo.Metalink,
+		Mirrorlist: repo.Mirrorlist,
+		GPGCheck:   common.BoolToPtr(repo.GPGCheck),
+	}
+	if repo.BaseURL != "" {
+		yumRepo.BaseURLs = []string{repo.BaseURL}
+	}
+	inline := 0
+	for _, key := range repo.GPGKeys {
+		if blueprint.IsInlineGPGKey(key) {
+			key = "file://" + repositoryGPGKeyPath(repo.ID, inline)
+			inline++
+		}
+		yumRepo.GPGKey = append(yumRepo.GPGKey, key)
+	}
+	return &osbuild.YumReposStageOptions{
+		Filename: repo.ID + ".repo",
+		Repos:    []osbuild.YumRepository{yumRepo},
+	}
+}
+
+// customizationFiles returns the files of the customizations and the files
+// generated from other customizations
+func customizationFiles(c *blueprint.Customizations) []blueprint.FileCustomization {
+	var generated []blueprint.FileCustomization
+	if sudoers := sudoersFile(c.GetSudoRules()); sudoers != nil {
+		generated = append(generated, *sudoers)
+	}
+	generated = append(generated, bannerFiles(c.GetBanner())...)
+	generated = append(generated, caCertFiles(c.GetCACerts())...)
+	if environment := proxyEnvironmentFile(c.GetProxy()); environment != nil {
+		generated = append(generated, *environment)
+	}
+	generated = append(generated, repositoryGPGKeyFiles(c.GetRepositories())...)
+	if len(generated) == 0 {
+		return c.GetFiles()
+	}
+	return append(append([]blueprint.FileCustomization{}, c.GetFiles()...), generated...)
+}
+
+// ostreeContainerStoragePath is the container storage of ostree commits. The
+// commits don't keep /var, so the images are stored in a read-only image
+// store under /usr instead.
+const ostreeContainerStoragePath = "/usr/share/containers/storage"
+
+// ostreeContainerStorageConfFile adds the image store of ostree commits to
+// the configuration of the container storage.
+var ostreeContainerStorageConfFile = blueprint.FileCustomization{
+	Path: "/etc/containers/storage.conf",
+	Data: `# Created by osbuild-composer, the container images of the blueprint are
+# stored in an additional image store of the ostree commit
+[storage]
+driver = "overlay"
+runroot = "/run/containers/storage"
+graphroot = "/var/lib/containers/storage"
+
+[storage.options]
+additionalimagestores = ["` + ostreeContainerStoragePath + `"]
+`,
+}
+
+// skopeoStage returns the org.osbuild.skopeo stage which copies the
+// container images into the container storage at storagePath, or into the
+// default storage if it is empty
+func skopeoStage(containers []container.Spec, storagePath string) *osbuild.Stage {
+	references := make(osbuild.ContainersInputReferences)
+	for _, c := range containers {
+		references[c.Digest] = osbuild.ContainersInputReference{Name: c.LocalName}
+	}
+	return osbuild.NewSkopeoStage(
+		&osbuild.SkopeoStageOptions{
+			Destination: osbuild.SkopeoDestination{
+				Type:        "containers-storage",
+				StoragePath: storagePath,
+			},
+		},
+		&osbuild.SkopeoStageInputs{
+			Images: osbuild.NewContainersInputForSources(references),
+		},
+	)
+}
+
+// systemFIPSFile marks the FIPS modules of the image as installed, like
+// fips-mode-setup does.
+var systemFIPSFile = blueprint.FileCustomization{
+	Path: "/etc/system-fips",
+	Data: "# FIPS module installation complete\n",
+}
+
+// sysctlStageOptions returns the options of the org.osbuild.sysctld stage
+// which sets the kernel parameters of the customizations, sorted by their
+// keys, or nil if there are none
+func sysctlStageOptions(sysctl []blueprint.SysctlCustomization) *osbuild.SysctldStageOptions {
+	if len(sysctl) == 0 {
+		return nil
+	}
+	config := make([]osbuild.SysctldConfigLine, len(sysctl))
+	for idx, entry := range sysctl {
+		config[idx] = osbuild.SysctldConfigLine{Key: entry.Key, Value: entry.Value}
+	}
+	sort.Slice(config, func(i, j int) bool { return config[i].Key < config[j].Key })
+	return osbuild.NewSysctldStageOptions("90-osbuild.conf", config)
+}
+
+// udevRulesStageOptions returns the options of the org.osbuild.udev.rules
+// stage which adds the rules file of the customization to /etc/udev/rules.d/.
+// The rules of the customization must be valid.
+func udevRulesStageOptions(rules blueprint.UdevRulesCustomization) *osbuild.UdevRulesStageOptions {
+	items := make(osbuild.UdevRules, 0, len(rules.Rules))
+	for _, line := range rules.Rules {
+		ops, err := blueprint.ParseUdevRule(line)
+		if err != nil {
+			panic(fmt.Sprintf("invalid udev rule %q: %v", line, err))
+		}
+		if ops == nil {
+			comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+			items = append(items, osbuild.NewUdevRuleComment(comment))
+			continue
+		}
+		rule := make(osbuild.UdevRule, len(ops))
+		for idx, op := range ops {
+			rule[idx] = osbuild.UdevOp{
+				Key:   osbuild.UdevKey{Key: op.Key, Arg: op.Arg},
+				Op:    op.Op,
+				Value: op.Value,
+			}
+		}
+		items = append(items, rule)
+	}
+	return &osbuild.UdevRulesStageOptions{
+		Filename: path.Join("/etc/udev/rules.d", rules.Filename),
+		Rules:    items,
+	}
+}
+
+// kernelModulesBlacklist returns the kernel modules blacklisted by the kernel
+// customization, without duplicates
+func kernelModulesBlacklist(kernel *blueprint.KernelCustomization) []string {
+	if kernel == nil || kernel.Modules == nil {
+		return nil
+	}
+	return appendUnique(nil, kernel.Modules.Blacklist...)
+}
+
+// modprobeBlacklistStageOptions returns the options of the org.osbuild.modprobe
+// stage which blacklists the given kernel modules, or nil if there are none
+func modprobeBlacklistStageOptions(blacklist []string) *osbuild.ModprobeStageOptions {
+	if len(blacklist) == 0 {
+		return nil
+	}
+	commands := make(osbuild.ModprobeConfigCmdList, len(blacklist))
+	for idx, module := range blacklist {
+		commands[idx] = osbuild.NewModprobeConfigCmdBlacklist(module)
+	}
+	return &osbuild.ModprobeStageOptions{
+		Filename: "blacklist-customizations.conf",
+		Commands: commands,
+	}
+}
+
+// kernelModulesBlacklistStages returns the stages which keep the blacklisted
+// kernel modules of the kernel customization from being loaded: they are
+// blacklisted in the modprobe configuration and omitted from initramfs
+// images dracut builds in the image later on
+func kernelModulesBlacklistStages(kernel *blueprint.KernelCustomization) []*osbuild.Stage {
+	blacklist := kernelModulesBlacklist(kernel)
+	if len(blacklist) == 0 {
+		return nil
+	}
+	return []*osbuild.Stage{
+		osbuild.NewModprobeStage(modprobeBlacklistStageOptions(blacklist)),
+		osbuild.NewDracutConfStage(&osbuild.DracutConfStageOptions{
+			Filename: "50-blacklist-customizations.conf",
+			Config: osbuild.DracutConfigFile{
+				OmitDrivers: blacklist,
+			},
+		}),
+	}
+}
+
+// imageKernelOptions returns the kernel options of a bootable image: the ones
+// of the image type without the arguments the kernel customization removes,
+// plus the arguments that blacklist kernel modules in the initramfs, reserve
+// memory for kdump, make SELinux permissive, and enable FIPS mode on pt
+func imageKernelOptions(t *imageType, pt *disk.PartitionTable, kernel *blueprint.KernelCustomization, selinux *blueprint.SELinuxCustomization, kdump *blueprint.KdumpCustomization, fips bool) string {
+	kernelOptions := t.kernelOptions
+	if kernel != nil {
+		kernelOptions = removeKernelOptions(kernelOptions, kernel.Remove)
+	}
+	if kdump != nil {
+		// the customization replaces the crashkernel= of the image type
+		kernelOptions = removeKernelOptions(kernelOptions, []string{"crashkernel"})
+		if crashkernel := kdump.CrashkernelArg(); crashkernel != "" {
+			kernelOptions = strings.TrimSpace(kernelOptions + " " + crashkernel)
+		}
+	}
+	if blacklist := kernelModulesBlacklist(kernel); len(blacklist) > 0 {
+		kernelOptions = strings.TrimSpace(kernelOptions + " rd.driver.blacklist=" + strings.Join(blacklist, ","))
+	}
+	return fipsKernelOptions(selinuxKernelOptions(kernelOptions, selinux), fips, pt)
+}
+
+// removeKernelOptions removes the arguments with one of the given keys, the
+// part of an argument before "=", from kernelOptions
+func removeKernelOptions(kernelOptions string, keys []string) string {
+	if len(keys) == 0 {
+		return kernelOptions
+	}
+	remove := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		remove[key] = true
+	}
+	var kept []string
+	for _, option := range strings.Fields(kernelOptions) {
+		if !remove[strings.SplitN(option, "=", 2)[0]] {
+			kept = append(kept, option)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// dedupeKernelOptions removes repeated arguments from kernelOptions, keeping
+// the first of each
+func dedupeKernelOptions(kernelOptions string) string {
+	return strings.Join(appendUnique(nil, strings.Fields(kernelOptions)...), " ")
+}
+
+// mediacheckKernelOptions appends the option to kernelOptions that makes the
+// initrd of the installer verify the checksum implanted in the ISO, if
+// mediacheck is set.
+func mediacheckKernelOptions(kernelOptions string, mediacheck bool) string {
+	if !mediacheck {
+		return kernelOptions
+	}
+	return strings.TrimSpace(kernelOptions + " rd.live.check")
+}
+
+// fipsKernelOptions appends the argument that enables FIPS mode to
+// kernelOptions if fips is set. The integrity check of the kernel needs the
+// boot= argument if /boot is a separate partition of pt.
+func fipsKernelOptions(kernelOptions string, fips bool, pt *disk.PartitionTable) string {
+	if !fips {
+		return kernelOptions
+	}
+	kernelOptions = strings.TrimSpace(kernelOptions + " fips=1")
+	if pt != nil && pt.BootPartition() != nil {
+		kernelOptions += " boot=" + pt.DeviceSpec(pt.BootPartitionIndex())
+	}
+	return kernelOptions
+}
+
+// fipsStages returns the stages that enable FIPS mode in the tree: the
+// initrd of the kernel is rebuilt with the fips dracut module, which later
+// rebuilds keep through a dracut configuration file, /etc/system-fips is
+// created, and the system-wide crypto policy is set to FIPS.
+func fipsStages(kernelVer string) ([]*osbuild.Stage, error) {
+	stages := []*osbuild.Stage{
+		osbuild.NewDracutConfStage(&osbuild.DracutConfStageOptions{
+			Filename: "40-fips.conf",
+			Config: osbuild.DracutConfigFile{
+				AddModules: []string{"fips"},
+			},
+		}),
+		osbuild.NewDracutStage(&osbuild.DracutStageOptions{
+			Kernel:     []string{kernelVer},
+			AddModules: []string{"fips"},
+		}),
+	}
+	files, err := fileStages([]blueprint.FileCustomization{systemFIPSFile})
+	if err != nil {
+		return nil, err
+	}
+	stages = append(stages, files...)
+	return append(stages, osbuild.NewUpdateCryptoPoliciesStage(&osbuild.UpdateCryptoPoliciesStageOptions{
+		Policy: "FIPS",
+	})), nil
+}
+
+// ignitionConfigPath is where Ignition looks for the configuration of bare
+// metal machines in the boot partition on first boot
+const ignitionConfigPath = "/boot/ignition/config.ign"
+
+// ignitionConfigFile returns the file of the embedded Ignition configuration
+// in the boot partition, or nil if no configuration is embedded.
+func ignitionConfigFile(ignition *blueprint.IgnitionCustomization) *blueprint.FileCustomization {
+	config := ignition.EmbeddedConfig()
+	if config == nil {
+		return nil
+	}
+	return &blueprint.FileCustomization{
+		Path: ignitionConfigPath,
+		Mode: "0600",
+		Data: string(config),
+	}
+}
+
+// ostreeDeployKernelOptions returns the kernel arguments of the deployment of
+// an edge image
+func ostreeDeployKernelOptions(ignition *blueprint.IgnitionCustomization, kdump *blueprint.KdumpCustomization) []string {
+	opts := append([]string{
+		"console=tty0",
+		"console=ttyS0",
+	}, ignitionKernelOptions(ignition)...)
+	if crashkernel := kdump.CrashkernelArg(); crashkernel != "" {
+		opts = append(opts, crashkernel)
+	}
+	return opts
+}
+
+// ignitionKernelOptions returns the kernel arguments of a deployment that is
+// provisioned with Ignition. GRUB sets $ignition_firstboot while the
+// ignition.firstboot flag file exists in the boot partition.
+func ignitionKernelOptions(ignition *blueprint.IgnitionCustomization) []string {
+	if ignition == nil {
+		return nil
+	}
+	opts := []string{"ignition.platform.id=metal", "$ignition_firstboot"}
+	if url := ignition.FirstBootURL(); url != "" {
+		opts = append(opts, "ignition.config.url="+url)
+	}
+	return opts
+}
+
+// fdoRootCertsPath is where the root certificates of the DIUN public key of
+// the FDO manufacturing server are stored in the initrd of the simplified
+// installer
+const fdoRootCertsPath = "/fdo_diun_pub_key_root_certs.pem"
+
+// fdoRootCertsFile returns the file of the root certificates of fdo, or nil
+// if fdo doesn't verify the DIUN public key with root certificates.
+func fdoRootCertsFile(fdo *blueprint.FDOCustomization) *blueprint.FileCustomization {
+	if fdo == nil || fdo.DiunPubKeyRootCerts == "" {
+		return nil
+	}
+	return &blueprint.FileCustomization{
+		Path: fdoRootCertsPath,
+		Data: fdo.DiunPubKeyRootCerts,
+	}
+}
+
+// secureExecutionImagePath is the path of the IBM Secure Execution image of
+// s390x images
+const secureExecutionImagePath = "/boot/secure-linux"
+
+// hostKeyDocumentFiles returns the host key documents of Secure Execution as
+// files, which only add them to the inline source: they are inputs of the
+// genprotimg stage and aren't copied into the tree.
+func hostKeyDocumentFiles(se *blueprint.SecureExecutionCustomization) []blueprint.FileCustomization {
+	if se == nil {
+		return nil
+	}
+	files := make([]blueprint.FileCustomization, len(se.HostKeyDocuments))
+	for idx, doc := range se.HostKeyDocuments {
+		files[idx] = blueprint.FileCustomization{Data: doc}
+	}
+	return files
+}
+
+// genprotimgStage returns the stage that encrypts the kernel and initrd of
+// kernelVer and the kernel command line written by the kernel-cmdline stage
+// into the Secure Execution image, for the hosts of the host key documents
+// of the inline source.
+func genprotimgStage(kernelVer string, se *blueprint.SecureExecutionCustomization) *osbuild.Stage {
+	const inputName = "hostkeys"
+
+	var checksums []string
+	var documents []string
+	seenChecksums := make(map[string]bool)
+	for _, doc := range se.HostKeyDocuments {
+		checksum := osbuild.InlineSourceChecksum([]byte(doc))
+		if seenChecksums[checksum] {
+			continue
+		}
+		seenChecksums[checksum] = true
+		checksums = append(checksums, checksum)
+		documents = append(documents, fmt.Sprintf("input://%s/%s", inputName, checksum))
+	}
+
+	options := &osbuild.GenprotimgStageOptions{
+		Kernel:           "/boot/vmlinuz-" + kernelVer,
+		Initrd:           "/boot/initramfs-" + kernelVer + ".img",
+		Parmfile:         "/etc/kernel/cmdline",
+		HostKeyDocuments: documents,
+		Output:           secureExecutionImagePath,
+	}
+	return osbuild.NewGenprotimgStage(options, osbuild.NewGenprotimgStageInputs(checksums))
+}
+
+// oscapRemediationStageOptions returns the options of the remediation of the
+// tree against the profile of the customization. The datastream of the
+// customization replaces the default datastream of the distribution.
+func oscapRemediationStageOptions(openscap *blueprint.OpenSCAPCustomization, defaultDataStream string) *osbuild.OscapRemediationStageOptions {
+	datastream := openscap.DataStream
+	if datastream == "" {
+		datastream = defaultDataStream
+	}
+	return &osbuild.OscapRemediationStageOptions{
+		DataDir: oscapDataDir,
+		Config: osbuild.OscapConfig{
+			Datastream: datastream,
+			ProfileID:  openscap.ProfileID,
+		},
+	}
+}
+
 func ostreeConfigStageOptions(repo string, readOnly bool) *osbuild.OSTreeConfigStageOptions {
 	return &osbuild.OSTreeConfigStageOptions{
 		Repo: repo,
@@ -612,13 +2208,82 @@ func ostreeConfigStageOptions(repo string, readOnly bool) *osbuild.OSTreeConfigS
 	}
 }
 
-func efiMkdirStageOptions() *osbuild.MkdirStageOptions {
-	return &osbuild.MkdirStageOptions{
-		Paths: []osbuild.Path{
-			{
-				Path: "/boot/efi",
-				Mode: os.FileMode(0700),
-			},
-		},
+// ostreeRemotesStageOptions returns the options of the
+// org.osbuild.ostree.remotes stage which adds the remote of the
+// customization, tracking ref, to the repository of the deployment
+func ostreeRemotesStageOptions(repo string, remote *blueprint.OSTreeRemoteCustomization, ref string) *osbuild.OSTreeRemotesStageOptions {
+	r := osbuild.OSTreeRemote{
+		Name:       remote.Name,
+		URL:        remote.URL,
+		ContentURL: remote.ContentURL,
+		Branches:   []string{ref},
+	}
+	if remote.GPGKey != "" {
+		r.GPGKeys = []string{remote.GPGKey}
+	}
+	return &osbuild.OSTreeRemotesStageOptions{
+		Repo:    repo,
+		Remotes: []osbuild.OSTreeRemote{r},
+	}
+}
+
+// ostreeStaticDeltaStageOptions returns the options of the
+// org.osbuild.ostree.static-delta stage which generates the delta from the
+// parent commit to the commit of the ref in repo
+func ostreeStaticDeltaStageOptions(repo string, options distro.OSTreeImageOptions) *osbuild.OSTreeStaticDeltaStageOptions {
+	return &osbuild.OSTreeStaticDeltaStageOptions{
+		Repo: repo,
+		From: options.Parent,
+		To:   options.Ref,
+	}
+}
+
+// ostreeRemoteFiles returns the configuration of the remote of the
+// customization in /etc/ostree/remotes.d and its GPG key, which ostree
+// commits carry instead of a repository configuration
+func ostreeRemoteFiles(remote *blueprint.OSTreeRemoteCustomization) []blueprint.FileCustomization {
+	if remote == nil {
+		return nil
+	}
+	var config strings.Builder
+	fmt.Fprintf(&config, "[remote %q]\nurl=%s\n", remote.Name, remote.URL)
+	if remote.ContentURL != "" {
+		fmt.Fprintf(&config, "contenturl=%s\n", remote.ContentURL)
+	}
+	if remote.GPGKey == "" {
+		config.WriteString("gpg-verify=false\n")
+		return []blueprint.FileCustomization{
+			{Path: "/etc/ostree/remotes.d/" + remote.Name + ".conf", Data: config.String()},
+		}
+	}
+	keyPath := "/etc/ostree/remotes.d/" + remote.Name + ".gpg"
+	fmt.Fprintf(&config, "gpg-verify=true\ngpgkeypath=%s\n", keyPath)
+	return []blueprint.FileCustomization{
+		{Path: "/etc/ostree/remotes.d/" + remote.Name + ".conf", Data: config.String()},
+		{Path: keyPath, Data: remote.GPGKey},
+	}
+}
+
+func efiMkdirStageOptions() (*osbuild.MkdirStageOptions, error) {
+	return mkdirStageOptions(osbuild.Path{
+		Path: "/boot/efi",
+		Mode: os.FileMode(0700),
+	})
+}
+
+// mkdirStageOptions returns the options of an org.osbuild.mkdir stage that
+// creates the directories in the given order. The paths are cleaned and
+// must be absolute.
+func mkdirStageOptions(paths ...osbuild.Path) (*osbuild.MkdirStageOptions, error) {
+	options := &osbuild.MkdirStageOptions{
+		Paths: make([]osbuild.Path, len(paths)),
+	}
+	for idx, p := range paths {
+		p.Path = path.Clean(p.Path)
+		if !path.IsAbs(p.Path) {
+			return nil, fmt.Errorf("directory %q must be an absolute path", paths[idx].Path)
+		}
+		options.Paths[idx] = p
 	}
+	return options, nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	"github.com/osbuild/osbuild-composer/internal/distro/distro_test_common"
 	rhel90 "github.com/osbuild/osbuild-composer/internal/distro/rhel90beta"
@@ -627,6 +628,69 @@ func TestDistro_TestRootMountPoint(t *testing.T) {
 	}
 }
 
+func TestDistro_EdgeContainerCustomizationNotSupported(t *testing.T) {
+	r9distro := rhel90.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	imgType, err := arch.GetImageType("edge-container")
+	require.NoError(t, err)
+
+	customizations := &blueprint.Customizations{
+		EdgeContainer: &blueprint.EdgeContainerCustomization{Port: 80},
+	}
+	_, err = imgType.Manifest(customizations, distro.ImageOptions{}, nil, nil, 0)
+	assert.EqualError(t, err, `edge container customizations are not supported for image type "edge-container"`)
+}
+
+func TestDistro_CustomFileSystemFSTabOptions(t *testing.T) {
+	r9distro := rhel90.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	customizations := &blueprint.Customizations{
+		Filesystem: []blueprint.FilesystemCustomization{
+			{Mountpoint: "/home", MinSize: 1073741824, Options: []string{"nodev", "nosuid"}},
+		},
+	}
+	manifest, err := qcow2.Manifest(customizations, distro.ImageOptions{Size: qcow2.Size(0)}, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Contains(t, string(manifest), `"path":"/home","options":"nodev,nosuid"`)
+
+	customizations.Filesystem[0].Options = []string{"no atime"}
+	_, err = qcow2.Manifest(customizations, distro.ImageOptions{Size: qcow2.Size(0)}, nil, nil, 0)
+	assert.EqualError(t, err, `invalid mount option "no atime" of /home`)
+}
+
+func TestDistro_CustomFileSystemTypeNotSupported(t *testing.T) {
+	r9distro := rhel90.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	for fsType, msg := range map[string]string{
+		"zfs":   `unsupported filesystem type "zfs" of /, must be one of xfs, ext4`,
+		"btrfs": `unsupported filesystem type "btrfs" of /, must be one of xfs, ext4`,
+	} {
+		customizations := &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{{Mountpoint: "/", FSType: fsType}},
+		}
+		_, err := qcow2.Manifest(customizations, distro.ImageOptions{}, nil, nil, 0)
+		assert.EqualError(t, err, msg)
+	}
+
+	customizations := &blueprint.Customizations{
+		Filesystem: []blueprint.FilesystemCustomization{{
+			Mountpoint: "/",
+			Subvolumes: []blueprint.BtrfsSubvolumeCustomization{{Name: "root", Mountpoint: "/"}},
+		}},
+	}
+	_, err = qcow2.Manifest(customizations, distro.ImageOptions{}, nil, nil, 0)
+	assert.EqualError(t, err, "subvolumes of / require the btrfs filesystem type")
+}
+
 func TestDistro_CustomFileSystemSubDirectories(t *testing.T) {
 	r9distro := rhel90.New()
 	bp := blueprint.Blueprint{
@@ -788,3 +852,38 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 		}
 	}
 }
+
+func TestDistro_UnsupportedImageOptions(t *testing.T) {
+	r9distro := rhel90.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	// the guest-image of the cloud API sets these for every distribution
+	for _, options := range []distro.ImageOptions{
+		{Size: qcow2.Size(0), SectorSize: 4096},
+		{Size: qcow2.Size(0), Qcow2Compression: "zstd"},
+		{Size: qcow2.Size(0), ComposeID: "RHEL-8.5.0-20220314.1"},
+	} {
+		_, err := qcow2.Manifest(nil, options, nil, nil, 0)
+		assert.Error(t, err)
+	}
+}
+
+func TestDistro_UnsupportedCustomizations(t *testing.T) {
+	r9distro := rhel90.New()
+	arch, err := r9distro.GetArch("x86_64")
+	require.NoError(t, err)
+	qcow2, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	for _, customizations := range []*blueprint.Customizations{
+		{Kdump: &blueprint.KdumpCustomization{}},
+		{Services: &blueprint.ServicesCustomization{Masked: []string{"rpcbind.socket"}}},
+		{User: []blueprint.UserCustomization{{Name: "admin", Sudo: common.BoolToPtr(true)}}},
+	} {
+		_, err := qcow2.Manifest(customizations, distro.ImageOptions{Size: qcow2.Size(0)}, nil, nil, 0)
+		assert.Error(t, err)
+	}
+}
@@ -53,7 +53,10 @@ func qcow2Pipelines(t *imageType, customizations *blueprint.Customizations, opti
 	imagePipeline := liveImagePipeline(treePipeline.Name, diskfile, &partitionTable, t.arch, kernelVer)
 	pipelines = append(pipelines, *imagePipeline)
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "1.1")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "1.1")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 
 	return pipelines, nil
@@ -93,7 +96,10 @@ func vhdPipelines(t *imageType, customizations *blueprint.Customizations, option
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vpc", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -125,7 +131,10 @@ func vmdkPipelines(t *imageType, customizations *blueprint.Customizations, optio
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vmdk", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "vmdk", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -156,7 +165,10 @@ func openstackPipelines(t *imageType, customizations *blueprint.Customizations,
 		return nil, err
 	}
 
-	qemuPipeline := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "")
+	qemuPipeline, err := qemuPipeline(imagePipeline.Name, diskfile, t.filename, "qcow2", "")
+	if err != nil {
+		return nil, err
+	}
 	pipelines = append(pipelines, *qemuPipeline)
 	return pipelines, nil
 }
@@ -1128,14 +1140,18 @@ func mkfsStages(pt *disk.PartitionTable, device *osbuild.Device) []*osbuild2.Sta
 	return stages
 }
 
-func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format, qcow2Compat string) *osbuild.Pipeline {
+func qemuPipeline(inputPipelineName, inputFilename, outputFilename, format, qcow2Compat string) (*osbuild.Pipeline, error) {
 	p := new(osbuild.Pipeline)
 	p.Name = format
 	p.Build = "name:build"
 
-	qemuStage := osbuild.NewQEMUStage(qemuStageOptions(outputFilename, format, qcow2Compat), qemuStageInputs(inputPipelineName, inputFilename))
+	options, err := qemuStageOptions(outputFilename, format, qcow2Compat)
+	if err != nil {
+		return nil, err
+	}
+	qemuStage := osbuild.NewQEMUStage(options, qemuStageInputs(inputPipelineName, inputFilename))
 	p.AddStage(qemuStage)
-	return p
+	return p, nil
 }
 
 func bootloaderConfigStage(t *imageType, partitionTable disk.PartitionTable, kernel *blueprint.KernelCustomization, kernelVer string) *osbuild.Stage {
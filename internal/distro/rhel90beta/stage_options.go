@@ -480,7 +480,7 @@ func ziplInstStageOptions(kernel string, pt *disk.PartitionTable) *osbuild.ZiplI
 	}
 }
 
-func qemuStageOptions(filename, format, compat string) *osbuild.QEMUStageOptions {
+func qemuStageOptions(filename, format, compat string) (*osbuild.QEMUStageOptions, error) {
 	var options osbuild.QEMUFormatOptions
 	switch format {
 	case "qcow2":
@@ -497,13 +497,13 @@ func qemuStageOptions(filename, format, compat string) *osbuild.QEMUStageOptions
 			Type: "vmdk",
 		}
 	default:
-		panic("unknown format in qemu stage: " + format)
+		return nil, fmt.Errorf("unknown format in qemu stage: %s", format)
 	}
 
 	return &osbuild.QEMUStageOptions{
 		Filename: filename,
 		Format:   options,
-	}
+	}, nil
 }
 
 func kernelCmdlineStageOptions(rootUUID string, kernelOptions string) *osbuild.KernelCmdlineStageOptions {
@@ -51,6 +51,7 @@ const (
 	TestImageTypeVhd           = "vhd"
 	TestImageTypeEdgeCommit    = "rhel-edge-commit"
 	TestImageTypeEdgeInstaller = "rhel-edge-installer"
+	TestImageTypeQcow2         = "qcow2"
 )
 
 // TestDistro
@@ -250,9 +251,13 @@ func newTestDistro(name, modulePlatformID, releasever string) *TestDistro {
 		name: TestImageTypeEdgeInstaller,
 	}
 
+	it7 := TestImageType{
+		name: TestImageTypeQcow2,
+	}
+
 	ta1.addImageTypes(it1)
 	ta2.addImageTypes(it1, it2)
-	ta3.addImageTypes(it3, it4, it5, it6)
+	ta3.addImageTypes(it3, it4, it5, it6, it7)
 
 	td.addArches(&ta1, &ta2, &ta3)
 
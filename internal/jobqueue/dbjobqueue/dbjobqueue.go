@@ -18,6 +18,7 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/osbuild/osbuild-composer/internal/jobqueue"
 	logrus "github.com/sirupsen/logrus"
 )
@@ -83,6 +84,12 @@ const (
 	sqlDeleteHeartbeat = `
                 DELETE FROM heartbeats
                 WHERE id = $1`
+
+	sqlCountJobs = `
+		SELECT type, started_at IS NOT NULL, count(*)
+		FROM jobs
+		WHERE finished_at IS NULL AND canceled = FALSE
+		GROUP BY type, started_at IS NOT NULL`
 )
 
 type dbJobQueue struct {
@@ -116,7 +123,7 @@ func (q *dbJobQueue) Enqueue(jobType string, args interface{}, dependencies []uu
 	}
 	defer func() {
 		err := tx.Rollback(context.Background())
-		if err != nil && !errors.As(err, &pgx.ErrTxClosed) {
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 			logrus.Error("error rolling back enqueue transaction: ", err)
 		}
 	}()
@@ -144,7 +151,10 @@ func (q *dbJobQueue) Enqueue(jobType string, args interface{}, dependencies []uu
 		return uuid.Nil, fmt.Errorf("unable to commit database transaction: %v", err)
 	}
 
-	logrus.Infof("Enqueued job of type %s with ID %s", jobType, id)
+	logrus.WithFields(logrus.Fields{
+		common.LogFieldJobID:   id.String(),
+		common.LogFieldJobType: jobType,
+	}).Infof("Enqueued job of type %s with ID %s", jobType, id)
 
 	return id, nil
 }
@@ -181,7 +191,7 @@ func (q *dbJobQueue) Dequeue(ctx context.Context, jobTypes []string) (uuid.UUID,
 		if err == nil {
 			break
 		}
-		if err != nil && !errors.As(err, &pgx.ErrNoRows) {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return uuid.Nil, uuid.Nil, nil, "", nil, fmt.Errorf("error dequeuing job: %v", err)
 		}
 		_, err = conn.Conn().WaitForNotification(ctx)
@@ -204,7 +214,10 @@ func (q *dbJobQueue) Dequeue(ctx context.Context, jobTypes []string) (uuid.UUID,
 		return uuid.Nil, uuid.Nil, nil, "", nil, fmt.Errorf("error querying the job's dependencies: %v", err)
 	}
 
-	logrus.Infof("Dequeued job of type %v with ID %s", jobType, id)
+	common.LoggerFromContext(ctx).WithFields(logrus.Fields{
+		common.LogFieldJobID:   id.String(),
+		common.LogFieldJobType: jobType,
+	}).Infof("Dequeued job of type %v with ID %s", jobType, id)
 
 	return id, token, dependencies, jobType, args, nil
 }
@@ -222,7 +235,7 @@ func (q *dbJobQueue) FinishJob(id uuid.UUID, result interface{}) error {
 	}
 	defer func() {
 		err = tx.Rollback(context.Background())
-		if err != nil && !errors.As(err, &pgx.ErrTxClosed) {
+		if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 			logrus.Error("error rolling back finish job transaction: ", err)
 		}
 
@@ -271,7 +284,7 @@ func (q *dbJobQueue) FinishJob(id uuid.UUID, result interface{}) error {
 		return fmt.Errorf("unable to commit database transaction: %v", err)
 	}
 
-	logrus.Infof("Finished job with ID %s", id)
+	logrus.WithField(common.LogFieldJobID, id.String()).Infof("Finished job with ID %s", id)
 
 	return nil
 }
@@ -292,7 +305,7 @@ func (q *dbJobQueue) CancelJob(id uuid.UUID) error {
 		return jobqueue.ErrNotRunning
 	}
 
-	logrus.Infof("Cancelled job with ID %s", id)
+	logrus.WithField(common.LogFieldJobID, id.String()).Infof("Cancelled job with ID %s", id)
 
 	return nil
 }
@@ -437,3 +450,39 @@ func (q *dbJobQueue) jobDependencies(ctx context.Context, conn *pgxpool.Conn, id
 
 	return dependencies, nil
 }
+
+func (q *dbJobQueue) CountJobs() (pending map[string]int, running map[string]int, err error) {
+	conn, err := q.pool.Acquire(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to database: %v", err)
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(context.Background(), sqlCountJobs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error counting jobs: %v", err)
+	}
+	defer rows.Close()
+
+	pending = make(map[string]int)
+	running = make(map[string]int)
+	for rows.Next() {
+		var jobType string
+		var started bool
+		var count int
+		err = rows.Scan(&jobType, &started, &count)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading job counts: %v", err)
+		}
+		if started {
+			running[jobType] = count
+		} else {
+			pending[jobType] = count
+		}
+	}
+	if rows.Err() != nil {
+		return nil, nil, fmt.Errorf("error reading job counts: %v", rows.Err())
+	}
+
+	return pending, running, nil
+}
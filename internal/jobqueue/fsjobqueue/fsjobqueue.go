@@ -212,7 +212,7 @@ func (q *fsJobQueue) Dequeue(ctx context.Context, jobTypes []string) (uuid.UUID,
 		}
 
 		if err != nil {
-			if errors.As(err, &context.Canceled) || errors.As(err, &context.DeadlineExceeded) {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 				return uuid.Nil, uuid.Nil, nil, "", nil, jobqueue.ErrDequeueTimeout
 			}
 			return uuid.Nil, uuid.Nil, nil, "", nil, err
@@ -376,6 +376,38 @@ func (q *fsJobQueue) RefreshHeartbeat(token uuid.UUID) {
 	}
 }
 
+func (q *fsJobQueue) CountJobs() (pending map[string]int, running map[string]int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids, err := q.db.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing jobs: %v", err)
+	}
+
+	pending = make(map[string]int)
+	running = make(map[string]int)
+	for _, id := range ids {
+		jobId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid job '%s' in db: %v", id, err)
+		}
+		j, err := q.readJob(jobId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if j.Canceled || !j.FinishedAt.IsZero() {
+			continue
+		}
+		if j.StartedAt.IsZero() {
+			pending[j.Type]++
+		} else {
+			running[j.Type]++
+		}
+	}
+	return pending, running, nil
+}
+
 // Reads job with `id`. This is a thin wrapper around `q.db.Read`, which
 // returns the job directly, or and error if a job with `id` does not exist.
 func (q *fsJobQueue) readJob(id uuid.UUID) (*job, error) {
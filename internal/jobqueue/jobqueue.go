@@ -71,6 +71,10 @@ type JobQueue interface {
 
 	// Reset the last heartbeat time to time.Now()
 	RefreshHeartbeat(token uuid.UUID)
+
+	// Returns the number of jobs that haven't started yet and of the jobs
+	// that are running, by job type. Canceled jobs are not counted.
+	CountJobs() (pending map[string]int, running map[string]int, err error)
 }
 
 var (
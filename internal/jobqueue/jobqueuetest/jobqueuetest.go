@@ -38,6 +38,7 @@ func TestJobQueue(t *testing.T, makeJobQueue MakeJobQueue) {
 	t.Run("multiple-workers", wrap(testMultipleWorkers))
 	t.Run("heartbeats", wrap(testHeartbeats))
 	t.Run("timeout", wrap(testDequeueTimeout))
+	t.Run("count-jobs", wrap(testCountJobs))
 }
 
 func pushTestJob(t *testing.T, q jobqueue.JobQueue, jobType string, args interface{}, dependencies []uuid.UUID) uuid.UUID {
@@ -368,3 +369,37 @@ func testHeartbeats(t *testing.T, q jobqueue.JobQueue) {
 	_, err = q.IdFromToken(tok)
 	require.Equal(t, err, jobqueue.ErrNotExist)
 }
+
+func testCountJobs(t *testing.T, q jobqueue.JobQueue) {
+	pending, running, err := q.CountJobs()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	require.Empty(t, running)
+
+	one := pushTestJob(t, q, "octopus", nil, nil)
+	pushTestJob(t, q, "octopus", nil, []uuid.UUID{one})
+	canceled := pushTestJob(t, q, "clownfish", nil, nil)
+	require.NoError(t, q.CancelJob(canceled))
+	pushTestJob(t, q, "clownfish", nil, nil)
+
+	pending, running, err = q.CountJobs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"octopus": 2, "clownfish": 1}, pending)
+	require.Empty(t, running)
+
+	// the dependency is running, the job depending on it still pending
+	id, _, _, _, _, err := q.Dequeue(context.Background(), []string{"octopus"})
+	require.NoError(t, err)
+	require.Equal(t, one, id)
+	pending, running, err = q.CountJobs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"octopus": 1, "clownfish": 1}, pending)
+	require.Equal(t, map[string]int{"octopus": 1}, running)
+
+	// finished jobs are not counted
+	require.NoError(t, q.FinishJob(one, &testResult{}))
+	pending, running, err = q.CountJobs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"octopus": 1, "clownfish": 1}, pending)
+	require.Empty(t, running)
+}
@@ -82,10 +82,11 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 	}
 
 	type imageRequest struct {
-		manifest distro.Manifest
-		arch     string
-		filename string
-		exports  []string
+		manifest  distro.Manifest
+		arch      string
+		filename  string
+		exports   []string
+		imageType string
 	}
 
 	imageRequests := make([]imageRequest, len(request.ImageRequests))
@@ -131,7 +132,7 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 			packageSpecSets[name] = packageSpecs
 		}
 
-		manifest, err := imageType.Manifest(nil, distro.ImageOptions{Size: imageType.Size(0)}, repositories, packageSpecSets, manifestSeed)
+		manifest, err := imageType.Manifest(nil, distro.ImageOptions{Size: imageType.Size(0), BuildDate: time.Now()}, repositories, packageSpecSets, manifestSeed)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadGateway, fmt.Sprintf("Failed to get manifest for for %s/%s/%s: %s", ir.ImageType, ir.Architecture, request.Distribution, err))
 		}
@@ -140,6 +141,7 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 		imageRequests[i].arch = arch.Name()
 		imageRequests[i].filename = imageType.Filename()
 		imageRequests[i].exports = imageType.Exports()
+		imageRequests[i].imageType = imageType.Name()
 
 		kojiFilenames[i] = fmt.Sprintf(
 			"%s-%s-%s.%s%s",
@@ -171,6 +173,7 @@ func (h *apiHandlers) PostCompose(ctx echo.Context) error {
 			KojiServer:    request.Koji.Server,
 			KojiDirectory: kojiDirectory,
 			KojiFilename:  kojiFilenames[i],
+			ImageType:     ir.imageType,
 		}, initID)
 		if err != nil {
 			// This is a programming error.
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -16,6 +17,7 @@ type StageResult struct {
 	Success  bool            `json:"success"`
 	Output   string          `json:"output"`
 	Metadata StageMetadata   `json:"metadata"`
+	Duration float64         `json:"duration,omitempty"`
 }
 
 // StageMetadata specify the metadata of a given stage-type.
@@ -33,6 +35,7 @@ type rawStageResult struct {
 	Success  bool            `json:"success"`
 	Output   string          `json:"output"`
 	Metadata json.RawMessage `json:"metadata"`
+	Duration float64         `json:"duration,omitempty"`
 }
 
 type buildResult struct {
@@ -48,6 +51,11 @@ type Result struct {
 	Stages    []StageResult `json:"stages"`
 	Assembler *StageResult  `json:"assembler"`
 	Success   bool          `json:"success"`
+
+	// Summary of the pipelines and the failed stage. It is computed when
+	// the result is decoded, because results of manifest v2 lose their
+	// pipeline structure when they are converted to this format.
+	Summary *osbuild2.ResultSummary `json:"summary,omitempty"`
 }
 
 func (result *StageResult) UnmarshalJSON(data []byte) error {
@@ -79,6 +87,7 @@ func (result *StageResult) UnmarshalJSON(data []byte) error {
 	result.Success = rawStageResult.Success
 	result.Output = rawStageResult.Output
 	result.Metadata = metadata
+	result.Duration = rawStageResult.Duration
 
 	return nil
 }
@@ -176,6 +185,7 @@ func (cr *Result) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		cr.fromV2(crv2)
+		cr.Summary = crv2.Summary()
 		return nil
 	}
 
@@ -189,9 +199,52 @@ func (cr *Result) UnmarshalJSON(data []byte) error {
 	}
 
 	*cr = Result(crv1)
+	if cr.Summary == nil {
+		cr.Summary = cr.legacySummary()
+	}
 	return nil
 }
 
+// names of stages converted from v2 results, see fromV2()
+var v2StageNameRegexp = regexp.MustCompile(`^(.+):(\d+)-(.+)$`)
+
+// legacySummary summarizes results of manifest v1 and results of manifest v2
+// which were converted before summaries were computed. Stages of v1 results
+// are assigned to the "build", "tree" and "assembler" pipelines.
+func (cr *Result) legacySummary() *osbuild2.ResultSummary {
+	var pipelines []string
+	log := make(map[string]osbuild2.PipelineResult)
+	add := func(pipeline, stageType string, stage StageResult) {
+		if _, exists := log[pipeline]; !exists {
+			pipelines = append(pipelines, pipeline)
+		}
+		log[pipeline] = append(log[pipeline], osbuild2.StageResult{
+			Type:     stageType,
+			Output:   stage.Output,
+			Success:  stage.Success,
+			Duration: stage.Duration,
+		})
+	}
+
+	if cr.Build != nil {
+		for _, stage := range cr.Build.Stages {
+			add("build", stage.Name, stage)
+		}
+	}
+	for _, stage := range cr.Stages {
+		if match := v2StageNameRegexp.FindStringSubmatch(stage.Name); match != nil {
+			add(match[1], match[3], stage)
+		} else {
+			add("tree", stage.Name, stage)
+		}
+	}
+	if cr.Assembler != nil && cr.Assembler.Name != "" {
+		add("assembler", cr.Assembler.Name, *cr.Assembler)
+	}
+
+	return osbuild2.SummarizePipelines(pipelines, log)
+}
+
 // Convert new OSBuild v2 format result into a v1 by copying the most useful
 // values:
 // - Compose success status
@@ -245,6 +298,7 @@ func (cr *Result) fromV2(crv2 osbuild2.Result) {
 				Success:  stage.Success,
 				Output:   stage.Output,
 				Metadata: stageMetadata,
+				Duration: stage.Duration,
 			}
 			cr.Stages = append(cr.Stages, stageResult)
 		}
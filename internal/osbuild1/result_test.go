@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshal(t *testing.T) {
@@ -148,6 +149,87 @@ func TestUnmarshalV2Failure(t *testing.T) {
 	assert.NotEmpty(t, result.Stages[0].Name)
 }
 
+func TestSummaryV1Success(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v1ResultSuccess), &result))
+	require.NotNil(t, result.Summary)
+
+	assert.Nil(t, result.Summary.FailedStage)
+	require.Len(t, result.Summary.Pipelines, 3)
+	assert.Equal(t, "build", result.Summary.Pipelines[0].Name)
+	assert.Len(t, result.Summary.Pipelines[0].Stages, 2)
+	assert.Equal(t, "tree", result.Summary.Pipelines[1].Name)
+	assert.Len(t, result.Summary.Pipelines[1].Stages, 11)
+	assert.Equal(t, "assembler", result.Summary.Pipelines[2].Name)
+	assert.Equal(t, "org.osbuild.qemu", result.Summary.Pipelines[2].Stages[0].Type)
+}
+
+func TestSummaryV1Failure(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v1ResultFailure), &result))
+	require.NotNil(t, result.Summary)
+
+	require.Len(t, result.Summary.Pipelines, 2)
+	failed := result.Summary.FailedStage
+	require.NotNil(t, failed)
+	assert.Equal(t, "tree", failed.Pipeline)
+	assert.Equal(t, 8, failed.Index)
+	assert.Equal(t, "org.osbuild.selinux", failed.Type)
+	assert.NotEmpty(t, failed.Output)
+}
+
+func TestSummaryV2Success(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultSuccess), &result))
+	require.NotNil(t, result.Summary)
+
+	assert.Nil(t, result.Summary.FailedStage)
+	var names []string
+	for _, p := range result.Summary.Pipelines {
+		names = append(names, p.Name)
+		assert.True(t, p.Success)
+	}
+	assert.Equal(t, []string{"assembler", "build", "container-tree", "ostree-commit", "ostree-tree"}, names)
+}
+
+func TestSummaryV2Failure(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultFailure), &result))
+	require.NotNil(t, result.Summary)
+
+	assert.Equal(t, "org.osbuild.error.stage", result.Summary.ErrorType)
+	failed := result.Summary.FailedStage
+	require.NotNil(t, failed)
+	assert.Equal(t, "ostree-tree", failed.Pipeline)
+	assert.Equal(t, 4, failed.Index)
+	assert.Equal(t, "org.osbuild.selinux", failed.Type)
+	assert.Equal(t, "failed in pipeline ostree-tree, stage org.osbuild.selinux", failed.String())
+}
+
+func TestSummaryRoundTrip(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultFailure), &result))
+
+	// the summary is stored with the converted result
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, result.Summary, decoded.Summary)
+
+	// converted results stored without a summary get one from the stage names
+	result.Summary = nil
+	data, err = json.Marshal(result)
+	require.NoError(t, err)
+	decoded = Result{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NotNil(t, decoded.Summary)
+	require.NotNil(t, decoded.Summary.FailedStage)
+	assert.Equal(t, "ostree-tree", decoded.Summary.FailedStage.Pipeline)
+	assert.Equal(t, 4, decoded.Summary.FailedStage.Index)
+	assert.Equal(t, "org.osbuild.selinux", decoded.Summary.FailedStage.Type)
+}
+
 func TestWriteFull(t *testing.T) {
 
 	const testOptions = `{"msg": "test"}`
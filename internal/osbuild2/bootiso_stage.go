@@ -16,6 +16,9 @@ type BootISOMonoStageOptions struct {
 
 	Templates string `json:"templates,omitempty"`
 
+	// Seconds until the default entry of the boot menu is booted
+	Timeout *int `json:"timeout,omitempty"`
+
 	RootFS RootFS `json:"rootfs,omitempty"`
 }
 
@@ -36,6 +39,12 @@ type RootFS struct {
 	Size int `json:"size"`
 }
 
+// Compression methods of the squashfs of the root filesystem
+const (
+	FSCompressionXz   = "xz"
+	FSCompressionZstd = "zstd"
+)
+
 type FSCompression struct {
 	Method  string                `json:"method"`
 	Options *FSCompressionOptions `json:"options,omitempty"`
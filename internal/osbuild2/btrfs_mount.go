@@ -1,10 +1,11 @@
 package osbuild2
 
-func NewBtrfsMount(name, source, target string) *Mount {
+func NewBtrfsMount(name, source, target string, options ...string) *Mount {
 	return &Mount{
-		Type:   "org.osbuild.btrfs",
-		Name:   name,
-		Source: source,
-		Target: target,
+		Type:    "org.osbuild.btrfs",
+		Name:    name,
+		Source:  source,
+		Target:  target,
+		Options: options,
 	}
 }
@@ -0,0 +1,23 @@
+package osbuild2
+
+// BtrfsSubVolStageOptions are the options of the org.osbuild.btrfs.subvol
+// stage, which creates subvolumes in the btrfs volume mounted at "volume"
+type BtrfsSubVolStageOptions struct {
+	Subvolumes []BtrfsSubVolume `json:"subvolumes"`
+}
+
+func (BtrfsSubVolStageOptions) isStageOptions() {}
+
+type BtrfsSubVolume struct {
+	// Path of the subvolume relative to the top-level volume
+	Name string `json:"name"`
+}
+
+func NewBtrfsSubVolStage(options *BtrfsSubVolStageOptions, devices *Devices, mounts *Mounts) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.btrfs.subvol",
+		Options: options,
+		Devices: *devices,
+		Mounts:  *mounts,
+	}
+}
@@ -0,0 +1,31 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBtrfsSubVolStage(t *testing.T) {
+	device := NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img"})
+	devices := Devices{"device": *device}
+	mounts := Mounts{*NewBtrfsMount("volume", "device", "/")}
+	options := &BtrfsSubVolStageOptions{
+		Subvolumes: []BtrfsSubVolume{{Name: "/root"}, {Name: "/home"}},
+	}
+
+	expectedStage := &Stage{
+		Type:    "org.osbuild.btrfs.subvol",
+		Options: options,
+		Devices: devices,
+		Mounts:  mounts,
+	}
+	actualStage := NewBtrfsSubVolStage(options, &devices, &mounts)
+	assert.Equal(t, expectedStage, actualStage)
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"subvolumes": [{"name": "/root"}, {"name": "/home"}]}`, string(data))
+}
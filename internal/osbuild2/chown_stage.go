@@ -0,0 +1,53 @@
+package osbuild2
+
+import "strconv"
+
+type ChownStageOptions struct {
+	Items map[string]ChownStagePathOptions `json:"items"`
+}
+
+// ChownStagePathOptions sets the owner and the group of a path. At least one
+// of them must be set.
+type ChownStagePathOptions struct {
+	User      ChownPrincipal `json:"user,omitempty"`
+	Group     ChownPrincipal `json:"group,omitempty"`
+	Recursive bool           `json:"recursive,omitempty"`
+}
+
+func (ChownStageOptions) isStageOptions() {}
+
+// ChownPrincipal is a user or a group, given either by name or by ID
+type ChownPrincipal interface {
+	isChownPrincipal()
+}
+
+// ChownPrincipalName is a user or a group given by name
+type ChownPrincipalName string
+
+func (ChownPrincipalName) isChownPrincipal() {}
+
+// ChownPrincipalID is a user or a group given by ID
+type ChownPrincipalID int
+
+func (ChownPrincipalID) isChownPrincipal() {}
+
+// NewChownPrincipal returns the principal of a user or group name as found in
+// blueprints, in which IDs are given as decimal numbers. An empty name
+// returns nil.
+func NewChownPrincipal(name string) ChownPrincipal {
+	if name == "" {
+		return nil
+	}
+	if id, err := strconv.Atoi(name); err == nil && id >= 0 {
+		return ChownPrincipalID(id)
+	}
+	return ChownPrincipalName(name)
+}
+
+// NewChownStage creates a new org.osbuild.chown stage
+func NewChownStage(options *ChownStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.chown",
+		Options: options,
+	}
+}
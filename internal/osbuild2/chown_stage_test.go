@@ -0,0 +1,57 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChownStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.chown",
+		Options: &ChownStageOptions{},
+	}
+	actualStage := NewChownStage(&ChownStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestNewChownPrincipal(t *testing.T) {
+	assert.Nil(t, NewChownPrincipal(""))
+	assert.Equal(t, ChownPrincipalID(0), NewChownPrincipal("0"))
+	assert.Equal(t, ChownPrincipalID(1000), NewChownPrincipal("1000"))
+	assert.Equal(t, ChownPrincipalName("root"), NewChownPrincipal("root"))
+	assert.Equal(t, ChownPrincipalName("user1"), NewChownPrincipal("user1"))
+	assert.Equal(t, ChownPrincipalName("-1"), NewChownPrincipal("-1"))
+}
+
+func TestChownStageOptions_MarshalJSON(t *testing.T) {
+	options := ChownStageOptions{
+		Items: map[string]ChownStagePathOptions{
+			"/etc/app": {
+				User:      ChownPrincipalName("app"),
+				Group:     ChownPrincipalID(1000),
+				Recursive: true,
+			},
+			"/etc/app.conf": {
+				User: ChownPrincipalID(0),
+			},
+			"/var/lib/app": {
+				Group: ChownPrincipalName("wheel"),
+			},
+		},
+	}
+	data, err := json.Marshal(NewChownStage(&options))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "org.osbuild.chown",
+		"options": {
+			"items": {
+				"/etc/app": {"user": "app", "group": 1000, "recursive": true},
+				"/etc/app.conf": {"user": 0},
+				"/var/lib/app": {"group": "wheel"}
+			}
+		}
+	}`, string(data))
+}
@@ -5,11 +5,17 @@ import (
 	"fmt"
 )
 
-// Exactly one of 'Timeservers' or 'Servers' must be specified
+// At most one of 'Timeservers' or 'Servers' may be specified, and either one
+// of them, 'Refclocks' or 'LeapsecTz' must be. The servers replace the server
+// and pool directives of the distribution configuration.
 type ChronyStageOptions struct {
-	Timeservers []string             `json:"timeservers,omitempty"`
-	Servers     []ChronyConfigServer `json:"servers,omitempty"`
-	LeapsecTz   *string              `json:"leapsectz,omitempty"`
+	Timeservers []string               `json:"timeservers,omitempty"`
+	Servers     []ChronyConfigServer   `json:"servers,omitempty"`
+	Refclocks   []ChronyConfigRefclock `json:"refclocks,omitempty"`
+	LeapsecTz   *string                `json:"leapsectz,omitempty"`
+	// Comment out the pool directives of the distribution configuration
+	// instead of keeping them when no servers are specified
+	CommentPools *bool `json:"comment_pools,omitempty"`
 }
 
 func (ChronyStageOptions) isStageOptions() {}
@@ -23,12 +29,36 @@ type ChronyConfigServer struct {
 	Prefer   *bool  `json:"prefer,omitempty"`
 }
 
+// A ChronyConfigRefclock is a hardware reference clock, e.g. the PTP clock
+// of a hypervisor
+type ChronyConfigRefclock struct {
+	Driver ChronyRefclockDriver `json:"driver"`
+	Poll   *int                 `json:"poll,omitempty"`
+	Dpoll  *int                 `json:"dpoll,omitempty"`
+	Offset *float64             `json:"offset,omitempty"`
+}
+
+type ChronyRefclockDriver struct {
+	// Name of the driver, e.g. PHC
+	Name string `json:"name"`
+	// Path of the clock device
+	Path string `json:"path"`
+}
+
 // Unexported alias for use in ChronyStageOptions's MarshalJSON() to prevent recursion
 type chronyStageOptions ChronyStageOptions
 
 func (o ChronyStageOptions) MarshalJSON() ([]byte, error) {
-	if (len(o.Timeservers) != 0 && len(o.Servers) != 0) || (len(o.Timeservers) == 0 && len(o.Servers) == 0) {
-		return nil, fmt.Errorf("exactly one of 'Timeservers' or 'Servers' must be specified")
+	if len(o.Timeservers) != 0 && len(o.Servers) != 0 {
+		return nil, fmt.Errorf("only one of 'Timeservers' or 'Servers' may be specified")
+	}
+	if len(o.Timeservers) == 0 && len(o.Servers) == 0 && len(o.Refclocks) == 0 {
+		if o.CommentPools != nil && *o.CommentPools {
+			return nil, fmt.Errorf("'CommentPools' requires one of 'Timeservers', 'Servers' or 'Refclocks'")
+		}
+		if o.LeapsecTz == nil {
+			return nil, fmt.Errorf("one of 'Timeservers', 'Servers', 'Refclocks' or 'LeapsecTz' must be specified")
+		}
 	}
 	stageOptions := chronyStageOptions(o)
 	return json.Marshal(stageOptions)
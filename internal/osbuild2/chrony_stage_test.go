@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewChronyStage(t *testing.T) {
@@ -25,6 +27,13 @@ func TestChronyStage_MarshalJSON_Invalid(t *testing.T) {
 			name:    "not-timeservers-nor-servers",
 			options: ChronyStageOptions{},
 		},
+		{
+			name: "comment-pools-without-time-source",
+			options: ChronyStageOptions{
+				LeapsecTz:    common.StringToPtr("right/UTC"),
+				CommentPools: common.BoolToPtr(true),
+			},
+		},
 		{
 			name: "timeservers-and-servers",
 			options: ChronyStageOptions{
@@ -40,3 +49,42 @@ func TestChronyStage_MarshalJSON_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestChronyStage_MarshalJSON_Refclocks(t *testing.T) {
+	options := ChronyStageOptions{
+		Timeservers: []string{"ntp.example.com"},
+		Refclocks: []ChronyConfigRefclock{
+			{
+				Driver: ChronyRefclockDriver{Name: "PHC", Path: "/dev/ptp_hyperv"},
+				Poll:   common.IntToPtr(3),
+				Dpoll:  common.IntToPtr(-2),
+			},
+		},
+	}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"timeservers": ["ntp.example.com"],
+		"refclocks": [{"driver": {"name": "PHC", "path": "/dev/ptp_hyperv"}, "poll": 3, "dpoll": -2}]
+	}`, string(data))
+
+	// the reference clock is the only time source
+	options = ChronyStageOptions{
+		Refclocks:    options.Refclocks,
+		CommentPools: common.BoolToPtr(true),
+	}
+	data, err = json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"refclocks": [{"driver": {"name": "PHC", "path": "/dev/ptp_hyperv"}, "poll": 3, "dpoll": -2}],
+		"comment_pools": true
+	}`, string(data))
+}
+
+func TestChronyStage_MarshalJSON_LeapsecTz(t *testing.T) {
+	// the leap seconds timezone can be set without changing the servers
+	options := ChronyStageOptions{LeapsecTz: common.StringToPtr("right/UTC")}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"leapsectz": "right/UTC"}`, string(data))
+}
@@ -0,0 +1,22 @@
+package osbuild2
+
+// Bind a LUKS2 container to a Clevis policy, which adds a key slot that the
+// pin unlocks
+
+type ClevisLuksBindStageOptions struct {
+	// Passphrase of an existing key slot
+	Passphrase string `json:"passphrase"`
+	Pin        string `json:"pin"`
+	// Configuration of the pin in JSON
+	Policy string `json:"policy"`
+}
+
+func (ClevisLuksBindStageOptions) isStageOptions() {}
+
+func NewClevisLuksBindStage(options *ClevisLuksBindStageOptions, device *Device) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.clevis.luks-bind",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+}
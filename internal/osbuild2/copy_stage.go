@@ -10,8 +10,27 @@ type CopyStageOptions struct {
 type CopyStagePath struct {
 	From string `json:"from"`
 	To   string `json:"to"`
+
+	// Remove existing files at the destination before copying over them
+	RemoveDestination bool `json:"remove_destination,omitempty"`
+
+	// Attributes of the copied files that are preserved. All of them are
+	// preserved if unset.
+	Preserve []CopyAttribute `json:"preserve,omitempty"`
 }
 
+// CopyAttribute is an attribute of files that the copy stage can preserve
+type CopyAttribute string
+
+const (
+	CopyAttributeMode       CopyAttribute = "mode"
+	CopyAttributeOwnership  CopyAttribute = "ownership"
+	CopyAttributeTimestamps CopyAttribute = "timestamps"
+	CopyAttributeLinks      CopyAttribute = "links"
+	CopyAttributeContext    CopyAttribute = "context"
+	CopyAttributeXattr      CopyAttribute = "xattr"
+)
+
 func (CopyStageOptions) isStageOptions() {}
 
 type CopyStageInputs map[string]CopyStageInput
@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewCopyStage(t *testing.T) {
@@ -46,3 +48,26 @@ func TestNewCopyStage(t *testing.T) {
 	actualStage := NewCopyStage(&CopyStageOptions{paths}, &CopyStageInputs{"tree-input": treeInput}, &stageDevices, &stageMounts)
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestCopyStageOptions_MarshalJSON(t *testing.T) {
+	// unset fields are omitted, which keeps the options of existing
+	// manifests unchanged
+	data, err := json.Marshal(&CopyStageOptions{
+		Paths: []CopyStagePath{{From: "input://root-tree/", To: "mount://root/"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":[{"from":"input://root-tree/","to":"mount://root/"}]}`, string(data))
+
+	data, err = json.Marshal(&CopyStageOptions{
+		Paths: []CopyStagePath{
+			{
+				From:              "input://root-tree/",
+				To:                "mount://root/",
+				RemoveDestination: true,
+				Preserve:          []CopyAttribute{CopyAttributeMode, CopyAttributeTimestamps},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":[{"from":"input://root-tree/","to":"mount://root/","remove_destination":true,"preserve":["mode","timestamps"]}]}`, string(data))
+}
@@ -0,0 +1,26 @@
+package osbuild2
+
+// The CrypttabStageOptions describe the content of the /etc/crypttab file,
+// which lists the encrypted volumes that are unlocked at boot.
+type CrypttabStageOptions struct {
+	Volumes []CrypttabVolume `json:"volumes"`
+}
+
+func (CrypttabStageOptions) isStageOptions() {}
+
+// A CrypttabVolume represents one line in /etc/crypttab. The device is
+// identified by its UUID.
+type CrypttabVolume struct {
+	// Name of the mapped device
+	Volume  string `json:"volume"`
+	UUID    string `json:"uuid"`
+	Keyfile string `json:"keyfile,omitempty"`
+	Options string `json:"options,omitempty"`
+}
+
+func NewCrypttabStage(options *CrypttabStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.crypttab",
+		Options: options,
+	}
+}
@@ -3,7 +3,10 @@ package osbuild2
 type Devices map[string]Device
 
 type Device struct {
-	Type    string        `json:"type"`
+	Type string `json:"type"`
+	// Name of the device that this device is on, e.g. the physical volume
+	// of a logical volume
+	Parent  string        `json:"parent,omitempty"`
 	Options DeviceOptions `json:"options,omitempty"`
 }
 
@@ -4,7 +4,7 @@ type DiscinfoStageOptions struct {
 	// Build architecture
 	BaseArch string `json:"basearch"`
 
-	// The product name
+	// The release of the compose, e.g. its compose ID
 	Release string `json:"release"`
 }
 
@@ -4,6 +4,8 @@ package osbuild2
 type DNFConfigStageOptions struct {
 	// List of DNF variables.
 	Variables []DNFVariable `json:"variables,omitempty"`
+	// Options of /etc/dnf/dnf.conf
+	Config *DNFConfig `json:"config,omitempty"`
 }
 
 func (DNFConfigStageOptions) isStageOptions() {}
@@ -30,3 +32,18 @@ type DNFVariable struct {
 	// Value of the variable.
 	Value string `json:"value"`
 }
+
+// DNFConfig represents the sections of /etc/dnf/dnf.conf
+type DNFConfig struct {
+	Main *DNFConfigMain `json:"main,omitempty"`
+}
+
+// DNFConfigMain represents the [main] section of /etc/dnf/dnf.conf
+type DNFConfigMain struct {
+	// URL of the proxy server for all repositories
+	Proxy string `json:"proxy,omitempty"`
+	// Install the weak dependencies, e.g. Recommends, of packages
+	InstallWeakDeps *bool `json:"install_weak_deps,omitempty"`
+	// Packages which are never installed or upgraded
+	ExcludePkgs []string `json:"excludepkgs,omitempty"`
+}
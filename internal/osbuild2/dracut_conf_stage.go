@@ -39,6 +39,9 @@ type DracutConfigFile struct {
 	// Add a specific kernel module
 	AddDrivers []string `json:"add_drivers,omitempty"`
 
+	// Kernel modules to not include
+	OmitDrivers []string `json:"omit_drivers,omitempty"`
+
 	// Add driver and ensure that they are tried to be loaded
 	ForceDrivers []string `json:"force_drivers,omitempty"`
 
@@ -65,6 +68,7 @@ func (c DracutConfigFile) MarshalJSON() ([]byte, error) {
 		len(c.OmitModules) == 0 &&
 		len(c.Drivers) == 0 &&
 		len(c.AddDrivers) == 0 &&
+		len(c.OmitDrivers) == 0 &&
 		len(c.ForceDrivers) == 0 &&
 		len(c.Filesystems) == 0 &&
 		len(c.Install) == 0 &&
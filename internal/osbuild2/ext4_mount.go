@@ -1,10 +1,11 @@
 package osbuild2
 
-func NewExt4Mount(name, source, target string) *Mount {
+func NewExt4Mount(name, source, target string, options ...string) *Mount {
 	return &Mount{
-		Type:   "org.osbuild.ext4",
-		Name:   name,
-		Source: source,
-		Target: target,
+		Type:    "org.osbuild.ext4",
+		Name:    name,
+		Source:  source,
+		Target:  target,
+		Options: options,
 	}
 }
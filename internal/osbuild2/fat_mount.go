@@ -1,10 +1,11 @@
 package osbuild2
 
-func NewFATMount(name, source, target string) *Mount {
+func NewFATMount(name, source, target string, options ...string) *Mount {
 	return &Mount{
-		Type:   "org.osbuild.fat",
-		Name:   name,
-		Source: source,
-		Target: target,
+		Type:    "org.osbuild.fat",
+		Name:    name,
+		Source:  source,
+		Target:  target,
+		Options: options,
 	}
 }
@@ -24,6 +24,9 @@ func NewFilesInputs(references FilesInputReferences) *FilesInputs {
 // inputs accepted by the XZ stage
 func (FilesInputs) isXzStageInputs() {}
 
+// inputs accepted by the gzip stage
+func (FilesInputs) isGzipStageInputs() {}
+
 // inputs accepted by the Copy stage
 func (FilesInputs) isCopyStageInputs() {}
 
@@ -4,6 +4,18 @@ type FirewallStageOptions struct {
 	Ports            []string `json:"ports,omitempty"`
 	EnabledServices  []string `json:"enabled_services,omitempty"`
 	DisabledServices []string `json:"disabled_services,omitempty"`
+	// Zone of the connections that aren't bound to another zone
+	DefaultZone string         `json:"default_zone,omitempty"`
+	Zones       []FirewallZone `json:"zones,omitempty"`
+}
+
+// FirewallZone binds sources and interfaces to a firewalld zone
+type FirewallZone struct {
+	Name string `json:"name"`
+	// Addresses, networks, MAC addresses, or ipsets of the zone
+	Sources []string `json:"sources,omitempty"`
+	// Network interfaces of the zone
+	Interfaces []string `json:"interfaces,omitempty"`
 }
 
 func (FirewallStageOptions) isStageOptions() {}
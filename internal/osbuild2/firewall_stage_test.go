@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewFirewallStage(t *testing.T) {
@@ -14,3 +16,25 @@ func TestNewFirewallStage(t *testing.T) {
 	actualFirewall := NewFirewallStage(&FirewallStageOptions{})
 	assert.Equal(t, expectedFirewall, actualFirewall)
 }
+
+func TestFirewallStageOptionsZonesJSON(t *testing.T) {
+	data, err := json.Marshal(&FirewallStageOptions{Ports: []string{"22:tcp"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ports": ["22:tcp"]}`, string(data))
+
+	data, err = json.Marshal(&FirewallStageOptions{
+		DefaultZone: "drop",
+		Zones: []FirewallZone{
+			{Name: "internal", Interfaces: []string{"eth1"}},
+			{Name: "trusted", Sources: []string{"10.0.0.0/8"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"default_zone": "drop",
+		"zones": [
+			{"name": "internal", "interfaces": ["eth1"]},
+			{"name": "trusted", "sources": ["10.0.0.0/8"]}
+		]
+	}`, string(data))
+}
@@ -28,8 +28,15 @@ func NewFSTabStage(options *FSTabStageOptions) *Stage {
 // An FSTabEntry represents one line in /etc/fstab. With the one exception
 // that the the spec field must be represented as an UUID.
 type FSTabEntry struct {
-	UUID    string `json:"uuid,omitempty"`
-	Label   string `json:"label,omitempty"`
+	UUID  string `json:"uuid,omitempty"`
+	Label string `json:"label,omitempty"`
+	// UUID of the partition the filesystem is on
+	PartUUID string `json:"partuuid,omitempty"`
+	// Name of the GPT partition the filesystem is on
+	PartLabel string `json:"partlabel,omitempty"`
+	// Path of the device or file, used for entries that aren't a
+	// filesystem, e.g. swap files
+	Device  string `json:"device,omitempty"`
 	VFSType string `json:"vfs_type"`
 	Path    string `json:"path,omitempty"`
 	Options string `json:"options,omitempty"`
@@ -0,0 +1,77 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GenprotimgStageOptions describe the IBM Secure Execution image that the
+// genprotimg stage creates from a kernel, an initrd and a parmfile with the
+// kernel command line. The image is encrypted for the hosts of the host key
+// documents, which are passed to the stage as inputs.
+type GenprotimgStageOptions struct {
+	// Paths in the tree of the kernel and the initrd
+	Kernel string `json:"kernel"`
+	Initrd string `json:"initrd"`
+
+	// Path in the tree of the file with the kernel command line
+	Parmfile string `json:"parmfile"`
+
+	// URLs of the host key documents, e.g. input://hostkeys/sha256:...
+	HostKeyDocuments []string `json:"host-key-documents"`
+
+	// Path in the tree of the Secure Execution image
+	Output string `json:"output"`
+}
+
+func (GenprotimgStageOptions) isStageOptions() {}
+
+// alias for custom marshaller
+type genprotimgStageOptions GenprotimgStageOptions
+
+// Custom marshaller for validating
+func (options GenprotimgStageOptions) MarshalJSON() ([]byte, error) {
+	if options.Kernel == "" || options.Initrd == "" || options.Parmfile == "" || options.Output == "" {
+		return nil, fmt.Errorf("org.osbuild.genprotimg: kernel, initrd, parmfile and output are required")
+	}
+	if len(options.HostKeyDocuments) == 0 {
+		return nil, fmt.Errorf("org.osbuild.genprotimg: at least one host key document is required")
+	}
+	return json.Marshal(genprotimgStageOptions(options))
+}
+
+type GenprotimgStageInputs struct {
+	HostKeys *GenprotimgStageInput `json:"hostkeys"`
+}
+
+func (GenprotimgStageInputs) isStageInputs() {}
+
+type GenprotimgStageInput struct {
+	inputCommon
+	References GenprotimgStageReferences `json:"references"`
+}
+
+func (GenprotimgStageInput) isStageInput() {}
+
+type GenprotimgStageReferences []string
+
+func (GenprotimgStageReferences) isReferences() {}
+
+// NewGenprotimgStageInputs returns the inputs of the host key documents,
+// which reference the items of a source by their checksums.
+func NewGenprotimgStageInputs(checksums []string) *GenprotimgStageInputs {
+	input := new(GenprotimgStageInput)
+	input.Type = InputTypeFiles
+	input.Origin = InputOriginSource
+	input.References = checksums
+	return &GenprotimgStageInputs{HostKeys: input}
+}
+
+// Create an IBM Secure Execution image for s390x
+func NewGenprotimgStage(options *GenprotimgStageOptions, inputs *GenprotimgStageInputs) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.genprotimg",
+		Options: options,
+		Inputs:  inputs,
+	}
+}
@@ -0,0 +1,27 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenprotimgStageOptions_MarshalJSON(t *testing.T) {
+	options := GenprotimgStageOptions{
+		Kernel:   "/boot/vmlinuz",
+		Initrd:   "/boot/initramfs.img",
+		Parmfile: "/etc/kernel/cmdline",
+		Output:   "/boot/secure-linux",
+	}
+	_, err := json.Marshal(&options)
+	assert.EqualError(t, err, "json: error calling MarshalJSON for type *osbuild2.GenprotimgStageOptions: org.osbuild.genprotimg: at least one host key document is required")
+
+	options.HostKeyDocuments = []string{"input://hostkeys/sha256:01"}
+	_, err = json.Marshal(&options)
+	assert.NoError(t, err)
+
+	options.Parmfile = ""
+	_, err = json.Marshal(&options)
+	assert.EqualError(t, err, "json: error calling MarshalJSON for type *osbuild2.GenprotimgStageOptions: org.osbuild.genprotimg: kernel, initrd, parmfile and output are required")
+}
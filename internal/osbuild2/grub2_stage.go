@@ -1,6 +1,12 @@
 package osbuild2
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
 
 // The GRUB2StageOptions describes the bootloader configuration.
 //
@@ -11,23 +17,93 @@ import "github.com/google/uuid"
 // Note that it is the role of an assembler to install any necessary
 // bootloaders that are stored in the image outside of any filesystem.
 type GRUB2StageOptions struct {
-	RootFilesystemUUID uuid.UUID  `json:"root_fs_uuid"`
-	BootFilesystemUUID *uuid.UUID `json:"boot_fs_uuid,omitempty"`
-	KernelOptions      string     `json:"kernel_opts,omitempty"`
-	Legacy             string     `json:"legacy,omitempty"`
-	UEFI               *GRUB2UEFI `json:"uefi,omitempty"`
-	SavedEntry         string     `json:"saved_entry,omitempty"`
-	Greenboot          bool       `json:"greenboot,omitempty"`
+	RootFilesystemUUID uuid.UUID `json:"root_fs_uuid"`
+	// Identifies the root filesystem by UUID or label instead of
+	// RootFilesystemUUID, which must be unset then
+	RootFilesystem     *GRUB2FSDesc `json:"rootfs,omitempty"`
+	BootFilesystemUUID *uuid.UUID   `json:"boot_fs_uuid,omitempty"`
+	KernelOptions      string       `json:"kernel_opts,omitempty"`
+	Legacy             string       `json:"legacy,omitempty"`
+	UEFI               *GRUB2UEFI   `json:"uefi,omitempty"`
+	SavedEntry         string       `json:"saved_entry,omitempty"`
+	Greenboot          bool         `json:"greenboot,omitempty"`
+	Config             *GRUB2Config `json:"config,omitempty"`
+	// Users allowed to edit menu entries and use the GRUB command line
+	Superusers []string `json:"superusers,omitempty"`
+	// PBKDF2 hash of the password of the superusers, as generated by
+	// grub2-mkpasswd-pbkdf2
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// GRUB2Config sets variables of /etc/default/grub
+type GRUB2Config struct {
+	// The default menu entry, GRUB_DEFAULT
+	Default string `json:"default,omitempty"`
+	// Seconds until the default entry is booted, GRUB_TIMEOUT
+	Timeout *int `json:"timeout,omitempty"`
+	// How the menu is shown while waiting, GRUB_TIMEOUT_STYLE
+	TimeoutStyle GRUB2TimeoutStyle `json:"timeout_style,omitempty"`
+	// Input and output terminals, e.g. console and serial, GRUB_TERMINAL
+	Terminal []string `json:"terminal,omitempty"`
+	// Command that sets up the serial terminal, GRUB_SERIAL_COMMAND
+	Serial string `json:"serial,omitempty"`
 }
 
+type GRUB2TimeoutStyle string
+
+const (
+	GRUB2TimeoutStyleMenu      GRUB2TimeoutStyle = "menu"
+	GRUB2TimeoutStyleCountdown GRUB2TimeoutStyle = "countdown"
+	GRUB2TimeoutStyleHidden    GRUB2TimeoutStyle = "hidden"
+)
+
 type GRUB2UEFI struct {
 	Vendor  string `json:"vendor"`
 	Install bool   `json:"install,omitempty"`
 	Unified bool   `json:"unified,omitempty"`
 }
 
+// GRUB2FSDesc identifies a filesystem by either its UUID or its label
+type GRUB2FSDesc struct {
+	UUID  *uuid.UUID `json:"uuid,omitempty"`
+	Label string     `json:"label,omitempty"`
+}
+
 func (GRUB2StageOptions) isStageOptions() {}
 
+// alias for custom marshaller
+type grub2StageOptions GRUB2StageOptions
+
+// Custom marshaller that omits root_fs_uuid when the root filesystem is
+// described by rootfs
+func (options GRUB2StageOptions) MarshalJSON() ([]byte, error) {
+	if (len(options.Superusers) > 0) != (options.PasswordHash != "") {
+		return nil, fmt.Errorf("superusers and password_hash of the grub2 stage must be set together")
+	}
+	// never let a plain text password end up in the manifest
+	if options.PasswordHash != "" && !strings.HasPrefix(options.PasswordHash, "grub.pbkdf2.") {
+		return nil, fmt.Errorf("password_hash of the grub2 stage must be a grub.pbkdf2 hash")
+	}
+
+	if options.RootFilesystem == nil {
+		return json.Marshal(grub2StageOptions(options))
+	}
+
+	if options.RootFilesystemUUID != uuid.Nil {
+		return nil, fmt.Errorf("root_fs_uuid and rootfs of the grub2 stage are mutually exclusive")
+	}
+	if (options.RootFilesystem.UUID == nil) == (options.RootFilesystem.Label == "") {
+		return nil, fmt.Errorf("rootfs of the grub2 stage needs either a uuid or a label")
+	}
+
+	return json.Marshal(struct {
+		grub2StageOptions
+		RootFilesystemUUID *uuid.UUID `json:"root_fs_uuid,omitempty"`
+	}{
+		grub2StageOptions: grub2StageOptions(options),
+	})
+}
+
 // NewGRUB2Stage creates a new GRUB2 stage object.
 func NewGRUB2Stage(options *GRUB2StageOptions) *Stage {
 	return &Stage{
@@ -1,9 +1,12 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewGRUB2Stage(t *testing.T) {
@@ -14,3 +17,76 @@ func TestNewGRUB2Stage(t *testing.T) {
 	actualStage := NewGRUB2Stage(&GRUB2StageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestGRUB2StageOptions_MarshalJSON(t *testing.T) {
+	rootUUID := uuid.MustParse("6e4ff95f-f662-45ee-a82a-bdf44a2d0b75")
+
+	data, err := json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"root_fs_uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75"}`, string(data))
+
+	data, err = json.Marshal(GRUB2StageOptions{RootFilesystem: &GRUB2FSDesc{Label: "root"}, KernelOptions: "ro"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rootfs":{"label":"root"},"kernel_opts":"ro"}`, string(data))
+
+	data, err = json.Marshal(&GRUB2StageOptions{RootFilesystem: &GRUB2FSDesc{UUID: &rootUUID}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"rootfs":{"uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75"}}`, string(data))
+
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID, RootFilesystem: &GRUB2FSDesc{Label: "root"}})
+	assert.Error(t, err)
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystem: &GRUB2FSDesc{}})
+	assert.Error(t, err)
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystem: &GRUB2FSDesc{UUID: &rootUUID, Label: "root"}})
+	assert.Error(t, err)
+}
+
+func TestGRUB2StageOptions_Config(t *testing.T) {
+	rootUUID := uuid.MustParse("6e4ff95f-f662-45ee-a82a-bdf44a2d0b75")
+	timeout := 0
+
+	data, err := json.Marshal(GRUB2StageOptions{
+		RootFilesystemUUID: rootUUID,
+		Config: &GRUB2Config{
+			Default:      "saved",
+			Timeout:      &timeout,
+			TimeoutStyle: GRUB2TimeoutStyleHidden,
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"root_fs_uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75","config":{"default":"saved","timeout":0,"timeout_style":"hidden"}}`, string(data))
+
+	data, err = json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID, Config: &GRUB2Config{Default: "1"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"root_fs_uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75","config":{"default":"1"}}`, string(data))
+
+	data, err = json.Marshal(GRUB2StageOptions{
+		RootFilesystemUUID: rootUUID,
+		Config: &GRUB2Config{
+			Terminal: []string{"serial"},
+			Serial:   "serial --speed=115200",
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"root_fs_uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75","config":{"terminal":["serial"],"serial":"serial --speed=115200"}}`, string(data))
+}
+
+func TestGRUB2StageOptions_Password(t *testing.T) {
+	rootUUID := uuid.MustParse("6e4ff95f-f662-45ee-a82a-bdf44a2d0b75")
+
+	data, err := json.Marshal(GRUB2StageOptions{
+		RootFilesystemUUID: rootUUID,
+		Superusers:         []string{"root"},
+		PasswordHash:       "grub.pbkdf2.sha512.10000.AB.CD",
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"root_fs_uuid":"6e4ff95f-f662-45ee-a82a-bdf44a2d0b75","superusers":["root"],"password_hash":"grub.pbkdf2.sha512.10000.AB.CD"}`, string(data))
+
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID, Superusers: []string{"root"}})
+	assert.Error(t, err)
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID, PasswordHash: "grub.pbkdf2.sha512.10000.AB.CD"})
+	assert.Error(t, err)
+	// plain text passwords are refused
+	_, err = json.Marshal(GRUB2StageOptions{RootFilesystemUUID: rootUUID, Superusers: []string{"root"}, PasswordHash: "secret"})
+	assert.Error(t, err)
+}
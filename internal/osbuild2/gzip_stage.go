@@ -0,0 +1,51 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type GzipStageOptions struct {
+	// Filename for gzip archive
+	Filename string `json:"filename"`
+
+	// Compression level from 1 to 9, the default of gzip if 0
+	Level uint `json:"level,omitempty"`
+}
+
+func (GzipStageOptions) isStageOptions() {}
+
+func NewGzipStageOptions(filename string) *GzipStageOptions {
+	return &GzipStageOptions{
+		Filename: filename,
+	}
+}
+
+// alias for custom marshaller
+type gzipStageOptions GzipStageOptions
+
+// Custom marshaller for validating
+func (options GzipStageOptions) MarshalJSON() ([]byte, error) {
+	if options.Level > 9 {
+		return nil, fmt.Errorf("invalid gzip compression level %d", options.Level)
+	}
+	return json.Marshal(gzipStageOptions(options))
+}
+
+type GzipStageInputs interface {
+	isGzipStageInputs()
+}
+
+// Compresses a file into a gzip archive.
+func NewGzipStage(options *GzipStageOptions, inputs GzipStageInputs) *Stage {
+	var stageInputs Inputs
+	if inputs != nil {
+		stageInputs = inputs.(Inputs)
+	}
+
+	return &Stage{
+		Type:    "org.osbuild.gzip",
+		Options: options,
+		Inputs:  stageInputs,
+	}
+}
@@ -0,0 +1,40 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGzipStageOptions(t *testing.T) {
+	filename := "image.raw.gz"
+
+	expectedOptions := &GzipStageOptions{
+		Filename: filename,
+	}
+
+	actualOptions := NewGzipStageOptions(filename)
+	assert.Equal(t, expectedOptions, actualOptions)
+}
+
+func TestNewGzipStage(t *testing.T) {
+	inputFilename := "image.raw"
+	filename := "image.raw.gz"
+	pipeline := "os"
+
+	expectedStage := &Stage{
+		Type:    "org.osbuild.gzip",
+		Options: NewGzipStageOptions(filename),
+		Inputs:  NewFilesInputs(NewFilesInputReferencesPipeline(pipeline, inputFilename)),
+	}
+
+	actualStage := NewGzipStage(NewGzipStageOptions(filename),
+		NewFilesInputs(NewFilesInputReferencesPipeline(pipeline, inputFilename)))
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestGzipStageOptions_MarshalJSON(t *testing.T) {
+	_, err := json.Marshal(GzipStageOptions{Filename: "image.raw.gz", Level: 10})
+	assert.Error(t, err)
+}
@@ -0,0 +1,19 @@
+package osbuild2
+
+// IgnitionStageOptions marks the tree for Ignition provisioning on first
+// boot. The stage creates /boot/ignition.firstboot, which GRUB reads to
+// add the first boot kernel arguments.
+type IgnitionStageOptions struct {
+	// Kernel arguments for the network configuration on first boot
+	Network []string `json:"network,omitempty"`
+}
+
+func (IgnitionStageOptions) isStageOptions() {}
+
+// NewIgnitionStage creates a new org.osbuild.ignition stage
+func NewIgnitionStage(options *IgnitionStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.ignition",
+		Options: options,
+	}
+}
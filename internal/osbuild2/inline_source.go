@@ -0,0 +1,45 @@
+package osbuild2
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// InlineSource embeds the data of files in the manifest. The items are
+// indexed by the checksum of their data.
+type InlineSource struct {
+	Items map[string]InlineSourceItem `json:"items"`
+}
+
+func (InlineSource) isSource() {}
+
+type InlineSourceItem struct {
+	// Encoding of the data, only base64 is supported
+	Encoding string `json:"encoding"`
+	Data     string `json:"data"`
+}
+
+// NewInlineSource creates a new org.osbuild.inline source without items.
+func NewInlineSource() *InlineSource {
+	return &InlineSource{
+		Items: make(map[string]InlineSourceItem),
+	}
+}
+
+// AddItem adds the data to the source and returns the checksum that
+// references it in inputs.
+func (s *InlineSource) AddItem(data []byte) string {
+	checksum := InlineSourceChecksum(data)
+	s.Items[checksum] = InlineSourceItem{
+		Encoding: "base64",
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}
+	return checksum
+}
+
+// InlineSourceChecksum returns the checksum under which data is added to an
+// inline source.
+func InlineSourceChecksum(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
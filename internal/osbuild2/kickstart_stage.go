@@ -7,6 +7,26 @@ type KickstartStageOptions struct {
 	OSTree *OSTreeOptions `json:"ostree,omitempty"`
 
 	LiveIMG *LiveIMG `json:"liveimg,omitempty"`
+
+	// Language of the installation, e.g. en_US.UTF-8
+	Language string `json:"lang,omitempty"`
+
+	// Keyboard layout of the installation, e.g. us
+	Keyboard string `json:"keyboard,omitempty"`
+
+	// Timezone of the installation, e.g. Europe/Berlin
+	Timezone string `json:"timezone,omitempty"`
+
+	// Users created by the installer, with the options of the users stage
+	// that kickstart supports
+	Users map[string]UsersStageOptionsUser `json:"users,omitempty"`
+
+	// Groups created by the installer
+	Groups map[string]GroupsStageOptionsGroup `json:"groups,omitempty"`
+
+	// %post sections, written to the kickstart file in the given order. The
+	// bodies of the scripts are passed to the stage as inputs.
+	Post []PostOptions `json:"post,omitempty"`
 }
 
 type LiveIMG struct {
@@ -20,8 +40,46 @@ type OSTreeOptions struct {
 	GPG    bool   `json:"gpg"`
 }
 
+type PostOptions struct {
+	// Interpreter used to run the script; defaults to /bin/sh
+	Interpreter string `json:"interpreter,omitempty"`
+	// Abort the installation if the script fails
+	ErrorOnFail bool `json:"erroronfail,omitempty"`
+	// Run the script outside of the chroot of the installed system
+	NoChroot bool `json:"nochroot,omitempty"`
+	// URL of the body of the script, e.g. input://post/sha256:...
+	Script string `json:"script"`
+}
+
 func (KickstartStageOptions) isStageOptions() {}
 
+type KickstartStageInputs struct {
+	Post *KickstartStageInput `json:"post"`
+}
+
+func (KickstartStageInputs) isStageInputs() {}
+
+type KickstartStageInput struct {
+	inputCommon
+	References KickstartStageReferences `json:"references"`
+}
+
+func (KickstartStageInput) isStageInput() {}
+
+type KickstartStageReferences []string
+
+func (KickstartStageReferences) isReferences() {}
+
+// NewKickstartStageInputs returns the inputs of the bodies of the %post
+// scripts, which reference the items of a source by their checksums.
+func NewKickstartStageInputs(checksums []string) *KickstartStageInputs {
+	input := new(KickstartStageInput)
+	input.Type = InputTypeFiles
+	input.Origin = InputOriginSource
+	input.References = checksums
+	return &KickstartStageInputs{Post: input}
+}
+
 // Creates an Anaconda kickstart file
 func NewKickstartStage(options *KickstartStageOptions) *Stage {
 	return &Stage{
@@ -29,3 +87,13 @@ func NewKickstartStage(options *KickstartStageOptions) *Stage {
 		Options: options,
 	}
 }
+
+// NewKickstartStageWithInputs creates an Anaconda kickstart file with %post
+// sections, whose scripts are read from the inputs
+func NewKickstartStageWithInputs(options *KickstartStageOptions, inputs *KickstartStageInputs) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.kickstart",
+		Options: options,
+		Inputs:  inputs,
+	}
+}
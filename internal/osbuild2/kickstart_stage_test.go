@@ -0,0 +1,98 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKickstartStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.kickstart",
+		Options: &KickstartStageOptions{},
+	}
+	actualStage := NewKickstartStage(&KickstartStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestKickstartStagePostJSON(t *testing.T) {
+	options := &KickstartStageOptions{
+		Path: "/osbuild.ks",
+		Post: []PostOptions{
+			{
+				Script: "input://post/sha256:01",
+			},
+			{
+				Interpreter: "/usr/bin/python3",
+				ErrorOnFail: true,
+				NoChroot:    true,
+				Script:      "input://post/sha256:02",
+			},
+		},
+	}
+	stage := NewKickstartStageWithInputs(options, NewKickstartStageInputs([]string{"sha256:01", "sha256:02"}))
+
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "org.osbuild.kickstart",
+		"inputs": {
+			"post": {"type": "org.osbuild.files", "origin": "org.osbuild.source", "references": ["sha256:01", "sha256:02"]}
+		},
+		"options": {
+			"path": "/osbuild.ks",
+			"post": [
+				{"script": "input://post/sha256:01"},
+				{"interpreter": "/usr/bin/python3", "erroronfail": true, "nochroot": true, "script": "input://post/sha256:02"}
+			]
+		}
+	}`, string(data))
+}
+
+func TestKickstartStageOptionsUsersJSON(t *testing.T) {
+	uid := 1000
+	gid := 1050
+	password := "$6$salt$hash"
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIF0 user@example.com"
+	options := &KickstartStageOptions{
+		Path: "/osbuild.ks",
+		Users: map[string]UsersStageOptionsUser{
+			"user": {
+				UID:      &uid,
+				Groups:   []string{"wheel", "admins"},
+				Password: &password,
+				Key:      &key,
+			},
+		},
+		Groups: map[string]GroupsStageOptionsGroup{
+			"admins": {Name: "admins", GID: &gid},
+		},
+	}
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"path": "/osbuild.ks",
+		"users": {
+			"user": {"uid": 1000, "groups": ["wheel", "admins"], "password": "$6$salt$hash", "key": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIF0 user@example.com"}
+		},
+		"groups": {
+			"admins": {"name": "admins", "gid": 1050}
+		}
+	}`, string(data))
+}
+
+func TestKickstartStageOptionsLocalizationJSON(t *testing.T) {
+	options := &KickstartStageOptions{
+		Path:     "/osbuild.ks",
+		Language: "de_DE.UTF-8",
+		Keyboard: "de",
+		Timezone: "Europe/Berlin",
+	}
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path": "/osbuild.ks", "lang": "de_DE.UTF-8", "keyboard": "de", "timezone": "Europe/Berlin"}`, string(data))
+}
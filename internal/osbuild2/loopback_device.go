@@ -14,6 +14,13 @@ type LoopbackDeviceOptions struct {
 
 	// Sector size (in bytes)
 	SectorSize *uint64 `json:"sector-size,omitempty"`
+
+	// Scan the device for partitions
+	Partscan bool `json:"partscan,omitempty"`
+
+	// Lock the device while it is in use, which serializes the use of
+	// the same file by concurrent builds
+	Lock bool `json:"lock,omitempty"`
 }
 
 func (LoopbackDeviceOptions) isDeviceOptions() {}
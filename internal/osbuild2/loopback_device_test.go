@@ -0,0 +1,27 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopbackDeviceOptions_MarshalJSON(t *testing.T) {
+	// unset fields are omitted, which keeps the devices of existing
+	// manifests unchanged
+	data, err := json.Marshal(NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img", Start: 2048, Size: 4096}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"org.osbuild.loopback","options":{"filename":"disk.img","start":2048,"size":4096}}`, string(data))
+
+	sectorSize := uint64(4096)
+	data, err = json.Marshal(NewLoopbackDevice(&LoopbackDeviceOptions{
+		Filename:   "disk.img",
+		SectorSize: &sectorSize,
+		Partscan:   true,
+		Lock:       true,
+	}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"org.osbuild.loopback","options":{"filename":"disk.img","sector-size":4096,"partscan":true,"lock":true}}`, string(data))
+}
@@ -0,0 +1,33 @@
+package osbuild2
+
+// Format a device as a LUKS2 container
+
+type LUKS2FormatStageOptions struct {
+	Passphrase string `json:"passphrase"`
+	UUID       string `json:"uuid"`
+	Label      string `json:"label,omitempty"`
+	Cipher     string `json:"cipher,omitempty"`
+
+	// Key derivation function and its parameters, the cryptsetup default
+	// if unset
+	PBKDF *LUKS2PBKDF `json:"pbkdf,omitempty"`
+}
+
+func (LUKS2FormatStageOptions) isStageOptions() {}
+
+type LUKS2PBKDF struct {
+	// argon2i, argon2id or pbkdf2
+	Method string `json:"method"`
+
+	Iterations  uint `json:"iterations,omitempty"`
+	Memory      uint `json:"memory,omitempty"`
+	Parallelism uint `json:"parallelism,omitempty"`
+}
+
+func NewLUKS2FormatStage(options *LUKS2FormatStageOptions, device *Device) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.luks2.format",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+}
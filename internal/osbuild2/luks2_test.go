@@ -0,0 +1,75 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLUKS2FormatStage(t *testing.T) {
+	device := NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img", Start: 2048, Size: 4096})
+	options := &LUKS2FormatStageOptions{
+		Passphrase: "secret",
+		UUID:       "0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+		PBKDF:      &LUKS2PBKDF{Method: "argon2i", Memory: 32, Iterations: 4, Parallelism: 1},
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.luks2.format",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+	assert.Equal(t, expectedStage, NewLUKS2FormatStage(options, device))
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"passphrase": "secret",
+		"uuid": "0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+		"pbkdf": {"method": "argon2i", "iterations": 4, "memory": 32, "parallelism": 1}
+	}`, string(data))
+}
+
+func TestNewLUKS2Device(t *testing.T) {
+	device := NewLUKS2Device("encrypted", &LUKS2DeviceOptions{Passphrase: "secret"})
+	data, err := json.Marshal(device)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "org.osbuild.luks2", "parent": "encrypted", "options": {"passphrase": "secret"}}`, string(data))
+}
+
+func TestNewClevisLuksBindStage(t *testing.T) {
+	device := NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img"})
+	options := &ClevisLuksBindStageOptions{
+		Passphrase: "secret",
+		Pin:        "tang",
+		Policy:     `{"url": "http://tang.example.com"}`,
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.clevis.luks-bind",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+	assert.Equal(t, expectedStage, NewClevisLuksBindStage(options, device))
+}
+
+func TestNewCrypttabStage(t *testing.T) {
+	options := &CrypttabStageOptions{
+		Volumes: []CrypttabVolume{
+			{
+				Volume:  "luks-0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+				UUID:    "0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+				Options: "discard",
+			},
+		},
+	}
+	assert.Equal(t, &Stage{Type: "org.osbuild.crypttab", Options: options}, NewCrypttabStage(options))
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"volumes": [{
+		"volume": "luks-0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+		"uuid": "0b07c1cc-c4c6-4ec2-bc15-bbb7dd0c3a6e",
+		"options": "discard"
+	}]}`, string(data))
+}
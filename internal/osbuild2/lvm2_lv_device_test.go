@@ -1 +1,22 @@
 package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLVM2LVDevice(t *testing.T) {
+	device := NewLVM2LVDevice("pv", &LVM2LVDeviceOptions{Volume: "rootlv"})
+	assert.Equal(t, &Device{
+		Type:    "org.osbuild.lvm2.lv",
+		Parent:  "pv",
+		Options: &LVM2LVDeviceOptions{Volume: "rootlv"},
+	}, device)
+
+	data, err := json.Marshal(device)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "org.osbuild.lvm2.lv", "parent": "pv", "options": {"volume": "rootlv"}}`, string(data))
+}
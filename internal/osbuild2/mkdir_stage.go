@@ -2,7 +2,7 @@ package osbuild2
 
 import "os"
 
-// Options for the org.osbuild.ostree.config stage.
+// Options for the org.osbuild.mkdir stage.
 type MkdirStageOptions struct {
 	Paths []Path `json:"paths"`
 }
@@ -11,11 +11,17 @@ type Path struct {
 	Path string `json:"path"`
 
 	Mode os.FileMode `json:"mode,omitempty"`
+
+	// Create missing parent directories, like mkdir -p
+	Parents bool `json:"parents,omitempty"`
+
+	// Don't fail if the directory already exists
+	ExistOk bool `json:"exist_ok,omitempty"`
 }
 
 func (MkdirStageOptions) isStageOptions() {}
 
-// A new org.osbuild.ostree.init stage to create an OSTree repository
+// A new org.osbuild.mkdir stage to create directories
 func NewMkdirStage(options *MkdirStageOptions) *Stage {
 	return &Stage{
 		Type:    "org.osbuild.mkdir",
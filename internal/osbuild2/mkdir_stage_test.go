@@ -0,0 +1,41 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMkdirStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.mkdir",
+		Options: &MkdirStageOptions{},
+	}
+	actualStage := NewMkdirStage(&MkdirStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestMkdirStageOptions_MarshalJSON(t *testing.T) {
+	// unset fields are omitted, which keeps the options of existing
+	// manifests unchanged
+	data, err := json.Marshal(&MkdirStageOptions{
+		Paths: []Path{
+			{Path: "/boot/efi", Mode: os.FileMode(0700)},
+			{Path: "images"},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":[{"path":"/boot/efi","mode":448},{"path":"images"}]}`, string(data))
+
+	data, err = json.Marshal(&MkdirStageOptions{
+		Paths: []Path{
+			{Path: "/var/lib/app/data", Mode: os.FileMode(0750), Parents: true, ExistOk: true},
+			{Path: "/srv", ExistOk: true},
+		},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"paths":[{"path":"/var/lib/app/data","mode":488,"parents":true,"exist_ok":true},{"path":"/srv","exist_ok":true}]}`, string(data))
+}
@@ -0,0 +1,18 @@
+package osbuild2
+
+// MkswapStageOptions set the UUID and the label of the swap signature the
+// org.osbuild.mkswap stage writes on a swap partition
+type MkswapStageOptions struct {
+	UUID  string `json:"uuid"`
+	Label string `json:"label,omitempty"`
+}
+
+func (MkswapStageOptions) isStageOptions() {}
+
+func NewMkswapStage(options *MkswapStageOptions, device *Device) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.mkswap",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+}
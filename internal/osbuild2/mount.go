@@ -3,13 +3,19 @@ package osbuild2
 type Mounts []Mount
 
 type Mount struct {
-	Name    string        `json:"name"`
-	Type    string        `json:"type"`
-	Source  string        `json:"source"`
-	Target  string        `json:"target"`
-	Options *MountOptions `json:"options,omitempty"`
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Source  string       `json:"source"`
+	Target  string       `json:"target"`
+	Options MountOptions `json:"options,omitempty"`
 }
 
-type MountOptions interface {
-	isMountOptions()
-}
+// MountOptions are passed to mount(8) when the filesystem is mounted
+type MountOptions []string
+
+const (
+	// Mount the filesystem read-only
+	MountOptionReadOnly = "ro"
+	// Don't update the access times of files
+	MountOptionNoAtime = "noatime"
+)
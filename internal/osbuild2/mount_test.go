@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewMounts(t *testing.T) {
@@ -53,3 +55,74 @@ func TestNewMounts(t *testing.T) {
 		assert.Equal(expected, actual)
 	}
 }
+
+func TestNewMountsWithOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	{ // btrfs
+		actual := NewBtrfsMount("btrfs", "/dev/sda1", "/mnt/btrfs", MountOptionNoAtime)
+		expected := &Mount{
+			Name:    "btrfs",
+			Type:    "org.osbuild.btrfs",
+			Source:  "/dev/sda1",
+			Target:  "/mnt/btrfs",
+			Options: MountOptions{"noatime"},
+		}
+		assert.Equal(expected, actual)
+	}
+
+	{ // ext4
+		actual := NewExt4Mount("ext4", "/dev/sda2", "/mnt/ext4", MountOptionReadOnly, MountOptionNoAtime)
+		expected := &Mount{
+			Name:    "ext4",
+			Type:    "org.osbuild.ext4",
+			Source:  "/dev/sda2",
+			Target:  "/mnt/ext4",
+			Options: MountOptions{"ro", "noatime"},
+		}
+		assert.Equal(expected, actual)
+	}
+
+	{ // fat
+		actual := NewFATMount("fat", "/dev/sda3", "/mnt/fat", MountOptionReadOnly)
+		expected := &Mount{
+			Name:    "fat",
+			Type:    "org.osbuild.fat",
+			Source:  "/dev/sda3",
+			Target:  "/mnt/fat",
+			Options: MountOptions{"ro"},
+		}
+		assert.Equal(expected, actual)
+	}
+
+	{ // xfs
+		actual := NewXfsMount("xfs", "/dev/sda4", "/mnt/xfs", MountOptionReadOnly)
+		expected := &Mount{
+			Name:    "xfs",
+			Type:    "org.osbuild.xfs",
+			Source:  "/dev/sda4",
+			Target:  "/mnt/xfs",
+			Options: MountOptions{"ro"},
+		}
+		assert.Equal(expected, actual)
+	}
+}
+
+func TestMount_MarshalJSON(t *testing.T) {
+	// mounts without options don't have an options property
+	for _, mount := range []*Mount{
+		NewBtrfsMount("root", "root", "/"),
+		NewExt4Mount("root", "root", "/"),
+		NewFATMount("root", "root", "/"),
+		NewXfsMount("root", "root", "/"),
+		{Name: "root", Type: "org.osbuild.xfs", Source: "root", Target: "/", Options: MountOptions{}},
+	} {
+		data, err := json.Marshal(mount)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"root","type":"`+mount.Type+`","source":"root","target":"/"}`, string(data))
+	}
+
+	data, err := json.Marshal(NewFATMount("efi", "efi", "/boot/efi", MountOptionReadOnly, MountOptionNoAtime))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"efi","type":"org.osbuild.fat","source":"efi","target":"/boot/efi","options":["ro","noatime"]}`, string(data))
+}
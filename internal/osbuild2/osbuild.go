@@ -17,6 +17,11 @@ type Pipeline struct {
 
 	Runner string `json:"runner,omitempty"`
 
+	// Timestamp, in seconds since the Unix epoch, that osbuild exports as
+	// SOURCE_DATE_EPOCH to all stages of the pipeline. Stages that honor it
+	// (rpm, squashfs, xorrisofs, tar, ...) use it instead of the current time.
+	SourceEpoch *int64 `json:"source-epoch,omitempty"`
+
 	// Sequence of stages that produce the filesystem tree, which is the
 	// payload of the produced image.
 	Stages []*Stage `json:"stages,omitempty"`
@@ -3,6 +3,7 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,3 +27,16 @@ func TestPipeline_AddStage(t *testing.T) {
 	assert.Equal(t, expectedPipeline, actualPipeline)
 	assert.Equal(t, 1, len(actualPipeline.Stages))
 }
+
+func TestPipeline_SourceEpoch(t *testing.T) {
+	pipeline := Pipeline{Name: "os"}
+	data, err := json.Marshal(pipeline)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"os"}`, string(data))
+
+	epoch := int64(1634428800)
+	pipeline.SourceEpoch = &epoch
+	data, err = json.Marshal(pipeline)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"os","source-epoch":1634428800}`, string(data))
+}
@@ -0,0 +1,38 @@
+package osbuild2
+
+// OscapRemediationStageOptions are the options of the
+// org.osbuild.oscap.remediation stage, which remediates the tree against a
+// profile of a SCAP datastream with oscap.
+type OscapRemediationStageOptions struct {
+	// Directory of the tree for the results and the reports of the scan
+	DataDir string      `json:"data_dir,omitempty"`
+	Config  OscapConfig `json:"config"`
+}
+
+type OscapConfig struct {
+	// Path of the datastream in the tree
+	Datastream string `json:"datastream"`
+	// ID of the profile the tree is remediated against
+	ProfileID string `json:"profile_id"`
+	// Select a component of the datastream
+	DatastreamID string `json:"datastream_id,omitempty"`
+	XCCDFID      string `json:"xccdf_id,omitempty"`
+	BenchmarkID  string `json:"benchmark_id,omitempty"`
+	// Path of a tailoring file in the tree
+	Tailoring string `json:"tailoring,omitempty"`
+	// File names of the results and the reports in DataDir
+	ARFResult    string `json:"arf_result,omitempty"`
+	HTMLReport   string `json:"html_report,omitempty"`
+	VerboseLog   string `json:"verbose_log,omitempty"`
+	VerboseLevel string `json:"verbose_level,omitempty"`
+}
+
+func (OscapRemediationStageOptions) isStageOptions() {}
+
+// NewOscapRemediationStage creates a new org.osbuild.oscap.remediation stage
+func NewOscapRemediationStage(options *OscapRemediationStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.oscap.remediation",
+		Options: options,
+	}
+}
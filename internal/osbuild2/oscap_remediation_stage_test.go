@@ -0,0 +1,30 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOscapRemediationStage(t *testing.T) {
+	options := &OscapRemediationStageOptions{
+		DataDir: "/var/tmp/oscap",
+		Config: OscapConfig{
+			Datastream: "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml",
+			ProfileID:  "xccdf_org.ssgproject.content_profile_cis",
+		},
+	}
+	assert.Equal(t, &Stage{Type: "org.osbuild.oscap.remediation", Options: options}, NewOscapRemediationStage(options))
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data_dir": "/var/tmp/oscap",
+		"config": {
+			"datastream": "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml",
+			"profile_id": "xccdf_org.ssgproject.content_profile_cis"
+		}
+	}`, string(data))
+}
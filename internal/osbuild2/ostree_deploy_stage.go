@@ -11,9 +11,15 @@ type OSTreeDeployStageOptions struct {
 
 	Ref string `json:"ref"`
 
+	// Name of the remote the ref is recorded with in the origin of the
+	// deployment
+	Remote string `json:"remote,omitempty"`
+
 	Mounts []string `json:"mounts"`
 
-	Rootfs Rootfs `json:"rootfs"`
+	// Root filesystem the root= kernel argument identifies, unless the
+	// kernel options include one
+	Rootfs *Rootfs `json:"rootfs,omitempty"`
 
 	KernelOpts []string `json:"kernel_opts"`
 }
@@ -40,8 +46,7 @@ func NewOSTreeDeployStage(options *OSTreeDeployStageOptions) *Stage {
 type ostreeDeployStageOptions OSTreeDeployStageOptions
 
 func (options OSTreeDeployStageOptions) MarshalJSON() ([]byte, error) {
-	rootfs := options.Rootfs
-	if (len(rootfs.UUID) == 0 && len(rootfs.Label) == 0) || (len(rootfs.UUID) != 0 && len(rootfs.Label) != 0) {
+	if rootfs := options.Rootfs; rootfs != nil && (len(rootfs.UUID) == 0) == (len(rootfs.Label) == 0) {
 		return nil, fmt.Errorf("exactly one of UUID or Label must be specified")
 	}
 
@@ -4,6 +4,9 @@ package osbuild2
 type OSTreePullStageOptions struct {
 	// Location of the ostree repo
 	Repo string `json:"repo"`
+	// Name of the remote the commits are pulled as, i.e. the prefix of
+	// their refs
+	Remote string `json:"remote,omitempty"`
 }
 
 func (OSTreePullStageOptions) isStageOptions() {}
@@ -20,11 +20,14 @@ type OSTreeRemote struct {
 	// URL of the repository.
 	URL string `json:"url"`
 
+	// URL of the content of the repository, if it differs from URL
+	ContentURL string `json:"contenturl,omitempty"`
+
 	// Configured branches for the remote
 	Branches []string `json:"branches,omitempty"`
 
-	// GPG keys to verify the commits
-	GPGKeys []string `json:"secrets,omitempty"`
+	// ASCII armored GPG keys to verify the commits
+	GPGKeys []string `json:"gpgkeys,omitempty"`
 }
 
 // A new org.osbuild.ostree.remotes stage to configure remotes
@@ -0,0 +1,22 @@
+package osbuild2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOSTreeRemotesStage(t *testing.T) {
+	options := &OSTreeRemotesStageOptions{
+		Repo: "/ostree/repo",
+		Remotes: []OSTreeRemote{
+			{Name: "edge", URL: "https://ostree.example.com/repo/"},
+		},
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.ostree.remotes",
+		Options: options,
+	}
+	actualStage := NewOSTreeRemotesStage(options)
+	assert.Equal(t, expectedStage, actualStage)
+}
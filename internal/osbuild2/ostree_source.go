@@ -15,5 +15,18 @@ type OSTreeSourceRemote struct {
 	// URL of the repository.
 	URL string `json:"url"`
 	// GPG keys to verify the commits
-	GPGKeys []string `json:"secrets,omitempty"`
+	GPGKeys []string `json:"gpgkeys,omitempty"`
+	// Secrets to access the repository with
+	Secrets *OSTreeSourceRemoteSecrets `json:"secrets,omitempty"`
 }
+
+type OSTreeSourceRemoteSecrets struct {
+	// Name of the secrets provider
+	Name string `json:"name"`
+}
+
+// The org.osbuild.mtls secrets provider reads the TLS client certificate
+// and key, and the CA certificate, from the files the
+// OSBUILD_SOURCES_OSTREE_SSL_CLIENT_CERT, OSBUILD_SOURCES_OSTREE_SSL_CLIENT_KEY
+// and OSBUILD_SOURCES_OSTREE_SSL_CA_CERT environment variables point to.
+const OSTreeMTLSSecrets = "org.osbuild.mtls"
@@ -0,0 +1,22 @@
+package osbuild2
+
+// Options for the org.osbuild.ostree.static-delta stage.
+type OSTreeStaticDeltaStageOptions struct {
+	// Location of the ostree repo
+	Repo string `json:"repo"`
+	// Commit ID of the commit the delta updates from
+	From string `json:"from"`
+	// Ref or commit ID of the commit the delta updates to
+	To string `json:"to"`
+}
+
+func (OSTreeStaticDeltaStageOptions) isStageOptions() {}
+
+// A new org.osbuild.ostree.static-delta stage to generate a static delta
+// between two commits of a repo, both of which must be in the repo
+func NewOSTreeStaticDeltaStage(options *OSTreeStaticDeltaStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.ostree.static-delta",
+		Options: options,
+	}
+}
@@ -0,0 +1,21 @@
+package osbuild2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOSTreeStaticDeltaStage(t *testing.T) {
+	options := &OSTreeStaticDeltaStageOptions{
+		Repo: "/repo",
+		From: "02604b2da6e954bd34b8b82a835e5a77d2b60ffa",
+		To:   "rhel/8/x86_64/edge",
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.ostree.static-delta",
+		Options: options,
+	}
+	actualStage := NewOSTreeStaticDeltaStage(options)
+	assert.Equal(t, expectedStage, actualStage)
+}
@@ -0,0 +1,19 @@
+package osbuild2
+
+// Options for the org.osbuild.ovf stage.
+type OVFStageOptions struct {
+	// Path of the stream optimized VMDK in the tree
+	VMDK string `json:"vmdk"`
+}
+
+func (OVFStageOptions) isStageOptions() {}
+
+// A new org.osbuild.ovf stage to describe a VMDK as an OVF virtual machine.
+// It writes the OVF descriptor and the manifest with the SHA256 sums of the
+// descriptor and the VMDK next to the VMDK, with the extensions .ovf and .mf.
+func NewOVFStage(options *OVFStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.ovf",
+		Options: options,
+	}
+}
@@ -0,0 +1,19 @@
+package osbuild2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOVFStage(t *testing.T) {
+	options := &OVFStageOptions{
+		VMDK: "image.vmdk",
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.ovf",
+		Options: options,
+	}
+	actualStage := NewOVFStage(options)
+	assert.Equal(t, expectedStage, actualStage)
+}
@@ -8,7 +8,16 @@ import (
 // Convert a disk image to a different format.
 //
 // Some formats support format-specific options:
-//   qcow2: The compatibility version can be specified via 'compat'
+//   qcow2: The compatibility version can be specified via 'compat', the
+//          cluster size via 'cluster_size', lazy refcounts can be enabled
+//          via 'lazy_refcounts' and the clusters can be compressed via
+//          'compression'
+//   vpc:   The image size can be forced to be used as the virtual size via
+//          'force_size'
+//   vmdk:  The layout of the files, e.g. stream optimized, can be chosen
+//          via 'subformat'
+//   vhdx:  The image can be dynamically growing or preallocated via
+//          'subformat'
 
 type QEMUStageOptions struct {
 	// Filename for resulting image
@@ -30,24 +39,80 @@ type Qcow2Options struct {
 
 	// The qcow2-compatibility-version to use
 	Compat string `json:"compat"`
+
+	// The size of the qcow2 clusters in bytes
+	ClusterSize uint64 `json:"cluster_size,omitempty"`
+
+	// Delay refcount updates (requires compat 1.1)
+	LazyRefcounts *bool `json:"lazy_refcounts,omitempty"`
+
+	// Compress the clusters of the image with the given algorithm, zstd
+	// requires compat 1.1
+	Compression string `json:"compression,omitempty"`
 }
 
 func (Qcow2Options) isQEMUFormatOptions() {}
 
+const (
+	// Compression readable by all qcow2 implementations
+	Qcow2CompressionZlib = "zlib"
+	// Faster and better compression, which requires qemu 5.1 or newer
+	Qcow2CompressionZstd = "zstd"
+)
+
 type VPCOptions struct {
 	// The type of the format must be 'vpc'
 	Type string `json:"type"`
+
+	// Use the size of the image as the virtual size instead of rounding it
+	// to the CHS geometry
+	ForceSize *bool `json:"force_size,omitempty"`
 }
 
 func (VPCOptions) isQEMUFormatOptions() {}
 
+const (
+	// A single growable file, the default of qemu-img
+	VMDKSubformatMonolithicSparse = "monolithicSparse"
+	// A single preallocated file
+	VMDKSubformatMonolithicFlat = "monolithicFlat"
+	// Growable files of at most 2GB
+	VMDKSubformatTwoGbMaxExtentSparse = "twoGbMaxExtentSparse"
+	// Preallocated files of at most 2GB
+	VMDKSubformatTwoGbMaxExtentFlat = "twoGbMaxExtentFlat"
+	// A compressed file, as required by OVA appliances
+	VMDKSubformatStreamOptimized = "streamOptimized"
+)
+
 type VMDKOptions struct {
-	// The type of the format must be 'vpc'
+	// The type of the format must be 'vmdk'
 	Type string `json:"type"`
+
+	// The layout of the VMDK files, qemu-img defaults to monolithicSparse
+	Subformat string `json:"subformat,omitempty"`
 }
 
 func (VMDKOptions) isQEMUFormatOptions() {}
 
+const (
+	// The VHDX file grows as data is written to the disk
+	VHDXSubformatDynamic = "dynamic"
+	// The VHDX file is preallocated to the size of the disk
+	VHDXSubformatFixed = "fixed"
+)
+
+// VHDXOptions describe a VHDX file. qemu-img creates VHDX files with 512 byte
+// logical sectors, there is no option for 4K logical sectors.
+type VHDXOptions struct {
+	// The type of the format must be 'vhdx'
+	Type string `json:"type"`
+
+	// Whether the file is dynamic or fixed, qemu-img defaults to dynamic
+	Subformat string `json:"subformat,omitempty"`
+}
+
+func (VHDXOptions) isQEMUFormatOptions() {}
+
 type QEMUStageInputs struct {
 	Image *QEMUStageInput `json:"image"`
 }
@@ -91,6 +156,18 @@ func (options QEMUStageOptions) MarshalJSON() ([]byte, error) {
 		if o.Type != "qcow2" {
 			return nil, fmt.Errorf("invalid format type %q for qcow2 options", o.Type)
 		}
+		if o.LazyRefcounts != nil && *o.LazyRefcounts && o.Compat == "0.10" {
+			return nil, fmt.Errorf("lazy refcounts are not supported by qcow2 compat %s", o.Compat)
+		}
+		switch o.Compression {
+		case "", Qcow2CompressionZlib:
+		case Qcow2CompressionZstd:
+			if o.Compat == "0.10" {
+				return nil, fmt.Errorf("zstd compression is not supported by qcow2 compat %s", o.Compat)
+			}
+		default:
+			return nil, fmt.Errorf("invalid qcow2 compression %q", o.Compression)
+		}
 	case VPCOptions:
 		if o.Type != "vpc" {
 			return nil, fmt.Errorf("invalid format type %q for vpc options", o.Type)
@@ -99,6 +176,18 @@ func (options QEMUStageOptions) MarshalJSON() ([]byte, error) {
 		if o.Type != "vmdk" {
 			return nil, fmt.Errorf("invalid format type %q for vmdk options", o.Type)
 		}
+		switch o.Subformat {
+		case "", VMDKSubformatMonolithicSparse, VMDKSubformatMonolithicFlat, VMDKSubformatTwoGbMaxExtentSparse, VMDKSubformatTwoGbMaxExtentFlat, VMDKSubformatStreamOptimized:
+		default:
+			return nil, fmt.Errorf("invalid vmdk subformat %q", o.Subformat)
+		}
+	case VHDXOptions:
+		if o.Type != "vhdx" {
+			return nil, fmt.Errorf("invalid format type %q for vhdx options", o.Type)
+		}
+		if o.Subformat != "" && o.Subformat != VHDXSubformatDynamic && o.Subformat != VHDXSubformatFixed {
+			return nil, fmt.Errorf("invalid vhdx subformat %q", o.Subformat)
+		}
 	default:
 		return nil, fmt.Errorf("unknown format options in QEMU stage: %#v", options.Format)
 	}
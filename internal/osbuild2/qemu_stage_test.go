@@ -1,9 +1,12 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewQemuStage(t *testing.T) {
@@ -19,6 +22,10 @@ func TestNewQemuStage(t *testing.T) {
 		VMDKOptions{
 			Type: "vmdk",
 		},
+		VHDXOptions{
+			Type:      "vhdx",
+			Subformat: VHDXSubformatDynamic,
+		},
 	}
 
 	input := new(QEMUStageInput)
@@ -46,3 +53,78 @@ func TestNewQemuStage(t *testing.T) {
 		assert.Equal(t, expectedStage, actualStage)
 	}
 }
+
+func TestQEMUStageOptions_MarshalJSON(t *testing.T) {
+	cases := []struct {
+		format   QEMUFormatOptions
+		expected string
+	}{
+		{
+			Qcow2Options{Type: "qcow2"},
+			`{"filename":"img.out","format":{"type":"qcow2","compat":""}}`,
+		},
+		{
+			Qcow2Options{Type: "qcow2", Compat: "1.1", ClusterSize: 2097152, LazyRefcounts: common.BoolToPtr(true)},
+			`{"filename":"img.out","format":{"type":"qcow2","compat":"1.1","cluster_size":2097152,"lazy_refcounts":true}}`,
+		},
+		{
+			Qcow2Options{Type: "qcow2", Compat: "0.10", LazyRefcounts: common.BoolToPtr(false)},
+			`{"filename":"img.out","format":{"type":"qcow2","compat":"0.10","lazy_refcounts":false}}`,
+		},
+		{
+			Qcow2Options{Type: "qcow2", Compat: "0.10", Compression: Qcow2CompressionZlib},
+			`{"filename":"img.out","format":{"type":"qcow2","compat":"0.10","compression":"zlib"}}`,
+		},
+		{
+			Qcow2Options{Type: "qcow2", Compat: "1.1", Compression: Qcow2CompressionZstd},
+			`{"filename":"img.out","format":{"type":"qcow2","compat":"1.1","compression":"zstd"}}`,
+		},
+		{
+			VPCOptions{Type: "vpc"},
+			`{"filename":"img.out","format":{"type":"vpc"}}`,
+		},
+		{
+			VPCOptions{Type: "vpc", ForceSize: common.BoolToPtr(false)},
+			`{"filename":"img.out","format":{"type":"vpc","force_size":false}}`,
+		},
+		{
+			VMDKOptions{Type: "vmdk"},
+			`{"filename":"img.out","format":{"type":"vmdk"}}`,
+		},
+		{
+			VMDKOptions{Type: "vmdk", Subformat: VMDKSubformatStreamOptimized},
+			`{"filename":"img.out","format":{"type":"vmdk","subformat":"streamOptimized"}}`,
+		},
+		{
+			VHDXOptions{Type: "vhdx"},
+			`{"filename":"img.out","format":{"type":"vhdx"}}`,
+		},
+		{
+			VHDXOptions{Type: "vhdx", Subformat: VHDXSubformatFixed},
+			`{"filename":"img.out","format":{"type":"vhdx","subformat":"fixed"}}`,
+		},
+	}
+
+	for _, c := range cases {
+		data, err := json.Marshal(QEMUStageOptions{Filename: "img.out", Format: c.format})
+		require.NoError(t, err)
+		assert.JSONEq(t, c.expected, string(data))
+	}
+
+	invalid := []QEMUFormatOptions{
+		Qcow2Options{Type: "vpc"},
+		Qcow2Options{Type: "qcow2", Compat: "0.10", LazyRefcounts: common.BoolToPtr(true)},
+		Qcow2Options{Type: "qcow2", Compat: "0.10", Compression: Qcow2CompressionZstd},
+		Qcow2Options{Type: "qcow2", Compat: "1.1", Compression: "lzma"},
+		VPCOptions{Type: "qcow2"},
+		VMDKOptions{},
+		VMDKOptions{Type: "vmdk", Subformat: "stream"},
+		VHDXOptions{Type: "vpc"},
+		VHDXOptions{Type: "vhdx", Subformat: "sparse"},
+		nil,
+	}
+	for _, format := range invalid {
+		_, err := json.Marshal(QEMUStageOptions{Filename: "img.out", Format: format})
+		assert.Error(t, err, "%#v", format)
+	}
+}
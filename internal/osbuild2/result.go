@@ -2,6 +2,8 @@ package osbuild2
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 )
 
 type PipelineResult []StageResult
@@ -11,7 +13,9 @@ type StageResult struct {
 	Type    string `json:"type"`
 	Output  string `json:"output"`
 	Success bool   `json:"success,omitempty"`
-	Error   string `json:"string,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Duration of the stage in seconds, if osbuild reports it
+	Duration float64 `json:"duration,omitempty"`
 }
 
 type PipelineMetadata map[string]StageMetadata
@@ -87,3 +91,113 @@ type Result struct {
 	Log      map[string]PipelineResult   `json:"log"`
 	Metadata map[string]PipelineMetadata `json:"metadata"`
 }
+
+// ResultSummary condenses the result of an osbuild run to the time spent in
+// each pipeline and the stage that made the build fail.
+type ResultSummary struct {
+	Pipelines   []PipelineSummary `json:"pipelines"`
+	FailedStage *FailedStage      `json:"failed_stage,omitempty"`
+	// Type of the error osbuild reported, e.g. org.osbuild.error.stage
+	ErrorType string `json:"error_type,omitempty"`
+}
+
+type PipelineSummary struct {
+	Name    string         `json:"name"`
+	Success bool           `json:"success"`
+	Stages  []StageSummary `json:"stages"`
+	// Sum of the durations of the stages in seconds
+	Duration float64 `json:"duration"`
+}
+
+type StageSummary struct {
+	Type     string  `json:"type"`
+	Success  bool    `json:"success"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// FailedStage describes the first stage that failed in a build
+type FailedStage struct {
+	Pipeline string  `json:"pipeline"`
+	Index    int     `json:"index"`
+	Type     string  `json:"type"`
+	Duration float64 `json:"duration,omitempty"`
+	Output   string  `json:"output,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+func (fs *FailedStage) String() string {
+	msg := fmt.Sprintf("failed in pipeline %s, stage %s", fs.Pipeline, fs.Type)
+	if fs.Duration > 0 {
+		msg += fmt.Sprintf(" after %.0fs", fs.Duration)
+	}
+	return msg
+}
+
+// Duration returns the sum of the durations of all pipelines in seconds.
+func (rs *ResultSummary) Duration() float64 {
+	var duration float64
+	for _, p := range rs.Pipelines {
+		duration += p.Duration
+	}
+	return duration
+}
+
+// SummarizePipelines summarizes the given pipeline results. The failed stage
+// is searched for in the order of the pipelines.
+func SummarizePipelines(pipelines []string, log map[string]PipelineResult) *ResultSummary {
+	summary := &ResultSummary{
+		Pipelines: []PipelineSummary{},
+	}
+	for _, name := range pipelines {
+		ps := PipelineSummary{
+			Name:    name,
+			Success: true,
+			Stages:  []StageSummary{},
+		}
+		for idx, stage := range log[name] {
+			ps.Stages = append(ps.Stages, StageSummary{
+				Type:     stage.Type,
+				Success:  stage.Success,
+				Duration: stage.Duration,
+			})
+			ps.Duration += stage.Duration
+			if stage.Success {
+				continue
+			}
+			ps.Success = false
+			if summary.FailedStage == nil {
+				summary.FailedStage = &FailedStage{
+					Pipeline: name,
+					Index:    idx,
+					Type:     stage.Type,
+					Duration: stage.Duration,
+					Output:   stage.Output,
+					Error:    stage.Error,
+				}
+			}
+		}
+		summary.Pipelines = append(summary.Pipelines, ps)
+	}
+	return summary
+}
+
+// Summary summarizes the result. Pipelines are ordered by their name, because
+// osbuild does not report the order in which they ran.
+func (r *Result) Summary() *ResultSummary {
+	names := make([]string, 0, len(r.Log))
+	for name := range r.Log {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := SummarizePipelines(names, r.Log)
+
+	var resultError struct {
+		Type string `json:"type"`
+	}
+	if len(r.Error) > 0 && json.Unmarshal(r.Error, &resultError) == nil {
+		summary.ErrorType = resultError.Type
+	}
+
+	return summary
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStageResult_UnmarshalJSON(t *testing.T) {
@@ -26,3 +27,72 @@ func TestStageResult_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestResult_SummarySuccess(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(resultSuccessWithDurations), &result))
+
+	summary := result.Summary()
+	assert.Nil(t, summary.FailedStage)
+	assert.Empty(t, summary.ErrorType)
+
+	// pipelines are ordered by name
+	require.Len(t, summary.Pipelines, 3)
+	assert.Equal(t, "build", summary.Pipelines[0].Name)
+	assert.Equal(t, "image", summary.Pipelines[1].Name)
+	assert.Equal(t, "os", summary.Pipelines[2].Name)
+
+	build := summary.Pipelines[0]
+	assert.True(t, build.Success)
+	assert.Equal(t, 65.0, build.Duration)
+	assert.Equal(t, []StageSummary{
+		{Type: "org.osbuild.rpm", Success: true, Duration: 61.5},
+		{Type: "org.osbuild.selinux", Success: true, Duration: 3.5},
+	}, build.Stages)
+
+	assert.Equal(t, 313.0, summary.Pipelines[2].Duration)
+	assert.Equal(t, 378.5, summary.Duration())
+}
+
+func TestResult_SummaryFailure(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(resultFailureWithDurations), &result))
+
+	summary := result.Summary()
+	assert.Equal(t, "org.osbuild.error.stage", summary.ErrorType)
+
+	require.Len(t, summary.Pipelines, 2)
+	assert.True(t, summary.Pipelines[0].Success)
+	assert.False(t, summary.Pipelines[1].Success)
+
+	require.NotNil(t, summary.FailedStage)
+	assert.Equal(t, "os", summary.FailedStage.Pipeline)
+	assert.Equal(t, 0, summary.FailedStage.Index)
+	assert.Equal(t, "org.osbuild.rpm", summary.FailedStage.Type)
+	assert.Equal(t, 312.25, summary.FailedStage.Duration)
+	assert.Equal(t, "org.osbuild.rpm failed with exit code 1", summary.FailedStage.Error)
+	assert.Contains(t, summary.FailedStage.Output, "No space left on device")
+	assert.Equal(t, "failed in pipeline os, stage org.osbuild.rpm after 312s", summary.FailedStage.String())
+}
+
+func TestSummarizePipelines(t *testing.T) {
+	log := map[string]PipelineResult{
+		"build": {{Type: "org.osbuild.rpm", Success: true}},
+		"os": {
+			{Type: "org.osbuild.rpm", Success: true},
+			{Type: "org.osbuild.users", Success: false, Output: "first"},
+		},
+		"image": {{Type: "org.osbuild.truncate", Success: false, Output: "second"}},
+	}
+
+	// the failed stage is the first one in the order of the given pipelines
+	summary := SummarizePipelines([]string{"build", "os", "image"}, log)
+	require.NotNil(t, summary.FailedStage)
+	assert.Equal(t, "os", summary.FailedStage.Pipeline)
+	assert.Equal(t, 1, summary.FailedStage.Index)
+	assert.Equal(t, "first", summary.FailedStage.Output)
+	assert.Equal(t, "failed in pipeline os, stage org.osbuild.users", summary.FailedStage.String())
+
+	summary = SummarizePipelines([]string{"build", "image", "os"}, log)
+	assert.Equal(t, "image", summary.FailedStage.Pipeline)
+}
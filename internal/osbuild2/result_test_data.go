@@ -0,0 +1,91 @@
+package osbuild2
+
+const resultSuccessWithDurations = `
+{
+  "type": "result",
+  "success": true,
+  "metadata": {},
+  "log": {
+    "build": [
+      {
+        "id": "56a93713050f49c966eda0391dce1340d16f168bcbfd542d9d90be668ecc8268",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\nimported gpg key\n",
+        "duration": 61.5
+      },
+      {
+        "id": "cac48f998b87f9c9007037f48202bea9ef7966eacdaaf35f8e9da4b543cfa7fb",
+        "type": "org.osbuild.selinux",
+        "output": "",
+        "duration": 3.5
+      }
+    ],
+    "os": [
+      {
+        "id": "52f9740ad68953831b503edbcdf2c54eb3eab87efa7dacedabe3ab83b2db708a",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\n",
+        "duration": 312.25
+      },
+      {
+        "id": "fb5e7b93a3eba924a02a89043554641b022abcdaf07eb933da24813277a93636",
+        "type": "org.osbuild.locale",
+        "output": "",
+        "duration": 0.75
+      }
+    ],
+    "image": [
+      {
+        "id": "621c966c005c56d311d6ac39117d738780406b594523a933222c8af51ba98541",
+        "type": "org.osbuild.truncate",
+        "output": "",
+        "duration": 0.5
+      }
+    ]
+  }
+}
+`
+
+const resultFailureWithDurations = `
+{
+  "type": "error",
+  "success": false,
+  "error": {
+    "type": "org.osbuild.error.stage",
+    "details": {
+      "stage": {
+        "id": "147fe506d915edb9e0eb8fdb88adb43c8603125f455f47d0228bca935bb997f6",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\nerror: unpacking of archive failed on file /usr/bin/bash: cpio: rename failed - No space left on device\n",
+        "error": null
+      }
+    }
+  },
+  "log": {
+    "build": [
+      {
+        "id": "56a93713050f49c966eda0391dce1340d16f168bcbfd542d9d90be668ecc8268",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\nimported gpg key\n",
+        "duration": 61.5
+      },
+      {
+        "id": "cac48f998b87f9c9007037f48202bea9ef7966eacdaaf35f8e9da4b543cfa7fb",
+        "type": "org.osbuild.selinux",
+        "output": "",
+        "duration": 3.5
+      }
+    ],
+    "os": [
+      {
+        "id": "147fe506d915edb9e0eb8fdb88adb43c8603125f455f47d0228bca935bb997f6",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\nerror: unpacking of archive failed on file /usr/bin/bash: cpio: rename failed - No space left on device\n",
+        "success": false,
+        "error": "org.osbuild.rpm failed with exit code 1",
+        "duration": 312.25
+      }
+    ]
+  }
+}
+`
@@ -38,6 +38,7 @@ type RHSMStageOptionsDnfPlugin struct {
 type RHSMStageOptionsSubMan struct {
 	Rhsm      *SubManConfigRHSMSection      `json:"rhsm,omitempty"`
 	Rhsmcertd *SubManConfigRHSMCERTDSection `json:"rhsmcertd,omitempty"`
+	Server    *SubManConfigServerSection    `json:"server,omitempty"`
 }
 
 // RHSM configuration section of /etc/rhsm/rhsm.conf
@@ -51,3 +52,13 @@ type SubManConfigRHSMCERTDSection struct {
 	// Automatic system registration
 	AutoRegistration *bool `json:"auto_registration,omitempty"`
 }
+
+// Server configuration section of /etc/rhsm/rhsm.conf
+type SubManConfigServerSection struct {
+	// Proxy server of the connections to the entitlement server
+	ProxyHostname string `json:"proxy_hostname,omitempty"`
+	ProxyPort     string `json:"proxy_port,omitempty"`
+	ProxyScheme   string `json:"proxy_scheme,omitempty"`
+	// Comma separated hosts that are connected to without the proxy
+	NoProxy string `json:"no_proxy,omitempty"`
+}
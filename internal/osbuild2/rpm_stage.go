@@ -8,6 +8,9 @@ type RPMStageOptions struct {
 	DisableDracut bool `json:"disable_dracut,omitempty"`
 
 	Exclude *Exclude `json:"exclude,omitempty"`
+
+	// Only install translations for these languages (%_install_langs)
+	InstallLangs []string `json:"install_langs,omitempty"`
 }
 
 type Exclude struct {
@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewRPMStage(t *testing.T) {
@@ -15,3 +17,18 @@ func TestNewRPMStage(t *testing.T) {
 	actualStage := NewRPMStage(&RPMStageOptions{}, &RPMStageInputs{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestRPMStageOptions_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(&RPMStageOptions{GPGKeys: []string{"key"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"gpgkeys":["key"]}`, string(data))
+
+	data, err = json.Marshal(&RPMStageOptions{
+		GPGKeys:       []string{"key"},
+		DisableDracut: true,
+		Exclude:       &Exclude{Docs: true},
+		InstallLangs:  []string{"en_US", "de_DE"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"gpgkeys":["key"],"disable_dracut":true,"exclude":{"docs":true},"install_langs":["en_US","de_DE"]}`, string(data))
+}
@@ -7,6 +7,15 @@ package osbuild2
 type SELinuxStageOptions struct {
 	FileContexts string            `json:"file_contexts"`
 	Labels       map[string]string `json:"labels,omitempty"`
+
+	// Paths that are not labeled
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+
+	// Paths below ExcludePaths that are labeled from FileContexts anyway
+	LabelPaths []string `json:"label_paths,omitempty"`
+
+	// Relabel the whole filesystem on the first boot of the image
+	ForceAutorelabel *bool `json:"force_autorelabel,omitempty"`
 }
 
 func (SELinuxStageOptions) isStageOptions() {}
@@ -1,9 +1,12 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSELinuxStageOptions(t *testing.T) {
@@ -22,3 +25,27 @@ func TestNewSELinuxStage(t *testing.T) {
 	actualStage := NewSELinuxStage(&SELinuxStageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestSELinuxStageOptions_MarshalJSON(t *testing.T) {
+	options := NewSELinuxStageOptions("etc/selinux/targeted/contexts/files/file_contexts")
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"file_contexts":"etc/selinux/targeted/contexts/files/file_contexts"}`, string(data))
+
+	options = &SELinuxStageOptions{
+		FileContexts:     "etc/selinux/mls/contexts/files/file_contexts",
+		Labels:           map[string]string{"/usr/bin/cp": "system_u:object_r:install_exec_t:s0"},
+		ExcludePaths:     []string{"/srv/data"},
+		LabelPaths:       []string{"/srv/data/app.conf"},
+		ForceAutorelabel: common.BoolToPtr(true),
+	}
+	data, err = json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"file_contexts": "etc/selinux/mls/contexts/files/file_contexts",
+		"labels": {"/usr/bin/cp": "system_u:object_r:install_exec_t:s0"},
+		"exclude_paths": ["/srv/data"],
+		"label_paths": ["/srv/data/app.conf"],
+		"force_autorelabel": true
+	}`, string(data))
+}
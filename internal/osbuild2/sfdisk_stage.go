@@ -1,5 +1,10 @@
 package osbuild2
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // Partition a target using sfdisk(8)
 
 type SfdiskStageOptions struct {
@@ -11,12 +16,50 @@ type SfdiskStageOptions struct {
 
 	// Partition layout
 	Partitions []Partition `json:"partitions,omitempty"`
+
+	// Sector size (in bytes), in which the starts and sizes of the
+	// partitions are given
+	SectorSize *uint64 `json:"sector-size,omitempty"`
 }
 
 func (SfdiskStageOptions) isStageOptions() {}
 
+// alias for custom marshaller
+type sfdiskStageOptions SfdiskStageOptions
+
+// Custom marshaller that rejects stages which both create a new partition
+// table and modify the partitions of an existing one, and partitions with
+// names or attributes that the partition table can't hold
+func (options SfdiskStageOptions) MarshalJSON() ([]byte, error) {
+	existing := 0
+	for idx, p := range options.Partitions {
+		if options.Label != "gpt" && (p.Name != "" || len(p.Attrs) > 0) {
+			return nil, fmt.Errorf("partition %d of the sfdisk stage has a name or attributes, which only GPT partition tables support", idx)
+		}
+		for _, attr := range p.Attrs {
+			if attr > 63 {
+				return nil, fmt.Errorf("partition %d of the sfdisk stage has invalid attribute bit %d, must be between 0 and 63", idx, attr)
+			}
+		}
+		if p.Preserve && p.Resize {
+			return nil, fmt.Errorf("partition %d of the sfdisk stage can't be both preserved and resized", idx)
+		}
+		if p.Preserve || p.Resize {
+			existing++
+		}
+	}
+	if existing != 0 && existing != len(options.Partitions) {
+		return nil, fmt.Errorf("the sfdisk stage can't both create partitions and modify existing ones")
+	}
+
+	return json.Marshal(sfdiskStageOptions(options))
+}
+
 // Description of a partition
 type Partition struct {
+	// Attribute bits of the partition (GPT)
+	Attrs []uint `json:"attrs,omitempty"`
+
 	// Mark the partition as bootable (dos)
 	Bootable bool `json:"bootable,omitempty"`
 
@@ -34,6 +77,12 @@ type Partition struct {
 
 	// UUID of the partition (GPT)
 	UUID string `json:"uuid,omitempty"`
+
+	// Keep the existing partition unchanged instead of creating it
+	Preserve bool `json:"preserve,omitempty"`
+
+	// Change the size of the existing partition to Size, keeping its start
+	Resize bool `json:"resize,omitempty"`
 }
 
 func NewSfdiskStage(options *SfdiskStageOptions, device *Device) *Stage {
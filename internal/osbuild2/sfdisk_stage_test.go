@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSfdiskStage(t *testing.T) {
@@ -35,3 +37,73 @@ func TestNewSfdiskStage(t *testing.T) {
 	actualStage := NewSfdiskStage(&options, device)
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestSfdiskStageOptions_MarshalJSON(t *testing.T) {
+	create := SfdiskStageOptions{
+		Label: "gpt",
+		UUID:  "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Partitions: []Partition{
+			{Start: 2048, Size: 204800, Type: "C12A7328-F81F-11D2-BA4B-00A0C93EC93B"},
+			{Start: 206848, Size: 4194304},
+		},
+	}
+	data, err := json.Marshal(create)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":"gpt","uuid":"D209C89E-EA5E-4FBD-B161-B461CCE297E0","partitions":[{"start":2048,"size":204800,"type":"C12A7328-F81F-11D2-BA4B-00A0C93EC93B"},{"start":206848,"size":4194304}]}`, string(data))
+
+	grow := SfdiskStageOptions{
+		Label: "gpt",
+		UUID:  "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Partitions: []Partition{
+			{Start: 2048, Size: 204800, Preserve: true},
+			{Start: 206848, Size: 8388608, Resize: true},
+		},
+	}
+	data, err = json.Marshal(&grow)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":"gpt","uuid":"D209C89E-EA5E-4FBD-B161-B461CCE297E0","partitions":[{"start":2048,"size":204800,"preserve":true},{"start":206848,"size":8388608,"resize":true}]}`, string(data))
+
+	mixed := grow
+	mixed.Partitions = []Partition{grow.Partitions[0], create.Partitions[1]}
+	_, err = json.Marshal(mixed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the sfdisk stage can't both create partitions and modify existing ones")
+
+	both := grow
+	both.Partitions = []Partition{grow.Partitions[0], {Start: 206848, Size: 8388608, Preserve: true, Resize: true}}
+	_, err = json.Marshal(both)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 1 of the sfdisk stage can't be both preserved and resized")
+}
+
+func TestSfdiskStageOptions_NamesAndAttrs(t *testing.T) {
+	options := SfdiskStageOptions{
+		Label: "gpt",
+		UUID:  "D209C89E-EA5E-4FBD-B161-B461CCE297E0",
+		Partitions: []Partition{
+			{Start: 2048, Size: 2048, Attrs: []uint{2}},
+			{Start: 4096, Size: 4194304, Name: "root", Attrs: []uint{55, 63}},
+		},
+	}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"label":"gpt","uuid":"D209C89E-EA5E-4FBD-B161-B461CCE297E0","partitions":[{"attrs":[2],"start":2048,"size":2048},{"attrs":[55,63],"name":"root","start":4096,"size":4194304}]}`, string(data))
+
+	invalid := options
+	invalid.Partitions = []Partition{{Start: 2048, Size: 2048, Attrs: []uint{64}}}
+	_, err = json.Marshal(invalid)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 0 of the sfdisk stage has invalid attribute bit 64, must be between 0 and 63")
+
+	// names and attributes are GPT only
+	dos := options
+	dos.Label = "dos"
+	dos.Partitions = []Partition{{Start: 2048, Size: 2048}, {Start: 4096, Size: 4194304, Name: "root"}}
+	_, err = json.Marshal(dos)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partition 1 of the sfdisk stage has a name or attributes, which only GPT partition tables support")
+
+	dos.Partitions = []Partition{{Start: 2048, Size: 2048, Attrs: []uint{2}}}
+	_, err = json.Marshal(dos)
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package osbuild2
+
+// The container images to fetch indexed by the digest of their manifest
+type SkopeoSource struct {
+	Items map[string]SkopeoSourceItem `json:"items"`
+}
+
+func (SkopeoSource) isSource() {}
+
+type SkopeoSourceItem struct {
+	Image SkopeoSourceImage `json:"image"`
+}
+
+type SkopeoSourceImage struct {
+	// Name of the image, without tag or digest
+	Name string `json:"name"`
+	// Digest of the image manifest
+	Digest string `json:"digest"`
+	// Verify TLS certificates of the registry, defaults to true
+	TLSVerify *bool `json:"tls-verify,omitempty"`
+}
+
+// NewSkopeoSource creates a new, empty org.osbuild.skopeo source.
+func NewSkopeoSource() *SkopeoSource {
+	return &SkopeoSource{
+		Items: make(map[string]SkopeoSourceItem),
+	}
+}
+
+// AddItem adds the image name pinned to digest to the source.
+func (source *SkopeoSource) AddItem(name, digest string, tlsVerify *bool) {
+	source.Items[digest] = SkopeoSourceItem{
+		Image: SkopeoSourceImage{
+			Name:      name,
+			Digest:    digest,
+			TLSVerify: tlsVerify,
+		},
+	}
+}
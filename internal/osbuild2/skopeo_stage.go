@@ -0,0 +1,60 @@
+package osbuild2
+
+// Options for the org.osbuild.skopeo stage, which copies container images
+// into the container storage of the tree
+type SkopeoStageOptions struct {
+	Destination SkopeoDestination `json:"destination"`
+}
+
+func (SkopeoStageOptions) isStageOptions() {}
+
+type SkopeoDestination struct {
+	// Type of the destination, only "containers-storage" is supported
+	Type string `json:"type"`
+	// Path of the storage in the tree, defaults to /var/lib/containers/storage
+	StoragePath string `json:"storage-path,omitempty"`
+}
+
+type SkopeoStageInputs struct {
+	Images *ContainersInput `json:"images"`
+}
+
+func (SkopeoStageInputs) isStageInputs() {}
+
+type ContainersInput struct {
+	inputCommon
+	References ContainersInputReferences `json:"references"`
+}
+
+func (ContainersInput) isStageInput() {}
+
+// ContainersInputReferences maps the digests of images of the
+// org.osbuild.skopeo source to the names they are stored under
+type ContainersInputReferences map[string]ContainersInputReference
+
+func (ContainersInputReferences) isReferences() {}
+
+type ContainersInputReference struct {
+	Name string `json:"name"`
+}
+
+// NewContainersInputForSources creates an org.osbuild.containers input of the
+// images of the org.osbuild.skopeo source.
+func NewContainersInputForSources(references ContainersInputReferences) *ContainersInput {
+	return &ContainersInput{
+		inputCommon: inputCommon{
+			Type:   "org.osbuild.containers",
+			Origin: InputOriginSource,
+		},
+		References: references,
+	}
+}
+
+// A new org.osbuild.skopeo stage to copy container images into the tree
+func NewSkopeoStage(options *SkopeoStageOptions, inputs *SkopeoStageInputs) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.skopeo",
+		Inputs:  inputs,
+		Options: options,
+	}
+}
@@ -0,0 +1,67 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSkopeoStage(t *testing.T) {
+	options := &SkopeoStageOptions{
+		Destination: SkopeoDestination{Type: "containers-storage"},
+	}
+	inputs := &SkopeoStageInputs{
+		Images: NewContainersInputForSources(ContainersInputReferences{
+			"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10": {Name: "quay.io/fedora/fedora:35"},
+		}),
+	}
+	expectedStage := &Stage{
+		Type:    "org.osbuild.skopeo",
+		Options: options,
+		Inputs:  inputs,
+	}
+	actualStage := NewSkopeoStage(options, inputs)
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestSkopeoStage_MarshalJSON(t *testing.T) {
+	stage := NewSkopeoStage(
+		&SkopeoStageOptions{
+			Destination: SkopeoDestination{
+				Type:        "containers-storage",
+				StoragePath: "/usr/share/containers/storage",
+			},
+		},
+		&SkopeoStageInputs{
+			Images: NewContainersInputForSources(ContainersInputReferences{
+				"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10": {Name: "localhost/app"},
+			}),
+		},
+	)
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "org.osbuild.skopeo",
+		"inputs": {
+			"images": {
+				"type": "org.osbuild.containers",
+				"origin": "org.osbuild.source",
+				"references": {
+					"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10": {"name": "localhost/app"}
+				}
+			}
+		},
+		"options": {
+			"destination": {
+				"type": "containers-storage",
+				"storage-path": "/usr/share/containers/storage"
+			}
+		}
+	}`, string(data))
+
+	var unmarshaled Stage
+	require.NoError(t, json.Unmarshal(data, &unmarshaled))
+	assert.Equal(t, stage, &unmarshaled)
+}
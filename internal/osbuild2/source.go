@@ -35,6 +35,10 @@ func (sources *Sources) UnmarshalJSON(data []byte) error {
 			source = new(CurlSource)
 		case "org.osbuild.ostree":
 			source = new(OSTreeSource)
+		case "org.osbuild.inline":
+			source = new(InlineSource)
+		case "org.osbuild.skopeo":
+			source = new(SkopeoSource)
 		default:
 			return errors.New("unexpected source name: " + name)
 		}
@@ -87,6 +87,55 @@ func TestSource_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"org.osbuild.curl":{"items":{"checksum1":"url1","checksum2":"url2"}}}`),
 			},
 		},
+		{
+			name: "inline",
+			fields: fields{
+				Type: "org.osbuild.inline",
+				Source: &InlineSource{
+					Items: map[string]InlineSourceItem{
+						"sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae": {Encoding: "base64", Data: "Zm9v"},
+					}},
+			},
+			args: args{
+				data: []byte(`{"org.osbuild.inline":{"items":{"sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae":{"encoding":"base64","data":"Zm9v"}}}}`),
+			},
+		},
+		{
+			name: "ostree-with-secrets",
+			fields: fields{
+				Type: "org.osbuild.ostree",
+				Source: &OSTreeSource{
+					Items: map[string]OSTreeSourceItem{
+						"02604b2da6e954bd34b8b82a835e5a77d2b60ffa": {
+							Remote: OSTreeSourceRemote{
+								URL:     "https://ostree.example.com/repo/",
+								Secrets: &OSTreeSourceRemoteSecrets{Name: OSTreeMTLSSecrets},
+							},
+						},
+					}},
+			},
+			args: args{
+				data: []byte(`{"org.osbuild.ostree":{"items":{"02604b2da6e954bd34b8b82a835e5a77d2b60ffa":{"remote":{"url":"https://ostree.example.com/repo/","secrets":{"name":"org.osbuild.mtls"}}}}}}`),
+			},
+		},
+		{
+			name: "skopeo",
+			fields: fields{
+				Type: "org.osbuild.skopeo",
+				Source: &SkopeoSource{
+					Items: map[string]SkopeoSourceItem{
+						"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10": {
+							Image: SkopeoSourceImage{
+								Name:   "quay.io/fedora/fedora",
+								Digest: "sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10",
+							},
+						},
+					}},
+			},
+			args: args{
+				data: []byte(`{"org.osbuild.skopeo":{"items":{"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10":{"image":{"name":"quay.io/fedora/fedora","digest":"sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10"}}}}}`),
+			},
+		},
 	}
 	for idx, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -113,3 +162,30 @@ func TestSource_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestInlineSource_AddItem(t *testing.T) {
+	source := NewInlineSource()
+	checksum := source.AddItem([]byte("foo"))
+	if checksum != "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae" {
+		t.Errorf("unexpected checksum %q", checksum)
+	}
+	if checksum != InlineSourceChecksum([]byte("foo")) {
+		t.Errorf("checksum %q differs from InlineSourceChecksum()", checksum)
+	}
+	if item := source.Items[checksum]; item.Encoding != "base64" || item.Data != "Zm9v" {
+		t.Errorf("unexpected item %v", item)
+	}
+}
+
+func TestSkopeoSource_AddItem(t *testing.T) {
+	source := NewSkopeoSource()
+	tlsVerify := false
+	source.AddItem("registry.example.com/app", "sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10", &tlsVerify)
+	item, ok := source.Items["sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10"]
+	if !ok {
+		t.Fatalf("item not added: %v", source.Items)
+	}
+	if item.Image.Name != "registry.example.com/app" || item.Image.Digest != "sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10" || item.Image.TLSVerify != &tlsVerify {
+		t.Errorf("unexpected item %v", item)
+	}
+}
@@ -0,0 +1,29 @@
+package osbuild2
+
+// SshdConfigStageOptions describe a drop-in of the configuration of the
+// OpenSSH server, which the org.osbuild.sshd.config stage writes to
+// /etc/ssh/sshd_config.d/. The stage includes the drop-ins at the top of
+// /etc/ssh/sshd_config if it doesn't yet, so that they take precedence.
+type SshdConfigStageOptions struct {
+	// Name of the drop-in file
+	Filename string     `json:"filename"`
+	Config   SshdConfig `json:"config"`
+}
+
+func (SshdConfigStageOptions) isStageOptions() {}
+
+// SshdConfig are the options of sshd_config(5). Options that are not set are
+// not written.
+type SshdConfig struct {
+	PermitRootLogin        *string `json:"PermitRootLogin,omitempty"`
+	PasswordAuthentication *bool   `json:"PasswordAuthentication,omitempty"`
+	ClientAliveInterval    *int    `json:"ClientAliveInterval,omitempty"`
+	MaxAuthTries           *int    `json:"MaxAuthTries,omitempty"`
+}
+
+func NewSshdConfigStage(options *SshdConfigStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.sshd.config",
+		Options: options,
+	}
+}
@@ -0,0 +1,29 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSshdConfigStage(t *testing.T) {
+	options := &SshdConfigStageOptions{
+		Filename: "00-blueprint.conf",
+		Config: SshdConfig{
+			PermitRootLogin:        common.StringToPtr("no"),
+			PasswordAuthentication: common.BoolToPtr(false),
+			MaxAuthTries:           common.IntToPtr(3),
+		},
+	}
+	assert.Equal(t, &Stage{Type: "org.osbuild.sshd.config", Options: options}, NewSshdConfigStage(options))
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"filename": "00-blueprint.conf",
+		"config": {"PermitRootLogin": "no", "PasswordAuthentication": false, "MaxAuthTries": 3}
+	}`, string(data))
+}
@@ -49,6 +49,12 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 		options = new(FixBLSStageOptions)
 	case "org.osbuild.fstab":
 		options = new(FSTabStageOptions)
+	case "org.osbuild.crypttab":
+		options = new(CrypttabStageOptions)
+	case "org.osbuild.swapfile":
+		options = new(SwapfileStageOptions)
+	case "org.osbuild.sshd.config":
+		options = new(SshdConfigStageOptions)
 	case "org.osbuild.grub2":
 		options = new(GRUB2StageOptions)
 	case "org.osbuild.locale":
@@ -61,6 +67,8 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 		options = new(HostnameStageOptions)
 	case "org.osbuild.users":
 		options = new(UsersStageOptions)
+	case "org.osbuild.wsl.conf":
+		options = new(WSLConfStageOptions)
 	case "org.osbuild.groups":
 		options = new(GroupsStageOptions)
 	case "org.osbuild.timezone":
@@ -93,6 +101,21 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 		options = new(ScriptStageOptions)
 	case "org.osbuild.sysconfig":
 		options = new(SysconfigStageOptions)
+	case "org.osbuild.oscap.remediation":
+		options = new(OscapRemediationStageOptions)
+	case "org.osbuild.update-crypto-policies":
+		options = new(UpdateCryptoPoliciesStageOptions)
+	case "org.osbuild.ignition":
+		options = new(IgnitionStageOptions)
+	case "org.osbuild.udev.rules":
+		options = new(UdevRulesStageOptions)
+	case "org.osbuild.pki.update-ca-trust":
+		options = new(UpdateCATrustStageOptions)
+	case "org.osbuild.yum.repos":
+		options = new(YumReposStageOptions)
+	case "org.osbuild.skopeo":
+		options = new(SkopeoStageOptions)
+		inputs = new(SkopeoStageInputs)
 	case "org.osbuild.sysctld":
 		options = new(SysctldStageOptions)
 	case "org.osbuild.tmpfilesd":
@@ -105,6 +128,8 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 	case "org.osbuild.oci-archive":
 		options = new(OCIArchiveStageOptions)
 		inputs = new(OCIArchiveStageInputs)
+	case "org.osbuild.ovf":
+		options = new(OVFStageOptions)
 	case "org.osbuild.ostree.commit":
 		options = new(OSTreeCommitStageOptions)
 		inputs = new(OSTreeCommitStageInputs)
@@ -114,6 +139,10 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 	case "org.osbuild.ostree.pull":
 		options = new(OSTreePullStageOptions)
 		inputs = new(OSTreePullStageInputs)
+	case "org.osbuild.ostree.remotes":
+		options = new(OSTreeRemotesStageOptions)
+	case "org.osbuild.ostree.static-delta":
+		options = new(OSTreeStaticDeltaStageOptions)
 	case "org.osbuild.ostree.init":
 		options = new(OSTreeInitStageOptions)
 	case "org.osbuild.ostree.preptree":
@@ -129,6 +158,8 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 	case "org.osbuild.copy":
 		options = new(CopyStageOptions)
 		inputs = new(CopyStageInputs)
+	case "org.osbuild.btrfs.subvol":
+		options = new(BtrfsSubVolStageOptions)
 	case "org.osbuild.mkfs.btrfs":
 		options = new(MkfsBtrfsStageOptions)
 	case "org.osbuild.mkfs.ext4":
@@ -137,6 +168,13 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 		options = new(MkfsFATStageOptions)
 	case "org.osbuild.mkfs.xfs":
 		options = new(MkfsXfsStageOptions)
+	case "org.osbuild.mkswap":
+		options = new(MkswapStageOptions)
+	case "org.osbuild.genprotimg":
+		options = new(GenprotimgStageOptions)
+		inputs = new(GenprotimgStageInputs)
+	case "org.osbuild.implantisomd5":
+		options = new(Implantisomd5StageOptions)
 	case "org.osbuild.qemu":
 		options = new(QEMUStageOptions)
 		inputs = new(QEMUStageInputs)
@@ -146,6 +184,9 @@ func (stage *Stage) UnmarshalJSON(data []byte) error {
 		// The stage accepts also source input, but we need to rework all inputs first to handle this nicely here.
 		// Only files input is used by the XZ stage at this moment.
 		inputs = new(FilesInputs)
+	case "org.osbuild.gzip":
+		options = new(GzipStageOptions)
+		inputs = new(FilesInputs)
 	default:
 		return fmt.Errorf("unexpected stage type: %s", rawStage.Type)
 	}
@@ -146,6 +146,24 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.dnf.config","options":{}}`),
 			},
 		},
+		{
+			name: "dnf.config-main",
+			fields: fields{
+				Type: "org.osbuild.dnf.config",
+				Options: &DNFConfigStageOptions{
+					Config: &DNFConfig{
+						Main: &DNFConfigMain{
+							Proxy:           "http://proxy.example.com:3128",
+							InstallWeakDeps: common.BoolToPtr(false),
+							ExcludePkgs:     []string{"kernel-debug*", "telnet"},
+						},
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.dnf.config","options":{"config":{"main":{"proxy":"http://proxy.example.com:3128","install_weak_deps":false,"excludepkgs":["kernel-debug*","telnet"]}}}}`),
+			},
+		},
 		{
 			name: "dracut",
 			fields: fields{
@@ -167,6 +185,7 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 						AddModules:     []string{"floppy"},
 						OmitModules:    []string{"nouveau"},
 						AddDrivers:     []string{"driver1"},
+						OmitDrivers:    []string{"driver3"},
 						ForceDrivers:   []string{"driver2"},
 						Filesystems:    []string{"ext4"},
 						Install:        []string{"file1"},
@@ -176,7 +195,7 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				},
 			},
 			args: args{
-				data: []byte(`{"type":"org.osbuild.dracut.conf","options":{"filename":"testing.conf","config":{"compress":"xz","add_dracutmodules":["floppy"],"omit_dracutmodules":["nouveau"],"add_drivers":["driver1"],"force_drivers":["driver2"],"filesystems":["ext4"],"install_items":["file1"],"early_microcode":false,"reproducible":false}}}`),
+				data: []byte(`{"type":"org.osbuild.dracut.conf","options":{"filename":"testing.conf","config":{"compress":"xz","add_dracutmodules":["floppy"],"omit_dracutmodules":["nouveau"],"add_drivers":["driver1"],"omit_drivers":["driver3"],"force_drivers":["driver2"],"filesystems":["ext4"],"install_items":["file1"],"early_microcode":false,"reproducible":false}}}`),
 			},
 		},
 		{
@@ -281,6 +300,18 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.hostname","options":{"hostname":""}}`),
 			},
 		},
+		{
+			name: "implantisomd5",
+			fields: fields{
+				Type: "org.osbuild.implantisomd5",
+				Options: &Implantisomd5StageOptions{
+					Filename: "installer.iso",
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.implantisomd5","options":{"filename":"installer.iso"}}`),
+			},
+		},
 		{
 			name: "keymap",
 			fields: fields{
@@ -427,6 +458,25 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.rhsm","options":{"dnf-plugins":{"product-id":{"enabled":false},"subscription-manager":{"enabled":false}},"subscription-manager":{"rhsm":{"manage_repos":false},"rhsmcertd":{"auto_registration":true}}}}`),
 			},
 		},
+		{
+			name: "rhsm-server",
+			fields: fields{
+				Type: "org.osbuild.rhsm",
+				Options: &RHSMStageOptions{
+					SubMan: &RHSMStageOptionsSubMan{
+						Server: &SubManConfigServerSection{
+							ProxyHostname: "proxy.example.com",
+							ProxyPort:     "3128",
+							ProxyScheme:   "http",
+							NoProxy:       "localhost,.example.com",
+						},
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.rhsm","options":{"subscription-manager":{"server":{"proxy_hostname":"proxy.example.com","proxy_port":"3128","proxy_scheme":"http","no_proxy":"localhost,.example.com"}}}}`),
+			},
+		},
 		{
 			name: "script",
 			fields: fields{
@@ -521,6 +571,21 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.sysconfig","options":{"kernel":{"update_default":true,"default_kernel":"kernel"},"network":{"networking":true,"no_zero_conf":true},"network-scripts":{"ifcfg":{"eth0":{"bootproto":"dhcp","device":"eth0","ipv6init":false,"onboot":true,"peerdns":true,"type":"Ethernet","userctl":true},"eth1":{"bootproto":"dhcp","device":"eth1","ipv6init":true,"onboot":true,"peerdns":true,"type":"Ethernet","userctl":false}}}}}`),
 			},
 		},
+		{
+			name: "oscap-remediation",
+			fields: fields{
+				Type: "org.osbuild.oscap.remediation",
+				Options: &OscapRemediationStageOptions{
+					Config: OscapConfig{
+						Datastream: "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml",
+						ProfileID:  "xccdf_org.ssgproject.content_profile_cis",
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.oscap.remediation","options":{"config":{"datastream":"/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml","profile_id":"xccdf_org.ssgproject.content_profile_cis"}}}`),
+			},
+		},
 		{
 			name: "sysctld",
 			fields: fields{
@@ -542,6 +607,132 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.sysctld","options":{"filename":"example.conf","config":[{"key":"net.ipv4.conf.*.rp_filter","value":"2"},{"key":"-net.ipv4.conf.all.rp_filter"}]}}`),
 			},
 		},
+		{
+			name: "udev.rules",
+			fields: fields{
+				Type: "org.osbuild.udev.rules",
+				Options: &UdevRulesStageOptions{
+					Filename: "/etc/udev/rules.d/70-serial.rules",
+					Rules: UdevRules{
+						UdevRule{
+							{Key: UdevKey{Key: "SUBSYSTEM"}, Op: "==", Value: "tty"},
+							{Key: UdevKey{Key: "ENV", Arg: "ID_SERIAL"}, Op: "==", Value: "usb"},
+							{Key: UdevKey{Key: "MODE"}, Op: "=", Value: "0660"},
+						},
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.udev.rules","options":{"filename":"/etc/udev/rules.d/70-serial.rules","rules":[[{"K":"SUBSYSTEM","O":"==","V":"tty"},{"K":{"key":"ENV","arg":"ID_SERIAL"},"O":"==","V":"usb"},{"K":"MODE","O":"=","V":"0660"}]]}}`),
+			},
+		},
+		{
+			name: "pki.update-ca-trust",
+			fields: fields{
+				Type:    "org.osbuild.pki.update-ca-trust",
+				Options: &UpdateCATrustStageOptions{},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.pki.update-ca-trust","options":{}}`),
+			},
+		},
+		{
+			name: "yum.repos",
+			fields: fields{
+				Type: "org.osbuild.yum.repos",
+				Options: &YumReposStageOptions{
+					Filename: "internal.repo",
+					Repos: []YumRepository{
+						{
+							ID:       "internal",
+							BaseURLs: []string{"https://repo.example.com/el8/"},
+							GPGKey:   []string{"file:///etc/pki/rpm-gpg/RPM-GPG-KEY-internal"},
+							GPGCheck: common.BoolToPtr(true),
+						},
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.yum.repos","options":{"filename":"internal.repo","repos":[{"id":"internal","baseurl":["https://repo.example.com/el8/"],"gpgkey":["file:///etc/pki/rpm-gpg/RPM-GPG-KEY-internal"],"gpgcheck":true}]}}`),
+			},
+		},
+		{
+			name: "ostree.remotes",
+			fields: fields{
+				Type: "org.osbuild.ostree.remotes",
+				Options: &OSTreeRemotesStageOptions{
+					Repo: "/ostree/repo",
+					Remotes: []OSTreeRemote{
+						{
+							Name:       "edge",
+							URL:        "https://ostree.example.com/repo/",
+							ContentURL: "mirrorlist=https://ostree.example.com/mirrorlist",
+							Branches:   []string{"rhel/8/x86_64/edge"},
+							GPGKeys:    []string{"key"},
+						},
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.ostree.remotes","options":{"repo":"/ostree/repo","remotes":[{"name":"edge","url":"https://ostree.example.com/repo/","contenturl":"mirrorlist=https://ostree.example.com/mirrorlist","branches":["rhel/8/x86_64/edge"],"gpgkeys":["key"]}]}}`),
+			},
+		},
+		{
+			name: "ostree.static-delta",
+			fields: fields{
+				Type: "org.osbuild.ostree.static-delta",
+				Options: &OSTreeStaticDeltaStageOptions{
+					Repo: "/repo",
+					From: "02604b2da6e954bd34b8b82a835e5a77d2b60ffa",
+					To:   "rhel/8/x86_64/edge",
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.ostree.static-delta","options":{"repo":"/repo","from":"02604b2da6e954bd34b8b82a835e5a77d2b60ffa","to":"rhel/8/x86_64/edge"}}`),
+			},
+		},
+		{
+			name: "ovf",
+			fields: fields{
+				Type: "org.osbuild.ovf",
+				Options: &OVFStageOptions{
+					VMDK: "image.vmdk",
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.ovf","options":{"vmdk":"image.vmdk"}}`),
+			},
+		},
+		{
+			name: "update-crypto-policies",
+			fields: fields{
+				Type:    "org.osbuild.update-crypto-policies",
+				Options: &UpdateCryptoPoliciesStageOptions{Policy: "FIPS"},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.update-crypto-policies","options":{"policy":"FIPS"}}`),
+			},
+		},
+		{
+			name: "ignition",
+			fields: fields{
+				Type:    "org.osbuild.ignition",
+				Options: &IgnitionStageOptions{},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.ignition","options":{}}`),
+			},
+		},
+		{
+			name: "ignition-network",
+			fields: fields{
+				Type:    "org.osbuild.ignition",
+				Options: &IgnitionStageOptions{Network: []string{"ip=dhcp", "rd.neednet=1"}},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.ignition","options":{"network":["ip=dhcp","rd.neednet=1"]}}`),
+			},
+		},
 		{
 			name: "systemd",
 			fields: fields{
@@ -637,6 +828,23 @@ func TestStage_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.users","options":{"users":null}}`),
 			},
 		},
+		{
+			name: "wsl.conf",
+			fields: fields{
+				Type: "org.osbuild.wsl.conf",
+				Options: &WSLConfStageOptions{
+					User: &WSLConfUserOptions{
+						Default: "user",
+					},
+					Network: &WSLConfNetworkOptions{
+						GenerateHostname: common.BoolToPtr(false),
+					},
+				},
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.wsl.conf","options":{"user":{"default":"user"},"network":{"generateHostname":false}}}`),
+			},
+		},
 	}
 	for idx, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -726,6 +934,23 @@ func TestStageV2_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.ostree.preptree","options":{"etc_group_members":["wheel"]}}`),
 			},
 		},
+		{
+			name: "genprotimg",
+			fields: fields{
+				Type: "org.osbuild.genprotimg",
+				Options: &GenprotimgStageOptions{
+					Kernel:           "/boot/vmlinuz",
+					Initrd:           "/boot/initramfs.img",
+					Parmfile:         "/etc/kernel/cmdline",
+					HostKeyDocuments: []string{"input://hostkeys/sha256:01"},
+					Output:           "/boot/secure-linux",
+				},
+				Inputs: NewGenprotimgStageInputs([]string{"sha256:01"}),
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.genprotimg","inputs":{"hostkeys":{"type":"org.osbuild.files","origin":"org.osbuild.source","references":["sha256:01"]}},"options":{"kernel":"/boot/vmlinuz","initrd":"/boot/initramfs.img","parmfile":"/etc/kernel/cmdline","host-key-documents":["input://hostkeys/sha256:01"],"output":"/boot/secure-linux"}}`),
+			},
+		},
 		{
 			name: "xz",
 			fields: fields{
@@ -739,6 +964,35 @@ func TestStageV2_UnmarshalJSON(t *testing.T) {
 				data: []byte(`{"type":"org.osbuild.xz","inputs":{"file":{"type":"org.osbuild.files","origin":"org.osbuild.pipeline","references":{"name:os":{"file":"image.raw"}}}},"options":{"filename":"image.raw.xz"}}`),
 			},
 		},
+		{
+			name: "xz-level-threads",
+			fields: fields{
+				Type: "org.osbuild.xz",
+				Options: &XzStageOptions{
+					Filename: "image.raw.xz",
+					Level:    9,
+					Threads:  4,
+				},
+				Inputs: NewFilesInputs(NewFilesInputReferencesPipeline("os", "image.raw")),
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.xz","inputs":{"file":{"type":"org.osbuild.files","origin":"org.osbuild.pipeline","references":{"name:os":{"file":"image.raw"}}}},"options":{"filename":"image.raw.xz","level":9,"threads":4}}`),
+			},
+		},
+		{
+			name: "gzip",
+			fields: fields{
+				Type: "org.osbuild.gzip",
+				Options: &GzipStageOptions{
+					Filename: "image.raw.gz",
+					Level:    1,
+				},
+				Inputs: NewFilesInputs(NewFilesInputReferencesPipeline("os", "image.raw")),
+			},
+			args: args{
+				data: []byte(`{"type":"org.osbuild.gzip","inputs":{"file":{"type":"org.osbuild.files","origin":"org.osbuild.pipeline","references":{"name:os":{"file":"image.raw"}}}},"options":{"filename":"image.raw.gz","level":1}}`),
+			},
+		},
 	}
 	for idx, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
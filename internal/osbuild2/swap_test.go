@@ -0,0 +1,48 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMkswapStage(t *testing.T) {
+	device := NewLoopbackDevice(&LoopbackDeviceOptions{Filename: "disk.img"})
+	options := &MkswapStageOptions{
+		UUID:  "6e4ff95f-f662-45ee-a82a-bdf44a2d0b75",
+		Label: "swap",
+	}
+
+	expectedStage := &Stage{
+		Type:    "org.osbuild.mkswap",
+		Options: options,
+		Devices: Devices{"device": *device},
+	}
+	assert.Equal(t, expectedStage, NewMkswapStage(options, device))
+}
+
+func TestNewSwapfileStage(t *testing.T) {
+	options := &SwapfileStageOptions{
+		Path: "/swapfile",
+		Size: 2147483648,
+	}
+	assert.Equal(t, &Stage{Type: "org.osbuild.swapfile", Options: options}, NewSwapfileStage(options))
+
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path": "/swapfile", "size": 2147483648}`, string(data))
+}
+
+func TestSwapFSTabEntry(t *testing.T) {
+	entry := &FSTabEntry{
+		Device:  "/swapfile",
+		VFSType: "swap",
+		Path:    "none",
+		Options: "sw",
+	}
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"device": "/swapfile", "vfs_type": "swap", "path": "none", "options": "sw"}`, string(data))
+}
@@ -0,0 +1,20 @@
+package osbuild2
+
+// SwapfileStageOptions describe the swap file the org.osbuild.swapfile stage
+// creates in the tree. The space of the file is allocated, since swap files
+// must not have holes.
+type SwapfileStageOptions struct {
+	// Location of the swap file in the tree
+	Path string `json:"path"`
+	// Size of the swap file in bytes
+	Size uint64 `json:"size"`
+}
+
+func (SwapfileStageOptions) isStageOptions() {}
+
+func NewSwapfileStage(options *SwapfileStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.swapfile",
+		Options: options,
+	}
+}
@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSystemdStage(t *testing.T) {
@@ -14,3 +16,12 @@ func TestNewSystemdStage(t *testing.T) {
 	actualStage := NewSystemdStage(&SystemdStageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestSystemdStageOptionsMaskedJSON(t *testing.T) {
+	data, err := json.Marshal(&SystemdStageOptions{
+		EnabledServices: []string{"sshd"},
+		MaskedServices:  []string{"kdump.service", "nfs-server.service"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"enabled_services": ["sshd"], "masked_services": ["kdump.service", "nfs-server.service"]}`, string(data))
+}
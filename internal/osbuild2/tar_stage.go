@@ -1,9 +1,24 @@
 package osbuild2
 
+const (
+	// POSIX.1-1988 archives, the format of OVA files
+	TarArchiveFormatUstar = "ustar"
+	// GNU tar 1.12 and earlier archives, the format Compute Engine imports
+	// disk images from
+	TarArchiveFormatOldGNU = "oldgnu"
+)
+
 type TarStageOptions struct {
 	// Filename for tar archive
 	Filename string `json:"filename"`
 
+	// Archive format, the tar default (gnu) if empty
+	Format string `json:"format,omitempty"`
+
+	// Paths of the tree to archive, in this order, instead of the whole
+	// tree
+	Paths []string `json:"paths,omitempty"`
+
 	// Enable support for POSIX ACLs
 	ACLs bool `json:"acls,omitempty"`
 
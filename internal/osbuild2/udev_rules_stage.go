@@ -0,0 +1,137 @@
+package osbuild2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UdevRulesStageOptions describes a udev rules file
+type UdevRulesStageOptions struct {
+	// Path of the rules file, under /etc/udev/rules.d/
+	Filename string `json:"filename"`
+	// Lines of the rules file, each a comment or a rule
+	Rules UdevRules `json:"rules"`
+}
+
+func (UdevRulesStageOptions) isStageOptions() {}
+
+// NewUdevRulesStage creates a new udev rules stage, which writes a rules
+// file to /etc/udev/rules.d/
+func NewUdevRulesStage(options *UdevRulesStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.udev.rules",
+		Options: options,
+	}
+}
+
+// UdevRulesItem is a line of a rules file, either a UdevRuleComment or a
+// UdevRule
+type UdevRulesItem interface {
+	isUdevRulesItem()
+}
+
+// UdevRules represents the lines of a rules file
+type UdevRules []UdevRulesItem
+
+func (rules *UdevRules) UnmarshalJSON(data []byte) error {
+	var rawRules []json.RawMessage
+	if err := json.Unmarshal(data, &rawRules); err != nil {
+		return err
+	}
+
+	for _, rawItem := range rawRules {
+		// rules are lists of operations, comments are objects
+		var item UdevRulesItem
+		if bytes.HasPrefix(bytes.TrimSpace(rawItem), []byte("[")) {
+			var rule UdevRule
+			if err := json.Unmarshal(rawItem, &rule); err != nil {
+				return err
+			}
+			item = rule
+		} else {
+			var comment UdevRuleComment
+			if err := json.Unmarshal(rawItem, &comment); err != nil {
+				return err
+			}
+			item = comment
+		}
+		*rules = append(*rules, item)
+	}
+	return nil
+}
+
+func (rules UdevRules) MarshalJSON() ([]byte, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("at least one udev rule must be specified for a rules file")
+	}
+	var items []UdevRulesItem = rules
+	return json.Marshal(items)
+}
+
+// UdevRuleComment represents comment lines of a rules file
+type UdevRuleComment struct {
+	Comment []string `json:"comment"`
+}
+
+func (UdevRuleComment) isUdevRulesItem() {}
+
+// NewUdevRuleComment creates a comment of the lines of text
+func NewUdevRuleComment(lines ...string) UdevRuleComment {
+	return UdevRuleComment{Comment: lines}
+}
+
+// UdevRule represents a rule of a rules file, the list of its match and
+// assignment operations
+type UdevRule []UdevOp
+
+func (UdevRule) isUdevRulesItem() {}
+
+func (rule UdevRule) MarshalJSON() ([]byte, error) {
+	if len(rule) == 0 {
+		return nil, fmt.Errorf("a udev rule needs at least one operation")
+	}
+	var ops []UdevOp = rule
+	return json.Marshal(ops)
+}
+
+// UdevOp is a match, e.g. KERNEL=="ttyUSB0", or an assignment, e.g.
+// MODE="0660", of a rule
+type UdevOp struct {
+	Key   UdevKey `json:"K"`
+	Op    string  `json:"O"`
+	Value string  `json:"V"`
+}
+
+// UdevKey is the key of an operation with its optional argument, e.g.
+// ATTR{address}
+type UdevKey struct {
+	Key string
+	Arg string
+}
+
+// keys with an argument are serialized as an object
+type udevKeyArg struct {
+	Key string `json:"key"`
+	Arg string `json:"arg"`
+}
+
+func (key UdevKey) MarshalJSON() ([]byte, error) {
+	if key.Arg == "" {
+		return json.Marshal(key.Key)
+	}
+	return json.Marshal(udevKeyArg(key))
+}
+
+func (key *UdevKey) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*key = UdevKey{}
+		return json.Unmarshal(data, &key.Key)
+	}
+	var keyArg udevKeyArg
+	if err := json.Unmarshal(data, &keyArg); err != nil {
+		return err
+	}
+	*key = UdevKey(keyArg)
+	return nil
+}
@@ -0,0 +1,58 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUdevRulesStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.udev.rules",
+		Options: &UdevRulesStageOptions{},
+	}
+	actualStage := NewUdevRulesStage(&UdevRulesStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestUdevRulesStage_MarshalJSON(t *testing.T) {
+	options := UdevRulesStageOptions{
+		Filename: "/etc/udev/rules.d/70-persistent-net.rules",
+		Rules: UdevRules{
+			NewUdevRuleComment("rename the onboard NIC"),
+			UdevRule{
+				{Key: UdevKey{Key: "SUBSYSTEM"}, Op: "==", Value: "net"},
+				{Key: UdevKey{Key: "ATTR", Arg: "address"}, Op: "==", Value: "52:54:00:12:34:56"},
+				{Key: UdevKey{Key: "NAME"}, Op: "=", Value: "lan0"},
+			},
+		},
+	}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	expected := `{
+		"filename": "/etc/udev/rules.d/70-persistent-net.rules",
+		"rules": [
+			{"comment": ["rename the onboard NIC"]},
+			[
+				{"K": "SUBSYSTEM", "O": "==", "V": "net"},
+				{"K": {"key": "ATTR", "arg": "address"}, "O": "==", "V": "52:54:00:12:34:56"},
+				{"K": "NAME", "O": "=", "V": "lan0"}
+			]
+		]
+	}`
+	assert.JSONEq(t, expected, string(data))
+
+	var parsed UdevRulesStageOptions
+	require.NoError(t, json.Unmarshal([]byte(expected), &parsed))
+	assert.Equal(t, options, parsed)
+}
+
+func TestUdevRulesStage_MarshalJSON_Invalid(t *testing.T) {
+	_, err := json.Marshal(UdevRulesStageOptions{Filename: "/etc/udev/rules.d/70-serial.rules"})
+	assert.Error(t, err)
+
+	_, err = json.Marshal(UdevRulesStageOptions{Filename: "/etc/udev/rules.d/70-serial.rules", Rules: UdevRules{UdevRule{}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,16 @@
+package osbuild2
+
+// UpdateCATrustStageOptions has no options. The stage runs update-ca-trust,
+// which extracts the trust store of the tree from the certificates in
+// /etc/pki/ca-trust/source and /usr/share/pki/ca-trust-source.
+type UpdateCATrustStageOptions struct{}
+
+func (UpdateCATrustStageOptions) isStageOptions() {}
+
+// NewUpdateCATrustStage creates a new org.osbuild.pki.update-ca-trust stage
+func NewUpdateCATrustStage() *Stage {
+	return &Stage{
+		Type:    "org.osbuild.pki.update-ca-trust",
+		Options: &UpdateCATrustStageOptions{},
+	}
+}
@@ -0,0 +1,17 @@
+package osbuild2
+
+// UpdateCryptoPoliciesStageOptions sets the system-wide crypto policy
+type UpdateCryptoPoliciesStageOptions struct {
+	// Name of the policy, e.g. DEFAULT or FIPS
+	Policy string `json:"policy"`
+}
+
+func (UpdateCryptoPoliciesStageOptions) isStageOptions() {}
+
+// NewUpdateCryptoPoliciesStage creates a new org.osbuild.update-crypto-policies stage
+func NewUpdateCryptoPoliciesStage(options *UpdateCryptoPoliciesStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.update-crypto-policies",
+		Options: options,
+	}
+}
@@ -15,6 +15,15 @@ type UsersStageOptionsUser struct {
 	Shell       *string  `json:"shell,omitempty"`
 	Password    *string  `json:"password,omitempty"`
 	Key         *string  `json:"key,omitempty"`
+	// Permissions of the home directory, as an octal string, e.g. "0700"
+	HomeMode *string `json:"home_mode,omitempty"`
+	// Create a system account, with a UID from the system range and
+	// without a home directory unless one is set
+	System *bool `json:"system,omitempty"`
+	// Lock the password of the account
+	Locked *bool `json:"locked,omitempty"`
+	// Date on which the account is disabled, as YYYY-MM-DD
+	ExpireDate *string `json:"expiredate,omitempty"`
 }
 
 func NewUsersStage(options *UsersStageOptions) *Stage {
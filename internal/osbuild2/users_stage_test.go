@@ -1,9 +1,12 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewUsersStage(t *testing.T) {
@@ -14,3 +17,19 @@ func TestNewUsersStage(t *testing.T) {
 	actualStage := NewUsersStage(&UsersStageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestUsersStageOptions_MarshalJSON(t *testing.T) {
+	options := &UsersStageOptions{
+		Users: map[string]UsersStageOptionsUser{
+			"alice": {},
+			"svc": {
+				UID:      common.IntToPtr(900),
+				HomeMode: common.StringToPtr("0700"),
+				System:   common.BoolToPtr(true),
+			},
+		},
+	}
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"users":{"alice":{},"svc":{"uid":900,"home_mode":"0700","system":true}}}`, string(data))
+}
@@ -0,0 +1,29 @@
+package osbuild2
+
+// Options for the org.osbuild.wsl.conf stage, which writes /etc/wsl.conf,
+// the configuration of the distribution in the Windows Subsystem for Linux
+type WSLConfStageOptions struct {
+	User    *WSLConfUserOptions    `json:"user,omitempty"`
+	Network *WSLConfNetworkOptions `json:"network,omitempty"`
+}
+
+func (WSLConfStageOptions) isStageOptions() {}
+
+// The [user] section of wsl.conf
+type WSLConfUserOptions struct {
+	// User WSL logs in as instead of root
+	Default string `json:"default"`
+}
+
+// The [network] section of wsl.conf
+type WSLConfNetworkOptions struct {
+	// Whether WSL sets the hostname to the one of the Windows host
+	GenerateHostname *bool `json:"generateHostname,omitempty"`
+}
+
+func NewWSLConfStage(options *WSLConfStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.wsl.conf",
+		Options: options,
+	}
+}
@@ -0,0 +1,16 @@
+package osbuild2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWSLConfStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.wsl.conf",
+		Options: &WSLConfStageOptions{},
+	}
+	actualStage := NewWSLConfStage(&WSLConfStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
@@ -1,10 +1,11 @@
 package osbuild2
 
-func NewXfsMount(name, source, target string) *Mount {
+func NewXfsMount(name, source, target string, options ...string) *Mount {
 	return &Mount{
-		Type:   "org.osbuild.xfs",
-		Name:   name,
-		Source: source,
-		Target: target,
+		Type:    "org.osbuild.xfs",
+		Name:    name,
+		Source:  source,
+		Target:  target,
+		Options: options,
 	}
 }
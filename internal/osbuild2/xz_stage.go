@@ -1,8 +1,19 @@
 package osbuild2
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type XzStageOptions struct {
 	// Filename for xz archive
 	Filename string `json:"filename"`
+
+	// Compression preset from 1 to 9, the default of xz if 0
+	Level uint `json:"level,omitempty"`
+
+	// Number of compression threads, the default of the stage if 0
+	Threads uint `json:"threads,omitempty"`
 }
 
 func (XzStageOptions) isStageOptions() {}
@@ -13,6 +24,17 @@ func NewXzStageOptions(filename string) *XzStageOptions {
 	}
 }
 
+// alias for custom marshaller
+type xzStageOptions XzStageOptions
+
+// Custom marshaller for validating
+func (options XzStageOptions) MarshalJSON() ([]byte, error) {
+	if options.Level > 9 {
+		return nil, fmt.Errorf("invalid xz compression level %d", options.Level)
+	}
+	return json.Marshal(xzStageOptions(options))
+}
+
 type XzStageInputs interface {
 	isXzStageInputs()
 }
@@ -1,6 +1,7 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,3 +46,12 @@ func TestNewXzStageNoInputs(t *testing.T) {
 	actualStage := NewXzStage(&XzStageOptions{Filename: filename}, nil)
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestXzStageOptions_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(XzStageOptions{Filename: "image.raw.xz", Level: 6, Threads: 2})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"filename":"image.raw.xz","level":6,"threads":2}`, string(data))
+
+	_, err = json.Marshal(XzStageOptions{Filename: "image.raw.xz", Level: 10})
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// yumReposFilenameRegexp matches the names of repo files
+var yumReposFilenameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.:-]+\.repo$`)
+
+// YumReposStageOptions describes a repo file in /etc/yum.repos.d
+type YumReposStageOptions struct {
+	// Name of the repo file, e.g. internal.repo
+	Filename string `json:"filename"`
+	// Repositories of the repo file
+	Repos []YumRepository `json:"repos"`
+}
+
+func (YumReposStageOptions) isStageOptions() {}
+
+// YumRepository describes a repository of a repo file
+type YumRepository struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	BaseURLs   []string `json:"baseurl,omitempty"`
+	Metalink   string   `json:"metalink,omitempty"`
+	Mirrorlist string   `json:"mirrorlist,omitempty"`
+	GPGKey     []string `json:"gpgkey,omitempty"`
+	GPGCheck   *bool    `json:"gpgcheck,omitempty"`
+	Enabled    *bool    `json:"enabled,omitempty"`
+}
+
+// Unexported alias for use in YumReposStageOptions's MarshalJSON() to prevent recursion
+type yumReposStageOptions YumReposStageOptions
+
+func (o YumReposStageOptions) MarshalJSON() ([]byte, error) {
+	if !yumReposFilenameRegexp.MatchString(o.Filename) {
+		return nil, fmt.Errorf("repo file name %q must be a file name with the .repo suffix", o.Filename)
+	}
+	if len(o.Repos) == 0 {
+		return nil, fmt.Errorf("at least one repository must be specified for a repo file")
+	}
+	for _, repo := range o.Repos {
+		if repo.ID == "" {
+			return nil, fmt.Errorf("repositories of a repo file require an ID")
+		}
+		if len(repo.BaseURLs) == 0 && repo.Metalink == "" && repo.Mirrorlist == "" {
+			return nil, fmt.Errorf("repository %q requires a baseurl, metalink, or mirrorlist", repo.ID)
+		}
+	}
+	options := yumReposStageOptions(o)
+	return json.Marshal(options)
+}
+
+// NewYumReposStage creates a new org.osbuild.yum.repos stage, which writes a
+// repo file to /etc/yum.repos.d
+func NewYumReposStage(options *YumReposStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.yum.repos",
+		Options: options,
+	}
+}
@@ -0,0 +1,32 @@
+package osbuild2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewYumReposStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.yum.repos",
+		Options: &YumReposStageOptions{},
+	}
+	actualStage := NewYumReposStage(&YumReposStageOptions{})
+	assert.Equal(t, expectedStage, actualStage)
+}
+
+func TestYumReposStage_MarshalJSON_Invalid(t *testing.T) {
+	repo := YumRepository{ID: "internal", BaseURLs: []string{"https://repo.example.com"}}
+	invalid := []YumReposStageOptions{
+		{Filename: "internal.repo"},
+		{Filename: "internal", Repos: []YumRepository{repo}},
+		{Filename: "../internal.repo", Repos: []YumRepository{repo}},
+		{Filename: "internal.repo", Repos: []YumRepository{{BaseURLs: []string{"https://repo.example.com"}}}},
+		{Filename: "internal.repo", Repos: []YumRepository{{ID: "internal"}}},
+	}
+	for _, options := range invalid {
+		_, err := json.Marshal(options)
+		assert.Error(t, err, options.Filename)
+	}
+}
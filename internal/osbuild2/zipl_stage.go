@@ -2,9 +2,13 @@ package osbuild2
 
 // The ZiplStageOptions describe how to create zipl stage
 //
-// The only configuration option available is a boot timeout and it is optional
+// The boot timeout and the kernel parameters are written to /etc/zipl.conf,
+// where zipl reads them from when a kernel update rewrites the boot record.
+// Both are optional.
 type ZiplStageOptions struct {
 	Timeout int `json:"timeout,omitempty"`
+	// Parameters of the kernel, including the root= argument
+	KernelOptions string `json:"kernel_opts,omitempty"`
 }
 
 func (ZiplStageOptions) isStageOptions() {}
@@ -1,9 +1,11 @@
 package osbuild2
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewZiplStageOptions(t *testing.T) {
@@ -22,3 +24,13 @@ func TestNewZiplStage(t *testing.T) {
 	actualStage := NewZiplStage(&ZiplStageOptions{})
 	assert.Equal(t, expectedStage, actualStage)
 }
+
+func TestZiplStageOptions_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(&ZiplStageOptions{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(data))
+
+	data, err = json.Marshal(&ZiplStageOptions{Timeout: 5, KernelOptions: "root=UUID=0194fdc2-fa2f-4cc0-81d3-ff12045b73c8 console=ttyS0"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"timeout":5,"kernel_opts":"root=UUID=0194fdc2-fa2f-4cc0-81d3-ff12045b73c8 console=ttyS0"}`, string(data))
+}
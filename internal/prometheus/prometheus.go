@@ -1,10 +1,14 @@
 package prometheus
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+const namespace = "composer"
+
 var (
 	TotalRequests = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "total_http_requests",
@@ -25,3 +29,103 @@ var (
 		Help: "total number of successful compose requests",
 	})
 )
+
+var (
+	Composes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "composes_total",
+		Help:      "total number of finished composes by status and image type",
+	}, []string{"status", "image_type"})
+)
+
+var (
+	PendingJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_jobs",
+		Help:      "number of jobs waiting in the job queue by job type",
+	}, []string{"job_type"})
+)
+
+var (
+	RunningJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "running_jobs",
+		Help:      "number of jobs currently being worked on by job type",
+	}, []string{"job_type"})
+)
+
+var (
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "time from the start to the end of a job by job type",
+		Buckets:   []float64{1, 10, 30, 60, 120, 300, 600, 900, 1800, 3600, 7200},
+	}, []string{"job_type"})
+)
+
+var (
+	LastHeartbeat = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_heartbeat_timestamp_seconds",
+		Help:      "unix time of the last heartbeat received from any worker",
+	})
+)
+
+var (
+	StaleJobs = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stale_jobs_total",
+		Help:      "total number of jobs failed because their worker stopped sending heartbeats",
+	})
+)
+
+// Depsolving happens both in osbuild-composer and in workers, so these
+// metrics are exported by both.
+var (
+	DepsolveDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "depsolve_duration_seconds",
+		Help:      "time it took to depsolve a package set by status",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"status"})
+)
+
+var (
+	DepsolveRepoMetadata = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "depsolve_repo_metadata_total",
+		Help:      "total number of repositories used for depsolving, by whether their metadata was unchanged since the previous depsolve (hit) or not (miss)",
+	}, []string{"cache"})
+)
+
+// Status label values
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Cache label values
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+func statusLabel(success bool) string {
+	if success {
+		return StatusSuccess
+	}
+	return StatusFailure
+}
+
+// ComposeFinished records a finished compose of the given image type.
+func ComposeFinished(imageType string, success bool) {
+	if imageType == "" {
+		imageType = "unknown"
+	}
+	Composes.WithLabelValues(statusLabel(success), imageType).Inc()
+}
+
+// DepsolveFinished records the duration of a depsolve.
+func DepsolveFinished(duration time.Duration, success bool) {
+	DepsolveDuration.WithLabelValues(statusLabel(success)).Observe(duration.Seconds())
+}
@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatherNames(t *testing.T, gatherer prometheus.Gatherer) map[string]bool {
+	families, err := gatherer.Gather()
+	require.NoError(t, err)
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	return names
+}
+
+func TestComposerMetrics(t *testing.T) {
+	ComposeFinished("qcow2", true)
+	ComposeFinished("", false)
+	PendingJobs.WithLabelValues("osbuild:x86_64").Inc()
+	RunningJobs.WithLabelValues("osbuild:x86_64").Inc()
+	JobDuration.WithLabelValues("osbuild:x86_64").Observe(42)
+	DepsolveFinished(3*time.Second, true)
+	DepsolveRepoMetadata.WithLabelValues(CacheHit).Inc()
+	LastHeartbeat.SetToCurrentTime()
+	StaleJobs.Inc()
+
+	names := gatherNames(t, prometheus.DefaultGatherer)
+	for _, name := range []string{
+		"total_http_requests",
+		"total_compose_requests",
+		"total_successful_compose_requests",
+		"composer_composes_total",
+		"composer_pending_jobs",
+		"composer_running_jobs",
+		"composer_job_duration_seconds",
+		"composer_depsolve_duration_seconds",
+		"composer_depsolve_repo_metadata_total",
+		"composer_last_heartbeat_timestamp_seconds",
+		"composer_stale_jobs_total",
+	} {
+		assert.Truef(t, names[name], "metric %s is missing", name)
+	}
+	// worker metrics are not exported by osbuild-composer
+	assert.False(t, names["composer_worker_upload_duration_seconds"])
+}
+
+func TestWorkerMetrics(t *testing.T) {
+	UploadFinished("org.osbuild.aws", time.Minute, true)
+	UploadFinished("org.osbuild.gcp", time.Minute, false)
+	WorkerLastHeartbeat.SetToCurrentTime()
+	StageFinished("org.osbuild.rpm", 312, false)
+	DepsolveFinished(time.Second, false)
+	DepsolveRepoMetadata.WithLabelValues(CacheMiss).Inc()
+
+	names := gatherNames(t, WorkerRegistry)
+	for _, name := range []string{
+		"composer_worker_upload_duration_seconds",
+		"composer_worker_upload_failures_total",
+		"composer_worker_last_heartbeat_timestamp_seconds",
+		"composer_worker_stage_duration_seconds",
+		"composer_depsolve_duration_seconds",
+		"composer_depsolve_repo_metadata_total",
+		"go_goroutines",
+	} {
+		assert.Truef(t, names[name], "metric %s is missing", name)
+	}
+	// composer metrics are not exported by workers
+	assert.False(t, names["composer_composes_total"])
+	assert.False(t, names["total_http_requests"])
+}
@@ -0,0 +1,75 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// WorkerRegistry holds the metrics exported by osbuild-worker. It is separate
+// from the default registry, so that the worker does not export the metrics
+// of osbuild-composer.
+var WorkerRegistry = prometheus.NewRegistry()
+
+var workerFactory = promauto.With(WorkerRegistry)
+
+var (
+	UploadDuration = workerFactory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "upload_duration_seconds",
+		Help:      "time it took to upload an image by target type and status",
+		Buckets:   []float64{10, 30, 60, 120, 300, 600, 900, 1800, 3600},
+	}, []string{"target", "status"})
+)
+
+var (
+	UploadFailures = workerFactory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "upload_failures_total",
+		Help:      "total number of failed uploads by target type",
+	}, []string{"target"})
+)
+
+var (
+	StageDuration = workerFactory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "stage_duration_seconds",
+		Help:      "time osbuild spent in a stage by stage type and status, if osbuild reported it",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800},
+	}, []string{"stage", "status"})
+)
+
+var (
+	WorkerLastHeartbeat = workerFactory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "last_heartbeat_timestamp_seconds",
+		Help:      "unix time of the last heartbeat this worker sent to osbuild-composer",
+	})
+)
+
+func init() {
+	WorkerRegistry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		DepsolveDuration,
+		DepsolveRepoMetadata,
+	)
+}
+
+// UploadFinished records an upload to the given target type.
+func UploadFinished(target string, duration time.Duration, success bool) {
+	UploadDuration.WithLabelValues(target, statusLabel(success)).Observe(duration.Seconds())
+	if !success {
+		UploadFailures.WithLabelValues(target).Inc()
+	}
+}
+
+// StageFinished records the duration of an osbuild stage in seconds.
+func StageFinished(stageType string, seconds float64, success bool) {
+	StageDuration.WithLabelValues(stageType, statusLabel(success)).Observe(seconds)
+}
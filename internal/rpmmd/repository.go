@@ -11,9 +11,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gobwas/glob"
+	"github.com/osbuild/osbuild-composer/internal/prometheus"
 	"github.com/osbuild/osbuild-composer/internal/rhsm"
 )
 
@@ -377,6 +379,11 @@ type rpmmdImpl struct {
 	CacheDir      string
 	subscriptions *rhsm.Subscriptions
 	dnfJsonPath   string
+
+	// metadata checksums of the repositories seen by previous depsolves,
+	// used to tell whether dnf could reuse its cached metadata
+	checksumsMu sync.Mutex
+	checksums   map[string]string
 }
 
 func NewRPMMD(cacheDir, dnfJsonPath string) RPMMD {
@@ -392,6 +399,7 @@ func NewRPMMD(cacheDir, dnfJsonPath string) RPMMD {
 		CacheDir:      cacheDir,
 		subscriptions: subscriptions,
 		dnfJsonPath:   dnfJsonPath,
+		checksums:     make(map[string]string),
 	}
 }
 
@@ -477,7 +485,12 @@ func (r *rpmmdImpl) Depsolve(packageSet PackageSet, repos []RepoConfig, modulePl
 		Checksums    map[string]string `json:"checksums"`
 		Dependencies []dnfPackageSpec  `json:"dependencies"`
 	}
+	start := time.Now()
 	err := runDNF(r.dnfJsonPath, "depsolve", arguments, &reply)
+	prometheus.DepsolveFinished(time.Since(start), err == nil)
+	if err == nil {
+		r.recordMetadataChecksums(repos, arch, reply.Checksums)
+	}
 
 	dependencies := make([]PackageSpec, len(reply.Dependencies))
 	for i, pack := range reply.Dependencies {
@@ -503,6 +516,28 @@ func (r *rpmmdImpl) Depsolve(packageSet PackageSet, repos []RepoConfig, modulePl
 	return dependencies, reply.Checksums, err
 }
 
+// recordMetadataChecksums counts a cache hit for each repository whose
+// metadata did not change since the previous depsolve, and a miss otherwise.
+// The checksums are keyed by the index of the repository in repos.
+func (r *rpmmdImpl) recordMetadataChecksums(repos []RepoConfig, arch string, checksums map[string]string) {
+	r.checksumsMu.Lock()
+	defer r.checksumsMu.Unlock()
+
+	for i, repo := range repos {
+		checksum, ok := checksums[strconv.Itoa(i)]
+		if !ok {
+			continue
+		}
+		key := strings.Join([]string{repo.BaseURL, repo.Metalink, repo.MirrorList, arch}, "|")
+		if previous, ok := r.checksums[key]; ok && previous == checksum {
+			prometheus.DepsolveRepoMetadata.WithLabelValues(prometheus.CacheHit).Inc()
+		} else {
+			prometheus.DepsolveRepoMetadata.WithLabelValues(prometheus.CacheMiss).Inc()
+		}
+		r.checksums[key] = checksum
+	}
+}
+
 func (packages PackageList) Search(globPatterns ...string) (PackageList, error) {
 	var globs []glob.Glob
 
@@ -30,6 +30,8 @@ type ImageBuild struct {
 	JobFinished time.Time
 	Size        uint64
 	JobID       uuid.UUID
+	// Set for reproducible builds only, nil otherwise.
+	Reproducible *ReproducibleBuild
 	// Kept for backwards compatibility. Image builds which were done
 	// before the move to the job queue use this to store whether they
 	// finished successfully.
@@ -43,6 +45,11 @@ func (ib *ImageBuild) DeepCopy() ImageBuild {
 		newTarget := *t
 		newTargets = append(newTargets, &newTarget)
 	}
+	var reproducible *ReproducibleBuild
+	if ib.Reproducible != nil {
+		r := *ib.Reproducible
+		reproducible = &r
+	}
 	// Create new image build struct
 	return ImageBuild{
 		ID:          ib.ID,
@@ -55,9 +62,19 @@ func (ib *ImageBuild) DeepCopy() ImageBuild {
 		JobFinished: ib.JobFinished,
 		Size:        ib.Size,
 		JobID:       ib.JobID,
+
+		Reproducible: reproducible,
 	}
 }
 
+// ReproducibleBuild holds the inputs of a manifest that are otherwise chosen
+// at random or taken from the current time. Generating the manifest again
+// with the same values yields the same manifest.
+type ReproducibleBuild struct {
+	Seed            int64
+	SourceDateEpoch int64
+}
+
 func (ib *ImageBuild) GetLocalTargetOptions() *target.LocalTargetOptions {
 	for _, t := range ib.Targets {
 		switch options := t.Options.(type) {
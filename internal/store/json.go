@@ -41,6 +41,7 @@ type composesV0 map[uuid.UUID]composeV0
 type imageBuildV0 struct {
 	ID          int              `json:"id"`
 	ImageType   string           `json:"image_type"`
+	Arch        string           `json:"arch,omitempty"`
 	Manifest    distro.Manifest  `json:"manifest"`
 	Targets     []*target.Target `json:"targets"`
 	JobCreated  time.Time        `json:"job_created"`
@@ -49,12 +50,19 @@ type imageBuildV0 struct {
 	Size        uint64           `json:"size"`
 	JobID       uuid.UUID        `json:"jobid,omitempty"`
 
+	Reproducible *reproducibleBuildV0 `json:"reproducible,omitempty"`
+
 	// Kept for backwards compatibility. Image builds which were done
 	// before the move to the job queue use this to store whether they
 	// finished successfully.
 	QueueStatus common.ImageBuildState `json:"queue_status,omitempty"`
 }
 
+type reproducibleBuildV0 struct {
+	Seed            int64 `json:"seed"`
+	SourceDateEpoch int64 `json:"source_date_epoch"`
+}
+
 type sourceV0 struct {
 	Name     string   `json:"name"`
 	Type     string   `json:"type"`
@@ -114,7 +122,27 @@ func newComposesFromV0(composesStruct composesV0, arch distro.Arch, log *log.Log
 }
 
 func newImageBuildFromV0(imageBuildStruct imageBuildV0, arch distro.Arch) (ImageBuild, error) {
-	imgType := imageTypeFromCompatString(imageBuildStruct.ImageType, arch)
+	// Image builds without an architecture were made before composes
+	// could be built for other architectures than the host's
+	if imageBuildStruct.Arch != "" && imageBuildStruct.Arch != arch.Name() {
+		var err error
+		arch, err = arch.Distro().GetArch(imageBuildStruct.Arch)
+		if err != nil {
+			return ImageBuild{}, err
+		}
+	}
+
+	var imgType distro.ImageType
+	if imageBuildStruct.ImageType == distro.CustomImageTypeName {
+		// custom image types are defined by the manifest they build
+		var err error
+		imgType, err = distro.NewCustomImageType(arch, imageBuildStruct.Manifest)
+		if err != nil {
+			return ImageBuild{}, err
+		}
+	} else {
+		imgType = imageTypeFromCompatString(imageBuildStruct.ImageType, arch)
+	}
 	if imgType == nil {
 		// Invalid type strings in serialization format, this may happen
 		// on upgrades.
@@ -140,6 +168,8 @@ func newImageBuildFromV0(imageBuildStruct imageBuildV0, arch distro.Arch) (Image
 		Size:        imageBuildStruct.Size,
 		JobID:       imageBuildStruct.JobID,
 		QueueStatus: queueStatus,
+
+		Reproducible: (*ReproducibleBuild)(imageBuildStruct.Reproducible),
 	}, nil
 }
 
@@ -273,6 +303,7 @@ func newComposeV0(compose Compose) composeV0 {
 			{
 				ID:          compose.ImageBuild.ID,
 				ImageType:   imageTypeToCompatString(compose.ImageBuild.ImageType),
+				Arch:        compose.ImageBuild.ImageType.Arch().Name(),
 				Manifest:    compose.ImageBuild.Manifest,
 				Targets:     compose.ImageBuild.Targets,
 				JobCreated:  compose.ImageBuild.JobCreated,
@@ -281,6 +312,8 @@ func newComposeV0(compose Compose) composeV0 {
 				Size:        compose.ImageBuild.Size,
 				JobID:       compose.ImageBuild.JobID,
 				QueueStatus: compose.ImageBuild.QueueStatus,
+
+				Reproducible: (*reproducibleBuildV0)(compose.ImageBuild.Reproducible),
 			},
 		},
 		Packages: pkgs,
@@ -346,6 +379,7 @@ var imageTypeCompatMapping = map[string]string{
 	"openstack":                      "OpenStack",
 	"qcow2":                          "qcow2",
 	"vmdk":                           "VMWare",
+	"ova":                            "ova",
 	"ext4-filesystem":                "Raw-filesystem",
 	"partitioned-disk":               "Partitioned-disk",
 	"tar":                            "Tar",
@@ -362,10 +396,16 @@ var imageTypeCompatMapping = map[string]string{
 	"edge-raw-image":                 "edge-raw-image",
 	"edge-simplified-installer":      "edge-simplified-installer",
 	"image-installer":                "image-installer",
+	"minimal-raw":                    "minimal-raw",
+	"wsl":                            "wsl",
+	"gce":                            "gce",
+	"vhd-gen2":                       "vhd-gen2",
+	"vhdx":                           "vhdx",
 	"test_type":                      "test_type",         // used only in json_test.go
 	"test_type_invalid":              "test_type_invalid", // used only in json_test.go
 	"ec2":                            "ec2",
 	"ec2-ha":                         "ec2-ha",
+	distro.CustomImageTypeName:       distro.CustomImageTypeName,
 }
 
 func imageTypeToCompatString(imgType distro.ImageType) string {
@@ -1040,6 +1040,7 @@ func Test_newComposeV0(t *testing.T) {
 					{
 						ID:        0,
 						ImageType: "test_type",
+						Arch:      test_distro.TestArchName,
 						Manifest:  []byte("JSON MANIFEST GOES HERE"),
 						Targets: []*target.Target{
 							{
@@ -1267,6 +1268,7 @@ func Test_newComposesV0(t *testing.T) {
 						imageBuildV0{
 							ID:        0,
 							ImageType: test_distro.TestImageTypeName,
+							Arch:      test_distro.TestArchName,
 							Manifest:  []byte("JSON MANIFEST GOES HERE"),
 							Targets: []*target.Target{
 								{
@@ -1298,6 +1300,7 @@ func Test_newComposesV0(t *testing.T) {
 						imageBuildV0{
 							ID:        0,
 							ImageType: test_distro.TestImageTypeName,
+							Arch:      test_distro.TestArchName,
 							Manifest:  []byte("JSON MANIFEST GOES HERE"),
 							Targets: []*target.Target{
 								{
@@ -1584,3 +1587,38 @@ func Test_newImageBuildFromV0(t *testing.T) {
 		})
 	}
 }
+
+func Test_newImageBuildFromV0_Custom(t *testing.T) {
+	testDistro := test_distro.New()
+	testArch, _ := testDistro.GetArch(test_distro.TestArchName)
+	manifest := distro.Manifest(`{"version":"2","pipelines":[{"name":"image","stages":[{"type":"org.osbuild.truncate","options":{"filename":"disk.img"}}]}]}`)
+
+	ib, err := newImageBuildFromV0(imageBuildV0{ImageType: distro.CustomImageTypeName, Manifest: manifest}, testArch)
+	require.NoError(t, err)
+	assert.Equal(t, distro.CustomImageTypeName, ib.ImageType.Name())
+	assert.Equal(t, "disk.img", ib.ImageType.Filename())
+	assert.Equal(t, []string{"image"}, ib.ImageType.Exports())
+	assert.Equal(t, distro.CustomImageTypeName, imageTypeToCompatString(ib.ImageType))
+
+	_, err = newImageBuildFromV0(imageBuildV0{ImageType: distro.CustomImageTypeName, Manifest: distro.Manifest(`{}`)}, testArch)
+	assert.Error(t, err)
+}
+
+func Test_newImageBuildFromV0_Arch(t *testing.T) {
+	testDistro := test_distro.New()
+	testArch, _ := testDistro.GetArch(test_distro.TestArchName)
+
+	// image builds are restored for the architecture they were built for
+	ib, err := newImageBuildFromV0(imageBuildV0{ImageType: test_distro.TestImageTypeName, Arch: test_distro.TestArch2Name}, testArch)
+	require.NoError(t, err)
+	assert.Equal(t, test_distro.TestArch2Name, ib.ImageType.Arch().Name())
+	assert.Equal(t, test_distro.TestArch2Name, newComposeV0(Compose{Blueprint: &blueprint.Blueprint{}, ImageBuild: ib}).ImageBuilds[0].Arch)
+
+	// and for the host architecture if they don't record one
+	ib, err = newImageBuildFromV0(imageBuildV0{ImageType: test_distro.TestImageTypeName}, testArch)
+	require.NoError(t, err)
+	assert.Equal(t, test_distro.TestArchName, ib.ImageType.Arch().Name())
+
+	_, err = newImageBuildFromV0(imageBuildV0{ImageType: test_distro.TestImageTypeName, Arch: "unknown_arch"}, testArch)
+	assert.Error(t, err)
+}
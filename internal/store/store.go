@@ -340,7 +340,8 @@ func (s *Store) PushCompose(composeID uuid.UUID,
 	size uint64,
 	targets []*target.Target,
 	jobId uuid.UUID,
-	packages []rpmmd.PackageSpec) error {
+	packages []rpmmd.PackageSpec,
+	reproducible *ReproducibleBuild) error {
 
 	if _, exists := s.GetCompose(composeID); exists {
 		panic("a compose with this id already exists")
@@ -361,6 +362,9 @@ func (s *Store) PushCompose(composeID uuid.UUID,
 				JobCreated: time.Now(),
 				Size:       size,
 				JobID:      jobId,
+				// the inputs needed to generate the manifest again, nil
+				// for composes that aren't reproducible
+				Reproducible: reproducible,
 			},
 			Packages: packages,
 		}
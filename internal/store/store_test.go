@@ -260,19 +260,32 @@ func (suite *storeTest) TestDeleteBlueprintFromWorkspace() {
 
 func (suite *storeTest) TestPushCompose() {
 	testID := uuid.New()
-	err := suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, uuid.New(), []rpmmd.PackageSpec{})
+	err := suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, uuid.New(), []rpmmd.PackageSpec{}, nil)
 	suite.NoError(err)
 	suite.Panics(func() {
-		err = suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, []*target.Target{suite.myTarget}, uuid.New(), []rpmmd.PackageSpec{})
+		err = suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, []*target.Target{suite.myTarget}, uuid.New(), []rpmmd.PackageSpec{}, nil)
 	})
 	suite.NoError(err)
 
 	// Test with PackageSets
 	testID = uuid.New()
-	err = suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, uuid.New(), suite.myPackages)
+	err = suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, uuid.New(), suite.myPackages, nil)
 	suite.NoError(err)
 }
 
+func (suite *storeTest) TestPushComposeReproducible() {
+	testID := uuid.New()
+	reproducible := ReproducibleBuild{Seed: 42, SourceDateEpoch: 1634428800}
+	err := suite.myStore.PushCompose(testID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, uuid.New(), []rpmmd.PackageSpec{}, &reproducible)
+	suite.NoError(err)
+	suite.Equal(&reproducible, suite.myStore.composes[testID].ImageBuild.Reproducible)
+
+	// the record survives a round trip through the serialization format
+	compose, err := newComposeFromV0(newComposeV0(suite.myStore.composes[testID]), suite.myArch)
+	suite.NoError(err)
+	suite.Equal(&reproducible, compose.ImageBuild.Reproducible)
+}
+
 func (suite *storeTest) TestPushTestCompose() {
 	ID := uuid.New()
 	err := suite.myStore.PushTestCompose(ID, suite.myManifest, suite.myImageType, &suite.myBP, 123, nil, true, []rpmmd.PackageSpec{})
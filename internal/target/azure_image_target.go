@@ -6,6 +6,28 @@ type AzureImageTargetOptions struct {
 	Location       string `json:"location"`
 	SubscriptionID string `json:"subscription_id"`
 	ResourceGroup  string `json:"resource_group"`
+
+	// Gallery the image is published to, the image is only registered as a
+	// managed image if it is nil
+	Gallery *AzureGalleryOptions `json:"gallery,omitempty"`
+}
+
+// AzureGalleryOptions is a version of an image definition of a Shared Image
+// Gallery in the resource group of the target
+type AzureGalleryOptions struct {
+	Name            string `json:"name"`
+	ImageDefinition string `json:"image_definition"`
+	Version         string `json:"version"`
+	// TargetRegions the version is replicated to, only the location of the
+	// target with a single replica if empty
+	TargetRegions []AzureGalleryTargetRegion `json:"target_regions,omitempty"`
+}
+
+// AzureGalleryTargetRegion is a region a gallery image version is replicated
+// to and the number of its replicas there
+type AzureGalleryTargetRegion struct {
+	Name         string `json:"name"`
+	ReplicaCount int    `json:"replica_count"`
 }
 
 func (AzureImageTargetOptions) isTargetOptions() {}
@@ -26,6 +48,10 @@ func (AzureImageTargetOptions) isTargetOptions() {}
 // options. This means that this target can be used for multi-tenant
 // applications.
 //
+// If the gallery options are set, the registered image is then published as
+// a version of an image definition of a Shared Image Gallery, which must
+// exist already.
+//
 // If you need to just upload a PageBlob into Azure Storage, see the
 // org.osbuild.azure target.
 func NewAzureImageTarget(options *AzureImageTargetOptions) *Target {
@@ -34,6 +60,9 @@ func NewAzureImageTarget(options *AzureImageTargetOptions) *Target {
 
 type AzureImageTargetResultOptions struct {
 	ImageName string `json:"image_name"`
+	// GalleryImageVersionID is the resource ID of the gallery image version,
+	// if the image was published to a gallery
+	GalleryImageVersionID string `json:"gallery_image_version_id,omitempty"`
 }
 
 func (AzureImageTargetResultOptions) isTargetResultOptions() {}
@@ -1,5 +1,24 @@
 package target
 
+import (
+	"fmt"
+	"strings"
+)
+
+// GCPGuestOSFeatures are the guest OS features Compute Engine images can
+// have, see https://cloud.google.com/compute/docs/images/create-custom#guest-os-features
+var GCPGuestOSFeatures = []string{
+	"GVNIC",
+	"MULTI_IP_SUBNET",
+	"SECURE_BOOT",
+	"SEV_CAPABLE",
+	"SEV_LIVE_MIGRATABLE",
+	"SEV_SNP_CAPABLE",
+	"UEFI_COMPATIBLE",
+	"VIRTIO_SCSI_MULTIQUEUE",
+	"WINDOWS",
+}
+
 type GCPTargetOptions struct {
 	Filename          string   `json:"filename"`
 	Region            string   `json:"region"`
@@ -7,6 +26,12 @@ type GCPTargetOptions struct {
 	Bucket            string   `json:"bucket"`
 	Object            string   `json:"object"`
 	ShareWithAccounts []string `json:"shareWithAccounts"`
+	// GuestOSFeatures the image is created with, e.g. UEFI_COMPATIBLE
+	GuestOSFeatures []string `json:"guestOsFeatures,omitempty"`
+
+	// Credentials of the service account used to upload the image, the
+	// credentials of the worker are used if empty
+	Credentials []byte `json:"credentials,omitempty"`
 }
 
 func (GCPTargetOptions) isTargetOptions() {}
@@ -25,3 +50,21 @@ func (GCPTargetResultOptions) isTargetResultOptions() {}
 func NewGCPTargetResult(options *GCPTargetResultOptions) *TargetResult {
 	return newTargetResult("org.osbuild.gcp", options)
 }
+
+// ValidateGCPGuestOSFeatures returns an error naming the first of the
+// features which isn't one of GCPGuestOSFeatures
+func ValidateGCPGuestOSFeatures(features []string) error {
+	for _, feature := range features {
+		valid := false
+		for _, f := range GCPGuestOSFeatures {
+			if feature == f {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid guest OS feature %q, must be one of %s", feature, strings.Join(GCPGuestOSFeatures, ", "))
+		}
+	}
+	return nil
+}
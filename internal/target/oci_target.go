@@ -0,0 +1,30 @@
+package target
+
+type OCITargetOptions struct {
+	Filename    string `json:"filename"`
+	Region      string `json:"region"`
+	Compartment string `json:"compartment"`
+	Bucket      string `json:"bucket"`
+	Object      string `json:"object"`
+
+	// LaunchMode of the instances created from the image, OCI chooses it
+	// if empty
+	LaunchMode string `json:"launch_mode,omitempty"`
+}
+
+func (OCITargetOptions) isTargetOptions() {}
+
+func NewOCITarget(options *OCITargetOptions) *Target {
+	return newTarget("org.osbuild.oci", options)
+}
+
+type OCITargetResultOptions struct {
+	ImageID string `json:"image_id"`
+	Region  string `json:"region"`
+}
+
+func (OCITargetResultOptions) isTargetResultOptions() {}
+
+func NewOCITargetResult(options *OCITargetResultOptions) *TargetResult {
+	return newTargetResult("org.osbuild.oci", options)
+}
@@ -81,6 +81,8 @@ func UnmarshalTargetOptions(targetName string, rawOptions json.RawMessage) (Targ
 		options = new(KojiTargetOptions)
 	case "org.osbuild.vmware":
 		options = new(VMWareTargetOptions)
+	case "org.osbuild.oci":
+		options = new(OCITargetOptions)
 	default:
 		return nil, errors.New("unexpected target name")
 	}
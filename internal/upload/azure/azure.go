@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/2019-03-01/compute/mgmt/compute"
 	"github.com/Azure/azure-sdk-for-go/profiles/2019-03-01/resources/mgmt/resources"
 	"github.com/Azure/azure-sdk-for-go/profiles/2019-03-01/storage/mgmt/storage"
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 )
 
@@ -148,3 +151,120 @@ func (ac Client) RegisterImage(ctx context.Context, subscriptionID, resourceGrou
 
 	return nil
 }
+
+// The vendored compute API doesn't support Shared Image Galleries, so their
+// requests are sent with a newer API version of the REST API directly
+const galleryAPIVersion = "2021-07-01"
+
+// DefaultGalleryReplicationTimeout defines how long CreateGalleryImageVersion
+// should wait at most for the replication of an image version to finish.
+const DefaultGalleryReplicationTimeout = 3 * time.Hour
+
+// GalleryImageVersion is a version of an image definition of a Shared Image
+// Gallery
+type GalleryImageVersion struct {
+	Gallery         string
+	ImageDefinition string
+	Version         string
+	// TargetRegions the version is replicated to, only the location of the
+	// version with a single replica if empty
+	TargetRegions []GalleryTargetRegion
+}
+
+// GalleryTargetRegion is a region a gallery image version is replicated to
+// and the number of its replicas there
+type GalleryTargetRegion struct {
+	Name         string `json:"name"`
+	ReplicaCount int    `json:"regionalReplicaCount"`
+}
+
+type galleryImageVersion struct {
+	ID         string `json:"id,omitempty"`
+	Location   string `json:"location"`
+	Properties struct {
+		PublishingProfile struct {
+			TargetRegions []GalleryTargetRegion `json:"targetRegions"`
+		} `json:"publishingProfile"`
+		StorageProfile struct {
+			Source struct {
+				ID string `json:"id"`
+			} `json:"source"`
+		} `json:"storageProfile"`
+		ProvisioningState string `json:"provisioningState,omitempty"`
+	} `json:"properties"`
+}
+
+// newGalleryImageVersion returns the gallery image version created from the
+// managed image with the given resource ID
+func newGalleryImageVersion(imageID, location string, targetRegions []GalleryTargetRegion) galleryImageVersion {
+	var v galleryImageVersion
+	v.Location = location
+	v.Properties.PublishingProfile.TargetRegions = targetRegions
+	if len(targetRegions) == 0 {
+		v.Properties.PublishingProfile.TargetRegions = []GalleryTargetRegion{{Name: location, ReplicaCount: 1}}
+	}
+	v.Properties.StorageProfile.Source.ID = imageID
+	return v
+}
+
+// CreateGalleryImageVersion publishes a managed image registered by
+// RegisterImage() as a version of an image definition of a Shared Image
+// Gallery in the same resource group, and returns the resource ID of the
+// version. It waits until the version is replicated to all its target
+// regions, which can take a long time, so the context should have a deadline.
+func (ac Client) CreateGalleryImageVersion(ctx context.Context, subscriptionID, resourceGroup, imageName, location string, version GalleryImageVersion) (string, error) {
+	client := autorest.NewClientWithUserAgent("osbuild-composer")
+	client.Authorizer = ac.authorizer
+
+	imageID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s", subscriptionID, resourceGroup, imageName)
+	pathParameters := map[string]interface{}{
+		"subscriptionId":          autorest.Encode("path", subscriptionID),
+		"resourceGroupName":       autorest.Encode("path", resourceGroup),
+		"galleryName":             autorest.Encode("path", version.Gallery),
+		"galleryImageName":        autorest.Encode("path", version.ImageDefinition),
+		"galleryImageVersionName": autorest.Encode("path", version.Version),
+	}
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx),
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.AsPut(),
+		autorest.WithBaseURL(azure.PublicCloud.ResourceManagerEndpoint),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Compute/galleries/{galleryName}/images/{galleryImageName}/versions/{galleryImageVersionName}", pathParameters),
+		autorest.WithJSON(newGalleryImageVersion(imageID, location, version.TargetRegions)),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": galleryAPIVersion}))
+	if err != nil {
+		return "", fmt.Errorf("preparing the create gallery image version request failed: %v", err)
+	}
+
+	resp, err := client.Send(req, azure.DoRetryWithRegistration(client))
+	if err != nil {
+		return "", fmt.Errorf("sending the create gallery image version request failed: %v", err)
+	}
+
+	future, err := azure.NewFutureFromResponse(resp)
+	if err != nil {
+		return "", fmt.Errorf("create gallery image version request failed: %v", err)
+	}
+
+	err = future.WaitForCompletionRef(ctx, client)
+	if err != nil {
+		return "", fmt.Errorf("waiting for the replication of the gallery image version failed: %v", err)
+	}
+
+	resp, err = future.GetResult(client)
+	if err != nil {
+		return "", fmt.Errorf("retrieving the gallery image version failed: %v", err)
+	}
+	var result galleryImageVersion
+	err = autorest.Respond(resp,
+		azure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		return "", fmt.Errorf("retrieving the gallery image version failed: %v", err)
+	}
+	if result.Properties.ProvisioningState != "Succeeded" {
+		return "", fmt.Errorf("gallery image version %s is in the %s state", result.ID, result.Properties.ProvisioningState)
+	}
+
+	return result.ID, nil
+}
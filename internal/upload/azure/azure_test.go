@@ -0,0 +1,50 @@
+package azure
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGalleryImageVersion(t *testing.T) {
+	imageID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/images/my-image"
+
+	// without target regions, the version is only replicated to its location
+	v := newGalleryImageVersion(imageID, "westeurope", nil)
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"location": "westeurope",
+		"properties": {
+			"publishingProfile": {
+				"targetRegions": [{"name": "westeurope", "regionalReplicaCount": 1}]
+			},
+			"storageProfile": {
+				"source": {"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/images/my-image"}
+			}
+		}
+	}`, string(data))
+
+	v = newGalleryImageVersion(imageID, "westeurope", []GalleryTargetRegion{
+		{Name: "westeurope", ReplicaCount: 2},
+		{Name: "eastus", ReplicaCount: 1},
+	})
+	data, err = json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"location": "westeurope",
+		"properties": {
+			"publishingProfile": {
+				"targetRegions": [
+					{"name": "westeurope", "regionalReplicaCount": 2},
+					{"name": "eastus", "regionalReplicaCount": 1}
+				]
+			},
+			"storageProfile": {
+				"source": {"id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/images/my-image"}
+			}
+		}
+	}`, string(data))
+}
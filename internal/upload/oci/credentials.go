@@ -0,0 +1,89 @@
+package oci
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Credentials of an OCI user with an API signing key
+type Credentials struct {
+	user        string
+	tenancy     string
+	fingerprint string
+	region      string
+	key         *rsa.PrivateKey
+}
+
+// ParseOCICredentialsFile parses a credentials file for OCI.
+// The file is in toml format and contains the OCIDs of the user and its
+// tenancy, the fingerprint and the path of the PEM encoded private key of
+// the API signing key of the user, and the region used when a target
+// doesn't set one.
+//
+// Example of the file:
+// user        = "ocid1.user.oc1..aaaaaaaa"
+// tenancy     = "ocid1.tenancy.oc1..aaaaaaaa"
+// fingerprint = "20:3b:97:13:55:1c:5b:0d:d3:37:d8:50:4e:c5:3a:34"
+// key_file    = "/etc/osbuild-worker/oci-api-key.pem"
+// region      = "eu-frankfurt-1"
+func ParseOCICredentialsFile(filename string) (*Credentials, error) {
+	var creds struct {
+		User        string `toml:"user"`
+		Tenancy     string `toml:"tenancy"`
+		Fingerprint string `toml:"fingerprint"`
+		KeyFile     string `toml:"key_file"`
+		Region      string `toml:"region"`
+	}
+	_, err := toml.DecodeFile(filename, &creds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse OCI credentials: %v", err)
+	}
+	if creds.User == "" || creds.Tenancy == "" || creds.Fingerprint == "" || creds.KeyFile == "" {
+		return nil, errors.New("cannot parse OCI credentials: user, tenancy, fingerprint and key_file are required")
+	}
+
+	keyPEM, err := ioutil.ReadFile(creds.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read OCI API signing key: %v", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		user:        creds.User,
+		tenancy:     creds.Tenancy,
+		fingerprint: creds.Fingerprint,
+		region:      creds.Region,
+		key:         key,
+	}, nil
+}
+
+// parsePrivateKey parses an unencrypted RSA private key in either PKCS#1 or
+// PKCS#8 PEM encoding, which are the ones the OCI console and CLI create
+func parsePrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("cannot parse OCI API signing key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse OCI API signing key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("cannot parse OCI API signing key: not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,337 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAuthentication is matched by the errors of requests OCI rejects
+	// because the credentials are invalid or lack the permissions for them
+	ErrAuthentication = errors.New("OCI authentication failed")
+	// ErrQuotaExceeded is matched by the errors of requests OCI rejects
+	// because they exceed the service limits or quotas of the tenancy
+	ErrQuotaExceeded = errors.New("OCI quota exceeded")
+)
+
+// APIError is an error response of the OCI API
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+// Kind returns ErrAuthentication or ErrQuotaExceeded if OCI rejected the
+// request because of the credentials or the limits of the tenancy, and nil
+// for all other errors
+func (e *APIError) Kind() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized,
+		e.Code == "NotAuthenticated",
+		e.Code == "NotAuthorizedOrNotFound",
+		e.Code == "NotAuthorizedOrResourceAlreadyExists":
+		return ErrAuthentication
+	case e.Code == "LimitExceeded", e.Code == "QuotaExceeded":
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// Is makes errors.Is() match authentication and quota errors with
+// ErrAuthentication and ErrQuotaExceeded
+func (e *APIError) Is(target error) bool {
+	kind := e.Kind()
+	return kind != nil && kind == target
+}
+
+func (e *APIError) Error() string {
+	prefix := "OCI request failed"
+	if kind := e.Kind(); kind != nil {
+		prefix = kind.Error()
+	}
+	return fmt.Sprintf("%s: %s (%d %s)", prefix, e.Message, e.StatusCode, e.Code)
+}
+
+// DefaultImageImportTimeout defines how long WaitForImage should wait at most
+// for the import of a custom image to finish.
+const DefaultImageImportTimeout = 2 * time.Hour
+
+// Client of the OCI object storage and compute APIs in a region
+type Client struct {
+	creds      Credentials
+	region     string
+	httpClient *http.Client
+
+	objectStorageURL string
+	computeURL       string
+	pollInterval     time.Duration
+}
+
+// NewClient creates a client for the region, or for the region of the
+// credentials if it is empty
+func NewClient(creds Credentials, region string) (*Client, error) {
+	if region == "" {
+		region = creds.region
+	}
+	if region == "" {
+		return nil, errors.New("no OCI region given and the credentials don't set one")
+	}
+
+	// uploads of images can take a long time, so only connecting and
+	// waiting for the responses is limited instead of whole requests
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSHandshakeTimeout = 30 * time.Second
+	transport.ResponseHeaderTimeout = 5 * time.Minute
+
+	return &Client{
+		creds:            creds,
+		region:           region,
+		httpClient:       &http.Client{Transport: transport},
+		objectStorageURL: fmt.Sprintf("https://objectstorage.%s.oraclecloud.com", region),
+		computeURL:       fmt.Sprintf("https://iaas.%s.oraclecloud.com", region),
+		pollInterval:     10 * time.Second,
+	}, nil
+}
+
+// Region returns the region the client creates images in
+func (c *Client) Region() string {
+	return c.region
+}
+
+// Namespace returns the object storage namespace of the tenancy
+func (c *Client) Namespace(ctx context.Context) (string, error) {
+	var namespace string
+	err := c.doJSON(ctx, http.MethodGet, c.objectStorageURL+"/n/", nil, &namespace)
+	if err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+func objectURL(base, namespace, bucket, object string) string {
+	return fmt.Sprintf("%s/n/%s/b/%s/o/%s", base, url.PathEscape(namespace), url.PathEscape(bucket), url.PathEscape(object))
+}
+
+// UploadObject uploads the file to the object in the bucket
+func (c *Client) UploadObject(ctx context.Context, namespace, bucket, object, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("cannot open the image: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot stat the image: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL(c.objectStorageURL, namespace, bucket, object), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	// object storage doesn't require the body of uploads to be signed
+	err = c.sign(req, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// DeleteObject deletes the object from the bucket
+func (c *Client) DeleteObject(ctx context.Context, namespace, bucket, object string) error {
+	return c.doJSON(ctx, http.MethodDelete, objectURL(c.objectStorageURL, namespace, bucket, object), nil, nil)
+}
+
+// ImageOptions are the options of a custom image created from a QCOW2 image
+// in object storage
+type ImageOptions struct {
+	Compartment string
+	DisplayName string
+	Namespace   string
+	Bucket      string
+	Object      string
+	// LaunchMode of the instances created from the image, OCI chooses it
+	// if empty
+	LaunchMode string
+}
+
+type image struct {
+	ID             string `json:"id"`
+	LifecycleState string `json:"lifecycleState"`
+}
+
+// CreateImage starts the import of a custom image and returns its OCID
+func (c *Client) CreateImage(ctx context.Context, options ImageOptions) (string, error) {
+	type imageSourceDetails struct {
+		SourceType      string `json:"sourceType"`
+		NamespaceName   string `json:"namespaceName"`
+		BucketName      string `json:"bucketName"`
+		ObjectName      string `json:"objectName"`
+		SourceImageType string `json:"sourceImageType"`
+	}
+	body := struct {
+		CompartmentID      string             `json:"compartmentId"`
+		DisplayName        string             `json:"displayName,omitempty"`
+		LaunchMode         string             `json:"launchMode,omitempty"`
+		ImageSourceDetails imageSourceDetails `json:"imageSourceDetails"`
+	}{
+		CompartmentID: options.Compartment,
+		DisplayName:   options.DisplayName,
+		LaunchMode:    options.LaunchMode,
+		ImageSourceDetails: imageSourceDetails{
+			SourceType:      "objectStorageTuple",
+			NamespaceName:   options.Namespace,
+			BucketName:      options.Bucket,
+			ObjectName:      options.Object,
+			SourceImageType: "QCOW2",
+		},
+	}
+
+	var img image
+	err := c.doJSON(ctx, http.MethodPost, c.computeURL+"/20160918/images", body, &img)
+	if err != nil {
+		return "", err
+	}
+	return img.ID, nil
+}
+
+// WaitForImage waits until the import of the image finished
+func (c *Client) WaitForImage(ctx context.Context, imageID string) error {
+	for {
+		var img image
+		err := c.doJSON(ctx, http.MethodGet, c.computeURL+"/20160918/images/"+url.PathEscape(imageID), nil, &img)
+		if err != nil {
+			return err
+		}
+
+		switch img.LifecycleState {
+		case "AVAILABLE":
+			return nil
+		case "PROVISIONING", "IMPORTING":
+		default:
+			return fmt.Errorf("import of image %s failed, its state is %s", imageID, img.LifecycleState)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// doJSON sends a request with the JSON encoding of body, if it isn't nil,
+// and decodes the response into result, if it isn't nil
+func (c *Client) doJSON(ctx context.Context, method, u string, body, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	err = c.sign(req, data)
+	if err != nil {
+		return err
+	}
+	return c.do(req, result)
+}
+
+func (c *Client) do(req *http.Request, result interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// sign signs the request with the API signing key of the credentials, as
+// described in https://docs.oracle.com/en-us/iaas/Content/API/Concepts/signingrequests.htm
+// The body is only signed for requests with a JSON body, for which data is
+// not nil.
+func (c *Client) sign(req *http.Request, data []byte) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	headers := []string{"date", "(request-target)", "host"}
+	if data != nil {
+		sum := sha256.Sum256(data)
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		headers = append(headers, "x-content-sha256", "content-type", "content-length")
+	}
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.creds.key, crypto.SHA256, signingDigest(req, headers))
+	if err != nil {
+		return fmt.Errorf("cannot sign the OCI request: %v", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s/%s/%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		c.creds.tenancy, c.creds.user, c.creds.fingerprint,
+		strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// signingDigest returns the SHA-256 digest of the signing string of the
+// headers of the request
+func signingDigest(req *http.Request, headers []string) []byte {
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.URL.Host
+		default:
+			value = req.Header.Get(h)
+		}
+		lines[i] = h + ": " + value
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return sum[:]
+}
@@ -0,0 +1,248 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var authorizationRegexp = regexp.MustCompile(`^Signature version="1",keyId="([^"]*)",algorithm="rsa-sha256",headers="([^"]*)",signature="([^"]*)"$`)
+
+// verifySignature checks the signature of the request the way OCI does
+func verifySignature(t *testing.T, key *rsa.PublicKey, req *http.Request) {
+	m := authorizationRegexp.FindStringSubmatch(req.Header.Get("Authorization"))
+	require.NotNil(t, m, "malformed Authorization header: %s", req.Header.Get("Authorization"))
+	assert.Equal(t, "ocid1.tenancy/ocid1.user/aa:bb", m[1])
+
+	headers := strings.Split(m[2], " ")
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+		default:
+			value = req.Header.Get(h)
+		}
+		lines[i] = h + ": " + value
+	}
+	signingString := strings.Join(lines, "\n")
+	digest := sha256.Sum256([]byte(signingString))
+
+	signature, err := base64.StdEncoding.DecodeString(m[3])
+	require.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature), "invalid signature of: %s", signingString)
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		verifySignature(t, &key.PublicKey, req)
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(Credentials{
+		user:        "ocid1.user",
+		tenancy:     "ocid1.tenancy",
+		fingerprint: "aa:bb",
+		region:      "eu-frankfurt-1",
+		key:         key,
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-frankfurt-1", c.Region())
+
+	c.objectStorageURL = server.URL
+	c.computeURL = server.URL
+	c.pollInterval = 0
+	return c
+}
+
+func TestUploadAndCreateImage(t *testing.T) {
+	imageFile := filepath.Join(t.TempDir(), "disk.qcow2")
+	require.NoError(t, ioutil.WriteFile(imageFile, []byte("qcow2 image"), 0600))
+
+	var uploaded, deleted bool
+	polls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/n/":
+			fmt.Fprint(w, `"ns"`)
+		case req.Method == http.MethodPut && req.URL.Path == "/n/ns/b/bucket/o/object":
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "qcow2 image", string(body))
+			uploaded = true
+		case req.Method == http.MethodPost && req.URL.Path == "/20160918/images":
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+			assert.Equal(t, map[string]interface{}{
+				"compartmentId": "ocid1.compartment",
+				"displayName":   "my-image",
+				"launchMode":    "PARAVIRTUALIZED",
+				"imageSourceDetails": map[string]interface{}{
+					"sourceType":      "objectStorageTuple",
+					"namespaceName":   "ns",
+					"bucketName":      "bucket",
+					"objectName":      "object",
+					"sourceImageType": "QCOW2",
+				},
+			}, body)
+			fmt.Fprint(w, `{"id": "ocid1.image", "lifecycleState": "PROVISIONING"}`)
+		case req.Method == http.MethodGet && req.URL.Path == "/20160918/images/ocid1.image":
+			polls++
+			state := "IMPORTING"
+			if polls == 2 {
+				state = "AVAILABLE"
+			}
+			fmt.Fprintf(w, `{"id": "ocid1.image", "lifecycleState": "%s"}`, state)
+		case req.Method == http.MethodDelete && req.URL.Path == "/n/ns/b/bucket/o/object":
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	namespace, err := c.Namespace(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ns", namespace)
+
+	require.NoError(t, c.UploadObject(ctx, namespace, "bucket", "object", imageFile))
+	assert.True(t, uploaded)
+
+	imageID, err := c.CreateImage(ctx, ImageOptions{
+		Compartment: "ocid1.compartment",
+		DisplayName: "my-image",
+		Namespace:   namespace,
+		Bucket:      "bucket",
+		Object:      "object",
+		LaunchMode:  "PARAVIRTUALIZED",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ocid1.image", imageID)
+
+	require.NoError(t, c.WaitForImage(ctx, imageID))
+	assert.Equal(t, 2, polls)
+
+	require.NoError(t, c.DeleteObject(ctx, namespace, "bucket", "object"))
+	assert.True(t, deleted)
+}
+
+func TestWaitForImageFailed(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"id": "ocid1.image", "lifecycleState": "DELETED"}`)
+	})
+
+	err := c.WaitForImage(context.Background(), "ocid1.image")
+	assert.EqualError(t, err, "import of image ocid1.image failed, its state is DELETED")
+}
+
+func TestWaitForImageTimeout(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `{"id": "ocid1.image", "lifecycleState": "IMPORTING"}`)
+	})
+	c.pollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.WaitForImage(ctx, "ocid1.image")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		code    string
+		kind    error
+		message string
+	}{
+		{http.StatusUnauthorized, "NotAuthenticated", ErrAuthentication, "OCI authentication failed: message (401 NotAuthenticated)"},
+		{http.StatusNotFound, "NotAuthorizedOrNotFound", ErrAuthentication, "OCI authentication failed: message (404 NotAuthorizedOrNotFound)"},
+		{http.StatusBadRequest, "LimitExceeded", ErrQuotaExceeded, "OCI quota exceeded: message (400 LimitExceeded)"},
+		{http.StatusBadRequest, "QuotaExceeded", ErrQuotaExceeded, "OCI quota exceeded: message (400 QuotaExceeded)"},
+		{http.StatusInternalServerError, "InternalError", nil, "OCI request failed: message (500 InternalError)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"code": "%s", "message": "message"}`, tt.code)
+			})
+
+			_, err := c.CreateImage(context.Background(), ImageOptions{})
+			require.Error(t, err)
+			assert.EqualError(t, err, tt.message)
+			assert.Equal(t, tt.kind == ErrAuthentication, errors.Is(err, ErrAuthentication))
+			assert.Equal(t, tt.kind == ErrQuotaExceeded, errors.Is(err, ErrQuotaExceeded))
+
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.kind, apiErr.Kind())
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.code, apiErr.Code)
+		})
+	}
+}
+
+func TestParseOCICredentialsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), 0600))
+
+	credsFile := filepath.Join(dir, "credentials.toml")
+	require.NoError(t, ioutil.WriteFile(credsFile, []byte(fmt.Sprintf(`
+user        = "ocid1.user"
+tenancy     = "ocid1.tenancy"
+fingerprint = "aa:bb"
+key_file    = "%s"
+`, keyFile)), 0600))
+
+	creds, err := ParseOCICredentialsFile(credsFile)
+	require.NoError(t, err)
+	assert.Equal(t, "ocid1.user", creds.user)
+	assert.Equal(t, "ocid1.tenancy", creds.tenancy)
+	assert.Equal(t, "aa:bb", creds.fingerprint)
+	assert.True(t, key.Equal(creds.key))
+
+	// the region must come from the target if the credentials don't set one
+	_, err = NewClient(*creds, "")
+	assert.Error(t, err)
+
+	require.NoError(t, os.Remove(keyFile))
+	_, err = ParseOCICredentialsFile(credsFile)
+	assert.Error(t, err)
+}
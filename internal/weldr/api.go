@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
 	errors_package "errors"
 	"fmt"
@@ -27,9 +28,12 @@ import (
 	"github.com/gobwas/glob"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/container"
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	"github.com/osbuild/osbuild-composer/internal/distroregistry"
 	"github.com/osbuild/osbuild-composer/internal/jobqueue"
@@ -61,8 +65,25 @@ type API struct {
 
 	//  List of ImageType names, which should not be exposed by the API
 	distrosImageTypeDenylist map[string][]string
+
+	// Whether the compose/manifest route builds manifests given by the
+	// user, and whether those may reference files of the host in their
+	// sources
+	customManifests          bool
+	allowManifestFileSources bool
+
+	// Rate limits compose previews, because each of them depsolves the
+	// blueprint
+	previewLimiter *rate.Limiter
 }
 
+// After a burst of composePreviewBurst compose previews, another one is
+// allowed every composePreviewInterval
+const (
+	composePreviewBurst    = 5
+	composePreviewInterval = 6 * time.Second
+)
+
 type ComposeState int
 
 const (
@@ -138,6 +159,7 @@ func NewTestAPI(rpm rpmmd.RPMMD, arch distro.Arch, dr *distroregistry.Registry,
 		distroRegistry:           dr,
 		distros:                  validDistros(rr, dr, arch.Name(), logger),
 		distrosImageTypeDenylist: distrosImageTypeDenylist,
+		previewLimiter:           rate.NewLimiter(rate.Every(composePreviewInterval), composePreviewBurst),
 	}
 	return setupRouter(api)
 }
@@ -186,6 +208,7 @@ func New(repoPaths []string, stateDir string, rpm rpmmd.RPMMD, dr *distroregistr
 		distroRegistry:           dr,
 		distros:                  validDistros(rr, dr, hostArch.Name(), logger),
 		distrosImageTypeDenylist: distrosImageTypeDenylist,
+		previewLimiter:           rate.NewLimiter(rate.Every(composePreviewInterval), composePreviewBurst),
 	}
 	return setupRouter(api), nil
 }
@@ -232,6 +255,8 @@ func setupRouter(api *API) *API {
 	api.router.DELETE("/api/v:version/blueprints/workspace/:blueprint", api.blueprintDeleteWorkspaceHandler)
 
 	api.router.POST("/api/v:version/compose", api.composeHandler)
+	api.router.POST("/api/v:version/compose/manifest", api.composeManifestHandler)
+	api.router.POST("/api/v:version/compose/preview", api.composePreviewHandler)
 	api.router.DELETE("/api/v:version/compose/delete/:uuids", api.composeDeleteHandler)
 	api.router.GET("/api/v:version/compose/types", api.composeTypesHandler)
 	api.router.GET("/api/v:version/compose/queue", api.composeQueueHandler)
@@ -261,6 +286,14 @@ func setupRouter(api *API) *API {
 	return api
 }
 
+// EnableCustomManifests enables the route that builds manifests given by the
+// user, which is disabled by default. Unless allowFileSources is set,
+// manifests whose sources reference files of the host are rejected.
+func (api *API) EnableCustomManifests(allowFileSources bool) {
+	api.customManifests = true
+	api.allowManifestFileSources = allowFileSources
+}
+
 func (api *API) Serve(listener net.Listener) error {
 	server := http.Server{Handler: api}
 
@@ -287,6 +320,12 @@ type composeStatus struct {
 	Started  time.Time
 	Finished time.Time
 	Result   *osbuild.Result
+	// Only set for finished composes built by a worker that records them
+	Artifacts []worker.ArtifactChecksum
+	// Only set for composes with a signed ostree commit
+	OSTreeSigningKeyID string
+	// Only set for failed composes
+	Error *ComposeError
 }
 
 func composeStateFromJobStatus(js *worker.JobStatus, result *worker.OSBuildJobResult) ComposeState {
@@ -312,6 +351,25 @@ func composeStateFromJobStatus(js *worker.JobStatus, result *worker.OSBuildJobRe
 // Returns the state of the image in `compose` and the times the job was
 // queued, started, and finished. Assumes that there's only one image in the
 // compose.
+// archWarnings returns a warning if no worker builds images for arch. There
+// is always a worker for the host architecture.
+func (api *API) archWarnings(arch string) []string {
+	if arch == api.arch.Name() || api.workers.HasWorkersForArch(arch) {
+		return nil
+	}
+	return []string{fmt.Sprintf("no worker is building images for %s, the compose waits until one is available", arch)}
+}
+
+// composeEntry returns the entry of a compose, with the warnings of
+// its architecture if it is still waiting for a worker
+func (api *API) composeEntry(id uuid.UUID, compose store.Compose, status *composeStatus, includeUploads bool) *ComposeEntry {
+	entry := composeToComposeEntry(id, compose, status, includeUploads)
+	if status.State == ComposeWaiting {
+		entry.Warnings = api.archWarnings(compose.ImageBuild.ImageType.Arch().Name())
+	}
+	return entry
+}
+
 func (api *API) getComposeStatus(compose store.Compose) *composeStatus {
 	jobId := compose.ImageBuild.JobID
 
@@ -330,13 +388,17 @@ func (api *API) getComposeStatus(compose store.Compose) *composeStatus {
 		case common.IBFailed:
 			state = ComposeFailed
 		}
-		return &composeStatus{
+		status := &composeStatus{
 			State:    state,
 			Queued:   compose.ImageBuild.JobCreated,
 			Started:  compose.ImageBuild.JobStarted,
 			Finished: compose.ImageBuild.JobFinished,
 			Result:   &osbuild.Result{},
 		}
+		if state == ComposeFailed {
+			status.Error = genericComposeError()
+		}
+		return status
 	}
 
 	// All jobs are "osbuild" jobs.
@@ -347,13 +409,20 @@ func (api *API) getComposeStatus(compose store.Compose) *composeStatus {
 		panic(err)
 	}
 
-	return &composeStatus{
+	status := &composeStatus{
 		State:    composeStateFromJobStatus(jobStatus, &result),
 		Queued:   jobStatus.Queued,
 		Started:  jobStatus.Started,
 		Finished: jobStatus.Finished,
 		Result:   result.OSBuildOutput,
+
+		Artifacts:          result.Artifacts,
+		OSTreeSigningKeyID: result.OSTreeSigningKeyID,
 	}
+	if status.State == ComposeFailed {
+		status.Error = composeErrorFromJobResult(jobStatus.Canceled, &result)
+	}
+	return status
 }
 
 // Opens the image file for `compose`. This asks the worker server for the
@@ -418,7 +487,7 @@ func (api *API) isImageTypeAllowed(distroName, imageType string) (bool, error) {
 // getImageType returns the ImageType for the selected distro
 // This is necessary because different distros support different image types, and the image
 // type may have a different package set than other distros.
-func (api *API) getImageType(distroName, imageType string) (distro.ImageType, error) {
+func (api *API) getImageType(distroName, archName, imageType string) (distro.ImageType, error) {
 	imgAllowed, err := api.isImageTypeAllowed(distroName, imageType)
 	if err != nil {
 		return nil, fmt.Errorf("error while checking if image type is allowed: %v", err)
@@ -431,7 +500,7 @@ func (api *API) getImageType(distroName, imageType string) (distro.ImageType, er
 	if distro == nil {
 		return nil, fmt.Errorf("GetDistro - unknown distribution: %s", distroName)
 	}
-	arch, err := distro.GetArch(api.arch.Name())
+	arch, err := distro.GetArch(archName)
 	if err != nil {
 		return nil, err
 	}
@@ -1432,7 +1501,7 @@ func (api *API) blueprintsInfoHandler(writer http.ResponseWriter, request *http.
 			})
 			continue
 		}
-		blueprints = append(blueprints, *blueprint)
+		blueprints = append(blueprints, blueprint.Redacted())
 		changes = append(changes, change{changed, blueprint.Name})
 	}
 
@@ -1528,7 +1597,7 @@ func (api *API) blueprintsDepsolveHandler(writer http.ResponseWriter, request *h
 			dependencies = []rpmmd.PackageSpec{}
 		}
 
-		blueprints = append(blueprints, entry{*blueprint, dependencies})
+		blueprints = append(blueprints, entry{blueprint.Redacted(), dependencies})
 	}
 
 	err := json.NewEncoder(writer).Encode(reply{
@@ -1606,7 +1675,7 @@ func (api *API) blueprintsFreezeHandler(writer http.ResponseWriter, request *htt
 			break
 		}
 		// Make a copy of the blueprint since we will be replacing the version globs
-		blueprint := bp.DeepCopy()
+		blueprint := bp.Redacted()
 		dependencies, err := api.depsolveBlueprint(blueprint)
 		if err != nil {
 			rerr := responseError{
@@ -1928,10 +1997,28 @@ func (api *API) blueprintsNewHandler(writer http.ResponseWriter, request *http.R
 		statusResponseError(writer, http.StatusBadRequest, errors)
 		return
 	}
+	logBlueprintWarnings(request, &blueprint)
 
 	statusResponseOK(writer)
 }
 
+// logBlueprintWarnings logs problems of a blueprint that don't prevent it
+// from being built, but that are likely mistakes.
+func logBlueprintWarnings(request *http.Request, bp *blueprint.Blueprint) {
+	logger := common.LoggerFromContext(request.Context())
+	for _, warning := range blueprintWarnings(bp) {
+		logger.Warnf("blueprint %s: %s", bp.Name, warning)
+	}
+}
+
+// blueprintWarnings returns the problems of a blueprint that don't prevent
+// it from being built, but that are likely mistakes
+func blueprintWarnings(bp *blueprint.Blueprint) []string {
+	warnings := bp.Customizations.GetFirewall().PortWarnings()
+	warnings = append(warnings, bp.Customizations.GetSSHD().Warnings(bp.Customizations.GetUsers())...)
+	return warnings
+}
+
 func (api *API) blueprintsWorkspaceHandler(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	if !verifyRequestVersion(writer, params, 0) {
 		return
@@ -1988,6 +2075,7 @@ func (api *API) blueprintsWorkspaceHandler(writer http.ResponseWriter, request *
 		statusResponseError(writer, http.StatusBadRequest, errors)
 		return
 	}
+	logBlueprintWarnings(request, &blueprint)
 
 	statusResponseOK(writer)
 }
@@ -2115,13 +2203,18 @@ func (api *API) depsolveBlueprintForImageType(bp blueprint.Blueprint, imageType
 	if err != nil {
 		return nil, err
 	}
+	bpRepos, err := blueprintRepositories(bp)
+	if err != nil {
+		return nil, err
+	}
+	imageTypeRepos = append(imageTypeRepos, bpRepos...)
 	platformID := imageType.Arch().Distro().ModulePlatformID()
 	releasever := imageType.Arch().Distro().Releasever()
 	for name, packageSet := range packageSets {
 		packageSpecs, _, err := api.rpmmd.Depsolve(packageSet,
 			imageTypeRepos,
 			platformID,
-			api.arch.Name(),
+			imageType.Arch().Name(),
 			releasever)
 		if err != nil {
 			return nil, err
@@ -2131,27 +2224,42 @@ func (api *API) depsolveBlueprintForImageType(bp blueprint.Blueprint, imageType
 	return packageSpecSets, nil
 }
 
-// Schedule new compose by first translating the appropriate blueprint into a pipeline and then
-// pushing it into the channel for waiting builds.
-func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	if !verifyRequestVersion(writer, params, 0) {
-		return
-	}
-
-	// https://weldr.io/lorax/pylorax.api.html#pylorax.api.v0.v0_compose_start
-	type ComposeRequest struct {
-		BlueprintName string               `json:"blueprint_name"`
-		ComposeType   string               `json:"compose_type"`
-		Size          uint64               `json:"size"`
-		OSTree        ostree.OSTreeRequest `json:"ostree"`
-		Branch        string               `json:"branch"`
-		Upload        *uploadRequest       `json:"upload"`
-	}
-	type ComposeReply struct {
-		BuildID uuid.UUID `json:"build_id"`
-		Status  bool      `json:"status"`
-	}
-
+// https://weldr.io/lorax/pylorax.api.html#pylorax.api.v0.v0_compose_start
+type composeRequest struct {
+	BlueprintName string               `json:"blueprint_name"`
+	ComposeType   string               `json:"compose_type"`
+	Size          uint64               `json:"size"`
+	OSTree        ostree.OSTreeRequest `json:"ostree"`
+	Branch        string               `json:"branch"`
+	Upload        *uploadRequest       `json:"upload"`
+	// Architecture to build the image for, the host's by default
+	Arch string `json:"arch"`
+	// Reproducible composes take the manifest seed and the source
+	// date epoch from the request, or record the generated ones so
+	// that the compose can be rebuilt later.
+	Reproducible    bool   `json:"reproducible"`
+	Seed            *int64 `json:"seed"`
+	SourceDateEpoch *int64 `json:"source_date_epoch"`
+	// ComposeID is recorded in the image, e.g. in the .discinfo of
+	// installer ISOs, instead of the one derived from the build date
+	ComposeID string `json:"compose_id"`
+}
+
+// composeManifest is the result of depsolving and generating the manifest
+// of a compose request
+type composeManifest struct {
+	blueprint       *blueprint.Blueprint
+	imageType       distro.ImageType
+	manifest        distro.Manifest
+	packageSets     map[string][]rpmmd.PackageSpec
+	size            uint64
+	seed            int64
+	sourceDateEpoch *time.Time
+}
+
+// decodeComposeRequest reads a compose request from the body of the request
+// it writes the error to the writer and returns false if it fails
+func decodeComposeRequest(writer http.ResponseWriter, request *http.Request, cr *composeRequest) bool {
 	contentType := request.Header["Content-Type"]
 	if len(contentType) != 1 || contentType[0] != "application/json" {
 		errors := responseError{
@@ -2159,11 +2267,10 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg: "blueprint must be json",
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return false
 	}
 
-	var cr ComposeRequest
-	err := json.NewDecoder(request.Body).Decode(&cr)
+	err := json.NewDecoder(request.Body).Decode(cr)
 	if err != nil {
 		errors := responseError{
 			Code: http.StatusNotFound,
@@ -2171,11 +2278,18 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg:  "Not Found",
 		}
 		statusResponseError(writer, http.StatusNotFound, errors)
-		return
+		return false
 	}
 
+	return true
+}
+
+// makeComposeManifest depsolves the blueprint of the compose request and
+// generates the manifest of the compose, without queueing anything
+// it writes the error to the writer and returns nil if any step fails
+func (api *API) makeComposeManifest(writer http.ResponseWriter, request *http.Request, cr *composeRequest, testMode string) *composeManifest {
 	if !verifyStringsWithRegex(writer, []string{cr.BlueprintName}, ValidBlueprintName) {
-		return
+		return nil
 	}
 
 	bp := api.store.GetBlueprintCommitted(cr.BlueprintName)
@@ -2185,31 +2299,45 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg: fmt.Sprintf("Unknown blueprint name: %s", cr.BlueprintName),
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return nil
 	}
 
 	distroName := bp.Distro
 	if distroName == "" {
 		distroName = api.hostDistroName
 	}
-	if api.getDistro(distroName) == nil {
+	d := api.getDistro(distroName)
+	if d == nil {
 		errors := responseError{
 			ID:  "DistroError",
 			Msg: fmt.Sprintf("Unknown distribution: %s", distroName),
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return nil
+	}
+
+	archName := cr.Arch
+	if archName == "" {
+		archName = api.arch.Name()
+	}
+	if _, err := d.GetArch(archName); err != nil {
+		errors := responseError{
+			ID:  "DistroError",
+			Msg: fmt.Sprintf("Unknown arch: %s", archName),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
 	}
 
 	// Get the imageType that corresponds to the distribution selected by the blueprint
-	imageType, err := api.getImageType(distroName, cr.ComposeType)
+	imageType, err := api.getImageType(distroName, archName, cr.ComposeType)
 	if err != nil {
 		errors := responseError{
 			ID:  "ComposeError",
 			Msg: fmt.Sprintf("Failed to get compose type %q: %v", cr.ComposeType, err),
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return nil
 	}
 
 	// set default ostree ref, if one not provided
@@ -2221,28 +2349,58 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg: "Invalid ostree ref",
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return nil
 	}
 
-	composeID := uuid.New()
+	if cr.OSTree.GPGKeyID != "" || cr.OSTree.GPGKey != "" {
+		var msg string
+		switch {
+		case imageType.Filename() != ostreeCommitArchive:
+			msg = fmt.Sprintf("compose type %q doesn't produce an ostree commit archive, which can be signed", imageType.Name())
+		case cr.OSTree.GPGKeyID != "" && cr.OSTree.GPGKey != "":
+			msg = "Supply at most one of gpg_keyid and gpg_key"
+		case cr.OSTree.GPGKeyID != "" && !ostree.VerifyGPGKeyID(cr.OSTree.GPGKeyID):
+			msg = fmt.Sprintf("invalid GPG key ID %q", cr.OSTree.GPGKeyID)
+		case cr.OSTree.GPGKey != "" && !ostree.IsGPGSecretKey(cr.OSTree.GPGKey):
+			// never echo the key
+			msg = "gpg_key must be an ASCII armored GPG secret key"
+		}
+		if msg != "" {
+			errors := responseError{
+				ID:  "OSTreeOptionsError",
+				Msg: msg,
+			}
+			statusResponseError(writer, http.StatusBadRequest, errors)
+			return nil
+		}
+	}
 
-	var targets []*target.Target
-	if isRequestVersionAtLeast(params, 1) && cr.Upload != nil {
-		t := uploadRequestToTarget(*cr.Upload, imageType)
-		targets = append(targets, t)
+	if mtls := cr.OSTree.MTLS(); mtls != nil {
+		var msg string
+		if cr.OSTree.URL == "" {
+			msg = "the TLS options require the url of the repository"
+		} else if err := mtls.Validate(); err != nil {
+			msg = err.Error()
+		}
+		if msg != "" {
+			errors := responseError{
+				ID:  "OSTreeOptionsError",
+				Msg: msg,
+			}
+			statusResponseError(writer, http.StatusBadRequest, errors)
+			return nil
+		}
 	}
 
-	// Check for test parameter
-	q, err := url.ParseQuery(request.URL.RawQuery)
-	if err != nil {
+	// the delta is generated from the parent commit pulled from url
+	if cr.OSTree.StaticDeltas && cr.OSTree.URL == "" {
 		errors := responseError{
-			ID:  "InvalidChars",
-			Msg: fmt.Sprintf("invalid query string: %v", err),
+			ID:  "OSTreeOptionsError",
+			Msg: "static_deltas require a parent commit, supply the url to retrieve it from",
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
-		return
+		return nil
 	}
-	testMode := q.Get("test")
 
 	// Fetch parent ostree commit from ref + url if commit is not
 	// provided. The parameter name "parent" is perhaps slightly misleading
@@ -2255,7 +2413,7 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 				Msg: "Supply at most one of Parent and URL",
 			}
 			statusResponseError(writer, http.StatusBadRequest, errors)
-			return
+			return nil
 		}
 		var parent string
 		if testMode == "1" || testMode == "2" {
@@ -2263,19 +2421,41 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			parent = "02604b2da6e954bd34b8b82a835e5a77d2b60ffa"
 		} else {
 			// Resolve the URL and get the parent commit
-			parent, err = ostree.ResolveRef(cr.OSTree.URL, cr.OSTree.Ref)
+			parent, err = ostree.ResolveRef(cr.OSTree.URL, cr.OSTree.Ref, cr.OSTree.MTLS())
 			if err != nil {
 				errors := responseError{
 					ID:  "OSTreeCommitError",
 					Msg: err.Error(),
 				}
 				statusResponseError(writer, http.StatusBadRequest, errors)
-				return
+				return nil
 			}
 		}
 		cr.OSTree.Parent = parent
 	}
 
+	// Pin the container images of the blueprint to the digests they
+	// currently resolve to, so the manifest always embeds the same images
+	containers, err := resolveContainers(*bp, testMode == "1" || testMode == "2")
+	if err != nil {
+		errors := responseError{
+			ID:  "ContainerResolutionError",
+			Msg: err.Error(),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
+	}
+
+	bpRepos, err := blueprintRepositories(*bp)
+	if err != nil {
+		errors := responseError{
+			ID:  "BlueprintsError",
+			Msg: err.Error(),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
+	}
+
 	packageSets, err := api.depsolveBlueprintForImageType(*bp, imageType)
 	if err != nil {
 		errors := responseError{
@@ -2283,25 +2463,49 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg: err.Error(),
 		}
 		statusResponseError(writer, http.StatusInternalServerError, errors)
-		return
+		return nil
 	}
 
-	var size uint64
+	// images are grown to hold their partitions unless the compose
+	// request sets the size
+	size := imageType.Size(cr.Size)
 
-	// check if filesytem customizations have been set.
-	// if compose size parameter is set, take the larger of
-	// the two values
-	if minSize := bp.Customizations.GetFilesystemsMinSize(); bp.Customizations != nil && minSize > 0 && minSize > cr.Size {
-		size = imageType.Size(minSize)
+	if !cr.Reproducible && (cr.Seed != nil || cr.SourceDateEpoch != nil) {
+		errors := responseError{
+			ID:  "ReproducibleOptionsError",
+			Msg: "seed and source_date_epoch require a reproducible compose",
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
+	}
+	if cr.SourceDateEpoch != nil && *cr.SourceDateEpoch < 0 {
+		errors := responseError{
+			ID:  "ReproducibleOptionsError",
+			Msg: "source_date_epoch must not be negative",
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
+	}
+
+	var seed int64
+	if cr.Seed != nil {
+		seed = *cr.Seed
 	} else {
-		size = imageType.Size(cr.Size)
+		bigSeed, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+		if err != nil {
+			panic("cannot generate a manifest seed: " + err.Error())
+		}
+		seed = bigSeed.Int64()
 	}
 
-	bigSeed, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
-	if err != nil {
-		panic("cannot generate a manifest seed: " + err.Error())
+	var sourceDateEpoch *time.Time
+	if cr.Reproducible {
+		epoch := time.Now().UTC().Truncate(time.Second)
+		if cr.SourceDateEpoch != nil {
+			epoch = time.Unix(*cr.SourceDateEpoch, 0).UTC()
+		}
+		sourceDateEpoch = &epoch
 	}
-	seed := bigSeed.Int64()
 
 	imageRepos, err := api.allRepositoriesByImageType(imageType)
 	// this should not happen if the api.depsolveBlueprintForImageType() call above worked
@@ -2311,55 +2515,160 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 			Msg: err.Error(),
 		}
 		statusResponseError(writer, http.StatusInternalServerError, errors)
-		return
+		return nil
 	}
+	imageRepos = append(imageRepos, bpRepos...)
 
 	manifest, err := imageType.Manifest(bp.Customizations,
 		distro.ImageOptions{
-			Size: size,
+			Size:            size,
+			FixedSize:       cr.Size > 0,
+			BuildDate:       time.Now(),
+			SourceDateEpoch: sourceDateEpoch,
+			ComposeID:       cr.ComposeID,
+			Containers:      containers,
 			OSTree: distro.OSTreeImageOptions{
-				Ref:    cr.OSTree.Ref,
-				Parent: cr.OSTree.Parent,
-				URL:    cr.OSTree.URL,
+				Ref:          cr.OSTree.Ref,
+				Parent:       cr.OSTree.Parent,
+				URL:          cr.OSTree.URL,
+				StaticDeltas: cr.OSTree.StaticDeltas,
+				MTLS:         cr.OSTree.MTLS() != nil,
 			},
 		},
 		imageRepos,
 		packageSets,
 		seed)
 	if err != nil {
+		common.LoggerFromContext(request.Context()).WithField(common.LogFieldImageType, imageType.Name()).Warnf("failed to create osbuild manifest: %v", err)
 		errors := responseError{
 			ID:  "ManifestCreationFailed",
 			Msg: fmt.Sprintf("failed to create osbuild manifest: %v", err),
 		}
 		statusResponseError(writer, http.StatusBadRequest, errors)
+		return nil
+	}
+
+	return &composeManifest{
+		blueprint:       bp,
+		imageType:       imageType,
+		manifest:        manifest,
+		packageSets:     packageSets,
+		size:            size,
+		seed:            seed,
+		sourceDateEpoch: sourceDateEpoch,
+	}
+}
+
+// Schedule new compose by first translating the appropriate blueprint into a pipeline and then
+// pushing it into the channel for waiting builds.
+func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	if !verifyRequestVersion(writer, params, 0) {
+		return
+	}
+
+	type ComposeReply struct {
+		BuildID  uuid.UUID `json:"build_id"`
+		Status   bool      `json:"status"`
+		Warnings []string  `json:"warnings,omitempty"`
+	}
+
+	var cr composeRequest
+	if !decodeComposeRequest(writer, request, &cr) {
+		return
+	}
+
+	// Check for test parameter
+	q, err := url.ParseQuery(request.URL.RawQuery)
+	if err != nil {
+		errors := responseError{
+			ID:  "InvalidChars",
+			Msg: fmt.Sprintf("invalid query string: %v", err),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
 		return
 	}
+	testMode := q.Get("test")
+
+	c := api.makeComposeManifest(writer, request, &cr, testMode)
+	if c == nil {
+		return
+	}
+	bp, imageType, manifest, packageSets, size := c.blueprint, c.imageType, c.manifest, c.packageSets, c.size
+	seed, sourceDateEpoch := c.seed, c.sourceDateEpoch
+
+	composeID := uuid.New()
+	logger := common.LoggerFromContext(request.Context()).WithFields(logrus.Fields{
+		common.LogFieldComposeID: composeID.String(),
+		common.LogFieldImageType: imageType.Name(),
+	})
+
+	var targets []*target.Target
+	if isRequestVersionAtLeast(params, 1) && cr.Upload != nil {
+		t, err := uploadRequestToTarget(*cr.Upload, imageType)
+		if err != nil {
+			errors := responseError{
+				ID:  "UploadError",
+				Msg: err.Error(),
+			}
+			statusResponseError(writer, http.StatusBadRequest, errors)
+			return
+		}
+		targets = append(targets, t)
+	}
+
+	// Composes for architectures without workers are queued anyway,
+	// because workers may join later, but they wait until one does.
+	warnings := api.archWarnings(imageType.Arch().Name())
+	for _, warning := range warnings {
+		logger.Warn(warning)
+	}
+	for _, warning := range bp.Customizations.GetSSHD().Warnings(bp.Customizations.GetUsers()) {
+		logger.Warnf("blueprint %s: %s", bp.Name, warning)
+		warnings = append(warnings, warning)
+	}
+
+	// the secrets of the blueprint are only needed by the worker
+	composeBlueprint := bp.Redacted()
+
+	// recorded with the compose, so that it can be rebuilt
+	var reproducible *store.ReproducibleBuild
+	if sourceDateEpoch != nil {
+		reproducible = &store.ReproducibleBuild{
+			Seed:            seed,
+			SourceDateEpoch: sourceDateEpoch.Unix(),
+		}
+	}
 
 	if testMode == "1" {
 		// Create a failed compose
-		err = api.store.PushTestCompose(composeID, manifest, imageType, bp, size, targets, false, packageSets["packages"])
+		err = api.store.PushTestCompose(composeID, manifest, imageType, &composeBlueprint, size, targets, false, packageSets["packages"])
 	} else if testMode == "2" {
 		// Create a successful compose
-		err = api.store.PushTestCompose(composeID, manifest, imageType, bp, size, targets, true, packageSets["packages"])
+		err = api.store.PushTestCompose(composeID, manifest, imageType, &composeBlueprint, size, targets, true, packageSets["packages"])
 	} else {
 		var jobId uuid.UUID
 
-		jobId, err = api.workers.EnqueueOSBuild(api.arch.Name(), &worker.OSBuildJob{
+		logger = logger.WithField(common.LogFieldJobType, "osbuild")
+		jobId, err = api.workers.EnqueueOSBuild(imageType.Arch().Name(), &worker.OSBuildJob{
 			Manifest:        manifest,
 			Targets:         targets,
 			ImageName:       imageType.Filename(),
 			StreamOptimized: imageType.Name() == "vmdk", // https://github.com/osbuild/osbuild/issues/528
 			Exports:         imageType.Exports(),
+			ImageType:       imageType.Name(),
+			OSTreeSigning:   ostreeSigning(cr.OSTree),
+			OSTreeTLSSecret: cr.OSTree.TLSSecret,
 		})
 		if err == nil {
-			err = api.store.PushCompose(composeID, manifest, imageType, bp, size, targets, jobId, packageSets["packages"])
+			logger = logger.WithField(common.LogFieldJobID, jobId.String())
+			err = api.store.PushCompose(composeID, manifest, imageType, &composeBlueprint, size, targets, jobId, packageSets["packages"], reproducible)
 		}
 	}
 
 	// TODO: we should probably do some kind of blueprint validation in future
 	// for now, let's just 500 and bail out
 	if err != nil {
-		log.Println("error when pushing new compose: ", err.Error())
+		logger.Errorf("error when pushing new compose: %v", err)
 		errors := responseError{
 			ID:  "ComposePushErrored",
 			Msg: err.Error(),
@@ -2368,7 +2677,166 @@ func (api *API) composeHandler(writer http.ResponseWriter, request *http.Request
 		return
 	}
 
+	logger.Infof("Compose %s queued", composeID)
+
 	err = json.NewEncoder(writer).Encode(ComposeReply{
+		BuildID:  composeID,
+		Status:   true,
+		Warnings: warnings,
+	})
+	common.PanicOnError(err)
+}
+
+// composePreviewHandler depsolves the blueprint of a compose request and
+// returns the manifest of the compose without queueing it. The sources of the
+// manifest are summarized, because they list every package.
+func (api *API) composePreviewHandler(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	if !verifyRequestVersion(writer, params, 0) {
+		return
+	}
+
+	type composePreviewReply struct {
+		Manifest distro.Manifest `json:"manifest"`
+		Status   bool            `json:"status"`
+	}
+
+	var cr composeRequest
+	if !decodeComposeRequest(writer, request, &cr) {
+		return
+	}
+
+	if !api.previewLimiter.Allow() {
+		errors := responseError{
+			ID:  "TooManyRequests",
+			Msg: "too many compose previews were requested, try again later",
+		}
+		statusResponseError(writer, http.StatusTooManyRequests, errors)
+		return
+	}
+
+	c := api.makeComposeManifest(writer, request, &cr, "")
+	if c == nil {
+		return
+	}
+
+	manifest, err := c.manifest.SummarizeSources()
+	if err != nil {
+		errors := responseError{
+			ID:  "InternalError",
+			Msg: err.Error(),
+		}
+		statusResponseError(writer, http.StatusInternalServerError, errors)
+		return
+	}
+
+	err = json.NewEncoder(writer).Encode(composePreviewReply{
+		Manifest: manifest,
+		Status:   true,
+	})
+	common.PanicOnError(err)
+}
+
+// composeManifestHandler queues a compose that builds a manifest given by the
+// user, without depsolving or generating a manifest. The compose has the
+// custom image type and its artifact is the file produced by the last
+// pipeline of the manifest.
+func (api *API) composeManifestHandler(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	if !api.customManifests {
+		notFoundHandler(writer, request)
+		return
+	}
+	if !verifyRequestVersion(writer, params, 0) {
+		return
+	}
+
+	type composeManifestRequest struct {
+		Manifest distro.Manifest `json:"manifest"`
+		Upload   *uploadRequest  `json:"upload"`
+	}
+	type composeManifestReply struct {
+		BuildID uuid.UUID `json:"build_id"`
+		Status  bool      `json:"status"`
+	}
+
+	contentType := request.Header["Content-Type"]
+	if len(contentType) != 1 || contentType[0] != "application/json" {
+		errors := responseError{
+			ID:  "MissingPost",
+			Msg: "manifest must be json",
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return
+	}
+
+	var cr composeManifestRequest
+	err := json.NewDecoder(request.Body).Decode(&cr)
+	if err != nil {
+		errors := responseError{
+			ID:  "InvalidManifest",
+			Msg: fmt.Sprintf("invalid compose request: %v", err),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return
+	}
+
+	imageType, err := distro.NewCustomImageType(api.arch, cr.Manifest)
+	if err == nil && !api.allowManifestFileSources {
+		err = distro.CheckManifestSources(cr.Manifest)
+	}
+	if err != nil {
+		errors := responseError{
+			ID:  "InvalidManifest",
+			Msg: err.Error(),
+		}
+		statusResponseError(writer, http.StatusBadRequest, errors)
+		return
+	}
+
+	composeID := uuid.New()
+	logger := common.LoggerFromContext(request.Context()).WithFields(logrus.Fields{
+		common.LogFieldComposeID: composeID.String(),
+		common.LogFieldImageType: imageType.Name(),
+		common.LogFieldJobType:   "osbuild",
+	})
+
+	var targets []*target.Target
+	if isRequestVersionAtLeast(params, 1) && cr.Upload != nil {
+		t, err := uploadRequestToTarget(*cr.Upload, imageType)
+		if err != nil {
+			errors := responseError{
+				ID:  "UploadError",
+				Msg: err.Error(),
+			}
+			statusResponseError(writer, http.StatusBadRequest, errors)
+			return
+		}
+		targets = append(targets, t)
+	}
+
+	jobId, err := api.workers.EnqueueOSBuild(api.arch.Name(), &worker.OSBuildJob{
+		Manifest:  cr.Manifest,
+		Targets:   targets,
+		ImageName: imageType.Filename(),
+		Exports:   imageType.Exports(),
+		ImageType: imageType.Name(),
+	})
+	if err == nil {
+		logger = logger.WithField(common.LogFieldJobID, jobId.String())
+		err = api.store.PushCompose(composeID, cr.Manifest, imageType, &blueprint.Blueprint{}, 0, targets, jobId, nil, nil)
+	}
+	if err != nil {
+		logger.Errorf("error when pushing new compose: %v", err)
+		errors := responseError{
+			ID:  "ComposePushErrored",
+			Msg: err.Error(),
+		}
+		statusResponseError(writer, http.StatusInternalServerError, errors)
+		return
+	}
+
+	logger.Infof("Compose %s of a custom manifest queued", composeID)
+
+	err = json.NewEncoder(writer).Encode(composeManifestReply{
 		BuildID: composeID,
 		Status:  true,
 	})
@@ -2586,7 +3054,7 @@ func (api *API) composeQueueHandler(writer http.ResponseWriter, request *http.Re
 		composeStatus := api.getComposeStatus(compose)
 		switch composeStatus.State {
 		case ComposeWaiting:
-			reply.New = append(reply.New, composeToComposeEntry(id, compose, composeStatus, includeUploads))
+			reply.New = append(reply.New, api.composeEntry(id, compose, composeStatus, includeUploads))
 		case ComposeRunning:
 			reply.Run = append(reply.Run, composeToComposeEntry(id, compose, composeStatus, includeUploads))
 		}
@@ -2670,7 +3138,7 @@ func (api *API) composeStatusHandler(writer http.ResponseWriter, request *http.R
 	for _, id := range filteredUUIDs {
 		if compose, exists := composes[id]; exists {
 			composeStatus := api.getComposeStatus(compose)
-			reply.UUIDs = append(reply.UUIDs, composeToComposeEntry(id, compose, composeStatus, includeUploads))
+			reply.UUIDs = append(reply.UUIDs, api.composeEntry(id, compose, composeStatus, includeUploads))
 		}
 	}
 	sortComposeEntries(reply.UUIDs)
@@ -2718,16 +3186,29 @@ func (api *API) composeInfoHandler(writer http.ResponseWriter, request *http.Req
 		QueueStatus string           `json:"queue_status"`
 		ImageSize   uint64           `json:"image_size"`
 		Uploads     []uploadResponse `json:"uploads,omitempty"`
+
+		Artifacts    []worker.ArtifactChecksum `json:"artifacts,omitempty"`
+		Reproducible *reproducibleResponse     `json:"reproducible,omitempty"`
+		Error        *ComposeError             `json:"error,omitempty"`
+		// ID of the GPG key the ostree commit was signed with
+		OSTreeSigningKeyID string `json:"ostree_signing_key_id,omitempty"`
 	}
 
 	reply.ID = id
-	reply.Blueprint = compose.Blueprint
+	if compose.Blueprint != nil {
+		bp := compose.Blueprint.Redacted()
+		reply.Blueprint = &bp
+	}
 	// Weldr API assumes only one image build per compose, that's why only the
 	// 1st build is considered
 	composeStatus := api.getComposeStatus(compose)
 	reply.ComposeType = compose.ImageBuild.ImageType.Name()
 	reply.QueueStatus = composeStatus.State.ToString()
 	reply.ImageSize = compose.ImageBuild.Size
+	reply.Reproducible = reproducibleToResponse(compose.ImageBuild.Reproducible)
+	reply.Artifacts = composeStatus.Artifacts
+	reply.Error = composeStatus.Error
+	reply.OSTreeSigningKeyID = composeStatus.OSTreeSigningKeyID
 
 	if isRequestVersionAtLeast(params, 1) {
 		reply.Uploads = targetsToUploadResponses(compose.ImageBuild.Targets, composeStatus.State)
@@ -2797,6 +3278,9 @@ func (api *API) composeImageHandler(writer http.ResponseWriter, request *http.Re
 	writer.Header().Set("Content-Disposition", "attachment; filename="+uuid.String()+"-"+imageName)
 	writer.Header().Set("Content-Type", imageMime)
 	writer.Header().Set("Content-Length", fmt.Sprintf("%d", fileSize))
+	if artifact := worker.FindArtifact(composeStatus.Artifacts, imageName); artifact != nil {
+		writer.Header().Set("X-Checksum-Sha256", artifact.Sha256)
+	}
 
 	_, err = io.Copy(writer, reader)
 	common.PanicOnError(err)
@@ -2859,6 +3343,24 @@ func (api *API) composeMetadataHandler(writer http.ResponseWriter, request *http
 	_, err = tw.Write(metadata)
 	common.PanicOnError(err)
 
+	// The checksums of the artifacts, in the format of sha256sum
+	if len(composeStatus.Artifacts) > 0 {
+		var checksums bytes.Buffer
+		for _, artifact := range composeStatus.Artifacts {
+			fmt.Fprintf(&checksums, "%s  %s\n", artifact.Sha256, artifact.Name)
+		}
+		hdr = &tar.Header{
+			Name:    "CHECKSUMS.sha256",
+			Mode:    0600,
+			Size:    int64(checksums.Len()),
+			ModTime: time.Now().Truncate(time.Second),
+		}
+		err = tw.WriteHeader(hdr)
+		common.PanicOnError(err)
+		_, err = tw.Write(checksums.Bytes())
+		common.PanicOnError(err)
+	}
+
 	err = tw.Close()
 	common.PanicOnError(err)
 }
@@ -3143,6 +3645,126 @@ func (api *API) allRepositoriesByImageType(imageType distro.ImageType) ([]rpmmd.
 	return repos, nil
 }
 
+// blueprintRepositories returns the repositories of the customizations of
+// the blueprint as rpmmd.RepoConfig. The packages of the image are checked
+// with the GPG keys of the repositories, so the keys given by their URLs
+// are fetched and passed on inline.
+func blueprintRepositories(bp blueprint.Blueprint) ([]rpmmd.RepoConfig, error) {
+	var repos []rpmmd.RepoConfig
+	for _, repo := range bp.Customizations.GetRepositories() {
+		var keys []string
+		for _, key := range repo.GPGKeys {
+			if !blueprint.IsInlineGPGKey(key) {
+				var err error
+				key, err = fetchGPGKey(key)
+				if err != nil {
+					return nil, fmt.Errorf("cannot fetch GPG key of repository %q: %v", repo.ID, err)
+				}
+			}
+			keys = append(keys, key)
+		}
+		repos = append(repos, rpmmd.RepoConfig{
+			Name:       repo.ID,
+			BaseURL:    repo.BaseURL,
+			Metalink:   repo.Metalink,
+			MirrorList: repo.Mirrorlist,
+			GPGKey:     strings.Join(keys, "\n"),
+			CheckGPG:   repo.GPGCheck,
+		})
+	}
+	return repos, nil
+}
+
+// maxGPGKeySize limits the size of the GPG keys fetched from URLs
+const maxGPGKeySize = 1024 * 1024
+
+// fetchGPGKey returns the ASCII armored GPG key of the http, https or file
+// URL keyURL
+func fetchGPGKey(keyURL string) (string, error) {
+	u, err := url.Parse(keyURL)
+	if err != nil {
+		return "", err
+	}
+
+	var reader io.Reader
+	switch u.Scheme {
+	case "file":
+		f, err := os.Open(u.Path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		reader = f
+	case "http", "https":
+		client := http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(keyURL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("%s returned %s", keyURL, resp.Status)
+		}
+		reader = resp.Body
+	default:
+		return "", fmt.Errorf("unsupported URL %q", keyURL)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxGPGKeySize))
+	if err != nil {
+		return "", err
+	}
+	if !blueprint.IsInlineGPGKey(string(data)) {
+		return "", fmt.Errorf("%s is not an ASCII armored GPG key", keyURL)
+	}
+	return string(data), nil
+}
+
+// ostreeCommitArchive is the filename of the images of the image types
+// that produce a tar archive of an ostree commit
+const ostreeCommitArchive = "commit.tar"
+
+// ostreeSigning returns the key the ostree commit of the request is signed
+// with, or nil if it isn't signed.
+func ostreeSigning(r ostree.OSTreeRequest) *worker.OSTreeSigning {
+	if r.GPGKeyID == "" && r.GPGKey == "" {
+		return nil
+	}
+	return &worker.OSTreeSigning{
+		KeyID: r.GPGKeyID,
+		Key:   r.GPGKey,
+	}
+}
+
+// resolveContainers pins the container images of the blueprint to the
+// digests of their manifests. With fake set, the digests are derived from
+// the sources instead of querying the registries, for test requests.
+func resolveContainers(bp blueprint.Blueprint, fake bool) ([]container.Spec, error) {
+	var specs []container.Spec
+	for _, c := range bp.Containers {
+		ref, err := container.ParseReference(c.Source)
+		if err != nil {
+			return nil, err
+		}
+		digest := ref.Digest
+		if digest == "" && fake {
+			digest = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(c.Source)))
+		} else if digest == "" {
+			digest, err = container.Resolve(c.Source, c.GetTLSVerify())
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve container %q: %v", c.Source, err)
+			}
+		}
+		specs = append(specs, container.Spec{
+			Source:    ref.Name(),
+			Digest:    digest,
+			LocalName: c.LocalName(),
+			TLSVerify: c.TLSVerify,
+		})
+	}
+	return specs, nil
+}
+
 // Returns all configured repositories (base + sources) as rpmmd.RepoConfig
 func (api *API) allRepositories(distroName string) ([]rpmmd.RepoConfig, error) {
 	archRepos, err := api.repoRegistry.ReposByArchName(distroName, api.arch.Name(), false)
@@ -3172,6 +3794,11 @@ func (api *API) depsolveBlueprint(bp blueprint.Blueprint) ([]rpmmd.PackageSpec,
 	if err != nil {
 		return nil, err
 	}
+	bpRepos, err := blueprintRepositories(bp)
+	if err != nil {
+		return nil, err
+	}
+	repos = append(repos, bpRepos...)
 
 	packages, _, err := api.rpmmd.Depsolve(rpmmd.PackageSet{Include: bp.GetPackages()}, repos, d.ModulePlatformID(), api.arch.Name(), d.Releasever())
 	if err != nil {
@@ -3,7 +3,10 @@ package weldr
 import (
 	"archive/tar"
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,24 +14,31 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/osbuild/osbuild-composer/internal/blueprint"
 	"github.com/osbuild/osbuild-composer/internal/common"
+	"github.com/osbuild/osbuild-composer/internal/container"
 	"github.com/osbuild/osbuild-composer/internal/distro"
 	"github.com/osbuild/osbuild-composer/internal/distro/test_distro"
 	"github.com/osbuild/osbuild-composer/internal/distroregistry"
+	"github.com/osbuild/osbuild-composer/internal/jobqueue/fsjobqueue"
 	rpmmd_mock "github.com/osbuild/osbuild-composer/internal/mocks/rpmmd"
+	osbuild "github.com/osbuild/osbuild-composer/internal/osbuild1"
 	"github.com/osbuild/osbuild-composer/internal/reporegistry"
 	"github.com/osbuild/osbuild-composer/internal/rpmmd"
 	"github.com/osbuild/osbuild-composer/internal/store"
 	"github.com/osbuild/osbuild-composer/internal/target"
 	"github.com/osbuild/osbuild-composer/internal/test"
+	"github.com/osbuild/osbuild-composer/internal/worker"
 
 	"github.com/BurntSushi/toml"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -382,6 +392,29 @@ func TestBlueprintsInfoToml(t *testing.T) {
 	require.Equalf(t, expected, got, "received unexpected blueprint")
 }
 
+func TestBlueprintsInfoRedactsPassphrase(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	api, _ := createWeldrAPI(tempdir, rpmmd_mock.BaseFixture)
+	test.SendHTTP(api, true, "POST", "/api/v0/blueprints/new", `{"name":"test1","description":"Test","version":"0.0.0","customizations":{"disk":{"encryption":{"passphrase":"hunter2"}}}}`)
+
+	for _, path := range []string{"/api/v0/blueprints/info/test1", "/api/v0/blueprints/info/test1?format=toml", "/api/v0/blueprints/depsolve/test1"} {
+		resp := test.SendHTTP(api, true, "GET", path, ``)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		require.NotContains(t, string(body), "hunter2", path)
+		require.NotContains(t, string(body), "passphrase", path)
+	}
+
+	// the stored blueprint keeps the passphrase for building images
+	bp, _ := api.store.GetBlueprint("test1")
+	require.NotNil(t, bp)
+	require.Equal(t, "hunter2", bp.Customizations.GetDiskEncryption().Passphrase)
+}
+
 func TestNonExistentBlueprintsInfoToml(t *testing.T) {
 	tempdir, err := ioutil.TempDir("", "weldr-tests-")
 	require.NoError(t, err)
@@ -726,6 +759,27 @@ func TestCompose(t *testing.T) {
 		Packages: []rpmmd.PackageSpec{},
 	}
 
+	expectedComposeReproducible := &store.Compose{
+		Blueprint: &blueprint.Blueprint{
+			Name:           "test",
+			Version:        "0.0.0",
+			Packages:       []blueprint.Package{},
+			Modules:        []blueprint.Package{},
+			Groups:         []blueprint.Group{},
+			Customizations: nil,
+		},
+		ImageBuild: store.ImageBuild{
+			QueueStatus: common.IBWaiting,
+			ImageType:   imgType,
+			Manifest:    manifest,
+			Reproducible: &store.ReproducibleBuild{
+				Seed:            42,
+				SourceDateEpoch: 1634428800,
+			},
+		},
+		Packages: []rpmmd.PackageSpec{},
+	}
+
 	// For 2nd distribution
 	arch2, err := test_distro.New2().GetArch(test_distro.TestArchName)
 	require.NoError(t, err)
@@ -769,7 +823,14 @@ func TestCompose(t *testing.T) {
 		{false, "POST", "/api/v1/compose?test=2", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","parent":"","url":"http://ostree/"}}`, test_distro.TestImageTypeName), http.StatusOK, `{"status": true}`, expectedComposeOSTreeURL, []string{"build_id"}},
 		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","parent":"","url":"invalid-url"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"OSTreeCommitError","msg":"Get \"invalid-url/refs/heads/refid\": unsupported protocol scheme \"\""}]}`, nil, []string{"build_id"}},
 		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"/bad/ref","parent":"","url":"http://ostree/"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"InvalidChars","msg":"Invalid ostree ref"}]}`, expectedComposeOSTreeURL, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","gpg_keyid":"DEADBEEF"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, fmt.Sprintf(`{"status":false,"errors":[{"id":"OSTreeOptionsError","msg":"compose type \"%s\" doesn't produce an ostree commit archive, which can be signed"}]}`, test_distro.TestImageTypeName), nil, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","parent":"02604b2da6e954bd34b8b82a835e5a77d2b60ffa","static_deltas":true}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"OSTreeOptionsError","msg":"static_deltas require a parent commit, supply the url to retrieve it from"}]}`, nil, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","parent":"02604b2da6e954bd34b8b82a835e5a77d2b60ffa","tls_secret":"edge-repo"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"OSTreeOptionsError","msg":"the TLS options require the url of the repository"}]}`, nil, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"refid","url":"https://ostree/","tls_secret":"edge-repo"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"OSTreeOptionsError","msg":"mutual TLS requires both a client certificate and key"}]}`, nil, []string{"build_id"}},
 		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test-distro-2","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName), http.StatusOK, `{"status": true}`, expectedComposeGoodDistro, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","reproducible":true,"seed":42,"source_date_epoch":1634428800}`, test_distro.TestImageTypeName), http.StatusOK, `{"status": true}`, expectedComposeReproducible, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","seed":42}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"ReproducibleOptionsError","msg":"seed and source_date_epoch require a reproducible compose"}]}`, nil, []string{"build_id"}},
+		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","reproducible":true,"source_date_epoch":-1}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"ReproducibleOptionsError","msg":"source_date_epoch must not be negative"}]}`, nil, []string{"build_id"}},
 		{false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test-fedora-1","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status": false,"errors":[{"id":"DistroError", "msg":"Unknown distribution: fedora-1"}]}`, nil, []string{"build_id"}},
 		{false, "POST", "/api/v1/compose", `{"blueprint_name": "test-distro-2","compose_type": "imaginary_type","branch": "master"}`, http.StatusBadRequest, `{"status": false,"errors":[{"id":"ComposeError", "msg":"Failed to get compose type \"imaginary_type\": invalid image type: imaginary_type"}]}`, nil, []string{"build_id"}},
 	}
@@ -850,10 +911,10 @@ func TestComposeStatus(t *testing.T) {
 		ExpectedJSON   string
 	}{
 		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/30000000-0000-0000-0000-000000000000,30000000-0000-0000-0000-000000000002", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
-		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/*", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
-		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/*?name=test", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
+		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/*", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"error":{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
+		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/*?name=test", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"error":{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
 		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/status/*?status=FINISHED", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
-		{rpmmd_mock.BaseFixture, "GET", fmt.Sprintf("/api/v0/compose/status/*?type=%s", test_distro.TestImageTypeName), ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
+		{rpmmd_mock.BaseFixture, "GET", fmt.Sprintf("/api/v0/compose/status/*?type=%s", test_distro.TestImageTypeName), ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140},{"id":"30000000-0000-0000-0000-000000000001","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"RUNNING","job_created":1574857140,"job_started":1574857140},{"id":"30000000-0000-0000-0000-000000000002","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140},{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"error":{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}},{"id":"30000000-0000-0000-0000-000000000004","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
 		{rpmmd_mock.BaseFixture, "GET", "/api/v1/compose/status/30000000-0000-0000-0000-000000000000", ``, http.StatusOK, fmt.Sprintf(`{"uuids":[{"id":"30000000-0000-0000-0000-000000000000","blueprint":"test","version":"0.0.0","compose_type":"%[1]s","image_size":0,"queue_status":"WAITING","job_created":1574857140,"uploads":[{"uuid":"10000000-0000-0000-0000-000000000000","status":"WAITING","provider_name":"aws","image_name":"awsimage","creation_time":1574857140,"settings":{"region":"frankfurt","bucket":"clay","key":"imagekey"}}]}]}`, test_distro.TestImageTypeName)},
 	}
 
@@ -900,6 +961,478 @@ func TestComposeInfo(t *testing.T) {
 	}
 }
 
+// osbuild result of a build that failed in the rpm stage of the os pipeline
+const failedOSBuildOutput = `{
+  "type": "error",
+  "success": false,
+  "error": {
+    "type": "org.osbuild.error.stage"
+  },
+  "log": {
+    "build": [
+      {
+        "id": "56a93713050f49c966eda0391dce1340d16f168bcbfd542d9d90be668ecc8268",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\n",
+        "success": true
+      }
+    ],
+    "os": [
+      {
+        "id": "147fe506d915edb9e0eb8fdb88adb43c8603125f455f47d0228bca935bb997f6",
+        "type": "org.osbuild.rpm",
+        "output": "Preparing packages...\nerror: unpacking of archive failed: No space left on device\n",
+        "success": false,
+        "error": "org.osbuild.rpm failed with exit code 1"
+      }
+    ]
+  }
+}`
+
+func TestComposeInfoFailedJob(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	var cases = []struct {
+		Name          string
+		Result        string
+		Cancel        bool
+		ExpectedError string
+	}{
+		{
+			"stage failure",
+			`{"success":false,"osbuild_output":` + failedOSBuildOutput + `}`,
+			false,
+			`{"id":"StageFailed","msg":"failed in pipeline os, stage org.osbuild.rpm: org.osbuild.rpm failed with exit code 1","pipeline":"os","stage":"org.osbuild.rpm","output":"Preparing packages...\nerror: unpacking of archive failed: No space left on device"}`,
+		},
+		{
+			"upload failure",
+			`{"success":false,"osbuild_output":{"success":true},"target_errors":["uploading to aws failed"]}`,
+			false,
+			`{"id":"UploadFailed","msg":"uploading to aws failed"}`,
+		},
+		{
+			"missing ostree TLS secret",
+			`{"success":false,"osbuild_output":{"success":false},"ostree_tls_secret_error":"ostree TLS secret \"edge-repo\" is not configured on the worker"}`,
+			false,
+			`{"id":"OSTreeTLSSecretMissing","msg":"ostree TLS secret \"edge-repo\" is not configured on the worker"}`,
+		},
+		{
+			// results of jobs which finished before errors were recorded
+			"legacy result",
+			`{"success":false,"osbuild_output":{"success":false}}`,
+			false,
+			`{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}`,
+		},
+		{
+			"canceled",
+			"",
+			true,
+			`{"id":"JobCanceled","msg":"the compose was canceled"}`,
+		},
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	for _, c := range cases {
+		api, sf := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+
+		jobID, err := api.workers.EnqueueOSBuild(test_distro.TestArchName, &worker.OSBuildJob{})
+		require.NoError(t, err, c.Name)
+		if c.Cancel {
+			require.NoError(t, api.workers.Cancel(jobID), c.Name)
+		} else {
+			_, token, _, _, _, err := api.workers.RequestJob(context.Background(), test_distro.TestArchName, []string{"osbuild"})
+			require.NoError(t, err, c.Name)
+			require.NoError(t, api.workers.FinishJob(token, json.RawMessage(c.Result)), c.Name)
+		}
+
+		composeID := uuid.MustParse("30000000-0000-0000-0000-000000000005")
+		imageType, err := api.arch.GetImageType(test_distro.TestImageTypeName)
+		require.NoError(t, err, c.Name)
+		err = sf.PushCompose(composeID, nil, imageType, &blueprint.Blueprint{Name: "test", Version: "0.0.0"}, 0, nil, jobID, []rpmmd.PackageSpec{}, nil)
+		require.NoError(t, err, c.Name)
+
+		test.TestRoute(t, api, false, "GET", "/api/v0/compose/info/"+composeID.String(), ``, http.StatusOK,
+			fmt.Sprintf(`{"id":"%s","config":"","blueprint":{"name":"test","description":"","distro":"","version":"0.0.0","packages":null,"modules":null,"groups":null},"commit":"","deps":{"packages":[]},"compose_type":"%s","queue_status":"FAILED","image_size":0,"error":%s}`,
+				composeID, test_distro.TestImageTypeName, c.ExpectedError))
+
+		test.TestRoute(t, api, false, "GET", "/api/v0/compose/status/"+composeID.String(), ``, http.StatusOK,
+			fmt.Sprintf(`{"uuids":[{"id":"%s","blueprint":"test","version":"0.0.0","compose_type":"%s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"error":%s}]}`,
+				composeID, test_distro.TestImageTypeName, c.ExpectedError), "job_created", "job_started", "job_finished")
+	}
+}
+
+func TestComposeErrorKinds(t *testing.T) {
+	canceled := composeErrorFromJobResult(true, &worker.OSBuildJobResult{})
+	require.True(t, errors.Is(canceled, ErrComposeJobCanceled))
+	require.False(t, errors.Is(canceled, ErrComposeBuildFailed))
+
+	var result worker.OSBuildJobResult
+	require.NoError(t, json.Unmarshal([]byte(`{"success":false,"osbuild_output":`+failedOSBuildOutput+`}`), &result))
+	err := fmt.Errorf("compose failed: %w", composeErrorFromJobResult(false, &result))
+	require.True(t, errors.Is(err, ErrComposeStageFailed))
+
+	var composeErr *ComposeError
+	require.True(t, errors.As(err, &composeErr))
+	require.Equal(t, "org.osbuild.rpm", composeErr.Stage)
+	require.Equal(t, "compose failed: StageFailed: "+composeErr.Msg, err.Error())
+
+	require.True(t, errors.Is(genericComposeError(), ErrComposeBuildFailed))
+}
+
+const customManifest = `{"version":"2","pipelines":[{"name":"build","runner":"org.osbuild.rhel86"},{"name":"image","build":"name:build","stages":[{"type":"org.osbuild.truncate","options":{"filename":"disk.img","size":"1048576"}}]}],"sources":{}}`
+
+func TestComposeManifest(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	api, _ := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+
+	// the route is disabled by default
+	test.TestRoute(t, api, false, "POST", "/api/v0/compose/manifest", `{"manifest":`+customManifest+`}`, http.StatusNotFound, `{"status":false,"errors":[{"code":404,"id":"HTTPError","msg":"Not Found"}]}`)
+
+	api.EnableCustomManifests(false)
+
+	response := test.SendHTTP(api, false, "POST", "/api/v0/compose/manifest", `{"manifest":`+customManifest+`}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var reply struct {
+		BuildID uuid.UUID `json:"build_id"`
+		Status  bool      `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&reply))
+	require.True(t, reply.Status)
+
+	compose, exists := api.store.GetCompose(reply.BuildID)
+	require.True(t, exists)
+	require.Equal(t, distro.CustomImageTypeName, compose.ImageBuild.ImageType.Name())
+	require.Equal(t, "disk.img", compose.ImageBuild.ImageType.Filename())
+
+	// the job builds the manifest as it was given
+	_, token, jobType, args, _, err := api.workers.RequestJob(context.Background(), test_distro.TestArchName, []string{"osbuild"})
+	require.NoError(t, err)
+	require.Equal(t, "osbuild", jobType)
+	var job worker.OSBuildJob
+	require.NoError(t, json.Unmarshal(args, &job))
+	require.JSONEq(t, customManifest, string(job.Manifest))
+	require.Equal(t, "disk.img", job.ImageName)
+	require.Equal(t, []string{"image"}, job.Exports)
+	require.Equal(t, distro.CustomImageTypeName, job.ImageType)
+
+	require.NoError(t, api.workers.FinishJob(token, json.RawMessage(`{"success":true,"osbuild_output":{"success":true}}`)))
+	test.TestRoute(t, api, false, "GET", "/api/v0/compose/status/"+reply.BuildID.String(), ``, http.StatusOK,
+		fmt.Sprintf(`{"uuids":[{"id":"%s","blueprint":"","version":"","compose_type":"custom","image_size":0,"queue_status":"FINISHED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, reply.BuildID),
+		"job_created", "job_started", "job_finished")
+}
+
+func TestComposeManifestErrors(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	fileSourceManifest := `{"version":"2","pipelines":[{"name":"image","stages":[{"type":"org.osbuild.truncate","options":{"filename":"disk.img"}}]}],"sources":{"org.osbuild.curl":{"items":{"sha256:aaaa":"file:///etc/shadow"}}}}`
+
+	var cases = []struct {
+		Body         string
+		ExpectedJSON string
+	}{
+		{`[]`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"invalid compose request: json: cannot unmarshal array into Go value of type weldr.composeManifestRequest"}]}`},
+		{`{}`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"invalid manifest: unexpected end of JSON input"}]}`},
+		{`{"manifest":{"pipeline":{}}}`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"unsupported manifest version \"\", only version 2 manifests can be built"}]}`},
+		{`{"manifest":{"version":"2","pipelines":[]}}`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"the manifest doesn't contain any pipelines"}]}`},
+		{`{"manifest":{"version":"2","pipelines":[{"name":"os"}]}}`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"the last pipeline of the manifest, \"os\", doesn't produce a file"}]}`},
+		{`{"manifest":` + fileSourceManifest + `}`, `{"status":false,"errors":[{"id":"InvalidManifest","msg":"source \"org.osbuild.curl\" references a local file: file:///etc/shadow"}]}`},
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	api, _ := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+	api.EnableCustomManifests(false)
+
+	for _, c := range cases {
+		test.TestRoute(t, api, false, "POST", "/api/v0/compose/manifest", c.Body, http.StatusBadRequest, c.ExpectedJSON)
+	}
+	require.Empty(t, api.store.GetAllComposes())
+
+	// file sources can be allowed
+	api.EnableCustomManifests(true)
+	response := test.SendHTTP(api, false, "POST", "/api/v0/compose/manifest", `{"manifest":`+fileSourceManifest+`}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestComposeArch(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	fixture := rpmmd_mock.NoComposesFixture(tempdir)
+	rr := reporegistry.NewFromDistrosRepoConfigs(rpmmd.DistrosRepoConfigs{
+		test_distro.TestDistroName: {
+			test_distro.TestArchName: {
+				{Name: "test-id", BaseURL: "http://example.com/test/os/x86_64", CheckGPG: true},
+			},
+			test_distro.TestArch2Name: {
+				{Name: "test-id", BaseURL: "http://example.com/test/os/aarch64", CheckGPG: true},
+			},
+		},
+	})
+	distro1 := test_distro.New()
+	arch, err := distro1.GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+	dr, err := distroregistry.New(distro1, distro1)
+	require.NoError(t, err)
+	api := NewTestAPI(rpmmd_mock.NewRPMMDMock(fixture), arch, dr, rr, nil, fixture.Store, fixture.Workers, "", nil)
+
+	type composeReply struct {
+		BuildID  uuid.UUID `json:"build_id"`
+		Status   bool      `json:"status"`
+		Warnings []string  `json:"warnings"`
+	}
+	compose := func(arch string) composeReply {
+		response := test.SendHTTP(api, false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","arch": "%s"}`, test_distro.TestImageTypeName, arch))
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		var reply composeReply
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&reply))
+		require.True(t, reply.Status)
+		return reply
+	}
+	requireJob := func(arch string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, _, jobType, _, _, err := api.workers.RequestJob(ctx, arch, []string{"osbuild"})
+		require.NoError(t, err)
+		require.Equal(t, "osbuild", jobType)
+	}
+
+	// the host architecture
+	reply := compose(test_distro.TestArchName)
+	require.Empty(t, reply.Warnings)
+	c, exists := api.store.GetCompose(reply.BuildID)
+	require.True(t, exists)
+	require.Equal(t, test_distro.TestArchName, c.ImageBuild.ImageType.Arch().Name())
+	requireJob(test_distro.TestArchName)
+
+	// another architecture without workers is queued with a warning
+	reply = compose(test_distro.TestArch2Name)
+	require.Equal(t, []string{"no worker is building images for test_arch2, the compose waits until one is available"}, reply.Warnings)
+	c, exists = api.store.GetCompose(reply.BuildID)
+	require.True(t, exists)
+	require.Equal(t, test_distro.TestArch2Name, c.ImageBuild.ImageType.Arch().Name())
+
+	// the status of the waiting compose carries the warning as well
+	response := test.SendHTTP(api, false, "GET", "/api/v1/compose/status/"+reply.BuildID.String(), "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var status struct {
+		UUIDs []ComposeEntry `json:"uuids"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&status))
+	require.Len(t, status.UUIDs, 1)
+	require.Equal(t, reply.Warnings, status.UUIDs[0].Warnings)
+	requireJob(test_distro.TestArch2Name)
+
+	// now that a worker asked for jobs of that architecture
+	reply = compose(test_distro.TestArch2Name)
+	require.Empty(t, reply.Warnings)
+	requireJob(test_distro.TestArch2Name)
+
+	// blueprints whose sshd configuration locks out users are composed with
+	// a warning
+	test.TestRoute(t, api, false, "POST", "/api/v0/blueprints/new",
+		`{"name":"test","version":"0.0.1","customizations":{"user":[{"name":"root","password":"secret"}],"sshd":{"permit_root_login":"no"}}}`,
+		http.StatusOK, `{"status":true}`)
+	reply = compose(test_distro.TestArchName)
+	require.Equal(t, []string{`user "root" has a password, but sshd doesn't permit root to log in with a password`}, reply.Warnings)
+	requireJob(test_distro.TestArchName)
+
+	// architectures which the distro doesn't have or which don't have the
+	// image type are rejected
+	test.TestRoute(t, api, false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","arch": "unknown_arch"}`, test_distro.TestImageTypeName), http.StatusBadRequest,
+		`{"status":false,"errors":[{"id":"DistroError","msg":"Unknown arch: unknown_arch"}]}`)
+	test.TestRoute(t, api, false, "POST", "/api/v1/compose", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","arch": "%s"}`, test_distro.TestImageTypeName, test_distro.TestArch3Name), http.StatusBadRequest,
+		`{"status":false,"errors":[{"id":"ComposeError","msg":"Failed to get compose type \"test_type\": invalid image type: test_type"}]}`)
+}
+
+func TestComposePreview(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	var cases = []struct {
+		Path           string
+		Body           string
+		ExpectedStatus int
+		ExpectedJSON   string
+	}{
+		{"/api/v0/compose/preview", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName), http.StatusOK, `{"status":true,"manifest":{"pipeline":{},"sources":{}}}`},
+		{"/api/v1/compose/preview", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","reproducible":true,"seed":42}`, test_distro.TestImageTypeName), http.StatusOK, `{"status":true,"manifest":{"pipeline":{},"sources":{}}}`},
+		{"/api/v1/compose/preview", fmt.Sprintf(`{"blueprint_name": "http-server","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"UnknownBlueprint","msg":"Unknown blueprint name: http-server"}]}`},
+		{"/api/v1/compose/preview", `{"blueprint_name": "test-distro-2","compose_type": "imaginary_type","branch": "master"}`, http.StatusBadRequest, `{"status":false,"errors":[{"id":"ComposeError","msg":"Failed to get compose type \"imaginary_type\": invalid image type: imaginary_type"}]}`},
+		{"/api/v1/compose/preview", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master","seed":42}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"ReproducibleOptionsError","msg":"seed and source_date_epoch require a reproducible compose"}]}`},
+		{"/api/v1/compose/preview", fmt.Sprintf(`{"blueprint_name": "test","compose_type":"%s","branch":"master","ostree":{"ref":"/bad/ref"}}`, test_distro.TestImageTypeName), http.StatusBadRequest, `{"status":false,"errors":[{"id":"InvalidChars","msg":"Invalid ostree ref"}]}`},
+		{"/api/v1/compose/preview", `[]`, http.StatusNotFound, `{"status":false,"errors":[{"code":404,"id":"HTTPError","msg":"Not Found"}]}`},
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	for _, c := range cases {
+		api, s := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+		test.TestRoute(t, api, false, "POST", c.Path, c.Body, c.ExpectedStatus, c.ExpectedJSON)
+		require.Emptyf(t, s.GetAllComposes(), "%s: preview created a compose", c.Body)
+	}
+}
+
+func TestComposePreviewDoesNotQueue(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	api, s := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+	for i := 0; i < 3; i++ {
+		response := test.SendHTTP(api, false, "POST", "/api/v1/compose/preview", fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName))
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	require.Empty(t, s.GetAllComposes())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, _, _, _, err = api.workers.RequestJob(ctx, test_distro.TestArchName, []string{"osbuild", "osbuild-koji", "koji-init", "koji-finalize", "depsolve"})
+	require.Error(t, err, "a job was queued for a compose preview")
+}
+
+func TestComposePreviewRateLimit(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	api, _ := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+	body := fmt.Sprintf(`{"blueprint_name": "test","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName)
+
+	// previews are limited by how many were requested, not by how many
+	// are running at the same time
+	for i := 0; i < composePreviewBurst; i++ {
+		test.TestRoute(t, api, false, "POST", "/api/v1/compose/preview", body, http.StatusOK, "*")
+	}
+	test.TestRoute(t, api, false, "POST", "/api/v1/compose/preview", body, http.StatusTooManyRequests,
+		`{"status":false,"errors":[{"id":"TooManyRequests","msg":"too many compose previews were requested, try again later"}]}`)
+}
+
+func TestComposeChecksums(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	fixture := rpmmd_mock.NoComposesFixture(tempdir)
+	require.NoError(t, os.Mkdir(path.Join(tempdir, "jobs"), 0700))
+	q, err := fsjobqueue.New(path.Join(tempdir, "jobs"))
+	require.NoError(t, err)
+	artifactsDir := path.Join(tempdir, "artifacts")
+	require.NoError(t, os.Mkdir(artifactsDir, 0700))
+	workers := worker.NewServer(nil, q, artifactsDir, "", time.Duration(0), "/api/worker/v1")
+
+	rr := reporegistry.NewFromDistrosRepoConfigs(rpmmd.DistrosRepoConfigs{
+		test_distro.TestDistroName: {
+			test_distro.TestArchName: {
+				{Name: "test-id", BaseURL: "http://example.com/test/os/x86_64", CheckGPG: true},
+			},
+		},
+	})
+	distro1 := test_distro.New()
+	arch, err := distro1.GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+	dr, err := distroregistry.New(distro1, distro1)
+	require.NoError(t, err)
+	api := NewTestAPI(rpmmd_mock.NewRPMMDMock(fixture), arch, dr, rr, nil, fixture.Store, workers, "", nil)
+
+	imageType, err := arch.GetImageType(test_distro.TestImageTypeName)
+	require.NoError(t, err)
+
+	// a compose whose export contains the image and another file
+	jobID, err := workers.EnqueueOSBuild(test_distro.TestArchName, &worker.OSBuildJob{})
+	require.NoError(t, err)
+	_, token, _, _, _, err := workers.RequestJob(context.Background(), test_distro.TestArchName, []string{"osbuild"})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path.Join(artifactsDir, "tmp", token.String(), imageType.Filename()), []byte("image"), 0600))
+	result, err := json.Marshal(worker.OSBuildJobResult{
+		Success:       true,
+		OSBuildOutput: &osbuild.Result{Success: true},
+		Artifacts: []worker.ArtifactChecksum{
+			{Name: imageType.Filename(), Size: 5, Sha256: "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d"},
+			{Name: "image.manifest", Size: 0, Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, workers.FinishJob(token, result))
+
+	composeID := uuid.MustParse("30000000-0000-0000-0000-000000000005")
+	err = fixture.Store.PushCompose(composeID, distro.Manifest(`{"sources":{},"pipeline":{}}`), imageType, &blueprint.Blueprint{Name: "test", Version: "0.0.0"}, 0, nil, jobID, []rpmmd.PackageSpec{}, nil)
+	require.NoError(t, err)
+
+	test.TestRoute(t, api, false, "GET", "/api/v0/compose/info/"+composeID.String(), ``, http.StatusOK, fmt.Sprintf(`{
+		"id": "%s",
+		"config": "",
+		"blueprint": {"name":"test","description":"","distro":"","version":"0.0.0","packages":null,"modules":null,"groups":null},
+		"commit": "",
+		"deps": {"packages":[]},
+		"compose_type": "%s",
+		"queue_status": "FINISHED",
+		"image_size": 0,
+		"artifacts": [
+			{"name": "%s", "size": 5, "sha256": "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d"},
+			{"name": "image.manifest", "size": 0, "sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}
+		]
+	}`, composeID, test_distro.TestImageTypeName, imageType.Filename()))
+
+	for _, version := range []string{"v0", "v1"} {
+		response := test.SendHTTP(api, false, "GET", "/api/"+version+"/compose/image/"+composeID.String(), ``)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d", response.Header.Get("X-Checksum-Sha256"))
+		image, err := ioutil.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, "image", string(image))
+
+		response = test.SendHTTP(api, false, "GET", "/api/"+version+"/compose/metadata/"+composeID.String(), ``)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		tr := tar.NewReader(response.Body)
+		_, err = tr.Next()
+		require.NoError(t, err)
+		h, err := tr.Next()
+		require.NoError(t, err)
+		require.Equal(t, "CHECKSUMS.sha256", h.Name)
+		checksums, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		require.Equal(t, "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d  "+imageType.Filename()+"\n"+
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  image.manifest\n", string(checksums))
+	}
+}
+
 func TestComposeLogs(t *testing.T) {
 	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
 		t.Skip("This test is for internal testing only")
@@ -1064,8 +1597,8 @@ func TestComposeFailed(t *testing.T) {
 		ExpectedStatus int
 		ExpectedJSON   string
 	}{
-		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/failed", ``, http.StatusOK, fmt.Sprintf(`{"failed":[{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140}]}`, test_distro.TestImageTypeName)},
-		{rpmmd_mock.BaseFixture, "GET", "/api/v1/compose/failed", ``, http.StatusOK, fmt.Sprintf(`{"failed":[{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"uploads":[{"uuid":"10000000-0000-0000-0000-000000000000","status":"FAILED","provider_name":"aws","image_name":"awsimage","creation_time":1574857140,"settings":{"region":"frankfurt","bucket":"clay","key":"imagekey"}}]}]}`, test_distro.TestImageTypeName)},
+		{rpmmd_mock.BaseFixture, "GET", "/api/v0/compose/failed", ``, http.StatusOK, fmt.Sprintf(`{"failed":[{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"error":{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}}]}`, test_distro.TestImageTypeName)},
+		{rpmmd_mock.BaseFixture, "GET", "/api/v1/compose/failed", ``, http.StatusOK, fmt.Sprintf(`{"failed":[{"id":"30000000-0000-0000-0000-000000000003","blueprint":"test","version":"0.0.0","compose_type":"%s","image_size":0,"queue_status":"FAILED","job_created":1574857140,"job_started":1574857140,"job_finished":1574857140,"uploads":[{"uuid":"10000000-0000-0000-0000-000000000000","status":"FAILED","provider_name":"aws","image_name":"awsimage","creation_time":1574857140,"settings":{"region":"frankfurt","bucket":"clay","key":"imagekey"}}],"error":{"id":"BuildFailed","msg":"the build failed, see the compose logs for details"}}]}`, test_distro.TestImageTypeName)},
 		{rpmmd_mock.NoComposesFixture, "GET", "/api/v0/compose/failed", ``, http.StatusOK, `{"failed":[]}`},
 	}
 
@@ -1742,3 +2275,98 @@ func TestComposePOST_ImageTypeDenylist(t *testing.T) {
 		}
 	}
 }
+
+func TestBlueprintRepositories(t *testing.T) {
+	repos, err := blueprintRepositories(blueprint.Blueprint{})
+	require.NoError(t, err)
+	require.Empty(t, repos)
+
+	key := "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQINBGAcScoBEADLf8YHkezJ6adl\n-----END PGP PUBLIC KEY BLOCK-----\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/RPM-GPG-KEY" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, key)
+	}))
+	defer server.Close()
+
+	// the keys of URLs are fetched, the packages are checked with them
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Repositories: []blueprint.RepositoryCustomization{
+				{ID: "internal", BaseURL: "https://repo.example.com/el8/", GPGCheck: true, GPGKeys: []string{server.URL + "/RPM-GPG-KEY", key}, InstallToImage: true},
+				{ID: "epel-8", Metalink: "https://mirrors.fedoraproject.org/metalink?repo=epel-8&arch=x86_64"},
+			},
+		},
+	}
+	repos, err = blueprintRepositories(bp)
+	require.NoError(t, err)
+	require.Equal(t, []rpmmd.RepoConfig{
+		{Name: "internal", BaseURL: "https://repo.example.com/el8/", GPGKey: key + "\n" + key, CheckGPG: true},
+		{Name: "epel-8", Metalink: "https://mirrors.fedoraproject.org/metalink?repo=epel-8&arch=x86_64"},
+	}, repos)
+
+	keyFile := filepath.Join(t.TempDir(), "RPM-GPG-KEY")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(key), 0600))
+	bp.Customizations.Repositories[0].GPGKeys = []string{"file://" + keyFile}
+	repos, err = blueprintRepositories(bp)
+	require.NoError(t, err)
+	require.Equal(t, key, repos[0].GPGKey)
+
+	bp.Customizations.Repositories[0].GPGKeys = []string{server.URL + "/missing"}
+	_, err = blueprintRepositories(bp)
+	require.EqualError(t, err, fmt.Sprintf(`cannot fetch GPG key of repository "internal": %s/missing returned 404 Not Found`, server.URL))
+
+	bp.Customizations.Repositories[0].GPGKeys = []string{"file://" + filepath.Dir(keyFile)}
+	_, err = blueprintRepositories(bp)
+	require.Error(t, err)
+}
+
+func TestComposeRepositoryGPGKeyURL(t *testing.T) {
+	if len(os.Getenv("OSBUILD_COMPOSER_TEST_EXTERNAL")) > 0 {
+		t.Skip("This test is for internal testing only")
+	}
+
+	tempdir, err := ioutil.TempDir("", "weldr-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html></html>")
+	}))
+	defer server.Close()
+
+	api, s := createWeldrAPI(tempdir, rpmmd_mock.NoComposesFixture)
+	response := test.SendHTTP(api, false, "POST", "/api/v0/blueprints/new", fmt.Sprintf(`{"name":"test-gpg","description":"Test","version":"0.0.0","customizations":{"repositories":[{"id":"internal","baseurl":"https://repo.example.com/el8/","gpgcheck":true,"gpgkeys":["%s/RPM-GPG-KEY"]}]}}`, server.URL))
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	test.TestRoute(t, api, false, "POST", "/api/v0/compose", fmt.Sprintf(`{"blueprint_name": "test-gpg","compose_type": "%s","branch": "master"}`, test_distro.TestImageTypeName), http.StatusBadRequest,
+		fmt.Sprintf(`{"status":false,"errors":[{"id":"BlueprintsError","msg":"cannot fetch GPG key of repository \"internal\": %s/RPM-GPG-KEY is not an ASCII armored GPG key"}]}`, server.URL))
+	require.Empty(t, s.GetAllComposes())
+}
+
+func TestResolveContainers(t *testing.T) {
+	specs, err := resolveContainers(blueprint.Blueprint{}, true)
+	require.NoError(t, err)
+	require.Empty(t, specs)
+
+	digest := "sha256:4c2ea3e5ea77d2e5b4c1f8f3a9b21c6e8bfe7de1a5ad50e3f1c2b2b6a3d3ef10"
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{Source: "fedora:35", Name: "localhost/fedora"},
+			{Source: "quay.io/example/app@" + digest, TLSVerify: common.BoolToPtr(false)},
+		},
+	}
+	specs, err = resolveContainers(bp, true)
+	require.NoError(t, err)
+	require.Equal(t, []container.Spec{
+		{Source: "docker.io/library/fedora", Digest: fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("fedora:35"))), LocalName: "localhost/fedora"},
+		{Source: "quay.io/example/app", Digest: digest, LocalName: "quay.io/example/app@" + digest, TLSVerify: common.BoolToPtr(false)},
+	}, specs)
+
+	// pinned images are not resolved
+	specs, err = resolveContainers(blueprint.Blueprint{Containers: bp.Containers[1:]}, false)
+	require.NoError(t, err)
+	require.Equal(t, digest, specs[0].Digest)
+}
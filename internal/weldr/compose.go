@@ -2,11 +2,13 @@ package weldr
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 
 	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/osbuild/osbuild-composer/internal/store"
+	"github.com/osbuild/osbuild-composer/internal/worker"
 )
 
 type ComposeEntry struct {
@@ -20,6 +22,128 @@ type ComposeEntry struct {
 	JobStarted  float64                `json:"job_started,omitempty"`
 	JobFinished float64                `json:"job_finished,omitempty"`
 	Uploads     []uploadResponse       `json:"uploads,omitempty"`
+
+	Reproducible *reproducibleResponse `json:"reproducible,omitempty"`
+	Error        *ComposeError         `json:"error,omitempty"`
+	// Why a waiting compose may not start soon
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// reproducibleResponse holds the values to pass in a compose request to
+// rebuild a reproducible compose
+type reproducibleResponse struct {
+	Seed            int64 `json:"seed"`
+	SourceDateEpoch int64 `json:"source_date_epoch"`
+}
+
+func reproducibleToResponse(r *store.ReproducibleBuild) *reproducibleResponse {
+	if r == nil {
+		return nil
+	}
+	return &reproducibleResponse{
+		Seed:            r.Seed,
+		SourceDateEpoch: r.SourceDateEpoch,
+	}
+}
+
+// ComposeError describes why a compose failed. The full osbuild log is
+// available from the compose logs route, Output only holds its tail.
+type ComposeError struct {
+	ID       string `json:"id"`
+	Msg      string `json:"msg"`
+	Pipeline string `json:"pipeline,omitempty"`
+	Stage    string `json:"stage,omitempty"`
+	Output   string `json:"output,omitempty"`
+}
+
+func (e *ComposeError) Error() string {
+	return e.ID + ": " + e.Msg
+}
+
+// Is reports whether target is a ComposeError of the same kind, so that
+// errors.Is(err, ErrComposeStageFailed) matches any failed stage.
+func (e *ComposeError) Is(target error) bool {
+	t, ok := target.(*ComposeError)
+	return ok && t.ID == e.ID
+}
+
+// The kinds of compose errors, compare them with errors.Is
+var (
+	ErrComposeJobCanceled            = &ComposeError{ID: "JobCanceled"}
+	ErrComposeUploadFailed           = &ComposeError{ID: "UploadFailed"}
+	ErrComposeOSTreeTLSSecretMissing = &ComposeError{ID: "OSTreeTLSSecretMissing"}
+	ErrComposeOSTreeSigningFailed    = &ComposeError{ID: "OSTreeSigningFailed"}
+	ErrComposeStageFailed            = &ComposeError{ID: "StageFailed"}
+	ErrComposeBuildFailed            = &ComposeError{ID: "BuildFailed"}
+)
+
+// Number of lines of the output of a failed stage that are included in
+// compose errors
+const composeErrorOutputLines = 20
+
+// Returns the last n lines of s
+func outputTail(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func composeErrorFromJobResult(canceled bool, result *worker.OSBuildJobResult) *ComposeError {
+	if canceled {
+		return &ComposeError{
+			ID:  ErrComposeJobCanceled.ID,
+			Msg: "the compose was canceled",
+		}
+	}
+
+	if result.OSBuildOutput != nil && result.OSBuildOutput.Success && len(result.TargetErrors) > 0 {
+		return &ComposeError{
+			ID:  ErrComposeUploadFailed.ID,
+			Msg: strings.Join(result.TargetErrors, ", "),
+		}
+	}
+
+	if result.OSTreeTLSSecretError != "" {
+		return &ComposeError{
+			ID:  ErrComposeOSTreeTLSSecretMissing.ID,
+			Msg: result.OSTreeTLSSecretError,
+		}
+	}
+
+	if result.OSTreeSigningError != "" {
+		return &ComposeError{
+			ID:  ErrComposeOSTreeSigningFailed.ID,
+			Msg: result.OSTreeSigningError,
+		}
+	}
+
+	if result.OSBuildOutput != nil && result.OSBuildOutput.Summary != nil && result.OSBuildOutput.Summary.FailedStage != nil {
+		failed := result.OSBuildOutput.Summary.FailedStage
+		msg := failed.String()
+		if failed.Error != "" {
+			msg += ": " + failed.Error
+		}
+		return &ComposeError{
+			ID:       ErrComposeStageFailed.ID,
+			Msg:      msg,
+			Pipeline: failed.Pipeline,
+			Stage:    failed.Type,
+			Output:   outputTail(failed.Output, composeErrorOutputLines),
+		}
+	}
+
+	return genericComposeError()
+}
+
+// genericComposeError is returned for failed composes that don't carry more
+// information, for example those that failed before this was recorded.
+func genericComposeError() *ComposeError {
+	return &ComposeError{
+		ID:  ErrComposeBuildFailed.ID,
+		Msg: "the build failed, see the compose logs for details",
+	}
 }
 
 func composeToComposeEntry(id uuid.UUID, compose store.Compose, status *composeStatus, includeUploads bool) *ComposeEntry {
@@ -34,6 +158,8 @@ func composeToComposeEntry(id uuid.UUID, compose store.Compose, status *composeS
 		composeEntry.Uploads = targetsToUploadResponses(compose.ImageBuild.Targets, status.State)
 	}
 
+	composeEntry.Reproducible = reproducibleToResponse(compose.ImageBuild.Reproducible)
+
 	switch status.State {
 	case ComposeWaiting:
 		composeEntry.QueueStatus = common.IBWaiting
@@ -53,6 +179,7 @@ func composeToComposeEntry(id uuid.UUID, compose store.Compose, status *composeS
 
 	case ComposeFailed:
 		composeEntry.QueueStatus = common.IBFailed
+		composeEntry.Error = status.Error
 		composeEntry.JobCreated = float64(status.Queued.UnixNano()) / 1000000000
 		composeEntry.JobStarted = float64(status.Started.UnixNano()) / 1000000000
 		composeEntry.JobFinished = float64(status.Finished.UnixNano()) / 1000000000
@@ -3,6 +3,7 @@ package weldr
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/osbuild/osbuild-composer/internal/common"
@@ -44,6 +45,18 @@ type azureUploadSettings struct {
 
 func (azureUploadSettings) isUploadSettings() {}
 
+type gcpUploadSettings struct {
+	Region string `json:"region"`
+	Bucket string `json:"bucket"`
+	Object string `json:"object,omitempty"`
+
+	// base64 encoded JSON credentials of the service account, the
+	// credentials of the worker are used if empty
+	Credentials []byte `json:"credentials,omitempty"`
+}
+
+func (gcpUploadSettings) isUploadSettings() {}
+
 type vmwareUploadSettings struct {
 	Host       string `json:"host"`
 	Username   string `json:"username"`
@@ -80,6 +93,8 @@ func (u *uploadRequest) UnmarshalJSON(data []byte) error {
 		settings = new(azureUploadSettings)
 	case "aws":
 		settings = new(awsUploadSettings)
+	case "gcp":
+		settings = new(gcpUploadSettings)
 	case "vmware":
 		settings = new(vmwareUploadSettings)
 	default:
@@ -142,6 +157,15 @@ func targetsToUploadResponses(targets []*target.Target, state ComposeState) []up
 				// StorageAccount and StorageAccessKey are intentionally not included.
 			}
 			uploads = append(uploads, upload)
+		case *target.GCPTargetOptions:
+			upload.ProviderName = "gcp"
+			upload.Settings = &gcpUploadSettings{
+				Region: options.Region,
+				Bucket: options.Bucket,
+				Object: options.Object,
+				// Credentials are intentionally not included.
+			}
+			uploads = append(uploads, upload)
 		case *target.VMWareTargetOptions:
 			upload.ProviderName = "vmware"
 			upload.Settings = &vmwareUploadSettings{
@@ -158,7 +182,7 @@ func targetsToUploadResponses(targets []*target.Target, state ComposeState) []up
 	return uploads
 }
 
-func uploadRequestToTarget(u uploadRequest, imageType distro.ImageType) *target.Target {
+func uploadRequestToTarget(u uploadRequest, imageType distro.ImageType) (*target.Target, error) {
 	var t target.Target
 
 	t.Uuid = uuid.New()
@@ -186,6 +210,24 @@ func uploadRequestToTarget(u uploadRequest, imageType distro.ImageType) *target.
 			StorageAccessKey: options.StorageAccessKey,
 			Container:        options.Container,
 		}
+	case *gcpUploadSettings:
+		// GCP only imports the archives of gce images
+		if imageType.Name() != "gce" {
+			return nil, fmt.Errorf("image type %q cannot be uploaded to gcp, only gce images can", imageType.Name())
+		}
+		t.Name = "org.osbuild.gcp"
+		object := options.Object
+		if object == "" {
+			// the uploaded archive is deleted once the image is created
+			object = "composer-" + t.Uuid.String() + ".tar.gz"
+		}
+		t.Options = &target.GCPTargetOptions{
+			Filename:    imageType.Filename(),
+			Region:      options.Region,
+			Bucket:      options.Bucket,
+			Object:      object,
+			Credentials: options.Credentials,
+		}
 	case *vmwareUploadSettings:
 		t.Name = "org.osbuild.vmware"
 		t.Options = &target.VMWareTargetOptions{
@@ -199,5 +241,5 @@ func uploadRequestToTarget(u uploadRequest, imageType distro.ImageType) *target.
 		}
 	}
 
-	return &t
+	return &t, nil
 }
@@ -0,0 +1,56 @@
+package weldr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/osbuild-composer/internal/distro/rhel86"
+	"github.com/osbuild/osbuild-composer/internal/target"
+)
+
+func TestGCPUploadRequestToTarget(t *testing.T) {
+	var u uploadRequest
+	err := json.Unmarshal([]byte(`{"provider":"gcp","image_name":"rhel-8","settings":{"region":"europe-west1","bucket":"images","credentials":"eyJ0eXBlIjoic2VydmljZV9hY2NvdW50In0="}}`), &u)
+	require.NoError(t, err)
+
+	arch, err := rhel86.New().GetArch("x86_64")
+	require.NoError(t, err)
+	imageType, err := arch.GetImageType("gce")
+	require.NoError(t, err)
+
+	tgt, err := uploadRequestToTarget(u, imageType)
+	require.NoError(t, err)
+	assert.Equal(t, "org.osbuild.gcp", tgt.Name)
+	assert.Equal(t, "rhel-8", tgt.ImageName)
+	options, ok := tgt.Options.(*target.GCPTargetOptions)
+	require.True(t, ok)
+	assert.Equal(t, "image.tar.gz", options.Filename)
+	assert.Equal(t, "europe-west1", options.Region)
+	assert.Equal(t, "images", options.Bucket)
+	assert.Equal(t, "composer-"+tgt.Uuid.String()+".tar.gz", options.Object)
+	assert.Equal(t, `{"type":"service_account"}`, string(options.Credentials))
+
+	uploads := targetsToUploadResponses([]*target.Target{tgt}, ComposeWaiting)
+	require.Len(t, uploads, 1)
+	assert.Equal(t, "gcp", uploads[0].ProviderName)
+	settings, err := json.Marshal(uploads[0].Settings)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"region":"europe-west1","bucket":"images","object":"`+options.Object+`"}`, string(settings))
+}
+
+func TestGCPUploadRequestToTargetImageType(t *testing.T) {
+	var u uploadRequest
+	err := json.Unmarshal([]byte(`{"provider":"gcp","image_name":"rhel-8","settings":{"region":"europe-west1","bucket":"images"}}`), &u)
+	require.NoError(t, err)
+
+	arch, err := rhel86.New().GetArch("x86_64")
+	require.NoError(t, err)
+	imageType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	_, err = uploadRequestToTarget(u, imageType)
+	assert.EqualError(t, err, `image type "qcow2" cannot be uploaded to gcp, only gce images can`)
+}
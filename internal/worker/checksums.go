@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ArtifactChecksum is the size and sha256 digest of a file produced by an
+// osbuild job. Name is relative to the directory of the exported pipeline.
+type ArtifactChecksum struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// ChecksumFile returns the checksum of the file at path, recorded as name
+func ChecksumFile(name, path string) (ArtifactChecksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ArtifactChecksum{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return ArtifactChecksum{}, err
+	}
+
+	return ArtifactChecksum{
+		Name:   name,
+		Size:   size,
+		Sha256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// ChecksumArtifacts returns the checksums of all regular files below dir,
+// sorted by their name
+func ChecksumArtifacts(dir string) ([]ArtifactChecksum, error) {
+	var artifacts []ArtifactChecksum
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		artifact, err := ChecksumFile(filepath.ToSlash(name), path)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// FindArtifact returns the checksum of the artifact called name, or nil if
+// there is none
+func FindArtifact(artifacts []ArtifactChecksum, name string) *ArtifactChecksum {
+	for idx := range artifacts {
+		if artifacts[idx].Name == name {
+			return &artifacts[idx]
+		}
+	}
+	return nil
+}
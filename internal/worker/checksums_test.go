@@ -0,0 +1,36 @@
+package worker_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/osbuild-composer/internal/worker"
+)
+
+func TestChecksumArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worker-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "disk.qcow2"), []byte("image"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "images"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "images", "install.img"), []byte(""), 0644))
+	require.NoError(t, os.Symlink("disk.qcow2", filepath.Join(dir, "link")))
+
+	artifacts, err := worker.ChecksumArtifacts(dir)
+	require.NoError(t, err)
+	require.Equal(t, []worker.ArtifactChecksum{
+		{Name: "disk.qcow2", Size: 5, Sha256: "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d"},
+		{Name: "images/install.img", Size: 0, Sha256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}, artifacts)
+
+	require.Equal(t, &artifacts[1], worker.FindArtifact(artifacts, "images/install.img"))
+	require.Nil(t, worker.FindArtifact(artifacts, "install.img"))
+
+	_, err = worker.ChecksumArtifacts(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
@@ -17,6 +17,23 @@ type OSBuildJob struct {
 	ImageName       string           `json:"image_name,omitempty"`
 	StreamOptimized bool             `json:"stream_optimized,omitempty"`
 	Exports         []string         `json:"export_stages,omitempty"`
+	ImageType       string           `json:"image_type,omitempty"`
+	// Signs the ostree commit of commit archives, commits are unsigned if
+	// unset
+	OSTreeSigning *OSTreeSigning `json:"ostree_signing,omitempty"`
+	// Name of the secret of the worker configuration with the TLS client
+	// certificate to pull ostree commits from a repository that requires
+	// mutual TLS
+	OSTreeTLSSecret string `json:"ostree_tls_secret,omitempty"`
+}
+
+// OSTreeSigning is the GPG key an ostree commit is signed with. Exactly one
+// of KeyID and Key is set.
+type OSTreeSigning struct {
+	// ID of a secret key in the GPG home directory of the worker
+	KeyID string `json:"key_id,omitempty"`
+	// ASCII armored secret key
+	Key string `json:"key,omitempty"`
 }
 
 type OSBuildJobResult struct {
@@ -25,6 +42,16 @@ type OSBuildJobResult struct {
 	TargetResults []*target.TargetResult `json:"target_results,omitempty"`
 	TargetErrors  []string               `json:"target_errors,omitempty"`
 	UploadStatus  string                 `json:"upload_status"`
+	// Checksums of the files of the exported pipeline
+	Artifacts []ArtifactChecksum `json:"artifacts,omitempty"`
+	// ID of the key the ostree commit was signed with
+	OSTreeSigningKeyID string `json:"ostree_signing_key_id,omitempty"`
+	// Error that signing the ostree commit failed with. The job isn't
+	// retried, because the key won't become available by itself.
+	OSTreeSigningError string `json:"ostree_signing_error,omitempty"`
+	// Error of a job whose ostree TLS secret isn't configured on the
+	// worker, which isn't retried either
+	OSTreeTLSSecretError string `json:"ostree_tls_secret_error,omitempty"`
 }
 
 type KojiInitJob struct {
@@ -47,6 +74,7 @@ type OSBuildKojiJob struct {
 	KojiServer    string          `json:"koji_server"`
 	KojiDirectory string          `json:"koji_directory"`
 	KojiFilename  string          `json:"koji_filename"`
+	ImageType     string          `json:"image_type,omitempty"`
 }
 
 type OSBuildKojiJobResult struct {
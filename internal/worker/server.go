@@ -12,6 +12,8 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,6 +23,7 @@ import (
 
 	"github.com/osbuild/osbuild-composer/internal/common"
 	"github.com/osbuild/osbuild-composer/internal/jobqueue"
+	"github.com/osbuild/osbuild-composer/internal/jsondb"
 	"github.com/osbuild/osbuild-composer/internal/prometheus"
 	"github.com/osbuild/osbuild-composer/internal/worker/api"
 )
@@ -30,8 +33,24 @@ type Server struct {
 	logger            *log.Logger
 	artifactsDir      string
 	requestJobTimeout time.Duration
+
+	// The last time a worker requested jobs, by architecture. It is kept
+	// in workerArchesDB, if set, so that it survives restarts.
+	workerArchesMu sync.Mutex
+	workerArches   map[string]time.Time
+	workerArchesDB *jsondb.JSONDatabase
 }
 
+// Workers don't request jobs while they are building, so an architecture
+// counts as served for this long after a worker last requested jobs for it.
+const workerArchTimeout = time.Hour
+
+// The last request times of workers are only written when they are older
+// than this, as workers request jobs whenever their requests time out.
+const workerArchesWriteInterval = time.Minute
+
+const workerArchesDBName = "worker-arches"
+
 type JobStatus struct {
 	Queued   time.Time
 	Started  time.Time
@@ -42,12 +61,36 @@ type JobStatus struct {
 var ErrInvalidToken = errors.New("token does not exist")
 var ErrJobNotRunning = errors.New("job isn't running")
 
-func NewServer(logger *log.Logger, jobs jobqueue.JobQueue, artifactsDir string, requestJobTimeout time.Duration, basePath string) *Server {
+// NewServer creates a worker server. The architectures of the workers are
+// kept in stateDir, unless it is empty.
+func NewServer(logger *log.Logger, jobs jobqueue.JobQueue, artifactsDir, stateDir string, requestJobTimeout time.Duration, basePath string) *Server {
 	s := &Server{
 		jobs:              jobs,
 		logger:            logger,
 		artifactsDir:      artifactsDir,
 		requestJobTimeout: requestJobTimeout,
+		workerArches:      make(map[string]time.Time),
+	}
+
+	if stateDir != "" {
+		s.workerArchesDB = jsondb.New(stateDir, 0600)
+		_, err := s.workerArchesDB.Read(workerArchesDBName, &s.workerArches)
+		if err != nil {
+			logrus.Errorf("cannot read the architectures of workers: %v", err)
+		}
+	}
+
+	// the gauges of jobs are only changed by the server, start them with
+	// the jobs the queue already holds
+	pending, running, err := jobs.CountJobs()
+	if err != nil {
+		logrus.Errorf("cannot count the jobs of the queue: %v", err)
+	}
+	for jobType, count := range pending {
+		prometheus.PendingJobs.WithLabelValues(jobType).Set(float64(count))
+	}
+	for jobType, count := range running {
+		prometheus.RunningJobs.WithLabelValues(jobType).Set(float64(count))
 	}
 
 	api.BasePath = basePath
@@ -81,33 +124,43 @@ func (s *Server) WatchHeartbeats() {
 	for range time.Tick(time.Second * 30) {
 		for _, token := range s.jobs.Heartbeats(time.Second * 120) {
 			id, _ := s.jobs.IdFromToken(token)
-			logrus.Infof("Removing unresponsive job: %s\n", id)
+			logger := s.jobLogger(id)
+			logger.Infof("Removing unresponsive job: %s\n", id)
+			prometheus.StaleJobs.Inc()
 			err := s.FinishJob(token, nil)
 			if err != nil {
-				logrus.Errorf("Error finishing unresponsive job: %v", err)
+				logger.Errorf("Error finishing unresponsive job: %v", err)
 			}
 		}
 	}
 }
 
 func (s *Server) EnqueueOSBuild(arch string, job *OSBuildJob) (uuid.UUID, error) {
-	return s.jobs.Enqueue("osbuild:"+arch, job, nil)
+	return s.enqueue("osbuild:"+arch, job, nil)
 }
 
 func (s *Server) EnqueueOSBuildKoji(arch string, job *OSBuildKojiJob, initID uuid.UUID) (uuid.UUID, error) {
-	return s.jobs.Enqueue("osbuild-koji:"+arch, job, []uuid.UUID{initID})
+	return s.enqueue("osbuild-koji:"+arch, job, []uuid.UUID{initID})
 }
 
 func (s *Server) EnqueueKojiInit(job *KojiInitJob) (uuid.UUID, error) {
-	return s.jobs.Enqueue("koji-init", job, nil)
+	return s.enqueue("koji-init", job, nil)
 }
 
 func (s *Server) EnqueueKojiFinalize(job *KojiFinalizeJob, initID uuid.UUID, buildIDs []uuid.UUID) (uuid.UUID, error) {
-	return s.jobs.Enqueue("koji-finalize", job, append([]uuid.UUID{initID}, buildIDs...))
+	return s.enqueue("koji-finalize", job, append([]uuid.UUID{initID}, buildIDs...))
 }
 
 func (s *Server) EnqueueDepsolve(job *DepsolveJob) (uuid.UUID, error) {
-	return s.jobs.Enqueue("depsolve", job, nil)
+	return s.enqueue("depsolve", job, nil)
+}
+
+func (s *Server) enqueue(jobType string, job interface{}, dependencies []uuid.UUID) (uuid.UUID, error) {
+	id, err := s.jobs.Enqueue(jobType, job, dependencies)
+	if err == nil {
+		prometheus.PendingJobs.WithLabelValues(jobType).Inc()
+	}
+	return id, err
 }
 
 func (s *Server) JobStatus(id uuid.UUID, result interface{}) (*JobStatus, []uuid.UUID, error) {
@@ -156,7 +209,28 @@ func (s *Server) Job(id uuid.UUID, job interface{}) (string, json.RawMessage, []
 }
 
 func (s *Server) Cancel(id uuid.UUID) error {
-	return s.jobs.CancelJob(id)
+	jobType, _, _, err := s.jobs.Job(id)
+	if err != nil {
+		return err
+	}
+	_, _, started, finished, canceled, _, err := s.jobs.JobStatus(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.jobs.CancelJob(id)
+	if err != nil {
+		return err
+	}
+
+	if !canceled && finished.IsZero() {
+		if started.IsZero() {
+			prometheus.PendingJobs.WithLabelValues(jobType).Dec()
+		} else {
+			prometheus.RunningJobs.WithLabelValues(jobType).Dec()
+		}
+	}
+	return nil
 }
 
 // Provides access to artifacts of a job. Returns an io.Reader for the artifact
@@ -207,7 +281,35 @@ func (s *Server) DeleteArtifacts(id uuid.UUID) error {
 	return os.RemoveAll(path.Join(s.artifactsDir, id.String()))
 }
 
+// HasWorkersForArch returns true if a worker requested jobs for arch
+// recently, i.e. if osbuild jobs of arch are likely to be picked up.
+func (s *Server) HasWorkersForArch(arch string) bool {
+	s.workerArchesMu.Lock()
+	defer s.workerArchesMu.Unlock()
+
+	lastSeen, exists := s.workerArches[arch]
+	return exists && time.Since(lastSeen) < workerArchTimeout
+}
+
+// recordWorkerArch records that a worker requested jobs for arch
+func (s *Server) recordWorkerArch(arch string) {
+	s.workerArchesMu.Lock()
+	defer s.workerArchesMu.Unlock()
+
+	lastSeen := s.workerArches[arch]
+	s.workerArches[arch] = time.Now()
+	if s.workerArchesDB == nil || time.Since(lastSeen) < workerArchesWriteInterval {
+		return
+	}
+	err := s.workerArchesDB.Write(workerArchesDBName, s.workerArches)
+	if err != nil {
+		logrus.Errorf("cannot write the architectures of workers: %v", err)
+	}
+}
+
 func (s *Server) RequestJob(ctx context.Context, arch string, jobTypes []string) (uuid.UUID, uuid.UUID, string, json.RawMessage, []json.RawMessage, error) {
+	s.recordWorkerArch(arch)
+
 	// treat osbuild jobs specially until we have found a generic way to
 	// specify dequeuing restrictions. For now, we only have one
 	// restriction: arch for osbuild jobs.
@@ -229,6 +331,9 @@ func (s *Server) RequestJob(ctx context.Context, arch string, jobTypes []string)
 	if err != nil {
 		return uuid.Nil, uuid.Nil, "", nil, nil, err
 	}
+	prometheus.PendingJobs.WithLabelValues(jobType).Dec()
+	prometheus.RunningJobs.WithLabelValues(jobType).Inc()
+	jobLogFields(common.LoggerFromContext(ctx), jobId, jobType, args).Infof("Job %s handed to a worker", jobId)
 
 	var dynamicArgs []json.RawMessage
 	for _, depID := range depIDs {
@@ -274,7 +379,7 @@ func (s *Server) FinishJob(token uuid.UUID, result json.RawMessage) error {
 	}
 
 	var jobResult OSBuildJobResult
-	_, _, err = s.JobStatus(jobId, &jobResult)
+	status, _, err := s.JobStatus(jobId, &jobResult)
 	if err != nil {
 		return fmt.Errorf("error finding job status: %v", err)
 	}
@@ -283,19 +388,75 @@ func (s *Server) FinishJob(token uuid.UUID, result json.RawMessage) error {
 		prometheus.ComposeSuccesses.Inc()
 	}
 
+	jobType, rawArgs, _, err := s.jobs.Job(jobId)
+	if err != nil {
+		return fmt.Errorf("error finding job: %v", err)
+	}
+	recordFinishedJob(jobType, rawArgs, result, status, jobResult.Success)
+
+	logger := jobLogFields(logrus.NewEntry(logrus.StandardLogger()), jobId, jobType, rawArgs)
+	logger.Infof("Job %s finished", jobId)
+
 	// Move artifacts from the temporary location to the final job
 	// location. Log any errors, but do not treat them as fatal. The job is
 	// already finished.
 	if s.artifactsDir != "" {
 		err := os.Rename(path.Join(s.artifactsDir, "tmp", token.String()), path.Join(s.artifactsDir, jobId.String()))
 		if err != nil {
-			logrus.Errorf("Error moving artifacts for job %s: %v", jobId, err)
+			logger.Errorf("Error moving artifacts for job %s: %v", jobId, err)
 		}
 	}
 
 	return nil
 }
 
+// jobLogger returns a logger which adds the ID, type and, for compose jobs,
+// the image type of the job with the given id to all lines it logs.
+func (s *Server) jobLogger(id uuid.UUID) *logrus.Entry {
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	jobType, rawArgs, _, err := s.jobs.Job(id)
+	if err != nil {
+		return logger.WithField(common.LogFieldJobID, id.String())
+	}
+	return jobLogFields(logger, id, jobType, rawArgs)
+}
+
+func jobLogFields(logger *logrus.Entry, id uuid.UUID, jobType string, rawArgs json.RawMessage) *logrus.Entry {
+	fields := logrus.Fields{
+		common.LogFieldJobID:   id.String(),
+		common.LogFieldJobType: jobType,
+	}
+	var args struct {
+		ImageType string `json:"image_type"`
+	}
+	if json.Unmarshal(rawArgs, &args) == nil && args.ImageType != "" {
+		fields[common.LogFieldImageType] = args.ImageType
+	}
+	return logger.WithFields(fields)
+}
+
+// recordFinishedJob updates the metrics of a job that has just finished.
+// osbuildSuccess is the success flag of the result of osbuild jobs.
+func recordFinishedJob(jobType string, rawArgs, result json.RawMessage, status *JobStatus, osbuildSuccess bool) {
+	prometheus.RunningJobs.WithLabelValues(jobType).Dec()
+	prometheus.JobDuration.WithLabelValues(jobType).Observe(status.Finished.Sub(status.Started).Seconds())
+
+	var args struct {
+		ImageType string `json:"image_type"`
+	}
+	switch {
+	case strings.HasPrefix(jobType, "osbuild:"):
+		_ = json.Unmarshal(rawArgs, &args)
+		prometheus.ComposeFinished(args.ImageType, osbuildSuccess)
+	case strings.HasPrefix(jobType, "osbuild-koji:"):
+		var jobResult OSBuildKojiJobResult
+		_ = json.Unmarshal(result, &jobResult)
+		_ = json.Unmarshal(rawArgs, &args)
+		success := jobResult.OSBuildOutput != nil && jobResult.OSBuildOutput.Success && jobResult.KojiError == ""
+		prometheus.ComposeFinished(args.ImageType, success)
+	}
+}
+
 // apiHandlers implements api.ServerInterface - the http api route handlers
 // generated from api/openapi.yml. This is a separate object, because these
 // handlers should not be exposed on the `Server` object.
@@ -407,6 +568,7 @@ func (h *apiHandlers) GetJob(ctx echo.Context, tokenstr string) error {
 	}
 
 	h.server.jobs.RefreshHeartbeat(token)
+	prometheus.LastHeartbeat.SetToCurrentTime()
 
 	status, _, err := h.server.JobStatus(jobId, &json.RawMessage{})
 	if err != nil {
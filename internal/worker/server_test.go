@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 
 	"github.com/osbuild/osbuild-composer/internal/distro"
@@ -28,7 +30,7 @@ func newTestServer(t *testing.T, tempdir string, jobRequestTimeout time.Duration
 	if err != nil {
 		t.Fatalf("error creating fsjobqueue: %v", err)
 	}
-	return worker.NewServer(nil, q, "", jobRequestTimeout, basePath)
+	return worker.NewServer(nil, q, "", "", jobRequestTimeout, basePath)
 }
 
 // Ensure that the status request returns OK.
@@ -323,7 +325,7 @@ func TestOAuth(t *testing.T) {
 
 	q, err := fsjobqueue.New(tempdir)
 	require.NoError(t, err)
-	workerServer := worker.NewServer(nil, q, tempdir, time.Duration(0), "/api/image-builder-worker/v1")
+	workerServer := worker.NewServer(nil, q, tempdir, "", time.Duration(0), "/api/image-builder-worker/v1")
 	handler := workerServer.Handler()
 
 	workSrv := httptest.NewServer(handler)
@@ -407,3 +409,133 @@ func TestTimeout(t *testing.T) {
 	test.TestRoute(t, server.Handler(), false, "POST", "/api/image-builder-worker/v1/jobs", `{"arch":"arch","types":["types"]}`, http.StatusNoContent,
 		`{"href":"/api/image-builder-worker/v1/jobs","id":"00000000-0000-0000-0000-000000000000","kind":"RequestJob"}`)
 }
+
+func TestHasWorkersForArch(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "worker-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	server := newTestServer(t, tempdir, time.Millisecond*10, "/api/worker/v1")
+	require.False(t, server.HasWorkersForArch("x86_64"))
+
+	// requests count even if there is no job to hand out
+	_, _, _, _, _, err = server.RequestJob(context.Background(), "x86_64", []string{"osbuild"})
+	require.Equal(t, jobqueue.ErrDequeueTimeout, err)
+	require.True(t, server.HasWorkersForArch("x86_64"))
+	require.False(t, server.HasWorkersForArch("aarch64"))
+}
+
+func TestHasWorkersForArchRestart(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "worker-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	q, err := fsjobqueue.New(tempdir)
+	require.NoError(t, err)
+	stateDir := path.Join(tempdir, "workers")
+	require.NoError(t, os.Mkdir(stateDir, 0700))
+	server := worker.NewServer(nil, q, "", stateDir, time.Millisecond*10, "/api/worker/v1")
+	_, _, _, _, _, err = server.RequestJob(context.Background(), "aarch64", []string{"osbuild"})
+	require.Equal(t, jobqueue.ErrDequeueTimeout, err)
+
+	// the architectures of workers survive a restart of composer
+	server = worker.NewServer(nil, q, "", stateDir, time.Millisecond*10, "/api/worker/v1")
+	require.True(t, server.HasWorkersForArch("aarch64"))
+	require.False(t, server.HasWorkersForArch("x86_64"))
+}
+
+// metricValue returns the value of the counter or gauge with the given name
+// and labels in the default registry, or 0 if it does not exist.
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := prom.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			if metric.GetCounter() != nil {
+				return metric.GetCounter().GetValue()
+			}
+			if metric.GetHistogram() != nil {
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+			return metric.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "worker-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	distroStruct := test_distro.New()
+	arch, err := distroStruct.GetArch(test_distro.TestArchName)
+	require.NoError(t, err)
+	imageType, err := arch.GetImageType(test_distro.TestImageTypeName)
+	require.NoError(t, err)
+	manifest, err := imageType.Manifest(nil, distro.ImageOptions{Size: imageType.Size(0)}, nil, nil, 0)
+	require.NoError(t, err)
+	server := newTestServer(t, tempdir, time.Duration(0), "/api/worker/v1")
+	handler := server.Handler()
+
+	jobType := map[string]string{"job_type": "osbuild:" + arch.Name()}
+	composeLabels := map[string]string{"status": "success", "image_type": imageType.Name()}
+	pending := metricValue(t, "composer_pending_jobs", jobType)
+	running := metricValue(t, "composer_running_jobs", jobType)
+	durations := metricValue(t, "composer_job_duration_seconds", jobType)
+	composes := metricValue(t, "composer_composes_total", composeLabels)
+
+	_, err = server.EnqueueOSBuild(arch.Name(), &worker.OSBuildJob{Manifest: manifest, ImageType: imageType.Name()})
+	require.NoError(t, err)
+	require.Equal(t, pending+1, metricValue(t, "composer_pending_jobs", jobType))
+
+	_, token, _, _, _, err := server.RequestJob(context.Background(), arch.Name(), []string{"osbuild"})
+	require.NoError(t, err)
+	require.Equal(t, pending, metricValue(t, "composer_pending_jobs", jobType))
+	require.Equal(t, running+1, metricValue(t, "composer_running_jobs", jobType))
+
+	test.TestRoute(t, handler, false, "GET", fmt.Sprintf("/api/worker/v1/jobs/%s", token), ``, http.StatusOK, `?`)
+	require.NotZero(t, metricValue(t, "composer_last_heartbeat_timestamp_seconds", nil))
+
+	test.TestRoute(t, handler, false, "PATCH", fmt.Sprintf("/api/worker/v1/jobs/%s", token), `{"status":"FINISHED","result":{"success":true}}`, http.StatusOK, `?`)
+	require.Equal(t, running, metricValue(t, "composer_running_jobs", jobType))
+	require.Equal(t, durations+1, metricValue(t, "composer_job_duration_seconds", jobType))
+	require.Equal(t, composes+1, metricValue(t, "composer_composes_total", composeLabels))
+
+	// canceling a pending job takes it out of the queue
+	jobID, err := server.EnqueueOSBuild(arch.Name(), &worker.OSBuildJob{Manifest: manifest})
+	require.NoError(t, err)
+	require.NoError(t, server.Cancel(jobID))
+	require.Equal(t, pending, metricValue(t, "composer_pending_jobs", jobType))
+}
+
+func TestMetricsRestart(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "worker-tests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempdir)
+
+	q, err := fsjobqueue.New(tempdir)
+	require.NoError(t, err)
+	_, err = q.Enqueue("depsolve", &worker.DepsolveJob{}, nil)
+	require.NoError(t, err)
+	_, err = q.Enqueue("depsolve", &worker.DepsolveJob{}, nil)
+	require.NoError(t, err)
+	_, _, _, _, _, err = q.Dequeue(context.Background(), []string{"depsolve"})
+	require.NoError(t, err)
+
+	// the gauges start with the jobs that were queued before composer
+	// started, instead of going negative when they are handed out
+	jobType := map[string]string{"job_type": "depsolve"}
+	worker.NewServer(nil, q, "", "", time.Duration(0), "/api/worker/v1")
+	require.Equal(t, float64(1), metricValue(t, "composer_pending_jobs", jobType))
+	require.Equal(t, float64(1), metricValue(t, "composer_running_jobs", jobType))
+}